@@ -100,6 +100,18 @@ const (
 	// NT_PRFPREG is for float point register.
 	NT_PRFPREG = 0x2
 
+	// NT_PRPSINFO is for process information.
+	NT_PRPSINFO = 0x3
+
+	// NT_AUXV is for the auxiliary vector.
+	NT_AUXV = 0x6
+
+	// NT_SIGINFO is for the siginfo_t of the signal that caused the dump.
+	NT_SIGINFO = 0x53494749
+
+	// NT_FILE is for the list of mapped files.
+	NT_FILE = 0x46494c45
+
 	// NT_X86_XSTATE is for x86 extended state using xsave.
 	NT_X86_XSTATE = 0x202
 
@@ -156,3 +168,95 @@ type ElfProg64 struct {
 	Memsz  uint64 // Size of contents in memory.
 	Align  uint64 // Alignment in memory and file.
 }
+
+// ElfNote64 is the header of an ELF64 note. It is followed by the note name
+// and descriptor, each padded to a 4-byte boundary.
+//
+// +marshal
+type ElfNote64 struct {
+	Namesz uint32 // Length of the note name, including the NUL terminator.
+	Descsz uint32 // Length of the note descriptor.
+	Type   uint32 // Note type.
+}
+
+// ElfSiginfo is the signal information embedded in ElfPrStatus.
+//
+// +marshal
+type ElfSiginfo struct {
+	Signo int32 // Signal number.
+	Code  int32 // Extra code.
+	Errno int32 // Errno.
+}
+
+// ElfPrStatus is the NT_PRSTATUS note descriptor, struct elf_prstatus in
+// include/linux/elfcore.h.
+//
+// +marshal
+type ElfPrStatus struct {
+	Info    ElfSiginfo // Info associated with signal.
+	Cursig  int16      // Current signal.
+	_       [2]byte
+	Sigpend uint64     // Set of pending signals.
+	Sighold uint64     // Set of held signals.
+	Pid     int32      // Thread ID.
+	Ppid    int32      // Parent thread group ID.
+	Pgrp    int32      // Process group ID.
+	Sid     int32      // Session ID.
+	Utime   Timeval    // User time.
+	Stime   Timeval    // System time.
+	Cutime  Timeval    // Cumulative user time.
+	Cstime  Timeval    // Cumulative system time.
+	Reg     PtraceRegs // General purpose registers.
+	Fpvalid int32      // True if math co-processor being used.
+	_       [4]byte
+}
+
+// ElfPrPsinfoFnameLen and ElfPrPsinfoArgsLen are the sizes of
+// ElfPrPsinfo.Fname and ElfPrPsinfo.Psargs respectively.
+const (
+	ElfPrPsinfoFnameLen = 16
+	ElfPrPsinfoArgsLen  = 80
+)
+
+// ElfPrPsinfo is the NT_PRPSINFO note descriptor, struct elf_prpsinfo in
+// include/linux/elfcore.h.
+//
+// +marshal
+type ElfPrPsinfo struct {
+	State  byte // Numeric process state.
+	Sname  byte // Char for State.
+	Zomb   byte // Zombie.
+	Nice   byte // Nice value.
+	_      [4]byte
+	Flag   uint64 // Flags.
+	UID    uint32
+	GID    uint32
+	Pid    int32
+	Ppid   int32
+	Pgrp   int32
+	Sid    int32
+	Fname  [ElfPrPsinfoFnameLen]byte // Filename of executable.
+	Psargs [ElfPrPsinfoArgsLen]byte  // Initial part of arg list.
+}
+
+// Bits in /proc/[pid]/coredump_filter, which select the kinds of mappings that
+// are written to core dumps.
+//
+// See include/linux/sched/coredump.h.
+const (
+	MMF_DUMP_ANON_PRIVATE    = 1 << 0
+	MMF_DUMP_ANON_SHARED     = 1 << 1
+	MMF_DUMP_MAPPED_PRIVATE  = 1 << 2
+	MMF_DUMP_MAPPED_SHARED   = 1 << 3
+	MMF_DUMP_ELF_HEADERS     = 1 << 4
+	MMF_DUMP_HUGETLB_PRIVATE = 1 << 5
+	MMF_DUMP_HUGETLB_SHARED  = 1 << 6
+	MMF_DUMP_DAX_PRIVATE     = 1 << 7
+	MMF_DUMP_DAX_SHARED      = 1 << 8
+
+	// MMF_DUMP_FILTER_MASK is the set of valid coredump_filter bits.
+	MMF_DUMP_FILTER_MASK = (1 << 9) - 1
+
+	// MMF_DUMP_FILTER_DEFAULT is the default coredump_filter.
+	MMF_DUMP_FILTER_DEFAULT = MMF_DUMP_ANON_PRIVATE | MMF_DUMP_ANON_SHARED | MMF_DUMP_ELF_HEADERS | MMF_DUMP_HUGETLB_PRIVATE
+)
@@ -56,30 +56,34 @@ const (
 	FUSE_STATFS  = 17
 	FUSE_RELEASE = 18
 	_
-	FUSE_FSYNC        = 20
-	FUSE_SETXATTR     = 21
-	FUSE_GETXATTR     = 22
-	FUSE_LISTXATTR    = 23
-	FUSE_REMOVEXATTR  = 24
-	FUSE_FLUSH        = 25
-	FUSE_INIT         = 26
-	FUSE_OPENDIR      = 27
-	FUSE_READDIR      = 28
-	FUSE_RELEASEDIR   = 29
-	FUSE_FSYNCDIR     = 30
-	FUSE_GETLK        = 31
-	FUSE_SETLK        = 32
-	FUSE_SETLKW       = 33
-	FUSE_ACCESS       = 34
-	FUSE_CREATE       = 35
-	FUSE_INTERRUPT    = 36
-	FUSE_BMAP         = 37
-	FUSE_DESTROY      = 38
-	FUSE_IOCTL        = 39
-	FUSE_POLL         = 40
-	FUSE_NOTIFY_REPLY = 41
-	FUSE_BATCH_FORGET = 42
-	FUSE_FALLOCATE    = 43
+	FUSE_FSYNC           = 20
+	FUSE_SETXATTR        = 21
+	FUSE_GETXATTR        = 22
+	FUSE_LISTXATTR       = 23
+	FUSE_REMOVEXATTR     = 24
+	FUSE_FLUSH           = 25
+	FUSE_INIT            = 26
+	FUSE_OPENDIR         = 27
+	FUSE_READDIR         = 28
+	FUSE_RELEASEDIR      = 29
+	FUSE_FSYNCDIR        = 30
+	FUSE_GETLK           = 31
+	FUSE_SETLK           = 32
+	FUSE_SETLKW          = 33
+	FUSE_ACCESS          = 34
+	FUSE_CREATE          = 35
+	FUSE_INTERRUPT       = 36
+	FUSE_BMAP            = 37
+	FUSE_DESTROY         = 38
+	FUSE_IOCTL           = 39
+	FUSE_POLL            = 40
+	FUSE_NOTIFY_REPLY    = 41
+	FUSE_BATCH_FORGET    = 42
+	FUSE_FALLOCATE       = 43
+	FUSE_READDIRPLUS     = 44
+	FUSE_RENAME2         = 45
+	FUSE_LSEEK           = 46
+	FUSE_COPY_FILE_RANGE = 47
 )
 
 const (
@@ -618,6 +622,12 @@ type FUSEWriteOut struct {
 	_ uint32
 }
 
+// FUSE_RELEASE flags, consistent with the ones in include/uapi/linux/fuse.h.
+const (
+	FUSE_RELEASE_FLUSH        = 1 << 0
+	FUSE_RELEASE_FLOCK_UNLOCK = 1 << 1
+)
+
 // FUSEReleaseIn is the request sent by the kernel to the daemon
 // when there is no more reference to a file.
 //
@@ -1137,3 +1147,467 @@ type FUSEFlushIn struct {
 	_         uint32 // padding
 	LockOwner uint64
 }
+
+// FUSEGetXattrIn is the request sent by the kernel to the daemon for
+// FUSE_LISTXATTR, and the static part of the request for FUSE_GETXATTR.
+//
+// +marshal
+type FUSEGetXattrIn struct {
+	// Size is the size of the caller's buffer. If it is 0, the daemon replies
+	// with a FUSEGetXattrOut containing the required size instead of the
+	// attribute value or name list.
+	Size uint32
+	// padding
+	_ uint32
+}
+
+// FUSEGetXattrOut is the reply sent by the daemon to the kernel for
+// FUSE_GETXATTR and FUSE_LISTXATTR requests with a zero size.
+//
+// +marshal
+type FUSEGetXattrOut struct {
+	// Size is the size of the attribute value or name list.
+	Size uint32
+	// padding
+	_ uint32
+}
+
+// FUSEGetXattrNameIn contains all the arguments sent by the kernel to the
+// daemon to get the value of an extended attribute.
+//
+// +marshal dynamic
+type FUSEGetXattrNameIn struct {
+	// GetXattrIn contains the size of the caller's buffer.
+	GetXattrIn FUSEGetXattrIn
+	// Name of the extended attribute.
+	Name CString
+}
+
+// MarshalBytes implements marshal.Marshallable.MarshalBytes.
+func (r *FUSEGetXattrNameIn) MarshalBytes(buf []byte) []byte {
+	buf = r.GetXattrIn.MarshalBytes(buf)
+	return r.Name.MarshalBytes(buf)
+}
+
+// UnmarshalBytes implements marshal.Marshallable.UnmarshalBytes.
+func (r *FUSEGetXattrNameIn) UnmarshalBytes(buf []byte) []byte {
+	panic("Unimplemented, FUSEGetXattrNameIn is never unmarshalled")
+}
+
+// SizeBytes implements marshal.Marshallable.SizeBytes.
+func (r *FUSEGetXattrNameIn) SizeBytes() int {
+	return r.GetXattrIn.SizeBytes() + r.Name.SizeBytes()
+}
+
+// FUSESetXattrMeta contains all the static fields of FUSESetXattrIn. This is
+// the compat layout of struct fuse_setxattr_in, which is used unless
+// FUSE_SETXATTR_EXT is negotiated.
+//
+// +marshal
+type FUSESetXattrMeta struct {
+	// Size is the size of the attribute value.
+	Size uint32
+	// Flags are the setxattr(2) flags.
+	Flags uint32
+}
+
+// FUSESetXattrIn contains all the arguments sent by the kernel to the daemon
+// to set an extended attribute.
+//
+// +marshal dynamic
+type FUSESetXattrIn struct {
+	// SetXattrMeta contains the size of the value and the setxattr(2) flags.
+	SetXattrMeta FUSESetXattrMeta
+	// Name of the extended attribute.
+	Name CString
+	// Value of the extended attribute, which is not null-terminated.
+	Value primitive.ByteSlice
+}
+
+// MarshalBytes implements marshal.Marshallable.MarshalBytes.
+func (r *FUSESetXattrIn) MarshalBytes(buf []byte) []byte {
+	buf = r.SetXattrMeta.MarshalBytes(buf)
+	buf = r.Name.MarshalBytes(buf)
+	return r.Value.MarshalBytes(buf)
+}
+
+// UnmarshalBytes implements marshal.Marshallable.UnmarshalBytes.
+func (r *FUSESetXattrIn) UnmarshalBytes(buf []byte) []byte {
+	panic("Unimplemented, FUSESetXattrIn is never unmarshalled")
+}
+
+// SizeBytes implements marshal.Marshallable.SizeBytes.
+func (r *FUSESetXattrIn) SizeBytes() int {
+	return r.SetXattrMeta.SizeBytes() + r.Name.SizeBytes() + r.Value.SizeBytes()
+}
+
+// FUSERemoveXattrIn is the request sent by the kernel to the daemon to remove
+// an extended attribute.
+//
+// +marshal dynamic
+type FUSERemoveXattrIn struct {
+	// Name of the extended attribute.
+	Name CString
+}
+
+// MarshalBytes implements marshal.Marshallable.MarshalBytes.
+func (r *FUSERemoveXattrIn) MarshalBytes(buf []byte) []byte {
+	return r.Name.MarshalBytes(buf)
+}
+
+// UnmarshalBytes implements marshal.Marshallable.UnmarshalBytes.
+func (r *FUSERemoveXattrIn) UnmarshalBytes(buf []byte) []byte {
+	panic("Unimplemented, FUSERemoveXattrIn is never unmarshalled")
+}
+
+// SizeBytes implements marshal.Marshallable.SizeBytes.
+func (r *FUSERemoveXattrIn) SizeBytes() int {
+	return r.Name.SizeBytes()
+}
+
+// FUSE_LK_FLOCK is set in FUSELkIn.LkFlags for BSD-style locks, see flock(2).
+const FUSE_LK_FLOCK = 1 << 0
+
+// FUSE_INT_REQ_BIT is set in the unique ID of FUSE_INTERRUPT requests, which
+// is otherwise the unique ID of the interrupted request.
+const FUSE_INT_REQ_BIT = 1 << 0
+
+// FUSEFileLock describes a file lock in FUSE_GETLK, FUSE_SETLK and
+// FUSE_SETLKW requests and replies.
+//
+// +marshal
+type FUSEFileLock struct {
+	// Start is the first byte of the locked range.
+	Start uint64
+	// End is the last byte of the locked range, inclusive, or math.MaxInt64
+	// (Linux's OFFSET_MAX) if the range extends to the end of the file.
+	End uint64
+	// Type is the lock type, one of F_RDLCK, F_WRLCK or F_UNLCK.
+	Type uint32
+	// PID is the thread group ID of the lock holder.
+	PID uint32
+}
+
+// FUSELkIn is the request sent by the kernel to the daemon for FUSE_GETLK,
+// FUSE_SETLK and FUSE_SETLKW.
+//
+// +marshal
+type FUSELkIn struct {
+	// Fh is the file handle of the file to lock.
+	Fh uint64
+	// Owner is the id of the lock owner.
+	Owner uint64
+	// Lk is the lock to test or set.
+	Lk FUSEFileLock
+	// LkFlags may contain FUSE_LK_FLOCK.
+	LkFlags uint32
+	// padding
+	_ uint32
+}
+
+// FUSELkOut is the reply sent by the daemon to the kernel for FUSE_GETLK.
+//
+// +marshal
+type FUSELkOut struct {
+	// Lk is a conflicting lock, or a lock of type F_UNLCK if there is none.
+	Lk FUSEFileLock
+}
+
+// FUSEInterruptIn is the request sent by the kernel to the daemon to
+// interrupt a request.
+//
+// +marshal
+type FUSEInterruptIn struct {
+	// Unique is the unique ID of the request to interrupt.
+	Unique FUSEOpID
+}
+
+// FUSENotifyCode is the type of a notification sent by the FUSE server. A
+// notification is a message written by the server with a FUSEHeaderOut whose
+// Unique is 0 and whose Error is the notification code.
+type FUSENotifyCode int32
+
+// Notification codes, consistent with enum fuse_notify_code in
+// include/uapi/linux/fuse.h.
+const (
+	FUSE_NOTIFY_POLL        FUSENotifyCode = 1
+	FUSE_NOTIFY_INVAL_INODE FUSENotifyCode = 2
+	FUSE_NOTIFY_INVAL_ENTRY FUSENotifyCode = 3
+	FUSE_NOTIFY_STORE       FUSENotifyCode = 4
+	FUSE_NOTIFY_RETRIEVE    FUSENotifyCode = 5
+	FUSE_NOTIFY_DELETE      FUSENotifyCode = 6
+)
+
+// FUSE_EXPIRE_ONLY is set in FUSENotifyInvalEntryOut.Flags if the entry
+// should only be expired, rather than dropped.
+const FUSE_EXPIRE_ONLY = 1 << 0
+
+// FUSENotifyPollWakeupOut is the payload of a FUSE_NOTIFY_POLL notification.
+//
+// +marshal
+type FUSENotifyPollWakeupOut struct {
+	// Kh is the poll handle of the file that became ready.
+	Kh uint64
+}
+
+// FUSENotifyInvalInodeOut is the payload of a FUSE_NOTIFY_INVAL_INODE
+// notification.
+//
+// +marshal
+type FUSENotifyInvalInodeOut struct {
+	// Ino is the node ID of the inode to invalidate.
+	Ino uint64
+	// Off is the offset of the cached data to invalidate. If negative, only
+	// the attributes are invalidated.
+	Off int64
+	// Len is the length of the cached data to invalidate. If 0 or negative,
+	// the data is invalidated up to the end of the file.
+	Len int64
+}
+
+// FUSENotifyInvalEntryOut is the payload of a FUSE_NOTIFY_INVAL_ENTRY
+// notification, which is followed by the null-terminated name of the entry.
+//
+// +marshal
+type FUSENotifyInvalEntryOut struct {
+	// Parent is the node ID of the directory containing the entry.
+	Parent uint64
+	// NameLen is the length of the name, excluding the null terminator.
+	NameLen uint32
+	// Flags may contain FUSE_EXPIRE_ONLY.
+	Flags uint32
+}
+
+// FUSENotifyDeleteOut is the payload of a FUSE_NOTIFY_DELETE notification,
+// which is followed by the null-terminated name of the entry.
+//
+// +marshal
+type FUSENotifyDeleteOut struct {
+	// Parent is the node ID of the directory containing the entry.
+	Parent uint64
+	// Child is the node ID of the deleted entry.
+	Child uint64
+	// NameLen is the length of the name, excluding the null terminator.
+	NameLen uint32
+	// padding
+	_ uint32
+}
+
+// FUSENotifyStoreOut is the payload of a FUSE_NOTIFY_STORE notification,
+// which is followed by the data to store.
+//
+// +marshal
+type FUSENotifyStoreOut struct {
+	// NodeID is the node ID of the inode whose data is stored.
+	NodeID uint64
+	// Offset is the file offset at which the data is stored.
+	Offset uint64
+	// Size is the size of the data.
+	Size uint32
+	// padding
+	_ uint32
+}
+
+// FUSENotifyRetrieveOut is the payload of a FUSE_NOTIFY_RETRIEVE notification.
+//
+// +marshal
+type FUSENotifyRetrieveOut struct {
+	// NotifyUnique is the unique ID of the FUSE_NOTIFY_REPLY request sent in
+	// response.
+	NotifyUnique FUSEOpID
+	// NodeID is the node ID of the inode whose data is retrieved.
+	NodeID uint64
+	// Offset is the file offset of the data to retrieve.
+	Offset uint64
+	// Size is the size of the data to retrieve.
+	Size uint32
+	// padding
+	_ uint32
+}
+
+// FUSENotifyRetrieveIn is the payload of the FUSE_NOTIFY_REPLY request sent
+// by the kernel in response to a FUSE_NOTIFY_RETRIEVE notification. It is
+// followed by the retrieved data.
+//
+// +marshal
+type FUSENotifyRetrieveIn struct {
+	_ uint64
+	// Offset is the file offset of the retrieved data.
+	Offset uint64
+	// Size is the size of the retrieved data.
+	Size uint32
+	_    uint32
+	_    uint64
+	_    uint64
+}
+
+// FUSE_IOCTL flags, consistent with the ones in include/uapi/linux/fuse.h.
+const (
+	// FUSE_IOCTL_COMPAT indicates a 32-bit compat ioctl on a 64-bit machine.
+	FUSE_IOCTL_COMPAT = 1 << 0
+	// FUSE_IOCTL_UNRESTRICTED indicates that the ioctl is not restricted to
+	// the buffer described by its command.
+	FUSE_IOCTL_UNRESTRICTED = 1 << 1
+	// FUSE_IOCTL_RETRY is set by the server to retry an unrestricted ioctl
+	// with the buffers it describes.
+	FUSE_IOCTL_RETRY = 1 << 2
+	// FUSE_IOCTL_32BIT indicates a 32-bit ioctl.
+	FUSE_IOCTL_32BIT = 1 << 3
+	// FUSE_IOCTL_DIR indicates an ioctl on a directory.
+	FUSE_IOCTL_DIR = 1 << 4
+	// FUSE_IOCTL_COMPAT_X32 indicates an x32 compat ioctl on a 64-bit machine.
+	FUSE_IOCTL_COMPAT_X32 = 1 << 5
+
+	// FUSE_IOCTL_MAX_IOV is the maximum number of iovecs of an unrestricted
+	// ioctl.
+	FUSE_IOCTL_MAX_IOV = 256
+)
+
+// FUSEIoctlIn is the request sent by the kernel to the daemon for
+// FUSE_IOCTL. It is followed by InSize bytes of input data.
+//
+// +marshal
+type FUSEIoctlIn struct {
+	// Fh is the file handle of the file.
+	Fh uint64
+	// Flags are FUSE_IOCTL_* flags.
+	Flags uint32
+	// Cmd is the ioctl command.
+	Cmd uint32
+	// Arg is the ioctl argument.
+	Arg uint64
+	// InSize is the size of the input data.
+	InSize uint32
+	// OutSize is the maximum size of the output data.
+	OutSize uint32
+}
+
+// FUSEIoctlPayloadIn combines FUSEIoctlIn and the input data of a FUSE_IOCTL
+// request in a single marshallable struct.
+//
+// +marshal dynamic
+type FUSEIoctlPayloadIn struct {
+	Header  FUSEIoctlIn
+	Payload primitive.ByteSlice
+}
+
+// SizeBytes implements marshal.Marshallable.SizeBytes.
+func (r *FUSEIoctlPayloadIn) SizeBytes() int {
+	if r == nil {
+		return (*FUSEIoctlIn)(nil).SizeBytes()
+	}
+	return r.Header.SizeBytes() + r.Payload.SizeBytes()
+}
+
+// MarshalBytes implements marshal.Marshallable.MarshalBytes.
+func (r *FUSEIoctlPayloadIn) MarshalBytes(dst []byte) []byte {
+	dst = r.Header.MarshalUnsafe(dst)
+	dst = r.Payload.MarshalUnsafe(dst)
+	return dst
+}
+
+// UnmarshalBytes implements marshal.Marshallable.UnmarshalBytes.
+func (r *FUSEIoctlPayloadIn) UnmarshalBytes(src []byte) []byte {
+	panic("Unimplemented, FUSEIoctlPayloadIn is never unmarshalled")
+}
+
+// FUSEIoctlOut is the reply sent by the daemon to the kernel for FUSE_IOCTL.
+// It is followed by the output data.
+//
+// +marshal
+type FUSEIoctlOut struct {
+	// Result is the return value of the ioctl.
+	Result int32
+	// Flags are FUSE_IOCTL_* flags.
+	Flags uint32
+	// InIovs is the number of input iovecs to retry with.
+	InIovs uint32
+	// OutIovs is the number of output iovecs to retry with.
+	OutIovs uint32
+}
+
+// FUSE_POLL flags, consistent with the ones in include/uapi/linux/fuse.h.
+const (
+	// FUSE_POLL_SCHEDULE_NOTIFY requests a FUSE_NOTIFY_POLL notification
+	// when the file becomes ready.
+	FUSE_POLL_SCHEDULE_NOTIFY = 1 << 0
+)
+
+// FUSEPollIn is the request sent by the kernel to the daemon for FUSE_POLL.
+//
+// +marshal
+type FUSEPollIn struct {
+	// Fh is the file handle of the file.
+	Fh uint64
+	// Kh is the kernel handle identifying the file in FUSE_NOTIFY_POLL.
+	Kh uint64
+	// Flags are FUSE_POLL_* flags.
+	Flags uint32
+	// Events are the poll events of interest.
+	Events uint32
+}
+
+// FUSEPollOut is the reply sent by the daemon to the kernel for FUSE_POLL.
+//
+// +marshal
+type FUSEPollOut struct {
+	// Revents are the poll events that are ready.
+	Revents uint32
+	_       uint32
+}
+
+// FUSELseekIn is the request sent by the kernel to the daemon for
+// FUSE_LSEEK.
+//
+// +marshal
+type FUSELseekIn struct {
+	// Fh is the file handle of the file.
+	Fh uint64
+	// Offset is the offset to seek from.
+	Offset uint64
+	// Whence is SEEK_DATA or SEEK_HOLE.
+	Whence uint32
+	_      uint32
+}
+
+// FUSELseekOut is the reply sent by the daemon to the kernel for FUSE_LSEEK.
+//
+// +marshal
+type FUSELseekOut struct {
+	// Offset is the resulting offset.
+	Offset uint64
+}
+
+// FUSECopyFileRangeIn is the request sent by the kernel to the daemon for
+// FUSE_COPY_FILE_RANGE. The header's node ID is the node ID of the source
+// file. The reply is a FUSEWriteOut.
+//
+// +marshal
+type FUSECopyFileRangeIn struct {
+	// FhIn is the file handle of the source file.
+	FhIn uint64
+	// OffIn is the offset in the source file.
+	OffIn uint64
+	// NodeIDOut is the node ID of the destination file.
+	NodeIDOut uint64
+	// FhOut is the file handle of the destination file.
+	FhOut uint64
+	// OffOut is the offset in the destination file.
+	OffOut uint64
+	// Len is the number of bytes to copy.
+	Len uint64
+	// Flags are copy_file_range(2) flags.
+	Flags uint64
+}
+
+// /dev/fuse ioctls, consistent with the ones in include/uapi/linux/fuse.h.
+const (
+	// FUSE_DEV_IOC_MAGIC is the ioctl type of /dev/fuse ioctls.
+	FUSE_DEV_IOC_MAGIC = 229
+
+	// FUSE_DEV_IOC_CLONE attaches a /dev/fuse file description to the
+	// connection of the /dev/fuse file descriptor passed as argument, such
+	// that the FUSE server can serve requests through both in parallel. It is
+	// _IOR(FUSE_DEV_IOC_MAGIC, 0, uint32_t).
+	FUSE_DEV_IOC_CLONE = 0x8004e500
+)
@@ -16,18 +16,38 @@ package linux
 
 // ioctl(2) request numbers from linux/if_tun.h
 var (
-	TUNSETIFF     = IOW('T', 202, 4)
-	TUNSETPERSIST = IOW('T', 203, 4)
-	TUNGETIFF     = IOR('T', 210, 4)
+	TUNSETIFF       = IOW('T', 202, 4)
+	TUNSETPERSIST   = IOW('T', 203, 4)
+	TUNGETFEATURES  = IOR('T', 207, 4)
+	TUNSETOFFLOAD   = IOW('T', 208, 4)
+	TUNGETIFF       = IOR('T', 210, 4)
+	TUNGETVNETHDRSZ = IOR('T', 215, 4)
+	TUNSETVNETHDRSZ = IOW('T', 216, 4)
+	TUNSETQUEUE     = IOW('T', 217, 4)
 )
 
 // Flags from net/if_tun.h
 const (
-	IFF_TUN      = 0x0001
-	IFF_TAP      = 0x0002
-	IFF_NO_PI    = 0x1000
-	IFF_NOFILTER = 0x1000
-	IFF_TUN_EXCL = 0x8000
+	IFF_TUN          = 0x0001
+	IFF_TAP          = 0x0002
+	IFF_MULTI_QUEUE  = 0x0100
+	IFF_ATTACH_QUEUE = 0x0200
+	IFF_DETACH_QUEUE = 0x0400
+	IFF_NO_PI        = 0x1000
+	IFF_NOFILTER     = 0x1000
+	IFF_VNET_HDR     = 0x4000
+	IFF_TUN_EXCL     = 0x8000
 	// According to linux/if_tun.h "This flag has no real effect"
 	IFF_ONE_QUEUE = 0x2000
 )
+
+// Features for TUNSETOFFLOAD from net/if_tun.h
+const (
+	TUN_F_CSUM    = 0x01
+	TUN_F_TSO4    = 0x02
+	TUN_F_TSO6    = 0x04
+	TUN_F_TSO_ECN = 0x08
+	TUN_F_UFO     = 0x10
+	TUN_F_USO4    = 0x20
+	TUN_F_USO6    = 0x40
+)
@@ -21,6 +21,11 @@ package linux
 // Defined in linux/interrupt.h.
 const NumSoftIRQ = 10
 
+// SI_LOAD_SHIFT is the number of fractional bits in Sysinfo.Loads.
+//
+// Defined in include/uapi/linux/kernel.h.
+const SI_LOAD_SHIFT = 16
+
 // Sysinfo is the structure provided by sysinfo on linux versions > 2.3.48.
 //
 // +marshal
@@ -0,0 +1,101 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// Device numbers from uapi/linux/major.h and include/linux/miscdevice.h.
+const (
+	// LOOP_MAJOR is the major device number for loop block devices.
+	LOOP_MAJOR = 7
+
+	// LOOP_CTRL_MINOR is the minor device number of /dev/loop-control, whose
+	// major device number is MISC_MAJOR.
+	LOOP_CTRL_MINOR = 237
+)
+
+// ioctl(2) request numbers from uapi/linux/loop.h.
+const (
+	LOOP_SET_FD         = 0x4C00
+	LOOP_CLR_FD         = 0x4C01
+	LOOP_SET_STATUS     = 0x4C02
+	LOOP_GET_STATUS     = 0x4C03
+	LOOP_SET_STATUS64   = 0x4C04
+	LOOP_GET_STATUS64   = 0x4C05
+	LOOP_CHANGE_FD      = 0x4C06
+	LOOP_SET_CAPACITY   = 0x4C07
+	LOOP_SET_DIRECT_IO  = 0x4C08
+	LOOP_SET_BLOCK_SIZE = 0x4C09
+	LOOP_CONFIGURE      = 0x4C0A
+
+	LOOP_CTL_ADD      = 0x4C80
+	LOOP_CTL_REMOVE   = 0x4C81
+	LOOP_CTL_GET_FREE = 0x4C82
+)
+
+// ioctl(2) request numbers from uapi/linux/fs.h.
+var (
+	BLKSSZGET    = IO(0x12, 104)
+	BLKGETSIZE64 = IOR(0x12, 114, 8)
+)
+
+// Flags for LoopInfo64.Flags, from uapi/linux/loop.h.
+const (
+	LO_FLAGS_READ_ONLY = 1
+	LO_FLAGS_AUTOCLEAR = 4
+	LO_FLAGS_PARTSCAN  = 8
+	LO_FLAGS_DIRECT_IO = 16
+
+	// LOOP_SET_STATUS_SETTABLE_FLAGS are the flags that may be changed by
+	// LOOP_SET_STATUS64.
+	LOOP_SET_STATUS_SETTABLE_FLAGS = LO_FLAGS_AUTOCLEAR | LO_FLAGS_PARTSCAN
+
+	// LOOP_CONFIGURE_SETTABLE_FLAGS are the flags that may be set by
+	// LOOP_CONFIGURE.
+	LOOP_CONFIGURE_SETTABLE_FLAGS = LO_FLAGS_READ_ONLY | LO_FLAGS_AUTOCLEAR | LO_FLAGS_PARTSCAN | LO_FLAGS_DIRECT_IO
+)
+
+// Sizes of LoopInfo64 fields.
+const (
+	LO_NAME_SIZE = 64
+	LO_KEY_SIZE  = 32
+)
+
+// LoopInfo64 is struct loop_info64, from uapi/linux/loop.h.
+//
+// +marshal
+type LoopInfo64 struct {
+	Device         uint64
+	Inode          uint64
+	Rdevice        uint64
+	Offset         uint64
+	Sizelimit      uint64
+	Number         uint32
+	EncryptType    uint32
+	EncryptKeySize uint32
+	Flags          uint32
+	FileName       [LO_NAME_SIZE]byte
+	CryptName      [LO_NAME_SIZE]byte
+	EncryptKey     [LO_KEY_SIZE]byte
+	Init           [2]uint64
+}
+
+// LoopConfig is struct loop_config, from uapi/linux/loop.h.
+//
+// +marshal
+type LoopConfig struct {
+	FD        uint32
+	BlockSize uint32
+	Info      LoopInfo64
+	Reserved  [8]uint64
+}
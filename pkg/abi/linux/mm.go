@@ -135,6 +135,60 @@ const (
 	MPOL_MF_VALID = MPOL_MF_STRICT | MPOL_MF_MOVE | MPOL_MF_MOVE_ALL
 )
 
+// Bits in /proc/[pid]/pagemap entries, from fs/proc/task_mmu.c.
+const (
+	PM_ENTRY_BYTES    = 8
+	PM_PFRAME_BITS    = 55
+	PM_PFRAME_MASK    = (1 << PM_PFRAME_BITS) - 1
+	PM_SOFT_DIRTY     = 1 << 55
+	PM_MMAP_EXCLUSIVE = 1 << 56
+	PM_UFFD_WP        = 1 << 57
+	PM_FILE           = 1 << 61
+	PM_SWAP           = 1 << 62
+	PM_PRESENT        = 1 << 63
+)
+
+// Values written to /proc/[pid]/clear_refs, from fs/proc/task_mmu.c.
+const (
+	CLEAR_REFS_ALL            = 1
+	CLEAR_REFS_ANON           = 2
+	CLEAR_REFS_MAPPED         = 3
+	CLEAR_REFS_SOFT_DIRTY     = 4
+	CLEAR_REFS_MM_HIWATER_RSS = 5
+)
+
+// Bit numbers in /proc/kpageflags entries, from
+// include/uapi/linux/kernel-page-flags.h.
+const (
+	KPF_LOCKED        = 0
+	KPF_ERROR         = 1
+	KPF_REFERENCED    = 2
+	KPF_UPTODATE      = 3
+	KPF_DIRTY         = 4
+	KPF_LRU           = 5
+	KPF_ACTIVE        = 6
+	KPF_SLAB          = 7
+	KPF_WRITEBACK     = 8
+	KPF_RECLAIM       = 9
+	KPF_BUDDY         = 10
+	KPF_MMAP          = 11
+	KPF_ANON          = 12
+	KPF_SWAPCACHE     = 13
+	KPF_SWAPBACKED    = 14
+	KPF_COMPOUND_HEAD = 15
+	KPF_COMPOUND_TAIL = 16
+	KPF_HUGE          = 17
+	KPF_UNEVICTABLE   = 18
+	KPF_HWPOISON      = 19
+	KPF_NOPAGE        = 20
+	KPF_KSM           = 21
+	KPF_THP           = 22
+	KPF_OFFLINE       = 23
+	KPF_ZERO_PAGE     = 24
+	KPF_IDLE          = 25
+	KPF_PGTABLE       = 26
+)
+
 // TaskSize is the address space size.
 var TaskSize = func() uintptr {
 	pageSize := uintptr(unix.Getpagesize())
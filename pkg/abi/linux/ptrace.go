@@ -14,6 +14,8 @@
 
 package linux
 
+import "gvisor.dev/gvisor/pkg/hostarch"
+
 // ptrace commands from include/uapi/linux/ptrace.h.
 const (
 	PTRACE_TRACEME              = 0
@@ -43,6 +45,7 @@ const (
 	PTRACE_SETSIGMASK           = 0x420b
 	PTRACE_SECCOMP_GET_FILTER   = 0x420c
 	PTRACE_SECCOMP_GET_METADATA = 0x420d
+	PTRACE_GET_SYSCALL_INFO     = 0x420e
 )
 
 // ptrace commands from arch/x86/include/uapi/asm/ptrace-abi.h.
@@ -88,6 +91,103 @@ const (
 	PTRACE_O_SUSPEND_SECCOMP = 1 << 21
 )
 
+// PTRACE_PEEKSIGINFO flags from include/uapi/linux/ptrace.h.
+const (
+	PTRACE_PEEKSIGINFO_SHARED = 1 << 0
+)
+
+// PtracePeekSiginfoArgs is equivalent to struct ptrace_peeksiginfo_args.
+//
+// +marshal
+type PtracePeekSiginfoArgs struct {
+	Off   uint64
+	Flags uint32
+	Nr    int32
+}
+
+// PTRACE_GETEVENTMSG values for syscall stops from
+// include/uapi/linux/ptrace.h.
+const (
+	PTRACE_EVENTMSG_SYSCALL_ENTRY = 1
+	PTRACE_EVENTMSG_SYSCALL_EXIT  = 2
+)
+
+// PtraceSyscallInfo.Op values from include/uapi/linux/ptrace.h.
+const (
+	PTRACE_SYSCALL_INFO_NONE    = 0
+	PTRACE_SYSCALL_INFO_ENTRY   = 1
+	PTRACE_SYSCALL_INFO_EXIT    = 2
+	PTRACE_SYSCALL_INFO_SECCOMP = 3
+)
+
+// Sizes of struct ptrace_syscall_info for each PtraceSyscallInfo.Op, as
+// returned by PTRACE_GET_SYSCALL_INFO. These are the offsets of the end of the
+// last field used by each operation.
+const (
+	PtraceSyscallInfoNoneSize    = 24
+	PtraceSyscallInfoEntrySize   = 80
+	PtraceSyscallInfoExitSize    = 33
+	PtraceSyscallInfoSeccompSize = 84
+)
+
+// PtraceSyscallInfo is equivalent to struct ptrace_syscall_info.
+//
+// +marshal
+type PtraceSyscallInfo struct {
+	Op                 uint8
+	_                  [3]uint8
+	Arch               uint32
+	InstructionPointer uint64
+	StackPointer       uint64
+
+	// struct ptrace_syscall_info contains a union of the entry, exit and
+	// seccomp structs. In PtraceSyscallInfo, fields in the union are accessed
+	// through methods.
+	//
+	// For reference, here is the definition of the union:
+	//
+	// union {
+	//	struct {
+	//		__u64 nr;
+	//		__u64 args[6];
+	//	} entry;
+	//	struct {
+	//		__s64 rval;
+	//		__u8 is_error;
+	//	} exit;
+	//	struct {
+	//		__u64 nr;
+	//		__u64 args[6];
+	//		__u32 ret_data;
+	//	} seccomp;
+	// };
+	Fields [64]byte
+}
+
+// SetEntry sets the entry fields of the union.
+func (i *PtraceSyscallInfo) SetEntry(nr uint64, args [6]uint64) {
+	hostarch.ByteOrder.PutUint64(i.Fields[0:8], nr)
+	for j, arg := range args {
+		hostarch.ByteOrder.PutUint64(i.Fields[8+8*j:16+8*j], arg)
+	}
+}
+
+// SetExit sets the exit fields of the union.
+func (i *PtraceSyscallInfo) SetExit(rval int64, isError bool) {
+	hostarch.ByteOrder.PutUint64(i.Fields[0:8], uint64(rval))
+	if isError {
+		i.Fields[8] = 1
+	} else {
+		i.Fields[8] = 0
+	}
+}
+
+// SetSeccomp sets the seccomp fields of the union.
+func (i *PtraceSyscallInfo) SetSeccomp(nr uint64, args [6]uint64, retData uint32) {
+	i.SetEntry(nr, args)
+	hostarch.ByteOrder.PutUint32(i.Fields[56:60], retData)
+}
+
 // YAMA ptrace_scope levels from security/yama/yama_lsm.c.
 const (
 	YAMA_SCOPE_DISABLED   = 0
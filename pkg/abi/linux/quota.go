@@ -0,0 +1,133 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// Quota types, from include/uapi/linux/quota.h.
+const (
+	USRQUOTA = 0
+	GRPQUOTA = 1
+	PRJQUOTA = 2
+)
+
+// quotactl(2) command encoding, from include/uapi/linux/quota.h.
+const (
+	SUBCMDMASK  = 0x00ff
+	SUBCMDSHIFT = 8
+)
+
+// QCMD returns the quotactl(2) command for the given subcommand and quota
+// type.
+func QCMD(cmd, ty uint32) uint32 {
+	return cmd<<SUBCMDSHIFT | ty&SUBCMDMASK
+}
+
+// quotactl(2) subcommands, from include/uapi/linux/quota.h.
+const (
+	Q_SYNC         = 0x800001
+	Q_QUOTAON      = 0x800002
+	Q_QUOTAOFF     = 0x800003
+	Q_GETFMT       = 0x800004
+	Q_GETINFO      = 0x800005
+	Q_SETINFO      = 0x800006
+	Q_GETQUOTA     = 0x800007
+	Q_SETQUOTA     = 0x800008
+	Q_GETNEXTQUOTA = 0x800009
+)
+
+// Quota formats, from include/uapi/linux/quota.h.
+const (
+	QFMT_VFS_OLD = 1
+	QFMT_VFS_V0  = 2
+	QFMT_OCFS2   = 3
+	QFMT_VFS_V1  = 4
+	QFMT_SHMEM   = 5
+)
+
+// QIF_DQBLKSIZE is the unit of IfDqblk block limits, from
+// include/uapi/linux/quota.h.
+const (
+	QIF_DQBLKSIZE_BITS = 10
+	QIF_DQBLKSIZE      = 1 << QIF_DQBLKSIZE_BITS
+)
+
+// IfDqblk.Valid flags, from include/uapi/linux/quota.h.
+const (
+	QIF_BLIMITS = 1 << 0
+	QIF_SPACE   = 1 << 1
+	QIF_ILIMITS = 1 << 2
+	QIF_INODES  = 1 << 3
+	QIF_BTIME   = 1 << 4
+	QIF_ITIME   = 1 << 5
+	QIF_LIMITS  = QIF_BLIMITS | QIF_ILIMITS
+	QIF_USAGE   = QIF_SPACE | QIF_INODES
+	QIF_TIMES   = QIF_BTIME | QIF_ITIME
+	QIF_ALL     = QIF_LIMITS | QIF_USAGE | QIF_TIMES
+)
+
+// IfDqinfo.Valid flags, from include/uapi/linux/quota.h.
+const (
+	IIF_BGRACE = 1 << 0
+	IIF_IGRACE = 1 << 1
+	IIF_FLAGS  = 1 << 2
+	IIF_ALL    = IIF_BGRACE | IIF_IGRACE | IIF_FLAGS
+)
+
+// Default quota grace periods in seconds, from include/linux/quota.h.
+const (
+	MAX_IQ_TIME = 604800
+	MAX_DQ_TIME = 604800
+)
+
+// IfDqblk is struct if_dqblk, from include/uapi/linux/quota.h.
+//
+// +marshal
+type IfDqblk struct {
+	BHardLimit uint64
+	BSoftLimit uint64
+	CurSpace   uint64
+	IHardLimit uint64
+	ISoftLimit uint64
+	CurInodes  uint64
+	BTime      uint64
+	ITime      uint64
+	Valid      uint32
+	_          uint32
+}
+
+// IfNextDqblk is struct if_nextdqblk, from include/uapi/linux/quota.h.
+//
+// +marshal
+type IfNextDqblk struct {
+	BHardLimit uint64
+	BSoftLimit uint64
+	CurSpace   uint64
+	IHardLimit uint64
+	ISoftLimit uint64
+	CurInodes  uint64
+	BTime      uint64
+	ITime      uint64
+	Valid      uint32
+	ID         uint32
+}
+
+// IfDqinfo is struct if_dqinfo, from include/uapi/linux/quota.h.
+//
+// +marshal
+type IfDqinfo struct {
+	BGrace uint64
+	IGrace uint64
+	Flags  uint32
+	Valid  uint32
+}
@@ -0,0 +1,48 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// Flags for VirtioNetHdr.Flags, from uapi/linux/virtio_net.h.
+const (
+	VIRTIO_NET_HDR_F_NEEDS_CSUM = 1
+	VIRTIO_NET_HDR_F_DATA_VALID = 2
+)
+
+// Values for VirtioNetHdr.GSOType, from uapi/linux/virtio_net.h.
+const (
+	VIRTIO_NET_HDR_GSO_NONE  = 0
+	VIRTIO_NET_HDR_GSO_TCPV4 = 1
+	VIRTIO_NET_HDR_GSO_UDP   = 3
+	VIRTIO_NET_HDR_GSO_TCPV6 = 4
+	VIRTIO_NET_HDR_GSO_ECN   = 0x80
+)
+
+// VirtioNetHdr is struct virtio_net_hdr, from uapi/linux/virtio_net.h.
+//
+// TUN/TAP devices use the native byte order for the header unless
+// TUNSETVNETLE or TUNSETVNETBE is used.
+//
+// +marshal
+type VirtioNetHdr struct {
+	Flags      uint8
+	GSOType    uint8
+	HdrLen     uint16
+	GSOSize    uint16
+	CsumStart  uint16
+	CsumOffset uint16
+}
+
+// SizeOfVirtioNetHdr is the size of a VirtioNetHdr struct.
+var SizeOfVirtioNetHdr = (*VirtioNetHdr)(nil).SizeBytes()
@@ -0,0 +1,638 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erofs
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+)
+
+// BuilderBlockSizeBits is the block size in bit shift of the images built by
+// Builder.
+const BuilderBlockSizeBits = 12
+
+// Builder builds EROFS images from directory trees and OCI image layers.
+//
+// The trees are merged in the order they are added, then Build() writes the
+// merged tree as an uncompressed image. Regular files always use the flat
+// plain layout, so that they can be mapped directly by the sentry; the tail
+// of directories and symlinks are packed inline with their inodes when
+// possible.
+//
+// Builder is not thread-safe.
+type Builder struct {
+	root *buildNode
+
+	// spill stores the data of the regular files read from tar streams,
+	// which can't be read again when writing the image. spill is created on
+	// demand and already unlinked.
+	spill     *os.File
+	spillSize int64
+
+	// layer is the index of the tree being added, starting from 1.
+	layer int
+}
+
+// buildNode represents a file in the tree being built. Hard links share the
+// same buildNode.
+type buildNode struct {
+	mode      uint16
+	uid       uint32
+	gid       uint32
+	mtime     uint64
+	mtimeNsec uint32
+	xattrs    map[string]string
+
+	// children are the entries of directories.
+	children map[string]buildDirent
+
+	// target is the target of symlinks.
+	target string
+
+	// rdev is the device number of character and block devices.
+	rdev uint32
+
+	// size is the size of regular files, and open returns their data.
+	size uint64
+	open func() (io.ReadCloser, error)
+
+	// The fields below are set by Builder.Build().
+	nid       uint64
+	nlink     uint32
+	parent    *buildNode
+	dataSize  uint64
+	blockAddr uint32
+	inline    bool
+	extended  bool
+	xattrBody []byte
+	dirents   [][]buildEntry
+}
+
+// buildDirent is a directory entry of the tree being built.
+type buildDirent struct {
+	node *buildNode
+
+	// layer is the index of the tree which added this entry.
+	layer int
+}
+
+// buildEntry is a directory entry including "." and "..", which are laid out
+// in the same order as on disk.
+type buildEntry struct {
+	name string
+	node *buildNode
+}
+
+// NewBuilder returns a Builder with an empty root directory.
+func NewBuilder() *Builder {
+	return &Builder{
+		root: newBuildDir(),
+	}
+}
+
+// newBuildDir returns a directory with the default attributes.
+func newBuildDir() *buildNode {
+	return &buildNode{
+		mode:     linux.S_IFDIR | 0755,
+		children: make(map[string]buildDirent),
+	}
+}
+
+// Close releases the resources held by the builder.
+func (b *Builder) Close() error {
+	if b.spill == nil {
+		return nil
+	}
+	return b.spill.Close()
+}
+
+// isDir returns true if n is a directory.
+func (n *buildNode) isDir() bool {
+	return n.mode&linux.S_IFMT == linux.S_IFDIR
+}
+
+// setAttrs copies the attributes of src to n.
+func (n *buildNode) setAttrs(src *buildNode) {
+	n.mode = src.mode
+	n.uid = src.uid
+	n.gid = src.gid
+	n.mtime = src.mtime
+	n.mtimeNsec = src.mtimeNsec
+	n.xattrs = src.xattrs
+}
+
+// splitPath splits the relative path p into its components.
+func splitPath(p string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(p, "/") {
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			return nil, fmt.Errorf("path %q escapes the root", p)
+		}
+		if len(name) > MaxNameLen {
+			return nil, fmt.Errorf("name too long in path %q", p)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// lookupDir returns the directory identified by names. The missing
+// directories are created with the default attributes. All the directories
+// on the path are considered as added by the current tree.
+func (b *Builder) lookupDir(names []string) (*buildNode, error) {
+	d := b.root
+	for k, name := range names {
+		child, ok := d.children[name]
+		if !ok {
+			child.node = newBuildDir()
+		} else if !child.node.isDir() {
+			return nil, fmt.Errorf("%q is not a directory", strings.Join(names[:k+1], "/"))
+		}
+		child.layer = b.layer
+		d.children[name] = child
+		d = child.node
+	}
+	return d, nil
+}
+
+// lookup returns the file identified by p, or nil if it doesn't exist.
+func (b *Builder) lookup(p string) (*buildNode, error) {
+	names, err := splitPath(p)
+	if err != nil {
+		return nil, err
+	}
+	n := b.root
+	for _, name := range names {
+		if !n.isDir() {
+			return nil, nil
+		}
+		child, ok := n.children[name]
+		if !ok {
+			return nil, nil
+		}
+		n = child.node
+	}
+	return n, nil
+}
+
+// insert adds the file n at path p, replacing the existing one. If both of
+// them are directories, only the attributes of the existing one are updated.
+func (b *Builder) insert(p string, n *buildNode) error {
+	names, err := splitPath(p)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		if !n.isDir() {
+			return fmt.Errorf("root must be a directory")
+		}
+		b.root.setAttrs(n)
+		return nil
+	}
+	parent, err := b.lookupDir(names[:len(names)-1])
+	if err != nil {
+		return err
+	}
+	name := names[len(names)-1]
+	if old, ok := parent.children[name]; ok && old.node.isDir() && n.isDir() {
+		old.node.setAttrs(n)
+		parent.children[name] = buildDirent{node: old.node, layer: b.layer}
+		return nil
+	}
+	if n.isDir() && n.children == nil {
+		n.children = make(map[string]buildDirent)
+	}
+	parent.children[name] = buildDirent{node: n, layer: b.layer}
+	return nil
+}
+
+// remove removes the file at path p if it exists.
+func (b *Builder) remove(p string) error {
+	names, err := splitPath(p)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("can't remove the root")
+	}
+	parent, err := b.lookup(strings.Join(names[:len(names)-1], "/"))
+	if err != nil || parent == nil || !parent.isDir() {
+		return err
+	}
+	delete(parent.children, names[len(names)-1])
+	return nil
+}
+
+// pruneLowerLayers removes the entries added by the previous trees from the
+// directory d recursively.
+func (b *Builder) pruneLowerLayers(d *buildNode) {
+	for name, child := range d.children {
+		if child.layer < b.layer {
+			delete(d.children, name)
+		} else if child.node.isDir() {
+			b.pruneLowerLayers(child.node)
+		}
+	}
+}
+
+// fileType returns the file type (FT_*) recorded in dirents.
+func (n *buildNode) fileType() uint8 {
+	switch n.mode & linux.S_IFMT {
+	case linux.S_IFREG:
+		return linux.FT_REG_FILE
+	case linux.S_IFDIR:
+		return linux.FT_DIR
+	case linux.S_IFCHR:
+		return linux.FT_CHRDEV
+	case linux.S_IFBLK:
+		return linux.FT_BLKDEV
+	case linux.S_IFIFO:
+		return linux.FT_FIFO
+	case linux.S_IFSOCK:
+		return linux.FT_SOCK
+	case linux.S_IFLNK:
+		return linux.FT_SYMLINK
+	default:
+		return linux.FT_UNKNOWN
+	}
+}
+
+// collect returns all the files in breadth-first order, starting from the
+// root. It also sets the parents and link counts of the files.
+func (b *Builder) collect() []*buildNode {
+	b.root.parent = b.root
+	b.root.nlink = 2
+	nodes := []*buildNode{b.root}
+	seen := map[*buildNode]struct{}{b.root: {}}
+	for k := 0; k < len(nodes); k++ {
+		d := nodes[k]
+		if !d.isDir() {
+			continue
+		}
+		names := make([]string, 0, len(d.children))
+		for name := range d.children {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			n := d.children[name].node
+			if _, ok := seen[n]; ok {
+				// Hard link to a file we have already seen.
+				n.nlink++
+				continue
+			}
+			seen[n] = struct{}{}
+			nodes = append(nodes, n)
+			if n.isDir() {
+				n.parent = d
+				n.nlink = 2
+				d.nlink++
+			} else {
+				n.nlink = 1
+			}
+		}
+	}
+	return nodes
+}
+
+// prepare computes the layout of n except for the locations.
+func (n *buildNode) prepare(buildTime uint64, buildTimeNsec uint32) error {
+	const blockSize = 1 << BuilderBlockSizeBits
+
+	xattrBody, err := encodeXattrs(n.xattrs)
+	if err != nil {
+		return err
+	}
+	n.xattrBody = xattrBody
+
+	switch n.mode & linux.S_IFMT {
+	case linux.S_IFREG:
+		n.dataSize = n.size
+	case linux.S_IFLNK:
+		n.dataSize = uint64(len(n.target))
+	case linux.S_IFDIR:
+		n.dirents = n.layoutDirents(blockSize)
+		last := n.dirents[len(n.dirents)-1]
+		n.dataSize = uint64(len(n.dirents)-1)*blockSize + direntsSize(last)
+	}
+
+	n.extended = n.uid > 0xffff || n.gid > 0xffff || n.nlink > 0xffff ||
+		n.dataSize > 0xffffffff || n.mtime != buildTime || n.mtimeNsec != buildTimeNsec
+
+	// Regular files are never inlined, as the inline data can't be mapped.
+	tail := n.dataSize % blockSize
+	n.inline = !n.isRegular() && tail != 0 && n.metaSize()+tail <= blockSize
+	return nil
+}
+
+// isRegular returns true if n is a regular file.
+func (n *buildNode) isRegular() bool {
+	return n.mode&linux.S_IFMT == linux.S_IFREG
+}
+
+// inodeSize returns the size of the on-disk inode of n.
+func (n *buildNode) inodeSize() uint64 {
+	if n.extended {
+		return InodeExtendedSize
+	}
+	return InodeCompactSize
+}
+
+// metaSize returns the size of the on-disk inode and the inline xattrs of n.
+func (n *buildNode) metaSize() uint64 {
+	return n.inodeSize() + uint64(len(n.xattrBody))
+}
+
+// layoutDirents returns the entries of directory n split into blocks.
+func (n *buildNode) layoutDirents(blockSize uint64) [][]buildEntry {
+	entries := []buildEntry{{".", n}, {"..", n.parent}}
+	for name, child := range n.children {
+		entries = append(entries, buildEntry{name, child.node})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+
+	var (
+		blocks [][]buildEntry
+		block  []buildEntry
+		used   uint64
+	)
+	for _, e := range entries {
+		size := DirentSize + uint64(len(e.name))
+		if used+size > blockSize {
+			blocks = append(blocks, block)
+			block, used = nil, 0
+		}
+		block = append(block, e)
+		used += size
+	}
+	return append(blocks, block)
+}
+
+// direntsSize returns the size of the dirents and names in a block.
+func direntsSize(block []buildEntry) uint64 {
+	size := uint64(len(block)) * DirentSize
+	for _, e := range block {
+		size += uint64(len(e.name))
+	}
+	return size
+}
+
+// encodeDirents returns the data of directory n. The last block is not
+// padded.
+func (n *buildNode) encodeDirents(blockSize uint64) []byte {
+	data := make([]byte, n.dataSize)
+	for k, block := range n.dirents {
+		b := data[uint64(k)*blockSize:]
+		nameOff := uint64(len(block)) * DirentSize
+		for j, e := range block {
+			d := Dirent{
+				NidLow:   uint32(e.node.nid),
+				NidHigh:  uint32(e.node.nid >> 32),
+				NameOff:  uint16(nameOff),
+				FileType: e.node.fileType(),
+			}
+			d.MarshalUnsafe(b[uint64(j)*DirentSize:])
+			nameOff += uint64(copy(b[nameOff:], e.name))
+		}
+	}
+	return data
+}
+
+// encodeXattrs returns the inline xattrs body of the given xattrs, or nil if
+// there are no xattrs.
+func encodeXattrs(xattrs map[string]string) ([]byte, error) {
+	if len(xattrs) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(xattrs))
+	for name := range xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	body := make([]byte, XattrIbodyHeaderSize)
+	for _, name := range names {
+		value := xattrs[name]
+		idx, suffix := XattrNameIndex(name)
+		if len(suffix) > 0xff || len(value) > 0xffff {
+			return nil, fmt.Errorf("xattr %q too large", name)
+		}
+		e := XattrEntry{
+			NameLen:   uint8(len(suffix)),
+			NameIndex: idx,
+			ValueSize: uint16(len(value)),
+		}
+		off := len(body)
+		body = append(body, make([]byte, XattrEntrySize)...)
+		e.MarshalUnsafe(body[off:])
+		body = append(body, suffix...)
+		body = append(body, value...)
+		body = append(body, make([]byte, roundUp(uint64(len(body)), XattrEntrySize)-uint64(len(body)))...)
+	}
+	if uint64(len(body)) > xattrIbodySize(0xffff) {
+		return nil, fmt.Errorf("xattrs too large")
+	}
+	return body, nil
+}
+
+// Build writes the image of the merged tree to w.
+func (b *Builder) Build(w io.WriterAt) error {
+	const blockSize = 1 << BuilderBlockSizeBits
+
+	nodes := b.collect()
+	// Inodes sharing the build time can use the compact layout.
+	buildTime, buildTimeNsec := b.root.mtime, b.root.mtimeNsec
+	for _, n := range nodes {
+		if err := n.prepare(buildTime, buildTimeNsec); err != nil {
+			return err
+		}
+	}
+
+	// The metadata area starts from block 0, right after the superblock.
+	// Inodes are 32-byte aligned, and never cross block boundaries so that
+	// their inline data stays in the same block.
+	pos := uint64(SuperBlockOffset + SuperBlockSize)
+	for _, n := range nodes {
+		pos = roundUp(pos, 1<<InodeSlotBits)
+		size := n.inodeSize()
+		if n.inline {
+			size = n.metaSize() + n.dataSize%blockSize
+		}
+		if pos%blockSize+size > blockSize {
+			pos = roundUp(pos, blockSize)
+		}
+		n.nid = pos >> InodeSlotBits
+		pos += n.metaSize()
+		if n.inline {
+			pos += n.dataSize % blockSize
+		}
+	}
+	if b.root.nid > 0xffff {
+		return fmt.Errorf("root nid %d too large", b.root.nid)
+	}
+	metaBlocks := roundUp(pos, blockSize) / blockSize
+
+	// The data area follows the metadata area.
+	blocks := metaBlocks
+	for _, n := range nodes {
+		nblocks := n.dataSize / blockSize
+		if !n.inline && n.dataSize%blockSize != 0 {
+			nblocks++
+		}
+		if nblocks != 0 {
+			n.blockAddr = uint32(blocks)
+			blocks += nblocks
+		}
+	}
+	if blocks > 0xffffffff {
+		return fmt.Errorf("image too large: %d blocks", blocks)
+	}
+
+	meta := make([]byte, metaBlocks*blockSize)
+	for ino, n := range nodes {
+		off := n.nid << InodeSlotBits
+		n.marshalInode(meta[off:], uint32(ino+1))
+		off += n.inodeSize()
+		off += uint64(copy(meta[off:], n.xattrBody))
+
+		var data []byte
+		switch n.mode & linux.S_IFMT {
+		case linux.S_IFREG:
+			if err := n.writeFileData(w, blockSize); err != nil {
+				return err
+			}
+			continue
+		case linux.S_IFLNK:
+			data = []byte(n.target)
+		case linux.S_IFDIR:
+			data = n.encodeDirents(blockSize)
+		default:
+			continue
+		}
+		if n.inline {
+			tailOff := n.dataSize &^ (blockSize - 1)
+			copy(meta[off:], data[tailOff:])
+			data = data[:tailOff]
+		} else {
+			data = append(data, make([]byte, roundUp(n.dataSize, blockSize)-n.dataSize)...)
+		}
+		if len(data) != 0 {
+			if _, err := w.WriteAt(data, int64(n.blockAddr)*blockSize); err != nil {
+				return err
+			}
+		}
+	}
+
+	sb := SuperBlock{
+		Magic:         SuperBlockMagicV1,
+		FeatureCompat: FeatureCompatSuperBlockChecksum,
+		BlockSizeBits: BuilderBlockSizeBits,
+		RootNid:       uint16(b.root.nid),
+		Inodes:        uint64(len(nodes)),
+		BuildTime:     buildTime,
+		BuildTimeNsec: buildTimeNsec,
+		Blocks:        uint32(blocks),
+	}
+	sb.MarshalUnsafe(meta[SuperBlockOffset:])
+	table := crc32.MakeTable(crc32.Castagnoli)
+	checksum := crc32.Checksum(meta[SuperBlockOffset:SuperBlockOffset+SuperBlockSize], table)
+	sb.Checksum = ^crc32.Update(checksum, table, meta[SuperBlockOffset+SuperBlockSize:blockSize])
+	sb.MarshalUnsafe(meta[SuperBlockOffset:])
+
+	_, err := w.WriteAt(meta, 0)
+	return err
+}
+
+// marshalInode serializes the on-disk inode of n into dst.
+func (n *buildNode) marshalInode(dst []byte, ino uint32) {
+	dataLayout := uint16(InodeDataLayoutFlatPlain)
+	if n.inline {
+		dataLayout = InodeDataLayoutFlatInline
+	}
+	rawBlockAddr := n.blockAddr
+	if t := n.mode & linux.S_IFMT; t == linux.S_IFCHR || t == linux.S_IFBLK {
+		rawBlockAddr = n.rdev
+	}
+	var xattrCount uint16
+	if len(n.xattrBody) != 0 {
+		xattrCount = XattrIbodyCount(uint64(len(n.xattrBody)))
+	}
+
+	if n.extended {
+		inode := InodeExtended{
+			Format:       InodeLayoutExtended | dataLayout<<InodeDataLayoutBit,
+			XattrCount:   xattrCount,
+			Mode:         n.mode,
+			Size:         n.dataSize,
+			RawBlockAddr: rawBlockAddr,
+			Ino:          ino,
+			UID:          n.uid,
+			GID:          n.gid,
+			Mtime:        n.mtime,
+			MtimeNsec:    n.mtimeNsec,
+			Nlink:        n.nlink,
+		}
+		inode.MarshalUnsafe(dst)
+		return
+	}
+	inode := InodeCompact{
+		Format:       InodeLayoutCompact | dataLayout<<InodeDataLayoutBit,
+		XattrCount:   xattrCount,
+		Mode:         n.mode,
+		Nlink:        uint16(n.nlink),
+		Size:         uint32(n.dataSize),
+		RawBlockAddr: rawBlockAddr,
+		Ino:          ino,
+		UID:          uint16(n.uid),
+		GID:          uint16(n.gid),
+	}
+	inode.MarshalUnsafe(dst)
+}
+
+// writeFileData writes the data of regular file n to w, padding the last
+// block with zeros.
+func (n *buildNode) writeFileData(w io.WriterAt, blockSize uint64) error {
+	if n.size == 0 {
+		return nil
+	}
+	r, err := n.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	off := int64(n.blockAddr) * int64(blockSize)
+	copied, err := io.Copy(io.NewOffsetWriter(w, off), io.LimitReader(r, int64(n.size)))
+	if err != nil {
+		return err
+	}
+	if uint64(copied) != n.size {
+		return fmt.Errorf("file data truncated: got %d bytes, want %d", copied, n.size)
+	}
+	if pad := roundUp(n.size, blockSize) - n.size; pad != 0 {
+		if _, err := w.WriteAt(make([]byte, pad), off+int64(n.size)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
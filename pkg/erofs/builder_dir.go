@@ -0,0 +1,129 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erofs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+)
+
+// AddDir merges the host directory tree rooted at dir into the tree. Files
+// are read when the image is built, so they must not be changed until then.
+func (b *Builder) AddDir(dir string) error {
+	b.layer++
+	// Hard links are detected by the device and inode numbers.
+	links := make(map[[2]uint64]*buildNode)
+	return filepath.WalkDir(dir, func(p string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		n, err := b.hostNode(p, links)
+		if err != nil {
+			return fmt.Errorf("failed to add %q: %w", p, err)
+		}
+		if n == nil {
+			return nil
+		}
+		return b.insert(rel, n)
+	})
+}
+
+// hostNode returns the file at host path p. It returns nil if p is of an
+// unsupported type.
+func (b *Builder) hostNode(p string, links map[[2]uint64]*buildNode) (*buildNode, error) {
+	var st unix.Stat_t
+	if err := unix.Lstat(p, &st); err != nil {
+		return nil, err
+	}
+	key := [2]uint64{st.Dev, st.Ino}
+	if n, ok := links[key]; ok {
+		return n, nil
+	}
+
+	xattrs, err := hostXattrs(p)
+	if err != nil {
+		return nil, err
+	}
+	n := &buildNode{
+		mode:      uint16(st.Mode),
+		uid:       st.Uid,
+		gid:       st.Gid,
+		mtime:     uint64(max(st.Mtim.Sec, 0)),
+		mtimeNsec: uint32(st.Mtim.Nsec),
+		xattrs:    xattrs,
+	}
+	switch st.Mode & unix.S_IFMT {
+	case unix.S_IFREG:
+		n.size = uint64(st.Size)
+		n.open = func() (io.ReadCloser, error) {
+			return os.Open(p)
+		}
+		if st.Nlink > 1 {
+			links[key] = n
+		}
+	case unix.S_IFLNK:
+		if n.target, err = os.Readlink(p); err != nil {
+			return nil, err
+		}
+	case unix.S_IFCHR, unix.S_IFBLK:
+		n.rdev = linux.MakeDeviceID(uint16(unix.Major(st.Rdev)), unix.Minor(st.Rdev))
+	case unix.S_IFDIR, unix.S_IFIFO, unix.S_IFSOCK:
+	default:
+		return nil, nil
+	}
+	return n, nil
+}
+
+// hostXattrs returns the xattrs of the host file at p.
+func hostXattrs(p string) (map[string]string, error) {
+	size, err := unix.Llistxattr(p, nil)
+	if err == unix.ENOTSUP || size == 0 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if size, err = unix.Llistxattr(p, buf); err != nil {
+		return nil, err
+	}
+	xattrs := make(map[string]string)
+	for _, name := range bytes.Split(bytes.TrimSuffix(buf[:size], []byte{0}), []byte{0}) {
+		size, err := unix.Lgetxattr(p, string(name), nil)
+		if err == unix.ENODATA {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, size)
+		if size, err = unix.Lgetxattr(p, string(name), value); err != nil {
+			return nil, err
+		}
+		xattrs[string(name)] = string(value[:size])
+	}
+	return xattrs, nil
+}
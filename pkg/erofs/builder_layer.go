@@ -0,0 +1,165 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erofs
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/log"
+)
+
+// Whiteout files defined by the OCI image spec.
+//
+// See https://github.com/opencontainers/image-spec/blob/main/layer.md#whiteouts.
+const (
+	whiteoutPrefix    = ".wh."
+	whiteoutOpaqueDir = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+// paxXattrPrefix is the prefix of PAX records holding xattrs.
+const paxXattrPrefix = "SCHILY.xattr."
+
+// AddLayer merges the OCI image layer read from the uncompressed tar stream
+// r into the tree. Whiteouts remove the files added by the previous layers.
+func (b *Builder) AddLayer(r io.Reader) error {
+	b.layer++
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := b.addTarEntry(tr, hdr); err != nil {
+			return fmt.Errorf("failed to add %q: %w", hdr.Name, err)
+		}
+	}
+}
+
+// addTarEntry adds the file described by hdr, whose data is read from r.
+func (b *Builder) addTarEntry(r io.Reader, hdr *tar.Header) error {
+	p := path.Clean("/" + hdr.Name)
+	dir, base := path.Split(p)
+	if base == whiteoutOpaqueDir {
+		names, err := splitPath(dir)
+		if err != nil {
+			return err
+		}
+		d, err := b.lookupDir(names)
+		if err != nil {
+			return err
+		}
+		b.pruneLowerLayers(d)
+		return nil
+	}
+	if strings.HasPrefix(base, whiteoutPrefix) {
+		return b.remove(dir + base[len(whiteoutPrefix):])
+	}
+
+	n := &buildNode{
+		mode: uint16(hdr.Mode & 07777),
+		uid:  uint32(hdr.Uid),
+		gid:  uint32(hdr.Gid),
+	}
+	if mtime := hdr.ModTime; mtime.Unix() > 0 {
+		n.mtime = uint64(mtime.Unix())
+		n.mtimeNsec = uint32(mtime.Nanosecond())
+	}
+	for key, value := range hdr.PAXRecords {
+		if name, ok := strings.CutPrefix(key, paxXattrPrefix); ok {
+			if n.xattrs == nil {
+				n.xattrs = make(map[string]string)
+			}
+			n.xattrs[name] = value
+		}
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeReg:
+		n.mode |= linux.S_IFREG
+		n.size = uint64(hdr.Size)
+		open, err := b.spillData(r, hdr.Size)
+		if err != nil {
+			return err
+		}
+		n.open = open
+	case tar.TypeLink:
+		target, err := b.lookup(hdr.Linkname)
+		if err != nil {
+			return err
+		}
+		if target == nil || target.isDir() {
+			return fmt.Errorf("invalid hard link target %q", hdr.Linkname)
+		}
+		n = target
+	case tar.TypeSymlink:
+		n.mode |= linux.S_IFLNK
+		n.target = hdr.Linkname
+	case tar.TypeChar:
+		n.mode |= linux.S_IFCHR
+		n.rdev = linux.MakeDeviceID(uint16(hdr.Devmajor), uint32(hdr.Devminor))
+	case tar.TypeBlock:
+		n.mode |= linux.S_IFBLK
+		n.rdev = linux.MakeDeviceID(uint16(hdr.Devmajor), uint32(hdr.Devminor))
+	case tar.TypeDir:
+		n.mode |= linux.S_IFDIR
+	case tar.TypeFifo:
+		n.mode |= linux.S_IFIFO
+	default:
+		log.Warningf("Skipping tar entry %q of unsupported type %q", hdr.Name, hdr.Typeflag)
+		return nil
+	}
+	return b.insert(p, n)
+}
+
+// spillData copies size bytes of regular file data from r to the spill file,
+// and returns a function to read it back.
+func (b *Builder) spillData(r io.Reader, size int64) (func() (io.ReadCloser, error), error) {
+	if size == 0 {
+		return nil, nil
+	}
+	if b.spill == nil {
+		f, err := os.CreateTemp("", "erofs-builder-")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Remove(f.Name()); err != nil {
+			f.Close()
+			return nil, err
+		}
+		b.spill = f
+	}
+	off := b.spillSize
+	n, err := io.Copy(io.NewOffsetWriter(b.spill, off), io.LimitReader(r, size))
+	if err != nil {
+		return nil, err
+	}
+	if n != size {
+		return nil, fmt.Errorf("file data truncated: got %d bytes, want %d", n, size)
+	}
+	b.spillSize += size
+	spill := b.spill
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(spill, off, size)), nil
+	}, nil
+}
@@ -0,0 +1,408 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erofs
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+)
+
+// buildImage builds an image with the given builder and opens it.
+func buildImage(t *testing.T, b *Builder) *Image {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "image"))
+	if err != nil {
+		t.Fatalf("os.Create failed: %v", err)
+	}
+	if err := b.Build(f); err != nil {
+		f.Close()
+		t.Fatalf("Build failed: %v", err)
+	}
+	image, err := OpenImage(f)
+	if err != nil {
+		f.Close()
+		t.Fatalf("OpenImage failed: %v", err)
+	}
+	t.Cleanup(image.Close)
+	return image
+}
+
+// lookupPath returns the inode at path p of the image.
+func lookupPath(image *Image, p string) (Inode, error) {
+	nid := image.RootNid()
+	for _, name := range strings.Split(p, "/") {
+		if name == "" {
+			continue
+		}
+		dir, err := image.Inode(nid)
+		if err != nil {
+			return Inode{}, err
+		}
+		if nid, err = dir.Lookup(name); err != nil {
+			return Inode{}, err
+		}
+	}
+	return image.Inode(nid)
+}
+
+// mustLookupPath is like lookupPath, but fails the test on errors.
+func mustLookupPath(t *testing.T, image *Image, p string) Inode {
+	t.Helper()
+	inode, err := lookupPath(image, p)
+	if err != nil {
+		t.Fatalf("failed to look up %q: %v", p, err)
+	}
+	return inode
+}
+
+// fileData returns the data of the regular file at path p of the image.
+func fileData(t *testing.T, image *Image, p string) []byte {
+	t.Helper()
+	inode := mustLookupPath(t, image, p)
+	if !inode.IsRegular() {
+		t.Fatalf("%q is not a regular file", p)
+	}
+	if inode.Size() == 0 {
+		return nil
+	}
+	off, err := inode.DataOffset()
+	if err != nil {
+		t.Fatalf("DataOffset of %q failed: %v", p, err)
+	}
+	data, err := image.BytesAt(off, inode.Size())
+	if err != nil {
+		t.Fatalf("BytesAt of %q failed: %v", p, err)
+	}
+	return data
+}
+
+// dirNames returns the names of the entries of the directory at path p.
+func dirNames(t *testing.T, image *Image, p string) []string {
+	t.Helper()
+	inode := mustLookupPath(t, image, p)
+	var names []string
+	if err := inode.IterDirents(func(name string, typ uint8, nid uint64) error {
+		names = append(names, name)
+		return nil
+	}); err != nil {
+		t.Fatalf("IterDirents of %q failed: %v", p, err)
+	}
+	return names
+}
+
+// tarLayer returns a tar stream with the given headers. Regular files get
+// their data from contents.
+func tarLayer(t *testing.T, hdrs []*tar.Header, contents map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range hdrs {
+		data := contents[hdr.Name]
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(data))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader(%q) failed: %v", hdr.Name, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatalf("Write(%q) failed: %v", hdr.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar.Writer.Close failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestBuilderLayers(t *testing.T) {
+	mtime := time.Unix(1700000000, 0)
+	bigFile := bytes.Repeat([]byte("0123456789abcdef"), 1000)
+	layer1 := tarLayer(t, []*tar.Header{
+		{Name: "./", Typeflag: tar.TypeDir, Mode: 0755, ModTime: mtime},
+		{Name: "bin/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: mtime},
+		{Name: "bin/sh", Typeflag: tar.TypeReg, Mode: 0755, ModTime: mtime},
+		{Name: "bin/bash", Typeflag: tar.TypeLink, Linkname: "bin/sh"},
+		{Name: "dev/null", Typeflag: tar.TypeChar, Mode: 0666, Devmajor: 1, Devminor: 3, ModTime: mtime},
+		{Name: "dev/fifo", Typeflag: tar.TypeFifo, Mode: 0600, ModTime: mtime},
+		{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: mtime},
+		{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0644, ModTime: mtime},
+		{
+			Name:     "etc/ping",
+			Typeflag: tar.TypeReg,
+			Mode:     0755,
+			Uid:      100000,
+			ModTime:  mtime.Add(time.Second),
+			PAXRecords: map[string]string{
+				"SCHILY.xattr.security.capability": "\x01\x00\x00\x02\x00\x20\x00\x00",
+				"SCHILY.xattr.user.comment":        "ping",
+			},
+		},
+		{Name: "lib", Typeflag: tar.TypeSymlink, Linkname: "usr/lib", ModTime: mtime},
+		{Name: "opt/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: mtime},
+		{Name: "opt/a", Typeflag: tar.TypeReg, Mode: 0644, ModTime: mtime},
+		{Name: "opt/sub/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: mtime},
+		{Name: "opt/sub/b", Typeflag: tar.TypeReg, Mode: 0644, ModTime: mtime},
+	}, map[string][]byte{
+		"bin/sh":     bigFile,
+		"etc/passwd": []byte("root:x:0:0:root:/root:/bin/sh\n"),
+		"etc/ping":   []byte("ping"),
+		"opt/a":      []byte("a"),
+		"opt/sub/b":  []byte("b"),
+	})
+	layer2 := tarLayer(t, []*tar.Header{
+		{Name: "etc/.wh.passwd", Typeflag: tar.TypeReg, ModTime: mtime},
+		{Name: "etc/hosts", Typeflag: tar.TypeReg, Mode: 0644, ModTime: mtime},
+		{Name: "opt/sub/c", Typeflag: tar.TypeReg, Mode: 0644, ModTime: mtime},
+		{Name: "opt/.wh..wh..opq", Typeflag: tar.TypeReg, ModTime: mtime},
+		{Name: "opt/d", Typeflag: tar.TypeReg, Mode: 0644, ModTime: mtime},
+	}, map[string][]byte{
+		"etc/hosts": []byte("127.0.0.1 localhost\n"),
+		"opt/sub/c": []byte("c"),
+		"opt/d":     []byte("d"),
+	})
+
+	b := NewBuilder()
+	defer b.Close()
+	for k, layer := range [][]byte{layer1, layer2} {
+		if err := b.AddLayer(bytes.NewReader(layer)); err != nil {
+			t.Fatalf("AddLayer(%d) failed: %v", k, err)
+		}
+	}
+	image := buildImage(t, b)
+
+	if got := fileData(t, image, "bin/sh"); !bytes.Equal(got, bigFile) {
+		t.Errorf("got unexpected data of bin/sh")
+	}
+	sh := mustLookupPath(t, image, "bin/sh")
+	bash := mustLookupPath(t, image, "bin/bash")
+	if sh.Nid() != bash.Nid() || sh.Nlink() != 2 {
+		t.Errorf("bin/bash (nid=%d) is not a hard link to bin/sh (nid=%d, nlink=%d)", bash.Nid(), sh.Nid(), sh.Nlink())
+	}
+	if sh.Mode() != linux.S_IFREG|0755 || sh.Mtime() != uint64(mtime.Unix()) {
+		t.Errorf("got unexpected attributes of bin/sh: mode %#o, mtime %d", sh.Mode(), sh.Mtime())
+	}
+
+	null := mustLookupPath(t, image, "dev/null")
+	if major, minor := linux.DecodeDeviceID(null.Rdev()); !null.IsCharDev() || major != 1 || minor != 3 {
+		t.Errorf("got unexpected dev/null: mode %#o, device %d:%d", null.Mode(), major, minor)
+	}
+	if fifo := mustLookupPath(t, image, "dev/fifo"); !fifo.IsFIFO() {
+		t.Errorf("got unexpected mode of dev/fifo: %#o", fifo.Mode())
+	}
+
+	if _, err := lookupPath(image, "etc/passwd"); err == nil {
+		t.Errorf("whited-out etc/passwd still exists")
+	}
+	if got, want := string(fileData(t, image, "etc/hosts")), "127.0.0.1 localhost\n"; got != want {
+		t.Errorf("got etc/hosts %q, want %q", got, want)
+	}
+
+	ping := mustLookupPath(t, image, "etc/ping")
+	if ping.UID() != 100000 || ping.Mtime() != uint64(mtime.Unix())+1 {
+		t.Errorf("got unexpected attributes of etc/ping: uid %d, mtime %d", ping.UID(), ping.Mtime())
+	}
+	xattrs := make(map[string]string)
+	if err := ping.IterXattrs(func(name string, value []byte) error {
+		xattrs[name] = string(value)
+		return nil
+	}); err != nil {
+		t.Fatalf("IterXattrs failed: %v", err)
+	}
+	if len(xattrs) != 2 || xattrs["security.capability"] != "\x01\x00\x00\x02\x00\x20\x00\x00" || xattrs["user.comment"] != "ping" {
+		t.Errorf("got unexpected xattrs of etc/ping: %q", xattrs)
+	}
+	if got := string(fileData(t, image, "etc/ping")); got != "ping" {
+		t.Errorf("got etc/ping %q, want %q", got, "ping")
+	}
+
+	lib := mustLookupPath(t, image, "lib")
+	if target, err := lib.Readlink(); err != nil || target != "usr/lib" {
+		t.Errorf("Readlink of lib got (%q, %v), want usr/lib", target, err)
+	}
+
+	// The opaque whiteout hides the files of the lower layer, including the
+	// ones in the subdirectories.
+	if got, want := fmt.Sprint(dirNames(t, image, "opt")), "[. .. d sub]"; got != want {
+		t.Errorf("got entries of opt %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(dirNames(t, image, "opt/sub")), "[. .. c]"; got != want {
+		t.Errorf("got entries of opt/sub %s, want %s", got, want)
+	}
+
+	root := mustLookupPath(t, image, "")
+	if root.Nlink() != 6 {
+		t.Errorf("got root nlink %d, want 6", root.Nlink())
+	}
+	if got, want := fmt.Sprint(dirNames(t, image, "")), "[. .. bin dev etc lib opt]"; got != want {
+		t.Errorf("got entries of root %s, want %s", got, want)
+	}
+}
+
+func TestBuilderLargeDir(t *testing.T) {
+	b := NewBuilder()
+	defer b.Close()
+	var names []string
+	for k := 0; k < 1000; k++ {
+		name := fmt.Sprintf("file-%04d-%s", k, strings.Repeat("x", k%50))
+		names = append(names, name)
+		if err := b.insert("dir/"+name, &buildNode{mode: linux.S_IFREG | 0644}); err != nil {
+			t.Fatalf("insert failed: %v", err)
+		}
+	}
+	image := buildImage(t, b)
+
+	dir := mustLookupPath(t, image, "dir")
+	if dir.Size() <= uint64(image.BlockSize()) {
+		t.Fatalf("directory size %d fits in a block", dir.Size())
+	}
+	for _, name := range names {
+		if _, err := dir.Lookup(name); err != nil {
+			t.Errorf("Lookup(%q) failed: %v", name, err)
+		}
+	}
+	got := dirNames(t, image, "dir")
+	if len(got) != len(names)+2 {
+		t.Fatalf("got %d entries, want %d", len(got), len(names)+2)
+	}
+	for k := 1; k < len(got); k++ {
+		if got[k-1] >= got[k] {
+			t.Errorf("entries not sorted: %q >= %q", got[k-1], got[k])
+		}
+	}
+}
+
+func TestBuilderDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "a/b"), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a/b/file"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := os.Link(filepath.Join(dir, "a/b/file"), filepath.Join(dir, "a/link")); err != nil {
+		t.Fatalf("Link failed: %v", err)
+	}
+	if err := os.Symlink("b/file", filepath.Join(dir, "a/symlink")); err != nil {
+		t.Fatalf("Symlink failed: %v", err)
+	}
+
+	b := NewBuilder()
+	defer b.Close()
+	if err := b.AddDir(dir); err != nil {
+		t.Fatalf("AddDir failed: %v", err)
+	}
+	image := buildImage(t, b)
+
+	if got := string(fileData(t, image, "a/link")); got != "hello" {
+		t.Errorf("got a/link %q, want %q", got, "hello")
+	}
+	file := mustLookupPath(t, image, "a/b/file")
+	link := mustLookupPath(t, image, "a/link")
+	if file.Nid() != link.Nid() || file.Nlink() != 2 {
+		t.Errorf("a/link (nid=%d) is not a hard link to a/b/file (nid=%d, nlink=%d)", link.Nid(), file.Nid(), file.Nlink())
+	}
+	symlink := mustLookupPath(t, image, "a/symlink")
+	if target, err := symlink.Readlink(); err != nil || target != "b/file" {
+		t.Errorf("Readlink of a/symlink got (%q, %v), want b/file", target, err)
+	}
+}
+
+// countingReader counts the bytes read from an image.
+type countingReader struct {
+	r io.ReaderAt
+	n int
+}
+
+// ReadAt implements io.ReaderAt.ReadAt.
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += n
+	return n, err
+}
+
+func TestOpenImageReader(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	// Make the image span several chunks, most of which are never read.
+	if err := os.WriteFile(filepath.Join(dir, "big"), bytes.Repeat([]byte{'x'}, 4*lazyChunkSize), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	b := NewBuilder()
+	defer b.Close()
+	if err := b.AddDir(dir); err != nil {
+		t.Fatalf("AddDir failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "image")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("os.Create failed: %v", err)
+	}
+	err = b.Build(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	r := &countingReader{r: bytes.NewReader(data)}
+	image, err := OpenImageReader(make([]byte, len(data)), r)
+	if err != nil {
+		t.Fatalf("OpenImageReader failed: %v", err)
+	}
+	defer image.Close()
+	if !image.InMemory() {
+		t.Errorf("InMemory got false, want true")
+	}
+	if fd := image.FD(); fd != -1 {
+		t.Errorf("FD got %d, want -1", fd)
+	}
+	if got := string(fileData(t, image, "file")); got != "hello" {
+		t.Errorf("got file %q, want %q", got, "hello")
+	}
+	if r.n >= len(data) {
+		t.Errorf("read %d bytes of a %d-byte image, want fewer", r.n, len(data))
+	}
+	if got := fileData(t, image, "big"); !bytes.Equal(got, bytes.Repeat([]byte{'x'}, 4*lazyChunkSize)) {
+		t.Errorf("got wrong contents of big")
+	}
+
+	if _, err := OpenImageReader(make([]byte, 4096), bytes.NewReader(make([]byte, 4096))); err == nil {
+		t.Errorf("OpenImageReader of zeroes succeeded, want error")
+	}
+	// Reads past the end of the reader fail.
+	truncated, err := OpenImageReader(make([]byte, len(data)), bytes.NewReader(data[:lazyChunkSize]))
+	if err != nil {
+		t.Fatalf("OpenImageReader of truncated image failed: %v", err)
+	}
+	defer truncated.Close()
+	if _, err := truncated.BytesAt(uint64(len(data))-1, 1); err != linuxerr.EIO {
+		t.Errorf("BytesAt end of truncated image got error %v, want %v", err, linuxerr.EIO)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erofs
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+)
+
+// decompress decodes the pcluster src of the extent m into dst. dst may be
+// shorter than m.LogicalLen, in which case only the leading part of the
+// extent is decoded.
+func (i *Image) decompress(m *Map, src, dst []byte) error {
+	switch m.Algorithm {
+	case CompressionShifted:
+		if len(src) < len(dst) {
+			return fmt.Errorf("plain pcluster too short: %d < %d", len(src), len(dst))
+		}
+		copy(dst, src)
+		return nil
+
+	case CompressionInterlaced:
+		// The data of interlaced pclusters is rotated by the offset of the
+		// extent within a block, i.e. the head of the extent is stored at
+		// the end of the pcluster.
+		if len(src) < len(dst) {
+			return fmt.Errorf("plain pcluster too short: %d < %d", len(src), len(dst))
+		}
+		blockSize := uint64(i.BlockSize())
+		n := min(int(blockSize-m.LogicalOff&(blockSize-1)), len(dst))
+		copy(dst[:n], src[len(src)-n:])
+		copy(dst[n:], src)
+		return nil
+
+	case CompressionLZ4:
+		src, err := i.trimZeroPadding(src)
+		if err != nil {
+			return err
+		}
+		n, err := lz4Decompress(dst, src)
+		if err != nil {
+			return err
+		}
+		if n != len(dst) {
+			return fmt.Errorf("lz4 output too short: %d < %d", n, len(dst))
+		}
+		return nil
+
+	case CompressionDeflate:
+		src, err := i.trimZeroPadding(src)
+		if err != nil {
+			return err
+		}
+		r := flate.NewReader(bytes.NewReader(src))
+		defer r.Close()
+		if _, err := io.ReadFull(r, dst); err != nil {
+			return fmt.Errorf("deflate: %v", err)
+		}
+		return nil
+
+	default:
+		// TODO: Support LZMA and Zstandard.
+		return linuxerr.ENOTSUP
+	}
+}
+
+// trimZeroPadding strips the leading zeros of the compressed data in src. If
+// the image has the zero padding feature, the compressed data is stored at
+// the end of the pcluster and the leading bytes of the first block are
+// zero-filled.
+func (i *Image) trimZeroPadding(src []byte) ([]byte, error) {
+	if i.sb.FeatureIncompat&FeatureIncompatZeroPadding == 0 {
+		return src, nil
+	}
+	head := src[:min(len(src), int(i.BlockSize()))]
+	for k, b := range head {
+		if b != 0 {
+			return src[k:], nil
+		}
+	}
+	return nil, fmt.Errorf("compressed data not found in the first block")
+}
+
+// lz4MinMatch is the minimum length of LZ4 matches.
+const lz4MinMatch = 4
+
+// errLZ4Corrupted is returned when the LZ4 block is corrupted.
+var errLZ4Corrupted = fmt.Errorf("corrupted lz4 block")
+
+// lz4Decompress decodes the LZ4 block [1] src into dst. Decoding stops once
+// dst is full, which allows decoding only the leading part of the data. It
+// returns the number of bytes written to dst.
+//
+// [1] https://github.com/lz4/lz4/blob/dev/doc/lz4_Block_format.md
+func lz4Decompress(dst, src []byte) (int, error) {
+	var si, di int
+	for si < len(src) && di < len(dst) {
+		token := src[si]
+		si++
+
+		// Copy the literals.
+		litLen, ok := lz4ReadLength(src, &si, int(token>>4))
+		if !ok || litLen > len(src)-si {
+			return di, errLZ4Corrupted
+		}
+		n := copy(dst[di:], src[si:si+litLen])
+		di += n
+		si += litLen
+		if n < litLen || si == len(src) {
+			// dst is full, or this is the last sequence which only
+			// contains literals.
+			break
+		}
+
+		// Copy the match.
+		if len(src)-si < 2 {
+			return di, errLZ4Corrupted
+		}
+		offset := int(src[si]) | int(src[si+1])<<8
+		si += 2
+		if offset == 0 || offset > di {
+			return di, errLZ4Corrupted
+		}
+		matchLen, ok := lz4ReadLength(src, &si, int(token&0xf))
+		if !ok {
+			return di, errLZ4Corrupted
+		}
+		end := min(di+matchLen+lz4MinMatch, len(dst))
+		if offset >= end-di {
+			di += copy(dst[di:end], dst[di-offset:])
+			continue
+		}
+		// The match overlaps with itself, copy it byte by byte.
+		for ; di < end; di++ {
+			dst[di] = dst[di-offset]
+		}
+	}
+	return di, nil
+}
+
+// lz4ReadLength reads the remaining bytes of a length whose token nibble is
+// n, starting at src[*si].
+func lz4ReadLength(src []byte, si *int, n int) (int, bool) {
+	if n != 0xf {
+		return n, true
+	}
+	for *si < len(src) {
+		b := src[*si]
+		*si++
+		n += int(b)
+		if b != 0xff {
+			return n, true
+		}
+	}
+	return 0, false
+}
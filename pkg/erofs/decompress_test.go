@@ -0,0 +1,154 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erofs
+
+import (
+	"bytes"
+	"testing"
+)
+
+// lz4Sequence encodes an LZ4 sequence with the literals lit followed by a
+// match of matchLen bytes at offset. If matchLen is 0, it encodes the last
+// sequence of a block.
+func lz4Sequence(lit []byte, offset, matchLen int) []byte {
+	appendLength := func(b []byte, n int) []byte {
+		for n -= 0xf; n >= 0xff; n -= 0xff {
+			b = append(b, 0xff)
+		}
+		return append(b, byte(n))
+	}
+	token := byte(min(len(lit), 0xf)) << 4
+	if matchLen != 0 {
+		token |= byte(min(matchLen-lz4MinMatch, 0xf))
+	}
+	b := []byte{token}
+	if len(lit) >= 0xf {
+		b = appendLength(b, len(lit))
+	}
+	b = append(b, lit...)
+	if matchLen == 0 {
+		return b
+	}
+	b = append(b, byte(offset), byte(offset>>8))
+	if matchLen-lz4MinMatch >= 0xf {
+		b = appendLength(b, matchLen-lz4MinMatch)
+	}
+	return b
+}
+
+func TestLZ4Decompress(t *testing.T) {
+	longLit := bytes.Repeat([]byte("0123456789"), 100)
+	for _, tc := range []struct {
+		name string
+		src  []byte
+		want []byte
+	}{
+		{
+			name: "literals",
+			src:  lz4Sequence([]byte("hello"), 0, 0),
+			want: []byte("hello"),
+		},
+		{
+			name: "long literals",
+			src:  lz4Sequence(longLit, 0, 0),
+			want: longLit,
+		},
+		{
+			name: "match",
+			src:  append(lz4Sequence([]byte("abcdefgh"), 8, 8), lz4Sequence([]byte("xyz"), 0, 0)...),
+			want: []byte("abcdefghabcdefghxyz"),
+		},
+		{
+			name: "overlapping match",
+			src:  append(lz4Sequence([]byte("ab"), 2, 300), lz4Sequence([]byte("end"), 0, 0)...),
+			want: append(bytes.Repeat([]byte("ab"), 151), []byte("end")...),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := make([]byte, len(tc.want))
+			n, err := lz4Decompress(dst, tc.src)
+			if err != nil {
+				t.Fatalf("lz4Decompress failed: %v", err)
+			}
+			if n != len(tc.want) || !bytes.Equal(dst, tc.want) {
+				t.Errorf("lz4Decompress got %q, want %q", dst[:n], tc.want)
+			}
+
+			// Decoding the leading part of the data should also work.
+			partial := make([]byte, len(tc.want)/2)
+			n, err = lz4Decompress(partial, tc.src)
+			if err != nil {
+				t.Fatalf("partial lz4Decompress failed: %v", err)
+			}
+			if n != len(partial) || !bytes.Equal(partial, tc.want[:n]) {
+				t.Errorf("partial lz4Decompress got %q, want %q", partial[:n], tc.want[:len(partial)])
+			}
+		})
+	}
+}
+
+func TestLZ4DecompressCorrupted(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  []byte
+	}{
+		{
+			name: "truncated literals",
+			src:  lz4Sequence([]byte("hello"), 0, 0)[:3],
+		},
+		{
+			name: "zero offset",
+			src:  lz4Sequence([]byte("abcd"), 0, 4),
+		},
+		{
+			name: "offset out of range",
+			src:  lz4Sequence([]byte("abcd"), 5, 4),
+		},
+		{
+			name: "truncated offset",
+			src:  lz4Sequence([]byte("abcd"), 4, 4)[:6],
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := make([]byte, 64)
+			if _, err := lz4Decompress(dst, tc.src); err == nil {
+				t.Errorf("lz4Decompress succeeded unexpectedly")
+			}
+		})
+	}
+}
+
+func TestDecompressInterlaced(t *testing.T) {
+	image := &Image{sb: SuperBlock{BlockSizeBits: 12}}
+	src := make([]byte, image.BlockSize())
+	for k := range src {
+		src[k] = byte(k)
+	}
+	// The extent starts at offset 0x100 of a block, so its first
+	// (BlockSize - 0x100) bytes are stored at the end of the pcluster.
+	m := &Map{
+		LogicalOff: 0x1100,
+		LogicalLen: uint64(len(src)),
+		Algorithm:  CompressionInterlaced,
+	}
+	dst := make([]byte, len(src))
+	if err := image.decompress(m, src, dst); err != nil {
+		t.Fatalf("decompress failed: %v", err)
+	}
+	want := append(append([]byte{}, src[0x100:]...), src[:0x100]...)
+	if !bytes.Equal(dst, want) {
+		t.Errorf("decompress got %v, want %v", dst, want)
+	}
+}
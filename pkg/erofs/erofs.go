@@ -17,7 +17,9 @@
 // The design principle of this package is that, it will just provide the ability
 // to access the contents in the image, and it will never cache any objects internally.
 // The whole disk image is mapped via a read-only/shared mapping, and it relies on
-// host kernel to cache the blocks/pages transparently.
+// host kernel to cache the blocks/pages transparently. Images without a host
+// file are read on demand into a mapping provided by the caller of
+// OpenImageReader instead.
 //
 // [1] https://docs.kernel.org/filesystems/erofs.html
 package erofs
@@ -26,10 +28,13 @@ import (
 	"bytes"
 	"fmt"
 	"hash/crc32"
+	"io"
+	"math/bits"
 	"os"
 
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/cleanup"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/gohacks"
@@ -37,6 +42,7 @@ import (
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/marshal"
 	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sync"
 )
 
 const (
@@ -89,7 +95,91 @@ const (
 //
 // This is not exhaustive, unused features are not listed.
 const (
-	FeatureIncompatSupported = 0x0
+	FeatureIncompatZeroPadding  = 0x00000001
+	FeatureIncompatComprCfgs    = 0x00000002
+	FeatureIncompatBigPcluster  = 0x00000002
+	FeatureIncompatChunkedFile  = 0x00000004
+	FeatureIncompatDeviceTable  = 0x00000008
+	FeatureIncompatComprHead2   = 0x00000008
+	FeatureIncompatZtailPacking = 0x00000010
+
+	FeatureIncompatSupported = FeatureIncompatZeroPadding |
+		FeatureIncompatComprCfgs |
+		FeatureIncompatBigPcluster |
+		FeatureIncompatChunkedFile |
+		FeatureIncompatDeviceTable |
+		FeatureIncompatComprHead2 |
+		FeatureIncompatZtailPacking
+)
+
+// Compression algorithms.
+const (
+	CompressionLZ4 = iota
+	CompressionLZMA
+	CompressionDeflate
+	CompressionZstd
+	CompressionMax
+
+	// Pseudo algorithms used for the uncompressed physical clusters in
+	// compressed files.
+	CompressionShifted    = CompressionMax
+	CompressionInterlaced = CompressionMax + 1
+)
+
+// Bit definitions for chunk formats.
+const (
+	ChunkFormatBlockBitsMask = 0x001f
+	ChunkFormatIndexes       = 0x0020
+	ChunkFormatAll           = ChunkFormatBlockBitsMask | ChunkFormatIndexes
+)
+
+// NullAddr is the block address of unallocated chunks.
+const NullAddr = 0xffffffff
+
+// Bit definitions for MapHeader::Advise.
+const (
+	AdviseCompacted2B        = 0x0001
+	AdviseBigPcluster1       = 0x0002
+	AdviseBigPcluster2       = 0x0004
+	AdviseInlinePcluster     = 0x0008
+	AdviseInterlacedPcluster = 0x0010
+	AdviseFragmentPcluster   = 0x0020
+)
+
+// MapHeaderFragmentInodeBit is set in MapHeader::ClusterBits if the whole
+// file is stored in the packed inode.
+const MapHeaderFragmentInodeBit = 7
+
+// Logical cluster types.
+const (
+	LclusterTypePlain   = 0
+	LclusterTypeHead1   = 1
+	LclusterTypeNonHead = 2
+	LclusterTypeHead2   = 3
+	LclusterTypeMask    = 3
+)
+
+// Limits of compressed inodes. See Linux's fs/erofs/internal.h.
+const (
+	// PclusterMaxSize is the maximum size of a physical cluster
+	// (Z_EROFS_PCLUSTER_MAX_SIZE).
+	PclusterMaxSize = 1 << 20
+
+	// PclusterMaxDecompressedSize is the maximum length of the extent
+	// decompressed from a physical cluster (Z_EROFS_PCLUSTER_MAX_DSIZE).
+	PclusterMaxDecompressedSize = 12 << 20
+)
+
+// Bit definitions for LclusterIndex.
+const (
+	// LclusterPartialRef is set in LclusterIndex::Advise if the extent only
+	// references a part of the decompressed physical cluster.
+	LclusterPartialRef = 0x8000
+
+	// LclusterD0CompressedBlocks is set in LclusterIndex::Delta(0) if the
+	// rest of the bits hold the count of compressed blocks of a big
+	// physical cluster.
+	LclusterD0CompressedBlocks = 0x0800
 )
 
 // Sizes of on-disk structures in bytes.
@@ -98,6 +188,14 @@ const (
 	InodeCompactSize  = 32
 	InodeExtendedSize = 64
 	DirentSize        = 12
+	MapHeaderSize     = 8
+	LclusterIndexSize = 8
+	ChunkIndexSize    = 8
+	BlockMapEntrySize = 4
+	DeviceSlotSize    = 128
+
+	XattrIbodyHeaderSize = 12
+	XattrEntrySize       = 4
 )
 
 // SuperBlock represents on-disk superblock.
@@ -200,6 +298,76 @@ func (d *Dirent) Nid() uint64 {
 	return (uint64(d.NidHigh) << 32) | uint64(d.NidLow)
 }
 
+// MapHeader represents on-disk header of compressed inodes.
+//
+// +marshal
+type MapHeader struct {
+	Reserved      uint16
+	IdataSize     uint16
+	Advise        uint16
+	AlgorithmType uint8
+	ClusterBits   uint8
+}
+
+// LclusterIndex represents on-disk full index of a logical cluster.
+//
+// +marshal
+type LclusterIndex struct {
+	Advise     uint16
+	ClusterOfs uint16
+	// BlockAddr is the physical block address of HEAD and PLAIN logical
+	// clusters, and holds the two deltas of NONHEAD logical clusters.
+	BlockAddr uint32
+}
+
+// Type returns the type of this logical cluster.
+func (l *LclusterIndex) Type() uint8 {
+	return uint8(l.Advise & LclusterTypeMask)
+}
+
+// Delta returns the n-th delta of this NONHEAD logical cluster.
+func (l *LclusterIndex) Delta(n int) uint16 {
+	return uint16(l.BlockAddr >> (16 * n))
+}
+
+// ChunkIndex represents on-disk chunk index.
+//
+// +marshal
+type ChunkIndex struct {
+	Advise    uint16
+	DeviceID  uint16
+	BlockAddr uint32
+}
+
+// DeviceSlot represents on-disk device table slot.
+//
+// +marshal
+type DeviceSlot struct {
+	Tag           [64]uint8
+	Blocks        uint32
+	MappedBlkAddr uint32
+	Reserved      [56]uint8
+}
+
+// XattrIbodyHeader represents on-disk header of inline xattrs.
+//
+// +marshal
+type XattrIbodyHeader struct {
+	NameFilter  uint32
+	SharedCount uint8
+	Reserved    [7]uint8
+}
+
+// XattrEntry represents on-disk xattr entry. It's followed by the name
+// suffix and the value.
+//
+// +marshal
+type XattrEntry struct {
+	NameLen   uint8
+	NameIndex uint8
+	ValueSize uint16
+}
+
 // Image represents an open EROFS image.
 //
 // +stateify savable
@@ -207,22 +375,53 @@ type Image struct {
 	src   *os.File `state:"nosave"`
 	bytes []byte   `state:"nosave"`
 	sb    SuperBlock
+
+	// lazy is the state of an image opened by OpenImageReader, in which case
+	// src is nil. lazy is nil if the image was opened by OpenImage.
+	lazy *lazyImage
+
+	// comprAlgs is the bitmap of compression algorithms available in this
+	// image.
+	comprAlgs uint16
+
+	// devices are the extra devices recorded in the device table.
+	devices []device `state:"nosave"`
+
+	// deviceIDMask is used to mask the device IDs in chunk indexes.
+	deviceIDMask uint16
+
+	// flatDev indicates that the extra devices are not provided, and the
+	// data of them have been merged into the primary image.
+	flatDev bool
+}
+
+// device represents an extra device of an image.
+type device struct {
+	src   *os.File
+	bytes []byte
+
+	// blocks is the count of blocks of this device.
+	blocks uint32
+
+	// mappedBlkAddr is the block address where this device is mapped in
+	// the unified address space of the image.
+	mappedBlkAddr uint32
 }
 
 // OpenImage returns an Image providing access to the contents in the image file src.
+// devices are the extra devices (e.g. blobs created by "mkfs.erofs --blobdev")
+// in the order of the device table. If no extra devices are provided, the
+// data of them is expected to be found in src.
 //
-// On success, the ownership of src is transferred to Image.
-func OpenImage(src *os.File) (*Image, error) {
+// On success, the ownership of src and devices is transferred to Image.
+func OpenImage(src *os.File, devices ...*os.File) (*Image, error) {
 	i := &Image{src: src}
 
 	var cu cleanup.Cleanup
 	defer cu.Clean()
 
-	stat, err := i.src.Stat()
-	if err != nil {
-		return nil, err
-	}
-	i.bytes, err = unix.Mmap(int(i.src.Fd()), 0, int(stat.Size()), unix.PROT_READ, unix.MAP_SHARED)
+	var err error
+	i.bytes, err = mmapFile(src)
 	if err != nil {
 		return nil, err
 	}
@@ -231,14 +430,144 @@ func OpenImage(src *os.File) (*Image, error) {
 	if err := i.initSuperBlock(); err != nil {
 		return nil, err
 	}
+	if err := i.initDevices(devices); err != nil {
+		return nil, err
+	}
 	cu.Release()
 	return i, nil
 }
 
+// lazyChunkSize is the granularity at which the contents of images opened by
+// OpenImageReader are read.
+const lazyChunkSize = 64 << 10
+
+// lazyImage is the state of an image opened by OpenImageReader.
+//
+// +stateify savable
+type lazyImage struct {
+	// r reads the contents of the image.
+	r io.ReaderAt `state:"nosave"`
+
+	// mu serializes reads from r.
+	mu sync.Mutex `state:"nosave"`
+
+	// read is a bitmap of the chunks of the image that have been read into
+	// Image.bytes. Bits are only set with mu locked, after the chunk has been
+	// read.
+	read []atomicbitops.Uint64
+}
+
+// OpenImageReader returns an Image providing access to the contents of the
+// image read from r, whose size is len(data). The image is read from r on
+// demand, into data, which must be zero-filled and writable. The Image has no
+// underlying host file, so FD returns -1. Extra devices are not supported;
+// their data is expected to be found in r.
+//
+// data must not be modified by the caller, and must remain valid until the
+// Image is closed. It isn't released by Close, nor saved along with the
+// Image; see RestoreReader.
+func OpenImageReader(data []byte, r io.ReaderAt) (*Image, error) {
+	chunks := (uint64(len(data)) + lazyChunkSize - 1) / lazyChunkSize
+	i := &Image{
+		bytes: data,
+		lazy: &lazyImage{
+			r:    r,
+			read: make([]atomicbitops.Uint64, (chunks+63)/64),
+		},
+	}
+	if err := i.initSuperBlock(); err != nil {
+		return nil, err
+	}
+	if err := i.initDevices(nil); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// RestoreReader replaces the data and reader passed to OpenImageReader after
+// the Image is restored. data must hold the contents of the image that had
+// been read when it was saved, and r must read the same image.
+func (i *Image) RestoreReader(data []byte, r io.ReaderAt) error {
+	var sb SuperBlock
+	buf := make([]byte, sb.SizeBytes())
+	if n, err := r.ReadAt(buf, SuperBlockOffset); n != len(buf) {
+		return fmt.Errorf("failed to read superblock: %v", err)
+	}
+	sb.UnmarshalUnsafe(buf)
+	if sb != i.sb {
+		return fmt.Errorf("superblock mismatch detected on restore, got %+v, expected %+v", sb, i.sb)
+	}
+	i.bytes = data
+	i.lazy.r = r
+	return nil
+}
+
+// populate ensures that the blocks containing the range [off, off+n) of an
+// image opened by OpenImageReader have been read. It does nothing for images
+// opened by OpenImage.
+//
+// Precondition: i.checkRange(off, n) == true.
+func (i *Image) populate(off, n uint64) error {
+	if i.lazy == nil {
+		return nil
+	}
+	// Callers may access the rest of the blocks containing the range without
+	// further checks, e.g. when iterating over the dirents in a block.
+	align := max(uint64(lazyChunkSize), uint64(i.BlockSize()))
+	start := off &^ (align - 1)
+	end := min((off+n+align-1)&^(align-1), uint64(len(i.bytes)))
+	for c := start / lazyChunkSize; c*lazyChunkSize < end; c++ {
+		if i.lazy.read[c/64].Load()&(1<<(c%64)) != 0 {
+			continue
+		}
+		if err := i.readChunk(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readChunk reads chunk c of an image opened by OpenImageReader.
+func (i *Image) readChunk(c uint64) error {
+	l := i.lazy
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	word, bit := &l.read[c/64], uint64(1)<<(c%64)
+	if word.Load()&bit != 0 {
+		// Another goroutine read the chunk first.
+		return nil
+	}
+	start := c * lazyChunkSize
+	end := min(start+lazyChunkSize, uint64(len(i.bytes)))
+	if n, err := l.r.ReadAt(i.bytes[start:end], int64(start)); uint64(n) != end-start {
+		log.Warningf("Failed to read image at [0x%x, 0x%x): read %d bytes: %v", start, end, n, err)
+		return linuxerr.EIO
+	}
+	word.Store(word.Load() | bit)
+	return nil
+}
+
+// mmapFile maps the whole file f via a read-only/shared mapping.
+func mmapFile(f *os.File) ([]byte, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return unix.Mmap(int(f.Fd()), 0, int(stat.Size()), unix.PROT_READ, unix.MAP_SHARED)
+}
+
 // Close closes the image.
 func (i *Image) Close() {
-	unix.Munmap(i.bytes)
-	i.src.Close()
+	if i.src != nil {
+		unix.Munmap(i.bytes)
+		i.src.Close()
+	}
+	for _, d := range i.devices {
+		if d.src != nil {
+			unix.Munmap(d.bytes)
+			d.src.Close()
+		}
+	}
 }
 
 // SuperBlock returns a copy of the image's superblock.
@@ -284,6 +613,57 @@ func (i *Image) initSuperBlock() error {
 		return fmt.Errorf("unsupported block size: 0x%x", i.BlockSize())
 	}
 
+	// Union1 is the bitmap of available compression algorithms if the
+	// compression configs are present, otherwise only LZ4 is available.
+	if i.sb.FeatureIncompat&FeatureIncompatComprCfgs != 0 {
+		i.comprAlgs = i.sb.Union1
+		if algs := i.comprAlgs &^ (1<<CompressionMax - 1); algs != 0 {
+			return fmt.Errorf("unsupported compression algorithms detected: 0x%x", algs)
+		}
+	} else {
+		i.comprAlgs = 1 << CompressionLZ4
+	}
+
+	return nil
+}
+
+// initDevices initializes the extra devices of this image.
+func (i *Image) initDevices(srcs []*os.File) error {
+	var n int
+	if i.sb.FeatureIncompat&FeatureIncompatDeviceTable != 0 {
+		n = int(i.sb.ExtraDevices)
+	}
+	if len(srcs) != 0 && len(srcs) != n {
+		return fmt.Errorf("%d extra devices provided, but the image has %d", len(srcs), n)
+	}
+	if n == 0 {
+		return nil
+	}
+	i.deviceIDMask = uint16(1<<bits.Len(uint(n)) - 1)
+	i.flatDev = len(srcs) == 0
+
+	devices := make([]device, n)
+	off := uint64(i.sb.DevTableSlotOff) * DeviceSlotSize
+	for k := range devices {
+		var slot DeviceSlot
+		if err := i.unmarshalAt(&slot, off+uint64(k)*DeviceSlotSize); err != nil {
+			return fmt.Errorf("invalid device table")
+		}
+		devices[k].blocks = slot.Blocks
+		devices[k].mappedBlkAddr = slot.MappedBlkAddr
+	}
+	for k, src := range srcs {
+		bytes, err := mmapFile(src)
+		if err != nil {
+			for _, d := range devices[:k] {
+				unix.Munmap(d.bytes)
+			}
+			return err
+		}
+		devices[k].src = src
+		devices[k].bytes = bytes
+	}
+	i.devices = devices
 	return nil
 }
 
@@ -311,11 +691,20 @@ func (i *Image) verifyChecksum() error {
 	return nil
 }
 
-// FD returns the host FD of underlying image file.
+// FD returns the host FD of underlying image file, or -1 if the image was
+// opened by OpenImageReader.
 func (i *Image) FD() int {
+	if i.src == nil {
+		return -1
+	}
 	return int(i.src.Fd())
 }
 
+// InMemory returns true if the image was opened by OpenImageReader.
+func (i *Image) InMemory() bool {
+	return i.lazy != nil
+}
+
 // checkRange checks whether the range [off, off+n) is valid.
 func (i *Image) checkRange(off, n uint64) bool {
 	size := uint64(len(i.bytes))
@@ -329,9 +718,41 @@ func (i *Image) BytesAt(off, n uint64) ([]byte, error) {
 		log.Warningf("Invalid byte range (off: 0x%x, n: 0x%x) for image (size: 0x%x)", off, n, len(i.bytes))
 		return nil, linuxerr.EFAULT
 	}
+	if err := i.populate(off, n); err != nil {
+		return nil, err
+	}
 	return i.bytes[off : off+n], nil
 }
 
+// DeviceFD returns the host FD of the device identified by dev, where 0 is
+// the primary image and N is the N-th extra device.
+func (i *Image) DeviceFD(dev uint16) int {
+	if dev == 0 {
+		return i.FD()
+	}
+	return int(i.devices[dev-1].src.Fd())
+}
+
+// DeviceBytesAt returns the bytes at [off, off+n) of the device identified by
+// dev, where 0 is the primary image and N is the N-th extra device.
+func (i *Image) DeviceBytesAt(dev uint16, off, n uint64) ([]byte, error) {
+	if dev == 0 {
+		return i.BytesAt(off, n)
+	}
+	if int(dev) > len(i.devices) || i.devices[dev-1].bytes == nil {
+		log.Warningf("Invalid device %d for image", dev)
+		return nil, linuxerr.ENODEV
+	}
+	bytes := i.devices[dev-1].bytes
+	size := uint64(len(bytes))
+	end := off + n
+	if off >= size || end < off || end > size {
+		log.Warningf("Invalid byte range (off: 0x%x, n: 0x%x) for device %d (size: 0x%x)", off, n, dev, size)
+		return nil, linuxerr.EFAULT
+	}
+	return bytes[off:end], nil
+}
+
 // checkInodeAlignment checks whether off matches inode's alignment requirement.
 func checkInodeAlignment(off uint64) bool {
 	// Each valid inode should be aligned with an inode slot, which is
@@ -348,6 +769,9 @@ func (i *Image) inodeFormatAt(off uint64) (uint16, error) {
 	if ok := i.checkRange(off, 2); !ok {
 		return 0, linuxerr.EFAULT
 	}
+	if err := i.populate(off, 2); err != nil {
+		return 0, err
+	}
 	return *(*uint16)(i.pointerAt(off)), nil
 }
 
@@ -360,6 +784,9 @@ func (i *Image) inodeCompactAt(off uint64) (*InodeCompact, error) {
 	if ok := i.checkRange(off, InodeCompactSize); !ok {
 		return nil, linuxerr.EFAULT
 	}
+	if err := i.populate(off, InodeCompactSize); err != nil {
+		return nil, err
+	}
 	return (*InodeCompact)(i.pointerAt(off)), nil
 }
 
@@ -372,6 +799,9 @@ func (i *Image) inodeExtendedAt(off uint64) (*InodeExtended, error) {
 	if ok := i.checkRange(off, InodeExtendedSize); !ok {
 		return nil, linuxerr.EFAULT
 	}
+	if err := i.populate(off, InodeExtendedSize); err != nil {
+		return nil, err
+	}
 	return (*InodeExtended)(i.pointerAt(off)), nil
 }
 
@@ -385,6 +815,9 @@ func (i *Image) direntAt(off uint64) (*Dirent, error) {
 	if ok := i.checkRange(off, DirentSize); !ok {
 		return nil, linuxerr.EFAULT
 	}
+	if err := i.populate(off, DirentSize); err != nil {
+		return nil, err
+	}
 	return (*Dirent)(i.pointerAt(off)), nil
 }
 
@@ -416,6 +849,7 @@ func (i *Image) Inode(nid uint64) (Inode, error) {
 	var (
 		rawBlockAddr uint32
 		inodeSize    int
+		xattrCount   uint16
 	)
 
 	switch layout := inode.Layout(); layout {
@@ -425,13 +859,9 @@ func (i *Image) Inode(nid uint64) (Inode, error) {
 			return Inode{}, err
 		}
 
-		if ino.XattrCount != 0 {
-			log.Warningf("Unsupported xattr at inode (nid=%v)", nid)
-			return Inode{}, linuxerr.ENOTSUP
-		}
-
 		rawBlockAddr = ino.RawBlockAddr
 		inodeSize = ino.SizeBytes()
+		xattrCount = ino.XattrCount
 
 		inode.size = uint64(ino.Size)
 		inode.nlink = uint32(ino.Nlink)
@@ -447,13 +877,9 @@ func (i *Image) Inode(nid uint64) (Inode, error) {
 			return Inode{}, err
 		}
 
-		if ino.XattrCount != 0 {
-			log.Warningf("Unsupported xattr at inode (nid=%v)", nid)
-			return Inode{}, linuxerr.ENOTSUP
-		}
-
 		rawBlockAddr = ino.RawBlockAddr
 		inodeSize = ino.SizeBytes()
+		xattrCount = ino.XattrCount
 
 		inode.size = ino.Size
 		inode.nlink = ino.Nlink
@@ -468,6 +894,15 @@ func (i *Image) Inode(nid uint64) (Inode, error) {
 		return Inode{}, linuxerr.ENOTSUP
 	}
 
+	// The inline xattrs follow the on-disk inode.
+	inode.xattrOff = off + uint64(inodeSize)
+	inode.xattrSize = xattrIbodySize(xattrCount)
+	metaEnd := inode.xattrOff + inode.xattrSize
+
+	if inode.IsCharDev() || inode.IsBlockDev() {
+		inode.rdev = rawBlockAddr
+	}
+
 	blockSize := uint64(i.BlockSize())
 	inode.blocks = (inode.size + (blockSize - 1)) / blockSize
 
@@ -476,16 +911,36 @@ func (i *Image) Inode(nid uint64) (Inode, error) {
 		// Check that whether the file data in the last block fits into
 		// the remaining room of the metadata block.
 		tailSize := inode.size & (blockSize - 1)
-		if tailSize == 0 || tailSize > blockSize-uint64(inodeSize) {
+		if tailSize == 0 || tailSize > blockSize-(metaEnd&(blockSize-1)) {
 			log.Warningf("Inline data not found or cross block boundary at inode (nid=%v)", nid)
 			return Inode{}, linuxerr.EUCLEAN
 		}
-		inode.idataOff = off + uint64(inodeSize)
+		inode.idataOff = metaEnd
 		fallthrough
 
 	case InodeDataLayoutFlatPlain:
 		inode.dataOff = i.sb.BlockAddrToOffset(rawBlockAddr)
 
+	case InodeDataLayoutFlatCompressionLegacy, InodeDataLayoutFlatCompression:
+		if !inode.IsRegular() {
+			log.Warningf("Unsupported data layout 0x%x at non-regular inode (nid=%v)", dataLayout, nid)
+			return Inode{}, linuxerr.ENOTSUP
+		}
+		inode.metaEnd = metaEnd
+		if err := inode.initCompression(); err != nil {
+			return Inode{}, err
+		}
+
+	case InodeDataLayoutChunkBased:
+		if !inode.IsRegular() {
+			log.Warningf("Unsupported data layout 0x%x at non-regular inode (nid=%v)", dataLayout, nid)
+			return Inode{}, linuxerr.ENOTSUP
+		}
+		inode.metaEnd = metaEnd
+		if err := inode.initChunks(uint16(rawBlockAddr)); err != nil {
+			return Inode{}, err
+		}
+
 	default:
 		log.Warningf("Unsupported data layout 0x%x at inode (nid=%v)", dataLayout, nid)
 		return Inode{}, linuxerr.ENOTSUP
@@ -517,6 +972,30 @@ type Inode struct {
 	// format is the format of this inode.
 	format uint16
 
+	// metaEnd points to the end of the on-disk inode (including xattrs),
+	// where the indexes of compressed and chunk-based inodes start.
+	metaEnd uint64
+
+	// xattrOff points to the inline xattrs of this inode, and xattrSize is
+	// the size of them. xattrSize is zero if this inode has no xattrs.
+	xattrOff  uint64
+	xattrSize uint64
+
+	// chunkFormat is the chunk format of chunk-based inodes.
+	chunkFormat uint16
+
+	// chunkBits is the chunk size in bit shift of chunk-based inodes.
+	chunkBits uint8
+
+	// Information about the compression of compressed inodes. See
+	// initCompression().
+	zAdvise       uint16
+	zAlgorithms   [2]uint8
+	zLclusterBits uint8
+	zIdataSize    uint16
+	zIdataOff     uint64
+	zTailHeadLcn  uint64
+
 	// Metadata.
 	mode      uint16
 	nid       uint64
@@ -526,6 +1005,7 @@ type Inode struct {
 	uid       uint32
 	gid       uint32
 	nlink     uint32
+	rdev      uint32
 }
 
 // bitRange returns the bits within the range [bit, bit+bits) in value.
@@ -618,6 +1098,11 @@ func (i *Inode) GID() uint32 {
 	return i.gid
 }
 
+// Rdev returns the device number of character and block device inodes.
+func (i *Inode) Rdev() uint32 {
+	return i.rdev
+}
+
 // DataOffset returns the data offset of this inode in image file.
 func (i *Inode) DataOffset() (uint64, error) {
 	// TODO: We don't support regular files with inline data yet, which means the image
@@ -633,9 +1118,17 @@ func (i *Inode) DataOffset() (uint64, error) {
 
 // Data returns the read-only file data of this inode.
 func (i *Inode) Data() (safemem.BlockSeq, error) {
+	return i.DataRange(0, i.size)
+}
+
+// DataRange returns the read-only file data of this inode in the range
+// [off, off+n).
+//
+// Precondition: off+n <= i.Size().
+func (i *Inode) DataRange(off, n uint64) (safemem.BlockSeq, error) {
 	switch dataLayout := i.DataLayout(); dataLayout {
 	case InodeDataLayoutFlatPlain:
-		bytes, err := i.image.BytesAt(i.dataOff, i.size)
+		bytes, err := i.image.BytesAt(i.dataOff+off, n)
 		if err != nil {
 			return safemem.BlockSeq{}, err
 		}
@@ -644,17 +1137,22 @@ func (i *Inode) Data() (safemem.BlockSeq, error) {
 	case InodeDataLayoutFlatInline:
 		sl := make([]safemem.Block, 0, 2)
 		idataSize := i.size & (uint64(i.image.BlockSize()) - 1)
-		if i.size > idataSize {
-			if bytes, err := i.image.BytesAt(i.dataOff, i.size-idataSize); err != nil {
+		blocksSize := i.size - idataSize
+		end := off + n
+		if off < blocksSize {
+			if bytes, err := i.image.BytesAt(i.dataOff+off, min(end, blocksSize)-off); err != nil {
 				return safemem.BlockSeq{}, err
 			} else {
 				sl = append(sl, safemem.BlockFromSafeSlice(bytes))
 			}
 		}
-		if bytes, err := i.image.BytesAt(i.idataOff, idataSize); err != nil {
-			return safemem.BlockSeq{}, err
-		} else {
-			sl = append(sl, safemem.BlockFromSafeSlice(bytes))
+		if end > blocksSize {
+			start := max(off, blocksSize)
+			if bytes, err := i.image.BytesAt(i.idataOff+(start-blocksSize), end-start); err != nil {
+				return safemem.BlockSeq{}, err
+			} else {
+				sl = append(sl, safemem.BlockFromSafeSlice(bytes))
+			}
 		}
 		return safemem.BlockSeqFromSlice(sl), nil
 
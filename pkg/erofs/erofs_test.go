@@ -34,4 +34,28 @@ func TestOnDiskStructureSizes(t *testing.T) {
 	if d := new(Dirent); d.SizeBytes() != DirentSize {
 		t.Errorf("wrong dirent size: want %d, got %d", DirentSize, d.SizeBytes())
 	}
+
+	if h := new(MapHeader); h.SizeBytes() != MapHeaderSize {
+		t.Errorf("wrong map header size: want %d, got %d", MapHeaderSize, h.SizeBytes())
+	}
+
+	if l := new(LclusterIndex); l.SizeBytes() != LclusterIndexSize {
+		t.Errorf("wrong lcluster index size: want %d, got %d", LclusterIndexSize, l.SizeBytes())
+	}
+
+	if c := new(ChunkIndex); c.SizeBytes() != ChunkIndexSize {
+		t.Errorf("wrong chunk index size: want %d, got %d", ChunkIndexSize, c.SizeBytes())
+	}
+
+	if d := new(DeviceSlot); d.SizeBytes() != DeviceSlotSize {
+		t.Errorf("wrong device slot size: want %d, got %d", DeviceSlotSize, d.SizeBytes())
+	}
+
+	if h := new(XattrIbodyHeader); h.SizeBytes() != XattrIbodyHeaderSize {
+		t.Errorf("wrong xattr ibody header size: want %d, got %d", XattrIbodyHeaderSize, h.SizeBytes())
+	}
+
+	if e := new(XattrEntry); e.SizeBytes() != XattrEntrySize {
+		t.Errorf("wrong xattr entry size: want %d, got %d", XattrEntrySize, e.SizeBytes())
+	}
 }
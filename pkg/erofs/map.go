@@ -0,0 +1,221 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erofs
+
+import (
+	"encoding/binary"
+
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/log"
+)
+
+// Bit definitions for Map.Flags.
+const (
+	// MapMapped indicates that the extent is backed by on-disk data.
+	// Otherwise, the extent is a hole and reads as zeros.
+	MapMapped = 1 << iota
+
+	// MapMeta indicates that the extent is stored inline in the metadata
+	// area of the primary image.
+	MapMeta
+
+	// MapEncoded indicates that the extent is stored in a physical cluster
+	// which needs to be decoded with Map.Algorithm.
+	MapEncoded
+
+	// MapPartialRef indicates that the extent only references a part of the
+	// decoded physical cluster.
+	MapPartialRef
+)
+
+// Map describes a logical extent of the data of an inode and where it is
+// stored. It's the counterpart of Linux's struct erofs_map_blocks.
+type Map struct {
+	// LogicalOff and LogicalLen describe the extent within the inode data.
+	LogicalOff uint64
+	LogicalLen uint64
+
+	// PhysicalOff and PhysicalLen describe the data backing the extent
+	// within Device.
+	PhysicalOff uint64
+	PhysicalLen uint64
+
+	// Device identifies where the data is stored. 0 is the primary image,
+	// and N is the N-th extra device.
+	Device uint16
+
+	// Algorithm is the compression algorithm of encoded extents.
+	Algorithm uint8
+
+	// Flags is a bitmask of Map* flags.
+	Flags uint32
+}
+
+// Mapped returns true if the extent is backed by on-disk data.
+func (m *Map) Mapped() bool {
+	return m.Flags&MapMapped != 0
+}
+
+// MapBlocks returns the extent which contains the data at offset off of this
+// inode. Only compressed and chunk-based inodes are supported, the data of
+// the other inodes can be accessed by Data().
+//
+// Precondition: off < i.Size().
+func (i *Inode) MapBlocks(off uint64) (Map, error) {
+	if off >= i.size {
+		return Map{}, linuxerr.EINVAL
+	}
+	switch dataLayout := i.DataLayout(); dataLayout {
+	case InodeDataLayoutFlatCompressionLegacy, InodeDataLayoutFlatCompression:
+		return i.mapCompressed(off, false /* findTail */)
+
+	case InodeDataLayoutChunkBased:
+		return i.mapChunk(off)
+
+	default:
+		log.Warningf("Unsupported data layout 0x%x at inode (nid=%v)", dataLayout, i.Nid())
+		return Map{}, linuxerr.ENOTSUP
+	}
+}
+
+// ReadExtent reads the data of the extent m returned by MapBlocks() into dst,
+// decoding it if necessary. If dst is shorter than m.LogicalLen, only the
+// leading part of the extent is read.
+func (i *Inode) ReadExtent(m *Map, dst []byte) error {
+	if uint64(len(dst)) > m.LogicalLen {
+		return linuxerr.EINVAL
+	}
+	if !m.Mapped() {
+		clear(dst)
+		return nil
+	}
+	src, err := i.image.DeviceBytesAt(m.Device, m.PhysicalOff, m.PhysicalLen)
+	if err != nil {
+		return err
+	}
+	if m.Flags&MapEncoded == 0 {
+		copy(dst, src)
+		return nil
+	}
+	if err := i.image.decompress(m, src, dst); err != nil {
+		log.Warningf("Failed to decompress extent (la: 0x%x, pa: 0x%x, algorithm: %v) at inode (nid=%v): %v", m.LogicalOff, m.PhysicalOff, m.Algorithm, i.Nid(), err)
+		if err != linuxerr.ENOTSUP {
+			err = linuxerr.EUCLEAN
+		}
+		return err
+	}
+	return nil
+}
+
+// roundUp rounds up x to a multiple of align.
+//
+// Precondition: align is a power of 2.
+func roundUp(x, align uint64) uint64 {
+	return (x + align - 1) &^ (align - 1)
+}
+
+// initChunks initializes the chunk information of this inode.
+func (i *Inode) initChunks(format uint16) error {
+	if i.image.sb.FeatureIncompat&FeatureIncompatChunkedFile == 0 {
+		log.Warningf("Chunk-based inode found in an image without chunked file feature (nid=%v)", i.Nid())
+		return linuxerr.EUCLEAN
+	}
+	if format&^ChunkFormatAll != 0 {
+		log.Warningf("Unsupported chunk format 0x%x at inode (nid=%v)", format, i.Nid())
+		return linuxerr.ENOTSUP
+	}
+	i.chunkFormat = format
+	i.chunkBits = i.image.sb.BlockSizeBits + uint8(format&ChunkFormatBlockBitsMask)
+	return nil
+}
+
+// mapChunk returns the extent of the chunk which contains the data at offset
+// off of this chunk-based inode. See Linux's fs/erofs/data.c:erofs_map_blocks().
+func (i *Inode) mapChunk(off uint64) (Map, error) {
+	unit := uint64(BlockMapEntrySize)
+	if i.chunkFormat&ChunkFormatIndexes != 0 {
+		unit = ChunkIndexSize
+	}
+	chunkNr := off >> i.chunkBits
+	pos := roundUp(i.metaEnd, unit) + unit*chunkNr
+
+	var m Map
+	m.LogicalOff = chunkNr << i.chunkBits
+	m.PhysicalLen = min(uint64(1)<<i.chunkBits, roundUp(i.size-m.LogicalOff, uint64(i.image.BlockSize())))
+	m.LogicalLen = m.PhysicalLen
+
+	var (
+		blockAddr uint32
+		deviceID  uint16
+	)
+	if i.chunkFormat&ChunkFormatIndexes != 0 {
+		var idx ChunkIndex
+		if err := i.image.unmarshalAt(&idx, pos); err != nil {
+			return Map{}, err
+		}
+		blockAddr = idx.BlockAddr
+		deviceID = idx.DeviceID & i.image.deviceIDMask
+	} else {
+		b, err := i.image.BytesAt(pos, BlockMapEntrySize)
+		if err != nil {
+			return Map{}, err
+		}
+		blockAddr = binary.LittleEndian.Uint32(b)
+	}
+	if blockAddr == NullAddr {
+		return m, nil
+	}
+	m.PhysicalOff = i.image.sb.BlockAddrToOffset(blockAddr)
+	m.Flags = MapMapped
+	if err := i.image.mapDevice(&m, deviceID); err != nil {
+		return Map{}, err
+	}
+	return m, nil
+}
+
+// mapDevice resolves the device where the physical data of m is stored. See
+// Linux's fs/erofs/data.c:erofs_map_dev().
+func (i *Image) mapDevice(m *Map, deviceID uint16) error {
+	if deviceID != 0 {
+		if int(deviceID) > len(i.devices) {
+			log.Warningf("Invalid device ID %d", deviceID)
+			return linuxerr.ENODEV
+		}
+		if i.flatDev {
+			m.PhysicalOff += i.sb.BlockAddrToOffset(i.devices[deviceID-1].mappedBlkAddr)
+			return nil
+		}
+		m.Device = deviceID
+		return nil
+	}
+	if i.flatDev {
+		return nil
+	}
+	// Data without an explicit device ID are located in the unified address
+	// space of the image.
+	for k, d := range i.devices {
+		if d.mappedBlkAddr == 0 {
+			continue
+		}
+		start := i.sb.BlockAddrToOffset(d.mappedBlkAddr)
+		length := i.sb.BlockAddrToOffset(d.blocks)
+		if m.PhysicalOff >= start && m.PhysicalOff < start+length {
+			m.PhysicalOff -= start
+			m.Device = uint16(k + 1)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erofs
+
+import (
+	"bytes"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+)
+
+const testBlockSize = 4096
+
+// newTestImage returns an in-memory image of nblocks blocks with the given
+// incompatible features. The metadata area starts at block 1.
+func newTestImage(t *testing.T, nblocks int, featureIncompat uint32) *Image {
+	t.Helper()
+	i := &Image{
+		bytes: make([]byte, nblocks*testBlockSize),
+		sb: SuperBlock{
+			Magic:           SuperBlockMagicV1,
+			BlockSizeBits:   12,
+			Blocks:          uint32(nblocks),
+			MetaBlockAddr:   1,
+			FeatureIncompat: featureIncompat,
+		},
+	}
+	i.sb.MarshalUnsafe(i.bytes[SuperBlockOffset:])
+	if err := i.initSuperBlock(); err != nil {
+		t.Fatalf("initSuperBlock failed: %v", err)
+	}
+	return i
+}
+
+// putInode writes a compact regular inode with nid and returns the offset
+// following it.
+func putInode(i *Image, nid uint64, dataLayout uint16, size uint32, rawBlockAddr uint32) uint64 {
+	ino := InodeCompact{
+		Format:       InodeLayoutCompact | dataLayout<<InodeDataLayoutBit,
+		Mode:         linux.S_IFREG | 0644,
+		Nlink:        1,
+		Size:         size,
+		RawBlockAddr: rawBlockAddr,
+	}
+	off := i.sb.NidToOffset(nid)
+	ino.MarshalUnsafe(i.bytes[off:])
+	return off + InodeCompactSize
+}
+
+// blockBytes returns the bytes of the block addr of the image.
+func blockBytes(i *Image, addr uint32) []byte {
+	off := i.sb.BlockAddrToOffset(addr)
+	return i.bytes[off : off+testBlockSize]
+}
+
+func readExtent(t *testing.T, inode *Inode, off uint64) (Map, []byte) {
+	t.Helper()
+	m, err := inode.MapBlocks(off)
+	if err != nil {
+		t.Fatalf("MapBlocks(%#x) failed: %v", off, err)
+	}
+	if off < m.LogicalOff || off >= m.LogicalOff+m.LogicalLen {
+		t.Fatalf("MapBlocks(%#x) got extent [%#x, %#x)", off, m.LogicalOff, m.LogicalOff+m.LogicalLen)
+	}
+	data := make([]byte, m.LogicalLen)
+	if err := inode.ReadExtent(&m, data); err != nil {
+		t.Fatalf("ReadExtent failed: %v", err)
+	}
+	return m, data
+}
+
+func TestChunkIndexes(t *testing.T) {
+	image := newTestImage(t, 8, FeatureIncompatChunkedFile)
+
+	// The file consists of 4 block-sized chunks, and the second one is a
+	// hole.
+	const size = 3*testBlockSize + 100
+	end := putInode(image, 0, InodeDataLayoutChunkBased, size, ChunkFormatIndexes)
+	blockAddrs := []uint32{2, NullAddr, 3, 4}
+	for k, addr := range blockAddrs {
+		idx := ChunkIndex{BlockAddr: addr}
+		idx.MarshalUnsafe(image.bytes[roundUp(end, ChunkIndexSize)+uint64(k)*ChunkIndexSize:])
+		if addr != NullAddr {
+			copy(blockBytes(image, addr), bytes.Repeat([]byte{byte('a' + k)}, testBlockSize))
+		}
+	}
+
+	inode, err := image.Inode(0)
+	if err != nil {
+		t.Fatalf("Inode failed: %v", err)
+	}
+	for k, addr := range blockAddrs {
+		off := uint64(k)*testBlockSize + 50
+		m, data := readExtent(t, &inode, off)
+		if m.LogicalOff != uint64(k)*testBlockSize || m.LogicalLen != testBlockSize {
+			t.Errorf("chunk %d: got extent [%#x, +%#x)", k, m.LogicalOff, m.LogicalLen)
+		}
+		want := bytes.Repeat([]byte{byte('a' + k)}, testBlockSize)
+		if addr == NullAddr {
+			if m.Mapped() {
+				t.Errorf("chunk %d: hole is mapped", k)
+			}
+			want = make([]byte, testBlockSize)
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("chunk %d: got unexpected data", k)
+		}
+	}
+}
+
+func TestChunkBlockMap(t *testing.T) {
+	image := newTestImage(t, 8, FeatureIncompatChunkedFile)
+
+	// Use 2-block chunks.
+	const size = 3 * testBlockSize
+	end := putInode(image, 0, InodeDataLayoutChunkBased, size, 1)
+	pos := roundUp(end, BlockMapEntrySize)
+	for k, addr := range []uint32{4, 2} {
+		image.bytes[pos+uint64(k)*BlockMapEntrySize] = byte(addr)
+	}
+	copy(blockBytes(image, 2), bytes.Repeat([]byte{'x'}, testBlockSize))
+	copy(blockBytes(image, 4), bytes.Repeat([]byte{'y'}, testBlockSize))
+
+	inode, err := image.Inode(0)
+	if err != nil {
+		t.Fatalf("Inode failed: %v", err)
+	}
+	m, data := readExtent(t, &inode, 2*testBlockSize)
+	if m.LogicalOff != 2*testBlockSize || m.LogicalLen != testBlockSize || m.PhysicalOff != 2*testBlockSize {
+		t.Errorf("got unexpected extent %+v", m)
+	}
+	if !bytes.Equal(data, bytes.Repeat([]byte{'x'}, testBlockSize)) {
+		t.Errorf("got unexpected data")
+	}
+}
+
+func TestFullIndexes(t *testing.T) {
+	image := newTestImage(t, 8, FeatureIncompatZeroPadding)
+
+	// The file consists of an LZ4-compressed extent [0, 6000) stored in
+	// block 5, and an uncompressed extent [6000, 9000) stored in block 6.
+	const size = 9000
+	end := putInode(image, 0, InodeDataLayoutFlatCompressionLegacy, size, 0)
+	h := MapHeader{AlgorithmType: CompressionLZ4}
+	h.MarshalUnsafe(image.bytes[roundUp(end, 8):])
+	indexes := []LclusterIndex{
+		{Advise: LclusterTypeHead1, ClusterOfs: 0, BlockAddr: 5},
+		{Advise: LclusterTypePlain, ClusterOfs: 6000 - testBlockSize, BlockAddr: 6},
+		// delta[0] = 1, delta[1] = 1.
+		{Advise: LclusterTypeNonHead, BlockAddr: 1<<16 | 1},
+	}
+	for k := range indexes {
+		indexes[k].MarshalUnsafe(image.bytes[roundUp(end, 8)+MapHeaderSize+8+uint64(k)*LclusterIndexSize:])
+	}
+
+	want := append(bytes.Repeat([]byte("abcd"), 1499), []byte("WXYZ")...)
+	compressed := append(lz4Sequence([]byte("abcd"), 4, 5992), lz4Sequence([]byte("WXYZ"), 0, 0)...)
+	copy(blockBytes(image, 5)[testBlockSize-len(compressed):], compressed)
+	plain := blockBytes(image, 6)
+	for k := range plain {
+		plain[k] = byte(k)
+	}
+	want = append(want, plain[:size-6000]...)
+
+	inode, err := image.Inode(0)
+	if err != nil {
+		t.Fatalf("Inode failed: %v", err)
+	}
+	for _, tc := range []struct {
+		off       uint64
+		la        uint64
+		llen      uint64
+		algorithm uint8
+	}{
+		{off: 0, la: 0, llen: 6000, algorithm: CompressionLZ4},
+		{off: 5000, la: 0, llen: 6000, algorithm: CompressionLZ4},
+		{off: 7000, la: 6000, llen: 3000, algorithm: CompressionShifted},
+		{off: 8500, la: 6000, llen: 3000, algorithm: CompressionShifted},
+	} {
+		m, data := readExtent(t, &inode, tc.off)
+		if m.LogicalOff != tc.la || m.LogicalLen != tc.llen || m.Algorithm != tc.algorithm {
+			t.Errorf("MapBlocks(%d) got extent [%d, +%d) with algorithm %d, want [%d, +%d) with algorithm %d", tc.off, m.LogicalOff, m.LogicalLen, m.Algorithm, tc.la, tc.llen, tc.algorithm)
+			continue
+		}
+		if !bytes.Equal(data, want[tc.la:tc.la+tc.llen]) {
+			t.Errorf("MapBlocks(%d) got unexpected data", tc.off)
+		}
+	}
+}
+
+func TestOversizedExtent(t *testing.T) {
+	image := newTestImage(t, 8, FeatureIncompatZeroPadding)
+
+	// The first extent of the file spans 4001 lclusters, which decompress
+	// to more than PclusterMaxDecompressedSize bytes.
+	const size = 4002 * testBlockSize
+	end := putInode(image, 0, InodeDataLayoutFlatCompressionLegacy, size, 0)
+	h := MapHeader{AlgorithmType: CompressionLZ4}
+	h.MarshalUnsafe(image.bytes[roundUp(end, 8):])
+	indexes := []LclusterIndex{
+		{Advise: LclusterTypeHead1, ClusterOfs: 0, BlockAddr: 5},
+		// delta[0] = 1, delta[1] = 4000.
+		{Advise: LclusterTypeNonHead, BlockAddr: 4000<<16 | 1},
+	}
+	for k := range indexes {
+		indexes[k].MarshalUnsafe(image.bytes[roundUp(end, 8)+MapHeaderSize+8+uint64(k)*LclusterIndexSize:])
+	}
+
+	inode, err := image.Inode(0)
+	if err != nil {
+		t.Fatalf("Inode failed: %v", err)
+	}
+	if m, err := inode.MapBlocks(0); !linuxerr.Equals(linuxerr.ENOTSUP, err) {
+		t.Errorf("MapBlocks(0) got extent [%d, +%d) and error %v, want ENOTSUP", m.LogicalOff, m.LogicalLen, err)
+	}
+}
+
+func TestCompactIndexes(t *testing.T) {
+	image := newTestImage(t, 8, FeatureIncompatZeroPadding)
+
+	// The file consists of two LZ4-compressed extents [0, 8292) and
+	// [8292, 12788) stored in block 5 and 6 respectively.
+	const size = 3*testBlockSize + 500
+	end := putInode(image, 0, InodeDataLayoutFlatCompression, size, 0)
+	h := MapHeader{AlgorithmType: CompressionLZ4}
+	h.MarshalUnsafe(image.bytes[roundUp(end, 8):])
+
+	// The index starts with 4-byte units. Each pack of 2 lclusters holds
+	// two 16-bit lclusters (12-bit value and 2-bit type), followed by the
+	// block address preceding the first pcluster of the pack. The last
+	// NONHEAD lcluster of a pack records delta[1].
+	packs := [][]byte{
+		{0x00, LclusterTypeHead1 << 4, 0x01, LclusterTypeNonHead << 4, 4, 0, 0, 0},
+		{100, LclusterTypeHead1 << 4, 0x01, LclusterTypeNonHead << 4, 5, 0, 0, 0},
+	}
+	for k, pack := range packs {
+		copy(image.bytes[roundUp(end, 8)+MapHeaderSize+uint64(k)*8:], pack)
+	}
+
+	extents := []struct {
+		lit, last []byte
+		length    int
+		addr      uint32
+	}{
+		{lit: []byte("abcd"), last: []byte("WXYZ"), length: 8292, addr: 5},
+		{lit: []byte("efgh"), last: []byte("IJKL"), length: 4496, addr: 6},
+	}
+	var want []byte
+	for _, e := range extents {
+		matchLen := e.length - len(e.lit) - len(e.last)
+		compressed := append(lz4Sequence(e.lit, len(e.lit), matchLen), lz4Sequence(e.last, 0, 0)...)
+		copy(blockBytes(image, e.addr)[testBlockSize-len(compressed):], compressed)
+		want = append(want, bytes.Repeat(e.lit, (len(e.lit)+matchLen)/len(e.lit))...)
+		want = append(want, e.last...)
+	}
+
+	inode, err := image.Inode(0)
+	if err != nil {
+		t.Fatalf("Inode failed: %v", err)
+	}
+	for _, tc := range []struct {
+		off  uint64
+		la   uint64
+		llen uint64
+		pa   uint64
+	}{
+		{off: 0, la: 0, llen: 8292, pa: 5 * testBlockSize},
+		{off: 5000, la: 0, llen: 8292, pa: 5 * testBlockSize},
+		{off: 8200, la: 0, llen: 8292, pa: 5 * testBlockSize},
+		{off: 9000, la: 8292, llen: 4496, pa: 6 * testBlockSize},
+		{off: 12000, la: 8292, llen: 4496, pa: 6 * testBlockSize},
+	} {
+		m, data := readExtent(t, &inode, tc.off)
+		if m.LogicalOff != tc.la || m.LogicalLen != tc.llen || m.PhysicalOff != tc.pa {
+			t.Errorf("MapBlocks(%d) got extent [%d, +%d) at %#x, want [%d, +%d) at %#x", tc.off, m.LogicalOff, m.LogicalLen, m.PhysicalOff, tc.la, tc.llen, tc.pa)
+			continue
+		}
+		if !bytes.Equal(data, want[tc.la:tc.la+tc.llen]) {
+			t.Errorf("MapBlocks(%d) got unexpected data", tc.off)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erofs
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/log"
+)
+
+// Xattr name indexes, which identify the prefixes of xattr names.
+const (
+	XattrIndexNone = iota
+	XattrIndexUser
+	XattrIndexPosixACLAccess
+	XattrIndexPosixACLDefault
+	XattrIndexTrusted
+	XattrIndexLustre
+	XattrIndexSecurity
+	XattrIndexMax
+)
+
+// xattrPrefixes maps xattr name indexes to the name prefixes.
+var xattrPrefixes = [XattrIndexMax]string{
+	XattrIndexNone:            "",
+	XattrIndexUser:            linux.XATTR_USER_PREFIX,
+	XattrIndexPosixACLAccess:  linux.XATTR_SYSTEM_PREFIX + "posix_acl_access",
+	XattrIndexPosixACLDefault: linux.XATTR_SYSTEM_PREFIX + "posix_acl_default",
+	XattrIndexTrusted:         linux.XATTR_TRUSTED_PREFIX,
+	XattrIndexLustre:          "lustre.",
+	XattrIndexSecurity:        linux.XATTR_SECURITY_PREFIX,
+}
+
+// XattrNameIndex splits the xattr name into the name index and the suffix
+// stored on disk.
+func XattrNameIndex(name string) (uint8, string) {
+	// Skip XattrIndexNone which matches any name.
+	for idx := XattrIndexMax - 1; idx > XattrIndexNone; idx-- {
+		if prefix := xattrPrefixes[idx]; strings.HasPrefix(name, prefix) {
+			return uint8(idx), name[len(prefix):]
+		}
+	}
+	return XattrIndexNone, name
+}
+
+// xattrIbodySize returns the size of the inline xattrs with the given xattr
+// count recorded in the on-disk inode.
+func xattrIbodySize(count uint16) uint64 {
+	if count == 0 {
+		return 0
+	}
+	return XattrIbodyHeaderSize + uint64(count-1)*XattrEntrySize
+}
+
+// XattrIbodyCount returns the xattr count recorded in the on-disk inode for
+// inline xattrs of the given size.
+//
+// Precondition: size >= XattrIbodyHeaderSize and size is a multiple of
+// XattrEntrySize.
+func XattrIbodyCount(size uint64) uint16 {
+	return uint16((size-XattrIbodyHeaderSize)/XattrEntrySize + 1)
+}
+
+// IterXattrs invokes cb on each xattr of this inode. The shared xattrs are
+// iterated before the inline ones. Iteration stops if cb returns an error.
+func (i *Inode) IterXattrs(cb func(name string, value []byte) error) error {
+	if i.xattrSize == 0 {
+		return nil
+	}
+	var h XattrIbodyHeader
+	if i.xattrSize < XattrIbodyHeaderSize {
+		log.Warningf("Invalid xattr size %d at inode (nid=%v)", i.xattrSize, i.Nid())
+		return linuxerr.EUCLEAN
+	}
+	if err := i.image.unmarshalAt(&h, i.xattrOff); err != nil {
+		return err
+	}
+	off := i.xattrOff + XattrIbodyHeaderSize
+	end := i.xattrOff + i.xattrSize
+	if off+uint64(h.SharedCount)*4 > end {
+		log.Warningf("Invalid shared xattr count %d at inode (nid=%v)", h.SharedCount, i.Nid())
+		return linuxerr.EUCLEAN
+	}
+
+	// Shared xattrs are referenced by their IDs, which are the offsets in
+	// units of 4 bytes in the xattr area of the image.
+	xattrBase := i.image.sb.BlockAddrToOffset(i.image.sb.XattrBlockAddr)
+	for k := 0; k < int(h.SharedCount); k++ {
+		b, err := i.image.BytesAt(off, 4)
+		if err != nil {
+			return err
+		}
+		off += 4
+		id := binary.LittleEndian.Uint32(b)
+		if _, err := i.xattrAt(xattrBase+uint64(id)*4, ^uint64(0), cb); err != nil {
+			return err
+		}
+	}
+
+	for off < end {
+		n, err := i.xattrAt(off, end, cb)
+		if err != nil {
+			return err
+		}
+		off += roundUp(n, XattrEntrySize)
+	}
+	return nil
+}
+
+// xattrAt invokes cb on the xattr entry at off, which must not go beyond
+// end. It returns the size of the entry.
+func (i *Inode) xattrAt(off, end uint64, cb func(name string, value []byte) error) (uint64, error) {
+	var e XattrEntry
+	if err := i.image.unmarshalAt(&e, off); err != nil {
+		return 0, err
+	}
+	size := XattrEntrySize + uint64(e.NameLen) + uint64(e.ValueSize)
+	if off+size > end {
+		log.Warningf("Xattr entry at 0x%x goes beyond 0x%x at inode (nid=%v)", off, end, i.Nid())
+		return 0, linuxerr.EUCLEAN
+	}
+	b, err := i.image.BytesAt(off+XattrEntrySize, size-XattrEntrySize)
+	if err != nil {
+		return 0, err
+	}
+	if int(e.NameIndex) >= len(xattrPrefixes) {
+		// Like Linux, skip the xattrs we don't know how to name, e.g. the
+		// ones with long name prefixes.
+		return size, nil
+	}
+	name := xattrPrefixes[e.NameIndex] + string(b[:e.NameLen])
+	if err := cb(name, b[e.NameLen:]); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// Xattr returns the value of the xattr identified by name.
+func (i *Inode) Xattr(name string) ([]byte, error) {
+	var value []byte
+	err := i.IterXattrs(func(n string, v []byte) error {
+		if n != name {
+			return nil
+		}
+		value = v
+		return errXattrFound
+	})
+	switch err {
+	case errXattrFound:
+		return value, nil
+	case nil:
+		return nil, linuxerr.ENODATA
+	default:
+		return nil, err
+	}
+}
+
+// errXattrFound is used to stop the iteration of xattrs in Xattr.
+var errXattrFound = errors.New("xattr found")
@@ -0,0 +1,512 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erofs
+
+import (
+	"encoding/binary"
+
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/log"
+)
+
+// The data of compressed inodes is split into fixed-size logical clusters
+// (lclusters), which are described by an index following the on-disk inode.
+// A HEAD (or PLAIN) lcluster starts a new extent at its cluster offset, and
+// the extent is stored in a physical cluster (pcluster) whose block address
+// is recorded in the index. NONHEAD lclusters record the distances to the
+// HEAD lcluster of their extent (delta[0]) and to the next HEAD lcluster
+// (delta[1]).
+//
+// The index is either the full index, which uses 8 bytes for each lcluster,
+// or the compact index, which packs multiple lclusters into 4-byte or 2-byte
+// amortized units.
+//
+// Refer: https://docs.kernel.org/filesystems/erofs.html#data-compression
+
+// initCompression initializes the compression information of this inode. See
+// Linux's fs/erofs/zmap.c:z_erofs_fill_inode_lazy().
+func (i *Inode) initCompression() error {
+	var h MapHeader
+	if err := i.image.unmarshalAt(&h, roundUp(i.metaEnd, 8)); err != nil {
+		return err
+	}
+	if h.ClusterBits>>MapHeaderFragmentInodeBit != 0 || h.Advise&AdviseFragmentPcluster != 0 {
+		log.Warningf("Unsupported fragments at inode (nid=%v)", i.Nid())
+		return linuxerr.ENOTSUP
+	}
+
+	i.zAdvise = h.Advise
+	i.zAlgorithms = [2]uint8{h.AlgorithmType & 0xf, h.AlgorithmType >> 4}
+	if i.zAlgorithms[0] >= CompressionMax || i.zAlgorithms[1] >= CompressionMax {
+		log.Warningf("Unknown compression algorithms 0x%x at inode (nid=%v)", h.AlgorithmType, i.Nid())
+		return linuxerr.ENOTSUP
+	}
+	i.zLclusterBits = i.image.sb.BlockSizeBits + (h.ClusterBits & 7)
+	if 1<<i.zLclusterBits > PclusterMaxSize {
+		log.Warningf("Unsupported lcluster size %d at inode (nid=%v)", 1<<i.zLclusterBits, i.Nid())
+		return linuxerr.ENOTSUP
+	}
+
+	bigPcluster := i.zAdvise & (AdviseBigPcluster1 | AdviseBigPcluster2)
+	if bigPcluster != 0 && i.image.sb.FeatureIncompat&FeatureIncompatBigPcluster == 0 {
+		log.Warningf("Big pcluster found in an image without big pcluster feature (nid=%v)", i.Nid())
+		return linuxerr.EUCLEAN
+	}
+	if i.DataLayout() == InodeDataLayoutFlatCompression && bigPcluster != 0 && bigPcluster != AdviseBigPcluster1|AdviseBigPcluster2 {
+		log.Warningf("Big pcluster head1/2 of compact indexes should be consistent (nid=%v)", i.Nid())
+		return linuxerr.EUCLEAN
+	}
+
+	if i.zAdvise&AdviseInlinePcluster != 0 && i.size != 0 {
+		i.zIdataSize = h.IdataSize
+		m, err := i.mapCompressed(i.size-1, true /* findTail */)
+		if err != nil {
+			return err
+		}
+		blockSize := uint64(i.image.BlockSize())
+		if m.PhysicalLen == 0 || m.PhysicalOff&(blockSize-1)+m.PhysicalLen > blockSize {
+			log.Warningf("Invalid inline pcluster at inode (nid=%v)", i.Nid())
+			return linuxerr.EUCLEAN
+		}
+	}
+	return nil
+}
+
+// mapRecorder records the information gathered while walking the index of a
+// compressed inode. It's the counterpart of Linux's struct
+// z_erofs_maprecorder.
+type mapRecorder struct {
+	inode *Inode
+
+	// lcn is the number of the most recently loaded lcluster.
+	lcn uint64
+
+	// typ is the type of the lcluster lcn, and headType is the type of the
+	// HEAD lcluster of the extent.
+	typ      uint8
+	headType uint8
+
+	clusterOfs     uint32
+	delta          [2]uint16
+	pblk           uint32
+	compressedBlks uint32
+	nextPackOff    uint64
+	partialRef     bool
+}
+
+// load loads the index of the lcluster lcn.
+func (m *mapRecorder) load(lcn uint64, lookahead bool) error {
+	if m.inode.DataLayout() == InodeDataLayoutFlatCompressionLegacy {
+		return m.loadFull(lcn)
+	}
+	return m.loadCompact(lcn, lookahead)
+}
+
+// loadFull loads the full index of the lcluster lcn.
+func (m *mapRecorder) loadFull(lcn uint64) error {
+	i := m.inode
+	pos := roundUp(i.metaEnd, 8) + MapHeaderSize + 8 + lcn*LclusterIndexSize
+	var di LclusterIndex
+	if err := i.image.unmarshalAt(&di, pos); err != nil {
+		return err
+	}
+	m.nextPackOff = pos + LclusterIndexSize
+	m.lcn = lcn
+	m.typ = di.Type()
+	if m.typ == LclusterTypeNonHead {
+		m.clusterOfs = 1 << i.zLclusterBits
+		m.delta[0] = di.Delta(0)
+		if m.delta[0]&LclusterD0CompressedBlocks != 0 {
+			if i.zAdvise&(AdviseBigPcluster1|AdviseBigPcluster2) == 0 {
+				log.Warningf("Unexpected compressed block count at inode (nid=%v, lcn=%v)", i.Nid(), lcn)
+				return linuxerr.EUCLEAN
+			}
+			m.compressedBlks = uint32(m.delta[0] &^ LclusterD0CompressedBlocks)
+			m.delta[0] = 1
+		}
+		m.delta[1] = di.Delta(1)
+		return nil
+	}
+	m.partialRef = di.Advise&LclusterPartialRef != 0
+	m.clusterOfs = uint32(di.ClusterOfs)
+	if m.clusterOfs >= 1<<i.zLclusterBits {
+		log.Warningf("Invalid cluster offset %v at inode (nid=%v, lcn=%v)", m.clusterOfs, i.Nid(), lcn)
+		return linuxerr.EUCLEAN
+	}
+	m.pblk = di.BlockAddr
+	return nil
+}
+
+// loadCompact loads the compact index of the lcluster lcn.
+//
+// The compact index starts with 4-byte units until it's aligned to 32 bytes,
+// followed by 2-byte units (if AdviseCompacted2B is set) in multiples of 16
+// lclusters, and then 4-byte units for the remaining lclusters.
+func (m *mapRecorder) loadCompact(lcn uint64, lookahead bool) error {
+	i := m.inode
+	totalIdx := i.blocks
+	if lcn >= totalIdx || i.zLclusterBits > 14 {
+		log.Warningf("Invalid lcluster %v at inode (nid=%v)", lcn, i.Nid())
+		return linuxerr.EUCLEAN
+	}
+	m.lcn = lcn
+
+	ebase := roundUp(i.metaEnd, 8) + MapHeaderSize
+	compacted4BInitial := ((32 - ebase%32) / 4) % 8
+	var compacted2B uint64
+	if i.zAdvise&AdviseCompacted2B != 0 && compacted4BInitial < totalIdx {
+		compacted2B = (totalIdx - compacted4BInitial) &^ 15
+	}
+
+	pos := ebase
+	var amortizedShift uint
+	switch {
+	case lcn < compacted4BInitial:
+		amortizedShift = 2
+	case lcn-compacted4BInitial < compacted2B:
+		pos += compacted4BInitial * 4
+		lcn -= compacted4BInitial
+		amortizedShift = 1
+	default:
+		pos += compacted4BInitial*4 + compacted2B*2
+		lcn -= compacted4BInitial + compacted2B
+		amortizedShift = 2
+	}
+	pos += lcn << amortizedShift
+	return m.unpackCompact(amortizedShift, pos, lookahead)
+}
+
+// decodeCompactedBits decodes the lcluster at bit pos of the pack in.
+func decodeCompactedBits(lobits uint, in []byte, pos uint) (uint32, uint8) {
+	v := binary.LittleEndian.Uint32(in[pos/8:]) >> (pos & 7)
+	return v & (1<<lobits - 1), uint8(v>>lobits) & LclusterTypeMask
+}
+
+// getCompactedLookaheadDistance returns the distance from the NONHEAD
+// lcluster idx of the pack in to the next HEAD lcluster.
+func getCompactedLookaheadDistance(lobits, encodeBits uint, vcnt int, in []byte, idx int) uint16 {
+	var (
+		lo uint32
+		d1 uint32
+	)
+	for ; idx < vcnt; idx++ {
+		var typ uint8
+		lo, typ = decodeCompactedBits(lobits, in, encodeBits*uint(idx))
+		if typ != LclusterTypeNonHead {
+			return uint16(d1)
+		}
+		d1++
+	}
+	// The last lcluster of the pack records delta[1] rather than delta[0].
+	if lo&LclusterD0CompressedBlocks == 0 {
+		d1 += lo - 1
+	}
+	return uint16(d1)
+}
+
+// unpackCompact unpacks the lcluster at pos of a pack of compact index. See
+// Linux's fs/erofs/zmap.c:unpack_compacted_index().
+func (m *mapRecorder) unpackCompact(amortizedShift uint, pos uint64, lookahead bool) error {
+	i := m.inode
+	lclusterBits := uint(i.zLclusterBits)
+	var vcnt int
+	switch {
+	case amortizedShift == 2 && lclusterBits <= 14:
+		vcnt = 2
+	case amortizedShift == 1 && lclusterBits <= 12:
+		vcnt = 16
+	default:
+		log.Warningf("Unsupported compact index at inode (nid=%v)", i.Nid())
+		return linuxerr.ENOTSUP
+	}
+
+	packSize := uint64(vcnt) << amortizedShift
+	base := pos &^ (packSize - 1)
+	m.nextPackOff = base + packSize
+	in, err := i.image.BytesAt(base, packSize)
+	if err != nil {
+		return err
+	}
+	bigPcluster := i.zAdvise&AdviseBigPcluster1 != 0
+	lobits := max(lclusterBits, 12)
+	encodeBits := uint((packSize - 4) * 8 / uint64(vcnt))
+	idx := int((pos - base) >> amortizedShift)
+
+	lo, typ := decodeCompactedBits(lobits, in, encodeBits*uint(idx))
+	m.typ = typ
+	if typ == LclusterTypeNonHead {
+		m.clusterOfs = 1 << lclusterBits
+		if lookahead {
+			m.delta[1] = getCompactedLookaheadDistance(lobits, encodeBits, vcnt, in, idx)
+		}
+		if lo&LclusterD0CompressedBlocks != 0 {
+			if !bigPcluster {
+				log.Warningf("Unexpected compressed block count at inode (nid=%v, lcn=%v)", i.Nid(), m.lcn)
+				return linuxerr.EUCLEAN
+			}
+			m.compressedBlks = lo &^ LclusterD0CompressedBlocks
+			m.delta[0] = 1
+			return nil
+		}
+		if idx+1 != vcnt {
+			m.delta[0] = uint16(lo)
+			return nil
+		}
+		// The last lcluster of the pack records delta[1] rather than
+		// delta[0], so get delta[0] from the previous lcluster indirectly.
+		lo, typ = decodeCompactedBits(lobits, in, encodeBits*uint(idx-1))
+		if typ != LclusterTypeNonHead {
+			lo = 0
+		} else if lo&LclusterD0CompressedBlocks != 0 {
+			lo = 1
+		}
+		m.delta[0] = uint16(lo + 1)
+		return nil
+	}
+
+	m.clusterOfs = lo
+	m.delta[0] = 0
+	// Figure out the block address of the HEAD lcluster by counting the
+	// blocks of the preceding pclusters in this pack.
+	var nblk uint32
+	if !bigPcluster {
+		nblk = 1
+		for idx > 0 {
+			idx--
+			lo, typ = decodeCompactedBits(lobits, in, encodeBits*uint(idx))
+			if typ == LclusterTypeNonHead {
+				idx -= int(lo)
+			}
+			if idx >= 0 {
+				nblk++
+			}
+		}
+	} else {
+		for idx > 0 {
+			idx--
+			lo, typ = decodeCompactedBits(lobits, in, encodeBits*uint(idx))
+			if typ == LclusterTypeNonHead {
+				if lo&LclusterD0CompressedBlocks != 0 {
+					idx--
+					nblk += lo &^ LclusterD0CompressedBlocks
+					continue
+				}
+				// Big pclusters shouldn't have plain delta[0] == 1.
+				if lo <= 1 {
+					log.Warningf("Invalid delta[0] at inode (nid=%v, lcn=%v)", i.Nid(), m.lcn)
+					return linuxerr.EUCLEAN
+				}
+				idx -= int(lo) - 2
+				continue
+			}
+			nblk++
+		}
+	}
+	m.pblk = binary.LittleEndian.Uint32(in[packSize-4:]) + nblk
+	return nil
+}
+
+// lookback walks back from the lcluster m.lcn by distance lclusters to find
+// the HEAD lcluster of the extent, and records the start of the extent in em.
+func (m *mapRecorder) lookback(em *Map, distance uint64) error {
+	for m.lcn >= distance {
+		lcn := m.lcn - distance
+		if err := m.load(lcn, false /* lookahead */); err != nil {
+			return err
+		}
+		if m.typ == LclusterTypeNonHead {
+			distance = uint64(m.delta[0])
+			if distance == 0 {
+				break
+			}
+			continue
+		}
+		m.headType = m.typ
+		em.LogicalOff = lcn<<m.inode.zLclusterBits | uint64(m.clusterOfs)
+		return nil
+	}
+	log.Warningf("Bogus lookback distance at inode (nid=%v, lcn=%v)", m.inode.Nid(), m.lcn)
+	return linuxerr.EUCLEAN
+}
+
+// compressedLen records the length of the pcluster of the extent in em.
+func (m *mapRecorder) compressedLen(em *Map) error {
+	i := m.inode
+	if m.headType == LclusterTypePlain ||
+		(m.headType == LclusterTypeHead1 && i.zAdvise&AdviseBigPcluster1 == 0) ||
+		(m.headType == LclusterTypeHead2 && i.zAdvise&AdviseBigPcluster2 == 0) {
+		em.PhysicalLen = 1 << i.zLclusterBits
+		return nil
+	}
+	if m.compressedBlks == 0 {
+		// The count of compressed blocks of a big pcluster is recorded in
+		// the first NONHEAD lcluster.
+		if err := m.load(m.lcn+1, false /* lookahead */); err != nil {
+			return err
+		}
+		switch m.typ {
+		case LclusterTypeNonHead:
+			if m.delta[0] != 1 || m.compressedBlks == 0 {
+				log.Warningf("Bogus compressed block count at inode (nid=%v, lcn=%v)", i.Nid(), m.lcn)
+				return linuxerr.EUCLEAN
+			}
+		default:
+			// A HEAD or PLAIN lcluster follows, so the pcluster is
+			// lcluster-sized.
+			m.compressedBlks = 1 << (i.zLclusterBits - i.image.sb.BlockSizeBits)
+		}
+	}
+	em.PhysicalLen = i.image.sb.BlockAddrToOffset(m.compressedBlks)
+	if em.PhysicalLen > PclusterMaxSize {
+		log.Warningf("Unsupported pcluster size %d at inode (nid=%v, lcn=%v)", em.PhysicalLen, i.Nid(), m.lcn)
+		return linuxerr.ENOTSUP
+	}
+	return nil
+}
+
+// decompressedLen records the full length of the extent in em by walking
+// forward to the next HEAD lcluster.
+func (m *mapRecorder) decompressedLen(em *Map) error {
+	i := m.inode
+	lclusterBits := i.zLclusterBits
+	headLcn := em.LogicalOff >> lclusterBits
+	lcn := headLcn
+	for {
+		// The last extent of the file is ended by EOF.
+		if lcn<<lclusterBits >= i.size {
+			em.LogicalLen = i.size - em.LogicalOff
+			return nil
+		}
+		if err := m.load(lcn, true /* lookahead */); err != nil {
+			return err
+		}
+		if m.typ != LclusterTypeNonHead {
+			if lcn != headLcn {
+				break
+			}
+			m.delta[1] = 1
+		}
+		if m.delta[1] == 0 {
+			break
+		}
+		lcn += uint64(m.delta[1])
+		if (lcn-headLcn-1)<<lclusterBits >= PclusterMaxDecompressedSize {
+			// The extent is already known to be too long, so don't walk
+			// it any further.
+			log.Warningf("Unsupported decompressed extent length at inode (nid=%v, lcn=%v)", i.Nid(), headLcn)
+			return linuxerr.ENOTSUP
+		}
+	}
+	em.LogicalLen = lcn<<lclusterBits + uint64(m.clusterOfs) - em.LogicalOff
+	return nil
+}
+
+// mapCompressed returns the extent which contains the data at offset off of
+// this compressed inode. If findTail is true, the tail extent is mapped to
+// initialize the information of the inline pcluster instead. See Linux's
+// fs/erofs/zmap.c:z_erofs_do_map_blocks().
+func (i *Inode) mapCompressed(off uint64, findTail bool) (Map, error) {
+	m := mapRecorder{inode: i}
+	lclusterBits := i.zLclusterBits
+	ztailPacking := i.zAdvise&AdviseInlinePcluster != 0
+
+	initialLcn := off >> lclusterBits
+	endOff := uint32(off & (1<<lclusterBits - 1))
+	if err := m.load(initialLcn, false /* lookahead */); err != nil {
+		return Map{}, err
+	}
+	if ztailPacking && findTail {
+		i.zIdataOff = m.nextPackOff
+	}
+
+	em := Map{Flags: MapMapped | MapEncoded}
+	end := (m.lcn + 1) << lclusterBits
+	switch {
+	case m.typ != LclusterTypeNonHead && endOff >= m.clusterOfs:
+		m.headType = m.typ
+		em.LogicalOff = m.lcn<<lclusterBits | uint64(m.clusterOfs)
+		// The EOF lcluster of ztailpacking inodes can be shorter.
+		if ztailPacking && end > i.size {
+			end = i.size
+		}
+	case m.typ != LclusterTypeNonHead:
+		// off belongs to the extent of the previous HEAD lcluster.
+		if m.lcn == 0 {
+			log.Warningf("Invalid cluster offset %v of lcluster 0 at inode (nid=%v)", m.clusterOfs, i.Nid())
+			return Map{}, linuxerr.EUCLEAN
+		}
+		end = m.lcn<<lclusterBits | uint64(m.clusterOfs)
+		if err := m.lookback(&em, 1); err != nil {
+			return Map{}, err
+		}
+	default:
+		if err := m.lookback(&em, uint64(m.delta[0])); err != nil {
+			return Map{}, err
+		}
+	}
+	if m.partialRef {
+		em.Flags |= MapPartialRef
+	}
+	em.LogicalLen = end - em.LogicalOff
+
+	if findTail {
+		i.zTailHeadLcn = m.lcn
+	}
+	if ztailPacking && m.lcn == i.zTailHeadLcn {
+		em.Flags |= MapMeta
+		em.PhysicalOff = i.zIdataOff
+		em.PhysicalLen = uint64(i.zIdataSize)
+	} else {
+		em.PhysicalOff = i.image.sb.BlockAddrToOffset(m.pblk)
+		if err := m.compressedLen(&em); err != nil {
+			return Map{}, err
+		}
+		if err := i.image.mapDevice(&em, 0); err != nil {
+			return Map{}, err
+		}
+	}
+
+	switch m.headType {
+	case LclusterTypePlain:
+		em.Algorithm = CompressionShifted
+		if i.zAdvise&AdviseInterlacedPcluster != 0 {
+			em.Algorithm = CompressionInterlaced
+		}
+	case LclusterTypeHead2:
+		em.Algorithm = i.zAlgorithms[1]
+	default:
+		em.Algorithm = i.zAlgorithms[0]
+	}
+	if em.Algorithm < CompressionMax && i.image.comprAlgs&(1<<em.Algorithm) == 0 {
+		log.Warningf("Unavailable compression algorithm %v at inode (nid=%v)", em.Algorithm, i.Nid())
+		return Map{}, linuxerr.EUCLEAN
+	}
+
+	if !findTail {
+		// Always map the whole extent, so that it's decompressed at once.
+		if err := m.decompressedLen(&em); err != nil {
+			return Map{}, err
+		}
+		if em.LogicalLen > PclusterMaxDecompressedSize {
+			log.Warningf("Unsupported decompressed extent length %d at inode (nid=%v, lcn=%v)", em.LogicalLen, i.Nid(), m.lcn)
+			return Map{}, linuxerr.ENOTSUP
+		}
+	}
+	if m.headType == LclusterTypePlain && em.LogicalLen > em.PhysicalLen {
+		log.Warningf("Invalid plain pcluster at inode (nid=%v)", i.Nid())
+		return Map{}, linuxerr.EUCLEAN
+	}
+	return em, nil
+}
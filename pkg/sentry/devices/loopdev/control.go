@@ -0,0 +1,86 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loopdev
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// loopControlDevice implements vfs.Device for /dev/loop-control.
+//
+// +stateify savable
+type loopControlDevice struct {
+	// devs are the loop devices, indexed by minor number. devs is immutable.
+	devs []*loopDevice
+}
+
+// Open implements vfs.Device.Open.
+func (dev *loopControlDevice) Open(ctx context.Context, mnt *vfs.Mount, vfsd *vfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	fd := &loopControlFD{dev: dev}
+	if err := fd.vfsfd.Init(fd, opts.Flags, mnt, vfsd, &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+	}); err != nil {
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// loopControlFD implements vfs.FileDescriptionImpl for /dev/loop-control.
+//
+// +stateify savable
+type loopControlFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	dev *loopControlDevice
+}
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *loopControlFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	request := args[1].Uint()
+	switch request {
+	case linux.LOOP_CTL_GET_FREE:
+		for _, dev := range fd.dev.devs {
+			dev.mu.Lock()
+			free := dev.file == nil
+			dev.mu.Unlock()
+			if free {
+				return uintptr(dev.minor), nil
+			}
+		}
+		// Linux would create a new loop device here, but the set of loop
+		// devices is fixed.
+		return 0, linuxerr.ENOSPC
+
+	case linux.LOOP_CTL_ADD:
+		// Loop devices can't be created on demand, but LOOP_CTL_ADD for an
+		// existing device fails with EEXIST as in Linux.
+		idx := args[2].Int()
+		if idx >= 0 && int(idx) < len(fd.dev.devs) {
+			return 0, linuxerr.EEXIST
+		}
+		return 0, linuxerr.ENOSPC
+
+	default:
+		return 0, linuxerr.ENOTTY
+	}
+}
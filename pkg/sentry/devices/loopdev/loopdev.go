@@ -0,0 +1,560 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loopdev implements loop block devices (/dev/loop*) and
+// /dev/loop-control, as implemented in Linux by drivers/block/loop.c.
+//
+// A loop device exposes the contents of a backing file as a block device.
+// Unlike Linux, the backing file may be any vfs.FileDescription that supports
+// positional reads, and the set of loop devices is fixed when the devices are
+// registered.
+package loopdev
+
+import (
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+const (
+	// numLoopDevices is the number of loop devices registered by Register.
+	// This is the default value of Linux's CONFIG_BLK_DEV_LOOP_MIN_COUNT.
+	numLoopDevices = 8
+
+	// defaultBlockSize is the logical block size of a loop device if none is
+	// configured.
+	defaultBlockSize = 512
+)
+
+// loopDevice implements vfs.Device for /dev/loopN.
+//
+// +stateify savable
+type loopDevice struct {
+	// minor is the device's minor number, which is also its index. minor is
+	// immutable.
+	minor uint32
+
+	mu sync.Mutex `state:"nosave"`
+
+	// file is the backing file, or nil if the device is unbound. If file is
+	// not nil, the loopDevice holds a reference on it.
+	//
+	// +checklocks:mu
+	file *vfs.FileDescription
+
+	// offset is the offset into file at which the device's contents begin.
+	//
+	// +checklocks:mu
+	offset uint64
+
+	// sizelimit is the maximum size of the device in bytes, or 0 if the size
+	// of the device is only limited by the size of file.
+	//
+	// +checklocks:mu
+	sizelimit uint64
+
+	// flags is a bitmask of LO_FLAGS_*.
+	//
+	// +checklocks:mu
+	flags uint32
+
+	// blockSize is the device's logical block size.
+	//
+	// +checklocks:mu
+	blockSize uint32
+
+	// fileName is the name of the backing file reported by
+	// LOOP_GET_STATUS64.
+	//
+	// +checklocks:mu
+	fileName [linux.LO_NAME_SIZE]byte
+
+	// opens is the number of open file descriptions of the device.
+	//
+	// +checklocks:mu
+	opens int
+}
+
+// Open implements vfs.Device.Open.
+func (dev *loopDevice) Open(ctx context.Context, mnt *vfs.Mount, vfsd *vfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	fd := &loopFD{dev: dev}
+	if err := fd.vfsfd.Init(fd, opts.Flags, mnt, vfsd, &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+	}); err != nil {
+		return nil, err
+	}
+	dev.mu.Lock()
+	dev.opens++
+	dev.mu.Unlock()
+	return &fd.vfsfd, nil
+}
+
+// backingFile returns the device's backing file and the range of it exposed
+// by the device, or a nil file if the device is unbound. If a file is
+// returned, a reference is taken on it that must be dropped by the caller.
+func (dev *loopDevice) backingFile(ctx context.Context) (file *vfs.FileDescription, offset, size uint64, readOnly bool, err error) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	if dev.file == nil {
+		return nil, 0, 0, false, nil
+	}
+	size, err = dev.sizeLocked(ctx)
+	if err != nil {
+		return nil, 0, 0, false, err
+	}
+	dev.file.IncRef()
+	return dev.file, dev.offset, size, dev.flags&linux.LO_FLAGS_READ_ONLY != 0, nil
+}
+
+// sizeLocked returns the size of the device in bytes.
+//
+// +checklocks:dev.mu
+func (dev *loopDevice) sizeLocked(ctx context.Context) (uint64, error) {
+	if dev.file == nil {
+		return 0, nil
+	}
+	stat, err := dev.file.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_SIZE})
+	if err != nil {
+		return 0, err
+	}
+	if stat.Size <= dev.offset {
+		return 0, nil
+	}
+	size := stat.Size - dev.offset
+	if dev.sizelimit != 0 && dev.sizelimit < size {
+		size = dev.sizelimit
+	}
+	// Like Linux, only expose whole 512-byte sectors.
+	return size &^ (defaultBlockSize - 1), nil
+}
+
+// configMu serializes the binding of loop devices to backing files, so that
+// cycles of loop devices can't be created concurrently. configMu is analogous
+// to Linux's loop_validate_mutex, and is ordered before loopDevice.mu.
+var configMu sync.Mutex
+
+// bind binds the device to file, which must be a regular file or a block
+// device. On success, the device takes ownership of the caller's reference on
+// file.
+func (dev *loopDevice) bind(ctx context.Context, file *vfs.FileDescription, writable bool, cfg *linux.LoopConfig) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	// Refuse to create cycles of loop devices. The chain of backing files
+	// can't be extended while configMu is held.
+	for f := file; f != nil; {
+		lfd, ok := f.Impl().(*loopFD)
+		if !ok {
+			break
+		}
+		if lfd.dev == dev {
+			return linuxerr.EBUSY
+		}
+		lfd.dev.mu.Lock()
+		f = lfd.dev.file
+		lfd.dev.mu.Unlock()
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	return dev.bindLocked(ctx, file, writable, cfg)
+}
+
+// bindLocked implements bind.
+//
+// Preconditions: configMu must be locked.
+//
+// +checklocks:dev.mu
+func (dev *loopDevice) bindLocked(ctx context.Context, file *vfs.FileDescription, writable bool, cfg *linux.LoopConfig) error {
+	if dev.file != nil {
+		return linuxerr.EBUSY
+	}
+	stat, err := file.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_TYPE})
+	if err != nil {
+		return err
+	}
+	switch stat.Mode & linux.S_IFMT {
+	case linux.S_IFREG, linux.S_IFBLK:
+	default:
+		return linuxerr.EINVAL
+	}
+	blockSize := cfg.BlockSize
+	if blockSize == 0 {
+		blockSize = defaultBlockSize
+	} else if err := checkBlockSize(blockSize); err != nil {
+		return err
+	}
+	flags := cfg.Info.Flags & linux.LOOP_CONFIGURE_SETTABLE_FLAGS
+	if !writable || !file.IsWritable() {
+		flags |= linux.LO_FLAGS_READ_ONLY
+	}
+
+	dev.file = file
+	dev.offset = cfg.Info.Offset
+	dev.sizelimit = cfg.Info.Sizelimit
+	dev.flags = flags
+	dev.blockSize = blockSize
+	dev.fileName = cfg.Info.FileName
+	if dev.fileName == ([linux.LO_NAME_SIZE]byte{}) {
+		if root := vfs.RootFromContext(ctx); root.Ok() {
+			name, _ := file.VirtualDentry().Mount().Filesystem().VirtualFilesystem().PathnameWithDeleted(ctx, root, file.VirtualDentry())
+			root.DecRef(ctx)
+			// Leave room for a terminating NUL byte.
+			copy(dev.fileName[:len(dev.fileName)-1], name)
+		}
+	}
+	return nil
+}
+
+// clearLocked unbinds the device from its backing file.
+//
+// +checklocks:dev.mu
+func (dev *loopDevice) clearLocked(ctx context.Context) {
+	if dev.file == nil {
+		return
+	}
+	dev.file.DecRef(ctx)
+	dev.file = nil
+	dev.offset = 0
+	dev.sizelimit = 0
+	dev.flags = 0
+	dev.blockSize = 0
+	dev.fileName = [linux.LO_NAME_SIZE]byte{}
+}
+
+// statusLocked returns the device's status as reported by
+// LOOP_GET_STATUS64.
+//
+// +checklocks:dev.mu
+func (dev *loopDevice) statusLocked(ctx context.Context) (linux.LoopInfo64, error) {
+	if dev.file == nil {
+		return linux.LoopInfo64{}, linuxerr.ENXIO
+	}
+	stat, err := dev.file.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_INO})
+	if err != nil {
+		return linux.LoopInfo64{}, err
+	}
+	return linux.LoopInfo64{
+		Device:    uint64(linux.MakeDeviceID(uint16(stat.DevMajor), stat.DevMinor)),
+		Inode:     stat.Ino,
+		Rdevice:   uint64(linux.MakeDeviceID(uint16(stat.RdevMajor), stat.RdevMinor)),
+		Offset:    dev.offset,
+		Sizelimit: dev.sizelimit,
+		Number:    dev.minor,
+		Flags:     dev.flags,
+		FileName:  dev.fileName,
+	}, nil
+}
+
+// setStatusLocked implements LOOP_SET_STATUS64.
+//
+// +checklocks:dev.mu
+func (dev *loopDevice) setStatusLocked(info *linux.LoopInfo64) error {
+	if dev.file == nil {
+		return linuxerr.ENXIO
+	}
+	// Encryption was removed from Linux's loop driver in 5.12.
+	if info.EncryptType != 0 || info.EncryptKeySize != 0 {
+		return linuxerr.EINVAL
+	}
+	dev.offset = info.Offset
+	dev.sizelimit = info.Sizelimit
+	dev.flags = (dev.flags &^ linux.LOOP_SET_STATUS_SETTABLE_FLAGS) | (info.Flags & linux.LOOP_SET_STATUS_SETTABLE_FLAGS)
+	dev.fileName = info.FileName
+	dev.fileName[len(dev.fileName)-1] = 0
+	return nil
+}
+
+// checkBlockSize returns an error if size is not a valid logical block size.
+func checkBlockSize(size uint32) error {
+	if size < defaultBlockSize || size > hostarch.PageSize || size&(size-1) != 0 {
+		return linuxerr.EINVAL
+	}
+	return nil
+}
+
+// loopFD implements vfs.FileDescriptionImpl for /dev/loopN.
+//
+// +stateify savable
+type loopFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	dev *loopDevice
+
+	// offMu protects off.
+	offMu sync.Mutex `state:"nosave"`
+
+	// off is the file offset.
+	//
+	// +checklocks:offMu
+	off int64
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *loopFD) Release(ctx context.Context) {
+	dev := fd.dev
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	dev.opens--
+	if dev.opens == 0 && dev.flags&linux.LO_FLAGS_AUTOCLEAR != 0 {
+		dev.clearLocked(ctx)
+	}
+}
+
+// PRead implements vfs.FileDescriptionImpl.PRead.
+func (fd *loopFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	file, base, size, _, err := fd.dev.backingFile(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if file == nil {
+		return 0, nil
+	}
+	defer file.DecRef(ctx)
+	if uint64(offset) >= size {
+		return 0, nil
+	}
+	dst = dst.TakeFirst64(int64(size - uint64(offset)))
+	return file.PRead(ctx, dst, int64(base)+offset, opts)
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (fd *loopFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	fd.offMu.Lock()
+	n, err := fd.PRead(ctx, dst, fd.off, opts)
+	fd.off += n
+	fd.offMu.Unlock()
+	return n, err
+}
+
+// PWrite implements vfs.FileDescriptionImpl.PWrite.
+func (fd *loopFD) PWrite(ctx context.Context, src usermem.IOSequence, offset int64, opts vfs.WriteOptions) (int64, error) {
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	file, base, size, readOnly, err := fd.dev.backingFile(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if file == nil {
+		return 0, linuxerr.ENOSPC
+	}
+	defer file.DecRef(ctx)
+	if readOnly {
+		return 0, linuxerr.EPERM
+	}
+	if uint64(offset) >= size {
+		return 0, linuxerr.ENOSPC
+	}
+	src = src.TakeFirst64(int64(size - uint64(offset)))
+	return file.PWrite(ctx, src, int64(base)+offset, opts)
+}
+
+// Write implements vfs.FileDescriptionImpl.Write.
+func (fd *loopFD) Write(ctx context.Context, src usermem.IOSequence, opts vfs.WriteOptions) (int64, error) {
+	fd.offMu.Lock()
+	n, err := fd.PWrite(ctx, src, fd.off, opts)
+	fd.off += n
+	fd.offMu.Unlock()
+	return n, err
+}
+
+// Sync implements vfs.FileDescriptionImpl.Sync.
+func (fd *loopFD) Sync(ctx context.Context) error {
+	file, _, _, _, err := fd.dev.backingFile(ctx)
+	if err != nil || file == nil {
+		return err
+	}
+	defer file.DecRef(ctx)
+	return file.Sync(ctx)
+}
+
+// Seek implements vfs.FileDescriptionImpl.Seek.
+func (fd *loopFD) Seek(ctx context.Context, offset int64, whence int32) (int64, error) {
+	fd.offMu.Lock()
+	defer fd.offMu.Unlock()
+	switch whence {
+	case linux.SEEK_SET:
+		// use offset as specified
+	case linux.SEEK_CUR:
+		offset += fd.off
+	case linux.SEEK_END:
+		fd.dev.mu.Lock()
+		size, err := fd.dev.sizeLocked(ctx)
+		fd.dev.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(size)
+	default:
+		return 0, linuxerr.EINVAL
+	}
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	fd.off = offset
+	return offset, nil
+}
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *loopFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	request := args[1].Uint()
+	data := args[2].Pointer()
+
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		panic("Ioctl should be called from a task context")
+	}
+
+	dev := fd.dev
+	switch request {
+	case linux.LOOP_SET_FD:
+		file := t.GetFile(args[2].Int())
+		if file == nil {
+			return 0, linuxerr.EBADF
+		}
+		if err := dev.bind(ctx, file, fd.vfsfd.IsWritable(), &linux.LoopConfig{}); err != nil {
+			file.DecRef(ctx)
+			return 0, err
+		}
+		return 0, nil
+
+	case linux.LOOP_CONFIGURE:
+		var cfg linux.LoopConfig
+		if _, err := cfg.CopyIn(t, data); err != nil {
+			return 0, err
+		}
+		file := t.GetFile(int32(cfg.FD))
+		if file == nil {
+			return 0, linuxerr.EBADF
+		}
+		if err := dev.bind(ctx, file, fd.vfsfd.IsWritable(), &cfg); err != nil {
+			file.DecRef(ctx)
+			return 0, err
+		}
+		return 0, nil
+
+	case linux.LOOP_CLR_FD:
+		dev.mu.Lock()
+		defer dev.mu.Unlock()
+		if dev.file == nil {
+			return 0, linuxerr.ENXIO
+		}
+		if dev.opens > 1 {
+			// Like Linux, defer clearing the device until it is no longer
+			// open.
+			dev.flags |= linux.LO_FLAGS_AUTOCLEAR
+			return 0, nil
+		}
+		dev.clearLocked(ctx)
+		return 0, nil
+
+	case linux.LOOP_GET_STATUS64:
+		dev.mu.Lock()
+		info, err := dev.statusLocked(ctx)
+		dev.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+		_, err = info.CopyOut(t, data)
+		return 0, err
+
+	case linux.LOOP_SET_STATUS64:
+		var info linux.LoopInfo64
+		if _, err := info.CopyIn(t, data); err != nil {
+			return 0, err
+		}
+		dev.mu.Lock()
+		defer dev.mu.Unlock()
+		return 0, dev.setStatusLocked(&info)
+
+	case linux.LOOP_SET_CAPACITY:
+		// The size of the device is always derived from the current size of
+		// the backing file.
+		dev.mu.Lock()
+		defer dev.mu.Unlock()
+		if dev.file == nil {
+			return 0, linuxerr.ENXIO
+		}
+		return 0, nil
+
+	case linux.LOOP_SET_BLOCK_SIZE:
+		size := args[2].Uint()
+		if err := checkBlockSize(size); err != nil {
+			return 0, err
+		}
+		dev.mu.Lock()
+		defer dev.mu.Unlock()
+		if dev.file == nil {
+			return 0, linuxerr.ENXIO
+		}
+		dev.blockSize = size
+		return 0, nil
+
+	case linux.BLKSSZGET:
+		dev.mu.Lock()
+		size := dev.blockSize
+		dev.mu.Unlock()
+		if size == 0 {
+			size = defaultBlockSize
+		}
+		_, err := primitive.CopyInt32Out(t, data, int32(size))
+		return 0, err
+
+	case linux.BLKGETSIZE64:
+		dev.mu.Lock()
+		size, err := dev.sizeLocked(ctx)
+		dev.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+		_, err = primitive.CopyUint64Out(t, data, size)
+		return 0, err
+
+	default:
+		return 0, linuxerr.ENOTTY
+	}
+}
+
+// Register registers all devices implemented by this package in vfsObj.
+func Register(vfsObj *vfs.VirtualFilesystem) error {
+	devs := make([]*loopDevice, numLoopDevices)
+	for minor := range devs {
+		devs[minor] = &loopDevice{minor: uint32(minor)}
+		if err := vfsObj.RegisterDevice(vfs.BlockDevice, linux.LOOP_MAJOR, uint32(minor), devs[minor], &vfs.RegisterDeviceOptions{
+			GroupName: "loop",
+			Pathname:  fmt.Sprintf("loop%d", minor),
+			FilePerms: 0660,
+		}); err != nil {
+			return err
+		}
+	}
+	return vfsObj.RegisterDevice(vfs.CharDevice, linux.MISC_MAJOR, linux.LOOP_CTRL_MINOR, &loopControlDevice{devs: devs}, &vfs.RegisterDeviceOptions{
+		Pathname:  "loop-control",
+		FilePerms: 0660,
+	})
+}
@@ -26,6 +26,7 @@ import (
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
@@ -117,6 +118,43 @@ func (fd *tunFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args
 		_, err := req.CopyOut(t, data)
 		return 0, err
 
+	case linux.TUNGETFEATURES:
+		_, err := primitive.CopyUint32Out(t, data, netstack.TUNFeatures)
+		return 0, err
+
+	case linux.TUNSETQUEUE:
+		var req linux.IFReq
+		if _, err := req.CopyIn(t, data); err != nil {
+			return 0, err
+		}
+		linuxFlags := hostarch.ByteOrder.Uint16(req.Data[:])
+		switch {
+		case linuxFlags&linux.IFF_ATTACH_QUEUE != 0:
+			return 0, fd.device.SetQueue(true)
+		case linuxFlags&linux.IFF_DETACH_QUEUE != 0:
+			return 0, fd.device.SetQueue(false)
+		default:
+			return 0, linuxerr.EINVAL
+		}
+
+	case linux.TUNGETVNETHDRSZ:
+		size, err := fd.device.VnetHdrSize()
+		if err != nil {
+			return 0, err
+		}
+		_, err = primitive.CopyInt32Out(t, data, size)
+		return 0, err
+
+	case linux.TUNSETVNETHDRSZ:
+		var size primitive.Int32
+		if _, err := size.CopyIn(t, data); err != nil {
+			return 0, err
+		}
+		return 0, fd.device.SetVnetHdrSize(int32(size))
+
+	case linux.TUNSETOFFLOAD:
+		return 0, fd.device.SetOffload(args[2].Uint())
+
 	default:
 		return 0, linuxerr.ENOTTY
 	}
@@ -159,11 +197,11 @@ func (fd *tunFD) Write(ctx context.Context, src usermem.IOSequence, opts vfs.Wri
 	if src.NumBytes() == 0 {
 		return 0, unix.EINVAL
 	}
-	mtu, err := fd.device.MTU()
+	maxSize, err := fd.device.MaxWriteSize()
 	if err != nil {
 		return 0, err
 	}
-	if int64(mtu) < src.NumBytes() {
+	if maxSize < src.NumBytes() {
 		return 0, unix.EMSGSIZE
 	}
 	data := buffer.NewView(int(src.NumBytes()))
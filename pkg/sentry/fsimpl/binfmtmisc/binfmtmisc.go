@@ -0,0 +1,398 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package binfmtmisc implements the binfmt_misc filesystem, which configures
+// the interpreters that execve(2) uses to run executables that can't be run
+// natively.
+//
+// As in Linux, entries are owned by the user namespace that mounts
+// binfmt_misc, so all mounts of binfmt_misc in a user namespace share the same
+// entries, and they only affect executables run from that user namespace and
+// its descendants that haven't mounted binfmt_misc themselves.
+package binfmtmisc
+
+import (
+	"bytes"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/loader"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+const (
+	// Name is the user-visible filesystem name.
+	Name = "binfmt_misc"
+
+	registerName = "register"
+	statusName   = "status"
+)
+
+// FilesystemType implements vfs.FilesystemType.
+//
+// +stateify savable
+type FilesystemType struct{}
+
+// Name implements vfs.FilesystemType.Name.
+func (FilesystemType) Name() string {
+	return Name
+}
+
+// Release implements vfs.FilesystemType.Release.
+func (FilesystemType) Release(ctx context.Context) {}
+
+// GetFilesystem implements vfs.FilesystemType.GetFilesystem.
+func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, source string, opts vfs.GetFilesystemOptions) (*vfs.Filesystem, *vfs.Dentry, error) {
+	k := kernel.KernelFromContext(ctx)
+	if k == nil {
+		return nil, nil, linuxerr.EINVAL
+	}
+	devMinor, err := vfsObj.GetAnonBlockDevMinor()
+	if err != nil {
+		return nil, nil, err
+	}
+	fs := &filesystem{
+		devMinor: devMinor,
+		userNS:   creds.UserNamespace,
+	}
+	fs.VFSFilesystem().Init(vfsObj, &fsType, fs)
+
+	root := fs.newRootInode(ctx, creds, k.GetBinfmtMisc(creds.UserNamespace))
+	var rootD kernfs.Dentry
+	rootD.InitRoot(&fs.Filesystem, root)
+	return fs.VFSFilesystem(), rootD.VFSDentry(), nil
+}
+
+// filesystem implements kernfs.Filesystem.
+//
+// +stateify savable
+type filesystem struct {
+	kernfs.Filesystem
+
+	devMinor uint32
+
+	// userNS is the user namespace whose binfmt_misc handlers the filesystem
+	// exposes. It is immutable.
+	userNS *auth.UserNamespace
+}
+
+// Release implements vfs.FilesystemImpl.Release.
+func (fs *filesystem) Release(ctx context.Context) {
+	fs.Filesystem.VFSFilesystem().VirtualFilesystem().PutAnonBlockDevMinor(fs.devMinor)
+	fs.Filesystem.Release(ctx)
+	kernel.KernelFromContext(ctx).PutBinfmtMisc(ctx, fs.userNS)
+}
+
+// MountOptions implements vfs.FilesystemImpl.MountOptions.
+func (fs *filesystem) MountOptions() string {
+	return ""
+}
+
+// +stateify savable
+type implStatFS struct{}
+
+// StatFS implements kernfs.Inode.StatFS.
+func (*implStatFS) StatFS(context.Context, *vfs.Filesystem) (linux.Statfs, error) {
+	return vfs.GenericStatFS(linux.BINFMTFS_MAGIC), nil
+}
+
+// rootInode is the root directory of binfmt_misc. It contains the register
+// and status files, and a file for each entry.
+//
+// +stateify savable
+type rootInode struct {
+	implStatFS
+	rootInodeRefs
+	kernfs.InodeAlwaysValid
+	kernfs.InodeAttrs
+	kernfs.InodeDirectoryNoNewChildren
+	kernfs.InodeNotAnonymous
+	kernfs.InodeNotSymlink
+	kernfs.InodeTemporary
+	kernfs.InodeWatches
+	kernfs.InodeFSOwned
+	kernfs.OrderedChildren
+
+	fs     *filesystem
+	binfmt *loader.BinfmtMisc
+	locks  vfs.FileLocks
+}
+
+var _ kernfs.Inode = (*rootInode)(nil)
+
+func (fs *filesystem) newRootInode(ctx context.Context, creds *auth.Credentials, binfmt *loader.BinfmtMisc) kernfs.Inode {
+	inode := &rootInode{
+		fs:     fs,
+		binfmt: binfmt,
+	}
+	inode.InodeAttrs.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), linux.ModeDirectory|0755)
+	inode.OrderedChildren.Init(kernfs.OrderedChildrenOptions{})
+	inode.InitRefs()
+
+	register := &registerData{binfmt: binfmt}
+	register.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), register, 0200)
+	status := &statusData{binfmt: binfmt}
+	status.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), status, 0644)
+	inode.IncLinks(inode.OrderedChildren.Populate(map[string]kernfs.Inode{
+		registerName: register,
+		statusName:   status,
+	}))
+	return inode
+}
+
+// Lookup implements kernfs.inodeDirectory.Lookup.
+func (i *rootInode) Lookup(ctx context.Context, name string) (kernfs.Inode, error) {
+	if d, err := i.OrderedChildren.Lookup(ctx, name); err == nil {
+		return d, nil
+	}
+	e := i.binfmt.Lookup(name)
+	if e == nil {
+		return nil, linuxerr.ENOENT
+	}
+	return i.fs.newEntryInode(ctx, i.binfmt, e), nil
+}
+
+// IterDirents implements kernfs.inodeDirectory.IterDirents.
+func (i *rootInode) IterDirents(ctx context.Context, mnt *vfs.Mount, cb vfs.IterDirentsCallback, offset, relOffset int64) (int64, error) {
+	entries := i.binfmt.Entries()
+	if relOffset < 0 {
+		relOffset = 0
+	}
+	for idx := relOffset; idx < int64(len(entries)); idx++ {
+		dirent := vfs.Dirent{
+			Name:    entries[idx].Name,
+			Type:    linux.DT_REG,
+			Ino:     i.fs.NextIno(),
+			NextOff: offset + 1,
+		}
+		if err := cb.Handle(dirent); err != nil {
+			return offset, err
+		}
+		offset++
+	}
+	return offset, nil
+}
+
+// Open implements kernfs.Inode.Open.
+func (i *rootInode) Open(ctx context.Context, rp *vfs.ResolvingPath, d *kernfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	fd, err := kernfs.NewGenericDirectoryFD(rp.Mount(), d, &i.OrderedChildren, &i.locks, &opts, kernfs.GenericDirectoryFDOptions{
+		SeekEnd: kernfs.SeekEndZero,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fd.VFSFileDescription(), nil
+}
+
+// SetStat implements kernfs.Inode.SetStat not allowing inode attributes to be changed.
+func (*rootInode) SetStat(context.Context, *vfs.Filesystem, *auth.Credentials, vfs.SetStatOptions) error {
+	return linuxerr.EPERM
+}
+
+// DecRef implements kernfs.Inode.DecRef.
+func (i *rootInode) DecRef(ctx context.Context) {
+	i.rootInodeRefs.DecRef(func() { i.Destroy(ctx) })
+}
+
+// registerData implements vfs.WritableDynamicBytesSource for
+// binfmt_misc/register.
+//
+// +stateify savable
+type registerData struct {
+	implStatFS
+	kernfs.DynamicBytesFile
+
+	binfmt *loader.BinfmtMisc
+}
+
+var _ vfs.WritableDynamicBytesSource = (*registerData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *registerData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	// register is write-only.
+	return linuxerr.EINVAL
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *registerData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	n := src.NumBytes()
+	if n < minRegisterLength || n > maxRegisterLength {
+		return 0, linuxerr.EINVAL
+	}
+	buf := make([]byte, n)
+	if _, err := src.CopyIn(ctx, buf); err != nil {
+		return 0, err
+	}
+	e, err := parseEntry(buf)
+	if err != nil {
+		return 0, err
+	}
+	// Entry names may not collide with the static files.
+	if e.Name == registerName || e.Name == statusName {
+		return 0, linuxerr.EEXIST
+	}
+	if e.Flags&loader.BinfmtMiscFixBinary != 0 {
+		fd, err := openInterpreter(ctx, e.Interpreter)
+		if err != nil {
+			return 0, err
+		}
+		e.InterpreterFile = fd
+	}
+	if err := d.binfmt.Register(e); err != nil {
+		if e.InterpreterFile != nil {
+			e.InterpreterFile.DecRef(ctx)
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+// openInterpreter opens the interpreter at path for an entry with
+// BinfmtMiscFixBinary, relative to the calling task's root and working
+// directory.
+func openInterpreter(ctx context.Context, path string) (*vfs.FileDescription, error) {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		return nil, linuxerr.EINVAL
+	}
+	root := t.FSContext().RootDirectory()
+	defer root.DecRef(t)
+	wd := t.FSContext().WorkingDirectory()
+	defer wd.DecRef(t)
+	pop := vfs.PathOperation{
+		Root:               root,
+		Start:              wd,
+		Path:               fspath.Parse(path),
+		FollowFinalSymlink: true,
+	}
+	return t.Kernel().VFS().OpenAt(t, t.Credentials(), &pop, &vfs.OpenOptions{
+		Flags:    linux.O_RDONLY,
+		FileExec: true,
+	})
+}
+
+// statusData implements vfs.WritableDynamicBytesSource for
+// binfmt_misc/status.
+//
+// +stateify savable
+type statusData struct {
+	implStatFS
+	kernfs.DynamicBytesFile
+
+	binfmt *loader.BinfmtMisc
+}
+
+var _ vfs.WritableDynamicBytesSource = (*statusData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *statusData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	if d.binfmt.Enabled() {
+		buf.WriteString("enabled\n")
+	} else {
+		buf.WriteString("disabled\n")
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *statusData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	cmd, n, err := copyInCommand(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	switch cmd {
+	case cmdDisable:
+		d.binfmt.SetEnabled(false)
+	case cmdEnable:
+		d.binfmt.SetEnabled(true)
+	case cmdRemove:
+		d.binfmt.Clear(ctx)
+	}
+	return n, nil
+}
+
+// entryData implements vfs.WritableDynamicBytesSource for the file
+// representing a binfmt_misc entry.
+//
+// +stateify savable
+type entryData struct {
+	implStatFS
+	kernfs.DynamicBytesFile
+
+	binfmt *loader.BinfmtMisc
+	entry  *loader.BinfmtMiscEntry
+}
+
+var _ vfs.WritableDynamicBytesSource = (*entryData)(nil)
+
+func (fs *filesystem) newEntryInode(ctx context.Context, binfmt *loader.BinfmtMisc, e *loader.BinfmtMiscEntry) kernfs.Inode {
+	d := &entryData{
+		binfmt: binfmt,
+		entry:  e,
+	}
+	d.Init(ctx, auth.CredentialsFromContext(ctx), linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), d, 0644)
+	return d
+}
+
+// Valid implements kernfs.Inode.Valid.
+func (d *entryData) Valid(ctx context.Context, parent *kernfs.Dentry, name string) bool {
+	return d.binfmt.Lookup(name) == d.entry
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *entryData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	writeEntryStatus(buf, d.entry)
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *entryData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	cmd, n, err := copyInCommand(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	switch cmd {
+	case cmdDisable:
+		d.entry.SetEnabled(false)
+	case cmdEnable:
+		d.entry.SetEnabled(true)
+	case cmdRemove:
+		d.binfmt.Remove(ctx, d.entry)
+	}
+	return n, nil
+}
+
+// copyInCommand reads and parses a command written to the status or an entry
+// file.
+func copyInCommand(ctx context.Context, src usermem.IOSequence) (int, int64, error) {
+	n := src.NumBytes()
+	if n > 3 {
+		return 0, 0, linuxerr.EINVAL
+	}
+	buf := make([]byte, n)
+	if _, err := src.CopyIn(ctx, buf); err != nil {
+		return 0, 0, err
+	}
+	cmd, err := parseCommand(buf)
+	if err != nil {
+		return 0, 0, err
+	}
+	return cmd, n, nil
+}
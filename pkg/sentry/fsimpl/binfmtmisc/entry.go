@@ -0,0 +1,260 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binfmtmisc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/loader"
+)
+
+const (
+	// minRegisterLength and maxRegisterLength bound the length of writes to
+	// the register file. From fs/binfmt_misc.c.
+	minRegisterLength = 11
+	maxRegisterLength = 1920
+)
+
+// parseEntry parses a registration string of the form
+// :name:type:offset:magic:mask:interpreter:flags, where ':' may be any
+// delimiter. This is fs/binfmt_misc.c:create_entry().
+func parseEntry(buf []byte) (*loader.BinfmtMiscEntry, error) {
+	if len(buf) < minRegisterLength || len(buf) > maxRegisterLength {
+		return nil, linuxerr.EINVAL
+	}
+	p := &entryParser{buf: buf, del: buf[0], pos: 1}
+	e := &loader.BinfmtMiscEntry{}
+
+	// Parse the 'name' field.
+	name, ok := p.field()
+	if !ok || len(name) == 0 || string(name) == "." || string(name) == ".." || bytes.IndexByte(name, '/') >= 0 {
+		return nil, linuxerr.EINVAL
+	}
+	e.Name = string(name)
+
+	// Parse the 'type' field.
+	typ, ok := p.field()
+	if !ok || len(typ) != 1 || (typ[0] != 'E' && typ[0] != 'M') {
+		return nil, linuxerr.EINVAL
+	}
+
+	if typ[0] == 'M' {
+		// Parse the 'offset' field.
+		offset, ok := p.field()
+		if !ok {
+			return nil, linuxerr.EINVAL
+		}
+		if len(offset) != 0 {
+			off, err := strconv.ParseInt(string(offset), 10, 32)
+			if err != nil || off < 0 {
+				return nil, linuxerr.EINVAL
+			}
+			e.Offset = int(off)
+		}
+		// Parse the 'magic' and 'mask' fields, which may contain hex escapes.
+		magic, ok := p.escapedField()
+		if !ok || len(magic) == 0 {
+			return nil, linuxerr.EINVAL
+		}
+		mask, ok := p.escapedField()
+		if !ok {
+			return nil, linuxerr.EINVAL
+		}
+		e.Magic = unescapeHex(magic)
+		if len(mask) != 0 {
+			e.Mask = unescapeHex(mask)
+			if len(e.Mask) != len(e.Magic) {
+				return nil, linuxerr.EINVAL
+			}
+		}
+		if len(e.Magic) > loader.BinprmBufSize || loader.BinprmBufSize-len(e.Magic) < e.Offset {
+			return nil, linuxerr.EINVAL
+		}
+	} else {
+		// Skip the 'offset' field.
+		if _, ok := p.field(); !ok {
+			return nil, linuxerr.EINVAL
+		}
+		// Parse the 'magic' field, which holds the extension.
+		ext, ok := p.field()
+		if !ok || len(ext) == 0 || bytes.IndexByte(ext, '/') >= 0 {
+			return nil, linuxerr.EINVAL
+		}
+		e.Extension = string(ext)
+		// Skip the 'mask' field.
+		if _, ok := p.field(); !ok {
+			return nil, linuxerr.EINVAL
+		}
+	}
+
+	// Parse the 'interpreter' field.
+	interp, ok := p.field()
+	if !ok || len(interp) == 0 {
+		return nil, linuxerr.EINVAL
+	}
+	e.Interpreter = string(interp)
+
+	// Parse the 'flags' field, which must end the string.
+flags:
+	for ; p.pos < len(buf); p.pos++ {
+		switch buf[p.pos] {
+		case 'P':
+			e.Flags |= loader.BinfmtMiscPreserveArgv0
+		case 'O':
+			e.Flags |= loader.BinfmtMiscOpenBinary
+		case 'C':
+			e.Flags |= loader.BinfmtMiscCredentials | loader.BinfmtMiscOpenBinary
+		case 'F':
+			e.Flags |= loader.BinfmtMiscFixBinary
+		default:
+			break flags
+		}
+	}
+	if p.pos < len(buf) && buf[p.pos] == '\n' {
+		p.pos++
+	}
+	if p.pos != len(buf) {
+		return nil, linuxerr.EINVAL
+	}
+	return e, nil
+}
+
+// entryParser splits a registration string into fields.
+type entryParser struct {
+	buf []byte
+	del byte
+	pos int
+}
+
+// field returns the bytes up to the next delimiter and advances past it. It
+// returns false if there is no delimiter.
+func (p *entryParser) field() ([]byte, bool) {
+	i := bytes.IndexByte(p.buf[p.pos:], p.del)
+	if i < 0 {
+		return nil, false
+	}
+	f := p.buf[p.pos : p.pos+i]
+	p.pos += i + 1
+	return f, true
+}
+
+// escapedField is equivalent to field, except that delimiters escaped as
+// \xHH do not end the field. This is fs/binfmt_misc.c:scanarg().
+func (p *entryParser) escapedField() ([]byte, bool) {
+	for i := p.pos; i < len(p.buf); i++ {
+		switch c := p.buf[i]; {
+		case c == p.del:
+			f := p.buf[p.pos:i]
+			p.pos = i + 1
+			return f, true
+		case c == '\\' && i+1 < len(p.buf) && p.buf[i+1] == 'x':
+			if i+3 >= len(p.buf) || !isHexDigit(p.buf[i+2]) || !isHexDigit(p.buf[i+3]) {
+				return nil, false
+			}
+			i += 3
+		}
+	}
+	return nil, false
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// unescapeHex replaces \xHH escapes in s with the bytes they represent, like
+// Linux's string_unescape_inplace(UNESCAPE_HEX). Other characters, including
+// other backslashes, are left as is.
+func unescapeHex(s []byte) []byte {
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' && isHexDigit(s[i+2]) && isHexDigit(s[i+3]) {
+			v, _ := strconv.ParseUint(string(s[i+2:i+4]), 16, 8)
+			out = append(out, byte(v))
+			i += 3
+			continue
+		}
+		out = append(out, s[i])
+	}
+	return out
+}
+
+// writeEntryStatus writes the contents of e's file to buf. This is
+// fs/binfmt_misc.c:entry_status().
+func writeEntryStatus(buf *bytes.Buffer, e *loader.BinfmtMiscEntry) {
+	if e.Enabled() {
+		buf.WriteString("enabled\n")
+	} else {
+		buf.WriteString("disabled\n")
+	}
+	fmt.Fprintf(buf, "interpreter %s\n", e.Interpreter)
+	buf.WriteString("flags: ")
+	if e.Flags&loader.BinfmtMiscPreserveArgv0 != 0 {
+		buf.WriteByte('P')
+	}
+	if e.Flags&loader.BinfmtMiscOpenBinary != 0 {
+		buf.WriteByte('O')
+	}
+	if e.Flags&loader.BinfmtMiscCredentials != 0 {
+		buf.WriteByte('C')
+	}
+	if e.Flags&loader.BinfmtMiscFixBinary != 0 {
+		buf.WriteByte('F')
+	}
+	buf.WriteByte('\n')
+	if e.Extension != "" {
+		fmt.Fprintf(buf, "extension .%s\n", e.Extension)
+		return
+	}
+	fmt.Fprintf(buf, "offset %d\nmagic %s\n", e.Offset, hex.EncodeToString(e.Magic))
+	if e.Mask != nil {
+		fmt.Fprintf(buf, "mask %s\n", hex.EncodeToString(e.Mask))
+	}
+}
+
+// Commands that may be written to the status and entry files.
+const (
+	cmdNone = iota
+	cmdDisable
+	cmdEnable
+	cmdRemove
+)
+
+// parseCommand parses a write to the status or an entry file. This is
+// fs/binfmt_misc.c:parse_command().
+func parseCommand(buf []byte) (int, error) {
+	if len(buf) > 3 {
+		return 0, linuxerr.EINVAL
+	}
+	if len(buf) == 0 {
+		return cmdNone, nil
+	}
+	if buf[len(buf)-1] == '\n' {
+		buf = buf[:len(buf)-1]
+	}
+	switch string(buf) {
+	case "0":
+		return cmdDisable, nil
+	case "1":
+		return cmdEnable, nil
+	case "-1":
+		return cmdRemove, nil
+	default:
+		return 0, linuxerr.EINVAL
+	}
+}
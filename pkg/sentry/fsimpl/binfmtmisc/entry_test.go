@@ -0,0 +1,124 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binfmtmisc
+
+import (
+	"bytes"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/loader"
+)
+
+func TestParseEntry(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		reg    string
+		status string
+	}{
+		{
+			name:   "extension",
+			reg:    ":py:E::py::/usr/bin/python3:\n",
+			status: "enabled\ninterpreter /usr/bin/python3\nflags: \nextension .py\n",
+		},
+		{
+			name:   "magic",
+			reg:    ":arm:M::\\x7fELF\\x01::/usr/bin/qemu-arm:POCF",
+			status: "enabled\ninterpreter /usr/bin/qemu-arm\nflags: POCF\noffset 0\nmagic 7f454c4601\n",
+		},
+		{
+			name:   "mask",
+			reg:    ":m:M:2:ab:\\xff\\x0f:/bin/m:C",
+			status: "enabled\ninterpreter /bin/m\nflags: OC\noffset 2\nmagic 6162\nmask ff0f\n",
+		},
+		{
+			name:   "escaped delimiter",
+			reg:    "|d|M||\\x7c||/bin/d|",
+			status: "enabled\ninterpreter /bin/d\nflags: \noffset 0\nmagic 7c\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			e, err := parseEntry([]byte(test.reg))
+			if err != nil {
+				t.Fatalf("parseEntry(%q) failed: %v", test.reg, err)
+			}
+			e.SetEnabled(true)
+			var buf bytes.Buffer
+			writeEntryStatus(&buf, e)
+			if got := buf.String(); got != test.status {
+				t.Errorf("got status %q, want %q", got, test.status)
+			}
+		})
+	}
+}
+
+func TestParseEntryInvalid(t *testing.T) {
+	for _, reg := range []string{
+		// Too short.
+		":a:E::b::",
+		// Invalid names.
+		":.:E::py::/bin/i:",
+		":a/b:E::py::/bin/i:",
+		// Invalid type.
+		":a:X::py::/bin/i:",
+		// Empty extension or interpreter.
+		":a:E::::/bin/i:",
+		":a:E::py:::",
+		// Negative offset.
+		":a:M:-1:ab::/bin/i:",
+		// Mask length differs from magic.
+		":a:M::ab:\\xff::/bin/i:",
+		// Magic doesn't fit in the buffer.
+		":a:M:255:ab::/bin/i:",
+		// Invalid flag.
+		":a:E::py::/bin/i:X",
+		// Missing field.
+		":a:E::py:/bin/i",
+	} {
+		if _, err := parseEntry([]byte(reg)); err != linuxerr.EINVAL {
+			t.Errorf("parseEntry(%q): got error %v, want %v", reg, err, linuxerr.EINVAL)
+		}
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	for _, test := range []struct {
+		cmd  string
+		want int
+		err  error
+	}{
+		{"", cmdNone, nil},
+		{"0", cmdDisable, nil},
+		{"1\n", cmdEnable, nil},
+		{"-1\n", cmdRemove, nil},
+		{"2", 0, linuxerr.EINVAL},
+		{"-1\n\n", 0, linuxerr.EINVAL},
+	} {
+		got, err := parseCommand([]byte(test.cmd))
+		if got != test.want || err != test.err {
+			t.Errorf("parseCommand(%q) = %d, %v; want %d, %v", test.cmd, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestEntryFlags(t *testing.T) {
+	e, err := parseEntry([]byte(":c:E::sh::/bin/sh:C"))
+	if err != nil {
+		t.Fatalf("parseEntry failed: %v", err)
+	}
+	if want := loader.BinfmtMiscCredentials | loader.BinfmtMiscOpenBinary; e.Flags != want {
+		t.Errorf("got flags %#x, want %#x", e.Flags, want)
+	}
+}
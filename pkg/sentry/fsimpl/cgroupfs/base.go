@@ -167,6 +167,30 @@ type cgroupInode struct {
 	//
 	// ts, and cgroup membership in general is protected by fs.tasksMu.
 	ts map[*kernel.Task]struct{}
+
+	// parent is the parent cgroup, or nil for the root cgroup. Immutable,
+	// since cgroupfs doesn't allow cross directory renames.
+	parent *cgroupInode
+
+	// The fields below are only used on the unified hierarchy.
+
+	// controlFiles are the control files for each controller, which are
+	// inserted into and removed from the directory as the controller is
+	// enabled and disabled in the parent's cgroup.subtree_control. Immutable.
+	controlFiles map[kernel.CgroupControllerType]map[string]kernfs.Inode
+
+	// subtreeControl is the set of controllers enabled for the children of
+	// this cgroup. Writers must hold both fs.subtreeMu and fs.tasksMu,
+	// readers either of them.
+	subtreeControl map[kernel.CgroupControllerType]struct{}
+
+	// freeze is the value of cgroup.freeze. Protected by fs.tasksMu.
+	freeze bool
+
+	// psi is the pressure stall information of the tasks in this cgroup and
+	// its descendants. The root cgroup reports system-wide pressure stall
+	// information. Immutable.
+	psi *kernel.PSIGroup
 }
 
 var _ kernel.CgroupImpl = (*cgroupInode)(nil)
@@ -176,6 +200,7 @@ func (fs *filesystem) newCgroupInode(ctx context.Context, creds *auth.Credential
 		dir:         dir{fs: fs},
 		ts:          make(map[*kernel.Task]struct{}),
 		controllers: make(map[kernel.CgroupControllerType]controller),
+		parent:      parent,
 	}
 	c.dir.cgi = c
 
@@ -191,13 +216,24 @@ func (fs *filesystem) newCgroupInode(ctx context.Context, creds *auth.Credential
 
 	contents := make(map[string]kernfs.Inode)
 	contents["cgroup.procs"] = fs.newControllerWritableFile(ctx, creds, &cgroupProcsData{c}, false)
-	contents["tasks"] = fs.newControllerWritableFile(ctx, creds, &tasksData{c}, false)
+	if fs.unified {
+		c.controlFiles = make(map[kernel.CgroupControllerType]map[string]kernfs.Inode)
+		c.subtreeControl = make(map[kernel.CgroupControllerType]struct{})
+		if parent != nil {
+			c.psi = kernel.NewPSIGroup(parent.psi)
+		} else {
+			c.psi = k.PSI()
+		}
+		fs.addUnifiedCoreFiles(ctx, creds, c, contents)
+	} else {
+		contents["tasks"] = fs.newControllerWritableFile(ctx, creds, &tasksData{c}, false)
+	}
 
 	if parent != nil {
 		for ty, ctl := range parent.controllers {
 			new := ctl.Clone()
 			c.controllers[ty] = new
-			new.AddControlFiles(ctx, creds, c, contents)
+			c.addControlFiles(ctx, creds, new, contents)
 		}
 	} else {
 		for _, ctl := range fs.controllers {
@@ -205,7 +241,7 @@ func (fs *filesystem) newCgroupInode(ctx context.Context, creds *auth.Credential
 			// creation. The root cgroup uses the controllers directly from the
 			// filesystem.
 			c.controllers[ctl.Type()] = ctl
-			ctl.AddControlFiles(ctx, creds, c, contents)
+			c.addControlFiles(ctx, creds, ctl, contents)
 		}
 	}
 
@@ -218,6 +254,31 @@ func (fs *filesystem) newCgroupInode(ctx context.Context, creds *auth.Credential
 	return c
 }
 
+// addControlFiles adds the control files for ctl to contents. On the unified
+// hierarchy, the control files of a non-root cgroup are only visible while ctl
+// is enabled in the parent's cgroup.subtree_control, so they're recorded in
+// c.controlFiles instead and inserted later by syncControlFilesLocked.
+func (c *cgroupInode) addControlFiles(ctx context.Context, creds *auth.Credentials, ctl controller, contents map[string]kernfs.Inode) {
+	if !c.fs.unified {
+		ctl.AddControlFiles(ctx, creds, c, contents)
+		return
+	}
+	files := make(map[string]kernfs.Inode)
+	ctl.AddControlFiles(ctx, creds, c, files)
+	c.controlFiles[ctl.Type()] = files
+	if c.isRoot() {
+		// All controllers are always enabled in the root cgroup.
+		for name, f := range files {
+			contents[name] = f
+		}
+	}
+}
+
+// isRoot returns whether c is the root cgroup of its hierarchy.
+func (c *cgroupInode) isRoot() bool {
+	return c.parent == nil
+}
+
 // HierarchyID implements kernel.CgroupImpl.HierarchyID.
 func (c *cgroupInode) HierarchyID() uint32 {
 	return c.fs.hierarchyID
@@ -254,6 +315,15 @@ func (c *cgroupInode) Enter(t *kernel.Task) {
 	for _, ctl := range c.controllers {
 		ctl.Enter(t)
 	}
+	if c.fs.unified {
+		t.SetPSIGroup(c.psi)
+	}
+	if c.fs.unified && c.frozenLocked() {
+		// We can't stop t here since we may be holding t.mu, which nests
+		// inside the signal mutex. Let t freeze itself before it returns to
+		// user space instead.
+		t.RegisterWork(&freezeWork{cg: c})
+	}
 }
 
 // Leave implements kernel.CgroupImpl.Leave.
@@ -265,10 +335,27 @@ func (c *cgroupInode) Leave(t *kernel.Task) {
 		ctl.Leave(t)
 	}
 	delete(c.ts, t)
+	delete(c.fs.frozenTasks, t)
+	if c.fs.unified {
+		t.SetPSIGroup(nil)
+	}
 }
 
 // PrepareMigrate implements kernel.CgroupImpl.PrepareMigrate.
 func (c *cgroupInode) PrepareMigrate(t *kernel.Task, src *kernel.Cgroup) error {
+	if c.fs.unified && !c.isRoot() {
+		// "... only the root cgroup and cgroups which don't have any domain
+		// controllers enabled in cgroup.subtree_control can contain
+		// processes." -- Documentation/admin-guide/cgroup-v2.rst, the
+		// no internal process constraint.
+		c.fs.tasksMu.RLock()
+		internal := len(c.subtreeControl) > 0
+		c.fs.tasksMu.RUnlock()
+		if internal {
+			return linuxerr.EBUSY
+		}
+	}
+
 	prepared := make([]controller, 0, len(c.controllers))
 	rollback := func() {
 		for _, p := range prepared {
@@ -299,6 +386,9 @@ func (c *cgroupInode) CommitMigrate(t *kernel.Task, src *kernel.Cgroup) {
 	srcI := src.CgroupImpl.(*cgroupInode)
 	delete(srcI.ts, t)
 	c.ts[t] = struct{}{}
+	if c.fs.unified {
+		t.SetPSIGroup(c.psi)
+	}
 }
 
 // AbortMigrate implements kernel.CgroupImpl.AbortMigrate.
@@ -395,6 +485,11 @@ func (c *cgroupInode) ID() uint32 {
 	return c.id
 }
 
+// Unified implements kernel.CgroupImpl.Unified.
+func (c *cgroupInode) Unified() bool {
+	return c.fs.unified
+}
+
 func sortTIDs(tids []kernel.ThreadID) {
 	sort.Slice(tids, func(i, j int) bool { return tids[i] < tids[j] })
 }
@@ -451,7 +546,21 @@ func (d *cgroupProcsData) Write(ctx context.Context, fd *vfs.FileDescription, sr
 	if targetTG == nil {
 		return 0, linuxerr.EINVAL
 	}
-	return n, targetTG.MigrateCgroup(d.CgroupFromControlFileFD(fd))
+	if err := targetTG.MigrateCgroup(d.CgroupFromControlFileFD(fd)); err != nil {
+		return n, err
+	}
+	if d.fs.unified {
+		// The migrated tasks may have moved in or out of a frozen cgroup.
+		var ts []*kernel.Task
+		targetTG.ForEachTask(func(t *kernel.Task) bool {
+			ts = append(ts, t)
+			return true
+		})
+		for _, t := range ts {
+			d.fs.reconcileFreeze(t, d.cgroupInode)
+		}
+	}
+	return n, nil
 }
 
 // +stateify savable
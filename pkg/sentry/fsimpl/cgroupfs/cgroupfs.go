@@ -31,6 +31,13 @@
 // system-wide state related to cgroups such as active hierarchies and the
 // controllers associated with them.
 //
+// cgroupfs also implements the cgroup v2 unified hierarchy, mounted as the
+// "cgroup2" filesystem type. There is at most one unified hierarchy on the
+// system, and it binds all v2-capable controllers that aren't already attached
+// to a v1 hierarchy. Controllers on the unified hierarchy are the same
+// implementations used by v1 hierarchies, but expose their v2 control files and
+// are enabled for a subtree through cgroup.subtree_control. See unified.go.
+//
 // Since cgroupfs doesn't allow hardlinks, there is a unique mapping between
 // cgroupfs dentries and inodes. Thus, cgroupfs inodes don't need to be ref
 // counted and exist until they're unlinked once or the FS is destroyed.
@@ -46,14 +53,21 @@
 // cgroupfs.filesystem.tasksMu. Tasks also maintain a set of all cgroups they're
 // in, and this list is protected by Task.mu.
 //
+// On the unified hierarchy, cgroup.subtree_control changes are serialized by
+// cgroupfs.filesystem.subtreeMu, and freezer state transitions by
+// cgroupfs.filesystem.freezeMu.
+//
 // Lock order:
 //
 //	kernel.CgroupRegistry.mu
 //		kernfs.filesystem.mu
+//		cgroupfs.filesystem.freezeMu
+//		cgroupfs.filesystem.subtreeMu
 //		kernel.TaskSet.mu
 //	  	kernel.Task.mu
 //	    	cgroupfs.filesystem.tasksMu.
 //	      	cgroupfs.dir.OrderedChildren.mu
+//	        	cgroupfs.memoryController.mu
 package cgroupfs
 
 import (
@@ -93,10 +107,11 @@ var allControllers = []kernel.CgroupControllerType{
 	kernel.CgroupControllerJob,
 	kernel.CgroupControllerMemory,
 	kernel.CgroupControllerPIDs,
+	kernel.CgroupControllerIO,
 }
 
 // SupportedMountOptions is the set of supported mount options for cgroupfs.
-var SupportedMountOptions = []string{"all", "cpu", "cpuacct", "cpuset", "devices", "job", "memory", "pids"}
+var SupportedMountOptions = []string{"all", "blkio", "cpu", "cpuacct", "cpuset", "devices", "job", "memory", "pids"}
 
 // FilesystemType implements vfs.FilesystemType.
 //
@@ -174,6 +189,22 @@ type filesystem struct {
 	// tasksMu serializes task membership changes across all cgroups within a
 	// filesystem.
 	tasksMu taskRWMutex `state:"nosave"`
+
+	// unified indicates this is the cgroup v2 unified hierarchy. Immutable.
+	unified bool
+
+	// subtreeMu serializes changes to cgroup.subtree_control, and the
+	// resulting changes to the set of control files visible in each cgroup.
+	// Only used on the unified hierarchy.
+	subtreeMu subtreeMutex `state:"nosave"`
+
+	// freezeMu serializes freezing and thawing of tasks. Only used on the
+	// unified hierarchy.
+	freezeMu freezeMutex `state:"nosave"`
+
+	// frozenTasks is the set of tasks currently frozen by the cgroup
+	// freezer. Protected by tasksMu.
+	frozenTasks map[*kernel.Task]struct{}
 }
 
 // InitializeHierarchyID implements kernel.cgroupFS.InitializeHierarchyID.
@@ -189,6 +220,11 @@ func (fs *filesystem) RootCgroup() kernel.Cgroup {
 	}
 }
 
+// EffectiveRootCgroup implements kernel.cgroupFS.EffectiveRootCgroup.
+func (fs *filesystem) EffectiveRootCgroup() kernel.Cgroup {
+	return fs.effectiveRootCgroup()
+}
+
 // Name implements vfs.FilesystemType.Name.
 func (FilesystemType) Name() string {
 	return Name
@@ -216,6 +252,10 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	}
 
 	var wantControllers []kernel.CgroupControllerType
+	if _, ok := mopts["blkio"]; ok {
+		delete(mopts, "blkio")
+		wantControllers = append(wantControllers, kernel.CgroupControllerIO)
+	}
 	if _, ok := mopts["cpu"]; ok {
 		delete(mopts, "cpu")
 		wantControllers = append(wantControllers, kernel.CgroupControllerCPU)
@@ -330,6 +370,14 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	}
 	fs.MaxCachedDentries = maxCachedDentries
 	fs.VFSFilesystem().Init(vfsObj, &fsType, fs)
+	return fs.initHierarchy(ctx, vfsObj, creds, wantControllers, opts)
+}
+
+// initHierarchy populates and registers a newly created hierarchy with the
+// controllers in wantControllers. On failure, initHierarchy releases fs.
+func (fs *filesystem) initHierarchy(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, wantControllers []kernel.CgroupControllerType, opts vfs.GetFilesystemOptions) (*vfs.Filesystem, *vfs.Dentry, error) {
+	k := kernel.KernelFromContext(ctx)
+	r := k.CgroupRegistry()
 
 	var defaults map[string]int64
 	if opts.InternalData != nil {
@@ -340,6 +388,8 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	for _, ty := range wantControllers {
 		var c controller
 		switch ty {
+		case kernel.CgroupControllerIO:
+			c = newIOController(fs)
 		case kernel.CgroupControllerCPU:
 			c = newCPUController(fs, defaults)
 		case kernel.CgroupControllerCPUAcct:
@@ -360,9 +410,11 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		fs.controllers = append(fs.controllers, c)
 	}
 
-	if len(defaults) != 0 {
+	if len(defaults) != 0 && !fs.unified {
 		// Internal data is always provided at sentry startup and unused values
-		// indicate a problem with the sandbox config. Fail fast.
+		// indicate a problem with the sandbox config. Fail fast. The unified
+		// hierarchy only binds controllers that aren't on a v1 hierarchy, so
+		// it may legitimately leave some defaults unused.
 		panic(fmt.Sprintf("cgroupfs.FilesystemType.GetFilesystem: unknown internal mount data: %v", defaults))
 	}
 
@@ -390,7 +442,13 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	// Register controllers. The registry may be modified concurrently, so if we
 	// get an error, we raced with someone else who registered the same
 	// controllers first.
-	if err := r.Register(name, fs.kcontrollers, fs); err != nil {
+	var err error
+	if fs.unified {
+		err = r.RegisterUnified(fs.kcontrollers, fs)
+	} else {
+		err = r.Register(fs.hierarchyName, fs.kcontrollers, fs)
+	}
+	if err != nil {
 		ctx.Infof("cgroupfs.FilesystemType.GetFilesystem: failed to register new hierarchy with controllers %v: %v", wantControllers, err)
 		rootD.DecRef(ctx)
 		fs.VFSFilesystem().DecRef(ctx)
@@ -469,6 +527,7 @@ func (fs *filesystem) Release(ctx context.Context) {
 	r := k.CgroupRegistry()
 
 	if fs.hierarchyID != kernel.InvalidCgroupHierarchyID {
+		fs.thawAll()
 		k.ReleaseCgroupHierarchy(fs.hierarchyID)
 		r.Unregister(fs.hierarchyID)
 	}
@@ -483,6 +542,10 @@ func (fs *filesystem) Release(ctx context.Context) {
 
 // MountOptions implements vfs.FilesystemImpl.MountOptions.
 func (fs *filesystem) MountOptions() string {
+	if fs.unified {
+		// cgroup2 mounts don't list controllers.
+		return ""
+	}
 	var cnames []string
 	for _, c := range fs.controllers {
 		cnames = append(cnames, string(c.Type()))
@@ -555,10 +618,23 @@ func (d *dir) newDirWithOwner(ctx context.Context, ownerCreds *auth.Credentials,
 		return nil, linuxerr.EINVAL
 	}
 	mode := opts.Mode.Permissions() | linux.ModeDirectory
-	return d.OrderedChildren.Inserter(name, func() kernfs.Inode {
+	child, err := d.OrderedChildren.Inserter(name, func() kernfs.Inode {
 		d.IncLinks(1)
 		return d.fs.newCgroupInode(ctx, ownerCreds, d.cgi, mode)
 	})
+	if err != nil {
+		return nil, err
+	}
+	if d.fs.unified {
+		// Expose the control files for controllers enabled in our
+		// cgroup.subtree_control. This can't happen while the child is being
+		// inserted above, since subtreeMu can't be acquired while holding
+		// d.OrderedChildren.mu.
+		d.fs.subtreeMu.Lock()
+		child.(*cgroupInode).syncControlFilesLocked(ctx)
+		d.fs.subtreeMu.Unlock()
+	}
+	return child, nil
 }
 
 // Rename implements kernfs.Inode.Rename. Cgroupfs only allows renaming of
@@ -640,6 +716,12 @@ func (d *dir) RmDir(ctx context.Context, name string, child kernfs.Inode) error
 	err := d.OrderedChildren.RmDir(ctx, name, child)
 	if err == nil {
 		d.InodeAttrs.DecLinks()
+		if mem, ok := cgi.controllers[kernel.CgroupControllerMemory].(*memoryController); ok {
+			mem.releaseEvents()
+		}
+		if io, ok := cgi.controllers[kernel.CgroupControllerIO].(*ioController); ok {
+			io.ioCg.Release()
+		}
 	}
 	return err
 }
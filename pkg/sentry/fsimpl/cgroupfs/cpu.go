@@ -15,17 +15,39 @@
 package cgroupfs
 
 import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// Limits on the CFS bandwidth period and quota, in microseconds. See Linux,
+// kernel/sched/core.c.
+const (
+	minCFSQuotaPeriod = 1000
+	maxCFSQuotaPeriod = 1000000
+)
+
+// Bounds and default for cpu.weight. See Linux, include/linux/cgroup.h.
+const (
+	cgroupWeightMin = 1
+	cgroupWeightDfl = 100
+	cgroupWeightMax = 10000
 )
 
 // +stateify savable
 type cpuController struct {
 	controllerCommon
-	controllerStateless
 	controllerNoResource
 
 	// CFS bandwidth control parameters, values in microseconds.
@@ -34,6 +56,10 @@ type cpuController struct {
 
 	// CPU shares, values should be (num core * 1024).
 	shares atomicbitops.Int64
+
+	// acct tracks CPU usage for cpu.stat on the unified hierarchy, which has
+	// no separate cpuacct controller. Nil on v1 hierarchies. Immutable.
+	acct *cpuacctController
 }
 
 var _ controller = (*cpuController)(nil)
@@ -58,6 +84,9 @@ func newCPUController(fs *filesystem, defaults map[string]int64) *cpuController
 		c.shares = atomicbitops.FromInt64(val)
 		delete(defaults, "cpu.shares")
 	}
+	if fs.unified {
+		c.acct = newCPUAcctController(fs)
+	}
 
 	c.controllerCommon.init(kernel.CgroupControllerCPU, fs)
 	return c
@@ -70,13 +99,165 @@ func (c *cpuController) Clone() controller {
 		cfsQuota:  atomicbitops.FromInt64(c.cfsQuota.Load()),
 		shares:    atomicbitops.FromInt64(c.shares.Load()),
 	}
+	if c.acct != nil {
+		new.acct = c.acct.Clone().(*cpuacctController)
+	}
 	new.controllerCommon.cloneFromParent(c)
 	return new
 }
 
 // AddControlFiles implements controller.AddControlFiles.
-func (c *cpuController) AddControlFiles(ctx context.Context, creds *auth.Credentials, _ *cgroupInode, contents map[string]kernfs.Inode) {
+func (c *cpuController) AddControlFiles(ctx context.Context, creds *auth.Credentials, cg *cgroupInode, contents map[string]kernfs.Inode) {
+	if c.fs.unified {
+		contents["cpu.stat"] = c.fs.newControllerFile(ctx, creds, &cpuStatData{&cpuacctCgroup{cg}}, true)
+		if !cg.isRoot() {
+			contents["cpu.max"] = c.fs.newControllerWritableFile(ctx, creds, &cpuMaxData{c: c}, true)
+			contents["cpu.weight"] = c.fs.newControllerWritableFile(ctx, creds, &cpuWeightData{c: c}, true)
+		}
+		return
+	}
 	contents["cpu.cfs_period_us"] = c.fs.newStubControllerFile(ctx, creds, &c.cfsPeriod, true)
 	contents["cpu.cfs_quota_us"] = c.fs.newStubControllerFile(ctx, creds, &c.cfsQuota, true)
 	contents["cpu.shares"] = c.fs.newStubControllerFile(ctx, creds, &c.shares, true)
 }
+
+// Enter implements controller.Enter.
+func (c *cpuController) Enter(t *kernel.Task) {
+	if c.acct != nil {
+		c.acct.Enter(t)
+	}
+}
+
+// Leave implements controller.Leave.
+func (c *cpuController) Leave(t *kernel.Task) {
+	if c.acct != nil {
+		c.acct.Leave(t)
+	}
+}
+
+// PrepareMigrate implements controller.PrepareMigrate.
+func (c *cpuController) PrepareMigrate(t *kernel.Task, src controller) error {
+	return nil
+}
+
+// CommitMigrate implements controller.CommitMigrate.
+func (c *cpuController) CommitMigrate(t *kernel.Task, src controller) {
+	if c.acct != nil {
+		c.acct.CommitMigrate(t, src.(*cpuController).acct)
+	}
+}
+
+// AbortMigrate implements controller.AbortMigrate.
+func (c *cpuController) AbortMigrate(t *kernel.Task, src controller) {}
+
+// +stateify savable
+type cpuStatData struct {
+	*cpuacctCgroup
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cpuStatData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	cs := d.collectCPUStats()
+	fmt.Fprintf(buf, "usage_usec %d\n", (cs.UserTime + cs.SysTime).Microseconds())
+	fmt.Fprintf(buf, "user_usec %d\n", cs.UserTime.Microseconds())
+	fmt.Fprintf(buf, "system_usec %d\n", cs.SysTime.Microseconds())
+	if !d.isRoot() {
+		// CFS bandwidth control isn't enforced, so tasks are never
+		// throttled.
+		fmt.Fprintf(buf, "nr_periods 0\n")
+		fmt.Fprintf(buf, "nr_throttled 0\n")
+		fmt.Fprintf(buf, "throttled_usec 0\n")
+	}
+	return nil
+}
+
+// cpuMaxData implements cpu.max, which combines cpu.cfs_quota_us and
+// cpu.cfs_period_us from v1 as "$QUOTA $PERIOD".
+//
+// +stateify savable
+type cpuMaxData struct {
+	c *cpuController
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cpuMaxData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	fmt.Fprintf(buf, "%s %d\n", formatMaxOrInt64(d.c.cfsQuota.Load(), -1), d.c.cfsPeriod.Load())
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cpuMaxData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+func (d *cpuMaxData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	buf := copyScratchBufferFromContext(ctx, hostarch.PageSize)
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(buf[:n]))
+	if len(fields) < 1 || len(fields) > 2 {
+		return 0, linuxerr.EINVAL
+	}
+	quota, err := parseMaxOrInt64(fields[0], -1)
+	if err != nil {
+		return 0, err
+	}
+	period := d.c.cfsPeriod.Load()
+	if len(fields) == 2 {
+		period, err = strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, linuxerr.EINVAL
+		}
+	}
+	if period < minCFSQuotaPeriod || period > maxCFSQuotaPeriod {
+		return 0, linuxerr.EINVAL
+	}
+	if quota != -1 && quota < minCFSQuotaPeriod {
+		return 0, linuxerr.EINVAL
+	}
+	d.c.cfsQuota.Store(quota)
+	d.c.cfsPeriod.Store(period)
+	return int64(n), nil
+}
+
+// cpuWeightData implements cpu.weight, which is a rescaled view of cpu.shares
+// from v1. See Linux, kernel/sched/core.c:cpu_weight_write_u64().
+//
+// +stateify savable
+type cpuWeightData struct {
+	c *cpuController
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cpuWeightData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	weight := (d.c.shares.Load()*cgroupWeightDfl + 512) / 1024
+	if weight < cgroupWeightMin {
+		weight = cgroupWeightMin
+	}
+	if weight > cgroupWeightMax {
+		weight = cgroupWeightMax
+	}
+	fmt.Fprintf(buf, "%d\n", weight)
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cpuWeightData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+func (d *cpuWeightData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	weight, n, err := parseInt64FromString(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	if weight < cgroupWeightMin || weight > cgroupWeightMax {
+		return 0, linuxerr.ERANGE
+	}
+	d.c.shares.Store((weight*1024 + cgroupWeightDfl/2) / cgroupWeightDfl)
+	return n, nil
+}
@@ -133,6 +133,11 @@ type cpuacctCgroup struct {
 }
 
 func (c *cpuacctCgroup) cpuacctController() *cpuacctController {
+	if c.fs.unified {
+		// On the unified hierarchy, CPU usage is tracked by the cpu
+		// controller.
+		return c.controllers[kernel.CgroupControllerCPU].(*cpuController).acct
+	}
 	return c.controllers[kernel.CgroupControllerCPUAcct].(*cpuacctController)
 }
 
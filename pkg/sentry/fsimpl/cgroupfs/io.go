@@ -0,0 +1,369 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroupfs
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// ioController accounts and throttles file I/O per device. It's called blkio
+// on v1 hierarchies and io on the unified hierarchy.
+//
+// Devices are the pseudo-devices of sentry filesystems that perform I/O
+// outside the sentry (e.g. gofer and host file mounts), identified by the
+// device numbers reported by stat(2). Each regular file read or write on such
+// a filesystem is accounted as one operation.
+//
+// +stateify savable
+type ioController struct {
+	controllerCommon
+	controllerNoResource
+
+	ioCg *kernel.IOCgroup
+}
+
+var _ controller = (*ioController)(nil)
+
+// newIOController creates the root node for an io cgroup. Child directories
+// should be created through Clone.
+func newIOController(fs *filesystem) *ioController {
+	c := &ioController{
+		ioCg: kernel.NewIOCgroup(nil),
+	}
+	c.controllerCommon.init(kernel.CgroupControllerIO, fs)
+	return c
+}
+
+// Clone implements controller.Clone.
+func (c *ioController) Clone() controller {
+	new := &ioController{
+		ioCg: kernel.NewIOCgroup(c.ioCg),
+	}
+	new.controllerCommon.cloneFromParent(c)
+	return new
+}
+
+// AddControlFiles implements controller.AddControlFiles.
+func (c *ioController) AddControlFiles(ctx context.Context, creds *auth.Credentials, cg *cgroupInode, contents map[string]kernfs.Inode) {
+	if c.fs.unified {
+		contents["io.stat"] = c.fs.newControllerFile(ctx, creds, &ioStatData{c: c}, true)
+		if !cg.isRoot() {
+			contents["io.max"] = c.fs.newControllerWritableFile(ctx, creds, &ioMaxData{c: c}, true)
+		}
+		return
+	}
+	contents["blkio.throttle.read_bps_device"] = c.fs.newControllerWritableFile(ctx, creds, &ioThrottleData{c: c, limit: ioLimitReadBPS}, true)
+	contents["blkio.throttle.write_bps_device"] = c.fs.newControllerWritableFile(ctx, creds, &ioThrottleData{c: c, limit: ioLimitWriteBPS}, true)
+	contents["blkio.throttle.read_iops_device"] = c.fs.newControllerWritableFile(ctx, creds, &ioThrottleData{c: c, limit: ioLimitReadIOPS}, true)
+	contents["blkio.throttle.write_iops_device"] = c.fs.newControllerWritableFile(ctx, creds, &ioThrottleData{c: c, limit: ioLimitWriteIOPS}, true)
+	contents["blkio.throttle.io_service_bytes"] = c.fs.newControllerFile(ctx, creds, &ioServiceData{c: c, bytes: true}, true)
+	contents["blkio.throttle.io_service_bytes_recursive"] = c.fs.newControllerFile(ctx, creds, &ioServiceData{c: c, bytes: true, recursive: true}, true)
+	contents["blkio.throttle.io_serviced"] = c.fs.newControllerFile(ctx, creds, &ioServiceData{c: c}, true)
+	contents["blkio.throttle.io_serviced_recursive"] = c.fs.newControllerFile(ctx, creds, &ioServiceData{c: c, recursive: true}, true)
+}
+
+// Enter implements controller.Enter.
+func (c *ioController) Enter(t *kernel.Task) {
+	t.SetIOCgroup(c.ioCg)
+}
+
+// Leave implements controller.Leave.
+func (c *ioController) Leave(t *kernel.Task) {
+	t.SetIOCgroup(nil)
+}
+
+// PrepareMigrate implements controller.PrepareMigrate.
+func (c *ioController) PrepareMigrate(t *kernel.Task, src controller) error {
+	return nil
+}
+
+// CommitMigrate implements controller.CommitMigrate.
+func (c *ioController) CommitMigrate(t *kernel.Task, src controller) {
+	t.SetIOCgroup(c.ioCg)
+}
+
+// AbortMigrate implements controller.AbortMigrate.
+func (c *ioController) AbortMigrate(t *kernel.Task, src controller) {}
+
+// sortedIODevices returns the keys of m in ascending order.
+func sortedIODevices[V any](m map[vfs.IODevice]V) []vfs.IODevice {
+	devs := make([]vfs.IODevice, 0, len(m))
+	for dev := range m {
+		devs = append(devs, dev)
+	}
+	sort.Slice(devs, func(i, j int) bool {
+		if devs[i].Major != devs[j].Major {
+			return devs[i].Major < devs[j].Major
+		}
+		return devs[i].Minor < devs[j].Minor
+	})
+	return devs
+}
+
+// parseIODevice parses a device in "$MAJOR:$MINOR" format.
+func parseIODevice(str string) (vfs.IODevice, error) {
+	majStr, minStr, ok := strings.Cut(str, ":")
+	if !ok {
+		return vfs.IODevice{}, linuxerr.EINVAL
+	}
+	major, err := strconv.ParseUint(majStr, 10, 32)
+	if err != nil {
+		return vfs.IODevice{}, linuxerr.EINVAL
+	}
+	minor, err := strconv.ParseUint(minStr, 10, 32)
+	if err != nil {
+		return vfs.IODevice{}, linuxerr.EINVAL
+	}
+	return vfs.IODevice{Major: uint32(major), Minor: uint32(minor)}, nil
+}
+
+// ioLimit identifies one of the limits in kernel.IOLimits.
+type ioLimit int
+
+const (
+	ioLimitReadBPS ioLimit = iota
+	ioLimitWriteBPS
+	ioLimitReadIOPS
+	ioLimitWriteIOPS
+)
+
+// field returns a pointer to the limit l in limits.
+func (l ioLimit) field(limits *kernel.IOLimits) *uint64 {
+	switch l {
+	case ioLimitReadBPS:
+		return &limits.ReadBPS
+	case ioLimitWriteBPS:
+		return &limits.WriteBPS
+	case ioLimitReadIOPS:
+		return &limits.ReadIOPS
+	case ioLimitWriteIOPS:
+		return &limits.WriteIOPS
+	default:
+		panic(fmt.Sprintf("unknown io limit %d", l))
+	}
+}
+
+// ioStatData implements io.stat.
+//
+// +stateify savable
+type ioStatData struct {
+	c *ioController
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *ioStatData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	stats := d.c.ioCg.Stats(true /* recursive */)
+	for _, dev := range sortedIODevices(stats) {
+		s := stats[dev]
+		fmt.Fprintf(buf, "%d:%d rbytes=%d wbytes=%d rios=%d wios=%d dbytes=0 dios=0\n", dev.Major, dev.Minor, s.ReadBytes, s.WriteBytes, s.ReadIOs, s.WriteIOs)
+	}
+	return nil
+}
+
+// ioMaxData implements io.max, which lists the limits of each device as
+// "$MAJOR:$MINOR rbps=$RBPS wbps=$WBPS riops=$RIOPS wiops=$WIOPS".
+//
+// +stateify savable
+type ioMaxData struct {
+	c *ioController
+}
+
+// ioMaxKeys are the keys of io.max, in display order.
+var ioMaxKeys = []struct {
+	name  string
+	limit ioLimit
+}{
+	{"rbps", ioLimitReadBPS},
+	{"wbps", ioLimitWriteBPS},
+	{"riops", ioLimitReadIOPS},
+	{"wiops", ioLimitWriteIOPS},
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *ioMaxData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	limits := d.c.ioCg.Limits()
+	for _, dev := range sortedIODevices(limits) {
+		l := limits[dev]
+		fmt.Fprintf(buf, "%d:%d", dev.Major, dev.Minor)
+		for _, k := range ioMaxKeys {
+			val := *k.limit.field(&l)
+			if val == 0 {
+				fmt.Fprintf(buf, " %s=max", k.name)
+			} else {
+				fmt.Fprintf(buf, " %s=%d", k.name, val)
+			}
+		}
+		buf.WriteString("\n")
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *ioMaxData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+//
+// The written value is a device followed by any number of "$KEY=$VALUE"
+// pairs, where $VALUE is a positive integer or "max". Limits that aren't
+// written are unchanged.
+func (d *ioMaxData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	buf := copyScratchBufferFromContext(ctx, hostarch.PageSize)
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(buf[:n]))
+	if len(fields) < 1 {
+		return 0, linuxerr.EINVAL
+	}
+	dev, err := parseIODevice(fields[0])
+	if err != nil {
+		return 0, err
+	}
+	vals := make(map[ioLimit]uint64)
+	for _, field := range fields[1:] {
+		key, valStr, ok := strings.Cut(field, "=")
+		if !ok {
+			return 0, linuxerr.EINVAL
+		}
+		found := false
+		for _, k := range ioMaxKeys {
+			if k.name == key {
+				val, err := parseMaxOrInt64(valStr, 0)
+				if err != nil || val < 0 || (val == 0 && valStr != "max") {
+					return 0, linuxerr.EINVAL
+				}
+				vals[k.limit] = uint64(val)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, linuxerr.EINVAL
+		}
+	}
+	d.c.ioCg.UpdateLimits(dev, func(limits *kernel.IOLimits) {
+		for l, val := range vals {
+			*l.field(limits) = val
+		}
+	})
+	return int64(n), nil
+}
+
+// ioThrottleData implements the blkio.throttle.*_device files, which list one
+// limit of each device as "$MAJOR:$MINOR $VALUE".
+//
+// +stateify savable
+type ioThrottleData struct {
+	c     *ioController
+	limit ioLimit
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *ioThrottleData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	limits := d.c.ioCg.Limits()
+	for _, dev := range sortedIODevices(limits) {
+		l := limits[dev]
+		if val := *d.limit.field(&l); val != 0 {
+			fmt.Fprintf(buf, "%d:%d %d\n", dev.Major, dev.Minor, val)
+		}
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *ioThrottleData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+//
+// The written value is "$MAJOR:$MINOR $VALUE". A value of 0 removes the limit.
+func (d *ioThrottleData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	buf := copyScratchBufferFromContext(ctx, hostarch.PageSize)
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(buf[:n]))
+	if len(fields) != 2 {
+		return 0, linuxerr.EINVAL
+	}
+	dev, err := parseIODevice(fields[0])
+	if err != nil {
+		return 0, err
+	}
+	val, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil || val > math.MaxInt64 {
+		return 0, linuxerr.EINVAL
+	}
+	d.c.ioCg.UpdateLimits(dev, func(limits *kernel.IOLimits) {
+		*d.limit.field(limits) = val
+	})
+	return int64(n), nil
+}
+
+// ioServiceData implements blkio.throttle.io_service_bytes and
+// blkio.throttle.io_serviced, and their recursive variants.
+//
+// +stateify savable
+type ioServiceData struct {
+	c *ioController
+
+	// bytes is true for io_service_bytes, which reports bytes rather than
+	// operations.
+	bytes bool
+
+	// recursive is true if the statistics include descendant cgroups.
+	recursive bool
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *ioServiceData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	stats := d.c.ioCg.Stats(d.recursive)
+	var total uint64
+	for _, dev := range sortedIODevices(stats) {
+		s := stats[dev]
+		read, write := s.ReadIOs, s.WriteIOs
+		if d.bytes {
+			read, write = s.ReadBytes, s.WriteBytes
+		}
+		fmt.Fprintf(buf, "%d:%d Read %d\n", dev.Major, dev.Minor, read)
+		fmt.Fprintf(buf, "%d:%d Write %d\n", dev.Major, dev.Minor, write)
+		fmt.Fprintf(buf, "%d:%d Sync 0\n", dev.Major, dev.Minor)
+		fmt.Fprintf(buf, "%d:%d Async %d\n", dev.Major, dev.Minor, read+write)
+		fmt.Fprintf(buf, "%d:%d Discard 0\n", dev.Major, dev.Minor)
+		fmt.Fprintf(buf, "%d:%d Total %d\n", dev.Major, dev.Minor, read+write)
+		total += read + write
+	}
+	fmt.Fprintf(buf, "Total %d\n", total)
+	return nil
+}
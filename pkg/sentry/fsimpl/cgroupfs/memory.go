@@ -18,14 +18,22 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/eventfd"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
 )
 
 // +stateify savable
@@ -38,6 +46,45 @@ type memoryController struct {
 	moveChargeAtImmigrate atomicbitops.Int64
 	pressureLevel         int64
 
+	// highBytes is the memory.high throttling threshold on the unified
+	// hierarchy.
+	highBytes atomicbitops.Int64
+
+	// events counts the memory.events occurrences local to this cgroup.
+	events memoryEvents
+
+	// oomKillDisable is the oom_kill_disable setting of memory.oom_control.
+	// While set, the OOM killer isn't invoked when the cgroup is over its
+	// limit.
+	oomKillDisable atomicbitops.Bool
+
+	// oomGroup is the memory.oom.group setting on cgroup v2. While set, the
+	// OOM killer kills all tasks in the cgroup if it kills any of them.
+	oomGroup atomicbitops.Bool
+
+	// underOOM is set while the cgroup is over its limit and the OOM killer
+	// is disabled.
+	underOOM atomicbitops.Bool
+
+	// overHigh is set while the cgroup's usage is over memory.high, so that
+	// events.high counts crossings of memory.high rather than samples above
+	// it.
+	overHigh atomicbitops.Bool
+
+	// oomControlFile is the memory.oom_control control file on cgroup v1, and
+	// eventsFile is the memory.events control file on cgroup v2. Immutable
+	// after AddControlFiles.
+	oomControlFile kernfs.Inode
+	eventsFile     kernfs.Inode
+
+	mu memoryControllerMutex `state:"nosave"`
+
+	// oomEvents are the eventfds registered through cgroup.event_control to
+	// be signalled when the cgroup runs out of memory.
+	//
+	// +checklocks:mu
+	oomEvents map[*oomEvent]struct{}
+
 	// memCg is the memory cgroup for this controller.
 	memCg *memoryCgroup
 }
@@ -52,6 +99,7 @@ func newMemoryController(fs *filesystem, defaults map[string]int64) *memoryContr
 
 		limitBytes:     atomicbitops.FromInt64(math.MaxInt64),
 		softLimitBytes: atomicbitops.FromInt64(math.MaxInt64),
+		highBytes:      atomicbitops.FromInt64(math.MaxInt64),
 	}
 
 	consumeDefault := func(name string, valPtr *atomicbitops.Int64) {
@@ -75,6 +123,8 @@ func (c *memoryController) Clone() controller {
 		limitBytes:            atomicbitops.FromInt64(c.limitBytes.Load()),
 		softLimitBytes:        atomicbitops.FromInt64(c.softLimitBytes.Load()),
 		moveChargeAtImmigrate: atomicbitops.FromInt64(c.moveChargeAtImmigrate.Load()),
+		highBytes:             atomicbitops.FromInt64(c.highBytes.Load()),
+		oomKillDisable:        atomicbitops.FromBool(c.oomKillDisable.Load()),
 	}
 	new.controllerCommon.cloneFromParent(c)
 	return new
@@ -83,11 +133,25 @@ func (c *memoryController) Clone() controller {
 // AddControlFiles implements controller.AddControlFiles.
 func (c *memoryController) AddControlFiles(ctx context.Context, creds *auth.Credentials, cg *cgroupInode, contents map[string]kernfs.Inode) {
 	c.memCg = &memoryCgroup{cg}
+	if c.fs.unified {
+		if !cg.isRoot() {
+			contents["memory.current"] = c.fs.newControllerFile(ctx, creds, &memoryUsageInBytesData{memCg: &memoryCgroup{cg}}, true)
+			contents["memory.max"] = c.fs.newControllerWritableFile(ctx, creds, &memoryMaxData{val: &c.limitBytes}, true)
+			contents["memory.high"] = c.fs.newControllerWritableFile(ctx, creds, &memoryMaxData{val: &c.highBytes}, true)
+			c.eventsFile = c.fs.newControllerFile(ctx, creds, &memoryEventsData{memCg: &memoryCgroup{cg}}, true)
+			contents["memory.events"] = c.eventsFile
+			contents["memory.oom.group"] = c.fs.newControllerWritableFile(ctx, creds, &memoryOOMGroupData{c: c}, true)
+		}
+		return
+	}
 	contents["memory.usage_in_bytes"] = c.fs.newControllerFile(ctx, creds, &memoryUsageInBytesData{memCg: &memoryCgroup{cg}}, true)
 	contents["memory.limit_in_bytes"] = c.fs.newStubControllerFile(ctx, creds, &c.limitBytes, true)
 	contents["memory.soft_limit_in_bytes"] = c.fs.newStubControllerFile(ctx, creds, &c.softLimitBytes, true)
 	contents["memory.move_charge_at_immigrate"] = c.fs.newStubControllerFile(ctx, creds, &c.moveChargeAtImmigrate, true)
 	contents["memory.pressure_level"] = c.fs.newStaticControllerFile(ctx, creds, linux.FileMode(0644), fmt.Sprintf("%d\n", c.pressureLevel))
+	c.oomControlFile = c.fs.newControllerWritableFile(ctx, creds, &memoryOOMControlData{memCg: &memoryCgroup{cg}}, true)
+	contents["memory.oom_control"] = c.oomControlFile
+	contents["cgroup.event_control"] = c.fs.newControllerWritableFile(ctx, creds, &cgroupEventControlData{c: c}, false)
 }
 
 // Enter implements controller.Enter.
@@ -135,6 +199,12 @@ func (memCg *memoryCgroup) collectMemCgIDs(memCgIDs map[uint32]struct{}) {
 // Returns the memory usage for all cgroup ids in memCgIDs.
 func getUsage(k *kernel.Kernel, memCgIDs map[uint32]struct{}) uint64 {
 	k.MemoryFile().UpdateUsage(memCgIDs)
+	return totalUsage(memCgIDs)
+}
+
+// totalUsage returns the memory usage for all cgroup ids in memCgIDs, as of
+// the last call to MemoryFile.UpdateUsage covering them.
+func totalUsage(memCgIDs map[uint32]struct{}) uint64 {
 	var totalBytes uint64
 	for id := range memCgIDs {
 		_, bytes := usage.MemoryAccounting.CopyPerCg(id)
@@ -158,3 +228,542 @@ func (d *memoryUsageInBytesData) Generate(ctx context.Context, buf *bytes.Buffer
 	fmt.Fprintf(buf, "%d\n", totalBytes)
 	return nil
 }
+
+// memoryEvents counts the events reported through memory.events.
+//
+// +stateify savable
+type memoryEvents struct {
+	// low and max count the number of times the cgroup's usage was found to
+	// be over the corresponding boundary. high counts the number of times the
+	// cgroup's usage went over memory.high.
+	low  atomicbitops.Uint64
+	high atomicbitops.Uint64
+	max  atomicbitops.Uint64
+	// oom counts the number of times the cgroup hit its limit and allocation
+	// failed.
+	oom atomicbitops.Uint64
+	// oomKill counts the number of processes killed due to the cgroup
+	// running out of memory.
+	oomKill atomicbitops.Uint64
+}
+
+// memoryEventCounts is a snapshot of memoryEvents.
+type memoryEventCounts struct {
+	low, high, max, oom, oomKill uint64
+}
+
+func (memCg *memoryCgroup) memoryController() *memoryController {
+	return memCg.controllers[kernel.CgroupControllerMemory].(*memoryController)
+}
+
+// collectEvents adds the memory events of the cgroup and its descendants to
+// acc.
+func (memCg *memoryCgroup) collectEvents(acc *memoryEventCounts) {
+	ev := &memCg.memoryController().events
+	acc.low += ev.low.Load()
+	acc.high += ev.high.Load()
+	acc.max += ev.max.Load()
+	acc.oom += ev.oom.Load()
+	acc.oomKill += ev.oomKill.Load()
+	memCg.forEachChildDir(func(d *dir) {
+		cg := memoryCgroup{d.cgi}
+		cg.collectEvents(acc)
+	})
+}
+
+// +stateify savable
+type memoryEventsData struct {
+	memCg *memoryCgroup
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *memoryEventsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	var ev memoryEventCounts
+	d.memCg.collectEvents(&ev)
+	fmt.Fprintf(buf, "low %d\n", ev.low)
+	fmt.Fprintf(buf, "high %d\n", ev.high)
+	fmt.Fprintf(buf, "max %d\n", ev.max)
+	fmt.Fprintf(buf, "oom %d\n", ev.oom)
+	fmt.Fprintf(buf, "oom_kill %d\n", ev.oomKill)
+	return nil
+}
+
+// memoryMaxData implements memory.max and memory.high on the unified
+// hierarchy.
+//
+// +stateify savable
+type memoryMaxData struct {
+	val *atomicbitops.Int64
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *memoryMaxData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	fmt.Fprintf(buf, "%s\n", formatMaxOrInt64(d.val.Load(), math.MaxInt64))
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *memoryMaxData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+func (d *memoryMaxData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	buf := copyScratchBufferFromContext(ctx, hostarch.PageSize)
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+	val, err := parseMemorySize(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		return 0, err
+	}
+	d.val.Store(val)
+	return int64(n), nil
+}
+
+// parseMemorySize parses a memory size written to a cgroup v2 control file.
+// The size is either "max", or a number of bytes with an optional K, M, G or T
+// suffix. Sizes are rounded down to a multiple of the page size. See Linux,
+// mm/page_counter.c:page_counter_memparse().
+func parseMemorySize(str string) (int64, error) {
+	if str == "max" {
+		return math.MaxInt64, nil
+	}
+	var shift uint
+	if len(str) > 0 {
+		switch str[len(str)-1] {
+		case 'k', 'K':
+			shift = 10
+		case 'm', 'M':
+			shift = 20
+		case 'g', 'G':
+			shift = 30
+		case 't', 'T':
+			shift = 40
+		}
+		if shift != 0 {
+			str = str[:len(str)-1]
+		}
+	}
+	val, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || val < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	if val > math.MaxInt64>>shift {
+		return math.MaxInt64, nil
+	}
+	return (val << shift) &^ (hostarch.PageSize - 1), nil
+}
+
+// limited returns whether c has a memory limit to enforce.
+func (c *memoryController) limited() bool {
+	return c.limitBytes.Load() != math.MaxInt64 || c.highBytes.Load() != math.MaxInt64
+}
+
+// limitedMemoryCgroup is a memory cgroup with a limit to enforce.
+type limitedMemoryCgroup struct {
+	memCg *memoryCgroup
+	path  string
+
+	// memCgIDs are the IDs of memCg and its descendants, whose memory usage is
+	// charged to memCg.
+	memCgIDs map[uint32]struct{}
+}
+
+// collectLimited appends the descendants of memCg that have memory limits to
+// cgs. path is the path of memCg relative to the hierarchy root.
+func (memCg *memoryCgroup) collectLimited(path string, cgs []limitedMemoryCgroup) []limitedMemoryCgroup {
+	memCg.OrderedChildren.ForEachChild(func(name string, i kernfs.Inode) {
+		cgi, ok := i.(*cgroupInode)
+		if !ok {
+			return
+		}
+		child := &memoryCgroup{cgi}
+		childPath := path + "/" + name
+		if c := child.memoryController(); c.limited() {
+			memCgIDs := make(map[uint32]struct{})
+			child.collectMemCgIDs(memCgIDs)
+			cgs = append(cgs, limitedMemoryCgroup{memCg: child, path: childPath, memCgIDs: memCgIDs})
+		} else {
+			// memory.high was removed, so the next time it's set, going
+			// over it is a new crossing.
+			c.overHigh.Store(false)
+		}
+		cgs = child.collectLimited(childPath, cgs)
+	})
+	return cgs
+}
+
+// EnforceMemoryLimits implements kernel.cgroupFS.EnforceMemoryLimits.
+//
+// Linux checks memory limits synchronously when memory is charged to a cgroup,
+// see mm/memcontrol.c:try_charge_memcg(). Application memory is committed
+// lazily by the host, so instead memory usage is sampled periodically and
+// cgroups may transiently exceed their limits. The root cgroup can't have a
+// limit, like on Linux.
+func (fs *filesystem) EnforceMemoryLimits(ctx context.Context) {
+	root := &memoryCgroup{fs.root.Inode().(*cgroupInode)}
+	if _, ok := root.controllers[kernel.CgroupControllerMemory]; !ok {
+		return
+	}
+	k := kernel.KernelFromContext(ctx)
+	// Control files must be notified and tasks killed without holding any
+	// cgroupfs locks, so collect the cgroups to check first.
+	cgs := root.collectLimited("", nil)
+	if len(cgs) == 0 {
+		return
+	}
+	// Limited cgroups may be nested, so update the usage of every cgroup
+	// involved in a single scan of the MemoryFile.
+	allMemCgIDs := make(map[uint32]struct{})
+	for _, lc := range cgs {
+		for id := range lc.memCgIDs {
+			allMemCgIDs[id] = struct{}{}
+		}
+	}
+	k.MemoryFile().UpdateUsage(allMemCgIDs)
+	for _, lc := range cgs {
+		lc.memCg.enforceLimits(ctx, k, lc.path, totalUsage(lc.memCgIDs))
+	}
+}
+
+// enforceLimits checks usage, the memory usage of memCg, against its
+// memory.high and memory.max (or memory.limit_in_bytes) limits.
+func (memCg *memoryCgroup) enforceLimits(ctx context.Context, k *kernel.Kernel, path string, usage uint64) {
+	c := memCg.memoryController()
+
+	changed := false
+	if usage <= uint64(c.highBytes.Load()) {
+		c.overHigh.Store(false)
+	} else if !c.overHigh.Swap(true) {
+		c.events.high.Add(1)
+		changed = true
+	}
+	limit := uint64(c.limitBytes.Load())
+	if usage <= limit {
+		c.underOOM.Store(false)
+	} else {
+		c.events.max.Add(1)
+		memCg.outOfMemory(ctx, k, kernel.OOMDomain{
+			Cgroup: path,
+			Limit:  limit,
+			Usage:  usage,
+		})
+		changed = true
+	}
+	if changed {
+		memCg.notifyEvents(ctx)
+	}
+}
+
+// outOfMemory handles memCg running out of memory. See Linux,
+// mm/memcontrol.c:mem_cgroup_out_of_memory().
+func (memCg *memoryCgroup) outOfMemory(ctx context.Context, k *kernel.Kernel, domain kernel.OOMDomain) {
+	c := memCg.memoryController()
+
+	memCg.fs.tasksMu.RLock()
+	ms := memCg.collectSubtreeTasksLocked(nil)
+	memCg.fs.tasksMu.RUnlock()
+	if len(ms) == 0 {
+		// The memory is charged to the cgroup, but there are no tasks left
+		// that can release it.
+		return
+	}
+
+	if c.oomKillDisable.Load() {
+		if !c.underOOM.Swap(true) {
+			c.events.oom.Add(1)
+			memCg.notifyOOM()
+		}
+		return
+	}
+
+	ts := make([]*kernel.Task, 0, len(ms))
+	for _, m := range ms {
+		ts = append(ts, m.t)
+	}
+	victim, waiting := k.OOMKill(ts, domain)
+	if waiting {
+		// A previous victim is still exiting.
+		return
+	}
+	c.events.oom.Add(1)
+	if victim != nil {
+		c.events.oomKill.Add(1)
+		if group := memCg.oomGroup(ms, victim); group != nil {
+			group.fs.tasksMu.RLock()
+			gms := group.collectSubtreeTasksLocked(nil)
+			group.fs.tasksMu.RUnlock()
+			gts := make([]*kernel.Task, 0, len(gms))
+			for _, m := range gms {
+				gts = append(gts, m.t)
+			}
+			c.events.oomKill.Add(uint64(k.OOMKillGroup(gts, domain)))
+		}
+	}
+	memCg.notifyOOM()
+}
+
+// oomGroup returns the highest cgroup with memory.oom.group set between the
+// cgroup of victim and memCg, which ran out of memory, or nil if there is no
+// such cgroup. ms are the task memberships of memCg's subtree. See Linux,
+// mm/memcontrol.c:mem_cgroup_get_oom_group().
+func (memCg *memoryCgroup) oomGroup(ms []taskMembership, victim *kernel.ThreadGroup) *memoryCgroup {
+	var victimCg *cgroupInode
+	for _, m := range ms {
+		if m.t.ThreadGroup() == victim {
+			victimCg = m.cg
+			break
+		}
+	}
+	var group *memoryCgroup
+	for cg := victimCg; cg != nil; cg = cg.parent {
+		if (&memoryCgroup{cg}).memoryController().oomGroup.Load() {
+			group = &memoryCgroup{cg}
+		}
+		if cg == memCg.cgroupInode {
+			break
+		}
+	}
+	return group
+}
+
+// notifyEvents generates inotify events for memory.events in memCg and its
+// ancestors, since memory.events reports hierarchical counts. See Linux,
+// mm/memcontrol.c:memcg_memory_event().
+func (memCg *memoryCgroup) notifyEvents(ctx context.Context) {
+	for cg := memCg.cgroupInode; cg != nil; cg = cg.parent {
+		c := (&memoryCgroup{cg}).memoryController()
+		if c.eventsFile == nil {
+			continue
+		}
+		c.eventsFile.Watches().Notify(ctx, "", linux.IN_MODIFY, 0, vfs.InodeEvent, false /* unlinked */)
+		cg.Watches().Notify(ctx, "memory.events", linux.IN_MODIFY, 0, vfs.InodeEvent, false /* unlinked */)
+	}
+}
+
+// notifyOOM signals the OOM eventfds registered on memCg and its descendants.
+// See Linux, mm/memcontrol-v1.c:mem_cgroup_oom_notify().
+func (memCg *memoryCgroup) notifyOOM() {
+	c := memCg.memoryController()
+	// Signal outside of c.mu, since signalling notifies the eventfd's waiter
+	// queue, which may call oomEvent.NotifyEvent.
+	c.mu.Lock()
+	fds := make([]*vfs.FileDescription, 0, len(c.oomEvents))
+	for ev := range c.oomEvents {
+		fds = append(fds, ev.fd)
+	}
+	c.mu.Unlock()
+	for _, fd := range fds {
+		fd.Impl().(*eventfd.EventFileDescription).Signal(1)
+	}
+	memCg.forEachChildDir(func(d *dir) {
+		cg := memoryCgroup{d.cgi}
+		cg.notifyOOM()
+	})
+}
+
+// releaseEvents signals and unregisters the eventfds registered on c. This is
+// called when the cgroup is removed. See Linux, mm/memcontrol-v1.c:
+// memcg1_css_offline().
+func (c *memoryController) releaseEvents() {
+	c.mu.Lock()
+	evs := c.oomEvents
+	c.oomEvents = nil
+	c.mu.Unlock()
+	for ev := range evs {
+		ev.fd.EventUnregister(&ev.entry)
+		ev.fd.Impl().(*eventfd.EventFileDescription).Signal(1)
+	}
+}
+
+// oomEvent is an eventfd registered through cgroup.event_control.
+//
+// oomEvent doesn't hold a reference on fd: the registration is dropped when fd
+// is released instead, so that closing the eventfd frees it. See Linux,
+// mm/memcontrol-v1.c:memcg_event_wake().
+//
+// +stateify savable
+type oomEvent struct {
+	c     *memoryController
+	fd    *vfs.FileDescription
+	entry waiter.Entry
+}
+
+// NotifyEvent implements waiter.EventListener.NotifyEvent. It is only called
+// for EventHUp, when fd is released.
+func (ev *oomEvent) NotifyEvent(waiter.EventMask) {
+	ev.c.mu.Lock()
+	defer ev.c.mu.Unlock()
+	delete(ev.c.oomEvents, ev)
+}
+
+// memoryOOMControlData implements memory.oom_control on cgroup v1.
+//
+// +stateify savable
+type memoryOOMControlData struct {
+	memCg *memoryCgroup
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *memoryOOMControlData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	c := d.memCg.memoryController()
+	boolToInt := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(buf, "oom_kill_disable %d\n", boolToInt(c.oomKillDisable.Load()))
+	fmt.Fprintf(buf, "under_oom %d\n", boolToInt(c.underOOM.Load()))
+	fmt.Fprintf(buf, "oom_kill %d\n", c.events.oomKill.Load())
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *memoryOOMControlData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+func (d *memoryOOMControlData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	val, n, err := parseInt64FromString(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	// The root cgroup can't run out of memory, see
+	// mm/memcontrol-v1.c:mem_cgroup_oom_control_write().
+	if d.memCg.isRoot() || (val != 0 && val != 1) {
+		return 0, linuxerr.EINVAL
+	}
+	d.memCg.memoryController().oomKillDisable.Store(val == 1)
+	return n, nil
+}
+
+// memoryOOMGroupData implements memory.oom.group on cgroup v2.
+//
+// +stateify savable
+type memoryOOMGroupData struct {
+	c *memoryController
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *memoryOOMGroupData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	if d.c.oomGroup.Load() {
+		buf.WriteString("1\n")
+	} else {
+		buf.WriteString("0\n")
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *memoryOOMGroupData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+func (d *memoryOOMGroupData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	val, n, err := parseInt64FromString(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	if val != 0 && val != 1 {
+		return 0, linuxerr.EINVAL
+	}
+	d.c.oomGroup.Store(val == 1)
+	return n, nil
+}
+
+// cgroupEventControlData implements cgroup.event_control on cgroup v1. Writing
+// "<event_fd> <control_fd>" registers the eventfd event_fd to be signalled on
+// events reported by the control file opened as control_fd. Only
+// memory.oom_control notifications are supported. See Linux,
+// mm/memcontrol-v1.c:memcg_write_event_control().
+//
+// +stateify savable
+type cgroupEventControlData struct {
+	c *memoryController
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cgroupEventControlData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	// cgroup.event_control is write-only.
+	return linuxerr.EINVAL
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cgroupEventControlData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		return 0, linuxerr.EINVAL
+	}
+	buf := copyScratchBufferFromContext(ctx, hostarch.PageSize)
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+	args := strings.Fields(string(buf[:n]))
+	if len(args) < 2 {
+		return 0, linuxerr.EINVAL
+	}
+	efdNum, err := strconv.ParseInt(args[0], 10, 32)
+	if err != nil {
+		return 0, linuxerr.EINVAL
+	}
+	cfdNum, err := strconv.ParseInt(args[1], 10, 32)
+	if err != nil {
+		return 0, linuxerr.EINVAL
+	}
+
+	efd := t.GetFile(int32(efdNum))
+	if efd == nil {
+		return 0, linuxerr.EBADF
+	}
+	if _, ok := efd.Impl().(*eventfd.EventFileDescription); !ok {
+		efd.DecRef(ctx)
+		return 0, linuxerr.EINVAL
+	}
+	cfd := t.GetFile(int32(cfdNum))
+	if cfd == nil {
+		efd.DecRef(ctx)
+		return 0, linuxerr.EBADF
+	}
+	defer cfd.DecRef(ctx)
+
+	// The control file must be memory.oom_control of this cgroup, and must be
+	// readable by the caller.
+	kd, ok := cfd.Dentry().Impl().(*kernfs.Dentry)
+	if !ok || kd.Inode() != d.c.oomControlFile {
+		efd.DecRef(ctx)
+		return 0, linuxerr.EINVAL
+	}
+	if err := kd.Inode().CheckPermissions(ctx, auth.CredentialsFromContext(ctx), vfs.MayRead); err != nil {
+		efd.DecRef(ctx)
+		return 0, err
+	}
+
+	ev := &oomEvent{c: d.c, fd: efd}
+	ev.entry.Init(ev, waiter.EventHUp)
+	d.c.mu.Lock()
+	if d.c.oomEvents == nil {
+		d.c.oomEvents = make(map[*oomEvent]struct{})
+	}
+	d.c.oomEvents[ev] = struct{}{}
+	d.c.mu.Unlock()
+	// efd can't be released before it is registered, since we hold a
+	// reference on it until then.
+	if err := efd.EventRegister(&ev.entry); err != nil {
+		d.c.mu.Lock()
+		delete(d.c.oomEvents, ev)
+		d.c.mu.Unlock()
+		efd.DecRef(ctx)
+		return 0, err
+	}
+	if d.c.underOOM.Load() {
+		efd.Impl().(*eventfd.EventFileDescription).Signal(1)
+	}
+	efd.DecRef(ctx)
+	return int64(n), nil
+}
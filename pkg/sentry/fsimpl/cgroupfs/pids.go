@@ -81,6 +81,10 @@ type pidsController struct {
 
 	// max is the PID limit for this cgroup. Protected by mu.
 	max int64
+
+	// maxEvents is the number of times a fork was denied because of max,
+	// reported through pids.events. Protected by mu.
+	maxEvents int64
 }
 
 var _ controller = (*pidsController)(nil)
@@ -112,6 +116,14 @@ func (c *pidsController) Clone() controller {
 
 // AddControlFiles implements controller.AddControlFiles.
 func (c *pidsController) AddControlFiles(ctx context.Context, creds *auth.Credentials, _ *cgroupInode, contents map[string]kernfs.Inode) {
+	if c.fs.unified {
+		if !c.isRoot {
+			contents["pids.current"] = c.fs.newControllerFile(ctx, creds, &pidsCurrentData{c: c}, true)
+			contents["pids.max"] = c.fs.newControllerWritableFile(ctx, creds, &pidsMaxData{c: c}, true)
+			contents["pids.events"] = c.fs.newControllerFile(ctx, creds, &pidsEventsData{c: c}, true)
+		}
+		return
+	}
 	contents["pids.current"] = c.fs.newControllerFile(ctx, creds, &pidsCurrentData{c: c}, true)
 	if !c.isRoot {
 		// "This is not available in the root cgroup for obvious reasons" --
@@ -235,6 +247,7 @@ func (c *pidsController) Charge(t *kernel.Task, d *kernfs.Dentry, res kernel.Cgr
 	if new > c.max {
 		log.Debugf("cgroupfs: pids controller charge denied due to limit: path: %q, requested: %d, current: %d (pending: %v, committed: %v), max: %v",
 			d.FSLocalPath(), value, c.committed+c.pendingTotal, c.pendingTotal, c.committed, c.max)
+		c.maxEvents++
 		return linuxerr.EAGAIN
 	}
 
@@ -256,6 +269,19 @@ func (d *pidsCurrentData) Generate(ctx context.Context, buf *bytes.Buffer) error
 	return nil
 }
 
+// +stateify savable
+type pidsEventsData struct {
+	c *pidsController
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *pidsEventsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	d.c.mu.Lock()
+	defer d.c.mu.Unlock()
+	fmt.Fprintf(buf, "max %d\n", d.c.maxEvents)
+	return nil
+}
+
 // +stateify savable
 type pidsMaxData struct {
 	c *pidsController
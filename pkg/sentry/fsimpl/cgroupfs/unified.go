@@ -0,0 +1,574 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroupfs
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// V2Name is the filesystem name of the cgroup v2 unified hierarchy.
+const V2Name = "cgroup2"
+
+// unifiedControllers are the controllers that support the unified hierarchy.
+// The remaining controllers are only available on v1 hierarchies.
+var unifiedControllers = []kernel.CgroupControllerType{
+	kernel.CgroupControllerCPU,
+	kernel.CgroupControllerCPUSet,
+	kernel.CgroupControllerMemory,
+	kernel.CgroupControllerPIDs,
+	kernel.CgroupControllerIO,
+}
+
+// SupportedV2MountOptions is the set of supported mount options for cgroup2.
+// They only affect behaviour we don't emulate, so they're accepted and
+// ignored.
+var SupportedV2MountOptions = []string{"nsdelegate", "favordynmods", "memory_localevents", "memory_recursiveprot"}
+
+// V2FilesystemType implements vfs.FilesystemType for the cgroup v2 unified
+// hierarchy.
+//
+// +stateify savable
+type V2FilesystemType struct{}
+
+// Name implements vfs.FilesystemType.Name.
+func (V2FilesystemType) Name() string {
+	return V2Name
+}
+
+// Release implements vfs.FilesystemType.Release.
+func (V2FilesystemType) Release(ctx context.Context) {}
+
+// GetFilesystem implements vfs.FilesystemType.GetFilesystem.
+//
+// All cgroup2 mounts are views into the single unified hierarchy. When the
+// hierarchy is first created, it binds all the unified controllers that aren't
+// already attached to a v1 hierarchy, like Linux does.
+func (fsType V2FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, source string, opts vfs.GetFilesystemOptions) (*vfs.Filesystem, *vfs.Dentry, error) {
+	mopts := vfs.GenericParseMountOptions(opts.Data)
+	maxCachedDentries := defaultMaxCachedDentries
+	if str, ok := mopts["dentry_cache_limit"]; ok {
+		delete(mopts, "dentry_cache_limit")
+		var err error
+		maxCachedDentries, err = strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			ctx.Warningf("cgroupfs.V2FilesystemType.GetFilesystem: invalid dentry cache limit: dentry_cache_limit=%s", str)
+			return nil, nil, linuxerr.EINVAL
+		}
+	}
+	for _, opt := range SupportedV2MountOptions {
+		delete(mopts, opt)
+	}
+	if len(mopts) != 0 {
+		ctx.Debugf("cgroupfs.V2FilesystemType.GetFilesystem: unknown options: %v", mopts)
+		return nil, nil, linuxerr.EINVAL
+	}
+
+	k := kernel.KernelFromContext(ctx)
+	r := k.CgroupRegistry()
+
+	if vfsfs := r.FindUnifiedHierarchy(); vfsfs != nil {
+		fs := vfsfs.Impl().(*filesystem)
+		ctx.Debugf("cgroupfs.V2FilesystemType.GetFilesystem: mounting new view to unified hierarchy %v", fs.hierarchyID)
+		fs.root.IncRef()
+		if fs.effectiveRoot != fs.root {
+			fs.effectiveRoot.IncRef()
+		}
+		return vfsfs, fs.root.VFSDentry(), nil
+	}
+
+	devMinor, err := vfsObj.GetAnonBlockDevMinor()
+	if err != nil {
+		return nil, nil, err
+	}
+	fs := &filesystem{
+		devMinor:    devMinor,
+		unified:     true,
+		frozenTasks: make(map[*kernel.Task]struct{}),
+	}
+	fs.MaxCachedDentries = maxCachedDentries
+	fs.VFSFilesystem().Init(vfsObj, &fsType, fs)
+	return fs.initHierarchy(ctx, vfsObj, creds, r.UnboundControllers(unifiedControllers), opts)
+}
+
+// addUnifiedCoreFiles adds the core interface files of the unified hierarchy
+// to contents.
+func (fs *filesystem) addUnifiedCoreFiles(ctx context.Context, creds *auth.Credentials, c *cgroupInode, contents map[string]kernfs.Inode) {
+	contents["cgroup.controllers"] = fs.newControllerFile(ctx, creds, &cgroupControllersData{c}, true)
+	contents["cgroup.subtree_control"] = fs.newControllerWritableFile(ctx, creds, &cgroupSubtreeControlData{c}, true)
+	contents["cgroup.threads"] = fs.newControllerWritableFile(ctx, creds, &cgroupThreadsData{c}, false)
+	// Threaded cgroups aren't supported, all cgroups are domain cgroups.
+	contents["cgroup.type"] = fs.newStaticControllerFile(ctx, creds, readonlyFileMode, "domain\n")
+	// Pressure files are core files rather than controller files, since
+	// pressure stall information is always tracked.
+	contents["cpu.pressure"] = fs.newControllerWritableFile(ctx, creds, kernel.NewPSIFile(c.psi, kernel.PSICPU), false)
+	contents["io.pressure"] = fs.newControllerWritableFile(ctx, creds, kernel.NewPSIFile(c.psi, kernel.PSIIO), false)
+	contents["memory.pressure"] = fs.newControllerWritableFile(ctx, creds, kernel.NewPSIFile(c.psi, kernel.PSIMemory), false)
+	if !c.isRoot() {
+		contents["cgroup.events"] = fs.newControllerFile(ctx, creds, &cgroupEventsData{c}, true)
+		contents["cgroup.freeze"] = fs.newControllerWritableFile(ctx, creds, &cgroupFreezeData{c}, true)
+	}
+}
+
+// syncControlFilesLocked makes the control files of each controller visible in
+// c if and only if the controller is enabled in the parent's
+// cgroup.subtree_control.
+//
+// Precondition: c.fs.subtreeMu must be locked.
+func (c *cgroupInode) syncControlFilesLocked(ctx context.Context) {
+	if c.isRoot() {
+		return
+	}
+	for ty, files := range c.controlFiles {
+		_, enabled := c.parent.subtreeControl[ty]
+		for name, f := range files {
+			cur, err := c.OrderedChildren.Lookup(ctx, name)
+			present := err == nil && cur == f
+			switch {
+			case enabled && !present:
+				if err := c.OrderedChildren.Insert(name, f); err != nil {
+					// A child cgroup is shadowing the control file.
+					ctx.Debugf("cgroupfs: can't add control file %q to cgroup %d: %v", name, c.id, err)
+				}
+			case !enabled && present:
+				if err := c.OrderedChildren.Unlink(ctx, name, f); err != nil {
+					ctx.Warningf("cgroupfs: can't remove control file %q from cgroup %d: %v", name, c.id, err)
+				}
+			}
+		}
+	}
+}
+
+// availableControllersLocked returns the set of controllers that may be
+// enabled in c's cgroup.subtree_control.
+//
+// Precondition: c.fs.tasksMu or c.fs.subtreeMu must be locked.
+func (c *cgroupInode) availableControllersLocked() map[kernel.CgroupControllerType]struct{} {
+	if !c.isRoot() {
+		return c.parent.subtreeControl
+	}
+	avail := make(map[kernel.CgroupControllerType]struct{}, len(c.fs.controllers))
+	for _, ctl := range c.fs.controllers {
+		avail[ctl.Type()] = struct{}{}
+	}
+	return avail
+}
+
+// populatedLocked returns whether c or any of its descendants contain tasks.
+//
+// Precondition: c.fs.tasksMu must be locked.
+func (c *cgroupInode) populatedLocked() bool {
+	if len(c.ts) > 0 {
+		return true
+	}
+	populated := false
+	c.forEachChildDir(func(d *dir) {
+		populated = populated || d.cgi.populatedLocked()
+	})
+	return populated
+}
+
+// frozenLocked returns whether c is frozen, either through its own
+// cgroup.freeze or through an ancestor's.
+//
+// Precondition: c.fs.tasksMu must be locked.
+func (c *cgroupInode) frozenLocked() bool {
+	for cg := c; cg != nil; cg = cg.parent {
+		if cg.freeze {
+			return true
+		}
+	}
+	return false
+}
+
+// taskMembership records a task along with the cgroup it belongs to.
+type taskMembership struct {
+	t  *kernel.Task
+	cg *cgroupInode
+}
+
+// collectSubtreeTasksLocked appends all tasks in c and its descendants to ms.
+//
+// Precondition: c.fs.tasksMu must be locked.
+func (c *cgroupInode) collectSubtreeTasksLocked(ms []taskMembership) []taskMembership {
+	for t := range c.ts {
+		ms = append(ms, taskMembership{t: t, cg: c})
+	}
+	c.forEachChildDir(func(d *dir) {
+		ms = d.cgi.collectSubtreeTasksLocked(ms)
+	})
+	return ms
+}
+
+// reconcileFreeze freezes or thaws t so that its state matches the freezer
+// state of c. It's a no-op if t is no longer a member of c, since whichever
+// operation moved t out of c is responsible for reconciling t with its new
+// cgroup.
+//
+// As in Linux, frozen tasks still respond to SIGKILL.
+func (fs *filesystem) reconcileFreeze(t *kernel.Task, c *cgroupInode) {
+	fs.freezeMu.Lock()
+	defer fs.freezeMu.Unlock()
+
+	fs.tasksMu.Lock()
+	if _, ok := c.ts[t]; !ok {
+		fs.tasksMu.Unlock()
+		return
+	}
+	frozen := c.frozenLocked()
+	if frozen {
+		fs.frozenTasks[t] = struct{}{}
+	} else {
+		delete(fs.frozenTasks, t)
+	}
+	fs.tasksMu.Unlock()
+
+	// The signal mutex nests outside tasksMu, so t must be frozen or thawed
+	// after dropping it. freezeMu ensures the order of these calls for t
+	// matches the order of the updates to fs.frozenTasks above.
+	t.SetCgroupFrozen(frozen)
+}
+
+// thawAll thaws all tasks frozen by this hierarchy. This is called when the
+// hierarchy is torn down.
+func (fs *filesystem) thawAll() {
+	fs.freezeMu.Lock()
+	defer fs.freezeMu.Unlock()
+
+	fs.tasksMu.Lock()
+	ts := make([]*kernel.Task, 0, len(fs.frozenTasks))
+	for t := range fs.frozenTasks {
+		ts = append(ts, t)
+		delete(fs.frozenTasks, t)
+	}
+	fs.tasksMu.Unlock()
+
+	for _, t := range ts {
+		t.SetCgroupFrozen(false)
+	}
+}
+
+// freezeWork is a kernel.TaskWorker that freezes a task that entered a frozen
+// cgroup.
+//
+// +stateify savable
+type freezeWork struct {
+	cg *cgroupInode
+}
+
+// TaskWork implements kernel.TaskWorker.TaskWork.
+func (w *freezeWork) TaskWork(t *kernel.Task) {
+	w.cg.fs.reconcileFreeze(t, w.cg)
+}
+
+// formatControllers returns the names of the controllers in set as a sorted,
+// space separated list.
+func formatControllers(set map[kernel.CgroupControllerType]struct{}) string {
+	names := make([]string, 0, len(set))
+	for ty := range set {
+		names = append(names, ty.UnifiedName())
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+// +stateify savable
+type cgroupControllersData struct {
+	*cgroupInode
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cgroupControllersData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	d.fs.tasksMu.RLock()
+	defer d.fs.tasksMu.RUnlock()
+	fmt.Fprintf(buf, "%s\n", formatControllers(d.availableControllersLocked()))
+	return nil
+}
+
+// +stateify savable
+type cgroupSubtreeControlData struct {
+	*cgroupInode
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cgroupSubtreeControlData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	d.fs.tasksMu.RLock()
+	defer d.fs.tasksMu.RUnlock()
+	fmt.Fprintf(buf, "%s\n", formatControllers(d.subtreeControl))
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cgroupSubtreeControlData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+//
+// The written value is a space separated list of controller names prefixed
+// with '+' to enable or '-' to disable the controller for the children of this
+// cgroup.
+func (d *cgroupSubtreeControlData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	buf := copyScratchBufferFromContext(ctx, hostarch.PageSize)
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+
+	enable := make(map[kernel.CgroupControllerType]struct{})
+	disable := make(map[kernel.CgroupControllerType]struct{})
+	for _, tok := range strings.Fields(string(buf[:n])) {
+		ty, err := kernel.ParseUnifiedCgroupController(tok[1:])
+		if err != nil {
+			return 0, linuxerr.EINVAL
+		}
+		switch tok[0] {
+		case '+':
+			enable[ty] = struct{}{}
+			delete(disable, ty)
+		case '-':
+			disable[ty] = struct{}{}
+			delete(enable, ty)
+		default:
+			return 0, linuxerr.EINVAL
+		}
+	}
+
+	d.fs.subtreeMu.Lock()
+	defer d.fs.subtreeMu.Unlock()
+	if err := d.updateSubtreeControlLocked(enable, disable); err != nil {
+		return 0, err
+	}
+	d.forEachChildDir(func(cd *dir) {
+		cd.cgi.syncControlFilesLocked(ctx)
+	})
+	return int64(n), nil
+}
+
+// updateSubtreeControlLocked enables and disables controllers in
+// c.subtreeControl. See Linux, kernel/cgroup/cgroup.c:
+// cgroup_subtree_control_write().
+//
+// Precondition: c.fs.subtreeMu must be locked.
+func (c *cgroupInode) updateSubtreeControlLocked(enable, disable map[kernel.CgroupControllerType]struct{}) error {
+	c.fs.tasksMu.Lock()
+	defer c.fs.tasksMu.Unlock()
+
+	avail := c.availableControllersLocked()
+	for ty := range enable {
+		if _, ok := avail[ty]; !ok {
+			return linuxerr.ENOENT
+		}
+		if _, ok := c.subtreeControl[ty]; ok {
+			delete(enable, ty)
+		}
+	}
+	for ty := range disable {
+		if _, ok := c.subtreeControl[ty]; !ok {
+			delete(disable, ty)
+		}
+	}
+
+	// The no internal process constraint, see cgroupInode.PrepareMigrate.
+	if len(enable) > 0 && !c.isRoot() && len(c.ts) > 0 {
+		return linuxerr.EBUSY
+	}
+
+	// A controller can't be disabled while it's enabled further down the
+	// tree.
+	var busy bool
+	c.forEachChildDir(func(d *dir) {
+		for ty := range disable {
+			if _, ok := d.cgi.subtreeControl[ty]; ok {
+				busy = true
+			}
+		}
+	})
+	if busy {
+		return linuxerr.EBUSY
+	}
+
+	for ty := range enable {
+		c.subtreeControl[ty] = struct{}{}
+	}
+	for ty := range disable {
+		delete(c.subtreeControl, ty)
+	}
+	return nil
+}
+
+// +stateify savable
+type cgroupEventsData struct {
+	*cgroupInode
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cgroupEventsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	d.fs.tasksMu.RLock()
+	defer d.fs.tasksMu.RUnlock()
+
+	populated, frozen := 0, 0
+	if d.populatedLocked() {
+		populated = 1
+	}
+	if d.frozenLocked() {
+		frozen = 1
+	}
+	fmt.Fprintf(buf, "populated %d\n", populated)
+	fmt.Fprintf(buf, "frozen %d\n", frozen)
+	return nil
+}
+
+// +stateify savable
+type cgroupFreezeData struct {
+	*cgroupInode
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cgroupFreezeData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	d.fs.tasksMu.RLock()
+	defer d.fs.tasksMu.RUnlock()
+
+	val := 0
+	if d.freeze {
+		val = 1
+	}
+	fmt.Fprintf(buf, "%d\n", val)
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cgroupFreezeData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+func (d *cgroupFreezeData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	val, n, err := parseInt64FromString(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	if val != 0 && val != 1 {
+		return 0, linuxerr.EINVAL
+	}
+
+	d.fs.tasksMu.Lock()
+	d.freeze = val == 1
+	ms := d.collectSubtreeTasksLocked(nil)
+	d.fs.tasksMu.Unlock()
+
+	for _, m := range ms {
+		d.fs.reconcileFreeze(m.t, m.cg)
+	}
+	return n, nil
+}
+
+// cgroupThreadsData implements cgroup.threads. Since threaded cgroups aren't
+// supported, threads can't be moved independently of their thread group.
+//
+// +stateify savable
+type cgroupThreadsData struct {
+	*cgroupInode
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cgroupThreadsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	return (&tasksData{d.cgroupInode}).Generate(ctx, buf)
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cgroupThreadsData) Write(ctx context.Context, fd *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	tid, n, err := parseInt64FromString(ctx, src)
+	if err != nil {
+		return n, err
+	}
+
+	t := kernel.TaskFromContext(ctx)
+	currPidns := t.ThreadGroup().PIDNamespace()
+	targetTask := t
+	if tid != 0 {
+		targetTask = currPidns.TaskWithID(kernel.ThreadID(tid))
+	}
+	if targetTask == nil {
+		return 0, linuxerr.EINVAL
+	}
+
+	d.fs.tasksMu.RLock()
+	_, ok := d.ts[targetTask]
+	d.fs.tasksMu.RUnlock()
+	if !ok {
+		// Moving a thread to a different domain cgroup than the rest of its
+		// thread group. See Linux, kernel/cgroup/cgroup.c:
+		// cgroup_attach_permissions().
+		return 0, linuxerr.EOPNOTSUPP
+	}
+	return n, nil
+}
+
+// Valid implements kernfs.Inode.Valid.
+//
+// Control files on the unified hierarchy are removed from their cgroup when
+// their controller is disabled, so a cached dentry may refer to a control file
+// that no longer exists.
+func (f *controllerFile) Valid(ctx context.Context, parent *kernfs.Dentry, name string) bool {
+	return controlFileValid(ctx, parent, name)
+}
+
+// Valid implements kernfs.Inode.Valid.
+func (f *staticControllerFile) Valid(ctx context.Context, parent *kernfs.Dentry, name string) bool {
+	return controlFileValid(ctx, parent, name)
+}
+
+func controlFileValid(ctx context.Context, parent *kernfs.Dentry, name string) bool {
+	cgi, ok := parent.Inode().(*cgroupInode)
+	if !ok || !cgi.fs.unified {
+		return true
+	}
+	_, err := cgi.OrderedChildren.Lookup(ctx, name)
+	return err == nil
+}
+
+// parseMaxOrInt64 parses a cgroup v2 limit, which is either the string "max" or
+// an integer. "max" is returned as maxVal.
+func parseMaxOrInt64(str string, maxVal int64) (int64, error) {
+	if str == "max" {
+		return maxVal, nil
+	}
+	val, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return 0, linuxerr.EINVAL
+	}
+	return val, nil
+}
+
+// formatMaxOrInt64 formats a cgroup v2 limit, the inverse of parseMaxOrInt64.
+func formatMaxOrInt64(val, maxVal int64) string {
+	if val == maxVal {
+		return "max"
+	}
+	return strconv.FormatInt(val, 10)
+}
@@ -16,20 +16,30 @@
 package erofs
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync/atomic"
 
+	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/cleanup"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/erofs"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/fsutil"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/usermem"
 )
 
 // Name is the filesystem name. It is part of the interface used by users,
@@ -68,6 +78,14 @@ type filesystem struct {
 	// mf implements memmap.File for this image.
 	mf imageMemmapFile
 
+	// memFile is used to allocate memory that caches the decompressed data of
+	// regular files. memFile is immutable.
+	memFile *pgalloc.MemoryFile `state:"nosave"`
+
+	// dev is the block device that image is read from, or nil if image is
+	// read from a host file. dev is immutable.
+	dev *blockDevice
+
 	// inodeBuckets contains the inodes in use. Multiple buckets are used to
 	// reduce the lock contention. Bucket is chosen based on the hash calculation
 	// on nid in filesystem.inodeBucket.
@@ -97,23 +115,42 @@ func (FilesystemType) Release(ctx context.Context) {}
 
 // GetFilesystem implements vfs.FilesystemType.GetFilesystem.
 func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, source string, opts vfs.GetFilesystemOptions) (*vfs.Filesystem, *vfs.Dentry, error) {
+	memFile := pgalloc.MemoryFileFromContext(ctx)
+	if memFile == nil {
+		ctx.Warningf("erofs.FilesystemType.GetFilesystem: CtxMemoryFile is nil")
+		return nil, nil, linuxerr.EINVAL
+	}
+
 	mopts := vfs.GenericParseMountOptions(opts.Data)
 
 	var cu cleanup.Cleanup
 	defer cu.Clean()
 
-	fd, err := getFDFromMountOptionsMap(ctx, mopts)
-	if err != nil {
-		return nil, nil, err
+	var (
+		image *erofs.Image
+		dev   *blockDevice
+		err   error
+	)
+	if opts.InternalMount {
+		image, err = openImageFromMountOptionsMap(ctx, mopts)
+	} else {
+		// Applications can't pass host FDs, so they may only mount images
+		// stored on block devices, e.g. loop devices.
+		if _, ok := mopts[moptImageFD]; ok {
+			ctx.Warningf("erofs.FilesystemType.GetFilesystem: %s is not allowed in mount(2)", moptImageFD)
+			return nil, nil, linuxerr.EINVAL
+		}
+		image, dev, err = openImageFromBlockDevice(ctx, vfsObj, creds, source, memFile)
 	}
-
-	f := os.NewFile(uintptr(fd), "EROFS image file")
-	image, err := erofs.OpenImage(f)
 	if err != nil {
-		f.Close()
 		return nil, nil, err
 	}
-	cu.Add(func() { image.Close() })
+	cu.Add(func() {
+		image.Close()
+		if dev != nil {
+			dev.release(ctx, memFile)
+		}
+	})
 
 	iopts, ok := opts.InternalData.(InternalFilesystemOptions)
 	if opts.InternalData != nil && !ok {
@@ -132,7 +169,11 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		image:    image,
 		devMinor: devMinor,
 		mf:       imageMemmapFile{image: image},
+		memFile:  memFile,
+		dev:      dev,
 	}
+	// The block device is now released by fs.Release.
+	dev = nil
 	fs.vfsfs.Init(vfsObj, &fstype, fs)
 	cu.Add(func() { fs.vfsfs.DecRef(ctx) })
 
@@ -155,6 +196,165 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	return &fs.vfsfs, &root.vfsd, nil
 }
 
+// openImageFromMountOptionsMap opens the image whose host FD is given by mount
+// options.
+func openImageFromMountOptionsMap(ctx context.Context, mopts map[string]string) (*erofs.Image, error) {
+	fd, err := getFDFromMountOptionsMap(ctx, mopts)
+	if err != nil {
+		return nil, err
+	}
+
+	f := os.NewFile(uintptr(fd), "EROFS image file")
+	image, err := erofs.OpenImage(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return image, nil
+}
+
+// maxBlockDeviceImageSize is the maximum size of an image stored on a block
+// device.
+const maxBlockDeviceImageSize = 1 << 30
+
+// blockDevice is a block device that an image is read from.
+//
+// +stateify savable
+type blockDevice struct {
+	// fd is the open block device. A reference is held on fd.
+	fd *vfs.FileDescription
+
+	// fr is the range of memFile that the image is read into. Block devices
+	// in the sentry aren't backed by host files that can be mapped, so the
+	// image is read into memory allocated from memFile on demand, which is
+	// accounted like the page cache and saved along with it.
+	fr memmap.FileRange
+
+	// mapping is the mapping of fr that the image is read into.
+	mapping []byte `state:"nosave"`
+}
+
+// ReadAt implements io.ReaderAt.ReadAt.
+func (dev *blockDevice) ReadAt(dst []byte, off int64) (int, error) {
+	// The image is read on demand by whichever task first accesses each part
+	// of it, so the read can't depend on that task.
+	n, err := dev.fd.ReadFull(context.Background(), usermem.BytesIOSequence(dst), off)
+	return int(n), err
+}
+
+// release releases the resources held by dev.
+func (dev *blockDevice) release(ctx context.Context, memFile *pgalloc.MemoryFile) {
+	if dev.mapping != nil {
+		unix.Munmap(dev.mapping)
+	}
+	memFile.DecRef(dev.fr)
+	dev.fd.DecRef(ctx)
+}
+
+// mapImage maps dev.fr of memFile into dev.mapping.
+func (dev *blockDevice) mapImage(memFile *pgalloc.MemoryFile) error {
+	fd, err := memFile.DataFD(dev.fr)
+	if err != nil {
+		return err
+	}
+	mapping, err := unix.Mmap(fd, int64(dev.fr.Start), int(dev.fr.Length()), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+	if err != nil {
+		return err
+	}
+	dev.mapping = mapping
+	return nil
+}
+
+// openImageFromBlockDevice opens the image stored on the block device at
+// source, which is resolved relative to the root of ctx. The image is read
+// from the block device on demand.
+func openImageFromBlockDevice(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, source string, memFile *pgalloc.MemoryFile) (*erofs.Image, *blockDevice, error) {
+	if source == "" {
+		return nil, nil, linuxerr.ENOENT
+	}
+	root := vfs.RootFromContext(ctx)
+	if !root.Ok() {
+		return nil, nil, linuxerr.ENOENT
+	}
+	defer root.DecRef(ctx)
+	// O_NONBLOCK prevents blocking on FIFOs, which are rejected below.
+	fd, err := vfsObj.OpenAt(ctx, creds, &vfs.PathOperation{
+		Root:               root,
+		Start:              root,
+		Path:               fspath.Parse(source),
+		FollowFinalSymlink: true,
+	}, &vfs.OpenOptions{Flags: linux.O_RDONLY | linux.O_NONBLOCK})
+	if err != nil {
+		return nil, nil, err
+	}
+	var cu cleanup.Cleanup
+	defer cu.Clean()
+	cu.Add(func() { fd.DecRef(ctx) })
+	stat, err := fd.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_TYPE})
+	if err != nil {
+		return nil, nil, err
+	}
+	if stat.Mode&linux.S_IFMT != linux.S_IFBLK {
+		return nil, nil, linuxerr.ENOTBLK
+	}
+
+	// The size of the image is given by its superblock.
+	var sb erofs.SuperBlock
+	buf := make([]byte, sb.SizeBytes())
+	if _, err := fd.ReadFull(ctx, usermem.BytesIOSequence(buf), erofs.SuperBlockOffset); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			ctx.Warningf("erofs.openImageFromBlockDevice: no superblock on %s", source)
+			return nil, nil, linuxerr.EINVAL
+		}
+		return nil, nil, err
+	}
+	sb.UnmarshalBytes(buf)
+	if sb.Magic != erofs.SuperBlockMagicV1 {
+		ctx.Warningf("erofs.openImageFromBlockDevice: unknown magic 0x%x on %s", sb.Magic, source)
+		return nil, nil, linuxerr.EINVAL
+	}
+	size := sb.BlockAddrToOffset(sb.Blocks)
+	if size > maxBlockDeviceImageSize {
+		ctx.Warningf("erofs.openImageFromBlockDevice: image on %s is too large: %d bytes, maximum is %d", source, size, maxBlockDeviceImageSize)
+		return nil, nil, linuxerr.EFBIG
+	}
+	if size < erofs.SuperBlockOffset+uint64(sb.SizeBytes()) {
+		ctx.Warningf("erofs.openImageFromBlockDevice: image on %s is too small: %d bytes", source, size)
+		return nil, nil, linuxerr.EINVAL
+	}
+	// Check that the block device holds the whole image, so that truncated
+	// images fail to mount rather than fail when they are accessed.
+	if _, err := fd.ReadFull(ctx, usermem.BytesIOSequence(buf[:1]), int64(size-1)); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			ctx.Warningf("erofs.openImageFromBlockDevice: image on %s is truncated", source)
+			return nil, nil, linuxerr.EINVAL
+		}
+		return nil, nil, err
+	}
+
+	fr, err := memFile.Allocate(hostarch.MustPageRoundUp(size), pgalloc.AllocOpts{
+		Kind:    usage.PageCache,
+		MemCgID: pgalloc.MemoryCgroupIDFromContext(ctx),
+		Mode:    pgalloc.AllocateUncommitted,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	cu.Add(func() { memFile.DecRef(fr) })
+	dev := &blockDevice{fd: fd, fr: fr}
+	if err := dev.mapImage(memFile); err != nil {
+		return nil, nil, err
+	}
+	cu.Add(func() { unix.Munmap(dev.mapping) })
+	image, err := erofs.OpenImageReader(dev.mapping[:size], dev)
+	if err != nil {
+		ctx.Warningf("erofs.openImageFromBlockDevice: invalid image on %s: %v", source, err)
+		return nil, nil, linuxerr.EINVAL
+	}
+	cu.Release()
+	return image, dev, nil
+}
+
 func getFDFromMountOptionsMap(ctx context.Context, mopts map[string]string) (int, error) {
 	ifdstr, ok := mopts[moptImageFD]
 	if !ok {
@@ -179,9 +379,33 @@ func (fs *filesystem) Release(ctx context.Context) {
 		fs.root.DecRef(ctx)
 	}
 	fs.image.Close()
+	if fs.dev != nil {
+		fs.dev.release(ctx, fs.memFile)
+	}
 	fs.vfsfs.VirtualFilesystem().PutAnonBlockDevMinor(fs.devMinor)
 }
 
+// PrepareSave implements vfs.FilesystemImplSaveRestoreExtension.PrepareSave.
+func (fs *filesystem) PrepareSave(ctx context.Context) error {
+	return nil
+}
+
+// CompleteRestore implements
+// vfs.FilesystemImplSaveRestoreExtension.CompleteRestore.
+func (fs *filesystem) CompleteRestore(ctx context.Context, opts vfs.CompleteRestoreOptions) error {
+	if fs.dev == nil {
+		return nil
+	}
+	if err := fs.dev.mapImage(fs.memFile); err != nil {
+		return fmt.Errorf("failed to map EROFS image: %w", err)
+	}
+	sb := fs.image.SuperBlock()
+	if err := fs.image.RestoreReader(fs.dev.mapping[:sb.BlockAddrToOffset(sb.Blocks)], fs.dev); err != nil {
+		return fmt.Errorf("erofs.Image.RestoreReader failed: %w", err)
+	}
+	return nil
+}
+
 func (fs *filesystem) statFS() linux.Statfs {
 	blockSize := int64(fs.image.BlockSize())
 	return linux.Statfs{
@@ -275,6 +499,28 @@ type inode struct {
 	// +checklocks:mapsMu
 	mappings memmap.MappingSet
 
+	// dataMu protects cache.
+	dataMu sync.Mutex `state:"nosave"`
+
+	// If this inode represents a compressed regular file, cache maps offsets
+	// into the file to offsets into fs.memFile that store the file's
+	// decompressed data. It also caches the zero-filled pages that back the
+	// holes of chunk-based regular files.
+	// +checklocks:dataMu
+	cache fsutil.FileRangeSet
+
+	// extentMu protects extent and extentData.
+	extentMu sync.Mutex `state:"nosave"`
+
+	// If extentData is not nil, it holds the decompressed data of extent,
+	// the compressed extent that the page cache is being filled from. Since
+	// extents can only be decompressed from their beginning, extentData is
+	// kept until the page cache has been filled up to the end of extent.
+	// +checklocks:extentMu
+	extent erofs.Map `state:"nosave"`
+	// +checklocks:extentMu
+	extentData []byte `state:"nosave"`
+
 	// locks supports POSIX and BSD style locks.
 	locks vfs.FileLocks
 
@@ -316,6 +562,12 @@ func (i *inode) DecRef(ctx context.Context) {
 	i.inodeRefs.DecRef(func() {
 		nid := i.Nid()
 		i.fs.inodeBucket(nid).removeInode(nid)
+		i.dataMu.Lock()
+		i.cache.DropAll(i.fs.memFile)
+		i.dataMu.Unlock()
+		i.extentMu.Lock()
+		i.extentData = nil
+		i.extentMu.Unlock()
 	})
 }
 
@@ -344,6 +596,60 @@ func (i *inode) statTo(stat *linux.Statx) {
 	stat.Ctime = stat.Mtime
 	stat.DevMajor = linux.UNNAMED_MAJOR
 	stat.DevMinor = i.fs.devMinor
+	if i.IsCharDev() || i.IsBlockDev() {
+		major, minor := linux.DecodeDeviceID(i.Rdev())
+		stat.RdevMajor = uint32(major)
+		stat.RdevMinor = minor
+	}
+}
+
+func (i *inode) listXattr(creds *auth.Credentials, size uint64) ([]string, error) {
+	// Keep track of the size of the buffer needed in listxattr(2) for the list.
+	listSize := 0
+	var names []string
+	haveCap := creds.HasCapability(linux.CAP_SYS_ADMIN)
+	if err := i.IterXattrs(func(name string, _ []byte) error {
+		// Hide extended attributes in the "trusted" namespace from
+		// non-privileged users. This is consistent with Linux's
+		// fs/erofs/xattr.c:erofs_xattr_trusted_list().
+		if !haveCap && strings.HasPrefix(name, linux.XATTR_TRUSTED_PREFIX) {
+			return nil
+		}
+		names = append(names, name)
+		// Add one byte per null terminator.
+		listSize += len(name) + 1
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if size != 0 && uint64(listSize) > size {
+		return nil, linuxerr.ERANGE
+	}
+	return names, nil
+}
+
+func (i *inode) getXattr(creds *auth.Credentials, opts *vfs.GetXattrOptions) (string, error) {
+	mode := linux.FileMode(i.Mode())
+	kuid := auth.KUID(i.UID())
+	if err := i.checkPermissions(creds, vfs.MayRead); err != nil {
+		return "", err
+	}
+	if err := vfs.CheckXattrPermissions(creds, vfs.MayRead, mode, kuid, opts.Name); err != nil {
+		return "", err
+	}
+	value, err := i.Xattr(opts.Name)
+	if err != nil {
+		return "", err
+	}
+	// Check that the size of the buffer provided in getxattr(2) is large enough
+	// to contain the value.
+	if opts.Size != 0 && uint64(len(value)) > opts.Size {
+		return "", linuxerr.ERANGE
+	}
+	if opts.Name == linux.XATTR_SECURITY_CAPABILITY {
+		return auth.FixupVfsCapDataOnGet(creds, string(value))
+	}
+	return string(value), nil
 }
 
 func (i *inode) fileType() uint16 {
@@ -528,12 +834,12 @@ func (fd *fileDescription) StatFS(ctx context.Context) (linux.Statfs, error) {
 
 // ListXattr implements vfs.FileDescriptionImpl.ListXattr.
 func (fd *fileDescription) ListXattr(ctx context.Context, size uint64) ([]string, error) {
-	return nil, linuxerr.ENOTSUP
+	return fd.inode().listXattr(auth.CredentialsFromContext(ctx), size)
 }
 
 // GetXattr implements vfs.FileDescriptionImpl.GetXattr.
 func (fd *fileDescription) GetXattr(ctx context.Context, opts vfs.GetXattrOptions) (string, error) {
-	return "", linuxerr.ENOTSUP
+	return fd.inode().getXattr(auth.CredentialsFromContext(ctx), &opts)
 }
 
 // SetXattr implements vfs.FileDescriptionImpl.SetXattr.
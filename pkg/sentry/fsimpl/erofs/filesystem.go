@@ -398,18 +398,20 @@ func (fs *filesystem) BoundEndpointAt(ctx context.Context, rp *vfs.ResolvingPath
 
 // ListXattrAt implements vfs.FilesystemImpl.ListXattrAt.
 func (fs *filesystem) ListXattrAt(ctx context.Context, rp *vfs.ResolvingPath, size uint64) ([]string, error) {
-	if _, err := resolve(ctx, rp); err != nil {
+	d, err := resolve(ctx, rp)
+	if err != nil {
 		return nil, err
 	}
-	return nil, linuxerr.ENOTSUP
+	return d.inode.listXattr(rp.Credentials(), size)
 }
 
 // GetXattrAt implements vfs.FilesystemImpl.GetXattrAt.
 func (fs *filesystem) GetXattrAt(ctx context.Context, rp *vfs.ResolvingPath, opts vfs.GetXattrOptions) (string, error) {
-	if _, err := resolve(ctx, rp); err != nil {
+	d, err := resolve(ctx, rp)
+	if err != nil {
 		return "", err
 	}
-	return "", linuxerr.ENOTSUP
+	return d.inode.getXattr(rp.Credentials(), &opts)
 }
 
 // SetXattrAt implements vfs.FilesystemImpl.SetXattrAt.
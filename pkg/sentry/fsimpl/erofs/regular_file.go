@@ -26,6 +26,8 @@ import (
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/safemem"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/usermem"
 )
@@ -59,7 +61,18 @@ func (fd *regularFileFD) PRead(ctx context.Context, dst usermem.IOSequence, offs
 		return 0, nil
 	}
 
-	data, err := fd.inode().Data()
+	i := fd.inode()
+	switch i.DataLayout() {
+	case erofs.InodeDataLayoutFlatCompressionLegacy, erofs.InodeDataLayoutFlatCompression, erofs.InodeDataLayoutChunkBased:
+		r := &mappedFileReader{
+			ctx:   ctx,
+			inode: i,
+			off:   uint64(offset),
+		}
+		return dst.CopyOutFrom(ctx, r)
+	}
+
+	data, err := i.Data()
 	if err != nil {
 		return 0, err
 	}
@@ -85,6 +98,146 @@ func (r *regularFileReader) ReadToBlocks(dsts safemem.BlockSeq) (uint64, error)
 	return cp, err
 }
 
+// mappedFileReader reads the data of compressed and chunk-based regular files.
+type mappedFileReader struct {
+	ctx   context.Context
+	inode *inode
+	off   uint64
+}
+
+// ReadToBlocks implements safemem.Reader.ReadToBlocks.
+func (r *mappedFileReader) ReadToBlocks(dsts safemem.BlockSeq) (uint64, error) {
+	if r.off >= r.inode.Size() {
+		return 0, io.EOF
+	}
+	var (
+		cp  uint64
+		err error
+	)
+	if r.inode.DataLayout() == erofs.InodeDataLayoutChunkBased {
+		// Chunks are stored uncompressed, so they can be read from the
+		// devices directly.
+		dsts = dsts.TakeFirst64(r.inode.Size() - r.off)
+		cp, err = r.inode.readToBlocksAt(r.ctx, dsts, r.off)
+	} else {
+		// Decompressing is expensive, so read through the page cache.
+		cp, err = r.inode.readCached(r.ctx, dsts, r.off)
+	}
+	r.off += cp
+	return cp, err
+}
+
+// readToBlocksAt reads the data of this inode at offset into dsts.
+//
+// Preconditions: offset+dsts.NumBytes() <= i.Size().
+func (i *inode) readToBlocksAt(ctx context.Context, dsts safemem.BlockSeq, offset uint64) (uint64, error) {
+	switch i.DataLayout() {
+	case erofs.InodeDataLayoutFlatPlain, erofs.InodeDataLayoutFlatInline:
+		// Flat inodes are only read through the page cache if the image is
+		// in memory. Only the range being read is read from the image.
+		data, err := i.DataRange(offset, dsts.NumBytes())
+		if err != nil {
+			return 0, err
+		}
+		return safemem.CopySeq(dsts, data)
+	}
+	var done uint64
+	for !dsts.IsEmpty() {
+		m, err := i.MapBlocks(offset)
+		if err != nil {
+			return done, err
+		}
+		n := min(m.LogicalOff+m.LogicalLen-offset, dsts.NumBytes())
+		var src []byte
+		switch {
+		case !m.Mapped():
+			// Holes read as zeros.
+		case m.Flags&erofs.MapEncoded == 0:
+			src, err = i.fs.image.DeviceBytesAt(m.Device, m.PhysicalOff+(offset-m.LogicalOff), n)
+		default:
+			src, err = i.decompressedExtent(&m, offset+n == m.LogicalOff+m.LogicalLen)
+			if err == nil {
+				src = src[offset-m.LogicalOff:][:n]
+			}
+		}
+		if err != nil {
+			return done, err
+		}
+		var cp uint64
+		if src == nil {
+			cp, err = safemem.ZeroSeq(dsts.TakeFirst64(n))
+		} else {
+			cp, err = safemem.CopySeq(dsts, safemem.BlockSeqOf(safemem.BlockFromSafeSlice(src)))
+		}
+		done += cp
+		offset += cp
+		dsts = dsts.DropFirst64(cp)
+		if err != nil {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
+// decompressedExtent returns the decompressed data of the compressed extent m.
+// If last is true, the data is about to be read up to the end of m, so it
+// isn't cached anymore.
+func (i *inode) decompressedExtent(m *erofs.Map, last bool) ([]byte, error) {
+	i.extentMu.Lock()
+	defer i.extentMu.Unlock()
+	data := i.extentData
+	if data == nil || i.extent != *m {
+		data = make([]byte, m.LogicalLen)
+		if err := i.ReadExtent(m, data); err != nil {
+			return nil, err
+		}
+	}
+	if last {
+		i.extentData = nil
+	} else {
+		i.extent = *m
+		i.extentData = data
+	}
+	return data, nil
+}
+
+// readCached reads the data of this inode at offset into dsts through the page
+// cache, filling the cache as needed.
+//
+// Preconditions: offset < i.Size().
+func (i *inode) readCached(ctx context.Context, dsts safemem.BlockSeq, offset uint64) (uint64, error) {
+	end := min(offset+dsts.NumBytes(), i.Size())
+	pgend, _ := hostarch.PageRoundUp(end)
+	mr := memmap.MappableRange{hostarch.PageRoundDown(offset), pgend}
+
+	i.dataMu.Lock()
+	defer i.dataMu.Unlock()
+	_, cerr := i.cache.Fill(ctx, mr, mr, i.Size(), i.fs.memFile, pgalloc.AllocOpts{
+		Kind:    usage.PageCache,
+		MemCgID: pgalloc.MemoryCgroupIDFromContext(ctx),
+		Mode:    pgalloc.AllocateAndWritePopulate,
+	}, i.readToBlocksAt)
+
+	var done uint64
+	for seg := i.cache.FindSegment(offset); seg.Ok() && seg.Start() < end; seg, _ = seg.NextNonEmpty() {
+		segMR := seg.Range().Intersect(memmap.MappableRange{offset + done, end})
+		ims, err := i.fs.memFile.MapInternal(seg.FileRangeOf(segMR), hostarch.Read)
+		if err != nil {
+			return done, err
+		}
+		cp, err := safemem.CopySeq(dsts, ims)
+		done += cp
+		dsts = dsts.DropFirst64(cp)
+		if err != nil {
+			return done, err
+		}
+	}
+	if done < end-offset && cerr != nil {
+		return done, cerr
+	}
+	return done, nil
+}
+
 // Read implements vfs.FileDescriptionImpl.Read.
 func (fd *regularFileFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
 	fd.offMu.Lock()
@@ -173,6 +326,16 @@ func (i *inode) Translate(ctx context.Context, required, optional memmap.Mappabl
 		})
 		return nil, &memmap.BusError{linuxerr.EROFS}
 	}
+	if i.fs.image.InMemory() {
+		// There is no host FD to map the image from.
+		return i.translateCached(ctx, required, optional)
+	}
+	switch i.DataLayout() {
+	case erofs.InodeDataLayoutFlatCompressionLegacy, erofs.InodeDataLayoutFlatCompression:
+		return i.translateCached(ctx, required, optional)
+	case erofs.InodeDataLayoutChunkBased:
+		return i.translateChunks(ctx, required, optional)
+	}
 	offset, err := i.DataOffset()
 	if err != nil {
 		return nil, &memmap.BusError{err}
@@ -190,6 +353,90 @@ func (i *inode) Translate(ctx context.Context, required, optional memmap.Mappabl
 
 var inodeTranslateWriteWarnOnce sync.Once
 
+// translateChunks translates the mappings of this chunk-based inode. Chunks
+// are mapped from the devices directly, while holes are backed by the page
+// cache.
+func (i *inode) translateChunks(ctx context.Context, required, optional memmap.MappableRange) ([]memmap.Translation, error) {
+	if i.fs.image.BlockSize() < hostarch.PageSize {
+		// Chunks may not be page-aligned on the devices.
+		return i.translateCached(ctx, required, optional)
+	}
+	var ts []memmap.Translation
+	for off := required.Start; off < required.End; {
+		m, err := i.MapBlocks(off)
+		if err != nil {
+			return ts, &memmap.BusError{err}
+		}
+		mr := memmap.MappableRange{m.LogicalOff, m.LogicalOff + m.LogicalLen}.Intersect(optional)
+		off = m.LogicalOff + m.LogicalLen
+		if !m.Mapped() {
+			hts, err := i.translateCached(ctx, mr.Intersect(required), mr)
+			ts = append(ts, hts...)
+			if err != nil {
+				return ts, err
+			}
+			continue
+		}
+		ts = append(ts, memmap.Translation{
+			Source: mr,
+			File:   &i.fs.mf,
+			Offset: m.PhysicalOff + (mr.Start - m.LogicalOff),
+			Perms:  hostarch.ReadExecute,
+		})
+	}
+	return ts, nil
+}
+
+// translateCached translates the mappings of this inode using the page cache,
+// filling the cache as needed.
+func (i *inode) translateCached(ctx context.Context, required, optional memmap.MappableRange) ([]memmap.Translation, error) {
+	i.dataMu.Lock()
+	defer i.dataMu.Unlock()
+	_, cerr := i.cache.Fill(ctx, required, maxFillRange(required, optional), i.Size(), i.fs.memFile, pgalloc.AllocOpts{
+		Kind:    usage.PageCache,
+		MemCgID: pgalloc.MemoryCgroupIDFromContext(ctx),
+		Mode:    pgalloc.AllocateAndWritePopulate,
+	}, i.readToBlocksAt)
+
+	var ts []memmap.Translation
+	var translatedEnd uint64
+	for seg := i.cache.FindSegment(required.Start); seg.Ok() && seg.Start() < required.End; seg, _ = seg.NextNonEmpty() {
+		segMR := seg.Range().Intersect(optional)
+		ts = append(ts, memmap.Translation{
+			Source: segMR,
+			File:   i.fs.memFile,
+			Offset: seg.FileRangeOf(segMR).Start,
+			Perms:  hostarch.ReadExecute,
+		})
+		translatedEnd = segMR.End
+	}
+
+	// Don't return the error returned by i.cache.Fill if it occurred outside
+	// of required.
+	if translatedEnd < required.End && cerr != nil {
+		return ts, &memmap.BusError{cerr}
+	}
+	return ts, nil
+}
+
+// maxFillRange returns the range to fill in the page cache for a translation
+// of required, limiting readahead within optional.
+func maxFillRange(required, optional memmap.MappableRange) memmap.MappableRange {
+	const maxReadahead = 64 << 10 // 64 KB, chosen arbitrarily
+	if required.Length() >= maxReadahead {
+		return required
+	}
+	if optional.Length() <= maxReadahead {
+		return optional
+	}
+	optional.Start = required.Start
+	if optional.Length() <= maxReadahead {
+		return optional
+	}
+	optional.End = optional.Start + maxReadahead
+	return optional
+}
+
 // InvalidateUnsavable implements memmap.Mappable.InvalidateUnsavable.
 func (i *inode) InvalidateUnsavable(ctx context.Context) error {
 	i.mapsMu.Lock()
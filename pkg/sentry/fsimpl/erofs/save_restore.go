@@ -20,11 +20,18 @@ import (
 	"os"
 
 	"gvisor.dev/gvisor/pkg/erofs"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 )
 
 // afterLoad is called by stateify.
 func (fs *filesystem) afterLoad(ctx context.Context) {
+	fs.memFile = pgalloc.MemoryFileFromContext(ctx)
+	if fs.image.InMemory() {
+		// The image's memory was saved along with memFile, and is mapped
+		// again by CompleteRestore once memFile is loaded.
+		return
+	}
 	fdmap := vfs.RestoreFilesystemFDMapFromContext(ctx)
 	fd, ok := fdmap[fs.iopts.UniqueID]
 	if !ok {
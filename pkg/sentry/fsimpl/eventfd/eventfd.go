@@ -139,6 +139,10 @@ func (efd *EventFileDescription) HostFD() (int, error) {
 
 // Release implements vfs.FileDescriptionImpl.Release.
 func (efd *EventFileDescription) Release(context.Context) {
+	// Let in-kernel waiters, such as cgroup OOM notifications, drop their
+	// registrations. See Linux, fs/eventfd.c:eventfd_release().
+	efd.queue.Notify(waiter.EventHUp)
+
 	efd.mu.Lock()
 	defer efd.mu.Unlock()
 	if efd.hostfd >= 0 {
@@ -51,6 +51,11 @@ const (
 type connection struct {
 	fd *DeviceFD
 
+	// devices is the set of /dev/fuse file descriptions through which the
+	// FUSE server serves the connection: fd, and the ones attached to the
+	// connection with FUSE_DEV_IOC_CLONE. It is protected by fd.mu.
+	devices map[*DeviceFD]struct{}
+
 	// mu protects access to struct members.
 	mu sync.Mutex `state:"nosave"`
 
@@ -60,8 +65,6 @@ type connection struct {
 	// We target FUSE 7.23.
 	// The following FUSE_INIT flags are currently unsupported by this implementation:
 	//	- FUSE_EXPORT_SUPPORT
-	//	- FUSE_POSIX_LOCKS: requires POSIX locks
-	//	- FUSE_FLOCK_LOCKS: requires POSIX locks
 	//	- FUSE_AUTO_INVAL_DATA: requires page caching eviction
 	//	- FUSE_DO_READDIRPLUS/FUSE_READDIRPLUS_AUTO: requires FUSE_READDIRPLUS implementation
 	//	- FUSE_ASYNC_DIO
@@ -139,6 +142,19 @@ type connection struct {
 	// +checklocks:asyncMu
 	asyncNumMax uint16
 
+	// asyncLimitsFixed is true if asyncNumMax and asyncCongestionThreshold
+	// were set by the max_background and congestion_threshold mount options,
+	// in which case the values negotiated in FUSE_INIT are ignored.
+	// +checklocks:asyncMu
+	asyncLimitsFixed bool
+
+	// asyncQueue holds the async requests that are not queued for the FUSE
+	// server yet because asyncNum reached asyncNumMax. They are queued in order
+	// as earlier async requests complete. This is analogous to Linux's
+	// fc->bg_queue.
+	// +checklocks:asyncMu
+	asyncQueue requestList
+
 	// maxRead is the maximum size of a read buffer in in bytes.
 	// Initialized from a fuse fs parameter.
 	maxRead uint32
@@ -174,6 +190,16 @@ type connection struct {
 	// Negotiated and only set in INIT.
 	writebackCache bool
 
+	// posixLocks is true if the FUSE server implements POSIX locks, in which
+	// case fcntl(2) locks are forwarded to it.
+	// Negotiated and only set in INIT.
+	posixLocks bool
+
+	// flockLocks is true if the FUSE server implements BSD locks, in which
+	// case flock(2) locks are forwarded to it.
+	// Negotiated and only set in INIT.
+	flockLocks bool
+
 	// bigWrites if doing multi-page cached writes.
 	// Negotiated and only set in INIT.
 	bigWrites bool
@@ -185,6 +211,55 @@ type connection struct {
 	// noOpen if FUSE server doesn't support open operation.
 	// This flag only influences performance, not correctness of the program.
 	noOpen bool
+
+	// noGetxattr, noSetxattr, noListxattr and noRemovexattr are set when the
+	// FUSE server replies ENOSYS to the corresponding request. Subsequent
+	// requests then fail with EOPNOTSUPP without being sent to the server.
+	// This is analogous to Linux's fc->no_getxattr and friends.
+	noGetxattr    atomicbitops.Bool
+	noSetxattr    atomicbitops.Bool
+	noListxattr   atomicbitops.Bool
+	noRemovexattr atomicbitops.Bool
+
+	// noInterrupt is set when the FUSE server replies ENOSYS to a
+	// FUSE_INTERRUPT request, after which interrupts are no longer sent.
+	noInterrupt atomicbitops.Bool
+
+	// ioctl is true if the FUSE server implements FUSE_IOCTL, and ioctlDir is
+	// true if it also implements it for directories.
+	// Negotiated and only set in INIT.
+	ioctl    bool
+	ioctlDir bool
+
+	// noPoll, noLseek and noCopyFileRange are set if the FUSE server doesn't
+	// implement FUSE_POLL, FUSE_LSEEK and FUSE_COPY_FILE_RANGE respectively,
+	// either because its protocol version predates them or because it
+	// replied ENOSYS. The sentry then falls back to its own implementation.
+	// This is analogous to Linux's fc->no_poll and friends.
+	noPoll          atomicbitops.Bool
+	noLseek         atomicbitops.Bool
+	noCopyFileRange atomicbitops.Bool
+
+	// inodesMu protects inodes.
+	inodesMu sync.Mutex `state:"nosave"`
+
+	// inodes maps node IDs to the inodes with that ID, so that notifications
+	// from the FUSE server can find them. Hard links may yield several inodes
+	// with the same node ID.
+	// +checklocks:inodesMu
+	inodes map[uint64][]*inode
+
+	// pollMu protects the poll handle fields.
+	pollMu sync.Mutex `state:"nosave"`
+
+	// pollHandles maps the poll handles of files polled with FUSE_POLL to the
+	// files notified when the FUSE server sends FUSE_NOTIFY_POLL.
+	// +checklocks:pollMu
+	pollHandles map[uint64]*regularFileFD
+
+	// nextPollHandle is the next poll handle to allocate.
+	// +checklocks:pollMu
+	nextPollHandle uint64
 }
 
 func connError(err error) error {
@@ -225,12 +300,13 @@ func newFUSEConnection(_ context.Context, fuseFD *DeviceFD, opts *filesystemOpti
 	// synchronization and without checking if fuseFD has already been used to
 	// mount another filesystem.
 
-	// Create the writeBuf for the header to be stored in.
 	fuseFD.completions = make(map[linux.FUSEOpID]*futureResponse)
+	fuseFD.processing = make(map[linux.FUSEOpID]*futureResponse)
 	fuseFD.fullQueueCh = make(chan struct{}, opts.maxActiveRequests)
 
-	return &connection{
+	conn := &connection{
 		fd:                       fuseFD,
+		devices:                  map[*DeviceFD]struct{}{fuseFD: {}},
 		asyncNumMax:              fuseDefaultMaxBackground,
 		asyncCongestionThreshold: fuseDefaultCongestionThreshold,
 		maxRead:                  opts.maxRead,
@@ -238,7 +314,18 @@ func newFUSEConnection(_ context.Context, fuseFD *DeviceFD, opts *filesystemOpti
 		maxActiveRequests:        opts.maxActiveRequests,
 		initializedChan:          make(chan struct{}),
 		connected:                true,
-	}, nil
+		inodes:                   make(map[uint64][]*inode),
+		pollHandles:              make(map[uint64]*regularFileFD),
+	}
+	if opts.maxBackground != 0 {
+		conn.asyncNumMax = opts.maxBackground
+		conn.asyncLimitsFixed = true
+	}
+	if opts.congestionThreshold != 0 {
+		conn.asyncCongestionThreshold = opts.congestionThreshold
+		conn.asyncLimitsFixed = true
+	}
+	return conn, nil
 }
 
 // CallAsync makes an async (aka background) request.
@@ -299,11 +386,80 @@ func (conn *connection) Call(ctx context.Context, r *Request) (*Response, error)
 
 	res, err := fut.resolve(ctx)
 	if err != nil {
+		if r.interruptible && linuxerr.Equals(linuxerr.ErrInterrupted, err) {
+			return conn.interrupt(ctx, r, fut)
+		}
 		return res, connError(err)
 	}
 	return res, nil
 }
 
+// interrupt is called when the task waiting for the reply to the
+// interruptible request r is interrupted. If the server hasn't read r yet, r
+// is withdrawn and EINTR is returned. Otherwise the server is sent a
+// FUSE_INTERRUPT request, and the task keeps waiting for the reply to r, which
+// the server may send early, until it is killed. This is analogous to Linux's
+// fs/fuse/dev.c:request_wait_answer().
+func (conn *connection) interrupt(ctx context.Context, r *Request, fut *futureResponse) (*Response, error) {
+	conn.fd.mu.Lock()
+	if _, ok := conn.fd.completions[r.id]; !ok {
+		// The reply raced with the interruption.
+		conn.fd.mu.Unlock()
+		return fut.getResponse(), nil
+	}
+	for e := conn.fd.queue.Front(); e != nil; e = e.Next() {
+		if e != r {
+			continue
+		}
+		conn.fd.queue.Remove(r)
+		delete(conn.fd.completions, r.id)
+		conn.fd.numActiveRequests--
+		select {
+		case conn.fd.fullQueueCh <- struct{}{}:
+		default:
+		}
+		conn.fd.mu.Unlock()
+		return nil, linuxerr.EINTR
+	}
+	if !conn.noInterrupt.Load() {
+		// If this fails, the connection was aborted and fut is resolved by
+		// Abort.
+		conn.callFutureLocked(newInterruptRequest(r.id))
+	}
+	conn.fd.mu.Unlock()
+
+	for {
+		if err := ctx.Block(fut.ch); err == nil {
+			return fut.getResponse(), nil
+		}
+		if ctx.Killed() {
+			return conn.abandon(ctx, r, fut)
+		}
+	}
+}
+
+// abandon is called when the task waiting for the reply to the request r,
+// which the server has already read, is killed. The reply to r is dropped
+// when it arrives, and EINTR is returned.
+func (conn *connection) abandon(ctx context.Context, r *Request, fut *futureResponse) (*Response, error) {
+	conn.fd.mu.Lock()
+	defer conn.fd.mu.Unlock()
+	if _, ok := conn.fd.completions[r.id]; !ok {
+		// The reply raced with the kill.
+		return fut.getResponse(), nil
+	}
+	delete(conn.fd.completions, r.id)
+	if fut.dev != nil {
+		delete(fut.dev.processing, r.id)
+	}
+	conn.fd.numActiveRequests--
+	select {
+	case conn.fd.fullQueueCh <- struct{}{}:
+	default:
+	}
+	return nil, linuxerr.EINTR
+}
+
 // callFuture makes a request to the server and returns a future response.
 // Call resolve() when the response needs to be fulfilled.
 // +checklocks:conn.fd.mu
@@ -346,13 +502,77 @@ func (conn *connection) callFutureLocked(r *Request) (*futureResponse, error) {
 	}
 	conn.mu.Unlock()
 
-	conn.fd.queue.PushBack(r)
 	conn.fd.numActiveRequests++
 	fut := newFutureResponse(r)
 	conn.fd.completions[r.id] = fut
 
+	if r.async {
+		conn.asyncMu.Lock()
+		if conn.asyncNum >= conn.asyncNumMax {
+			// Too many async requests are being processed already, r is
+			// queued when one of them completes.
+			conn.asyncQueue.PushBack(r)
+			conn.asyncMu.Unlock()
+			return fut, nil
+		}
+		conn.asyncNum++
+		conn.asyncMu.Unlock()
+	}
+
+	if r.hdr.Opcode == linux.FUSE_INTERRUPT {
+		// Interrupts take priority over other requests.
+		conn.fd.queue.PushFront(r)
+	} else {
+		conn.fd.queue.PushBack(r)
+	}
+
 	// Signal the readers that there is something to read.
-	conn.fd.waitQueue.Notify(waiter.ReadableEvents)
+	conn.notifyDevicesLocked(waiter.ReadableEvents)
 
 	return fut, nil
 }
+
+// asyncDoneLocked is called when the FUSE server is done with an async
+// request. It queues the async requests that were held back by asyncNumMax,
+// as long as they fit. This is analogous to Linux's
+// fs/fuse/dev.c:flush_bg_queue().
+//
+// +checklocks:conn.fd.mu
+func (conn *connection) asyncDoneLocked() {
+	conn.asyncMu.Lock()
+	defer conn.asyncMu.Unlock()
+	// asyncNum is reset when the connection is aborted.
+	if conn.asyncNum > 0 {
+		conn.asyncNum--
+	}
+	queued := false
+	for conn.asyncNum < conn.asyncNumMax && !conn.asyncQueue.Empty() {
+		r := conn.asyncQueue.Front()
+		conn.asyncQueue.Remove(r)
+		conn.asyncNum++
+		conn.fd.queue.PushBack(r)
+		queued = true
+	}
+	if queued {
+		conn.notifyDevicesLocked(waiter.ReadableEvents)
+	}
+}
+
+// congested returns true if the number of async requests being processed by
+// the FUSE server reached the congestion threshold. Synchronous requests then
+// overtake the async ones that are queued.
+func (conn *connection) congested() bool {
+	conn.asyncMu.Lock()
+	defer conn.asyncMu.Unlock()
+	return conn.asyncNum >= conn.asyncCongestionThreshold
+}
+
+// notifyDevicesLocked notifies the waiters of all the /dev/fuse file
+// descriptions serving the connection.
+//
+// +checklocks:conn.fd.mu
+func (conn *connection) notifyDevicesLocked(mask waiter.EventMask) {
+	for dev := range conn.devices {
+		dev.waitQueue.Notify(mask)
+	}
+}
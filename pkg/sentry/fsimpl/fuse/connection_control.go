@@ -20,6 +20,7 @@ import (
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/waiter"
 )
 
 // consts used by FUSE_INIT negotiation.
@@ -42,7 +43,7 @@ const (
 
 	// The FUSE_INIT_IN flags sent to the daemon.
 	// TODO(gvisor.dev/issue/3199): complete the flags.
-	fuseDefaultInitFlags = linux.FUSE_MAX_PAGES
+	fuseDefaultInitFlags = linux.FUSE_MAX_PAGES | linux.FUSE_POSIX_LOCKS | linux.FUSE_FLOCK_LOCKS | linux.FUSE_HAS_IOCTL_DIR
 
 	// An INIT response needs to be at least this long.
 	minInitSize = 24
@@ -149,6 +150,14 @@ func (conn *connection) initProcessReply(out *linux.FUSEInitOut, hasSysAdminCap
 		conn.dontMask = out.Flags&linux.FUSE_DONT_MASK != 0
 		conn.writebackCache = out.Flags&linux.FUSE_WRITEBACK_CACHE != 0
 		conn.atomicOTrunc = out.Flags&linux.FUSE_ATOMIC_O_TRUNC != 0
+		conn.posixLocks = out.Flags&linux.FUSE_POSIX_LOCKS != 0
+		// Before minor version 17, servers implementing POSIX locks are
+		// assumed to implement BSD locks too.
+		if out.Minor >= 17 {
+			conn.flockLocks = out.Flags&linux.FUSE_FLOCK_LOCKS != 0
+		} else {
+			conn.flockLocks = conn.posixLocks
+		}
 
 		// TODO(gvisor.dev/issue/3195): figure out how to use TimeGran (0 < TimeGran <= fuseMaxTimeGranNs).
 
@@ -164,10 +173,20 @@ func (conn *connection) initProcessReply(out *linux.FUSEInitOut, hasSysAdminCap
 		}
 	}
 
-	// No support for limits before minor version 13.
-	if out.Minor >= 13 {
-		conn.asyncMu.Lock()
-
+	// FUSE_IOCTL and FUSE_POLL were introduced in minor version 11,
+	// FUSE_IOCTL on directories in minor version 18, FUSE_LSEEK in minor
+	// version 24 and FUSE_COPY_FILE_RANGE in minor version 28. Older servers
+	// don't know these opcodes, so they are never sent.
+	conn.ioctl = out.Minor >= 11
+	conn.ioctlDir = out.Minor >= 18 && out.Flags&linux.FUSE_HAS_IOCTL_DIR != 0
+	conn.noPoll.Store(out.Minor < 11)
+	conn.noLseek.Store(out.Minor < 24)
+	conn.noCopyFileRange.Store(out.Minor < 28)
+
+	// No support for limits before minor version 13. Limits set by mount
+	// options take precedence over the ones of the FUSE server.
+	conn.asyncMu.Lock()
+	if out.Minor >= 13 && !conn.asyncLimitsFixed {
 		if out.MaxBackground > 0 {
 			conn.asyncNumMax = out.MaxBackground
 
@@ -185,9 +204,8 @@ func (conn *connection) initProcessReply(out *linux.FUSEInitOut, hasSysAdminCap
 				conn.asyncCongestionThreshold = MaxUserCongestionThreshold
 			}
 		}
-
-		conn.asyncMu.Unlock()
 	}
+	conn.asyncMu.Unlock()
 
 	return nil
 }
@@ -217,6 +235,14 @@ func (conn *connection) Abort(ctx context.Context) {
 		conn.fd.queue.Remove(req)
 	}
 
+	// Likewise for the async requests held back by asyncNumMax, which are
+	// not counted in asyncNum.
+	for !conn.asyncQueue.Empty() {
+		req := conn.asyncQueue.Front()
+		conn.asyncQueue.Remove(req)
+	}
+	conn.asyncNum = 0
+
 	var terminate []linux.FUSEOpID
 
 	// 2. Collect the requests have not been sent to FUSE daemon,
@@ -248,5 +274,8 @@ func (conn *connection) Abort(ctx context.Context) {
 	// Will reach callFutureLocked() `connected` check and return.
 	close(conn.fd.fullQueueCh)
 
+	// Wake up the FUSE server threads waiting for requests.
+	conn.notifyDevicesLocked(waiter.ReadableEvents)
+
 	// TODO(gvisor.dev/issue/3528): Forget all pending forget reqs.
 }
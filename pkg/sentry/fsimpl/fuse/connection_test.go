@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
@@ -105,3 +106,79 @@ func TestConnectionAbort(t *testing.T) {
 	}
 
 }
+
+// TestConnectionInitOptionalOpcodes tests that opcodes introduced after the
+// first FUSE protocol versions are only sent to servers that know them.
+func TestConnectionInitOptionalOpcodes(t *testing.T) {
+	s := setup(t)
+	defer s.Destroy()
+
+	for _, tc := range []struct {
+		name            string
+		minor           uint32
+		flags           uint32
+		ioctl           bool
+		ioctlDir        bool
+		noPoll          bool
+		noLseek         bool
+		noCopyFileRange bool
+	}{
+		{
+			name:            "7.10",
+			minor:           10,
+			flags:           linux.FUSE_HAS_IOCTL_DIR,
+			noPoll:          true,
+			noLseek:         true,
+			noCopyFileRange: true,
+		},
+		{
+			name:            "7.18 without FUSE_HAS_IOCTL_DIR",
+			minor:           18,
+			ioctl:           true,
+			noLseek:         true,
+			noCopyFileRange: true,
+		},
+		{
+			name:            "7.24",
+			minor:           24,
+			flags:           linux.FUSE_HAS_IOCTL_DIR,
+			ioctl:           true,
+			ioctlDir:        true,
+			noCopyFileRange: true,
+		},
+		{
+			name:     "7.31",
+			minor:    31,
+			flags:    linux.FUSE_HAS_IOCTL_DIR,
+			ioctl:    true,
+			ioctlDir: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, _, err := newTestConnection(s, maxActiveRequestsDefault)
+			if err != nil {
+				t.Fatalf("newTestConnection: %v", err)
+			}
+			out := linux.FUSEInitOut{
+				Major: linux.FUSE_KERNEL_VERSION,
+				Minor: tc.minor,
+				Flags: tc.flags,
+			}
+			if err := conn.initProcessReply(&out, false /* hasSysAdminCap */); err != nil {
+				t.Fatalf("initProcessReply: %v", err)
+			}
+			if conn.ioctl != tc.ioctl || conn.ioctlDir != tc.ioctlDir {
+				t.Errorf("got ioctl %t and ioctlDir %t, want %t and %t", conn.ioctl, conn.ioctlDir, tc.ioctl, tc.ioctlDir)
+			}
+			if got := conn.noPoll.Load(); got != tc.noPoll {
+				t.Errorf("got noPoll %t, want %t", got, tc.noPoll)
+			}
+			if got := conn.noLseek.Load(); got != tc.noLseek {
+				t.Errorf("got noLseek %t, want %t", got, tc.noLseek)
+			}
+			if got := conn.noCopyFileRange.Load(); got != tc.noCopyFileRange {
+				t.Errorf("got noCopyFileRange %t, want %t", got, tc.noCopyFileRange)
+			}
+		})
+	}
+}
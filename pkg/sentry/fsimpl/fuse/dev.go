@@ -19,6 +19,8 @@ import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
@@ -74,6 +76,12 @@ type DeviceFD struct {
 	nextOpID linux.FUSEOpID
 
 	// queue is the list of requests that need to be processed by the FUSE server.
+	// Only the queue of the FD that the connection was created with is used:
+	// the FUSE server reads requests from it through any FD attached to the
+	// connection, including those attached with FUSE_DEV_IOC_CLONE. This is
+	// analogous to Linux's struct fuse_iqueue, which is shared by all the
+	// struct fuse_dev of a connection, unlike their struct fuse_pqueue (see
+	// processing).
 	// +checklocks:mu
 	queue requestList
 
@@ -87,10 +95,12 @@ type DeviceFD struct {
 	// +checklocks:mu
 	completions map[linux.FUSEOpID]*futureResponse
 
-	// writeBuf is the memory buffer used to copy in the FUSE out header from
-	// userspace.
-	// +checklocks:mu
-	writeBuf [fuseHeaderOutSize]byte
+	// processing maps the requests that the FUSE server read through this FD,
+	// and that still expect a reply, to their future response. The server
+	// must reply to a request through the FD it read it from. This is
+	// analogous to Linux's struct fuse_pqueue. It is protected by conn.fd.mu,
+	// which is mu unless this FD was attached to conn with FUSE_DEV_IOC_CLONE.
+	processing map[linux.FUSEOpID]*futureResponse
 
 	// conn is the FUSE connection that this FD is being used for.
 	// +checklocks:mu
@@ -99,19 +109,92 @@ type DeviceFD struct {
 
 // Release implements vfs.FileDescriptionImpl.Release.
 func (fd *DeviceFD) Release(ctx context.Context) {
+	conn := fd.attachedConn()
+	if conn == nil {
+		return
+	}
+	q := conn.fd
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(conn.devices, fd)
+	if len(conn.devices) != 0 {
+		// The FUSE server keeps serving the connection through other FDs, but
+		// can't reply to the requests it read through this one anymore.
+		for id := range fd.processing {
+			q.sendError(ctx, -int32(unix.ECONNABORTED), id)
+		}
+		return
+	}
+
+	conn.mu.Lock()
+	conn.connected = false
+	conn.mu.Unlock()
+
+	conn.Abort(ctx) // +checklocksforce: conn.fd.mu=q.mu
+	fd.waitQueue.Notify(waiter.ReadableEvents)
+	if fd == q {
+		fd.conn = nil // +checklocksforce: fd.mu=q.mu
+	}
+}
+
+// attachedConn returns the FUSE connection that fd is attached to, if any.
+func (fd *DeviceFD) attachedConn() *connection {
 	fd.mu.Lock()
 	defer fd.mu.Unlock()
-	if fd.conn != nil {
-		fd.conn.mu.Lock()
-		fd.conn.connected = false
-		fd.conn.mu.Unlock()
+	return fd.conn
+}
 
-		fd.conn.Abort(ctx) // +checklocksforce: fd.conn.fd.mu=fd.mu
-		fd.waitQueue.Notify(waiter.ReadableEvents)
-		fd.conn = nil
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *DeviceFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	switch args[1].Uint() {
+	case linux.FUSE_DEV_IOC_CLONE:
+		t := kernel.TaskFromContext(ctx)
+		if t == nil {
+			panic("Ioctl should be called from a task context")
+		}
+		var oldFD primitive.Uint32
+		if _, err := oldFD.CopyIn(t, args[2].Pointer()); err != nil {
+			return 0, err
+		}
+		file := t.GetFile(int32(oldFD))
+		if file == nil {
+			return 0, linuxerr.EINVAL
+		}
+		defer file.DecRef(ctx)
+		old, ok := file.Impl().(*DeviceFD)
+		if !ok {
+			return 0, linuxerr.EINVAL
+		}
+		return 0, fd.clone(old)
+	default:
+		return 0, linuxerr.ENOTTY
 	}
 }
 
+// clone attaches fd to the FUSE connection that old is attached to, so that
+// the FUSE server can read requests and reply to them through fd too. This is
+// analogous to Linux's fs/fuse/dev.c:fuse_device_clone().
+func (fd *DeviceFD) clone(old *DeviceFD) error {
+	if fd == old {
+		return linuxerr.EINVAL
+	}
+	conn := old.attachedConn()
+	if conn == nil {
+		return linuxerr.EINVAL
+	}
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if fd.conn != nil {
+		return linuxerr.EINVAL
+	}
+	conn.fd.mu.Lock()
+	defer conn.fd.mu.Unlock()
+	fd.processing = make(map[linux.FUSEOpID]*futureResponse)
+	conn.devices[fd] = struct{}{}
+	fd.conn = conn
+	return nil
+}
+
 // connected returns true if fd.conn is set and the connection has not been
 // aborted.
 // +checklocks:fd.mu
@@ -140,9 +223,15 @@ func (fd *DeviceFD) PRead(ctx context.Context, dst usermem.IOSequence, offset in
 
 // Read implements vfs.FileDescriptionImpl.Read.
 func (fd *DeviceFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
-	fd.mu.Lock()
-	defer fd.mu.Unlock()
-	if !fd.connected() {
+	conn := fd.attachedConn()
+	if conn == nil {
+		return 0, linuxerr.EPERM
+	}
+	// The requests are queued on the FD the connection was created with.
+	q := conn.fd
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.connected() {
 		return 0, linuxerr.EPERM
 	}
 	// We require that any Read done on this filesystem have a sane minimum
@@ -150,9 +239,9 @@ func (fd *DeviceFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.R
 	// header (Linux uses the request header and the FUSEWriteIn header for this
 	// calculation) + the negotiated MaxWrite room for the data.
 	minBuffSize := linux.FUSE_MIN_READ_BUFFER
-	fd.conn.mu.Lock()
-	negotiatedMinBuffSize := linux.SizeOfFUSEHeaderIn + linux.SizeOfFUSEHeaderOut + fd.conn.maxWrite
-	fd.conn.mu.Unlock()
+	conn.mu.Lock()
+	negotiatedMinBuffSize := linux.SizeOfFUSEHeaderIn + linux.SizeOfFUSEHeaderOut + conn.maxWrite
+	conn.mu.Unlock()
 	if minBuffSize < negotiatedMinBuffSize {
 		minBuffSize = negotiatedMinBuffSize
 	}
@@ -164,7 +253,16 @@ func (fd *DeviceFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.R
 	// Find the first valid request. For the normal case this loop only executes
 	// once.
 	var req *Request
-	for req = fd.queue.Front(); !fd.queue.Empty(); req = fd.queue.Front() {
+	for req = q.nextRequestLocked(); req != nil; req = q.nextRequestLocked() {
+		if req.hdr.Opcode == linux.FUSE_INTERRUPT {
+			if _, ok := q.completions[req.hdr.Unique&^linux.FUSE_INT_REQ_BIT]; !ok {
+				// The interrupted request was already answered.
+				q.queue.Remove(req)
+				q.numActiveRequests--
+				delete(q.completions, req.id)
+				continue
+			}
+		}
 		if int64(req.hdr.Len) <= dst.NumBytes() {
 			break
 		}
@@ -176,11 +274,10 @@ func (fd *DeviceFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.R
 			errno = -int32(unix.E2BIG)
 		}
 
-		if err := fd.sendError(ctx, errno, req.hdr.Unique); err != nil {
+		if err := q.sendError(ctx, errno, req.id); err != nil {
 			return 0, err
 		}
-		fd.queue.Remove(req)
-		req = nil
+		q.queue.Remove(req)
 	}
 	if req == nil {
 		return 0, linuxerr.ErrWouldBlock
@@ -194,15 +291,41 @@ func (fd *DeviceFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.R
 	if n != len(req.data) {
 		return 0, linuxerr.EIO
 	}
-	fd.queue.Remove(req)
+	q.queue.Remove(req)
 	// Remove noReply ones from the map of requests expecting a reply.
 	if req.noReply {
-		fd.numActiveRequests--
-		delete(fd.completions, req.hdr.Unique)
+		q.numActiveRequests--
+		delete(q.completions, req.id)
+		if req.async {
+			conn.asyncDoneLocked() // +checklocksforce: conn.fd.mu=q.mu
+		}
+	} else {
+		fut := q.completions[req.id]
+		fut.dev = fd
+		fd.processing[req.id] = fut
 	}
 	return int64(n), nil
 }
 
+// nextRequestLocked returns the next request for the FUSE server to read, or
+// nil if there is none. Requests are read in order, except that synchronous
+// requests overtake async ones while the connection is congested, as tasks are
+// waiting for them.
+//
+// +checklocks:fd.mu
+func (fd *DeviceFD) nextRequestLocked() *Request {
+	req := fd.queue.Front()
+	if req == nil || !req.async || !fd.conn.congested() {
+		return req
+	}
+	for r := req.Next(); r != nil; r = r.Next() {
+		if !r.async {
+			return r
+		}
+	}
+	return req
+}
+
 // PWrite implements vfs.FileDescriptionImpl.PWrite.
 func (fd *DeviceFD) PWrite(ctx context.Context, src usermem.IOSequence, offset int64, opts vfs.WriteOptions) (int64, error) {
 	// Operations on /dev/fuse don't make sense until a FUSE filesystem is
@@ -219,47 +342,78 @@ func (fd *DeviceFD) PWrite(ctx context.Context, src usermem.IOSequence, offset i
 
 // Write implements vfs.FileDescriptionImpl.Write.
 func (fd *DeviceFD) Write(ctx context.Context, src usermem.IOSequence, opts vfs.WriteOptions) (int64, error) {
-	fd.mu.Lock()
-	defer fd.mu.Unlock()
-	if !fd.connected() {
+	conn := fd.attachedConn()
+	if conn == nil {
 		return 0, linuxerr.EPERM
 	}
-
 	var hdr linux.FUSEHeaderOut
 	if src.NumBytes() < int64(hdr.SizeBytes()) {
 		return 0, linuxerr.EINVAL
 	}
-	n, err := src.CopyIn(ctx, fd.writeBuf[:])
+	var buf [fuseHeaderOutSize]byte
+	n, err := src.CopyIn(ctx, buf[:])
 	if err != nil {
 		return 0, err
 	}
-	hdr.UnmarshalBytes(fd.writeBuf[:])
+	hdr.UnmarshalBytes(buf[:])
 	if src.NumBytes() != int64(hdr.Len) {
 		return 0, linuxerr.EINVAL
 	}
 
-	fut, ok := fd.completions[hdr.Unique]
+	if hdr.Unique == 0 {
+		// This is a notification rather than a reply. It is handled without
+		// holding conn.fd.mu, since that requires locking inodes.
+		conn.mu.Lock()
+		connected := conn.connected
+		conn.mu.Unlock()
+		if !connected {
+			return 0, linuxerr.EPERM
+		}
+		if err := conn.notify(ctx, &hdr, src.DropFirst(hdr.SizeBytes())); err != nil {
+			return 0, err
+		}
+		return src.NumBytes(), nil
+	}
+
+	// The requests are tracked by the FD the connection was created with.
+	q := conn.fd
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.connected() {
+		return 0, linuxerr.EPERM
+	}
+
+	if hdr.Unique&linux.FUSE_INT_REQ_BIT != 0 {
+		if err := q.handleInterruptReply(&hdr); err != nil {
+			return 0, err
+		}
+		return int64(n), nil
+	}
+
+	fut, ok := fd.processing[hdr.Unique]
 	if !ok {
-		// Server sent us a response for a request we never sent, or for which we
-		// already received a reply (e.g. aborted), an unlikely event.
+		// Server sent us a response for a request we never sent, for which we
+		// already received a reply (e.g. aborted), or that it read through
+		// another FD, an unlikely event.
 		return 0, linuxerr.EINVAL
 	}
-	delete(fd.completions, hdr.Unique)
+	delete(fd.processing, hdr.Unique)
+	delete(q.completions, hdr.Unique)
 
 	// Copy over the header into the future response. The rest of the payload
 	// will be copied over to the FR's data in the next iteration.
 	fut.hdr = &hdr
 	fut.data = make([]byte, fut.hdr.Len)
-	copy(fut.data, fd.writeBuf[:])
-	if fut.hdr.Len > uint32(len(fd.writeBuf)) {
-		src = src.DropFirst(len(fd.writeBuf))
-		n2, err := src.CopyIn(ctx, fut.data[len(fd.writeBuf):])
+	copy(fut.data, buf[:])
+	if fut.hdr.Len > uint32(len(buf)) {
+		src = src.DropFirst(len(buf))
+		n2, err := src.CopyIn(ctx, fut.data[len(buf):])
 		if err != nil {
 			return 0, err
 		}
 		n += n2
 	}
-	if err := fd.sendResponse(ctx, fut); err != nil {
+	if err := q.sendResponse(ctx, fut); err != nil {
 		return 0, err
 	}
 	return int64(n), nil
@@ -267,18 +421,24 @@ func (fd *DeviceFD) Write(ctx context.Context, src usermem.IOSequence, opts vfs.
 
 // Readiness implements vfs.FileDescriptionImpl.Readiness.
 func (fd *DeviceFD) Readiness(mask waiter.EventMask) waiter.EventMask {
-	fd.mu.Lock()
-	defer fd.mu.Unlock()
 	var ready waiter.EventMask
+	conn := fd.attachedConn()
+	if conn == nil {
+		ready |= waiter.EventErr
+		return ready & mask
+	}
+	q := conn.fd
+	q.mu.Lock()
+	defer q.mu.Unlock()
 
-	if !fd.connected() {
+	if !q.connected() {
 		ready |= waiter.EventErr
 		return ready & mask
 	}
 
 	// FD is always writable.
 	ready |= waiter.WritableEvents
-	if !fd.queue.Empty() {
+	if !q.queue.Empty() {
 		// Have reqs available, FD is readable.
 		ready |= waiter.ReadableEvents
 	}
@@ -320,6 +480,32 @@ func (fd *DeviceFD) Seek(ctx context.Context, offset int64, whence int32) (int64
 	return 0, linuxerr.ENOSYS
 }
 
+// handleInterruptReply handles a reply to a FUSE_INTERRUPT request. The server
+// only replies to refuse an interrupt: with ENOSYS if it doesn't support
+// interrupts at all, or with EAGAIN if the interrupt should be sent again.
+// This is analogous to the FUSE_INT_REQ_BIT case of Linux's
+// fs/fuse/dev.c:fuse_dev_do_write().
+//
+// +checklocks:fd.mu
+func (fd *DeviceFD) handleInterruptReply(hdr *linux.FUSEHeaderOut) error {
+	id := hdr.Unique &^ linux.FUSE_INT_REQ_BIT
+	if _, ok := fd.completions[id]; !ok {
+		return linuxerr.ENOENT
+	}
+	if hdr.Len != linux.SizeOfFUSEHeaderOut {
+		return linuxerr.EINVAL
+	}
+	switch unix.Errno(-hdr.Error) {
+	case unix.ENOSYS:
+		fd.conn.noInterrupt.Store(true)
+	case unix.EAGAIN:
+		if _, err := fd.conn.callFutureLocked(newInterruptRequest(id)); err != nil { // +checklocksforce: fd.conn.fd.mu=fd.mu
+			return err
+		}
+	}
+	return nil
+}
+
 // sendResponse sends a response to the waiting task (if any).
 //
 // +checklocks:fd.mu
@@ -335,7 +521,8 @@ func (fd *DeviceFD) sendResponse(ctx context.Context, fut *futureResponse) error
 	fd.numActiveRequests--
 
 	if fut.async {
-		return fd.asyncCallBack(ctx, fut.getResponse())
+		fd.conn.asyncDoneLocked() // +checklocksforce: fd.conn.fd.mu=fd.mu
+		return fd.asyncCallBack(ctx, fut)
 	}
 
 	return nil
@@ -359,21 +546,27 @@ func (fd *DeviceFD) sendError(ctx context.Context, errno int32, unique linux.FUS
 		return linuxerr.EINVAL
 	}
 	delete(fd.completions, respHdr.Unique)
+	if fut.dev != nil {
+		delete(fut.dev.processing, respHdr.Unique)
+	}
 
 	fut.hdr = &respHdr
 	return fd.sendResponse(ctx, fut)
 }
 
 // asyncCallBack executes pre-defined callback function for async requests.
-// Currently used by: FUSE_INIT.
+// Currently used by: FUSE_INIT, FUSE_POLL.
 // +checklocks:fd.mu
-func (fd *DeviceFD) asyncCallBack(ctx context.Context, r *Response) error {
+func (fd *DeviceFD) asyncCallBack(ctx context.Context, fut *futureResponse) error {
+	r := fut.getResponse()
 	switch r.opcode {
 	case linux.FUSE_INIT:
 		creds := auth.CredentialsFromContext(ctx)
 		rootUserNs := kernel.KernelFromContext(ctx).RootUserNamespace()
 		return fd.conn.InitRecv(r, creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, rootUserNs))
 		// TODO(gvisor.dev/issue/3247): support async read: correctly process the response.
+	case linux.FUSE_POLL:
+		fut.pollFD.pollDone(r)
 	}
 
 	return nil
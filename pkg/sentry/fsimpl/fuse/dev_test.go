@@ -19,6 +19,7 @@ import (
 	"math/rand"
 	"testing"
 
+	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
@@ -345,3 +346,177 @@ func fuseServerRun(t *testing.T, s *testutil.System, k *kernel.Kernel, fd *vfs.F
 		}
 	}
 }
+
+// readTestRequest reads a request from the FUSE device fd and returns its
+// header.
+func readTestRequest(t *testing.T, s *testutil.System, fd *DeviceFD) linux.FUSEHeaderIn {
+	t.Helper()
+	buf := make([]byte, linux.FUSE_MIN_READ_BUFFER)
+	if _, err := fd.Read(s.Ctx, usermem.BytesIOSequence(buf), vfs.ReadOptions{}); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	var hdr linux.FUSEHeaderIn
+	hdr.UnmarshalUnsafe(buf)
+	return hdr
+}
+
+// writeTestReply writes an empty reply to the request with the given ID to
+// the FUSE device fd.
+func writeTestReply(s *testutil.System, fd *DeviceFD, unique linux.FUSEOpID) error {
+	hdr := linux.FUSEHeaderOut{
+		Len:    linux.SizeOfFUSEHeaderOut,
+		Unique: unique,
+	}
+	buf := make([]byte, hdr.SizeBytes())
+	hdr.MarshalUnsafe(buf)
+	_, err := fd.Write(s.Ctx, usermem.BytesIOSequence(buf), vfs.WriteOptions{})
+	return err
+}
+
+// queueTestRequest queues a request for the FUSE server of conn.
+func queueTestRequest(t *testing.T, s *testutil.System, conn *connection, async bool) (*Request, *futureResponse) {
+	t.Helper()
+	testObj := primitive.Uint32(rand.Uint32())
+	req := conn.NewRequest(auth.CredentialsFromContext(s.Ctx), 0 /* pid */, 0 /* ino */, echoTestOpcode, &testObj)
+	req.async = async
+	conn.fd.mu.Lock()
+	fut, err := conn.callFutureLocked(req)
+	conn.fd.mu.Unlock()
+	if err != nil {
+		t.Fatalf("callFutureLocked: %v", err)
+	}
+	return req, fut
+}
+
+// TestDeviceClone tests that FUSE devices attached to a connection with
+// FUSE_DEV_IOC_CLONE serve its requests.
+func TestDeviceClone(t *testing.T) {
+	s := setup(t)
+	defer s.Destroy()
+	conn, fd, err := newTestConnection(s, maxActiveRequestsDefault)
+	if err != nil {
+		t.Fatalf("newTestConnection: %v", err)
+	}
+	master := fd.Impl().(*DeviceFD)
+	clone, err := newTestDevice(s)
+	if err != nil {
+		t.Fatalf("newTestDevice: %v", err)
+	}
+	if err := clone.clone(master); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if err := clone.clone(master); !linuxerr.Equals(linuxerr.EINVAL, err) {
+		t.Errorf("clone of an attached device got error %v, want EINVAL", err)
+	}
+
+	// Replies must be written to the device the request was read from.
+	req, fut := queueTestRequest(t, s, conn, false /* async */)
+	if hdr := readTestRequest(t, s, clone); hdr.Unique != req.id {
+		t.Fatalf("read request %d, want %d", hdr.Unique, req.id)
+	}
+	if err := writeTestReply(s, master, req.id); !linuxerr.Equals(linuxerr.EINVAL, err) {
+		t.Errorf("reply through another device got error %v, want EINVAL", err)
+	}
+	if err := writeTestReply(s, clone, req.id); err != nil {
+		t.Fatalf("writeTestReply: %v", err)
+	}
+	select {
+	case <-fut.ch:
+	default:
+		t.Errorf("request not completed by the reply")
+	}
+
+	// Releasing the clone aborts the requests read through it, but not the
+	// connection.
+	_, fut = queueTestRequest(t, s, conn, false /* async */)
+	readTestRequest(t, s, clone)
+	clone.vfsfd.DecRef(s.Ctx)
+	select {
+	case <-fut.ch:
+		if got, want := fut.getResponse().hdr.Error, -int32(unix.ECONNABORTED); got != want {
+			t.Errorf("request read through a released device got error %d, want %d", got, want)
+		}
+	default:
+		t.Errorf("request read through a released device not completed")
+	}
+	conn.mu.Lock()
+	connected := conn.connected
+	conn.mu.Unlock()
+	if !connected {
+		t.Errorf("connection aborted by the release of a clone")
+	}
+}
+
+// TestAsyncRequestLimit tests that async requests above the MaxBackground
+// limit are held back until earlier ones complete.
+func TestAsyncRequestLimit(t *testing.T) {
+	s := setup(t)
+	defer s.Destroy()
+	conn, fd, err := newTestConnection(s, maxActiveRequestsDefault)
+	if err != nil {
+		t.Fatalf("newTestConnection: %v", err)
+	}
+	dev := fd.Impl().(*DeviceFD)
+	conn.asyncMu.Lock()
+	conn.asyncNumMax = 2
+	conn.asyncCongestionThreshold = 2
+	conn.asyncMu.Unlock()
+
+	var reqs []*Request
+	for i := 0; i < 3; i++ {
+		req, _ := queueTestRequest(t, s, conn, true /* async */)
+		reqs = append(reqs, req)
+	}
+	first := readTestRequest(t, s, dev)
+	readTestRequest(t, s, dev)
+	buf := make([]byte, linux.FUSE_MIN_READ_BUFFER)
+	if _, err := dev.Read(s.Ctx, usermem.BytesIOSequence(buf), vfs.ReadOptions{}); err != linuxerr.ErrWouldBlock {
+		t.Fatalf("Read above the async request limit got error %v, want ErrWouldBlock", err)
+	}
+
+	if err := writeTestReply(s, dev, first.Unique); err != nil {
+		t.Fatalf("writeTestReply: %v", err)
+	}
+	if hdr := readTestRequest(t, s, dev); hdr.Unique != reqs[2].id {
+		t.Errorf("read request %d after a reply, want %d", hdr.Unique, reqs[2].id)
+	}
+}
+
+// TestCongestedDispatch tests that synchronous requests overtake async ones
+// while the connection is congested.
+func TestCongestedDispatch(t *testing.T) {
+	s := setup(t)
+	defer s.Destroy()
+	conn, fd, err := newTestConnection(s, maxActiveRequestsDefault)
+	if err != nil {
+		t.Fatalf("newTestConnection: %v", err)
+	}
+	dev := fd.Impl().(*DeviceFD)
+	conn.asyncMu.Lock()
+	conn.asyncNumMax = 4
+	conn.asyncCongestionThreshold = 2
+	conn.asyncMu.Unlock()
+
+	async1, _ := queueTestRequest(t, s, conn, true /* async */)
+	async2, _ := queueTestRequest(t, s, conn, true /* async */)
+	syncReq, _ := queueTestRequest(t, s, conn, false /* async */)
+	for _, want := range []*Request{syncReq, async1, async2} {
+		if hdr := readTestRequest(t, s, dev); hdr.Unique != want.id {
+			t.Errorf("read request %d, want %d", hdr.Unique, want.id)
+		}
+	}
+
+	// Without congestion, requests are read in order.
+	for _, req := range []*Request{async1, async2} {
+		if err := writeTestReply(s, dev, req.id); err != nil {
+			t.Fatalf("writeTestReply: %v", err)
+		}
+	}
+	async3, _ := queueTestRequest(t, s, conn, true /* async */)
+	syncReq, _ = queueTestRequest(t, s, conn, false /* async */)
+	for _, want := range []*Request{async3, syncReq} {
+		if hdr := readTestRequest(t, s, dev); hdr.Unique != want.id {
+			t.Errorf("read request %d, want %d", hdr.Unique, want.id)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/usermem"
@@ -48,6 +49,15 @@ type fileDescription struct {
 
 	// off is the file offset.
 	off atomicbitops.Int64
+
+	// lockOwner identifies this file description as the owner of the BSD and
+	// open file description locks it holds, to the FUSE server. lockOwner is
+	// immutable.
+	lockOwner uint64
+
+	// flock is set once a BSD lock has been forwarded to the FUSE server
+	// through this file description.
+	flock atomicbitops.Bool
 }
 
 func (fd *fileDescription) dentry() *kernfs.Dentry {
@@ -78,7 +88,11 @@ func (fd *fileDescription) Release(ctx context.Context) {
 		Fh:    fd.Fh,
 		Flags: fd.statusFlags(),
 	}
-	// TODO(gvisor.dev/issue/3245): add logic when we support file lock owners.
+	if fd.flock.Load() {
+		// Let the server release the BSD locks held by fd.
+		in.ReleaseFlags |= linux.FUSE_RELEASE_FLOCK_UNLOCK
+		in.LockOwner = fd.lockOwner
+	}
 	inode := fd.inode()
 	inode.attrMu.Lock()
 	defer inode.attrMu.Unlock()
@@ -102,8 +116,14 @@ func (fd *fileDescription) OnClose(ctx context.Context) error {
 	defer inode.attrMu.Unlock()
 
 	in := linux.FUSEFlushIn{
-		Fh:        fd.Fh,
-		LockOwner: 0, // TODO(gvisor.dev/issue/3245): file lock
+		Fh: fd.Fh,
+	}
+	// The server is expected to release the POSIX locks held by the closing
+	// FDTable.
+	if t := kernel.TaskFromContext(ctx); t != nil {
+		if fdTable := t.FDTable(); fdTable != nil {
+			in.LockOwner = fdTable.ID()
+		}
 	}
 	req := conn.NewRequest(auth.CredentialsFromContext(ctx), pidFromContext(ctx), inode.nodeID, linux.FUSE_FLUSH, &in)
 	return conn.CallAsync(ctx, req)
@@ -175,3 +195,23 @@ func (fd *fileDescription) Sync(ctx context.Context) error {
 	conn.CallAsync(ctx, req)
 	return nil
 }
+
+// ListXattr implements vfs.FileDescriptionImpl.ListXattr.
+func (fd *fileDescription) ListXattr(ctx context.Context, size uint64) ([]string, error) {
+	return fd.inode().ListXattr(ctx, auth.CredentialsFromContext(ctx), size)
+}
+
+// GetXattr implements vfs.FileDescriptionImpl.GetXattr.
+func (fd *fileDescription) GetXattr(ctx context.Context, opts vfs.GetXattrOptions) (string, error) {
+	return fd.inode().GetXattr(ctx, auth.CredentialsFromContext(ctx), opts)
+}
+
+// SetXattr implements vfs.FileDescriptionImpl.SetXattr.
+func (fd *fileDescription) SetXattr(ctx context.Context, opts vfs.SetXattrOptions) error {
+	return fd.inode().SetXattr(ctx, auth.CredentialsFromContext(ctx), opts)
+}
+
+// RemoveXattr implements vfs.FileDescriptionImpl.RemoveXattr.
+func (fd *fileDescription) RemoveXattr(ctx context.Context, name string) error {
+	return fd.inode().RemoveXattr(ctx, auth.CredentialsFromContext(ctx), name)
+}
@@ -28,6 +28,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/ktime"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
 )
 
 // Name is the default filesystem name.
@@ -78,6 +79,18 @@ type filesystemOptions struct {
 	//
 	// Immutable after mount.
 	allowOther bool
+
+	// maxBackground is the max_background mount option. It is the maximum
+	// number of async requests that the FUSE server processes at any time,
+	// and takes precedence over the value negotiated in FUSE_INIT. Zero means
+	// unset.
+	maxBackground uint16
+
+	// congestionThreshold is the congestion_threshold mount option. It is the
+	// number of async requests processed by the FUSE server above which
+	// synchronous requests take priority, and takes precedence over the value
+	// negotiated in FUSE_INIT. Zero means unset.
+	congestionThreshold uint16
 }
 
 // filesystem implements vfs.FilesystemImpl.
@@ -96,6 +109,10 @@ type filesystem struct {
 
 	// clock is a real-time clock used to set timestamps in file operations.
 	clock ktime.Clock
+
+	// childrenMu protects inode.children, inode.parent and inode.name for all
+	// inodes in the filesystem.
+	childrenMu sync.Mutex `state:"nosave"`
 }
 
 // Name implements vfs.FilesystemType.Name.
@@ -223,6 +240,35 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		fsopts.allowOther = true
 	}
 
+	// As with the values negotiated in FUSE_INIT, unprivileged users can't
+	// raise the async request limits above the defaults.
+	hasSysAdminCap := creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, kernelTask.Kernel().RootUserNamespace())
+	if maxBackgroundStr, ok := mopts["max_background"]; ok {
+		delete(mopts, "max_background")
+		maxBackground, err := strconv.ParseUint(maxBackgroundStr, 10, 16)
+		if err != nil || maxBackground == 0 {
+			log.Warningf("%s.GetFilesystem: invalid max_background: max_background=%s", fsType.Name(), maxBackgroundStr)
+			return nil, nil, linuxerr.EINVAL
+		}
+		fsopts.maxBackground = uint16(maxBackground)
+		if !hasSysAdminCap && fsopts.maxBackground > MaxUserBackgroundRequest {
+			fsopts.maxBackground = MaxUserBackgroundRequest
+		}
+	}
+
+	if congestionThresholdStr, ok := mopts["congestion_threshold"]; ok {
+		delete(mopts, "congestion_threshold")
+		congestionThreshold, err := strconv.ParseUint(congestionThresholdStr, 10, 16)
+		if err != nil || congestionThreshold == 0 {
+			log.Warningf("%s.GetFilesystem: invalid congestion_threshold: congestion_threshold=%s", fsType.Name(), congestionThresholdStr)
+			return nil, nil, linuxerr.EINVAL
+		}
+		fsopts.congestionThreshold = uint16(congestionThreshold)
+		if !hasSysAdminCap && fsopts.congestionThreshold > MaxUserCongestionThreshold {
+			fsopts.congestionThreshold = MaxUserCongestionThreshold
+		}
+	}
+
 	// Check for unparsed options.
 	if len(mopts) != 0 {
 		log.Warningf("%s.GetFilesystem: unsupported or unknown options: %v", fsType.Name(), mopts)
@@ -295,6 +341,7 @@ func (fs *filesystem) newRoot(ctx context.Context, creds *auth.Credentials, mode
 	i.attrMu.Unlock()
 	i.OrderedChildren.Init(kernfs.OrderedChildrenOptions{})
 	i.InitRefs()
+	fs.conn.addInode(i)
 
 	var d kernfs.Dentry
 	d.InitRoot(&fs.Filesystem, i)
@@ -317,6 +364,7 @@ func (fs *filesystem) newInode(ctx context.Context, out linux.FUSEEntryOut) (ker
 
 	i.OrderedChildren.Init(kernfs.OrderedChildrenOptions{})
 	i.InitRefs()
+	fs.conn.addInode(i)
 	return i, nil
 }
 
@@ -74,6 +74,17 @@ type inode struct {
 	// attrTime is the time at which the attributes become invalid.
 	attrTime ktime.Time
 
+	// children maps the names of children of this directory that have live
+	// dentries to their inodes, so that entries invalidated by the server can
+	// be found. Each inode is used by exactly one dentry. children is
+	// protected by filesystem.childrenMu.
+	children map[string]*inode
+
+	// If parent is not nil, i is parent.children[name]. parent and name are
+	// protected by filesystem.childrenMu.
+	parent *inode
+	name   string
+
 	// link is result of following a symbolic link.
 	link string
 
@@ -84,6 +95,11 @@ type inode struct {
 	locks   vfs.FileLocks
 	watches vfs.Watches
 
+	// posixLocked is set once a POSIX lock on this inode has been forwarded to
+	// the FUSE server, so that releasing the locks of closed files can be
+	// skipped for inodes that were never locked.
+	posixLocked atomicbitops.Bool
+
 	// attrMu protects the attributes of this inode.
 	attrMu sync.Mutex `state:"nosave"`
 
@@ -304,6 +320,7 @@ func (i *inode) Open(ctx context.Context, rp *vfs.ResolvingPath, d *kernfs.Dentr
 	}
 
 	fd.LockFD.Init(&i.locks)
+	fd.lockOwner = kernel.KernelFromContext(ctx).UniqueID()
 	// FOPEN_KEEP_CACHE is the default flag for noOpen.
 	fd.OpenFlag = linux.FOPEN_KEEP_CACHE
 
@@ -421,6 +438,64 @@ func (i *inode) Valid(ctx context.Context, parent *kernfs.Dentry, name string) b
 	return true
 }
 
+// invalidateEntry forces the child name of i to be looked up again on next
+// use. Names without a live dentry will be looked up on next use anyway, so
+// they're ignored.
+func (i *inode) invalidateEntry(name string) {
+	i.fs.childrenMu.Lock()
+	child := i.children[name]
+	i.fs.childrenMu.Unlock()
+	if child == nil {
+		return
+	}
+	child.attrMu.Lock()
+	defer child.attrMu.Unlock()
+	SeqAtomicStoreTime(&child.entryTimeSeq, &child.entryTime, ktime.ZeroTime)
+}
+
+// addChildLocked records that child is the inode of the dentry for the child
+// name of i.
+//
+// Preconditions: i.fs.childrenMu must be locked.
+func (i *inode) addChildLocked(name string, child *inode) {
+	if i.children == nil {
+		i.children = make(map[string]*inode)
+	}
+	if old := i.children[name]; old != nil {
+		old.parent = nil
+	}
+	i.children[name] = child
+	child.parent = i
+	child.name = name
+}
+
+// detachLocked removes i from its parent's children.
+//
+// Preconditions: i.fs.childrenMu must be locked.
+func (i *inode) detachLocked() {
+	if i.parent == nil {
+		return
+	}
+	delete(i.parent.children, i.name)
+	i.parent = nil
+	i.name = ""
+}
+
+// detach removes i from its parent's children.
+func (i *inode) detach() {
+	i.fs.childrenMu.Lock()
+	defer i.fs.childrenMu.Unlock()
+	i.detachLocked()
+}
+
+// invalidateAttrs forces the attributes of i to be fetched from the server on
+// next use.
+func (i *inode) invalidateAttrs() {
+	i.attrMu.Lock()
+	defer i.attrMu.Unlock()
+	i.attrTime = ktime.ZeroTime
+}
+
 // Lookup implements kernfs.Inode.Lookup.
 func (i *inode) Lookup(ctx context.Context, name string) (kernfs.Inode, error) {
 	in := linux.FUSELookupIn{Name: linux.CString(name)}
@@ -497,7 +572,11 @@ func (i *inode) Unlink(ctx context.Context, name string, child kernfs.Inode) err
 		return err
 	}
 	// only return error, discard res.
-	return res.Error()
+	if err := res.Error(); err != nil {
+		return err
+	}
+	child.(*inode).detach()
+	return nil
 }
 
 // NewDir implements kernfs.Inode.NewDir.
@@ -520,7 +599,11 @@ func (i *inode) RmDir(ctx context.Context, name string, child kernfs.Inode) erro
 	if err != nil {
 		return err
 	}
-	return res.Error()
+	if err := res.Error(); err != nil {
+		return err
+	}
+	child.(*inode).detach()
+	return nil
 }
 
 // Rename implements kernfs.Inode.Rename.
@@ -536,7 +619,15 @@ func (i *inode) Rename(ctx context.Context, oldname, newname string, child, dstD
 	if err != nil {
 		return err
 	}
-	return res.Error()
+	if err := res.Error(); err != nil {
+		return err
+	}
+	i.fs.childrenMu.Lock()
+	defer i.fs.childrenMu.Unlock()
+	childInode := child.(*inode)
+	childInode.detachLocked()
+	dstDirInode.addChildLocked(newname, childInode)
+	return nil
 }
 
 // newEntry calls FUSE server for entry creation and allocates corresponding
@@ -568,6 +659,9 @@ func (i *inode) newEntry(ctx context.Context, name string, fileType linux.FileMo
 	if err != nil {
 		return nil, err
 	}
+	i.fs.childrenMu.Lock()
+	i.addChildLocked(name, child.(*inode))
+	i.fs.childrenMu.Unlock()
 	if opcode == linux.FUSE_CREATE {
 		// File handler is returned by fuse server at a time of file create.
 		// Save it temporary in a created child, so Open could return it when invoked
@@ -773,7 +867,11 @@ func (i *inode) Stat(ctx context.Context, fs *vfs.Filesystem, opts vfs.StatOptio
 
 // DecRef implements kernfs.Inode.DecRef.
 func (i *inode) DecRef(ctx context.Context) {
-	i.inodeRefs.DecRef(func() { i.Destroy(ctx) })
+	i.inodeRefs.DecRef(func() {
+		i.detach()
+		i.fs.conn.removeInode(i)
+		i.Destroy(ctx)
+	})
 }
 
 // StatFS implements kernfs.Inode.StatFS.
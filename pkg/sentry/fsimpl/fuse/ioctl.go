@@ -0,0 +1,104 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuse
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+//
+// Only restricted ioctls are supported: the buffer exchanged with the FUSE
+// server is the one described by the direction and size encoded in the ioctl
+// command, and servers asking to retry with other buffers get EIO. This is
+// analogous to Linux's fs/fuse/ioctl.c:fuse_do_ioctl() without
+// FUSE_IOCTL_UNRESTRICTED, which is only used by CUSE.
+func (fd *fileDescription) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	inode := fd.inode()
+	conn := inode.fs.conn
+	in := linux.FUSEIoctlIn{
+		Fh:  fd.Fh,
+		Cmd: args[1].Uint(),
+		Arg: args[2].Uint64(),
+	}
+	if inode.filemode().IsDir() {
+		if !conn.ioctlDir {
+			return 0, linuxerr.ENOTTY
+		}
+		in.Flags |= linux.FUSE_IOCTL_DIR
+	} else if !conn.ioctl {
+		return 0, linuxerr.ENOTTY
+	}
+
+	argAddr := args[2].Pointer()
+	dir := linux.IOC_DIR(in.Cmd)
+	size := linux.IOC_SIZE(in.Cmd)
+	var payload primitive.ByteSlice
+	if dir&linux.IOC_WRITE != 0 && size != 0 {
+		payload = make([]byte, size)
+		if _, err := uio.CopyIn(ctx, argAddr, payload, usermem.IOOpts{}); err != nil {
+			return 0, err
+		}
+		in.InSize = size
+	}
+	if dir&linux.IOC_READ != 0 {
+		in.OutSize = size
+	}
+
+	req := conn.NewRequest(auth.CredentialsFromContext(ctx), pidFromContext(ctx), inode.nodeID, linux.FUSE_IOCTL, &linux.FUSEIoctlPayloadIn{
+		Header:  in,
+		Payload: payload,
+	})
+	res, err := conn.Call(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	if err := res.Error(); err != nil {
+		if linuxerr.Equals(linuxerr.ENOSYS, err) {
+			return 0, linuxerr.ENOTTY
+		}
+		return 0, err
+	}
+	var out linux.FUSEIoctlOut
+	if err := res.UnmarshalPayload(&out); err != nil {
+		return 0, err
+	}
+	if out.Flags&linux.FUSE_IOCTL_RETRY != 0 {
+		// Retrying is only allowed for unrestricted ioctls.
+		return 0, linuxerr.EIO
+	}
+
+	data := res.data[res.hdr.SizeBytes()+out.SizeBytes():]
+	if uint32(len(data)) > in.OutSize {
+		return 0, linuxerr.EIO
+	}
+	if len(data) != 0 {
+		if _, err := uio.CopyOut(ctx, argAddr, data, usermem.IOOpts{}); err != nil {
+			return 0, err
+		}
+	}
+	// A negative result is a negated errno, as for the ioctl handlers of
+	// Linux drivers.
+	if out.Result < 0 {
+		return 0, errorFromErrno(out.Result)
+	}
+	return uintptr(out.Result), nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuse
+
+import (
+	"math"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	fslock "gvisor.dev/gvisor/pkg/sentry/fsimpl/lock"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+)
+
+// If the FUSE server implements locks, as negotiated in FUSE_INIT, locks are
+// forwarded to it. Otherwise they are handled by the sentry, as for other
+// filesystems, and are only visible to the sandbox.
+
+// LockBSD implements vfs.FileDescriptionImpl.LockBSD.
+func (fd *fileDescription) LockBSD(ctx context.Context, uid fslock.UniqueID, ownerPID int32, t fslock.LockType, block bool) error {
+	if !fd.inode().fs.conn.flockLocks {
+		return fd.LockFD.LockBSD(ctx, uid, ownerPID, t, block)
+	}
+	fd.flock.Store(true)
+	lk := fuseFileLock(fuseLockType(t), fslock.LockRange{Start: 0, End: fslock.LockEOF}, tgidFromContext(ctx))
+	return fd.setlk(ctx, fd.lockOwner, lk, linux.FUSE_LK_FLOCK, block)
+}
+
+// UnlockBSD implements vfs.FileDescriptionImpl.UnlockBSD.
+func (fd *fileDescription) UnlockBSD(ctx context.Context, uid fslock.UniqueID) error {
+	if !fd.inode().fs.conn.flockLocks {
+		return fd.LockFD.UnlockBSD(ctx, uid)
+	}
+	if !fd.flock.Load() {
+		return nil
+	}
+	lk := fuseFileLock(linux.F_UNLCK, fslock.LockRange{Start: 0, End: fslock.LockEOF}, 0)
+	return fd.setlk(ctx, fd.lockOwner, lk, linux.FUSE_LK_FLOCK, false /* block */)
+}
+
+// LockPOSIX implements vfs.FileDescriptionImpl.LockPOSIX.
+func (fd *fileDescription) LockPOSIX(ctx context.Context, uid fslock.UniqueID, ownerPID int32, t fslock.LockType, r fslock.LockRange, block bool) error {
+	inode := fd.inode()
+	if !inode.fs.conn.posixLocks {
+		return fd.LockFD.LockPOSIX(ctx, uid, ownerPID, t, r, block)
+	}
+	inode.posixLocked.Store(true)
+	lk := fuseFileLock(fuseLockType(t), r, tgidFromContext(ctx))
+	return fd.setlk(ctx, fd.lockOwnerID(uid), lk, 0 /* lkFlags */, block)
+}
+
+// UnlockPOSIX implements vfs.FileDescriptionImpl.UnlockPOSIX.
+func (fd *fileDescription) UnlockPOSIX(ctx context.Context, uid fslock.UniqueID, r fslock.LockRange) error {
+	inode := fd.inode()
+	if !inode.fs.conn.posixLocks {
+		return fd.LockFD.UnlockPOSIX(ctx, uid, r)
+	}
+	// UnlockPOSIX is called whenever a file is closed; don't bother the server
+	// if the file was never locked.
+	if !inode.posixLocked.Load() {
+		return nil
+	}
+	lk := fuseFileLock(linux.F_UNLCK, r, 0)
+	return fd.setlk(ctx, fd.lockOwnerID(uid), lk, 0 /* lkFlags */, false /* block */)
+}
+
+// TestPOSIX implements vfs.FileDescriptionImpl.TestPOSIX.
+func (fd *fileDescription) TestPOSIX(ctx context.Context, uid fslock.UniqueID, t fslock.LockType, r fslock.LockRange) (linux.Flock, error) {
+	inode := fd.inode()
+	conn := inode.fs.conn
+	if !conn.posixLocks {
+		return fd.LockFD.TestPOSIX(ctx, uid, t, r)
+	}
+	in := linux.FUSELkIn{
+		Fh:    fd.Fh,
+		Owner: fd.lockOwnerID(uid),
+		Lk:    fuseFileLock(fuseLockType(t), r, 0),
+	}
+	req := conn.NewRequest(auth.CredentialsFromContext(ctx), pidFromContext(ctx), inode.nodeID, linux.FUSE_GETLK, &in)
+	res, err := conn.Call(ctx, req)
+	if err != nil {
+		return linux.Flock{}, err
+	}
+	if err := res.Error(); err != nil {
+		return linux.Flock{}, err
+	}
+	var out linux.FUSELkOut
+	if err := res.UnmarshalPayload(&out); err != nil {
+		return linux.Flock{}, err
+	}
+	return flockFromFUSE(&out.Lk)
+}
+
+// setlk sends a FUSE_SETLK or, if block is true, FUSE_SETLKW request for lk
+// on behalf of owner. This is analogous to Linux's fs/fuse/file.c:fuse_setlk().
+func (fd *fileDescription) setlk(ctx context.Context, owner uint64, lk linux.FUSEFileLock, lkFlags uint32, block bool) error {
+	inode := fd.inode()
+	conn := inode.fs.conn
+	in := linux.FUSELkIn{
+		Fh:      fd.Fh,
+		Owner:   owner,
+		Lk:      lk,
+		LkFlags: lkFlags,
+	}
+	opcode := linux.FUSEOpcode(linux.FUSE_SETLK)
+	if block {
+		opcode = linux.FUSE_SETLKW
+	}
+	req := conn.NewRequest(auth.CredentialsFromContext(ctx), pidFromContext(ctx), inode.nodeID, opcode, &in)
+	// Waiting for a conflicting lock to be released may take forever, so let
+	// signals interrupt the server.
+	req.interruptible = block
+	res, err := conn.Call(ctx, req)
+	if err == nil {
+		err = res.Error()
+	}
+	if linuxerr.Equals(linuxerr.EINTR, err) {
+		// Locking is restartable.
+		return linuxerr.ERESTARTSYS
+	}
+	return err
+}
+
+// lockOwnerID returns the lock owner that identifies uid to the FUSE server.
+func (fd *fileDescription) lockOwnerID(uid fslock.UniqueID) uint64 {
+	if fdTable, ok := uid.(*kernel.FDTable); ok {
+		return fdTable.ID()
+	}
+	// Open file description locks are owned by fd itself.
+	return fd.lockOwner
+}
+
+// fuseLockType returns the fcntl(2) lock type corresponding to t.
+func fuseLockType(t fslock.LockType) uint32 {
+	if t == fslock.ReadLock {
+		return linux.F_RDLCK
+	}
+	return linux.F_WRLCK
+}
+
+// fuseFileLock returns a lock of type typ on r, held by pid.
+func fuseFileLock(typ uint32, r fslock.LockRange, pid uint32) linux.FUSEFileLock {
+	// Unlike fslock.LockRange.End, linux.FUSEFileLock.End is inclusive.
+	end := uint64(math.MaxInt64)
+	if r.End != fslock.LockEOF {
+		end = r.End - 1
+	}
+	return linux.FUSEFileLock{
+		Start: r.Start,
+		End:   end,
+		Type:  typ,
+		PID:   pid,
+	}
+}
+
+// flockFromFUSE converts a lock returned by the FUSE server in reply to
+// FUSE_GETLK. This is analogous to Linux's
+// fs/fuse/file.c:convert_fuse_file_lock().
+func flockFromFUSE(lk *linux.FUSEFileLock) (linux.Flock, error) {
+	switch lk.Type {
+	case linux.F_UNLCK:
+		return linux.Flock{Type: linux.F_UNLCK}, nil
+	case linux.F_RDLCK, linux.F_WRLCK:
+		if lk.Start > math.MaxInt64 || lk.End > math.MaxInt64 || lk.End < lk.Start {
+			return linux.Flock{}, linuxerr.EIO
+		}
+		f := linux.Flock{
+			Type:   int16(lk.Type),
+			Whence: linux.SEEK_SET,
+			Start:  int64(lk.Start),
+			PID:    int32(lk.PID),
+		}
+		// A length of 0 means the lock extends to the end of the file.
+		if lk.End != math.MaxInt64 {
+			f.Len = int64(lk.End - lk.Start + 1)
+		}
+		return f, nil
+	default:
+		return linux.Flock{}, linuxerr.EIO
+	}
+}
+
+// tgidFromContext returns the thread group ID of the task in ctx, in the root
+// PID namespace, or 0 if ctx has no task.
+func tgidFromContext(ctx context.Context) uint32 {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		return 0
+	}
+	return uint32(t.TGIDInRoot())
+}
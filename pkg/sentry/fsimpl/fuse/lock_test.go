@@ -0,0 +1,104 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuse
+
+import (
+	"math"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	fslock "gvisor.dev/gvisor/pkg/sentry/fsimpl/lock"
+)
+
+func TestFUSEFileLock(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		r    fslock.LockRange
+		want linux.FUSEFileLock
+	}{
+		{
+			name: "bounded",
+			r:    fslock.LockRange{Start: 10, End: 20},
+			want: linux.FUSEFileLock{Start: 10, End: 19, Type: linux.F_WRLCK, PID: 1},
+		},
+		{
+			name: "to EOF",
+			r:    fslock.LockRange{Start: 10, End: fslock.LockEOF},
+			want: linux.FUSEFileLock{Start: 10, End: math.MaxInt64, Type: linux.F_WRLCK, PID: 1},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := fuseFileLock(linux.F_WRLCK, tc.r, 1); got != tc.want {
+				t.Errorf("fuseFileLock(%+v) = %+v, want %+v", tc.r, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFlockFromFUSE(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		lk      linux.FUSEFileLock
+		want    linux.Flock
+		wantErr bool
+	}{
+		{
+			name: "unlocked",
+			lk:   linux.FUSEFileLock{Start: 10, End: 5, Type: linux.F_UNLCK, PID: 1},
+			want: linux.Flock{Type: linux.F_UNLCK},
+		},
+		{
+			name: "bounded",
+			lk:   linux.FUSEFileLock{Start: 10, End: 19, Type: linux.F_RDLCK, PID: 1},
+			want: linux.Flock{Type: linux.F_RDLCK, Whence: linux.SEEK_SET, Start: 10, Len: 10, PID: 1},
+		},
+		{
+			name: "to EOF",
+			lk:   linux.FUSEFileLock{Start: 10, End: math.MaxInt64, Type: linux.F_WRLCK, PID: 1},
+			want: linux.Flock{Type: linux.F_WRLCK, Whence: linux.SEEK_SET, Start: 10, PID: 1},
+		},
+		{
+			name:    "end before start",
+			lk:      linux.FUSEFileLock{Start: 10, End: 9, Type: linux.F_WRLCK},
+			wantErr: true,
+		},
+		{
+			name:    "end too large",
+			lk:      linux.FUSEFileLock{Start: 10, End: math.MaxUint64, Type: linux.F_WRLCK},
+			wantErr: true,
+		},
+		{
+			name:    "invalid type",
+			lk:      linux.FUSEFileLock{Start: 10, End: 19, Type: 3},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := flockFromFUSE(&tc.lk)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("flockFromFUSE(%+v) = %+v, want error", tc.lk, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("flockFromFUSE(%+v) failed: %v", tc.lk, err)
+			}
+			if got != tc.want {
+				t.Errorf("flockFromFUSE(%+v) = %+v, want %+v", tc.lk, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuse
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/marshal"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// notify handles a notification sent by the FUSE server, whose header is hdr
+// and whose payload is read from src. This is analogous to Linux's
+// fs/fuse/dev.c:fuse_notify().
+//
+// Preconditions: conn.fd.mu must not be locked, since handling notifications
+// requires locking inodes.
+func (conn *connection) notify(ctx context.Context, hdr *linux.FUSEHeaderOut, src usermem.IOSequence) error {
+	switch linux.FUSENotifyCode(hdr.Error) {
+	case linux.FUSE_NOTIFY_POLL:
+		var out linux.FUSENotifyPollWakeupOut
+		if err := copyInNotification(ctx, src, &out, 0); err != nil {
+			return err
+		}
+		conn.notifyPoll(out.Kh)
+		return nil
+
+	case linux.FUSE_NOTIFY_INVAL_INODE:
+		var out linux.FUSENotifyInvalInodeOut
+		if err := copyInNotification(ctx, src, &out, 0); err != nil {
+			return err
+		}
+		return conn.notifyInvalInode(ctx, out.Ino)
+
+	case linux.FUSE_NOTIFY_INVAL_ENTRY:
+		var out linux.FUSENotifyInvalEntryOut
+		name, err := copyInNotificationName(ctx, src, &out, &out.NameLen)
+		if err != nil {
+			return err
+		}
+		// FUSE_EXPIRE_ONLY makes no difference: invalidated entries are
+		// always looked up again rather than dropped outright.
+		return conn.notifyInvalEntry(ctx, out.Parent, 0, name)
+
+	case linux.FUSE_NOTIFY_DELETE:
+		var out linux.FUSENotifyDeleteOut
+		name, err := copyInNotificationName(ctx, src, &out, &out.NameLen)
+		if err != nil {
+			return err
+		}
+		return conn.notifyInvalEntry(ctx, out.Parent, out.Child, name)
+
+	case linux.FUSE_NOTIFY_STORE, linux.FUSE_NOTIFY_RETRIEVE:
+		// File data isn't cached, so there is nothing to store data into or
+		// retrieve data from.
+		return linuxerr.ENOSYS
+
+	default:
+		return linuxerr.EINVAL
+	}
+}
+
+// copyInNotification copies the fixed-size payload of a notification from src
+// into out. extra is the number of bytes that must follow it in src, or -1 if
+// any number of bytes may follow.
+func copyInNotification(ctx context.Context, src usermem.IOSequence, out marshal.Marshallable, extra int64) error {
+	size := int64(out.SizeBytes())
+	if n := src.NumBytes(); n < size || (extra >= 0 && n != size+extra) {
+		return linuxerr.EINVAL
+	}
+	buf := make([]byte, size)
+	if _, err := src.CopyIn(ctx, buf); err != nil {
+		return err
+	}
+	out.UnmarshalUnsafe(buf)
+	return nil
+}
+
+// copyInNotificationName copies the payload of a notification that is followed
+// by a null-terminated name from src into out, and returns the name. nameLen
+// points to the field of out that holds the length of the name.
+func copyInNotificationName(ctx context.Context, src usermem.IOSequence, out marshal.Marshallable, nameLen *uint32) (string, error) {
+	if err := copyInNotification(ctx, src, out, -1); err != nil {
+		return "", err
+	}
+	if *nameLen > linux.FUSE_NAME_MAX {
+		return "", linuxerr.ENAMETOOLONG
+	}
+	size := out.SizeBytes()
+	if src.NumBytes() != int64(size)+int64(*nameLen)+1 {
+		return "", linuxerr.EINVAL
+	}
+	buf := make([]byte, *nameLen)
+	if _, err := src.DropFirst(size).CopyIn(ctx, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// notifyPoll wakes up the waiters of the file with poll handle kh. The waiters
+// are expected to poll the file again with FUSE_POLL.
+func (conn *connection) notifyPoll(kh uint64) {
+	conn.pollMu.Lock()
+	fd, ok := conn.pollHandles[kh]
+	conn.pollMu.Unlock()
+	// Notifications for unknown handles are ignored, as the file may have
+	// been released concurrently.
+	if ok {
+		fd.invalidateRevents()
+	}
+}
+
+// notifyInvalInode invalidates the cached attributes of the inodes with node
+// ID nodeID. This is analogous to Linux's
+// fs/fuse/inode.c:fuse_reverse_inval_inode().
+func (conn *connection) notifyInvalInode(ctx context.Context, nodeID uint64) error {
+	inodes := conn.findInodes(nodeID)
+	if len(inodes) == 0 {
+		return linuxerr.ENOENT
+	}
+	// Make attributes fetched before the notification stale. File data isn't
+	// cached, so there is nothing else to invalidate.
+	conn.attributeVersion.Add(1)
+	for _, i := range inodes {
+		i.invalidateAttrs()
+		i.DecRef(ctx)
+	}
+	return nil
+}
+
+// notifyInvalEntry invalidates the entry name in the directories with node ID
+// parent, such that it is looked up again on next use. If child is not 0, the
+// entry was deleted, and the cached attributes of inodes with node ID child
+// are invalidated as well. This is analogous to Linux's
+// fs/fuse/dir.c:fuse_reverse_inval_entry().
+func (conn *connection) notifyInvalEntry(ctx context.Context, parent, child uint64, name string) error {
+	parents := conn.findInodes(parent)
+	if len(parents) == 0 {
+		return linuxerr.ENOENT
+	}
+	conn.attributeVersion.Add(1)
+	for _, i := range parents {
+		i.invalidateEntry(name)
+		i.invalidateAttrs()
+		i.DecRef(ctx)
+	}
+	if child != 0 {
+		for _, i := range conn.findInodes(child) {
+			i.invalidateAttrs()
+			i.DecRef(ctx)
+		}
+	}
+	return nil
+}
+
+// addInode makes i findable by notifications.
+func (conn *connection) addInode(i *inode) {
+	conn.inodesMu.Lock()
+	defer conn.inodesMu.Unlock()
+	conn.inodes[i.nodeID] = append(conn.inodes[i.nodeID], i)
+}
+
+// removeInode undoes a previous call to addInode.
+func (conn *connection) removeInode(i *inode) {
+	conn.inodesMu.Lock()
+	defer conn.inodesMu.Unlock()
+	inodes := conn.inodes[i.nodeID]
+	for j, other := range inodes {
+		if other != i {
+			continue
+		}
+		inodes[j] = inodes[len(inodes)-1]
+		inodes[len(inodes)-1] = nil
+		inodes = inodes[:len(inodes)-1]
+		break
+	}
+	if len(inodes) == 0 {
+		delete(conn.inodes, i.nodeID)
+	} else {
+		conn.inodes[i.nodeID] = inodes
+	}
+}
+
+// findInodes returns the live inodes with node ID nodeID. The caller must call
+// DecRef on each of them when done.
+func (conn *connection) findInodes(nodeID uint64) []*inode {
+	conn.inodesMu.Lock()
+	defer conn.inodesMu.Unlock()
+	var inodes []*inode
+	for _, i := range conn.inodes[nodeID] {
+		if i.TryIncRef() {
+			inodes = append(inodes, i)
+		}
+	}
+	return inodes
+}
+
+// addPollHandle returns a new poll handle for fd. The handle is sent to the
+// server with FUSE_POLL, and FUSE_NOTIFY_POLL notifications for it wake up
+// the waiters of fd.
+func (conn *connection) addPollHandle(fd *regularFileFD) uint64 {
+	conn.pollMu.Lock()
+	defer conn.pollMu.Unlock()
+	conn.nextPollHandle++
+	kh := conn.nextPollHandle
+	conn.pollHandles[kh] = fd
+	return kh
+}
+
+// removePollHandle releases a poll handle returned by addPollHandle.
+func (conn *connection) removePollHandle(kh uint64) {
+	conn.pollMu.Lock()
+	defer conn.pollMu.Unlock()
+	delete(conn.pollHandles, kh)
+}
@@ -0,0 +1,210 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuse
+
+import (
+	"math"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/marshal"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/testutil"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/ktime"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// writeNotification writes a notification with the given code and payload,
+// followed by extra, to the FUSE device fd.
+func writeNotification(s *testutil.System, fd *vfs.FileDescription, code linux.FUSENotifyCode, payload marshal.Marshallable, extra []byte) error {
+	size := linux.SizeOfFUSEHeaderOut + uint32(payload.SizeBytes()) + uint32(len(extra))
+	buf := make([]byte, size)
+	hdr := linux.FUSEHeaderOut{
+		Len:   size,
+		Error: int32(code),
+	}
+	rest := hdr.MarshalUnsafe(buf)
+	rest = payload.MarshalUnsafe(rest)
+	copy(rest, extra)
+	_, err := fd.Write(s.Ctx, usermem.BytesIOSequence(buf), vfs.WriteOptions{})
+	return err
+}
+
+// newTestRoot creates a filesystem on a new test connection, and returns the
+// connection's device and the root inode of the filesystem.
+func newTestRoot(t *testing.T, s *testutil.System) (*vfs.FileDescription, *inode) {
+	_, fd, err := newTestConnection(s, maxActiveRequestsDefault)
+	if err != nil {
+		t.Fatalf("newTestConnection: %v", err)
+	}
+	fs, err := newTestFilesystem(s, fd, maxActiveRequestsDefault)
+	if err != nil {
+		t.Fatalf("newTestFilesystem: %v", err)
+	}
+	root := fs.newRoot(s.Ctx, auth.CredentialsFromContext(s.Ctx), linux.ModeDirectory|0755)
+	return fd, root.Inode().(*inode)
+}
+
+func TestNotifyInvalid(t *testing.T) {
+	s := setup(t)
+	defer s.Destroy()
+	fd, _ := newTestRoot(t, s)
+
+	for _, tc := range []struct {
+		name    string
+		code    linux.FUSENotifyCode
+		payload marshal.Marshallable
+		extra   []byte
+		want    error
+	}{
+		{
+			name:    "unknown code",
+			code:    100,
+			payload: &linux.FUSENotifyPollWakeupOut{},
+			want:    linuxerr.EINVAL,
+		},
+		{
+			name:    "trailing bytes",
+			code:    linux.FUSE_NOTIFY_POLL,
+			payload: &linux.FUSENotifyPollWakeupOut{},
+			extra:   []byte{0},
+			want:    linuxerr.EINVAL,
+		},
+		{
+			name:    "unknown inode",
+			code:    linux.FUSE_NOTIFY_INVAL_INODE,
+			payload: &linux.FUSENotifyInvalInodeOut{Ino: 2},
+			want:    linuxerr.ENOENT,
+		},
+		{
+			name:    "name length mismatch",
+			code:    linux.FUSE_NOTIFY_INVAL_ENTRY,
+			payload: &linux.FUSENotifyInvalEntryOut{Parent: 1, NameLen: 4},
+			extra:   []byte("foo\x00"),
+			want:    linuxerr.EINVAL,
+		},
+		{
+			name:    "store",
+			code:    linux.FUSE_NOTIFY_STORE,
+			payload: &linux.FUSENotifyStoreOut{NodeID: 1, Size: 4},
+			extra:   []byte("data"),
+			want:    linuxerr.ENOSYS,
+		},
+		{
+			name:    "retrieve",
+			code:    linux.FUSE_NOTIFY_RETRIEVE,
+			payload: &linux.FUSENotifyRetrieveOut{NotifyUnique: 42, NodeID: 1, Size: 4},
+			want:    linuxerr.ENOSYS,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := writeNotification(s, fd, tc.code, tc.payload, tc.extra); !linuxerr.Equals(tc.want, err) {
+				t.Errorf("writeNotification got error %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestNotifyPoll(t *testing.T) {
+	s := setup(t)
+	defer s.Destroy()
+	conn, fd, err := newTestConnection(s, maxActiveRequestsDefault)
+	if err != nil {
+		t.Fatalf("newTestConnection: %v", err)
+	}
+
+	var file regularFileFD
+	kh := conn.addPollHandle(&file)
+	e, ch := waiter.NewChannelEntry(waiter.ReadableEvents)
+	file.pollQueue.EventRegister(&e)
+	defer file.pollQueue.EventUnregister(&e)
+
+	if err := writeNotification(s, fd, linux.FUSE_NOTIFY_POLL, &linux.FUSENotifyPollWakeupOut{Kh: kh}, nil); err != nil {
+		t.Fatalf("writeNotification: %v", err)
+	}
+	select {
+	case <-ch:
+	default:
+		t.Errorf("FUSE_NOTIFY_POLL didn't wake up waiters")
+	}
+
+	// Notifications for released handles are ignored.
+	conn.removePollHandle(kh)
+	if err := writeNotification(s, fd, linux.FUSE_NOTIFY_POLL, &linux.FUSENotifyPollWakeupOut{Kh: kh}, nil); err != nil {
+		t.Fatalf("writeNotification: %v", err)
+	}
+	select {
+	case <-ch:
+		t.Errorf("FUSE_NOTIFY_POLL woke up waiters of a released handle")
+	default:
+	}
+}
+
+func TestNotifyInval(t *testing.T) {
+	s := setup(t)
+	defer s.Destroy()
+	fd, root := newTestRoot(t, s)
+
+	root.attrMu.Lock()
+	root.attrTime = ktime.MaxTime
+	root.attrMu.Unlock()
+	if err := writeNotification(s, fd, linux.FUSE_NOTIFY_INVAL_INODE, &linux.FUSENotifyInvalInodeOut{Ino: root.nodeID}, nil); err != nil {
+		t.Fatalf("writeNotification: %v", err)
+	}
+	root.attrMu.Lock()
+	attrTime := root.attrTime
+	root.attrMu.Unlock()
+	if attrTime != ktime.ZeroTime {
+		t.Errorf("FUSE_NOTIFY_INVAL_INODE didn't invalidate attributes, attrTime = %v", attrTime)
+	}
+
+	name := "foo"
+	ci, err := root.fs.newInode(s.Ctx, linux.FUSEEntryOut{
+		NodeID:     2,
+		EntryValid: math.MaxInt32,
+		Attr:       linux.FUSEAttr{Mode: linux.S_IFREG | 0644, Nlink: 1},
+	})
+	if err != nil {
+		t.Fatalf("newInode: %v", err)
+	}
+	child := ci.(*inode)
+	root.fs.childrenMu.Lock()
+	root.addChildLocked(name, child)
+	root.fs.childrenMu.Unlock()
+
+	in := linux.FUSENotifyInvalEntryOut{Parent: root.nodeID, NameLen: uint32(len(name))}
+	if err := writeNotification(s, fd, linux.FUSE_NOTIFY_INVAL_ENTRY, &in, []byte(name+"\x00")); err != nil {
+		t.Fatalf("writeNotification: %v", err)
+	}
+	if entryTime := SeqAtomicLoadTime(&child.entryTimeSeq, &child.entryTime); entryTime != ktime.ZeroTime {
+		t.Errorf("FUSE_NOTIFY_INVAL_ENTRY didn't invalidate entry %q, entryTime = %v", name, entryTime)
+	}
+
+	// Names without a live dentry must not be recorded.
+	unknown := "bar"
+	in = linux.FUSENotifyInvalEntryOut{Parent: root.nodeID, NameLen: uint32(len(unknown))}
+	if err := writeNotification(s, fd, linux.FUSE_NOTIFY_INVAL_ENTRY, &in, []byte(unknown+"\x00")); err != nil {
+		t.Fatalf("writeNotification: %v", err)
+	}
+	root.fs.childrenMu.Lock()
+	n := len(root.children)
+	root.fs.childrenMu.Unlock()
+	if n != 1 {
+		t.Errorf("got %d children after invalidating unknown entry %q, want 1", n, unknown)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuse
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// If the FUSE server implements FUSE_POLL, the readiness of regular files is
+// queried from it. Readiness can't block, so FUSE_POLL is sent as an async
+// request, and the readiness it reports is cached until a FUSE_NOTIFY_POLL
+// notification invalidates it. Once a file has waiters, it is assigned a poll
+// handle and the server is asked to send a FUSE_NOTIFY_POLL notification for
+// that handle when the file becomes ready, which wakes up the waiters.
+// Otherwise, regular files are always ready, as in Linux.
+
+// Readiness implements waiter.Waitable.Readiness. This is analogous to Linux's
+// fs/fuse/file.c:fuse_file_poll(), except that it returns the readiness last
+// reported by the server rather than waiting for the reply to FUSE_POLL.
+func (fd *regularFileFD) Readiness(mask waiter.EventMask) waiter.EventMask {
+	inode := fd.inode()
+	conn := inode.fs.conn
+	if conn.noPoll.Load() {
+		return fd.fileDescription.Readiness(mask)
+	}
+
+	in := linux.FUSEPollIn{
+		Fh:     fd.Fh,
+		Events: waiter.AllEvents.ToLinux(),
+	}
+	fd.pollMu.Lock()
+	revents, valid := fd.revents, fd.reventsValid
+	// Without a poll handle, the server doesn't notify us of readiness
+	// changes, so the cached readiness is refreshed on every call.
+	refresh := !fd.pollPending && (!valid || fd.kh == 0)
+	if refresh {
+		fd.pollPending = true
+		if fd.kh != 0 {
+			in.Kh = fd.kh
+			in.Flags = linux.FUSE_POLL_SCHEDULE_NOTIFY
+		}
+	}
+	fd.pollMu.Unlock()
+
+	if refresh {
+		if err := conn.pollAsync(inode.nodeID, fd, &in); err != nil {
+			fd.pollMu.Lock()
+			fd.pollPending = false
+			fd.pollMu.Unlock()
+			if !valid {
+				return waiter.EventErr
+			}
+		}
+	}
+	return revents & mask
+}
+
+// pollAsync sends the FUSE_POLL request in for fd without blocking. The reply
+// is passed to fd.pollDone.
+func (conn *connection) pollAsync(nodeID uint64, fd *regularFileFD, in *linux.FUSEPollIn) error {
+	req := conn.NewRequest(auth.CredentialsFromContext(context.Background()), 0 /* pid */, nodeID, linux.FUSE_POLL, in)
+	req.async = true
+	req.pollFD = fd
+
+	conn.fd.mu.Lock()
+	defer conn.fd.mu.Unlock()
+	// Unlike conn.Call, don't wait for room in the queue.
+	if conn.fd.numActiveRequests == conn.maxActiveRequests {
+		return linuxerr.EAGAIN
+	}
+	_, err := conn.callFutureLocked(req)
+	return err
+}
+
+// pollDone is called with the reply r to the FUSE_POLL request sent by
+// Readiness. It caches the readiness reported by the server, and wakes up the
+// waiters of fd if it changed.
+func (fd *regularFileFD) pollDone(r *Response) {
+	err := r.Error()
+	if linuxerr.Equals(linuxerr.ENOSYS, err) {
+		fd.inode().fs.conn.noPoll.Store(true)
+		fd.pollMu.Lock()
+		fd.pollPending = false
+		fd.pollMu.Unlock()
+		fd.pollQueue.Notify(waiter.AllEvents)
+		return
+	}
+	revents := waiter.EventErr
+	if err == nil {
+		var out linux.FUSEPollOut
+		if err := r.UnmarshalPayload(&out); err == nil {
+			revents = waiter.EventMaskFromLinux(out.Revents)
+		}
+	}
+
+	fd.pollMu.Lock()
+	fd.pollPending = false
+	changed := !fd.reventsValid || fd.revents != revents
+	fd.revents = revents
+	// If the server sent FUSE_NOTIFY_POLL after receiving the request, r may
+	// predate the event it notified, so the readiness must be queried again.
+	fd.reventsValid = !fd.pollRaced
+	fd.pollRaced = false
+	fd.pollMu.Unlock()
+	if changed {
+		fd.pollQueue.Notify(waiter.AllEvents)
+	}
+}
+
+// invalidateRevents is called when the server sends FUSE_NOTIFY_POLL for the
+// poll handle of fd. It invalidates the cached readiness of fd, and wakes up
+// its waiters, which are expected to poll it again.
+func (fd *regularFileFD) invalidateRevents() {
+	fd.pollMu.Lock()
+	fd.reventsValid = false
+	fd.pollRaced = fd.pollPending
+	fd.pollMu.Unlock()
+	fd.pollQueue.Notify(waiter.AllEvents)
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (fd *regularFileFD) EventRegister(e *waiter.Entry) error {
+	conn := fd.inode().fs.conn
+	fd.pollQueue.EventRegister(e)
+	if !conn.noPoll.Load() {
+		fd.pollMu.Lock()
+		if fd.kh == 0 {
+			fd.kh = conn.addPollHandle(fd)
+			// The server must be asked to notify the new handle.
+			fd.reventsValid = false
+		}
+		fd.pollMu.Unlock()
+	}
+	return nil
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (fd *regularFileFD) EventUnregister(e *waiter.Entry) {
+	fd.pollQueue.EventUnregister(e)
+}
+
+// Epollable implements FileDescriptionImpl.Epollable.
+func (fd *regularFileFD) Epollable() bool {
+	return true
+}
+
+// releasePollHandle releases the poll handle of fd, if any.
+func (fd *regularFileFD) releasePollHandle() {
+	fd.pollMu.Lock()
+	defer fd.pollMu.Unlock()
+	if fd.kh != 0 {
+		fd.inode().fs.conn.removePollHandle(fd.kh)
+		fd.kh = 0
+	}
+}
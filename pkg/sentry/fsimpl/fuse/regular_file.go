@@ -28,6 +28,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
 )
 
 // +stateify savable
@@ -57,6 +58,42 @@ type regularFileFD struct {
 	//
 	// Protected by dataMu.
 	data fsutil.FileRangeSet
+
+	// pollQueue is the queue of waiters for readiness events of the file.
+	pollQueue waiter.Queue
+
+	// pollMu protects the FUSE_POLL fields below.
+	pollMu sync.Mutex `state:"nosave"`
+
+	// kh is the poll handle of the file, which is allocated once the file
+	// has waiters, or 0.
+	// +checklocks:pollMu
+	kh uint64
+
+	// revents is the readiness of the file last reported by the server in
+	// reply to FUSE_POLL.
+	// +checklocks:pollMu
+	revents waiter.EventMask
+
+	// reventsValid is true if revents was reported by the server since the
+	// last FUSE_NOTIFY_POLL notification for kh.
+	// +checklocks:pollMu
+	reventsValid bool
+
+	// pollPending is true if a FUSE_POLL request for the file is in flight.
+	// +checklocks:pollMu
+	pollPending bool
+
+	// pollRaced is true if the server sent FUSE_NOTIFY_POLL for kh while a
+	// FUSE_POLL request was in flight.
+	// +checklocks:pollMu
+	pollRaced bool
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *regularFileFD) Release(ctx context.Context) {
+	fd.releasePollHandle()
+	fd.fileDescription.Release(ctx)
 }
 
 // Seek implements vfs.FileDescriptionImpl.Allocate.
@@ -79,6 +116,9 @@ func (fd *regularFileFD) Allocate(ctx context.Context, mode, offset, length uint
 	if err := res.Error(); err != nil {
 		return err
 	}
+	if mode&linux.FALLOC_FL_KEEP_SIZE != 0 {
+		return nil
+	}
 	i.attrMu.Lock()
 	defer i.attrMu.Unlock()
 	if uint64(offset+length) > i.size.Load() {
@@ -107,6 +147,11 @@ func (fd *regularFileFD) Seek(ctx context.Context, offset int64, whence int32) (
 		offset += fd.off
 	case linux.SEEK_END:
 		offset += int64(inode.size.Load())
+	case linux.SEEK_DATA, linux.SEEK_HOLE:
+		var err error
+		if offset, err = fd.seekData(ctx, offset, whence); err != nil {
+			return 0, err
+		}
 	default:
 		return 0, linuxerr.EINVAL
 	}
@@ -117,6 +162,55 @@ func (fd *regularFileFD) Seek(ctx context.Context, offset int64, whence int32) (
 	return offset, nil
 }
 
+// seekData returns the offset of the first data (if whence is SEEK_DATA) or
+// hole (if whence is SEEK_HOLE) at or after offset. This is analogous to
+// Linux's fs/fuse/file.c:fuse_lseek().
+//
+// Preconditions: fd.inode().attrMu must be locked.
+func (fd *regularFileFD) seekData(ctx context.Context, offset int64, whence int32) (int64, error) {
+	if offset < 0 {
+		return 0, linuxerr.ENXIO
+	}
+	inode := fd.inode()
+	conn := inode.fs.conn
+	if !conn.noLseek.Load() {
+		in := linux.FUSELseekIn{
+			Fh:     fd.Fh,
+			Offset: uint64(offset),
+			Whence: uint32(whence),
+		}
+		req := conn.NewRequest(auth.CredentialsFromContext(ctx), pidFromContext(ctx), inode.nodeID, linux.FUSE_LSEEK, &in)
+		res, err := conn.Call(ctx, req)
+		if err != nil {
+			return 0, err
+		}
+		if err := res.Error(); err == nil {
+			var out linux.FUSELseekOut
+			if err := res.UnmarshalPayload(&out); err != nil {
+				return 0, err
+			}
+			return int64(out.Offset), nil
+		} else if !linuxerr.Equals(linuxerr.ENOSYS, err) {
+			return 0, err
+		}
+		conn.noLseek.Store(true)
+	}
+
+	// Without server support, the whole file is data followed by an implicit
+	// hole at the end, as for other filesystems.
+	if err := inode.reviseAttr(ctx, linux.FUSE_GETATTR_FH, fd.Fh); err != nil {
+		return 0, err
+	}
+	size := int64(inode.size.Load())
+	if offset >= size {
+		return 0, linuxerr.ENXIO
+	}
+	if whence == linux.SEEK_HOLE {
+		return size, nil
+	}
+	return offset, nil
+}
+
 // PRead implements vfs.FileDescriptionImpl.PRead.
 func (fd *regularFileFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
 	if offset < 0 {
@@ -285,6 +379,75 @@ func (fd *regularFileFD) pwrite(ctx context.Context, src usermem.IOSequence, off
 	return n, offset, err
 }
 
+// CopyRange implements vfs.FileDescriptionCopyRangeImpl.CopyRange. Copies
+// between files of the same FUSE filesystem are offloaded to the FUSE server.
+// This is analogous to Linux's fs/fuse/file.c:__fuse_copy_file_range().
+func (fd *regularFileFD) CopyRange(ctx context.Context, srcOff int64, dst *vfs.FileDescription, dstOff, length int64) (int64, error) {
+	dstFD, ok := dst.Impl().(*regularFileFD)
+	if !ok {
+		return 0, linuxerr.EXDEV
+	}
+	inode := fd.inode()
+	dstInode := dstFD.inode()
+	if inode.fs != dstInode.fs {
+		return 0, linuxerr.EXDEV
+	}
+	conn := inode.fs.conn
+	if conn.noCopyFileRange.Load() {
+		return 0, linuxerr.EOPNOTSUPP
+	}
+	if srcOff < 0 || dstOff < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	limit, err := vfs.CheckLimit(ctx, dstOff, length)
+	if err != nil {
+		return 0, err
+	}
+	if limit == 0 {
+		return 0, nil
+	}
+
+	in := linux.FUSECopyFileRangeIn{
+		FhIn:      fd.Fh,
+		OffIn:     uint64(srcOff),
+		NodeIDOut: dstInode.nodeID,
+		FhOut:     dstFD.Fh,
+		OffOut:    uint64(dstOff),
+		Len:       uint64(limit),
+	}
+	req := conn.NewRequest(auth.CredentialsFromContext(ctx), pidFromContext(ctx), inode.nodeID, linux.FUSE_COPY_FILE_RANGE, &in)
+	res, err := conn.Call(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	if err := res.Error(); err != nil {
+		if linuxerr.Equals(linuxerr.ENOSYS, err) {
+			conn.noCopyFileRange.Store(true)
+			return 0, linuxerr.EOPNOTSUPP
+		}
+		return 0, err
+	}
+	var out linux.FUSEWriteOut
+	if err := res.UnmarshalPayload(&out); err != nil {
+		return 0, err
+	}
+	n := int64(out.Size)
+	if n > limit {
+		return 0, linuxerr.EIO
+	}
+
+	dstInode.attrMu.Lock()
+	defer dstInode.attrMu.Unlock()
+	if end := dstOff + n; end > int64(dstInode.size.Load()) {
+		dstInode.size.Store(uint64(end))
+		conn.attributeVersion.Add(1)
+	}
+	if n > 0 {
+		dstInode.touchCMtime()
+	}
+	return n, nil
+}
+
 // ConfigureMMap implements vfs.FileDescriptionImpl.ConfigureMMap.
 func (fd *regularFileFD) ConfigureMMap(ctx context.Context, opts *memmap.MMapOpts) error {
 	return linuxerr.ENOSYS
@@ -101,6 +101,14 @@ type Request struct {
 	// If we don't care its response.
 	// Manually set by the caller.
 	noReply bool
+
+	// If the server should be sent a FUSE_INTERRUPT request when the waiting
+	// task is interrupted. Manually set by the caller.
+	interruptible bool
+
+	// pollFD is the file polled by an async FUSE_POLL request, which is
+	// updated with the reply.
+	pollFD *regularFileFD
 }
 
 // NewRequest creates a new request that can be sent to the FUSE server.
@@ -131,6 +139,30 @@ func (conn *connection) NewRequest(creds *auth.Credentials, pid uint32, ino uint
 	}
 }
 
+// newInterruptRequest creates a FUSE_INTERRUPT request for the request with
+// the given ID. Unlike other requests, it does not consume a unique ID.
+func newInterruptRequest(id linux.FUSEOpID) *Request {
+	in := linux.FUSEInterruptIn{Unique: id}
+	hdr := linux.FUSEHeaderIn{
+		Len:    linux.SizeOfFUSEHeaderIn + uint32(in.SizeBytes()),
+		Opcode: linux.FUSE_INTERRUPT,
+		Unique: id | linux.FUSE_INT_REQ_BIT,
+	}
+
+	buf := make([]byte, hdr.Len)
+
+	hdr.MarshalUnsafe(buf[:linux.SizeOfFUSEHeaderIn])
+	in.MarshalUnsafe(buf[linux.SizeOfFUSEHeaderIn:])
+
+	// The server doesn't reply to interrupts unless it wants to refuse them.
+	return &Request{
+		id:      hdr.Unique,
+		hdr:     &hdr,
+		data:    buf,
+		noReply: true,
+	}
+}
+
 // futureResponse represents an in-flight request, that may or may not have
 // completed yet. Convert it to a resolved Response by calling Resolve, but note
 // that this may block.
@@ -144,6 +176,13 @@ type futureResponse struct {
 
 	// If this request is async.
 	async bool
+
+	// dev is the /dev/fuse file description through which the FUSE server read
+	// the request, and through which it must reply. It is nil until then.
+	dev *DeviceFD
+
+	// pollFD is Request.pollFD.
+	pollFD *regularFileFD
 }
 
 // newFutureResponse creates a future response to a FUSE request.
@@ -152,6 +191,7 @@ func newFutureResponse(req *Request) *futureResponse {
 		opcode: req.hdr.Opcode,
 		ch:     make(chan struct{}),
 		async:  req.async,
+		pollFD: req.pollFD,
 	}
 }
 
@@ -195,7 +235,14 @@ func (r *Response) Error() error {
 	if errno >= 0 {
 		return nil
 	}
+	return errorFromErrno(errno)
+}
 
+// errorFromErrno returns the error corresponding to the negated errno errno
+// sent by the FUSE server.
+//
+// Preconditions: errno < 0.
+func errorFromErrno(errno int32) error {
 	// If we get a bad error in the response, warn and convert it to EINVAL.
 	sysErrNo := unix.Errno(-errno)
 	if !syserr.IsValid(sysErrNo) {
@@ -49,11 +49,8 @@ func setup(t *testing.T) *testutil.System {
 // newTestConnection creates a fuse connection that the sentry can communicate with
 // and the FD for the server to communicate with.
 func newTestConnection(system *testutil.System, maxActiveRequests uint64) (*connection, *vfs.FileDescription, error) {
-	fuseDev := &DeviceFD{}
-
-	vd := system.VFS.NewAnonVirtualDentry("fuse")
-	defer vd.DecRef(system.Ctx)
-	if err := fuseDev.vfsfd.Init(fuseDev, linux.O_RDWR, vd.Mount(), vd.Dentry(), &vfs.FileDescriptionOptions{}); err != nil {
+	fuseDev, err := newTestDevice(system)
+	if err != nil {
 		return nil, nil, err
 	}
 
@@ -75,6 +72,19 @@ func newTestConnection(system *testutil.System, maxActiveRequests uint64) (*conn
 	return conn, &fuseDev.vfsfd, nil
 }
 
+// newTestDevice creates a FUSE device FD that is not attached to any
+// connection.
+func newTestDevice(system *testutil.System) (*DeviceFD, error) {
+	fuseDev := &DeviceFD{}
+
+	vd := system.VFS.NewAnonVirtualDentry("fuse")
+	defer vd.DecRef(system.Ctx)
+	if err := fuseDev.vfsfd.Init(fuseDev, linux.O_RDWR, vd.Mount(), vd.Dentry(), &vfs.FileDescriptionOptions{}); err != nil {
+		return nil, err
+	}
+	return fuseDev, nil
+}
+
 // newTestFilesystem creates a filesystem that the sentry can communicate with
 // and the FD for the server to communicate with.
 func newTestFilesystem(system *testutil.System, fd *vfs.FileDescription, maxActiveRequests uint64) (*filesystem, error) {
@@ -0,0 +1,218 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuse
+
+import (
+	"bytes"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/marshal"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/ktime"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// ListXattr implements kernfs.InodeXattrs.ListXattr.
+func (i *inode) ListXattr(ctx context.Context, creds *auth.Credentials, size uint64) ([]string, error) {
+	if !i.allowCredentials(creds) {
+		return nil, linuxerr.EACCES
+	}
+	conn := i.fs.conn
+	if conn.noListxattr.Load() {
+		return nil, linuxerr.EOPNOTSUPP
+	}
+	if size == 0 {
+		// The caller wants the whole list, so ask the server how large it is.
+		var err error
+		if size, err = i.xattrSize(ctx, creds, linux.FUSE_LISTXATTR, &linux.FUSEGetXattrIn{}, &conn.noListxattr); err != nil || size == 0 {
+			return nil, err
+		}
+	}
+	size = min(size, linux.XATTR_LIST_MAX)
+	in := linux.FUSEGetXattrIn{Size: uint32(size)}
+	data, err := i.xattrData(ctx, creds, linux.FUSE_LISTXATTR, &in, size, &conn.noListxattr)
+	if err != nil {
+		return nil, err
+	}
+	return parseXattrList(data)
+}
+
+// GetXattr implements kernfs.InodeXattrs.GetXattr.
+func (i *inode) GetXattr(ctx context.Context, creds *auth.Credentials, opts vfs.GetXattrOptions) (string, error) {
+	if err := i.checkXattrPermissions(ctx, creds, opts.Name, vfs.MayRead); err != nil {
+		return "", err
+	}
+	conn := i.fs.conn
+	if conn.noGetxattr.Load() {
+		return "", linuxerr.EOPNOTSUPP
+	}
+	size := opts.Size
+	if size == 0 {
+		// The caller wants the whole value, so ask the server how large it is.
+		in := linux.FUSEGetXattrNameIn{Name: linux.CString(opts.Name)}
+		var err error
+		if size, err = i.xattrSize(ctx, creds, linux.FUSE_GETXATTR, &in, &conn.noGetxattr); err != nil || size == 0 {
+			return "", err
+		}
+	}
+	size = min(size, linux.XATTR_SIZE_MAX)
+	in := linux.FUSEGetXattrNameIn{
+		GetXattrIn: linux.FUSEGetXattrIn{Size: uint32(size)},
+		Name:       linux.CString(opts.Name),
+	}
+	data, err := i.xattrData(ctx, creds, linux.FUSE_GETXATTR, &in, size, &conn.noGetxattr)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// SetXattr implements kernfs.InodeXattrs.SetXattr.
+func (i *inode) SetXattr(ctx context.Context, creds *auth.Credentials, opts vfs.SetXattrOptions) error {
+	if err := i.checkXattrPermissions(ctx, creds, opts.Name, vfs.MayWrite); err != nil {
+		return err
+	}
+	conn := i.fs.conn
+	if conn.noSetxattr.Load() {
+		return linuxerr.EOPNOTSUPP
+	}
+	in := linux.FUSESetXattrIn{
+		SetXattrMeta: linux.FUSESetXattrMeta{
+			Size:  uint32(len(opts.Value)),
+			Flags: opts.Flags,
+		},
+		Name:  linux.CString(opts.Name),
+		Value: primitive.ByteSlice(opts.Value),
+	}
+	if _, err := i.callXattr(ctx, creds, linux.FUSE_SETXATTR, &in, &conn.noSetxattr); err != nil {
+		return err
+	}
+	i.xattrChanged()
+	return nil
+}
+
+// RemoveXattr implements kernfs.InodeXattrs.RemoveXattr.
+func (i *inode) RemoveXattr(ctx context.Context, creds *auth.Credentials, name string) error {
+	if err := i.checkXattrPermissions(ctx, creds, name, vfs.MayWrite); err != nil {
+		return err
+	}
+	conn := i.fs.conn
+	if conn.noRemovexattr.Load() {
+		return linuxerr.EOPNOTSUPP
+	}
+	in := linux.FUSERemoveXattrIn{Name: linux.CString(name)}
+	if _, err := i.callXattr(ctx, creds, linux.FUSE_REMOVEXATTR, &in, &conn.noRemovexattr); err != nil {
+		return err
+	}
+	i.xattrChanged()
+	return nil
+}
+
+// checkXattrPermissions checks that creds may access the extended attribute
+// name. This is analogous to Linux's fs/xattr.c:xattr_permission().
+func (i *inode) checkXattrPermissions(ctx context.Context, creds *auth.Credentials, name string, ats vfs.AccessTypes) error {
+	if !i.allowCredentials(creds) {
+		return linuxerr.EACCES
+	}
+	if err := vfs.CheckXattrPermissions(creds, ats, i.Mode(), i.UID(), name); err != nil {
+		return err
+	}
+	// Without default_permissions, access checks are left to the server.
+	if i.fs.opts.defaultPermissions {
+		return i.CheckPermissions(ctx, creds, ats)
+	}
+	return nil
+}
+
+// xattrSize sends a size-probe FUSE_GETXATTR or FUSE_LISTXATTR request, whose
+// size is 0, and returns the size reported by the server.
+func (i *inode) xattrSize(ctx context.Context, creds *auth.Credentials, opcode linux.FUSEOpcode, payload marshal.Marshallable, unsupported *atomicbitops.Bool) (uint64, error) {
+	res, err := i.callXattr(ctx, creds, opcode, payload, unsupported)
+	if err != nil {
+		return 0, err
+	}
+	var out linux.FUSEGetXattrOut
+	if err := res.UnmarshalPayload(&out); err != nil {
+		return 0, err
+	}
+	return uint64(out.Size), nil
+}
+
+// xattrData sends a FUSE_GETXATTR or FUSE_LISTXATTR request for at most size
+// bytes and returns the data in the server's reply.
+func (i *inode) xattrData(ctx context.Context, creds *auth.Credentials, opcode linux.FUSEOpcode, payload marshal.Marshallable, size uint64, unsupported *atomicbitops.Bool) ([]byte, error) {
+	res, err := i.callXattr(ctx, creds, opcode, payload, unsupported)
+	if err != nil {
+		return nil, err
+	}
+	n := uint64(res.DataLen())
+	if n > size {
+		return nil, linuxerr.EIO
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return res.data[res.hdr.SizeBytes():], nil
+}
+
+// callXattr sends an extended attribute request to the server. If the server
+// doesn't implement the request, callXattr sets unsupported so that future
+// requests aren't sent, and returns EOPNOTSUPP.
+func (i *inode) callXattr(ctx context.Context, creds *auth.Credentials, opcode linux.FUSEOpcode, payload marshal.Marshallable, unsupported *atomicbitops.Bool) (*Response, error) {
+	req := i.fs.conn.NewRequest(creds, pidFromContext(ctx), i.nodeID, opcode, payload)
+	res, err := i.fs.conn.Call(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := res.Error(); err != nil {
+		if linuxerr.Equals(linuxerr.ENOSYS, err) {
+			unsupported.Store(true)
+			return nil, linuxerr.EOPNOTSUPP
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// xattrChanged updates the inode after its extended attributes were changed
+// by the server.
+func (i *inode) xattrChanged() {
+	i.attrMu.Lock()
+	defer i.attrMu.Unlock()
+	i.ctime.Store(i.fs.clock.Now().Nanoseconds())
+	// Attributes such as the mode may have changed as a side effect, e.g. by
+	// setting a POSIX ACL.
+	i.attrTime = ktime.ZeroTime
+}
+
+// parseXattrList parses a list of null-terminated extended attribute names
+// as returned by FUSE_LISTXATTR. This is analogous to Linux's
+// fs/fuse/xattr.c:fuse_verify_xattr_list().
+func parseXattrList(data []byte) ([]string, error) {
+	var names []string
+	for len(data) > 0 {
+		n := bytes.IndexByte(data, 0)
+		if n <= 0 {
+			return nil, linuxerr.EIO
+		}
+		names = append(names, string(data[:n]))
+		data = data[n+1:]
+	}
+	return names, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuse
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseXattrList(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			data: "",
+		},
+		{
+			name: "single",
+			data: "user.a\x00",
+			want: []string{"user.a"},
+		},
+		{
+			name: "multiple",
+			data: "user.a\x00security.selinux\x00trusted.b\x00",
+			want: []string{"user.a", "security.selinux", "trusted.b"},
+		},
+		{
+			name:    "unterminated",
+			data:    "user.a\x00user.b",
+			wantErr: true,
+		},
+		{
+			name:    "empty name",
+			data:    "user.a\x00\x00",
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseXattrList([]byte(tc.data))
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("parseXattrList(%q) = %q, want error", tc.data, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseXattrList(%q) failed: %v", tc.data, err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("parseXattrList(%q) = %q, want %q", tc.data, got, tc.want)
+			}
+		})
+	}
+}
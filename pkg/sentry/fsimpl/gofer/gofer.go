@@ -1388,15 +1388,31 @@ func (d *dentry) setStat(ctx context.Context, creds *auth.Credentials, opts *vfs
 	return nil
 }
 
-// doAllocate performs an allocate operation on d. Note that d.metadataMu will
-// be held when allocate is called.
-func (d *dentry) doAllocate(ctx context.Context, offset, length uint64, allocate func() error) error {
+// doAllocate performs an allocate operation with the given fallocate(2) mode
+// on d. Note that d.metadataMu will be held when allocate is called.
+func (d *dentry) doAllocate(ctx context.Context, mode, offset, length uint64, allocate func() error) error {
 	d.metadataMu.Lock()
 	defer d.metadataMu.Unlock()
 
-	// Allocating a smaller size is a noop.
-	size := offset + length
-	if d.cachedMetadataAuthoritative() && size <= d.size.RacyLoad() {
+	oldSize := d.size.RacyLoad()
+	newSize := oldSize
+	switch {
+	case mode&linux.FALLOC_FL_COLLAPSE_RANGE != 0:
+		// The remote filesystem rejects ranges that reach the end of the
+		// file.
+		if length < oldSize {
+			newSize = oldSize - length
+		}
+	case mode&linux.FALLOC_FL_INSERT_RANGE != 0:
+		newSize = oldSize + length
+	case mode&linux.FALLOC_FL_KEEP_SIZE == 0:
+		if end := offset + length; end > oldSize {
+			newSize = end
+		}
+	}
+
+	// Allocating within the file is a noop.
+	if mode&^linux.FALLOC_FL_KEEP_SIZE == 0 && d.cachedMetadataAuthoritative() && offset+length <= oldSize {
 		return nil
 	}
 
@@ -1404,7 +1420,9 @@ func (d *dentry) doAllocate(ctx context.Context, offset, length uint64, allocate
 	if err != nil {
 		return err
 	}
-	d.updateSizeLocked(size)
+	if newSize != oldSize {
+		d.updateSizeLocked(newSize)
+	}
 	if d.cachedMetadataAuthoritative() {
 		d.touchCMtimeLocked()
 	}
@@ -20,6 +20,7 @@ import (
 	"gvisor.dev/gvisor/pkg/lisafs"
 	"gvisor.dev/gvisor/pkg/safemem"
 	"gvisor.dev/gvisor/pkg/sentry/hostfd"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sync"
 )
 
@@ -51,30 +52,48 @@ func (h *handle) readToBlocksAt(ctx context.Context, dsts safemem.BlockSeq, offs
 	if dsts.IsEmpty() {
 		return 0, nil
 	}
+	var (
+		n   uint64
+		err error
+	)
 	if h.fd >= 0 {
 		ctx.UninterruptibleSleepStart(false)
-		n, err := hostfd.Preadv2(h.fd, dsts, int64(offset), 0 /* flags */)
+		n, err = hostfd.Preadv2(h.fd, dsts, int64(offset), 0 /* flags */)
 		ctx.UninterruptibleSleepFinish(false)
-		return n, err
+	} else {
+		rw := getHandleReadWriter(ctx, h, int64(offset))
+		n, err = safemem.FromIOReader{rw}.ReadToBlocks(dsts)
+		putHandleReadWriter(rw)
 	}
-	rw := getHandleReadWriter(ctx, h, int64(offset))
-	defer putHandleReadWriter(rw)
-	return safemem.FromIOReader{rw}.ReadToBlocks(dsts)
+	// Charge the read to the caller's storage I/O, which also allows page
+	// faults that required it to be counted as major faults.
+	if io := usage.IOFromContext(ctx); io != nil {
+		io.AccountReadIO(int64(n))
+	}
+	return n, err
 }
 
 func (h *handle) writeFromBlocksAt(ctx context.Context, srcs safemem.BlockSeq, offset uint64) (uint64, error) {
 	if srcs.IsEmpty() {
 		return 0, nil
 	}
+	var (
+		n   uint64
+		err error
+	)
 	if h.fd >= 0 {
 		ctx.UninterruptibleSleepStart(false)
-		n, err := hostfd.Pwritev2(h.fd, srcs, int64(offset), 0 /* flags */)
+		n, err = hostfd.Pwritev2(h.fd, srcs, int64(offset), 0 /* flags */)
 		ctx.UninterruptibleSleepFinish(false)
-		return n, err
+	} else {
+		rw := getHandleReadWriter(ctx, h, int64(offset))
+		n, err = safemem.FromIOWriter{rw}.WriteFromBlocks(srcs)
+		putHandleReadWriter(rw)
 	}
-	rw := getHandleReadWriter(ctx, h, int64(offset))
-	defer putHandleReadWriter(rw)
-	return safemem.FromIOWriter{rw}.WriteFromBlocks(srcs)
+	if io := usage.IOFromContext(ctx); io != nil {
+		io.AccountWriteIO(int64(n))
+	}
+	return n, err
 }
 
 func (h *handle) allocate(ctx context.Context, mode, offset, length uint64) error {
@@ -100,11 +100,29 @@ func (fd *regularFileFD) OnClose(ctx context.Context) error {
 // Allocate implements vfs.FileDescriptionImpl.Allocate.
 func (fd *regularFileFD) Allocate(ctx context.Context, mode, offset, length uint64) error {
 	d := fd.dentry()
-	return d.doAllocate(ctx, offset, length, func() error {
+	return d.doAllocate(ctx, mode, offset, length, func() error {
+		if mode&(linux.FALLOC_FL_PUNCH_HOLE|linux.FALLOC_FL_ZERO_RANGE|linux.FALLOC_FL_COLLAPSE_RANGE|linux.FALLOC_FL_INSERT_RANGE) != 0 {
+			// These modes change the contents of the remote file, so cached
+			// pages in the affected range must not be used or written back
+			// afterward. Collapsing or inserting a range moves all data
+			// after offset.
+			size := int64(length)
+			if mode&(linux.FALLOC_FL_COLLAPSE_RANGE|linux.FALLOC_FL_INSERT_RANGE) != 0 {
+				size = math.MaxInt64 - int64(offset)
+			}
+			if err := d.evictCache(ctx, int64(offset), size); err != nil {
+				return err
+			}
+		}
 		return d.allocate(ctx, mode, offset, length)
 	})
 }
 
+// IODevice implements vfs.IOAccountedFileDescriptionImpl.IODevice.
+func (fd *regularFileFD) IODevice() (vfs.IODevice, bool) {
+	return vfs.IODevice{Major: linux.UNNAMED_MAJOR, Minor: fd.dentry().fs.devMinor}, true
+}
+
 // PRead implements vfs.FileDescriptionImpl.PRead.
 func (fd *regularFileFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
 	start := fsmetric.StartReadWait()
@@ -279,15 +297,22 @@ func (fd *regularFileFD) pwrite(ctx context.Context, src usermem.IOSequence, off
 }
 
 func (fd *regularFileFD) writeCache(ctx context.Context, d *dentry, offset int64, src usermem.IOSequence) error {
+	return d.evictCache(ctx, offset, src.NumBytes())
+}
+
+// evictCache writes dirty cached pages in the given range back to the remote
+// file, then removes them from the cache. It is used before operations that
+// modify the remote file's contents directly.
+func (d *dentry) evictCache(ctx context.Context, offset, size int64) error {
 	// Write dirty cached pages that will be touched by the write back to
 	// the remote file.
-	if err := d.writeback(ctx, offset, src.NumBytes()); err != nil {
+	if err := d.writeback(ctx, offset, size); err != nil {
 		return err
 	}
 
 	// Remove touched pages from the cache.
 	pgstart := hostarch.PageRoundDown(uint64(offset))
-	pgend, ok := hostarch.PageRoundUp(uint64(offset + src.NumBytes()))
+	pgend, ok := hostarch.PageRoundUp(uint64(offset + size))
 	if !ok {
 		return linuxerr.EINVAL
 	}
@@ -198,7 +198,7 @@ func (fd *specialFileFD) Epollable() bool {
 func (fd *specialFileFD) Allocate(ctx context.Context, mode, offset, length uint64) error {
 	if fd.isRegularFile {
 		d := fd.dentry()
-		return d.doAllocate(ctx, offset, length, func() error {
+		return d.doAllocate(ctx, mode, offset, length, func() error {
 			return fd.handle.allocate(ctx, mode, offset, length)
 		})
 	}
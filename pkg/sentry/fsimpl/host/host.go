@@ -39,6 +39,7 @@ import (
 	unixsocket "gvisor.dev/gvisor/pkg/sentry/socket/unix"
 	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
 	"gvisor.dev/gvisor/pkg/sentry/uniqueid"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/usermem"
@@ -799,6 +800,15 @@ func (f *fileDescription) Allocate(ctx context.Context, mode, offset, length uin
 	return unix.Fallocate(f.inode.hostFD, uint32(mode), int64(offset), int64(length))
 }
 
+// IODevice implements vfs.IOAccountedFileDescriptionImpl.IODevice. Only I/O on
+// regular files is accounted.
+func (f *fileDescription) IODevice() (vfs.IODevice, bool) {
+	if f.inode.ftype != unix.S_IFREG {
+		return vfs.IODevice{}, false
+	}
+	return vfs.IODevice{Major: linux.UNNAMED_MAJOR, Minor: f.inode.devMinor}, true
+}
+
 // PRead implements vfs.FileDescriptionImpl.PRead.
 func (f *fileDescription) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
 	// Check that flags are supported.
@@ -813,7 +823,9 @@ func (f *fileDescription) PRead(ctx context.Context, dst usermem.IOSequence, off
 		return 0, linuxerr.ESPIPE
 	}
 
-	return readFromHostFD(ctx, i.hostFD, dst, offset, opts.Flags)
+	n, err := readFromHostFD(ctx, i.hostFD, dst, offset, opts.Flags)
+	i.accountIO(ctx, n, false /* write */)
+	return n, err
 }
 
 // Read implements vfs.FileDescriptionImpl.Read.
@@ -849,9 +861,27 @@ func (f *fileDescription) Read(ctx context.Context, dst usermem.IOSequence, opts
 	n, err := readFromHostFD(ctx, i.hostFD, dst, f.offset, opts.Flags)
 	f.offset += n
 	f.offsetMu.Unlock()
+	i.accountIO(ctx, n, false /* write */)
 	return n, err
 }
 
+// accountIO charges n bytes of storage I/O to the task in ctx if i is a
+// regular file, matching Linux's accounting in /proc/[pid]/io and getrusage.
+func (i *inode) accountIO(ctx context.Context, n int64, write bool) {
+	if n <= 0 || i.ftype != unix.S_IFREG {
+		return
+	}
+	io := usage.IOFromContext(ctx)
+	if io == nil {
+		return
+	}
+	if write {
+		io.AccountWriteIO(n)
+	} else {
+		io.AccountReadIO(n)
+	}
+}
+
 func (i *inode) readFromBuf(ctx context.Context, dst *usermem.IOSequence) (int64, error) {
 	if i.haveBuf.Load() == 0 {
 		return 0, nil
@@ -884,7 +914,9 @@ func (f *fileDescription) PWrite(ctx context.Context, src usermem.IOSequence, of
 		return 0, linuxerr.ESPIPE
 	}
 
-	return f.writeToHostFD(ctx, src, offset, opts.Flags)
+	n, err := f.writeToHostFD(ctx, src, offset, opts.Flags)
+	f.inode.accountIO(ctx, n, true /* write */)
+	return n, err
 }
 
 // Write implements vfs.FileDescriptionImpl.Write.
@@ -914,6 +946,7 @@ func (f *fileDescription) Write(ctx context.Context, src usermem.IOSequence, opt
 	n, err := f.writeToHostFD(ctx, src, f.offset, opts.Flags)
 	f.offset += n
 	f.offsetMu.Unlock()
+	i.accountIO(ctx, n, true /* write */)
 	return n, err
 }
 
@@ -23,6 +23,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
 )
 
 // DynamicBytesFile implements kernfs.Inode and represents a read-only file
@@ -88,6 +89,25 @@ func (f *DynamicBytesFile) Data() vfs.DynamicBytesSource {
 	return f.data
 }
 
+// WaitableDynamicBytesSource is a vfs.DynamicBytesSource whose FDs can be
+// waited on. Since the source is shared by all FDs on the file, each method
+// is passed the FD it applies to.
+type WaitableDynamicBytesSource interface {
+	vfs.DynamicBytesSource
+
+	// Readiness implements waiter.Waitable.Readiness for fd.
+	Readiness(fd *vfs.FileDescription, mask waiter.EventMask) waiter.EventMask
+
+	// EventRegister implements waiter.Waitable.EventRegister for fd.
+	EventRegister(fd *vfs.FileDescription, e *waiter.Entry) error
+
+	// EventUnregister implements waiter.Waitable.EventUnregister for fd.
+	EventUnregister(fd *vfs.FileDescription, e *waiter.Entry)
+
+	// ReleaseFD is called when fd is released.
+	ReleaseFD(ctx context.Context, fd *vfs.FileDescription)
+}
+
 // DynamicBytesFD implements vfs.FileDescriptionImpl for an FD backed by a
 // DynamicBytesFile.
 //
@@ -101,6 +121,10 @@ type DynamicBytesFD struct {
 
 	vfsfd vfs.FileDescription
 	inode Inode
+
+	// waitable is the data source if it implements
+	// WaitableDynamicBytesSource, or nil otherwise. Immutable.
+	waitable WaitableDynamicBytesSource
 }
 
 // Init initializes a DynamicBytesFD.
@@ -114,6 +138,7 @@ func (fd *DynamicBytesFD) Init(m *vfs.Mount, d *Dentry, data vfs.DynamicBytesSou
 		return err
 	}
 	fd.inode = d.inode
+	fd.waitable, _ = data.(WaitableDynamicBytesSource)
 	fd.DynamicBytesFileDescriptionImpl.Init(&fd.vfsfd, data)
 	return nil
 }
@@ -144,7 +169,41 @@ func (fd *DynamicBytesFD) PWrite(ctx context.Context, src usermem.IOSequence, of
 }
 
 // Release implements vfs.FileDescriptionImpl.Release.
-func (fd *DynamicBytesFD) Release(context.Context) {}
+func (fd *DynamicBytesFD) Release(ctx context.Context) {
+	if fd.waitable != nil {
+		fd.waitable.ReleaseFD(ctx, &fd.vfsfd)
+	}
+}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (fd *DynamicBytesFD) Readiness(mask waiter.EventMask) waiter.EventMask {
+	if fd.waitable != nil {
+		return fd.waitable.Readiness(&fd.vfsfd, mask)
+	}
+	return fd.FileDescriptionDefaultImpl.Readiness(mask)
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (fd *DynamicBytesFD) EventRegister(e *waiter.Entry) error {
+	if fd.waitable != nil {
+		return fd.waitable.EventRegister(&fd.vfsfd, e)
+	}
+	return fd.FileDescriptionDefaultImpl.EventRegister(e)
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (fd *DynamicBytesFD) EventUnregister(e *waiter.Entry) {
+	if fd.waitable != nil {
+		fd.waitable.EventUnregister(&fd.vfsfd, e)
+		return
+	}
+	fd.FileDescriptionDefaultImpl.EventUnregister(e)
+}
+
+// Epollable implements vfs.FileDescriptionImpl.Epollable.
+func (fd *DynamicBytesFD) Epollable() bool {
+	return fd.waitable != nil
+}
 
 // Stat implements vfs.FileDescriptionImpl.Stat.
 func (fd *DynamicBytesFD) Stat(ctx context.Context, opts vfs.StatOptions) (linux.Statx, error) {
@@ -1072,11 +1072,13 @@ func (fs *Filesystem) ListXattrAt(ctx context.Context, rp *vfs.ResolvingPath, si
 	fs.mu.RLock()
 	defer fs.processDeferredDecRefs(ctx)
 	defer fs.mu.RUnlock()
-	_, err := fs.walkExistingLocked(ctx, rp)
+	d, err := fs.walkExistingLocked(ctx, rp)
 	if err != nil {
 		return nil, err
 	}
-	// kernfs currently does not support extended attributes.
+	if xi, ok := d.inode.(InodeXattrs); ok {
+		return xi.ListXattr(ctx, rp.Credentials(), size)
+	}
 	return nil, linuxerr.ENOTSUP
 }
 
@@ -1085,11 +1087,13 @@ func (fs *Filesystem) GetXattrAt(ctx context.Context, rp *vfs.ResolvingPath, opt
 	fs.mu.RLock()
 	defer fs.processDeferredDecRefs(ctx)
 	defer fs.mu.RUnlock()
-	_, err := fs.walkExistingLocked(ctx, rp)
+	d, err := fs.walkExistingLocked(ctx, rp)
 	if err != nil {
 		return "", err
 	}
-	// kernfs currently does not support extended attributes.
+	if xi, ok := d.inode.(InodeXattrs); ok {
+		return xi.GetXattr(ctx, rp.Credentials(), opts)
+	}
 	return "", linuxerr.ENOTSUP
 }
 
@@ -1097,26 +1101,46 @@ func (fs *Filesystem) GetXattrAt(ctx context.Context, rp *vfs.ResolvingPath, opt
 func (fs *Filesystem) SetXattrAt(ctx context.Context, rp *vfs.ResolvingPath, opts vfs.SetXattrOptions) error {
 	fs.mu.RLock()
 	defer fs.processDeferredDecRefs(ctx)
-	defer fs.mu.RUnlock()
-	_, err := fs.walkExistingLocked(ctx, rp)
+	d, err := fs.walkExistingLocked(ctx, rp)
+	if err != nil {
+		fs.mu.RUnlock()
+		return err
+	}
+	xi, ok := d.inode.(InodeXattrs)
+	if !ok {
+		fs.mu.RUnlock()
+		return linuxerr.ENOTSUP
+	}
+	err = xi.SetXattr(ctx, rp.Credentials(), opts)
+	fs.mu.RUnlock()
 	if err != nil {
 		return err
 	}
-	// kernfs currently does not support extended attributes.
-	return linuxerr.ENOTSUP
+	d.InotifyWithParent(ctx, linux.IN_ATTRIB, 0, vfs.InodeEvent)
+	return nil
 }
 
 // RemoveXattrAt implements vfs.FilesystemImpl.RemoveXattrAt.
 func (fs *Filesystem) RemoveXattrAt(ctx context.Context, rp *vfs.ResolvingPath, name string) error {
 	fs.mu.RLock()
 	defer fs.processDeferredDecRefs(ctx)
-	defer fs.mu.RUnlock()
-	_, err := fs.walkExistingLocked(ctx, rp)
+	d, err := fs.walkExistingLocked(ctx, rp)
 	if err != nil {
+		fs.mu.RUnlock()
 		return err
 	}
-	// kernfs currently does not support extended attributes.
-	return linuxerr.ENOTSUP
+	xi, ok := d.inode.(InodeXattrs)
+	if !ok {
+		fs.mu.RUnlock()
+		return linuxerr.ENOTSUP
+	}
+	err = xi.RemoveXattr(ctx, rp.Credentials(), name)
+	fs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	d.InotifyWithParent(ctx, linux.IN_ATTRIB, 0, vfs.InodeEvent)
+	return nil
 }
 
 // PrependPath implements vfs.FilesystemImpl.PrependPath.
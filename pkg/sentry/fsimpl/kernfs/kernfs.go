@@ -907,3 +907,24 @@ type inodeSymlink interface {
 	//		VirtualDentry, "", EINVAL).
 	Getlink(ctx context.Context, mnt *vfs.Mount) (vfs.VirtualDentry, string, error)
 }
+
+// InodeXattrs may be implemented by inodes that support extended attributes.
+// Extended attribute operations on inodes that don't implement it fail with
+// ENOTSUP.
+//
+// Implementations are responsible for permission checks.
+type InodeXattrs interface {
+	// ListXattr returns all extended attribute names for the inode.
+	ListXattr(ctx context.Context, creds *auth.Credentials, size uint64) ([]string, error)
+
+	// GetXattr returns the value associated with the given extended
+	// attribute for the inode.
+	GetXattr(ctx context.Context, creds *auth.Credentials, opts vfs.GetXattrOptions) (string, error)
+
+	// SetXattr changes the value associated with the given extended
+	// attribute for the inode.
+	SetXattr(ctx context.Context, creds *auth.Credentials, opts vfs.SetXattrOptions) error
+
+	// RemoveXattr removes the given extended attribute from the inode.
+	RemoveXattr(ctx context.Context, creds *auth.Credentials, name string) error
+}
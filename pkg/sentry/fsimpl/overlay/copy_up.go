@@ -31,6 +31,16 @@ func (d *dentry) isCopiedUp() bool {
 	return d.copiedUp.Load() != 0
 }
 
+func (d *dentry) isMetacopy() bool {
+	return d.metacopy.Load() != 0
+}
+
+// isDataCopiedUp returns true if d has been copied-up, and either isn't a
+// regular file or has had its data copied-up as well.
+func (d *dentry) isDataCopiedUp() bool {
+	return d.isCopiedUp() && !d.isMetacopy()
+}
+
 func (d *dentry) canBeCopiedUp() bool {
 	ftype := d.mode.Load() & linux.S_IFMT
 	switch ftype {
@@ -44,6 +54,9 @@ func (d *dentry) canBeCopiedUp() bool {
 }
 
 // copyUpLocked ensures that d exists on the upper layer, i.e. d.upperVD.Ok().
+// If filesystem.opts.MetaCopy is true, the upper layer copy of a regular file
+// may contain only metadata; callers that require its data must use
+// copyUpDataLocked instead.
 //
 // Preconditions: filesystem.renameMu must be locked.
 func (d *dentry) copyUpLocked(ctx context.Context) error {
@@ -91,7 +104,6 @@ func (d *dentry) copyUpMaybeSyntheticMountpointLocked(ctx context.Context, forSy
 		Root:  d.lowerVDs[0],
 		Start: d.lowerVDs[0],
 	}
-	const timestampsMask = linux.STATX_ATIME | linux.STATX_MTIME
 	oldStat, err := vfsObj.StatAt(ctx, d.fs.creds, &oldpop, &vfs.StatOptions{
 		Mask: timestampsMask,
 	})
@@ -107,7 +119,9 @@ func (d *dentry) copyUpMaybeSyntheticMountpointLocked(ctx context.Context, forSy
 		Path:  fspath.Parse(d.name),
 	}
 	// Used during copy-up of memory-mapped regular files.
-	var mmapOpts *memmap.MMapOpts
+	var upperMappable memmap.Mappable
+	// If metacopy is true, only the metadata of a regular file is copied up.
+	metacopy := ftype == linux.S_IFREG && d.fs.opts.MetaCopy
 	cleanupUndoCopyUp := func() {
 		var err error
 		if ftype == linux.S_IFDIR {
@@ -125,13 +139,6 @@ func (d *dentry) copyUpMaybeSyntheticMountpointLocked(ctx context.Context, forSy
 	}
 	switch ftype {
 	case linux.S_IFREG:
-		oldFD, err := vfsObj.OpenAt(ctx, d.fs.creds, &oldpop, &vfs.OpenOptions{
-			Flags: linux.O_RDONLY,
-		})
-		if err != nil {
-			return err
-		}
-		defer oldFD.DecRef(ctx)
 		newFD, err := vfsObj.OpenAt(ctx, d.fs.creds, &newpop, &vfs.OpenOptions{
 			Flags: linux.O_WRONLY | linux.O_CREAT | linux.O_EXCL,
 			// d.mode can be read because d.copyMu is locked.
@@ -141,26 +148,56 @@ func (d *dentry) copyUpMaybeSyntheticMountpointLocked(ctx context.Context, forSy
 			return err
 		}
 		defer newFD.DecRef(ctx)
-		if _, err := vfs.CopyRegularFileData(ctx, newFD, oldFD); err != nil {
-			cleanupUndoCopyUp()
-			return err
-		}
-		if d.wrappedMappable != nil {
-			// We may have memory mappings of the file on the lower layer.
-			// Switch to mapping the file on the upper layer instead.
-			mmapOpts = &memmap.MMapOpts{
-				Perms:    hostarch.ReadWrite,
-				MaxPerms: hostarch.ReadWrite,
+		dataVD := d.lowerDataVD()
+		if metacopy {
+			// Give the upper layer file the size of the file's data, without
+			// copying any of it.
+			dataStat, err := vfsObj.StatAt(ctx, d.fs.creds, &vfs.PathOperation{
+				Root:  dataVD,
+				Start: dataVD,
+			}, &vfs.StatOptions{
+				Mask: linux.STATX_SIZE,
+			})
+			if err != nil {
+				cleanupUndoCopyUp()
+				return err
 			}
-			if err := newFD.ConfigureMMap(ctx, mmapOpts); err != nil {
+			if err := newFD.SetStat(ctx, vfs.SetStatOptions{
+				Stat: linux.Statx{
+					Mask: linux.STATX_SIZE,
+					Size: dataStat.Size,
+				},
+			}); err != nil {
 				cleanupUndoCopyUp()
 				return err
 			}
-			if mmapOpts.MappingIdentity != nil {
-				mmapOpts.MappingIdentity.DecRef(ctx)
+		} else {
+			oldFD, err := vfsObj.OpenAt(ctx, d.fs.creds, &vfs.PathOperation{
+				Root:  dataVD,
+				Start: dataVD,
+			}, &vfs.OpenOptions{
+				Flags: linux.O_RDONLY,
+			})
+			if err != nil {
+				cleanupUndoCopyUp()
+				return err
+			}
+			defer oldFD.DecRef(ctx)
+			if _, err := vfs.CopyRegularFileData(ctx, newFD, oldFD); err != nil {
+				cleanupUndoCopyUp()
+				return err
+			}
+			if d.wrappedMappable != nil {
+				// We may have memory mappings of the file on the lower layer.
+				// Switch to mapping the file on the upper layer instead. Don't
+				// actually switch Mappables until the end of copy-up; see
+				// dentry.switchMappableLocked() for why.
+				upperMappable, err = configureUpperMMap(ctx, newFD)
+				if err != nil {
+					cleanupUndoCopyUp()
+					return err
+				}
 			}
-			// Don't actually switch Mappables until the end of copy-up; see
-			// below for why.
 		}
 		if err := newFD.SetStat(ctx, vfs.SetStatOptions{
 			Stat: linux.Statx{
@@ -273,6 +310,17 @@ func (d *dentry) copyUpMaybeSyntheticMountpointLocked(ctx context.Context, forSy
 		cleanupUndoCopyUp()
 		return err
 	}
+	if metacopy {
+		if err := vfsObj.SetXattrAt(ctx, d.fs.creds, &vfs.PathOperation{
+			Root:  d.upperVD,
+			Start: d.upperVD,
+		}, &vfs.SetXattrOptions{
+			Name: d.fs.xattrPrefix + _OVL_XATTR_METACOPY_POSTFIX,
+		}); err != nil {
+			cleanupUndoCopyUp()
+			return err
+		}
+	}
 
 	// Update the dentry's device and inode numbers (except for directories,
 	// for which these remain overlay-assigned).
@@ -296,70 +344,220 @@ func (d *dentry) copyUpMaybeSyntheticMountpointLocked(ctx context.Context, forSy
 		d.ino.Store(upperStat.Ino)
 
 		// Lower level dentries for non-directories are no longer accessible from
-		// the overlayfs anymore after copyup. Ask filesystems to release their
-		// resources whenever possible.
-		for _, lowerDentry := range d.lowerVDs {
-			lowerDentry.Dentry().MarkEvictable()
+		// the overlayfs anymore after copyup, unless they still provide the
+		// data of a metacopy file. Ask filesystems to release their resources
+		// whenever possible.
+		if !metacopy {
+			d.markLowerEvictable()
 		}
 	}
 
-	if mmapOpts != nil && mmapOpts.Mappable != nil {
+	if upperMappable != nil {
 		d.mapsMu.Lock()
 		defer d.mapsMu.Unlock()
+		if err := d.switchMappableLocked(ctx, upperMappable); err != nil {
+			cleanupUndoCopyUp()
+			return err
+		}
+	}
 
-		// Propagate mappings of d to the new Mappable. Remember which mappings
-		// we added so we can remove them on failure.
-		upperMappable := mmapOpts.Mappable
-		allAdded := make(map[memmap.MappableRange]memmap.MappingsOfRange)
-		for seg := d.lowerMappings.FirstSegment(); seg.Ok(); seg = seg.NextSegment() {
-			added := make(memmap.MappingsOfRange)
-			for m := range seg.Value() {
-				if err := upperMappable.AddMapping(ctx, m.MappingSpace, m.AddrRange, seg.Start(), m.Writable); err != nil {
-					for m := range added {
-						upperMappable.RemoveMapping(ctx, m.MappingSpace, m.AddrRange, seg.Start(), m.Writable)
-					}
-					for mr, mappings := range allAdded {
-						for m := range mappings {
-							upperMappable.RemoveMapping(ctx, m.MappingSpace, m.AddrRange, mr.Start, m.Writable)
-						}
+	// Make sure that d never appears to be copied-up without a metacopy file's
+	// data being found on the lower layer.
+	if metacopy {
+		d.metacopy.Store(1)
+		d.copiedUp.Store(1)
+	} else {
+		d.copiedUp.Store(1)
+		d.metacopy.Store(0)
+	}
+	return nil
+}
+
+// copyUpDataLocked is like copyUpLocked, but additionally ensures that, if d
+// is a regular file, its data exists on the upper layer.
+//
+// Preconditions: filesystem.renameMu must be locked.
+func (d *dentry) copyUpDataLocked(ctx context.Context) error {
+	if err := d.copyUpLocked(ctx); err != nil {
+		return err
+	}
+	// Fast path.
+	if !d.isMetacopy() {
+		return nil
+	}
+
+	ctx = auth.ContextWithCredentials(ctx, d.fs.creds)
+	d.copyMu.Lock()
+	defer d.copyMu.Unlock()
+	if !d.isMetacopy() {
+		// Raced with another call to d.copyUpDataLocked().
+		return nil
+	}
+
+	// Writing the data changes the upper layer file's timestamps, which must
+	// be restored afterward.
+	vfsObj := d.fs.vfsfs.VirtualFilesystem()
+	upperpop := vfs.PathOperation{
+		Root:  d.upperVD,
+		Start: d.upperVD,
+	}
+	upperStat, err := vfsObj.StatAt(ctx, d.fs.creds, &upperpop, &vfs.StatOptions{
+		Mask: timestampsMask,
+	})
+	if err != nil {
+		return err
+	}
+	dataVD := d.lowerDataVD()
+	oldFD, err := vfsObj.OpenAt(ctx, d.fs.creds, &vfs.PathOperation{
+		Root:  dataVD,
+		Start: dataVD,
+	}, &vfs.OpenOptions{
+		Flags: linux.O_RDONLY,
+	})
+	if err != nil {
+		return err
+	}
+	defer oldFD.DecRef(ctx)
+	newFD, err := vfsObj.OpenAt(ctx, d.fs.creds, &upperpop, &vfs.OpenOptions{
+		Flags: linux.O_WRONLY,
+	})
+	if err != nil {
+		return err
+	}
+	defer newFD.DecRef(ctx)
+	// Until the metacopy attribute is removed below, the upper layer file's
+	// contents are ignored, so there is nothing to undo if this fails.
+	if _, err := vfs.CopyRegularFileData(ctx, newFD, oldFD); err != nil {
+		return err
+	}
+	var upperMappable memmap.Mappable
+	if d.wrappedMappable != nil {
+		if upperMappable, err = configureUpperMMap(ctx, newFD); err != nil {
+			return err
+		}
+	}
+	if err := newFD.SetStat(ctx, vfs.SetStatOptions{
+		Stat: linux.Statx{
+			Mask:  upperStat.Mask & timestampsMask,
+			Atime: upperStat.Atime,
+			Mtime: upperStat.Mtime,
+		},
+	}); err != nil {
+		return err
+	}
+	if err := vfsObj.RemoveXattrAt(ctx, d.fs.creds, &upperpop, d.fs.xattrPrefix+_OVL_XATTR_METACOPY_POSTFIX); err != nil {
+		return err
+	}
+	if upperMappable != nil {
+		d.mapsMu.Lock()
+		defer d.mapsMu.Unlock()
+		if err := d.switchMappableLocked(ctx, upperMappable); err != nil {
+			return err
+		}
+	}
+	d.metacopy.Store(0)
+	d.markLowerEvictable()
+	return nil
+}
+
+// copyUpForSetStatLocked copies-up d as required to apply opts to it. Only
+// truncation requires d's data to be copied-up.
+//
+// Preconditions: filesystem.renameMu must be locked.
+func (d *dentry) copyUpForSetStatLocked(ctx context.Context, opts *vfs.SetStatOptions) error {
+	if opts.Stat.Mask&linux.STATX_SIZE != 0 {
+		return d.copyUpDataLocked(ctx)
+	}
+	return d.copyUpLocked(ctx)
+}
+
+// timestampsMask is the set of timestamps preserved by copy-up.
+const timestampsMask = linux.STATX_ATIME | linux.STATX_MTIME
+
+// markLowerEvictable asks the filesystems of d's lower layers to release their
+// resources for d's lower layer files whenever possible.
+//
+// Preconditions: d's lower layer files are no longer accessible from the
+// overlay.
+func (d *dentry) markLowerEvictable() {
+	for _, lowerDentry := range d.lowerVDs {
+		lowerDentry.Dentry().MarkEvictable()
+	}
+}
+
+// configureUpperMMap returns the Mappable for newFD, which represents the
+// upper layer file of a regular file that is being copied-up.
+func configureUpperMMap(ctx context.Context, newFD *vfs.FileDescription) (memmap.Mappable, error) {
+	mmapOpts := memmap.MMapOpts{
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.ReadWrite,
+	}
+	if err := newFD.ConfigureMMap(ctx, &mmapOpts); err != nil {
+		return nil, err
+	}
+	if mmapOpts.MappingIdentity != nil {
+		mmapOpts.MappingIdentity.DecRef(ctx)
+	}
+	return mmapOpts.Mappable, nil
+}
+
+// switchMappableLocked replaces d.wrappedMappable, which maps d's lower layer
+// file, with upperMappable, which maps its upper layer file.
+//
+// Preconditions:
+//   - d.copyMu must be locked for writing.
+//   - d.mapsMu must be locked.
+//   - upperMappable maps a copy of d's data.
+func (d *dentry) switchMappableLocked(ctx context.Context, upperMappable memmap.Mappable) error {
+	// Propagate mappings of d to the new Mappable. Remember which mappings
+	// we added so we can remove them on failure.
+	allAdded := make(map[memmap.MappableRange]memmap.MappingsOfRange)
+	for seg := d.lowerMappings.FirstSegment(); seg.Ok(); seg = seg.NextSegment() {
+		added := make(memmap.MappingsOfRange)
+		for m := range seg.Value() {
+			if err := upperMappable.AddMapping(ctx, m.MappingSpace, m.AddrRange, seg.Start(), m.Writable); err != nil {
+				for m := range added {
+					upperMappable.RemoveMapping(ctx, m.MappingSpace, m.AddrRange, seg.Start(), m.Writable)
+				}
+				for mr, mappings := range allAdded {
+					for m := range mappings {
+						upperMappable.RemoveMapping(ctx, m.MappingSpace, m.AddrRange, mr.Start, m.Writable)
 					}
-					return err
 				}
-				added[m] = struct{}{}
-			}
-			allAdded[seg.Range()] = added
-		}
-
-		// Switch to the new Mappable. We do this at the end of copy-up
-		// because:
-		//
-		//	- We need to switch Mappables (by changing d.wrappedMappable) before
-		//		invalidating Translations from the old Mappable (to pick up
-		//		Translations from the new one).
-		//
-		//	- We need to lock d.dataMu while changing d.wrappedMappable, but
-		//		must invalidate Translations with d.dataMu unlocked (due to lock
-		//		ordering).
-		//
-		//	- Consequently, once we unlock d.dataMu, other threads may
-		//		immediately observe the new (copied-up) Mappable, which we want to
-		//		delay until copy-up is guaranteed to succeed.
-		d.dataMu.Lock()
-		lowerMappable := d.wrappedMappable
-		d.wrappedMappable = upperMappable
-		d.dataMu.Unlock()
-		d.lowerMappings.InvalidateAll(memmap.InvalidateOpts{})
-
-		// Remove mappings from the old Mappable.
-		for seg := d.lowerMappings.FirstSegment(); seg.Ok(); seg = seg.NextSegment() {
-			for m := range seg.Value() {
-				lowerMappable.RemoveMapping(ctx, m.MappingSpace, m.AddrRange, seg.Start(), m.Writable)
+				return err
 			}
+			added[m] = struct{}{}
 		}
-		d.lowerMappings.RemoveAll()
+		allAdded[seg.Range()] = added
 	}
 
-	d.copiedUp.Store(1)
+	// Switch to the new Mappable. We do this at the end of copy-up
+	// because:
+	//
+	//	- We need to switch Mappables (by changing d.wrappedMappable) before
+	//		invalidating Translations from the old Mappable (to pick up
+	//		Translations from the new one).
+	//
+	//	- We need to lock d.dataMu while changing d.wrappedMappable, but
+	//		must invalidate Translations with d.dataMu unlocked (due to lock
+	//		ordering).
+	//
+	//	- Consequently, once we unlock d.dataMu, other threads may
+	//		immediately observe the new (copied-up) Mappable, which we want to
+	//		delay until copy-up is guaranteed to succeed.
+	d.dataMu.Lock()
+	lowerMappable := d.wrappedMappable
+	d.wrappedMappable = upperMappable
+	d.dataMu.Unlock()
+	d.lowerMappings.InvalidateAll(memmap.InvalidateOpts{})
+
+	// Remove mappings from the old Mappable.
+	for seg := d.lowerMappings.FirstSegment(); seg.Ok(); seg = seg.NextSegment() {
+		for m := range seg.Value() {
+			lowerMappable.RemoveMapping(ctx, m.MappingSpace, m.AddrRange, seg.Start(), m.Writable)
+		}
+	}
+	d.lowerMappings.RemoveAll()
 	return nil
 }
 
@@ -384,7 +582,7 @@ func (d *dentry) copyXattrsLocked(ctx context.Context) error {
 
 	for _, name := range lowerXattrs {
 		// Do not copy up overlay attributes.
-		if isOverlayXattr(name) {
+		if d.fs.isOverlayXattr(name) {
 			continue
 		}
 
@@ -421,7 +619,9 @@ func (d *dentry) copyUpDescendantsLocked(ctx context.Context, ds **[]*dentry) er
 		if err != nil {
 			return err
 		}
-		if err := child.copyUpLocked(ctx); err != nil {
+		// The data of metacopy files can't be found after their parent is
+		// renamed without a redirect, so it must be copied up as well.
+		if err := child.copyUpDataLocked(ctx); err != nil {
 			return err
 		}
 		if child.isDir() {
@@ -16,6 +16,7 @@ package overlay
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -36,10 +37,26 @@ import (
 // Linux: fs/overlayfs/overlayfs.h:OVL_XATTR_PREFIX
 const _OVL_XATTR_PREFIX = linux.XATTR_TRUSTED_PREFIX + "overlay."
 
-// _OVL_XATTR_OPAQUE is an extended attribute key whose value is set to "y" for
-// opaque directories.
-// Linux: fs/overlayfs/overlayfs.h:OVL_XATTR_OPAQUE
-const _OVL_XATTR_OPAQUE = _OVL_XATTR_PREFIX + "opaque"
+// _OVL_XATTR_USER_PREFIX replaces _OVL_XATTR_PREFIX when
+// FilesystemOptions.UserXattr is in effect.
+// Linux: fs/overlayfs/overlayfs.h:OVL_XATTR_USER_PREFIX
+const _OVL_XATTR_USER_PREFIX = linux.XATTR_USER_PREFIX + "overlay."
+
+// _OVL_XATTR_OPAQUE_POSTFIX names an extended attribute whose value is set to
+// "y" for opaque directories.
+// Linux: fs/overlayfs/overlayfs.h:OVL_XATTR_OPAQUE_POSTFIX
+const _OVL_XATTR_OPAQUE_POSTFIX = "opaque"
+
+// _OVL_XATTR_REDIRECT_POSTFIX names an extended attribute whose value is the
+// path at which a renamed file's lower layer files are found; see
+// dentry.redirect.
+// Linux: fs/overlayfs/overlayfs.h:OVL_XATTR_REDIRECT_POSTFIX
+const _OVL_XATTR_REDIRECT_POSTFIX = "redirect"
+
+// _OVL_XATTR_METACOPY_POSTFIX names an extended attribute that is present on
+// regular files that contain only metadata; see dentry.metacopy.
+// Linux: fs/overlayfs/overlayfs.h:OVL_XATTR_METACOPY_POSTFIX
+const _OVL_XATTR_METACOPY_POSTFIX = "metacopy"
 
 func isWhiteout(stat *linux.Statx) bool {
 	return stat.Mode&linux.S_IFMT == linux.S_IFCHR && stat.RdevMajor == 0 && stat.RdevMinor == 0
@@ -208,17 +225,61 @@ func (fs *filesystem) getChildLocked(ctx context.Context, parent *dentry, name s
 //   - fs.renameMu must be locked.
 //   - parent.dirMu must be locked.
 func (fs *filesystem) lookupLocked(ctx context.Context, parent *dentry, name string) (*dentry, lookupLayer, error) {
-	childPath := fspath.Parse(name)
 	child := fs.newDentry()
 	topLookupLayer := lookupLayerNone
 	var lookupErr error
 
+	// lookupPath is the path at which child is looked up on each layer. It is
+	// relative to parent's directory on the layer, unless a layer has
+	// redirected child to an absolute path, in which case remaining lower
+	// layers are searched from their roots, starting from
+	// fs.opts.LowerRoots[nextRoot].
+	lookupPath := name
+	redirectedToRoots := false
+	nextRoot := 0
+	// If wantData is true, child is a metacopy file whose data has not yet
+	// been found.
+	wantData := false
+
 	vfsObj := fs.vfsfs.VirtualFilesystem()
-	parent.iterLayers(func(parentVD vfs.VirtualDentry, isUpper bool) bool {
+	// followRedirect updates lookupPath for the layers below the one
+	// containing childVD, if childVD has a redirect. It returns false if
+	// lookup should stop.
+	followRedirect := func(layerVD, childVD vfs.VirtualDentry, isUpper bool) bool {
+		if fs.opts.RedirectDir == RedirectDirNoFollow {
+			return true
+		}
+		redirect, ok := fs.getOverlayXattr(ctx, childVD, _OVL_XATTR_REDIRECT_POSTFIX, 0)
+		if !ok {
+			return true
+		}
+		if strings.HasPrefix(redirect, "/") {
+			lookupPath = redirect
+			if !isUpper {
+				nextRoot = fs.lowerLayerIndex(layerVD) + 1
+			}
+			redirectedToRoots = true
+		} else if redirect == "" || strings.Contains(redirect, "/") {
+			// Relative redirects must name a file in the same directory. See
+			// fs/overlayfs/namei.c:ovl_check_redirect().
+			ctx.Infof("overlay.filesystem.lookupLocked: invalid redirect %q for %q", redirect, name)
+			lookupErr = linuxerr.EIO
+			return false
+		} else if redirectedToRoots {
+			lookupPath = path.Join(path.Dir(lookupPath), redirect)
+		} else {
+			lookupPath = redirect
+		}
+		if isUpper {
+			child.redirect = redirect
+		}
+		return true
+	}
+	lookupInLayer := func(layerVD vfs.VirtualDentry, isUpper bool) bool {
 		childVD, err := vfsObj.GetDentryAt(ctx, fs.creds, &vfs.PathOperation{
-			Root:  parentVD,
-			Start: parentVD,
-			Path:  childPath,
+			Root:  layerVD,
+			Start: layerVD,
+			Path:  fspath.Parse(lookupPath),
 		}, &vfs.GetDentryOptions{})
 		if linuxerr.Equals(linuxerr.ENOENT, err) || linuxerr.Equals(linuxerr.ENAMETOOLONG, err) {
 			// The file doesn't exist on this layer. Proceed to the next one.
@@ -260,6 +321,26 @@ func (fs *filesystem) lookupLocked(ctx context.Context, parent *dentry, name str
 			return false
 		}
 		isDir := stat.Mode&linux.S_IFMT == linux.S_IFDIR
+		// Metacopy files are only honored if the overlay creates them; in
+		// particular, they are ignored if FilesystemOptions.UserXattr is true,
+		// since user.* attributes may be forged on lower layers.
+		isMetacopy := false
+		if fs.opts.MetaCopy && stat.Mode&linux.S_IFMT == linux.S_IFREG {
+			_, isMetacopy = fs.getOverlayXattr(ctx, childVD, _OVL_XATTR_METACOPY_POSTFIX, 0)
+		}
+		if wantData {
+			// Only a regular file can provide the data of a metacopy file.
+			if stat.Mode&linux.S_IFMT != linux.S_IFREG {
+				return false
+			}
+			childVD.IncRef()
+			child.lowerVDs = append(child.lowerVDs, childVD)
+			if !isMetacopy {
+				wantData = false
+				return false
+			}
+			return followRedirect(layerVD, childVD, isUpper)
+		}
 		if topLookupLayer != lookupLayerNone && !isDir {
 			// Directories are not merged with non-directory files from lower
 			// layers; instead, layers including and below the first
@@ -292,9 +373,15 @@ func (fs *filesystem) lookupLocked(ctx context.Context, parent *dentry, name str
 		}
 
 		// For non-directory files, only the topmost layer that contains a file
-		// matters.
+		// matters, unless that file is a metacopy file, in which case lower
+		// layers must also be searched for its data.
 		if !isDir {
-			return false
+			if !isMetacopy {
+				return false
+			}
+			child.metacopy = atomicbitops.FromUint32(1)
+			wantData = true
+			return followRedirect(layerVD, childVD, isUpper)
 		}
 
 		// Directories use the lowest layer inode and device numbers to generate a
@@ -306,15 +393,36 @@ func (fs *filesystem) lookupLocked(ctx context.Context, parent *dentry, name str
 
 		// Directories are merged with directories from lower layers if they
 		// are not explicitly opaque.
-		opaqueVal, err := vfsObj.GetXattrAt(ctx, fs.creds, &vfs.PathOperation{
-			Root:  childVD,
-			Start: childVD,
-		}, &vfs.GetXattrOptions{
-			Name: _OVL_XATTR_OPAQUE,
-			Size: 1,
-		})
-		return !(err == nil && opaqueVal == "y")
-	})
+		if opaqueVal, ok := fs.getOverlayXattr(ctx, childVD, _OVL_XATTR_OPAQUE_POSTFIX, 1); ok && opaqueVal == "y" {
+			return false
+		}
+		return followRedirect(layerVD, childVD, isUpper)
+	}
+
+	// Look up child on parent's upper layer directory, then on its lower
+	// layer directories, or the roots of lower layers if an absolute redirect
+	// is encountered.
+	more := true
+	if parent.isCopiedUp() {
+		more = lookupInLayer(parent.upperVD, true)
+	}
+	layerVDs := parent.lowerVDs
+	if redirectedToRoots {
+		layerVDs = fs.opts.LowerRoots
+	}
+	for i := 0; more && i < len(layerVDs); i++ {
+		wasRedirectedToRoots := redirectedToRoots
+		more = lookupInLayer(layerVDs[i], false)
+		if redirectedToRoots && !wasRedirectedToRoots {
+			layerVDs = fs.opts.LowerRoots[nextRoot:]
+			i = -1
+		}
+	}
+	if lookupErr == nil && wantData {
+		// Compare fs/overlayfs/namei.c:ovl_lookup() => ovl_maybe_lookup_lowerdata().
+		ctx.Infof("overlay.filesystem.lookupLocked: failed to find data for metacopy file %q", name)
+		lookupErr = linuxerr.EIO
+	}
 
 	if lookupErr != nil {
 		child.destroyLocked(ctx)
@@ -355,6 +463,18 @@ func (fs *filesystem) lookupLocked(ctx context.Context, parent *dentry, name str
 	return child, topLookupLayer, nil
 }
 
+// lowerLayerIndex returns the index in fs.opts.LowerRoots of the lower layer
+// containing vd, or len(fs.opts.LowerRoots) if vd is not on a lower layer.
+func (fs *filesystem) lowerLayerIndex(vd vfs.VirtualDentry) int {
+	// Each layer is a distinct private mount; see clonePrivateMount().
+	for i, rootVD := range fs.opts.LowerRoots {
+		if rootVD.Mount() == vd.Mount() {
+			return i
+		}
+	}
+	return len(fs.opts.LowerRoots)
+}
+
 // lookupLayerLocked is similar to lookupLocked, but only returns information
 // about the file rather than a dentry.
 //
@@ -679,7 +799,8 @@ func (fs *filesystem) LinkAt(ctx context.Context, rp *vfs.ResolvingPath, vd vfs.
 		if old.isDir() {
 			return linuxerr.EPERM
 		}
-		if err := old.copyUpLocked(ctx); err != nil {
+		// Lookup of the new link couldn't find the data of a metacopy file.
+		if err := old.copyUpDataLocked(ctx); err != nil {
 			return err
 		}
 		vfsObj := fs.vfsfs.VirtualFilesystem()
@@ -745,7 +866,7 @@ func (fs *filesystem) MkdirAt(ctx context.Context, rp *vfs.ResolvingPath, opts v
 			// the new directory should not be merged with, so mark as opaque.
 			// See fs/overlayfs/dir.c:ovl_create_over_whiteout() -> ovl_set_opaque().
 			if err := vfsObj.SetXattrAt(ctx, fs.creds, &pop, &vfs.SetXattrOptions{
-				Name:  _OVL_XATTR_OPAQUE,
+				Name:  fs.xattrPrefix + _OVL_XATTR_OPAQUE_POSTFIX,
 				Value: "y",
 			}); err != nil {
 				if cleanupErr := vfsObj.RmdirAt(ctx, fs.creds, &pop); cleanupErr != nil {
@@ -763,7 +884,7 @@ func (fs *filesystem) MkdirAt(ctx context.Context, rp *vfs.ResolvingPath, opts v
 			// fs.lookupLocked(). Allow it to fail since this is an optimization.
 			// See fs/overlayfs/dir.c:ovl_create_upper() -> ovl_set_opaque().
 			_ = vfsObj.SetXattrAt(ctx, fs.creds, &pop, &vfs.SetXattrOptions{
-				Name:  _OVL_XATTR_OPAQUE,
+				Name:  fs.xattrPrefix + _OVL_XATTR_OPAQUE_POSTFIX,
 				Value: "y",
 			})
 		}
@@ -915,7 +1036,7 @@ func (d *dentry) ensureOpenableLocked(ctx context.Context, rp *vfs.ResolvingPath
 		return err
 	}
 	defer rp.Mount().EndWrite()
-	return d.copyUpLocked(ctx)
+	return d.copyUpDataLocked(ctx)
 }
 
 // Preconditions: If vfs.AccessTypesForOpenFlags(opts).MayWrite(), then d has
@@ -937,7 +1058,7 @@ func (d *dentry) openCopiedUp(ctx context.Context, rp *vfs.ResolvingPath, opts *
 		return &fd.vfsfd, nil
 	}
 
-	layerVD, isUpper := d.topLayerInfo()
+	layerVD, isUpper := d.dataLayerInfo()
 	layerFD, err := rp.VirtualFilesystem().OpenAt(ctx, d.fs.creds, &vfs.PathOperation{
 		Root:  layerVD,
 		Start: layerVD,
@@ -1188,12 +1309,37 @@ func (fs *filesystem) RenameAt(ctx context.Context, rp *vfs.ResolvingPath, oldPa
 	if err := renamed.copyUpLocked(ctx); err != nil {
 		return err
 	}
-	// If renamed is a directory, all of its descendants need to be copied-up
-	// before they're renamed on the upper layer.
-	if renamed.isDir() {
+	// If renamed has lower layer files that lookup won't find at its new
+	// name, either record where they are in a redirect, or copy-up everything
+	// that's needed from them: all of a directory's descendants (which is
+	// then made opaque below), or a metacopy file's data.
+	useRedirect := fs.opts.RedirectDir == RedirectDirOn && len(renamed.lowerVDs) != 0 && (renamed.isDir() || renamed.isMetacopy())
+	if useRedirect {
+		// Compare fs/overlayfs/dir.c:ovl_set_redirect(). The redirect also
+		// describes renamed's current location, so it can be set before
+		// renaming without needing to be undone if renaming fails.
+		redirect := renamed.redirect
+		if oldParent != newParent || strings.HasPrefix(redirect, "/") {
+			redirect = renamed.lowerPathLocked()
+		} else if redirect == "" {
+			redirect = oldName
+		}
+		if err := fs.vfsfs.VirtualFilesystem().SetXattrAt(ctx, fs.creds, &vfs.PathOperation{
+			Root:  renamed.upperVD,
+			Start: renamed.upperVD,
+		}, &vfs.SetXattrOptions{
+			Name:  fs.xattrPrefix + _OVL_XATTR_REDIRECT_POSTFIX,
+			Value: redirect,
+		}); err != nil {
+			return err
+		}
+		renamed.redirect = redirect
+	} else if renamed.isDir() {
 		if err := renamed.copyUpDescendantsLocked(ctx, &ds); err != nil {
 			return err
 		}
+	} else if err := renamed.copyUpDataLocked(ctx); err != nil {
+		return err
 	}
 	// newParent must be copied-up before it can contain renamed on the upper
 	// layer.
@@ -1319,9 +1465,9 @@ func (fs *filesystem) RenameAt(ctx context.Context, rp *vfs.ResolvingPath, oldPa
 	if err := CreateWhiteout(ctx, vfsObj, fs.creds, &oldpop); err != nil {
 		panic(fmt.Sprintf("unrecoverable overlayfs inconsistency: failed to create whiteout at origin after RenameAt: %v", err))
 	}
-	if renamed.isDir() {
+	if renamed.isDir() && !useRedirect {
 		if err := vfsObj.SetXattrAt(ctx, fs.creds, &newpop, &vfs.SetXattrOptions{
-			Name:  _OVL_XATTR_OPAQUE,
+			Name:  fs.xattrPrefix + _OVL_XATTR_OPAQUE_POSTFIX,
 			Value: "y",
 		}); err != nil {
 			panic(fmt.Sprintf("unrecoverable overlayfs inconsistency: failed to make renamed directory opaque: %v", err))
@@ -1497,7 +1643,7 @@ func (d *dentry) setStatLocked(ctx context.Context, rp *vfs.ResolvingPath, opts
 		return err
 	}
 	defer mnt.EndWrite()
-	if err := d.copyUpLocked(ctx); err != nil {
+	if err := d.copyUpForSetStatLocked(ctx, &opts); err != nil {
 		return err
 	}
 	// Changes to d's attributes are serialized by d.copyMu.
@@ -1531,12 +1677,8 @@ func (fs *filesystem) StatAt(ctx context.Context, rp *vfs.ResolvingPath, opts vf
 
 	var stat linux.Statx
 	if layerMask := opts.Mask &^ statInternalMask; layerMask != 0 {
-		layerVD := d.topLayer()
 		var err error
-		stat, err = fs.vfsfs.VirtualFilesystem().StatAt(ctx, fs.creds, &vfs.PathOperation{
-			Root:  layerVD,
-			Start: layerVD,
-		}, &vfs.StatOptions{
+		stat, err = d.statLayers(ctx, &vfs.StatOptions{
 			Mask: layerMask,
 			Sync: opts.Sync,
 		})
@@ -1548,6 +1690,37 @@ func (fs *filesystem) StatAt(ctx context.Context, rp *vfs.ResolvingPath, opts vf
 	return stat, nil
 }
 
+// statLayers returns the attributes of d that are not stored in d itself.
+func (d *dentry) statLayers(ctx context.Context, opts *vfs.StatOptions) (linux.Statx, error) {
+	vfsObj := d.fs.vfsfs.VirtualFilesystem()
+	layerVD := d.topLayer()
+	stat, err := vfsObj.StatAt(ctx, d.fs.creds, &vfs.PathOperation{
+		Root:  layerVD,
+		Start: layerVD,
+	}, opts)
+	if err != nil {
+		return linux.Statx{}, err
+	}
+	if d.isMetacopy() && opts.Mask&linux.STATX_BLOCKS != 0 {
+		// The topmost layer of a metacopy file is sparse, so report the
+		// space used by its data instead, as in Linux's
+		// fs/overlayfs/inode.c:ovl_getattr().
+		dataVD := d.lowerDataVD()
+		dataStat, err := vfsObj.StatAt(ctx, d.fs.creds, &vfs.PathOperation{
+			Root:  dataVD,
+			Start: dataVD,
+		}, &vfs.StatOptions{
+			Mask: linux.STATX_BLOCKS,
+			Sync: opts.Sync,
+		})
+		if err != nil {
+			return linux.Statx{}, err
+		}
+		stat.Blocks = dataStat.Blocks
+	}
+	return stat, nil
+}
+
 // StatFSAt implements vfs.FilesystemImpl.StatFSAt.
 func (fs *filesystem) StatFSAt(ctx context.Context, rp *vfs.ResolvingPath) (linux.Statfs, error) {
 	var ds *[]*dentry
@@ -1688,8 +1861,23 @@ func (fs *filesystem) UnlinkAt(ctx context.Context, rp *vfs.ResolvingPath) error
 
 // isOverlayXattr returns whether the given extended attribute configures the
 // overlay.
-func isOverlayXattr(name string) bool {
-	return strings.HasPrefix(name, _OVL_XATTR_PREFIX)
+func (fs *filesystem) isOverlayXattr(name string) bool {
+	return strings.HasPrefix(name, fs.xattrPrefix)
+}
+
+// getOverlayXattr returns the value of the overlay's extended attribute with
+// the given postfix on vd, and whether the attribute exists. Errors other than
+// the attribute's absence (e.g. layers that don't support extended attributes)
+// are treated as absence.
+func (fs *filesystem) getOverlayXattr(ctx context.Context, vd vfs.VirtualDentry, postfix string, size uint64) (string, bool) {
+	value, err := fs.vfsfs.VirtualFilesystem().GetXattrAt(ctx, fs.creds, &vfs.PathOperation{
+		Root:  vd,
+		Start: vd,
+	}, &vfs.GetXattrOptions{
+		Name: fs.xattrPrefix + postfix,
+		Size: size,
+	})
+	return value, err == nil
 }
 
 // ListXattrAt implements vfs.FilesystemImpl.ListXattrAt.
@@ -1716,7 +1904,7 @@ func (fs *filesystem) listXattr(ctx context.Context, d *dentry, size uint64) ([]
 	// Filter out all overlay attributes.
 	n := 0
 	for _, name := range names {
-		if !isOverlayXattr(name) {
+		if !fs.isOverlayXattr(name) {
 			names[n] = name
 			n++
 		}
@@ -1744,7 +1932,7 @@ func (fs *filesystem) getXattr(ctx context.Context, d *dentry, creds *auth.Crede
 
 	// Return EOPNOTSUPP when fetching an overlay attribute.
 	// See fs/overlayfs/super.c:ovl_own_xattr_get().
-	if isOverlayXattr(opts.Name) {
+	if fs.isOverlayXattr(opts.Name) {
 		return "", linuxerr.EOPNOTSUPP
 	}
 
@@ -1782,7 +1970,7 @@ func (fs *filesystem) setXattrLocked(ctx context.Context, d *dentry, mnt *vfs.Mo
 
 	// Return EOPNOTSUPP when setting an overlay attribute.
 	// See fs/overlayfs/super.c:ovl_own_xattr_set().
-	if isOverlayXattr(opts.Name) {
+	if fs.isOverlayXattr(opts.Name) {
 		return linuxerr.EOPNOTSUPP
 	}
 
@@ -1827,7 +2015,7 @@ func (fs *filesystem) removeXattrLocked(ctx context.Context, d *dentry, mnt *vfs
 	// Like SetXattrAt, return EOPNOTSUPP when removing an overlay attribute.
 	// Linux passes the remove request to xattr_handler->set.
 	// See fs/xattr.c:vfs_removexattr().
-	if isOverlayXattr(name) {
+	if fs.isOverlayXattr(name) {
 		return linuxerr.EOPNOTSUPP
 	}
 
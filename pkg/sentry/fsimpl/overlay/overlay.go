@@ -36,6 +36,7 @@ package overlay
 
 import (
 	"fmt"
+	"path"
 	"strings"
 	"sync/atomic"
 
@@ -84,6 +85,70 @@ type FilesystemOptions struct {
 	// LowerRoots contains the roots of the immutable lower layers of the
 	// overlay. LowerRoots is immutable.
 	LowerRoots []vfs.VirtualDentry
+
+	// If MetaCopy is true, changing the metadata of a regular file on a lower
+	// layer copies up only the file's metadata; its data continues to be read
+	// from the lower layer until the file is opened for writing or truncated.
+	// This is equivalent to Linux's metacopy=on.
+	MetaCopy bool
+
+	// RedirectDir configures the use of redirected directories, which allow
+	// directories from lower layers to be renamed without copying up their
+	// contents.
+	RedirectDir RedirectDirMode
+
+	// If UserXattr is true, the overlay's own extended attributes (e.g. those
+	// marking opaque directories) are stored on layers in the "user.overlay."
+	// namespace rather than "trusted.overlay.". This is equivalent to Linux's
+	// userxattr mount option. If UserXattr is true, MetaCopy must be false
+	// and RedirectDir must be RedirectDirNoFollow.
+	UserXattr bool
+}
+
+// RedirectDirMode configures an overlay's use of redirected directories.
+type RedirectDirMode uint8
+
+const (
+	// RedirectDirFollow causes redirects found on layers to be followed, but
+	// doesn't create new ones; renaming a directory that exists on a lower
+	// layer copies up the directory's contents instead. This is the default,
+	// consistent with Linux's redirect_dir=off when
+	// CONFIG_OVERLAY_FS_REDIRECT_ALWAYS_FOLLOW is enabled.
+	RedirectDirFollow RedirectDirMode = iota
+
+	// RedirectDirOn causes redirects to be both followed and created.
+	RedirectDirOn
+
+	// RedirectDirNoFollow causes redirects to be neither followed nor
+	// created.
+	RedirectDirNoFollow
+)
+
+// upperXattrsSupported returns false if the upper layer rooted at upperRoot
+// doesn't support the "trusted.overlay." extended attributes that the overlay
+// stores on it.
+func upperXattrsSupported(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, upperRoot vfs.VirtualDentry) bool {
+	_, err := vfsObj.GetXattrAt(ctx, creds, &vfs.PathOperation{
+		Root:  upperRoot,
+		Start: upperRoot,
+	}, &vfs.GetXattrOptions{
+		Name: _OVL_XATTR_PREFIX + _OVL_XATTR_METACOPY_POSTFIX,
+	})
+	return !linuxerr.Equals(linuxerr.EOPNOTSUPP, err)
+}
+
+// parseRedirectDirMode parses the value of the redirect_dir mount option.
+func parseRedirectDirMode(s string) (RedirectDirMode, bool) {
+	switch s {
+	case "on":
+		return RedirectDirOn, true
+	case "follow", "off":
+		return RedirectDirFollow, true
+	case "nofollow":
+		return RedirectDirNoFollow, true
+	default:
+		return 0, false
+	}
 }
 
 // filesystem implements vfs.FilesystemImpl.
@@ -138,6 +203,11 @@ type filesystem struct {
 
 	// MaxFilenameLen is the maximum filename length allowed by the overlayfs.
 	maxFilenameLen uint64
+
+	// xattrPrefix is the prefix of the names of extended attributes that
+	// configure the overlay on its layers; see FilesystemOptions.UserXattr.
+	// xattrPrefix is immutable.
+	xattrPrefix string
 }
 
 // +stateify savable
@@ -274,6 +344,74 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		}
 	}
 
+	haveRedirectDir := false
+	if redirectDir, ok := mopts["redirect_dir"]; ok {
+		delete(mopts, "redirect_dir")
+		mode, ok := parseRedirectDirMode(redirectDir)
+		if !ok {
+			ctx.Infof("overlay.FilesystemType.GetFilesystem: invalid redirect_dir: %q", redirectDir)
+			return nil, nil, linuxerr.EINVAL
+		}
+		fsopts.RedirectDir = mode
+		haveRedirectDir = true
+	}
+	if metacopy, ok := mopts["metacopy"]; ok {
+		delete(mopts, "metacopy")
+		switch metacopy {
+		case "on":
+			fsopts.MetaCopy = true
+		case "off":
+			fsopts.MetaCopy = false
+		default:
+			ctx.Infof("overlay.FilesystemType.GetFilesystem: invalid metacopy: %q", metacopy)
+			return nil, nil, linuxerr.EINVAL
+		}
+	}
+	if userxattr, ok := mopts["userxattr"]; ok {
+		delete(mopts, "userxattr")
+		if userxattr != "" {
+			ctx.Infof("overlay.FilesystemType.GetFilesystem: userxattr doesn't take a value")
+			return nil, nil, linuxerr.EINVAL
+		}
+		fsopts.UserXattr = true
+	}
+	if fsopts.UserXattr {
+		// Unprivileged users can forge user.* attributes on lower layers, so
+		// redirects and metacopy files, which point the overlay at other
+		// files, are neither followed nor created, as in Linux's
+		// fs/overlayfs/params.c:ovl_fs_params_verify().
+		if fsopts.MetaCopy {
+			ctx.Infof("overlay.FilesystemType.GetFilesystem: userxattr conflicts with metacopy=on")
+			return nil, nil, linuxerr.EINVAL
+		}
+		if haveRedirectDir && fsopts.RedirectDir != RedirectDirNoFollow {
+			ctx.Infof("overlay.FilesystemType.GetFilesystem: userxattr requires redirect_dir=nofollow")
+			return nil, nil, linuxerr.EINVAL
+		}
+		fsopts.RedirectDir = RedirectDirNoFollow
+	}
+	if fsopts.MetaCopy {
+		// Renaming a metacopy file requires redirects to locate its data, so
+		// metacopy=on implies redirect_dir=on as in Linux's
+		// fs/overlayfs/params.c:ovl_fs_params_verify().
+		if !haveRedirectDir {
+			fsopts.RedirectDir = RedirectDirOn
+		} else if fsopts.RedirectDir != RedirectDirOn {
+			ctx.Infof("overlay.FilesystemType.GetFilesystem: metacopy=on requires redirect_dir=on")
+			return nil, nil, linuxerr.EINVAL
+		}
+	}
+	if fsopts.MetaCopy && fsopts.UpperRoot.Ok() && !upperXattrsSupported(ctx, vfsObj, creds, fsopts.UpperRoot) {
+		// Metacopy files are marked with an extended attribute, so fall back
+		// to copying up data if the upper layer can't store it, as in Linux's
+		// fs/overlayfs/super.c:ovl_make_workdir().
+		ctx.Infof("overlay.FilesystemType.GetFilesystem: upper layer doesn't support %s* extended attributes, falling back to metacopy=off", _OVL_XATTR_PREFIX)
+		fsopts.MetaCopy = false
+		if !haveRedirectDir {
+			fsopts.RedirectDir = RedirectDirFollow
+		}
+	}
+
 	if len(mopts) != 0 {
 		ctx.Infof("overlay.FilesystemType.GetFilesystem: unused options: %v", mopts)
 		return nil, nil, linuxerr.EINVAL
@@ -314,6 +452,10 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		lowerDevMinors: make(map[layerDevNumber]uint32),
 		dirInoCache:    make(map[layerDevNoAndIno]uint64),
 		maxFilenameLen: linux.NAME_MAX,
+		xattrPrefix:    _OVL_XATTR_PREFIX,
+	}
+	if fsopts.UserXattr {
+		fs.xattrPrefix = _OVL_XATTR_USER_PREFIX
 	}
 	fs.vfsfs.Init(vfsObj, &fstype, fs)
 
@@ -527,6 +669,19 @@ type dentry struct {
 	// 0 otherwise.
 	copiedUp atomicbitops.Uint32
 
+	// metacopy is 1 if this dentry represents a regular file whose topmost
+	// layer holds only the file's metadata, such that its data must be read
+	// from the bottommost of lowerVDs, and 0 otherwise. metacopy can only
+	// transition from 1 to 0, with copyMu locked for writing, when the file's
+	// data is copied up.
+	metacopy atomicbitops.Uint32
+
+	// If redirect is not empty, it is the path at which this dentry's lower
+	// layer files were found, either relative to the parent directory's
+	// lower layer directories or (if absolute) to the roots of the lower
+	// layers. redirect is protected by fs.renameMu.
+	redirect string
+
 	// parent is the dentry corresponding to this dentry's parent directory.
 	// name is this dentry's name in parent. If this dentry is a filesystem
 	// root, parent is nil and name is the empty string. parent and name are
@@ -819,6 +974,41 @@ func (d *dentry) topLayer() vfs.VirtualDentry {
 	return vd
 }
 
+// dataLayerInfo is like topLayerInfo, but returns the layer containing the
+// file's data, which differs from the topmost layer for metacopy files.
+func (d *dentry) dataLayerInfo() (vd vfs.VirtualDentry, isUpper bool) {
+	if d.isMetacopy() {
+		return d.lowerDataVD(), false
+	}
+	return d.topLayerInfo()
+}
+
+// lowerDataVD returns the bottommost lower layer of d, which contains the
+// data of metacopy files.
+func (d *dentry) lowerDataVD() vfs.VirtualDentry {
+	return d.lowerVDs[len(d.lowerVDs)-1]
+}
+
+// lowerPathLocked returns the path, relative to the roots of the lower
+// layers, at which d's lower layer files are found. Compare Linux's
+// fs/overlayfs/dir.c:ovl_get_redirect().
+//
+// Preconditions: d.fs.renameMu must be locked.
+func (d *dentry) lowerPathLocked() string {
+	if strings.HasPrefix(d.redirect, "/") {
+		return d.redirect
+	}
+	parent := d.parent.Load()
+	if parent == nil {
+		return "/"
+	}
+	name := d.name
+	if d.redirect != "" {
+		name = d.redirect
+	}
+	return path.Join(parent.lowerPathLocked(), name)
+}
+
 func (d *dentry) topLookupLayer() lookupLayer {
 	if d.upperVD.Ok() {
 		return lookupLayerUpper
@@ -48,11 +48,11 @@ func (d *dentry) readlink(ctx context.Context) (string, error) {
 type regularFileFD struct {
 	fileDescription
 
-	// If copiedUp is false, cachedFD represents
-	// fileDescription.dentry().lowerVDs[0]; otherwise, cachedFD represents
-	// fileDescription.dentry().upperVD. cachedFlags is the last known value of
-	// cachedFD.StatusFlags(). copiedUp, cachedFD, and cachedFlags are
-	// protected by mu.
+	// If copiedUp is false, cachedFD represents the lower layer file
+	// containing fileDescription.dentry()'s data; otherwise, cachedFD
+	// represents fileDescription.dentry().upperVD. cachedFlags is the last
+	// known value of cachedFD.StatusFlags(). copiedUp, cachedFD, and
+	// cachedFlags are protected by mu.
 	mu          regularFileFDMutex `state:"nosave"`
 	copiedUp    bool
 	cachedFD    *vfs.FileDescription
@@ -73,7 +73,7 @@ func (fd *regularFileFD) getCurrentFD(ctx context.Context) (*vfs.FileDescription
 func (fd *regularFileFD) currentFDLocked(ctx context.Context) (*vfs.FileDescription, error) {
 	d := fd.dentry()
 	statusFlags := fd.vfsfd.StatusFlags()
-	if !fd.copiedUp && d.isCopiedUp() {
+	if !fd.copiedUp && d.isDataCopiedUp() {
 		// Switch to the copied-up file.
 		upperVD := d.topLayer()
 		upperFD, err := fd.filesystem().vfsfs.VirtualFilesystem().OpenAt(ctx, d.fs.creds, &vfs.PathOperation{
@@ -135,7 +135,19 @@ func (fd *regularFileFD) OnClose(ctx context.Context) error {
 // Stat implements vfs.FileDescriptionImpl.Stat.
 func (fd *regularFileFD) Stat(ctx context.Context, opts vfs.StatOptions) (linux.Statx, error) {
 	var stat linux.Statx
-	if layerMask := opts.Mask &^ statInternalMask; layerMask != 0 {
+	d := fd.dentry()
+	if layerMask := opts.Mask &^ statInternalMask; layerMask != 0 && d.isMetacopy() {
+		// fd.cachedFD represents the lower layer file containing d's data,
+		// but d's other attributes come from its topmost layer.
+		var err error
+		stat, err = d.statLayers(ctx, &vfs.StatOptions{
+			Mask: layerMask,
+			Sync: opts.Sync,
+		})
+		if err != nil {
+			return linux.Statx{}, err
+		}
+	} else if layerMask != 0 {
 		wrappedFD, err := fd.getCurrentFD(ctx)
 		if err != nil {
 			return linux.Statx{}, err
@@ -149,7 +161,7 @@ func (fd *regularFileFD) Stat(ctx context.Context, opts vfs.StatOptions) (linux.
 			return linux.Statx{}, err
 		}
 	}
-	fd.dentry().statInternalTo(ctx, &opts, &stat)
+	d.statInternalTo(ctx, &opts, &stat)
 	return stat, nil
 }
 
@@ -175,12 +187,36 @@ func (fd *regularFileFD) SetStat(ctx context.Context, opts vfs.SetStatOptions) e
 		return err
 	}
 	defer mnt.EndWrite()
-	if err := d.copyUpLocked(ctx); err != nil {
+	if err := d.copyUpForSetStatLocked(ctx, &opts); err != nil {
 		return err
 	}
 	// Changes to d's attributes are serialized by d.copyMu.
 	d.copyMu.Lock()
 	defer d.copyMu.Unlock()
+	if d.isMetacopy() {
+		// fd.cachedFD represents the lower layer file containing d's data, so
+		// change the attributes of the upper layer file instead.
+		vfsObj := d.fs.vfsfs.VirtualFilesystem()
+		upperpop := vfs.PathOperation{
+			Root:  d.upperVD,
+			Start: d.upperVD,
+		}
+		if err := vfsObj.SetStatAt(ctx, d.fs.creds, &upperpop, &opts); err != nil {
+			return err
+		}
+		if opts.Stat.Mask&(linux.STATX_UID|linux.STATX_GID) != 0 {
+			stat, err := vfsObj.StatAt(ctx, d.fs.creds, &upperpop, &vfs.StatOptions{
+				Mask: linux.STATX_MODE,
+			})
+			if err != nil {
+				return err
+			}
+			opts.Stat.Mode = stat.Mode
+			opts.Stat.Mask |= linux.STATX_MODE
+		}
+		d.updateAfterSetStatLocked(&opts)
+		return nil
+	}
 	wrappedFD, err := fd.currentFDLocked(ctx)
 	if err != nil {
 		return err
@@ -265,6 +301,27 @@ func (fd *regularFileFD) Epollable() bool {
 	return wrappedFD.Epollable()
 }
 
+// IODevice implements vfs.IOAccountedFileDescriptionImpl.IODevice. Only I/O on
+// the upper layer is accounted to the overlay; I/O on lower layers, and on
+// upper layers whose own files are accounted, is accounted by the layer's
+// filesystem when it's performed on the wrapped file.
+func (fd *regularFileFD) IODevice() (vfs.IODevice, bool) {
+	d := fd.dentry()
+	if !d.isDataCopiedUp() {
+		return vfs.IODevice{}, false
+	}
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if fd.copiedUp {
+		if impl, ok := fd.cachedFD.Impl().(vfs.IOAccountedFileDescriptionImpl); ok {
+			if _, ok := impl.IODevice(); ok {
+				return vfs.IODevice{}, false
+			}
+		}
+	}
+	return vfs.IODevice{Major: d.devMajor.Load(), Minor: d.devMinor.Load()}, true
+}
+
 // PRead implements vfs.FileDescriptionImpl.PRead.
 func (fd *regularFileFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
 	wrappedFD, err := fd.getCurrentFD(ctx)
@@ -349,7 +406,7 @@ func (fd *regularFileFD) Seek(ctx context.Context, offset int64, whence int32) (
 // Sync implements vfs.FileDescriptionImpl.Sync.
 func (fd *regularFileFD) Sync(ctx context.Context) error {
 	fd.mu.Lock()
-	if !fd.dentry().isCopiedUp() {
+	if !fd.dentry().isDataCopiedUp() {
 		fd.mu.Unlock()
 		return nil
 	}
@@ -436,7 +493,7 @@ func (d *dentry) AddMapping(ctx context.Context, ms memmap.MappingSpace, ar host
 	if err := d.wrappedMappable.AddMapping(ctx, ms, ar, offset, writable); err != nil {
 		return err
 	}
-	if !d.isCopiedUp() {
+	if !d.isDataCopiedUp() {
 		d.lowerMappings.AddMapping(ms, ar, offset, writable)
 	}
 	return nil
@@ -447,7 +504,7 @@ func (d *dentry) RemoveMapping(ctx context.Context, ms memmap.MappingSpace, ar h
 	d.mapsMu.Lock()
 	defer d.mapsMu.Unlock()
 	d.wrappedMappable.RemoveMapping(ctx, ms, ar, offset, writable)
-	if !d.isCopiedUp() {
+	if !d.isDataCopiedUp() {
 		d.lowerMappings.RemoveMapping(ms, ar, offset, writable)
 	}
 }
@@ -459,7 +516,7 @@ func (d *dentry) CopyMapping(ctx context.Context, ms memmap.MappingSpace, srcAR,
 	if err := d.wrappedMappable.CopyMapping(ctx, ms, srcAR, dstAR, offset, writable); err != nil {
 		return err
 	}
-	if !d.isCopiedUp() {
+	if !d.isDataCopiedUp() {
 		d.lowerMappings.AddMapping(ms, dstAR, offset, writable)
 	}
 	return nil
@@ -0,0 +1,310 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proc
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// pageEntryBatchSize is the maximum number of entries read from a
+// pageEntrySource at a time.
+const pageEntryBatchSize = 512
+
+// pageEntrySource provides the contents of a file consisting of 64-bit
+// entries indexed by page, such as /proc/[pid]/pagemap and /proc/kpageflags.
+type pageEntrySource interface {
+	// ReadEntries sets entries[i] to the entry at index+i, and returns the
+	// number of entries set, which is less than len(entries) at end of file.
+	ReadEntries(ctx context.Context, index uint64, entries []uint64) int
+}
+
+// pagemapSource implements pageEntrySource for /proc/[pid]/pagemap.
+//
+// +stateify savable
+type pagemapSource struct {
+	task *kernel.Task
+
+	// showPFN is true if the file was opened with CAP_SYS_ADMIN in the root
+	// user namespace; otherwise, page frame numbers are scrubbed from entries,
+	// as in Linux's fs/proc/task_mmu.c:pagemap_open(). showPFN is immutable.
+	showPFN bool
+}
+
+// ReadEntries implements pageEntrySource.ReadEntries.
+func (s *pagemapSource) ReadEntries(ctx context.Context, index uint64, entries []uint64) int {
+	m, err := getMMIncRef(s.task)
+	if err != nil {
+		// The task has exited, so there are no pages to describe.
+		return 0
+	}
+	defer m.DecUsers(ctx)
+	// Offsets past the end of the address space are at end of file, as in
+	// Linux's fs/proc/task_mmu.c:pagemap_read(). This also ensures that the
+	// address computed below doesn't overflow.
+	if index >= m.PagemapEntries() {
+		return 0
+	}
+	return m.ReadPagemap(hostarch.Addr(index*hostarch.PageSize), entries, s.showPFN)
+}
+
+// kpageflagsSource implements pageEntrySource for /proc/kpageflags.
+//
+// +stateify savable
+type kpageflagsSource struct{}
+
+// ReadEntries implements pageEntrySource.ReadEntries.
+func (kpageflagsSource) ReadEntries(ctx context.Context, index uint64, entries []uint64) int {
+	if index > math.MaxUint64/hostarch.PageSize {
+		return 0
+	}
+	return kernel.KernelFromContext(ctx).MemoryFile().ReadPageFlags(index*hostarch.PageSize, entries)
+}
+
+var _ kernfs.Inode = (*pageEntryInode)(nil)
+
+// pageEntryInode implements kernfs.Inode for /proc/[pid]/pagemap and
+// /proc/kpageflags.
+//
+// +stateify savable
+type pageEntryInode struct {
+	kernfs.InodeAttrs
+	kernfs.InodeNoStatFS
+	kernfs.InodeNoopRefCount
+	kernfs.InodeNotAnonymous
+	kernfs.InodeNotDirectory
+	kernfs.InodeNotSymlink
+	kernfs.InodeWatches
+	kernfs.InodeFSOwned
+
+	// If task is not nil, this inode represents task's /proc/[pid]/pagemap.
+	// Otherwise, it represents /proc/kpageflags.
+	task  *kernel.Task
+	locks vfs.FileLocks
+}
+
+func (fs *filesystem) newPagemapInode(ctx context.Context, task *kernel.Task, ino uint64, perm linux.FileMode) kernfs.Inode {
+	// Note: credentials are overridden by taskOwnedInode.
+	inode := &pageEntryInode{task: task}
+	inode.InodeAttrs.Init(ctx, task.Credentials(), linux.UNNAMED_MAJOR, fs.devMinor, ino, linux.ModeRegular|perm)
+	return &taskOwnedInode{Inode: inode, owner: task}
+}
+
+func (fs *filesystem) newKpageflagsInode(ctx context.Context, creds *auth.Credentials, ino uint64, perm linux.FileMode) kernfs.Inode {
+	inode := &pageEntryInode{}
+	inode.InodeAttrs.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, ino, linux.ModeRegular|perm)
+	return inode
+}
+
+// Open implements kernfs.Inode.Open.
+func (i *pageEntryInode) Open(ctx context.Context, rp *vfs.ResolvingPath, d *kernfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	var src pageEntrySource
+	if i.task != nil {
+		// Permission to read pagemap is governed by PTRACE_MODE_READ_FSCREDS.
+		// Since we dont implement setfsuid/setfsgid we can just use
+		// PTRACE_MODE_READ.
+		if !kernel.ContextCanTrace(ctx, i.task, false) {
+			return nil, linuxerr.EACCES
+		}
+		if err := checkTaskState(i.task); err != nil {
+			return nil, err
+		}
+		creds := auth.CredentialsFromContext(ctx)
+		src = &pagemapSource{
+			task:    i.task,
+			showPFN: creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, creds.UserNamespace.Root()),
+		}
+	} else {
+		// /proc/kpageflags describes every page in the sandbox, including
+		// pages used by other containers, so it's restricted to the
+		// sandbox's root user namespace.
+		creds := auth.CredentialsFromContext(ctx)
+		if !creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, creds.UserNamespace.Root()) {
+			return nil, linuxerr.EPERM
+		}
+		src = kpageflagsSource{}
+	}
+	fd := &pageEntryFD{src: src}
+	fd.LockFD.Init(&i.locks)
+	if err := fd.vfsfd.Init(fd, opts.Flags, rp.Mount(), d.VFSDentry(), &vfs.FileDescriptionOptions{}); err != nil {
+		return nil, err
+	}
+	fd.inode = i
+	return &fd.vfsfd, nil
+}
+
+// SetStat implements kernfs.Inode.SetStat.
+func (*pageEntryInode) SetStat(context.Context, *vfs.Filesystem, *auth.Credentials, vfs.SetStatOptions) error {
+	return linuxerr.EPERM
+}
+
+var _ vfs.FileDescriptionImpl = (*pageEntryFD)(nil)
+
+// pageEntryFD implements vfs.FileDescriptionImpl for pageEntryInode.
+//
+// +stateify savable
+type pageEntryFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.LockFD
+
+	inode *pageEntryInode
+	src   pageEntrySource
+
+	// mu guards the fields below.
+	mu     sync.Mutex `state:"nosave"`
+	offset int64
+}
+
+// Seek implements vfs.FileDescriptionImpl.Seek.
+func (fd *pageEntryFD) Seek(ctx context.Context, offset int64, whence int32) (int64, error) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	switch whence {
+	case linux.SEEK_SET:
+	case linux.SEEK_CUR:
+		offset += fd.offset
+	default:
+		return 0, linuxerr.EINVAL
+	}
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	fd.offset = offset
+	return offset, nil
+}
+
+// PRead implements vfs.FileDescriptionImpl.PRead.
+func (fd *pageEntryFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
+	// Reads must be of whole entries.
+	if offset%linux.PM_ENTRY_BYTES != 0 || dst.NumBytes()%linux.PM_ENTRY_BYTES != 0 {
+		return 0, linuxerr.EINVAL
+	}
+	var (
+		entries [pageEntryBatchSize]uint64
+		buf     [pageEntryBatchSize * linux.PM_ENTRY_BYTES]byte
+	)
+	index := uint64(offset) / linux.PM_ENTRY_BYTES
+	var total int64
+	for dst.NumBytes() != 0 {
+		want := entries[:]
+		if rem := dst.NumBytes() / linux.PM_ENTRY_BYTES; rem < int64(len(want)) {
+			want = want[:rem]
+		}
+		n := fd.src.ReadEntries(ctx, index, want)
+		for i, e := range want[:n] {
+			hostarch.ByteOrder.PutUint64(buf[i*linux.PM_ENTRY_BYTES:], e)
+		}
+		copied, err := dst.CopyOut(ctx, buf[:n*linux.PM_ENTRY_BYTES])
+		total += int64(copied)
+		if err != nil {
+			return total, err
+		}
+		if n < len(want) {
+			break
+		}
+		dst = dst.DropFirst(copied)
+		index += uint64(n)
+	}
+	return total, nil
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (fd *pageEntryFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	fd.mu.Lock()
+	n, err := fd.PRead(ctx, dst, fd.offset, opts)
+	fd.offset += n
+	fd.mu.Unlock()
+	return n, err
+}
+
+// Stat implements vfs.FileDescriptionImpl.Stat.
+func (fd *pageEntryFD) Stat(ctx context.Context, opts vfs.StatOptions) (linux.Statx, error) {
+	fs := fd.vfsfd.VirtualDentry().Mount().Filesystem()
+	return fd.inode.Stat(ctx, fs, opts)
+}
+
+// SetStat implements vfs.FileDescriptionImpl.SetStat.
+func (fd *pageEntryFD) SetStat(context.Context, vfs.SetStatOptions) error {
+	return linuxerr.EPERM
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *pageEntryFD) Release(context.Context) {}
+
+// clearRefsData implements vfs.WritableDynamicBytesSource for
+// /proc/[pid]/clear_refs.
+//
+// +stateify savable
+type clearRefsData struct {
+	kernfs.DynamicBytesFile
+
+	task *kernel.Task
+}
+
+var _ vfs.WritableDynamicBytesSource = (*clearRefsData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *clearRefsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	// Linux's clear_refs is write-only.
+	return linuxerr.EINVAL
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *clearRefsData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	if src.NumBytes() == 0 {
+		return 0, nil
+	}
+
+	// Limit input size so as not to impact performance if input size is large.
+	src = src.TakeFirst(hostarch.PageSize - 1)
+
+	str, err := usermem.CopyStringIn(ctx, src.IO, src.Addrs.Head().Start, int(src.Addrs.Head().Length()), src.Opts)
+	if err != nil && err != linuxerr.ENAMETOOLONG {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(str), 10, 32)
+	if err != nil || v < linux.CLEAR_REFS_ALL || v > linux.CLEAR_REFS_MM_HIWATER_RSS {
+		return 0, linuxerr.EINVAL
+	}
+
+	m, err := getMMIncRef(d.task)
+	if err != nil {
+		return 0, linuxerr.ESRCH
+	}
+	defer m.DecUsers(ctx)
+	switch v {
+	case linux.CLEAR_REFS_SOFT_DIRTY:
+		m.ClearSoftDirty()
+	case linux.CLEAR_REFS_MM_HIWATER_RSS:
+		m.ResetMaxResidentSetSize()
+	default:
+		// Referenced bits aren't tracked, so there is nothing to clear.
+	}
+	return src.NumBytes(), nil
+}
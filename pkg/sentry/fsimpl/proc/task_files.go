@@ -701,16 +701,16 @@ func (s *taskStatData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	fmt.Fprintf(buf, "%d ", s.pidns.IDOfSession(s.task.ThreadGroup().Session()))
 	fmt.Fprintf(buf, "0 0 " /* tty_nr tpgid */)
 	fmt.Fprintf(buf, "0 " /* flags */)
-	fmt.Fprintf(buf, "0 0 0 0 " /* minflt cminflt majflt cmajflt */)
 	var cputime usage.CPUStats
 	if s.tgstats {
 		cputime = s.task.ThreadGroup().CPUStats()
 	} else {
 		cputime = s.task.CPUStats()
 	}
+	childCPUTime := s.task.ThreadGroup().JoinedChildCPUStats()
+	fmt.Fprintf(buf, "%d %d %d %d ", cputime.MinorFaults, childCPUTime.MinorFaults, cputime.MajorFaults, childCPUTime.MajorFaults)
 	fmt.Fprintf(buf, "%d %d ", linux.ClockTFromDuration(cputime.UserTime), linux.ClockTFromDuration(cputime.SysTime))
-	cputime = s.task.ThreadGroup().JoinedChildCPUStats()
-	fmt.Fprintf(buf, "%d %d ", linux.ClockTFromDuration(cputime.UserTime), linux.ClockTFromDuration(cputime.SysTime))
+	fmt.Fprintf(buf, "%d %d ", linux.ClockTFromDuration(childCPUTime.UserTime), linux.ClockTFromDuration(childCPUTime.SysTime))
 	fmt.Fprintf(buf, "%d %d ", s.task.Priority(), s.task.Niceness())
 	fmt.Fprintf(buf, "%d ", s.task.ThreadGroup().Count())
 
@@ -886,7 +886,7 @@ func (s *statusFD) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	egid := creds.EffectiveKGID.In(s.userns).OrOverflow()
 	sgid := creds.SavedKGID.In(s.userns).OrOverflow()
 	var fds int
-	var vss, rss, data uint64
+	var vss, rss, hwm, data uint64
 	s.task.WithMuLocked(func(t *kernel.Task) {
 		if fdTable := t.FDTable(); fdTable != nil {
 			fds = fdTable.CurrentMaxFDs()
@@ -895,6 +895,7 @@ func (s *statusFD) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	if mm := getMM(s.task); mm != nil {
 		vss = mm.VirtualMemorySize()
 		rss = mm.ResidentSetSize()
+		hwm = mm.MaxResidentSetSize()
 		data = mm.VirtualDataSize()
 	}
 	// Filesystem user/group IDs aren't implemented; effective UID/GID are used
@@ -913,6 +914,7 @@ func (s *statusFD) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	buf.WriteString(" \n")
 
 	fmt.Fprintf(buf, "VmSize:\t%d kB\n", vss>>10)
+	fmt.Fprintf(buf, "VmHWM:\t%d kB\n", hwm>>10)
 	fmt.Fprintf(buf, "VmRSS:\t%d kB\n", rss>>10)
 	fmt.Fprintf(buf, "VmData:\t%d kB\n", data>>10)
 
@@ -949,7 +951,7 @@ func (i *ioData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	io := usage.IO{}
 	io.Accumulate(i.IOUsage())
 
-	fmt.Fprintf(buf, "char: %d\n", io.CharsRead.RacyLoad())
+	fmt.Fprintf(buf, "rchar: %d\n", io.CharsRead.RacyLoad())
 	fmt.Fprintf(buf, "wchar: %d\n", io.CharsWritten.RacyLoad())
 	fmt.Fprintf(buf, "syscr: %d\n", io.ReadSyscalls.RacyLoad())
 	fmt.Fprintf(buf, "syscw: %d\n", io.WriteSyscalls.RacyLoad())
@@ -959,7 +961,7 @@ func (i *ioData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	return nil
 }
 
-// oomScoreAdj is a stub of the /proc/<pid>/oom_score_adj file.
+// oomScoreAdj implements the /proc/<pid>/oom_score_adj file.
 //
 // +stateify savable
 type oomScoreAdj struct {
@@ -1009,6 +1011,70 @@ func (o *oomScoreAdj) Write(ctx context.Context, _ *vfs.FileDescription, src use
 	return src.NumBytes(), nil
 }
 
+// coredumpFilterData implements vfs.WritableDynamicBytesSource for
+// /proc/[pid]/coredump_filter.
+//
+// +stateify savable
+type coredumpFilterData struct {
+	kernfs.DynamicBytesFile
+
+	task *kernel.Task
+}
+
+var _ vfs.WritableDynamicBytesSource = (*coredumpFilterData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *coredumpFilterData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	if d.task.ExitState() == kernel.TaskExitDead {
+		return linuxerr.ESRCH
+	}
+	m, err := getMMIncRef(d.task)
+	if err != nil {
+		// Return empty file.
+		return nil
+	}
+	defer m.DecUsers(ctx)
+	fmt.Fprintf(buf, "%08x\n", m.CoreDumpFilter())
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *coredumpFilterData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	if offset != 0 {
+		// No need to handle partial writes thus far.
+		return 0, linuxerr.EINVAL
+	}
+	if src.NumBytes() == 0 {
+		return 0, nil
+	}
+
+	// Limit input size so as not to impact performance if input size is large.
+	src = src.TakeFirst(hostarch.PageSize - 1)
+
+	str, err := usermem.CopyStringIn(ctx, src.IO, src.Addrs.Head().Start, int(src.Addrs.Head().Length()), src.Opts)
+	if err != nil && err != linuxerr.ENAMETOOLONG {
+		return 0, err
+	}
+
+	str = strings.TrimSpace(str)
+	v, err := strconv.ParseUint(str, 0, 32)
+	if err != nil {
+		return 0, linuxerr.EINVAL
+	}
+
+	if d.task.ExitState() == kernel.TaskExitDead {
+		return 0, linuxerr.ESRCH
+	}
+	m, err := getMMIncRef(d.task)
+	if err != nil {
+		return 0, linuxerr.ESRCH
+	}
+	defer m.DecUsers(ctx)
+	m.SetCoreDumpFilter(uint32(v))
+
+	return src.NumBytes(), nil
+}
+
 // exeSymlink is an symlink for the /proc/[pid]/exe file.
 //
 // +stateify savable
@@ -1512,3 +1578,98 @@ func (d *childrenData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 
 	return nil
 }
+
+// syscallData implements vfs.DynamicBytesSource for /proc/[pid]/syscall.
+//
+// +stateify savable
+type syscallData struct {
+	kernfs.DynamicBytesFile
+
+	task *kernel.Task
+}
+
+var _ dynamicInode = (*syscallData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *syscallData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	// Linux requires PTRACE_MODE_ATTACH_FSCREDS, see
+	// fs/proc/base.c:proc_pid_syscall().
+	if !kernel.ContextCanTrace(ctx, d.task, true) {
+		return linuxerr.EACCES
+	}
+	if d.task.ExitState() == kernel.TaskExitDead {
+		return linuxerr.ESRCH
+	}
+	info := d.task.BlockInfo()
+	switch {
+	case !info.Blocked():
+		buf.WriteString("running\n")
+	case info.Sysno < 0:
+		fmt.Fprintf(buf, "%d 0x%x 0x%x\n", info.Sysno, info.SP, info.PC)
+	default:
+		fmt.Fprintf(buf, "%d 0x%x 0x%x 0x%x 0x%x 0x%x 0x%x 0x%x 0x%x\n",
+			info.Sysno, info.Args[0], info.Args[1], info.Args[2], info.Args[3], info.Args[4], info.Args[5], info.SP, info.PC)
+	}
+	return nil
+}
+
+// wchanData implements vfs.DynamicBytesSource for /proc/[pid]/wchan.
+//
+// +stateify savable
+type wchanData struct {
+	kernfs.DynamicBytesFile
+
+	task *kernel.Task
+}
+
+var _ dynamicInode = (*wchanData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *wchanData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	// As in Linux, callers without PTRACE_MODE_READ access see "0" rather
+	// than an error, as do callers reading the wait channel of a task that
+	// isn't blocked.
+	if kernel.ContextCanTrace(ctx, d.task, false) {
+		buf.WriteString(d.task.WaitChannel())
+		return nil
+	}
+	buf.WriteString("0")
+	return nil
+}
+
+// stackData implements vfs.DynamicBytesSource for /proc/[pid]/stack.
+//
+// +stateify savable
+type stackData struct {
+	kernfs.DynamicBytesFile
+
+	task *kernel.Task
+}
+
+var _ dynamicInode = (*stackData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *stackData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	// Kernel stacks may leak sensitive information, so Linux requires
+	// CAP_SYS_ADMIN in the initial user namespace in addition to
+	// PTRACE_MODE_ATTACH_FSCREDS, see fs/proc/base.c:proc_pid_stack().
+	creds := auth.CredentialsFromContext(ctx)
+	if !creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, creds.UserNamespace.Root()) {
+		return linuxerr.EACCES
+	}
+	if !kernel.ContextCanTrace(ctx, d.task, true) {
+		return linuxerr.EACCES
+	}
+	if d.task.ExitState() == kernel.TaskExitDead {
+		return linuxerr.ESRCH
+	}
+	// The stack of a running task is meaningless by the time it's read, so
+	// only blocked tasks have one. Sentry function names would expose
+	// implementation details of the sandbox, so the stack is expressed in
+	// terms of the equivalent Linux functions. As when kptr_restrict hides
+	// kernel addresses, addresses are shown as 0.
+	for _, fn := range d.task.KernelStack() {
+		fmt.Fprintf(buf, "[<0>] %s\n", fn)
+	}
+	return nil
+}
@@ -86,9 +86,11 @@ func (fs *filesystem) newTasksInode(ctx context.Context, k *kernel.Kernel, pidns
 		"bus":            fs.newStaticDir(ctx, root, map[string]kernfs.Inode{}),
 		"fs":             fs.newStaticDir(ctx, root, map[string]kernfs.Inode{}),
 		"irq":            fs.newStaticDir(ctx, root, map[string]kernfs.Inode{}),
+		"kpageflags":     fs.newKpageflagsInode(ctx, root, fs.NextIno(), 0400),
 		"meminfo":        fs.newInode(ctx, root, 0444, &meminfoData{}),
 		"mounts":         kernfs.NewStaticSymlink(ctx, root, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), "self/mounts"),
 		"net":            kernfs.NewStaticSymlink(ctx, root, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), "self/net"),
+		"pressure":       fs.newPressureDir(ctx, root, k),
 		"sentry-meminfo": fs.newInode(ctx, root, 0444, &sentryMeminfoData{}),
 		"stat":           fs.newInode(ctx, root, 0444, &statData{}),
 		"sysrq-trigger":  fs.newInode(ctx, root, 0200, newStaticFile("")),
@@ -223,12 +223,10 @@ func (*statData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	fmt.Fprintf(buf, "processes 0\n")
 
 	// Number of runnable tasks.
-	// TODO(b/37226836): Count this.
-	fmt.Fprintf(buf, "procs_running 0\n")
+	fmt.Fprintf(buf, "procs_running %d\n", k.NumRunningTasks())
 
 	// Number of tasks waiting on IO.
-	// TODO(b/37226836): Count this.
-	fmt.Fprintf(buf, "procs_blocked 0\n")
+	fmt.Fprintf(buf, "procs_blocked %d\n", k.NumUninterruptibleTasks())
 
 	// Number of each softirq handled.
 	fmt.Fprintf(buf, "softirq 0") // total
@@ -250,14 +248,41 @@ var _ dynamicInode = (*loadavgData)(nil)
 
 // Generate implements vfs.DynamicBytesSource.Generate.
 func (*loadavgData) Generate(ctx context.Context, buf *bytes.Buffer) error {
-	// TODO(b/62345059): Include real data in fields.
-	// Column 1-3: CPU and IO utilization of the last 1, 5, and 10 minute periods.
-	// Column 4-5: currently running processes and the total number of processes.
+	k := kernel.KernelFromContext(ctx)
+	// Column 1-3: load averages of the last 1, 5, and 15 minute periods.
+	// Column 4-5: currently running tasks and the total number of tasks.
 	// Column 6: the last process ID used.
-	fmt.Fprintf(buf, "%.2f %.2f %.2f %d/%d %d\n", 0.00, 0.00, 0.00, 0, 0, 0)
+	// All columns are system-wide, so that they are consistent with each other.
+	for _, avg := range k.LoadAverage() {
+		// Round to two decimal places as in Linux's fs/proc/loadavg.c.
+		avg += (1 << kernel.LoadAvgShift) / 200
+		frac := (avg & (1<<kernel.LoadAvgShift - 1)) * 100 >> kernel.LoadAvgShift
+		fmt.Fprintf(buf, "%d.%02d ", avg>>kernel.LoadAvgShift, frac)
+	}
+	root := k.TaskSet().Root
+	fmt.Fprintf(buf, "%d/%d %d\n", k.NumRunningTasks(), root.NumTasks(), root.LastThreadID())
 	return nil
 }
 
+// pressureData implements vfs.WritableDynamicBytesSource for
+// /proc/pressure/{cpu,memory,io}.
+//
+// +stateify savable
+type pressureData struct {
+	dynamicBytesFileSetAttr
+	*kernel.PSIFile
+}
+
+var _ dynamicInode = (*pressureData)(nil)
+
+func (fs *filesystem) newPressureDir(ctx context.Context, root *auth.Credentials, k *kernel.Kernel) kernfs.Inode {
+	return fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
+		"cpu":    fs.newInode(ctx, root, 0666, &pressureData{PSIFile: kernel.NewPSIFile(k.PSI(), kernel.PSICPU)}),
+		"io":     fs.newInode(ctx, root, 0666, &pressureData{PSIFile: kernel.NewPSIFile(k.PSI(), kernel.PSIIO)}),
+		"memory": fs.newInode(ctx, root, 0666, &pressureData{PSIFile: kernel.NewPSIFile(k.PSI(), kernel.PSIMemory)}),
+	})
+}
+
 // meminfoData implements vfs.DynamicBytesSource for /proc/meminfo.
 //
 // +stateify savable
@@ -53,10 +53,12 @@ var (
 		"filesystems":    linux.DT_REG,
 		"fs":             linux.DT_DIR,
 		"irq":            linux.DT_DIR,
+		"kpageflags":     linux.DT_REG,
 		"loadavg":        linux.DT_REG,
 		"meminfo":        linux.DT_REG,
 		"mounts":         linux.DT_LNK,
 		"net":            linux.DT_LNK,
+		"pressure":       linux.DT_DIR,
 		"self":           linux.DT_LNK,
 		"sentry-meminfo": linux.DT_REG,
 		"stat":           linux.DT_REG,
@@ -71,33 +73,39 @@ var (
 		"thread-self": threadSelfLink.NextOff,
 	}
 	taskStaticFiles = map[string]testutil.DirentType{
-		"auxv":          linux.DT_REG,
-		"cgroup":        linux.DT_REG,
-		"cwd":           linux.DT_LNK,
-		"cmdline":       linux.DT_REG,
-		"comm":          linux.DT_REG,
-		"environ":       linux.DT_REG,
-		"exe":           linux.DT_LNK,
-		"fd":            linux.DT_DIR,
-		"fdinfo":        linux.DT_DIR,
-		"gid_map":       linux.DT_REG,
-		"io":            linux.DT_REG,
-		"limits":        linux.DT_REG,
-		"maps":          linux.DT_REG,
-		"mem":           linux.DT_REG,
-		"mountinfo":     linux.DT_REG,
-		"mounts":        linux.DT_REG,
-		"net":           linux.DT_DIR,
-		"ns":            linux.DT_DIR,
-		"oom_score":     linux.DT_REG,
-		"oom_score_adj": linux.DT_REG,
-		"root":          linux.DT_LNK,
-		"smaps":         linux.DT_REG,
-		"stat":          linux.DT_REG,
-		"statm":         linux.DT_REG,
-		"status":        linux.DT_REG,
-		"task":          linux.DT_DIR,
-		"uid_map":       linux.DT_REG,
+		"auxv":            linux.DT_REG,
+		"cgroup":          linux.DT_REG,
+		"cwd":             linux.DT_LNK,
+		"cmdline":         linux.DT_REG,
+		"comm":            linux.DT_REG,
+		"clear_refs":      linux.DT_REG,
+		"coredump_filter": linux.DT_REG,
+		"environ":         linux.DT_REG,
+		"exe":             linux.DT_LNK,
+		"fd":              linux.DT_DIR,
+		"fdinfo":          linux.DT_DIR,
+		"gid_map":         linux.DT_REG,
+		"io":              linux.DT_REG,
+		"limits":          linux.DT_REG,
+		"maps":            linux.DT_REG,
+		"mem":             linux.DT_REG,
+		"mountinfo":       linux.DT_REG,
+		"mounts":          linux.DT_REG,
+		"net":             linux.DT_DIR,
+		"ns":              linux.DT_DIR,
+		"oom_score":       linux.DT_REG,
+		"oom_score_adj":   linux.DT_REG,
+		"pagemap":         linux.DT_REG,
+		"root":            linux.DT_LNK,
+		"smaps":           linux.DT_REG,
+		"stack":           linux.DT_REG,
+		"stat":            linux.DT_REG,
+		"statm":           linux.DT_REG,
+		"status":          linux.DT_REG,
+		"syscall":         linux.DT_REG,
+		"task":            linux.DT_DIR,
+		"uid_map":         linux.DT_REG,
+		"wchan":           linux.DT_REG,
 	}
 )
 
@@ -0,0 +1,403 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sys
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/inet"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// Network interfaces are listed in /sys/devices/virtual/net, and linked to
+// from /sys/class/net. Both directories, and the attributes of each
+// interface, are generated on demand from the network stack of the network
+// namespace that sysfs was mounted in, so interfaces added or removed via
+// rtnetlink are reflected immediately.
+
+// netStack returns the network stack of the network namespace that fs was
+// mounted in, or nil if there is no such stack.
+func (fs *filesystem) netStack() inet.Stack {
+	if fs.netns == nil {
+		return nil
+	}
+	return fs.netns.Stack()
+}
+
+// netInterfaces returns the network interfaces of fs.netStack(), or nil if
+// there is no such stack.
+func (fs *filesystem) netInterfaces() map[int32]inet.Interface {
+	stack := fs.netStack()
+	if stack == nil {
+		return nil
+	}
+	return stack.Interfaces()
+}
+
+// netInterfaceIndex returns the index of the network interface with the given
+// name.
+func (fs *filesystem) netInterfaceIndex(name string) (int32, bool) {
+	for idx, iface := range fs.netInterfaces() {
+		if iface.Name == name {
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+// netInterfaceExists returns true if the network interface with index idx
+// exists and is named name.
+func (fs *filesystem) netInterfaceExists(idx int32, name string) bool {
+	iface, ok := fs.netInterfaces()[idx]
+	return ok && iface.Name == name
+}
+
+// netDir implements kernfs.Inode for /sys/class/net and
+// /sys/devices/virtual/net.
+//
+// +stateify savable
+type netDir struct {
+	dir
+
+	fs *filesystem
+
+	// If class is true, this is /sys/class/net, whose entries are symlinks to
+	// interface directories in /sys/devices/virtual/net.
+	class bool
+}
+
+var _ kernfs.Inode = (*netDir)(nil)
+
+func (fs *filesystem) newNetDir(ctx context.Context, creds *auth.Credentials, class bool) kernfs.Inode {
+	d := &netDir{fs: fs, class: class}
+	d.InodeAttrs.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), linux.ModeDirectory|0755)
+	d.OrderedChildren.Init(kernfs.OrderedChildrenOptions{})
+	d.InitRefs()
+	return d
+}
+
+// Lookup implements kernfs.inodeDirectory.Lookup.
+func (d *netDir) Lookup(ctx context.Context, name string) (kernfs.Inode, error) {
+	idx, ok := d.fs.netInterfaceIndex(name)
+	if !ok {
+		return nil, linuxerr.ENOENT
+	}
+	creds := auth.CredentialsFromContext(ctx)
+	if d.class {
+		return d.fs.newNetClassSymlink(ctx, creds, idx, name), nil
+	}
+	return d.fs.newNetInterfaceDir(ctx, creds, idx), nil
+}
+
+// IterDirents implements kernfs.inodeDirectory.IterDirents.
+func (d *netDir) IterDirents(ctx context.Context, mnt *vfs.Mount, cb vfs.IterDirentsCallback, offset, relOffset int64) (int64, error) {
+	ifaces := d.fs.netInterfaces()
+	if relOffset >= int64(len(ifaces)) {
+		return offset, nil
+	}
+	idxs := make([]int, 0, len(ifaces))
+	for idx := range ifaces {
+		idxs = append(idxs, int(idx))
+	}
+	sort.Ints(idxs)
+
+	typ := uint8(linux.DT_DIR)
+	if d.class {
+		typ = linux.DT_LNK
+	}
+	for _, idx := range idxs[relOffset:] {
+		dirent := vfs.Dirent{
+			Name:    ifaces[int32(idx)].Name,
+			Type:    typ,
+			Ino:     d.fs.NextIno(),
+			NextOff: offset + 1,
+		}
+		if err := cb.Handle(dirent); err != nil {
+			return offset, err
+		}
+		offset++
+	}
+	return offset, nil
+}
+
+// netClassSymlink implements kernfs.Inode for /sys/class/net/[iface].
+//
+// +stateify savable
+type netClassSymlink struct {
+	kernfs.StaticSymlink
+
+	fs  *filesystem
+	idx int32
+}
+
+func (fs *filesystem) newNetClassSymlink(ctx context.Context, creds *auth.Credentials, idx int32, name string) kernfs.Inode {
+	s := &netClassSymlink{fs: fs, idx: idx}
+	s.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), "../../devices/virtual/net/"+name)
+	return s
+}
+
+// Valid implements kernfs.Inode.Valid.
+func (s *netClassSymlink) Valid(ctx context.Context, parent *kernfs.Dentry, name string) bool {
+	return s.fs.netInterfaceExists(s.idx, name)
+}
+
+// netInterfaceDir implements kernfs.Inode for
+// /sys/devices/virtual/net/[iface].
+//
+// +stateify savable
+type netInterfaceDir struct {
+	dir
+
+	fs  *filesystem
+	idx int32
+}
+
+// netAttrs are the attributes in /sys/devices/virtual/net/[iface].
+var netAttrs = []string{
+	"addr_len",
+	"address",
+	"broadcast",
+	"carrier",
+	"dev_id",
+	"dormant",
+	"flags",
+	"ifalias",
+	"ifindex",
+	"iflink",
+	"mtu",
+	"operstate",
+	"tx_queue_len",
+	"type",
+	"uevent",
+}
+
+// netStatistics maps the files in /sys/devices/virtual/net/[iface]/statistics
+// to indices into inet.StatDev, or -1 for statistics that aren't tracked.
+var netStatistics = map[string]int{
+	"rx_bytes":            0,
+	"rx_packets":          1,
+	"rx_errors":           2,
+	"rx_dropped":          3,
+	"rx_fifo_errors":      4,
+	"rx_frame_errors":     5,
+	"rx_compressed":       6,
+	"multicast":           7,
+	"tx_bytes":            8,
+	"tx_packets":          9,
+	"tx_errors":           10,
+	"tx_dropped":          11,
+	"tx_fifo_errors":      12,
+	"collisions":          13,
+	"tx_carrier_errors":   14,
+	"tx_compressed":       15,
+	"rx_crc_errors":       -1,
+	"rx_length_errors":    -1,
+	"rx_missed_errors":    -1,
+	"rx_nohandler":        -1,
+	"rx_over_errors":      -1,
+	"tx_aborted_errors":   -1,
+	"tx_heartbeat_errors": -1,
+	"tx_window_errors":    -1,
+}
+
+func (fs *filesystem) newNetInterfaceDir(ctx context.Context, creds *auth.Credentials, idx int32) kernfs.Inode {
+	stats := make(map[string]kernfs.Inode, len(netStatistics))
+	for name, stat := range netStatistics {
+		stats[name] = fs.newNetStatFile(ctx, creds, idx, stat)
+	}
+	contents := map[string]kernfs.Inode{
+		"statistics": fs.newDir(ctx, creds, defaultSysDirMode, stats),
+	}
+	for _, attr := range netAttrs {
+		contents[attr] = fs.newNetAttrFile(ctx, creds, idx, attr)
+	}
+
+	d := &netInterfaceDir{fs: fs, idx: idx}
+	d.InodeAttrs.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), linux.ModeDirectory|0755)
+	d.OrderedChildren.Init(kernfs.OrderedChildrenOptions{})
+	d.InitRefs()
+	d.IncLinks(d.OrderedChildren.Populate(contents))
+	return d
+}
+
+// Valid implements kernfs.Inode.Valid.
+func (d *netInterfaceDir) Valid(ctx context.Context, parent *kernfs.Dentry, name string) bool {
+	return d.fs.netInterfaceExists(d.idx, name)
+}
+
+// netAttrFile implements kernfs.Inode for the attributes in
+// /sys/devices/virtual/net/[iface].
+//
+// +stateify savable
+type netAttrFile struct {
+	implStatFS
+	kernfs.DynamicBytesFile
+
+	fs   *filesystem
+	idx  int32
+	attr string
+}
+
+func (fs *filesystem) newNetAttrFile(ctx context.Context, creds *auth.Credentials, idx int32, attr string) kernfs.Inode {
+	f := &netAttrFile{fs: fs, idx: idx, attr: attr}
+	f.DynamicBytesFile.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), f, defaultSysMode)
+	return f
+}
+
+// hardwareAddr returns iface's hardware address and broadcast address.
+func hardwareAddr(iface *inet.Interface) (addr, broadcast []byte) {
+	addr = iface.Addr
+	switch iface.DeviceType {
+	case linux.ARPHRD_LOOPBACK:
+		if len(addr) == 0 {
+			addr = make([]byte, 6)
+		}
+		broadcast = make([]byte, len(addr))
+	case linux.ARPHRD_ETHER:
+		broadcast = bytes.Repeat([]byte{0xff}, len(addr))
+	default:
+		broadcast = make([]byte, len(addr))
+	}
+	return addr, broadcast
+}
+
+// formatHardwareAddr formats addr as in Linux's net/ethernet/eth.c:sysfs_format_mac().
+func formatHardwareAddr(addr []byte) string {
+	parts := make([]string, len(addr))
+	for i, b := range addr {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// operState returns iface's RFC 2863 operational state, as in Linux's
+// net/core/net-sysfs.c:operstate_show().
+func operState(iface *inet.Interface) string {
+	switch {
+	case iface.Flags&linux.IFF_UP == 0:
+		return "down"
+	case iface.Flags&linux.IFF_LOOPBACK != 0:
+		// Linux never sets an operational state for loopback devices.
+		return "unknown"
+	case iface.Flags&linux.IFF_RUNNING != 0:
+		return "up"
+	default:
+		return "down"
+	}
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (f *netAttrFile) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	iface, ok := f.fs.netInterfaces()[f.idx]
+	if !ok {
+		return linuxerr.ENODEV
+	}
+	addr, broadcast := hardwareAddr(&iface)
+	switch f.attr {
+	case "addr_len":
+		fmt.Fprintf(buf, "%d\n", len(addr))
+	case "address":
+		fmt.Fprintf(buf, "%s\n", formatHardwareAddr(addr))
+	case "broadcast":
+		fmt.Fprintf(buf, "%s\n", formatHardwareAddr(broadcast))
+	case "carrier":
+		// Carrier state is only meaningful for interfaces that are up.
+		if iface.Flags&linux.IFF_UP == 0 {
+			return linuxerr.EINVAL
+		}
+		if iface.Flags&linux.IFF_RUNNING != 0 {
+			buf.WriteString("1\n")
+		} else {
+			buf.WriteString("0\n")
+		}
+	case "dev_id":
+		buf.WriteString("0x0\n")
+	case "dormant":
+		buf.WriteString("0\n")
+	case "flags":
+		fmt.Fprintf(buf, "%#x\n", iface.Flags)
+	case "ifalias":
+		buf.WriteString("\n")
+	case "ifindex", "iflink":
+		fmt.Fprintf(buf, "%d\n", f.idx)
+	case "mtu":
+		fmt.Fprintf(buf, "%d\n", iface.MTU)
+	case "operstate":
+		fmt.Fprintf(buf, "%s\n", operState(&iface))
+	case "tx_queue_len":
+		buf.WriteString("1000\n")
+	case "type":
+		fmt.Fprintf(buf, "%d\n", iface.DeviceType)
+	case "uevent":
+		fmt.Fprintf(buf, "INTERFACE=%s\nIFINDEX=%d\n", iface.Name, f.idx)
+	default:
+		panic(fmt.Sprintf("unknown network interface attribute %q", f.attr))
+	}
+	return nil
+}
+
+// netStatFile implements kernfs.Inode for the files in
+// /sys/devices/virtual/net/[iface]/statistics.
+//
+// +stateify savable
+type netStatFile struct {
+	implStatFS
+	kernfs.DynamicBytesFile
+
+	fs  *filesystem
+	idx int32
+
+	// stat is an index into inet.StatDev, or -1 if the statistic is always
+	// zero.
+	stat int
+}
+
+func (fs *filesystem) newNetStatFile(ctx context.Context, creds *auth.Credentials, idx int32, stat int) kernfs.Inode {
+	f := &netStatFile{fs: fs, idx: idx, stat: stat}
+	f.DynamicBytesFile.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), f, defaultSysMode)
+	return f
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (f *netStatFile) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	stack := f.fs.netStack()
+	if stack == nil {
+		return linuxerr.ENODEV
+	}
+	iface, ok := stack.Interfaces()[f.idx]
+	if !ok {
+		return linuxerr.ENODEV
+	}
+	var val uint64
+	if f.stat >= 0 {
+		var stats inet.StatDev
+		if err := stack.Statistics(&stats, iface.Name); err != nil {
+			log.Warningf("Failed to retrieve interface statistics for %v: %v", iface.Name, err)
+			return linuxerr.EIO
+		}
+		val = stats[f.stat]
+	}
+	fmt.Fprintf(buf, "%d\n", val)
+	return nil
+}
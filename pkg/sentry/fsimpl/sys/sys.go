@@ -30,6 +30,7 @@ import (
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
@@ -74,6 +75,11 @@ type filesystem struct {
 	enableTPUProxyPaths bool
 	testSysfsPathPrefix string
 	root                *dir
+
+	// netns is the network namespace that the filesystem was mounted in,
+	// whose interfaces are listed in /sys/class/net. netns may be nil if
+	// there is no such namespace. fs holds a reference on netns.
+	netns *inet.Namespace
 }
 
 // Name implements vfs.FilesystemType.Name.
@@ -109,6 +115,14 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	fs.VFSFilesystem().Init(vfsObj, &fsType, fs)
 
 	k := kernel.KernelFromContext(ctx)
+	// Like Linux, network interfaces are listed from the network namespace
+	// of the mounting task; see fs/sysfs/mount.c:sysfs_init_fs_context().
+	if t := kernel.TaskFromContext(ctx); t != nil {
+		fs.netns = t.GetNetworkNamespace()
+	} else {
+		fs.netns = k.RootNetworkNamespace()
+		fs.netns.IncRef()
+	}
 	fsDirChildren := make(map[string]kernfs.Inode)
 	// Create an empty directory to serve as the mount point for cgroupfs when
 	// cgroups are available. This emulates Linux behaviour, see
@@ -121,8 +135,12 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	}
 
 	classSub := map[string]kernfs.Inode{
+		"net":          fs.newNetDir(ctx, creds, true /* class */),
 		"power_supply": fs.newDir(ctx, creds, defaultSysDirMode, nil),
 	}
+	virtualSub := map[string]kernfs.Inode{
+		"net": fs.newNetDir(ctx, creds, false /* class */),
+	}
 	devicesSub := map[string]kernfs.Inode{
 		"system": fs.newDir(ctx, creds, defaultSysDirMode, map[string]kernfs.Inode{
 			"cpu": cpuDir(ctx, fs, creds),
@@ -183,14 +201,13 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		classSub["dmi"] = fs.newDir(ctx, creds, defaultSysDirMode, map[string]kernfs.Inode{
 			"id": kernfs.NewStaticSymlink(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), "../../devices/virtual/dmi/id"),
 		})
-		devicesSub["virtual"] = fs.newDir(ctx, creds, defaultSysDirMode, map[string]kernfs.Inode{
-			"dmi": fs.newDir(ctx, creds, defaultSysDirMode, map[string]kernfs.Inode{
-				"id": fs.newDir(ctx, creds, defaultSysDirMode, map[string]kernfs.Inode{
-					"product_name": fs.newStaticFile(ctx, creds, defaultSysMode, productName+"\n"),
-				}),
+		virtualSub["dmi"] = fs.newDir(ctx, creds, defaultSysDirMode, map[string]kernfs.Inode{
+			"id": fs.newDir(ctx, creds, defaultSysDirMode, map[string]kernfs.Inode{
+				"product_name": fs.newStaticFile(ctx, creds, defaultSysMode, productName+"\n"),
 			}),
 		})
 	}
+	devicesSub["virtual"] = fs.newDir(ctx, creds, defaultSysDirMode, virtualSub)
 	root := fs.newDir(ctx, creds, defaultSysDirMode, map[string]kernfs.Inode{
 		"block":    fs.newDir(ctx, creds, defaultSysDirMode, nil),
 		"bus":      fs.newDir(ctx, creds, defaultSysDirMode, busSub),
@@ -341,6 +358,9 @@ func (fs *filesystem) mirrorIOMMUGroups(ctx context.Context, creds *auth.Credent
 func (fs *filesystem) Release(ctx context.Context) {
 	fs.Filesystem.VFSFilesystem().VirtualFilesystem().PutAnonBlockDevMinor(fs.devMinor)
 	fs.Filesystem.Release(ctx)
+	if fs.netns != nil {
+		fs.netns.DecRef(ctx)
+	}
 }
 
 // MountOptions implements vfs.FilesystemImpl.MountOptions.
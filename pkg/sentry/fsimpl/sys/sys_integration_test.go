@@ -103,6 +103,20 @@ func TestSysRootContainsExpectedEntries(t *testing.T) {
 	})
 }
 
+func TestNetDirsExist(t *testing.T) {
+	s := newTestSystem(t, "" /*pciTestDir*/)
+	defer s.Destroy()
+	pop := s.PathOpAtRoot("/class")
+	s.AssertAllDirentTypes(s.ListDirents(pop), map[string]testutil.DirentType{
+		"net":          linux.DT_DIR,
+		"power_supply": linux.DT_DIR,
+	})
+	pop = s.PathOpAtRoot("/devices/virtual")
+	s.AssertAllDirentTypes(s.ListDirents(pop), map[string]testutil.DirentType{
+		"net": linux.DT_DIR,
+	})
+}
+
 func TestCgroupMountpointExists(t *testing.T) {
 	// Note: The mountpoint is only created if cgroups are available.
 	s := newTestSystem(t, "" /*pciTestDir*/)
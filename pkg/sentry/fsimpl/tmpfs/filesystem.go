@@ -303,6 +303,9 @@ func (fs *filesystem) MkdirAt(ctx context.Context, rp *vfs.ResolvingPath, opts v
 		if parentDir.inode.nlink.Load() == maxLinks {
 			return linuxerr.EMLINK
 		}
+		if err := fs.checkNewInodeLocked(creds.EffectiveKUID, creds.EffectiveKGID, parentDir); err != nil {
+			return err
+		}
 		parentDir.inode.incLinksLocked() // from child's ".."
 		childDir := fs.newDirectory(creds.EffectiveKUID, creds.EffectiveKGID, opts.Mode, parentDir)
 		parentDir.insertChildLocked(&childDir.dentry, name)
@@ -314,6 +317,9 @@ func (fs *filesystem) MkdirAt(ctx context.Context, rp *vfs.ResolvingPath, opts v
 func (fs *filesystem) MknodAt(ctx context.Context, rp *vfs.ResolvingPath, opts vfs.MknodOptions) error {
 	return fs.doCreateAt(ctx, rp, false /* dir */, func(parentDir *directory, name string) error {
 		creds := rp.Credentials()
+		if err := fs.checkNewInodeLocked(creds.EffectiveKUID, creds.EffectiveKGID, parentDir); err != nil {
+			return err
+		}
 		var childInode *inode
 		switch opts.Mode.FileType() {
 		case linux.S_IFREG:
@@ -418,6 +424,9 @@ afterTrailingSymlink:
 		defer rp.Mount().EndWrite()
 		// Create and open the child.
 		creds := rp.Credentials()
+		if err := fs.checkNewInodeLocked(creds.EffectiveKUID, creds.EffectiveKGID, parentDir); err != nil {
+			return nil, err
+		}
 		child := fs.newDentry(fs.newRegularFile(creds.EffectiveKUID, creds.EffectiveKGID, opts.Mode, parentDir))
 		parentDir.insertChildLocked(child, name)
 		child.IncRef()
@@ -793,17 +802,15 @@ func (fs *filesystem) StatFSAt(ctx context.Context, rp *vfs.ResolvingPath) (linu
 // SymlinkAt implements vfs.FilesystemImpl.SymlinkAt.
 func (fs *filesystem) SymlinkAt(ctx context.Context, rp *vfs.ResolvingPath, target string) error {
 	return fs.doCreateAt(ctx, rp, false /* dir */, func(parentDir *directory, name string) error {
-		// Linux allocates a page to store symlink targets that have length larger
-		// than shortSymlinkLen. Targets are just stored as string here, but simulate
-		// the page accounting for it. See mm/shmem.c:shmem_symlink().
-		if len(target) >= shortSymlinkLen {
-			if !fs.accountPages(1) {
-				return linuxerr.ENOSPC
-			}
-		}
 		creds := rp.Credentials()
-		child := fs.newDentry(fs.newSymlink(creds.EffectiveKUID, creds.EffectiveKGID, 0777, target, parentDir))
-		parentDir.insertChildLocked(child, name)
+		if err := fs.checkNewInodeLocked(creds.EffectiveKUID, creds.EffectiveKGID, parentDir); err != nil {
+			return err
+		}
+		inode, err := fs.newSymlink(creds.EffectiveKUID, creds.EffectiveKGID, 0777, target, parentDir)
+		if err != nil {
+			return err
+		}
+		parentDir.insertChildLocked(fs.newDentry(inode), name)
 		return nil
 	})
 }
@@ -980,18 +987,6 @@ func (fs *filesystem) MountOptions() string {
 	return fs.mopts
 }
 
-// adjustPageAcct adjusts the accounting done against filesystem size limit in
-// case there is any discrepancy between the number of pages reserved vs the
-// number of pages actually allocated.
-func (fs *filesystem) adjustPageAcct(reserved, alloced uint64) {
-	if reserved < alloced {
-		panic(fmt.Sprintf("More pages were allocated than the pages reserved: reserved=%d, alloced=%d", reserved, alloced))
-	}
-	if pagesDiff := reserved - alloced; pagesDiff > 0 {
-		fs.unaccountPages(pagesDiff)
-	}
-}
-
 // accountPagesPartial increases the pagesUsed if tmpfs is mounted with size
 // option by as much as possible without going over the size mount option. It
 // returns the number of pages that we were able to account for. It returns false
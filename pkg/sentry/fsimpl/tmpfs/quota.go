@@ -0,0 +1,579 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmpfs
+
+import (
+	"fmt"
+	"sort"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// numQuotaTypes is the number of quota types supported by tmpfs,
+// linux.USRQUOTA and linux.GRPQUOTA. Compare Linux's mm/shmem_quota.c.
+const numQuotaTypes = 2
+
+// dquot is the quota of a user or group.
+//
+// +stateify savable
+type dquot struct {
+	// bHardLimit and bSoftLimit are the limits on space, in bytes. A limit
+	// of 0 is unlimited.
+	bHardLimit uint64
+	bSoftLimit uint64
+
+	// iHardLimit and iSoftLimit are the limits on the number of inodes. A
+	// limit of 0 is unlimited.
+	iHardLimit uint64
+	iSoftLimit uint64
+
+	// pages and inodes are the usage charged to the quota.
+	pages  uint64
+	inodes uint64
+
+	// bTime and iTime are the times, in seconds since the epoch, at which
+	// the grace periods for exceeding the soft limits end, or 0 if usage
+	// doesn't exceed the soft limits.
+	bTime int64
+	iTime int64
+}
+
+// quotaAllowance returns how many of want units may be charged to a quota
+// with usage cur, given its limits (in bytes, for units of size unit) and
+// soft limit grace period end.
+func quotaAllowance(cur, want, unit, hardLimit, softLimit uint64, graceEnd, now int64) uint64 {
+	avail := want
+	if hardLimit != 0 {
+		hard := hardLimit / unit
+		if cur >= hard {
+			return 0
+		}
+		avail = min(avail, hard-cur)
+	}
+	if softLimit != 0 && graceEnd != 0 && now >= graceEnd {
+		soft := softLimit / unit
+		if cur >= soft {
+			return 0
+		}
+		avail = min(avail, soft-cur)
+	}
+	return avail
+}
+
+// updateGrace starts or ends the grace period of a soft limit after a change
+// in usage.
+func updateGrace(graceEnd *int64, cur, unit, softLimit, grace uint64, now int64) {
+	if softLimit == 0 || cur*unit <= softLimit {
+		*graceEnd = 0
+	} else if *graceEnd == 0 {
+		*graceEnd = now + int64(grace)
+	}
+}
+
+// quotaSet is the set of quotas of one type on a filesystem.
+//
+// +stateify savable
+type quotaSet struct {
+	// bGrace and iGrace are the soft limit grace periods in seconds.
+	bGrace uint64
+	iGrace uint64
+
+	// defaults holds the limits of quotas that haven't been set explicitly,
+	// from mount options.
+	defaults dquot
+
+	// dquots are the quotas with usage or explicitly set limits, indexed by
+	// auth.KUID or auth.KGID.
+	dquots map[uint32]*dquot
+}
+
+func newQuotaSet(bHardLimit, iHardLimit uint64) *quotaSet {
+	return &quotaSet{
+		bGrace: linux.MAX_DQ_TIME,
+		iGrace: linux.MAX_IQ_TIME,
+		defaults: dquot{
+			bHardLimit: bHardLimit,
+			iHardLimit: iHardLimit,
+		},
+		dquots: make(map[uint32]*dquot),
+	}
+}
+
+// peek returns the quota of id, without creating it.
+func (s *quotaSet) peek(id uint32) *dquot {
+	if d, ok := s.dquots[id]; ok {
+		return d
+	}
+	return &s.defaults
+}
+
+// get returns the quota of id, creating it if necessary.
+func (s *quotaSet) get(id uint32) *dquot {
+	d, ok := s.dquots[id]
+	if !ok {
+		d = &dquot{}
+		*d = s.defaults
+		s.dquots[id] = d
+	}
+	return d
+}
+
+// update updates the grace periods of the quota of id after a change in
+// usage, and forgets the quota if it's indistinguishable from an unused one.
+func (s *quotaSet) update(id uint32, d *dquot, now int64) {
+	updateGrace(&d.bTime, d.pages, hostarch.PageSize, d.bSoftLimit, s.bGrace, now)
+	updateGrace(&d.iTime, d.inodes, 1, d.iSoftLimit, s.iGrace, now)
+	if *d == s.defaults {
+		delete(s.dquots, id)
+	}
+}
+
+// quotas are the user and group quotas of a filesystem.
+//
+// +stateify savable
+type quotas struct {
+	mu quotasMutex `state:"nosave"`
+
+	// sets holds the quotas of each type, or nil if quotas of that type are
+	// disabled. The pointers are immutable; the quotaSets are protected by
+	// mu.
+	sets [numQuotaTypes]*quotaSet
+}
+
+// enabled returns true if quotas of any type are enabled.
+func (q *quotas) enabled() bool {
+	return q.sets[linux.USRQUOTA] != nil || q.sets[linux.GRPQUOTA] != nil
+}
+
+// set returns the quotas of type qtype, or ESRCH if they're disabled.
+func (q *quotas) set(qtype uint32) (*quotaSet, error) {
+	switch qtype {
+	case linux.USRQUOTA, linux.GRPQUOTA:
+		if s := q.sets[qtype]; s != nil {
+			return s, nil
+		}
+		return nil, linuxerr.ESRCH
+	case linux.PRJQUOTA:
+		return nil, linuxerr.ESRCH
+	default:
+		return nil, linuxerr.EINVAL
+	}
+}
+
+// allowanceLocked returns how many of pages may be charged to the quota of id,
+// or EDQUOT if inodes may not be charged. If not all pages may be charged and
+// partial is false, allowanceLocked returns EDQUOT.
+//
+// +checklocks:q.mu
+func (q *quotas) allowanceLocked(s *quotaSet, id uint32, pages, inodes uint64, partial bool, now int64) (uint64, error) {
+	d := s.peek(id)
+	if quotaAllowance(d.inodes, inodes, 1, d.iHardLimit, d.iSoftLimit, d.iTime, now) < inodes {
+		return 0, linuxerr.EDQUOT
+	}
+	avail := quotaAllowance(d.pages, pages, hostarch.PageSize, d.bHardLimit, d.bSoftLimit, d.bTime, now)
+	if avail < pages && !partial {
+		return 0, linuxerr.EDQUOT
+	}
+	return avail, nil
+}
+
+// addLocked adds pages and inodes to the usage of the quota of id.
+//
+// +checklocks:q.mu
+func (q *quotas) addLocked(s *quotaSet, id uint32, pages, inodes uint64, now int64) {
+	d := s.get(id)
+	d.pages += pages
+	d.inodes += inodes
+	s.update(id, d, now)
+}
+
+// subLocked subtracts pages and inodes from the usage of the quota of id.
+//
+// +checklocks:q.mu
+func (q *quotas) subLocked(s *quotaSet, id uint32, pages, inodes uint64, now int64) {
+	d := s.get(id)
+	d.pages -= min(pages, d.pages)
+	d.inodes -= min(inodes, d.inodes)
+	s.update(id, d, now)
+}
+
+// chargeLocked charges pages and inodes to the quotas of the owners of an
+// inode. If the quotas don't permit charging all pages and partial is true,
+// chargeLocked charges as many pages as permitted. chargeLocked returns the
+// number of pages charged, or EDQUOT if nothing could be charged.
+//
+// +checklocks:q.mu
+func (q *quotas) chargeLocked(ids [numQuotaTypes]uint32, pages, inodes uint64, partial bool, now int64) (uint64, error) {
+	want := pages
+	for ty, s := range q.sets {
+		if s == nil {
+			continue
+		}
+		avail, err := q.allowanceLocked(s, ids[ty], pages, inodes, partial, now)
+		if err != nil {
+			return 0, err
+		}
+		pages = avail
+	}
+	if want != 0 && pages == 0 {
+		return 0, linuxerr.EDQUOT
+	}
+	q.forceChargeLocked(ids, pages, inodes, now)
+	return pages, nil
+}
+
+// forceChargeLocked charges pages and inodes to the quotas of the owners of
+// an inode without checking limits.
+//
+// +checklocks:q.mu
+func (q *quotas) forceChargeLocked(ids [numQuotaTypes]uint32, pages, inodes uint64, now int64) {
+	for ty, s := range q.sets {
+		if s != nil {
+			q.addLocked(s, ids[ty], pages, inodes, now)
+		}
+	}
+}
+
+// releaseLocked returns pages and inodes charged to the quotas of the owners
+// of an inode.
+//
+// +checklocks:q.mu
+func (q *quotas) releaseLocked(ids [numQuotaTypes]uint32, pages, inodes uint64, now int64) {
+	for ty, s := range q.sets {
+		if s != nil {
+			q.subLocked(s, ids[ty], pages, inodes, now)
+		}
+	}
+}
+
+// quotaIDs returns the ids of the quotas charged for an inode owned by kuid
+// and kgid.
+func quotaIDs(kuid auth.KUID, kgid auth.KGID) [numQuotaTypes]uint32 {
+	return [numQuotaTypes]uint32{
+		linux.USRQUOTA: uint32(kuid),
+		linux.GRPQUOTA: uint32(kgid),
+	}
+}
+
+// quotaIDs returns the ids of the quotas charged for i.
+func (i *inode) quotaIDs() [numQuotaTypes]uint32 {
+	return quotaIDs(auth.KUID(i.uid.Load()), auth.KGID(i.gid.Load()))
+}
+
+// quotaNow returns the current time in seconds, for quota grace periods.
+func (fs *filesystem) quotaNow() int64 {
+	return fs.clock.Now().Seconds()
+}
+
+// checkNewInodeLocked returns an error if fs can't accommodate a new inode
+// owned by kuid and kgid in parentDir, due to fs's inode limit or quotas.
+//
+// Preconditions: filesystem.mu must be locked for writing.
+func (fs *filesystem) checkNewInodeLocked(kuid auth.KUID, kgid auth.KGID, parentDir *directory) error {
+	if fs.maxInodes != 0 && fs.inodesUsed.Load() >= fs.maxInodes {
+		return linuxerr.ENOSPC
+	}
+	if !fs.quotas.enabled() {
+		return nil
+	}
+	ids := quotaIDs(kuid, inheritedKGID(kgid, parentDir))
+	now := fs.quotaNow()
+	fs.quotas.mu.Lock()
+	defer fs.quotas.mu.Unlock()
+	for ty, s := range fs.quotas.sets {
+		if s != nil {
+			if _, err := fs.quotas.allowanceLocked(s, ids[ty], 0, 1, false /* partial */, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// chargeInode accounts i, which has just been initialized, to fs's inode
+// count and quotas.
+func (fs *filesystem) chargeInode(i *inode) {
+	fs.inodesUsed.Add(1)
+	if !fs.quotas.enabled() {
+		return
+	}
+	now := fs.quotaNow()
+	fs.quotas.mu.Lock()
+	fs.quotas.forceChargeLocked(i.quotaIDs(), 0, 1, now)
+	fs.quotas.mu.Unlock()
+}
+
+// releaseInode reverses chargeInode when i is destroyed.
+func (fs *filesystem) releaseInode(i *inode) {
+	fs.inodesUsed.Add(^uint64(0))
+	if !fs.quotas.enabled() {
+		return
+	}
+	now := fs.quotaNow()
+	fs.quotas.mu.Lock()
+	fs.quotas.releaseLocked(i.quotaIDs(), 0, 1, now)
+	fs.quotas.mu.Unlock()
+}
+
+// accountPages charges pages to i's filesystem size limit and its owners'
+// quotas. It returns ENOSPC or EDQUOT if they don't permit it.
+func (i *inode) accountPages(pages uint64) error {
+	if !i.fs.accountPages(pages) {
+		return linuxerr.ENOSPC
+	}
+	if pages == 0 || !i.fs.quotas.enabled() {
+		return nil
+	}
+	now := i.fs.quotaNow()
+	i.fs.quotas.mu.Lock()
+	defer i.fs.quotas.mu.Unlock()
+	if _, err := i.fs.quotas.chargeLocked(i.quotaIDs(), pages, 0, false /* partial */, now); err != nil {
+		i.fs.unaccountPages(pages)
+		return err
+	}
+	i.quotaPages += pages
+	return nil
+}
+
+// accountPagesPartial is like accountPages, but charges as many of pages as
+// permitted. It returns the number of pages charged, and an error if none
+// could be.
+func (i *inode) accountPagesPartial(pages uint64) (uint64, error) {
+	if pages == 0 {
+		return 0, nil
+	}
+	reserved := i.fs.accountPagesPartial(pages)
+	if reserved == 0 {
+		return 0, linuxerr.ENOSPC
+	}
+	if !i.fs.quotas.enabled() {
+		return reserved, nil
+	}
+	now := i.fs.quotaNow()
+	i.fs.quotas.mu.Lock()
+	defer i.fs.quotas.mu.Unlock()
+	charged, err := i.fs.quotas.chargeLocked(i.quotaIDs(), reserved, 0, true /* partial */, now)
+	i.fs.unaccountPages(reserved - charged)
+	i.quotaPages += charged
+	return charged, err
+}
+
+// unaccountPages reverses accountPages.
+func (i *inode) unaccountPages(pages uint64) {
+	if pages == 0 {
+		return
+	}
+	i.fs.unaccountPages(pages)
+	if !i.fs.quotas.enabled() {
+		return
+	}
+	now := i.fs.quotaNow()
+	i.fs.quotas.mu.Lock()
+	defer i.fs.quotas.mu.Unlock()
+	pages = min(pages, i.quotaPages)
+	i.quotaPages -= pages
+	i.fs.quotas.releaseLocked(i.quotaIDs(), pages, 0, now)
+}
+
+// adjustPageAcct returns pages that were charged by accountPages or
+// accountPagesPartial, but not allocated.
+func (i *inode) adjustPageAcct(reserved, alloced uint64) {
+	if reserved < alloced {
+		panic(fmt.Sprintf("More pages were allocated than the pages reserved: reserved=%d, alloced=%d", reserved, alloced))
+	}
+	i.unaccountPages(reserved - alloced)
+}
+
+// setOwner changes the owners of i, transferring its usage between quotas.
+//
+// Preconditions: i.mu must be locked.
+func (i *inode) setOwner(kuid auth.KUID, kgid auth.KGID) error {
+	if !i.fs.quotas.enabled() {
+		i.uid.Store(uint32(kuid))
+		i.gid.Store(uint32(kgid))
+		return nil
+	}
+	now := i.fs.quotaNow()
+	q := &i.fs.quotas
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// Only quotas whose id changes are transferred.
+	oldIDs, newIDs := i.quotaIDs(), quotaIDs(kuid, kgid)
+	for ty, s := range q.sets {
+		if s != nil && oldIDs[ty] != newIDs[ty] {
+			if _, err := q.allowanceLocked(s, newIDs[ty], i.quotaPages, 1, false /* partial */, now); err != nil {
+				return err
+			}
+		}
+	}
+	for ty, s := range q.sets {
+		if s != nil && oldIDs[ty] != newIDs[ty] {
+			q.addLocked(s, newIDs[ty], i.quotaPages, 1, now)
+			q.subLocked(s, oldIDs[ty], i.quotaPages, 1, now)
+		}
+	}
+	i.uid.Store(uint32(kuid))
+	i.gid.Store(uint32(kgid))
+	return nil
+}
+
+// GetQuotaFormat implements vfs.QuotaFilesystemImpl.GetQuotaFormat.
+func (fs *filesystem) GetQuotaFormat(qtype uint32) (uint32, error) {
+	if _, err := fs.quotas.set(qtype); err != nil {
+		return 0, err
+	}
+	return linux.QFMT_SHMEM, nil
+}
+
+// GetQuotaInfo implements vfs.QuotaFilesystemImpl.GetQuotaInfo.
+func (fs *filesystem) GetQuotaInfo(qtype uint32) (linux.IfDqinfo, error) {
+	s, err := fs.quotas.set(qtype)
+	if err != nil {
+		return linux.IfDqinfo{}, err
+	}
+	fs.quotas.mu.Lock()
+	defer fs.quotas.mu.Unlock()
+	return linux.IfDqinfo{
+		BGrace: s.bGrace,
+		IGrace: s.iGrace,
+		Valid:  linux.IIF_ALL,
+	}, nil
+}
+
+// SetQuotaInfo implements vfs.QuotaFilesystemImpl.SetQuotaInfo.
+func (fs *filesystem) SetQuotaInfo(qtype uint32, info *linux.IfDqinfo) error {
+	s, err := fs.quotas.set(qtype)
+	if err != nil {
+		return err
+	}
+	if info.Valid&linux.IIF_FLAGS != 0 && info.Flags != 0 {
+		return linuxerr.EINVAL
+	}
+	fs.quotas.mu.Lock()
+	defer fs.quotas.mu.Unlock()
+	if info.Valid&linux.IIF_BGRACE != 0 {
+		s.bGrace = info.BGrace
+	}
+	if info.Valid&linux.IIF_IGRACE != 0 {
+		s.iGrace = info.IGrace
+	}
+	return nil
+}
+
+// toDqblk returns d in the format used by quotactl(2).
+func (d *dquot) toDqblk() linux.IfDqblk {
+	return linux.IfDqblk{
+		BHardLimit: d.bHardLimit / linux.QIF_DQBLKSIZE,
+		BSoftLimit: d.bSoftLimit / linux.QIF_DQBLKSIZE,
+		CurSpace:   d.pages * hostarch.PageSize,
+		IHardLimit: d.iHardLimit,
+		ISoftLimit: d.iSoftLimit,
+		CurInodes:  d.inodes,
+		BTime:      uint64(d.bTime),
+		ITime:      uint64(d.iTime),
+		Valid:      linux.QIF_ALL,
+	}
+}
+
+// GetQuota implements vfs.QuotaFilesystemImpl.GetQuota.
+func (fs *filesystem) GetQuota(qtype, id uint32) (linux.IfDqblk, error) {
+	s, err := fs.quotas.set(qtype)
+	if err != nil {
+		return linux.IfDqblk{}, err
+	}
+	fs.quotas.mu.Lock()
+	defer fs.quotas.mu.Unlock()
+	return s.peek(id).toDqblk(), nil
+}
+
+// GetNextQuota implements vfs.QuotaFilesystemImpl.GetNextQuota.
+func (fs *filesystem) GetNextQuota(qtype, id uint32) (linux.IfNextDqblk, error) {
+	s, err := fs.quotas.set(qtype)
+	if err != nil {
+		return linux.IfNextDqblk{}, err
+	}
+	fs.quotas.mu.Lock()
+	defer fs.quotas.mu.Unlock()
+	ids := make([]uint32, 0, len(s.dquots))
+	for qid := range s.dquots {
+		if qid >= id {
+			ids = append(ids, qid)
+		}
+	}
+	if len(ids) == 0 {
+		return linux.IfNextDqblk{}, linuxerr.ENOENT
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	dq := s.dquots[ids[0]].toDqblk()
+	return linux.IfNextDqblk{
+		BHardLimit: dq.BHardLimit,
+		BSoftLimit: dq.BSoftLimit,
+		CurSpace:   dq.CurSpace,
+		IHardLimit: dq.IHardLimit,
+		ISoftLimit: dq.ISoftLimit,
+		CurInodes:  dq.CurInodes,
+		BTime:      dq.BTime,
+		ITime:      dq.ITime,
+		Valid:      dq.Valid,
+		ID:         ids[0],
+	}, nil
+}
+
+// SetQuota implements vfs.QuotaFilesystemImpl.SetQuota.
+func (fs *filesystem) SetQuota(qtype, id uint32, dq *linux.IfDqblk) error {
+	s, err := fs.quotas.set(qtype)
+	if err != nil {
+		return err
+	}
+	if dq.Valid&^linux.QIF_ALL != 0 {
+		return linuxerr.EINVAL
+	}
+	const maxBlocks = ^uint64(0) / linux.QIF_DQBLKSIZE
+	if dq.Valid&linux.QIF_BLIMITS != 0 && (dq.BHardLimit > maxBlocks || dq.BSoftLimit > maxBlocks) {
+		return linuxerr.ERANGE
+	}
+	now := fs.quotaNow()
+	fs.quotas.mu.Lock()
+	defer fs.quotas.mu.Unlock()
+	d := s.get(id)
+	if dq.Valid&linux.QIF_BLIMITS != 0 {
+		d.bHardLimit = dq.BHardLimit * linux.QIF_DQBLKSIZE
+		d.bSoftLimit = dq.BSoftLimit * linux.QIF_DQBLKSIZE
+	}
+	if dq.Valid&linux.QIF_SPACE != 0 {
+		d.pages = (dq.CurSpace + hostarch.PageSize - 1) / hostarch.PageSize
+	}
+	if dq.Valid&linux.QIF_ILIMITS != 0 {
+		d.iHardLimit = dq.IHardLimit
+		d.iSoftLimit = dq.ISoftLimit
+	}
+	if dq.Valid&linux.QIF_INODES != 0 {
+		d.inodes = dq.CurInodes
+	}
+	if dq.Valid&linux.QIF_BTIME != 0 {
+		d.bTime = int64(dq.BTime)
+	}
+	if dq.Valid&linux.QIF_ITIME != 0 {
+		d.iTime = int64(dq.ITime)
+	}
+	s.update(id, d, now)
+	return nil
+}
+
+var _ vfs.QuotaFilesystemImpl = (*filesystem)(nil)
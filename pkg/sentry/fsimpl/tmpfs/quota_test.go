@@ -0,0 +1,166 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmpfs
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/sentry/contexttest"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// newTmpfsRootWithOptions is like newTmpfsRoot, but mounts tmpfs with the
+// given mount options.
+func newTmpfsRootWithOptions(t *testing.T, ctx context.Context, data string) (*vfs.VirtualFilesystem, vfs.VirtualDentry) {
+	t.Helper()
+	creds := auth.CredentialsFromContext(ctx)
+	vfsObj := &vfs.VirtualFilesystem{}
+	if err := vfsObj.Init(ctx); err != nil {
+		t.Fatalf("VFS init: %v", err)
+	}
+	vfsObj.MustRegisterFilesystemType("tmpfs", FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
+		AllowUserMount: true,
+	})
+	mntns, err := vfsObj.NewMountNamespace(ctx, creds, "", "tmpfs", &vfs.MountOptions{
+		GetFilesystemOptions: vfs.GetFilesystemOptions{Data: data},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create tmpfs root mount with options %q: %v", data, err)
+	}
+	root := mntns.Root(ctx)
+	t.Cleanup(func() {
+		root.DecRef(ctx)
+		mntns.DecRef(ctx)
+	})
+	return vfsObj, root
+}
+
+func mkdir(ctx context.Context, vfsObj *vfs.VirtualFilesystem, root vfs.VirtualDentry, name string) error {
+	return vfsObj.MkdirAt(ctx, auth.CredentialsFromContext(ctx), &vfs.PathOperation{
+		Root:  root,
+		Start: root,
+		Path:  fspath.Parse(name),
+	}, &vfs.MkdirOptions{Mode: 0755})
+}
+
+func TestInodeLimit(t *testing.T) {
+	ctx := contexttest.Context(t)
+	creds := auth.CredentialsFromContext(ctx)
+	vfsObj, root := newTmpfsRootWithOptions(t, ctx, "nr_inodes=3")
+	pop := &vfs.PathOperation{Root: root, Start: root}
+
+	// The root directory uses one inode.
+	for _, name := range []string{"a", "b"} {
+		if err := mkdir(ctx, vfsObj, root, name); err != nil {
+			t.Fatalf("mkdir %q: %v", name, err)
+		}
+	}
+	if err := mkdir(ctx, vfsObj, root, "c"); !linuxerr.Equals(linuxerr.ENOSPC, err) {
+		t.Errorf("mkdir beyond nr_inodes: got error %v, want ENOSPC", err)
+	}
+	statfs, err := vfsObj.StatFSAt(ctx, creds, pop)
+	if err != nil {
+		t.Fatalf("StatFSAt: %v", err)
+	}
+	if statfs.Files != 3 || statfs.FilesFree != 0 {
+		t.Errorf("statfs: got Files=%d FilesFree=%d, want Files=3 FilesFree=0", statfs.Files, statfs.FilesFree)
+	}
+
+	// Removing a file frees its inode.
+	if err := vfsObj.RmdirAt(ctx, creds, &vfs.PathOperation{Root: root, Start: root, Path: fspath.Parse("b")}); err != nil {
+		t.Fatalf("rmdir: %v", err)
+	}
+	statfs, err = vfsObj.StatFSAt(ctx, creds, pop)
+	if err != nil {
+		t.Fatalf("StatFSAt: %v", err)
+	}
+	if statfs.FilesFree != 1 {
+		t.Errorf("statfs after rmdir: got FilesFree=%d, want 1", statfs.FilesFree)
+	}
+	if err := mkdir(ctx, vfsObj, root, "c"); err != nil {
+		t.Errorf("mkdir after rmdir: %v", err)
+	}
+}
+
+func TestUserQuotaInodeLimit(t *testing.T) {
+	ctx := contexttest.Context(t)
+	creds := auth.CredentialsFromContext(ctx)
+	vfsObj, root := newTmpfsRootWithOptions(t, ctx, "usrquota,usrquota_inode_hardlimit=2")
+	qfs := root.Mount().Filesystem().Impl().(vfs.QuotaFilesystemImpl)
+	kuid := uint32(creds.EffectiveKUID)
+
+	// The root directory is owned by the mounter, and is charged to its quota.
+	if err := mkdir(ctx, vfsObj, root, "a"); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := mkdir(ctx, vfsObj, root, "b"); !linuxerr.Equals(linuxerr.EDQUOT, err) {
+		t.Errorf("mkdir beyond quota: got error %v, want EDQUOT", err)
+	}
+	dq, err := qfs.GetQuota(linux.USRQUOTA, kuid)
+	if err != nil {
+		t.Fatalf("GetQuota: %v", err)
+	}
+	if dq.CurInodes != 2 || dq.IHardLimit != 2 {
+		t.Errorf("GetQuota: got CurInodes=%d IHardLimit=%d, want 2 and 2", dq.CurInodes, dq.IHardLimit)
+	}
+	if _, err := qfs.GetQuota(linux.GRPQUOTA, kuid); !linuxerr.Equals(linuxerr.ESRCH, err) {
+		t.Errorf("GetQuota for disabled group quotas: got error %v, want ESRCH", err)
+	}
+
+	// Raising the limit permits more inodes.
+	if err := qfs.SetQuota(linux.USRQUOTA, kuid, &linux.IfDqblk{IHardLimit: 3, Valid: linux.QIF_ILIMITS}); err != nil {
+		t.Fatalf("SetQuota: %v", err)
+	}
+	if err := mkdir(ctx, vfsObj, root, "b"); err != nil {
+		t.Errorf("mkdir after raising quota: %v", err)
+	}
+	next, err := qfs.GetNextQuota(linux.USRQUOTA, 0)
+	if err != nil {
+		t.Fatalf("GetNextQuota: %v", err)
+	}
+	if next.ID != kuid || next.CurInodes != 3 {
+		t.Errorf("GetNextQuota: got ID=%d CurInodes=%d, want ID=%d CurInodes=3", next.ID, next.CurInodes, kuid)
+	}
+}
+
+func TestQuotaAllowance(t *testing.T) {
+	const now = 1000
+	for _, test := range []struct {
+		name      string
+		cur, want uint64
+		hard      uint64
+		soft      uint64
+		graceEnd  int64
+		allowance uint64
+	}{
+		{name: "unlimited", cur: 10, want: 5, allowance: 5},
+		{name: "under hard limit", cur: 10, want: 5, hard: 20, allowance: 5},
+		{name: "at hard limit", cur: 20, want: 5, hard: 20, allowance: 0},
+		{name: "partially over hard limit", cur: 18, want: 5, hard: 20, allowance: 2},
+		{name: "over soft limit in grace period", cur: 18, want: 5, soft: 10, graceEnd: now + 1, allowance: 5},
+		{name: "over soft limit after grace period", cur: 18, want: 5, soft: 10, graceEnd: now, allowance: 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := quotaAllowance(test.cur, test.want, 1, test.hard, test.soft, test.graceEnd, now); got != test.allowance {
+				t.Errorf("quotaAllowance: got %d, want %d", got, test.allowance)
+			}
+		})
+	}
+}
@@ -89,7 +89,8 @@ type regularFile struct {
 	// alignment padding.
 	initiallyUnlinked bool
 
-	// huge is true if pages in this file may be hugepage-backed.
+	// huge is true if pages in this file may be hugepage-backed regardless of
+	// the filesystem's hugepage policy. huge is immutable.
 	huge bool
 
 	// size is the size of data.
@@ -237,7 +238,7 @@ func (rf *regularFile) truncateLocked(newSize uint64) (bool, error) {
 	rf.dataMu.Lock()
 	decPages := rf.data.Truncate(newSize, rf.inode.fs.mf)
 	rf.dataMu.Unlock()
-	rf.inode.fs.unaccountPages(decPages)
+	rf.inode.unaccountPages(decPages)
 	return true, nil
 }
 
@@ -287,6 +288,44 @@ func (rf *regularFile) RemoveMapping(ctx context.Context, ms memmap.MappingSpace
 	}
 }
 
+// mayHuge returns true if pages in rf before offset end may be
+// hugepage-backed, given that rf's size is size and that the pages are
+// accessed through a mapping with the given advice (HugepageAdviceNone if the
+// pages aren't accessed through a mapping).
+func (rf *regularFile) mayHuge(advice memmap.HugepageAdvice, end, size uint64) bool {
+	if !rf.inode.fs.mf.HugepagesEnabled() {
+		return false
+	}
+	if rf.huge {
+		return true
+	}
+	if advice == memmap.HugepageAdviceNoHuge {
+		return false
+	}
+	switch rf.inode.fs.huge {
+	case hugeAlways:
+		return true
+	case hugeWithinSize:
+		// The huge page containing end must lie entirely within size. Compare
+		// Linux's mm/shmem.c:shmem_huge_global_enabled().
+		hugeEnd, ok := hostarch.HugePageRoundUp(end)
+		if !ok {
+			return false
+		}
+		pgSize, ok := hostarch.PageRoundUp(size)
+		return ok && hugeEnd <= pgSize
+	case hugeAdvise:
+		return advice == memmap.HugepageAdviceHuge
+	default:
+		return false
+	}
+}
+
+// MayHugepage implements memmap.HugepageMappable.MayHugepage.
+func (rf *regularFile) MayHugepage() bool {
+	return rf.inode.fs.mf.HugepagesEnabled() && (rf.huge || rf.inode.fs.huge != hugeNever)
+}
+
 // CopyMapping implements memmap.Mappable.CopyMapping.
 func (rf *regularFile) CopyMapping(ctx context.Context, ms memmap.MappingSpace, srcAR, dstAR hostarch.AddrRange, offset uint64, writable bool) error {
 	return rf.AddMapping(ctx, ms, dstAR, offset, writable)
@@ -295,14 +334,14 @@ func (rf *regularFile) CopyMapping(ctx context.Context, ms memmap.MappingSpace,
 // Translate implements memmap.Mappable.Translate.
 func (rf *regularFile) Translate(ctx context.Context, required, optional memmap.MappableRange, at hostarch.AccessType) ([]memmap.Translation, error) {
 	memCgID := pgalloc.MemoryCgroupIDFromContext(ctx)
-	mayHuge := rf.huge && rf.inode.fs.mf.HugepagesEnabled()
 
 	rf.dataMu.Lock()
 	defer rf.dataMu.Unlock()
 
 	// Constrain translations to f.attr.Size (rounded up) to prevent
 	// translation to pages that may be concurrently truncated.
-	pgend := offsetPageEnd(int64(rf.size.RacyLoad()))
+	size := rf.size.RacyLoad()
+	pgend := offsetPageEnd(int64(size))
 	var beyondEOF bool
 	if required.End > pgend {
 		if required.Start >= pgend {
@@ -314,6 +353,8 @@ func (rf *regularFile) Translate(ctx context.Context, required, optional memmap.
 	if optional.End > pgend {
 		optional.End = pgend
 	}
+	mayHuge := rf.mayHuge(memmap.HugepageAdviceFromContext(ctx), optional.End, size)
+	maxOptional := optional
 	// Constrain allocation to at most maxOptionalBytes or required.Length(),
 	// whichever is greater.
 	const maxOptionalBytes = 64 << 10 // 64 KB, arbitrarily matches Linux's default fault_around_pages
@@ -327,14 +368,26 @@ func (rf *regularFile) Translate(ctx context.Context, required, optional memmap.
 			}
 		}
 	}
+	// If the file may be hugepage-backed, allocate whole huge pages containing
+	// required where possible. Compare Linux's mm/shmem.c:shmem_fault() =>
+	// shmem_get_folio_gfp().
+	if mayHuge {
+		hugeStart := hostarch.HugePageRoundDown(required.Start)
+		if hugeEnd, ok := hostarch.HugePageRoundUp(required.End); ok {
+			hugeMR := memmap.MappableRange{hugeStart, hugeEnd}
+			if maxOptional.IsSupersetOf(hugeMR) {
+				optional = hugeMR
+			}
+		}
+	}
 	pagesToFill := rf.data.PagesToFill(required, optional)
-	if !rf.inode.fs.accountPages(pagesToFill) {
+	if rf.inode.accountPages(pagesToFill) != nil {
 		// If we can not accommodate pagesToFill pages, then retry with just
 		// the required range. Because optional may be larger than required.
 		// Only error out if even the required range can not be allocated for.
 		pagesToFill = rf.data.PagesToFill(required, required)
-		if !rf.inode.fs.accountPages(pagesToFill) {
-			return nil, &memmap.BusError{linuxerr.ENOSPC}
+		if err := rf.inode.accountPages(pagesToFill); err != nil {
+			return nil, &memmap.BusError{err}
 		}
 		optional = required
 	}
@@ -345,7 +398,7 @@ func (rf *regularFile) Translate(ctx context.Context, required, optional memmap.
 	}, nil)
 	// rf.data.Fill() may fail mid-way. We still want to account any pages that
 	// were allocated, irrespective of an error.
-	rf.inode.fs.adjustPageAcct(pagesToFill, pagesAlloced)
+	rf.inode.adjustPageAcct(pagesToFill, pagesAlloced)
 
 	var ts []memmap.Translation
 	var translatedEnd uint64
@@ -399,6 +452,27 @@ func (fd *regularFileFD) Allocate(ctx context.Context, mode, offset, length uint
 	// To be consistent with Linux, inode.mu must be locked throughout.
 	f.inode.mu.Lock()
 	defer f.inode.mu.Unlock()
+
+	// Linux's mm/shmem.c:shmem_fallocate() only supports FALLOC_FL_KEEP_SIZE
+	// and FALLOC_FL_PUNCH_HOLE. We also support the modes that ext4 and xfs
+	// do, since they are straightforward for files stored in a FileRangeSet.
+	switch {
+	case mode&^(linux.FALLOC_FL_KEEP_SIZE|linux.FALLOC_FL_PUNCH_HOLE|linux.FALLOC_FL_ZERO_RANGE|linux.FALLOC_FL_COLLAPSE_RANGE|linux.FALLOC_FL_INSERT_RANGE) != 0:
+		return linuxerr.EOPNOTSUPP
+	case mode&linux.FALLOC_FL_PUNCH_HOLE != 0:
+		return f.punchHoleLocked(offset, length)
+	case mode&linux.FALLOC_FL_COLLAPSE_RANGE != 0:
+		return f.collapseRangeLocked(offset, length)
+	case mode&linux.FALLOC_FL_INSERT_RANGE != 0:
+		return f.insertRangeLocked(offset, length)
+	case mode&linux.FALLOC_FL_ZERO_RANGE != 0:
+		// Zeroing a range is equivalent to punching a hole and then
+		// allocating it again.
+		if err := f.punchHoleLocked(offset, length); err != nil {
+			return err
+		}
+	}
+
 	end := offset + length
 	pgEnd, ok := hostarch.PageRoundUp(end)
 	if !ok {
@@ -448,8 +522,8 @@ func (rf *regularFile) allocateLocked(ctx context.Context, mode, newSize uint64,
 	// specified by offset and len are guaranteed not to fail because of
 	// lack of disk space."  - fallocate(2)
 	pagesToFill := rf.data.PagesToFill(required, required)
-	if !rf.inode.fs.accountPages(pagesToFill) {
-		return linuxerr.ENOSPC
+	if err := rf.inode.accountPages(pagesToFill); err != nil {
+		return err
 	}
 	// Given our definitions in pgalloc, fallocate(2) semantics imply that pages
 	// in the MemoryFile must be committed, in addition to being allocated.
@@ -467,22 +541,137 @@ func (rf *regularFile) allocateLocked(ctx context.Context, mode, newSize uint64,
 		Kind:    rf.memoryUsageKind,
 		MemCgID: memCgID,
 		Mode:    allocMode,
-		Huge:    rf.huge && rf.inode.fs.mf.HugepagesEnabled(),
+		Huge:    rf.mayHuge(memmap.HugepageAdviceNone, required.End, newSize),
 	}, nil /* r */)
 	// f.data.Fill() may fail mid-way. We still want to account any pages that
 	// were allocated, irrespective of an error.
-	rf.inode.fs.adjustPageAcct(pagesToFill, pagesAlloced)
+	rf.inode.adjustPageAcct(pagesToFill, pagesAlloced)
 	if err != nil && err != io.EOF {
 		return err
 	}
 
 	oldSize := rf.size.Load()
-	if oldSize >= newSize {
+	if mode&linux.FALLOC_FL_KEEP_SIZE != 0 || oldSize >= newSize {
 		return nil
 	}
 	return rf.growLocked(newSize)
 }
 
+// punchHoleLocked deallocates the given range of the file, as for
+// fallocate(FALLOC_FL_PUNCH_HOLE). Subsequent reads of the range return
+// zeroes.
+//
+// Preconditions: rf.inode.mu must be locked.
+func (rf *regularFile) punchHoleLocked(offset, length uint64) error {
+	mr := memmap.MappableRange{offset, offset + length}
+	rf.dataMu.Lock()
+	if rf.seals&linux.F_SEAL_WRITE != 0 {
+		rf.dataMu.Unlock()
+		return linuxerr.EPERM
+	}
+	pagesFreed, err := rf.data.PunchHole(mr, rf.inode.fs.mf)
+	rf.dataMu.Unlock()
+	rf.inode.unaccountPages(pagesFreed)
+	if err != nil {
+		return err
+	}
+
+	// Invalidate past translations of freed pages. Bytes in partially
+	// covered pages were zeroed in place. Since mappings hold their own
+	// references on translated pages, it is safe to do this after freeing
+	// the pages. Compare Linux's mm/shmem.c:shmem_fallocate() =>
+	// mm/memory.c:unmap_mapping_range(evencows=0).
+	if pgStart, ok := hostarch.PageRoundUp(offset); ok {
+		if pgEnd := hostarch.PageRoundDown(mr.End); pgStart < pgEnd {
+			rf.mapsMu.Lock()
+			rf.mappings.Invalidate(memmap.MappableRange{pgStart, pgEnd}, memmap.InvalidateOpts{})
+			rf.mapsMu.Unlock()
+		}
+	}
+	rf.inode.touchCMtimeLocked()
+	return nil
+}
+
+// collapseRangeLocked removes the given range from the file, as for
+// fallocate(FALLOC_FL_COLLAPSE_RANGE). Data after the range is moved to
+// offset, and the file shrinks by length.
+//
+// Preconditions: rf.inode.mu must be locked.
+func (rf *regularFile) collapseRangeLocked(offset, length uint64) error {
+	if hostarch.PageRoundDown(offset) != offset || hostarch.PageRoundDown(length) != length {
+		return linuxerr.EINVAL
+	}
+	rf.dataMu.Lock()
+	if rf.seals&(linux.F_SEAL_WRITE|linux.F_SEAL_SHRINK) != 0 {
+		rf.dataMu.Unlock()
+		return linuxerr.EPERM
+	}
+	oldSize := rf.size.RacyLoad()
+	// The range may not reach the end of the file; ftruncate(2) should be
+	// used instead.
+	if offset+length >= oldSize {
+		rf.dataMu.Unlock()
+		return linuxerr.EINVAL
+	}
+	pagesFreed := rf.data.Collapse(memmap.MappableRange{offset, offset + length}, rf.inode.fs.mf)
+	rf.size.Store(oldSize - length)
+	rf.dataMu.Unlock()
+	rf.inode.unaccountPages(pagesFreed)
+	rf.invalidateShiftedLocked(offset, oldSize)
+	rf.inode.touchCMtimeLocked()
+	return nil
+}
+
+// insertRangeLocked inserts a hole of the given length at offset, as for
+// fallocate(FALLOC_FL_INSERT_RANGE). Data at or after offset is moved to
+// offset+length, and the file grows by length.
+//
+// Preconditions: rf.inode.mu must be locked.
+func (rf *regularFile) insertRangeLocked(offset, length uint64) error {
+	if hostarch.PageRoundDown(offset) != offset || hostarch.PageRoundDown(length) != length {
+		return linuxerr.EINVAL
+	}
+	rf.dataMu.Lock()
+	if rf.seals&(linux.F_SEAL_WRITE|linux.F_SEAL_GROW) != 0 {
+		rf.dataMu.Unlock()
+		return linuxerr.EPERM
+	}
+	oldSize := rf.size.RacyLoad()
+	if offset >= oldSize {
+		rf.dataMu.Unlock()
+		return linuxerr.EINVAL
+	}
+	if length > math.MaxInt64-oldSize {
+		rf.dataMu.Unlock()
+		return linuxerr.EFBIG
+	}
+	rf.data.Expand(offset, length)
+	rf.size.Store(oldSize + length)
+	rf.dataMu.Unlock()
+	rf.invalidateShiftedLocked(offset, oldSize)
+	rf.inode.touchCMtimeLocked()
+	return nil
+}
+
+// invalidateShiftedLocked invalidates past translations of file offsets
+// between offset and oldSize, whose contents have moved. Compare Linux's
+// fs/ext4/extents.c:ext4_collapse_range() => truncate_pagecache().
+//
+// Preconditions:
+//   - rf.inode.mu must be locked.
+//   - offset must be page-aligned.
+func (rf *regularFile) invalidateShiftedLocked(offset, oldSize uint64) {
+	oldpgend := offsetPageEnd(int64(oldSize))
+	if offset >= oldpgend {
+		return
+	}
+	rf.mapsMu.Lock()
+	rf.mappings.Invalidate(memmap.MappableRange{offset, oldpgend}, memmap.InvalidateOpts{
+		InvalidatePrivate: true,
+	})
+	rf.mapsMu.Unlock()
+}
+
 // PRead implements vfs.FileDescriptionImpl.PRead.
 func (fd *regularFileFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
 	start := fsmetric.StartReadWait()
@@ -773,7 +962,9 @@ func (rw *regularFileReadWriter) WriteFromBlocks(srcs safemem.BlockSeq) (uint64,
 	pgendaddr, _ := hostarch.Addr(end).RoundUp()
 	pgMR := memmap.MappableRange{uint64(pgstartaddr), uint64(pgendaddr)}
 	fs := rw.file.inode.fs
-	mayHuge := rw.file.huge && fs.mf.HugepagesEnabled()
+	// Pages allocated for the write are within the file's size after the
+	// write.
+	mayHuge := rw.file.mayHuge(memmap.HugepageAdviceNone, pgMR.End, max(rw.file.size.RacyLoad(), uint64(end)))
 
 	var (
 		done   uint64
@@ -800,10 +991,10 @@ func (rw *regularFileReadWriter) WriteFromBlocks(srcs safemem.BlockSeq) (uint64,
 			// Allocate memory for the write.
 			gapMR := gap.Range().Intersect(pgMR)
 			pagesToFill := gapMR.Length() / hostarch.PageSize
-			pagesReserved := fs.accountPagesPartial(pagesToFill)
+			pagesReserved, err := rw.file.inode.accountPagesPartial(pagesToFill)
 			if pagesReserved == 0 {
 				if done == 0 {
-					retErr = linuxerr.ENOSPC
+					retErr = err
 					goto exitLoop
 				}
 				retErr = nil
@@ -832,7 +1023,7 @@ func (rw *regularFileReadWriter) WriteFromBlocks(srcs safemem.BlockSeq) (uint64,
 			})
 			if err != nil {
 				retErr = err
-				fs.unaccountPages(pagesReserved)
+				rw.file.inode.unaccountPages(pagesReserved)
 				goto exitLoop
 			}
 
@@ -26,13 +26,22 @@ type symlink struct {
 	target string // immutable
 }
 
-func (fs *filesystem) newSymlink(kuid auth.KUID, kgid auth.KGID, mode linux.FileMode, target string, parentDir *directory) *inode {
+func (fs *filesystem) newSymlink(kuid auth.KUID, kgid auth.KGID, mode linux.FileMode, target string, parentDir *directory) (*inode, error) {
 	link := &symlink{
 		target: target,
 	}
 	link.inode.init(link, fs, kuid, kgid, linux.S_IFLNK|mode, parentDir)
+	// Linux allocates a page to store symlink targets that have length larger
+	// than shortSymlinkLen. Targets are just stored as string here, but simulate
+	// the page accounting for it. See mm/shmem.c:shmem_symlink().
+	if len(target) >= shortSymlinkLen {
+		if err := link.inode.accountPages(1); err != nil {
+			fs.releaseInode(&link.inode)
+			return nil, err
+		}
+	}
 	link.inode.nlink = atomicbitops.FromUint32(1) // from parent directory
-	return &link.inode
+	return &link.inode, nil
 }
 
 // O_PATH is unimplemented, so there's no way to get a FileDescription
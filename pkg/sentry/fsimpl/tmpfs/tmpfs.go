@@ -25,6 +25,7 @@
 //		      *** "memmap.Mappable locks taken by Translate" below this point
 //		      regularFile.dataMu
 //		        fs.pagesUsedMu
+//		          fs.quotas.mu
 //		    filesystem.ancestryMu
 //		  directory.iterMu
 package tmpfs
@@ -102,6 +103,20 @@ type filesystem struct {
 	// pagesUsed is the number of pages used by this filesystem.
 	pagesUsed atomicbitops.Uint64
 
+	// maxInodes is the maximum number of inodes in the filesystem, or 0 if
+	// the number of inodes is unlimited. maxInodes is immutable.
+	maxInodes uint64
+
+	// inodesUsed is the number of inodes in the filesystem.
+	inodesUsed atomicbitops.Uint64
+
+	// quotas are the filesystem's user and group quotas.
+	quotas quotas
+
+	// huge is the filesystem's transparent hugepage policy. huge is
+	// immutable.
+	huge hugePolicy
+
 	// allowXattrPrefix is a set of xattr namespace prefixes that this
 	// tmpfs mount will allow. It is immutable.
 	allowXattrPrefix map[string]struct{}
@@ -279,6 +294,70 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		}
 	}
 
+	// As in Linux, the default inode limit is the number of pages in the
+	// default size limit.
+	maxInodes := getDefaultSizeLimit(disableDefaultSizeLimit) / hostarch.PageSize
+	maxInodesStr, ok := mopts["nr_inodes"]
+	if ok {
+		delete(mopts, "nr_inodes")
+		var err error
+		maxInodes, err = parseSize(maxInodesStr)
+		if err != nil {
+			ctx.Warningf("tmpfs.FilesystemType.GetFilesystem: invalid nr_inodes: %q", maxInodesStr)
+			return nil, nil, linuxerr.EINVAL
+		}
+	}
+	// tmpfs is never swapped out, so noswap is always in effect.
+	delete(mopts, "noswap")
+	huge := hugeNever
+	hugeStr, ok := mopts["huge"]
+	if ok {
+		delete(mopts, "huge")
+		var err error
+		huge, err = parseHugePolicy(hugeStr)
+		if err != nil {
+			ctx.Warningf("tmpfs.FilesystemType.GetFilesystem: invalid huge: %q", hugeStr)
+			return nil, nil, err
+		}
+	}
+	var quotaSets [numQuotaTypes]*quotaSet
+	if _, ok := mopts["quota"]; ok {
+		delete(mopts, "quota")
+		quotaSets[linux.USRQUOTA] = newQuotaSet(0, 0)
+		quotaSets[linux.GRPQUOTA] = newQuotaSet(0, 0)
+	}
+	for qtype, name := range []string{linux.USRQUOTA: "usrquota", linux.GRPQUOTA: "grpquota"} {
+		if _, ok := mopts[name]; ok {
+			delete(mopts, name)
+			quotaSets[qtype] = newQuotaSet(0, 0)
+		}
+		for _, limit := range []string{"block", "inode"} {
+			opt := name + "_" + limit + "_hardlimit"
+			str, ok := mopts[opt]
+			if !ok {
+				continue
+			}
+			delete(mopts, opt)
+			val, err := parseSize(str)
+			if err != nil || quotaSets[qtype] == nil {
+				ctx.Warningf("tmpfs.FilesystemType.GetFilesystem: invalid %s: %q", opt, str)
+				return nil, nil, linuxerr.EINVAL
+			}
+			if limit == "block" {
+				quotaSets[qtype].defaults.bHardLimit = val
+			} else {
+				quotaSets[qtype].defaults.iHardLimit = val
+			}
+		}
+	}
+
+	// As in Linux, quotas can only be enabled by the root user namespace,
+	// which quotactl(2) relies on for its permission checks.
+	if (quotaSets[linux.USRQUOTA] != nil || quotaSets[linux.GRPQUOTA] != nil) && creds.UserNamespace != creds.UserNamespace.Root() {
+		ctx.Warningf("tmpfs.FilesystemType.GetFilesystem: quotas in unprivileged tmpfs mounts are unsupported")
+		return nil, nil, linuxerr.EINVAL
+	}
+
 	if len(mopts) != 0 {
 		ctx.Warningf("tmpfs.FilesystemType.GetFilesystem: unknown options: %v", mopts)
 		return nil, nil, linuxerr.EINVAL
@@ -301,8 +380,11 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		usage:            memUsage,
 		maxFilenameLen:   linux.NAME_MAX,
 		maxSizeInPages:   maxSizeInPages,
+		maxInodes:        maxInodes,
+		huge:             huge,
 		allowXattrPrefix: allowXattrPrefix,
 	}
+	fs.quotas.sets = quotaSets
 	fs.vfsfs.Init(vfsObj, newFSType, &fs)
 	if tmpfsOptsOk && tmpfsOpts.MaxFilenameLen > 0 {
 		fs.maxFilenameLen = tmpfsOpts.MaxFilenameLen
@@ -313,7 +395,12 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	case linux.S_IFREG:
 		root = fs.newDentry(fs.newRegularFile(rootKUID, rootKGID, rootMode, nil /* parentDir */))
 	case linux.S_IFLNK:
-		root = fs.newDentry(fs.newSymlink(rootKUID, rootKGID, rootMode, tmpfsOpts.RootSymlinkTarget, nil /* parentDir */))
+		inode, err := fs.newSymlink(rootKUID, rootKGID, rootMode, tmpfsOpts.RootSymlinkTarget, nil /* parentDir */)
+		if err != nil {
+			fs.vfsfs.DecRef(ctx)
+			return nil, nil, err
+		}
+		root = fs.newDentry(inode)
 	case linux.S_IFDIR:
 		root = &fs.newDirectory(rootKUID, rootKGID, rootMode, nil /* parentDir */).dentry
 	default:
@@ -381,6 +468,12 @@ func (fs *filesystem) statFS() linux.Statfs {
 	pagesUsed := fs.pagesUsed.Load()
 	st.BlocksFree = fs.maxSizeInPages - pagesUsed
 	st.BlocksAvailable = fs.maxSizeInPages - pagesUsed
+
+	// As in Linux, an unlimited number of inodes is reported as 0.
+	if fs.maxInodes != 0 {
+		st.Files = fs.maxInodes
+		st.FilesFree = fs.maxInodes - min(fs.inodesUsed.Load(), fs.maxInodes)
+	}
 	return st
 }
 
@@ -499,6 +592,10 @@ type inode struct {
 
 	locks vfs.FileLocks
 
+	// quotaPages is the number of pages charged to the quotas of the inode's
+	// owners. quotaPages is protected by fs.quotas.mu.
+	quotaPages uint64
+
 	// Inotify watches for this inode.
 	watches vfs.Watches
 
@@ -513,11 +610,9 @@ func (i *inode) init(impl any, fs *filesystem, kuid auth.KUID, kgid auth.KGID, m
 	}
 
 	// Inherit the group and setgid bit as in fs/inode.c:inode_init_owner().
-	if parentDir != nil && parentDir.inode.mode.Load()&linux.S_ISGID == linux.S_ISGID {
-		kgid = auth.KGID(parentDir.inode.gid.Load())
-		if mode&linux.S_IFDIR == linux.S_IFDIR {
-			mode |= linux.S_ISGID
-		}
+	kgid = inheritedKGID(kgid, parentDir)
+	if parentDir != nil && parentDir.inode.mode.Load()&linux.S_ISGID == linux.S_ISGID && mode&linux.S_IFDIR == linux.S_IFDIR {
+		mode |= linux.S_ISGID
 	}
 
 	i.fs = fs
@@ -533,6 +628,16 @@ func (i *inode) init(impl any, fs *filesystem, kuid auth.KUID, kgid auth.KGID, m
 	// i.nlink initialized by caller
 	i.impl = impl
 	i.refs.InitRefs()
+	fs.chargeInode(i)
+}
+
+// inheritedKGID returns the group of a new inode created by a task with
+// effective group kgid in parentDir.
+func inheritedKGID(kgid auth.KGID, parentDir *directory) auth.KGID {
+	if parentDir != nil && parentDir.inode.mode.Load()&linux.S_ISGID == linux.S_ISGID {
+		return auth.KGID(parentDir.inode.gid.Load())
+	}
+	return kgid
 }
 
 // incLinksLocked increments i's link count.
@@ -581,15 +686,16 @@ func (i *inode) decRef(ctx context.Context) {
 		switch impl := i.impl.(type) {
 		case *symlink:
 			if len(impl.target) >= shortSymlinkLen {
-				impl.inode.fs.unaccountPages(1)
+				i.unaccountPages(1)
 			}
 		case *regularFile:
 			// Release memory used by regFile to store data. Since regFile is
 			// no longer usable, we don't need to grab any locks or update any
 			// metadata.
 			pagesDec := impl.data.DropAll(i.fs.mf)
-			impl.inode.fs.unaccountPages(pagesDec)
+			i.unaccountPages(pagesDec)
 		}
+		i.fs.releaseInode(i)
 
 	})
 }
@@ -665,6 +771,22 @@ func (i *inode) setStat(ctx context.Context, creds *auth.Credentials, opts *vfs.
 	)
 	clearSID := false
 	mask := stat.Mask
+	// Change ownership first, since it may fail if the new owners' quotas are
+	// exceeded.
+	if mask&(linux.STATX_UID|linux.STATX_GID) != 0 {
+		kuid, kgid := auth.KUID(i.uid.Load()), auth.KGID(i.gid.Load())
+		if mask&linux.STATX_UID != 0 {
+			kuid = auth.KUID(stat.UID)
+		}
+		if mask&linux.STATX_GID != 0 {
+			kgid = auth.KGID(stat.GID)
+		}
+		if err := i.setOwner(kuid, kgid); err != nil {
+			return err
+		}
+		needsCtimeBump = true
+		clearSID = true
+	}
 	if mask&linux.STATX_SIZE != 0 {
 		switch impl := i.impl.(type) {
 		case *regularFile:
@@ -683,16 +805,6 @@ func (i *inode) setStat(ctx context.Context, creds *auth.Credentials, opts *vfs.
 			return linuxerr.EINVAL
 		}
 	}
-	if mask&linux.STATX_UID != 0 {
-		i.uid.Store(stat.UID)
-		needsCtimeBump = true
-		clearSID = true
-	}
-	if mask&linux.STATX_GID != 0 {
-		i.gid.Store(stat.GID)
-		needsCtimeBump = true
-		clearSID = true
-	}
 	if mask&linux.STATX_MODE != 0 {
 		for {
 			old := i.mode.Load()
@@ -957,6 +1069,42 @@ func (*fileDescription) Sync(context.Context) error {
 	return nil
 }
 
+// hugePolicy is a tmpfs transparent hugepage policy, as set by the huge mount
+// option. Compare Linux's mm/shmem.c:shmem_huge.
+type hugePolicy uint8
+
+const (
+	// hugeNever never backs files with huge pages.
+	hugeNever hugePolicy = iota
+
+	// hugeAlways backs hugepage-aligned file ranges with huge pages.
+	hugeAlways
+
+	// hugeWithinSize is like hugeAlways, but only for huge pages that lie
+	// entirely within the file's size.
+	hugeWithinSize
+
+	// hugeAdvise is like hugeAlways, but only for mappings with
+	// madvise(MADV_HUGEPAGE).
+	hugeAdvise
+)
+
+// parseHugePolicy parses the value of the huge mount option.
+func parseHugePolicy(s string) (hugePolicy, error) {
+	switch s {
+	case "never":
+		return hugeNever, nil
+	case "always":
+		return hugeAlways, nil
+	case "within_size":
+		return hugeWithinSize, nil
+	case "advise":
+		return hugeAdvise, nil
+	default:
+		return hugeNever, linuxerr.EINVAL
+	}
+}
+
 // parseSize converts size in string to an integer bytes.
 // Supported suffixes in string are:K, M, G, T, P, E.
 func parseSize(s string) (uint64, error) {
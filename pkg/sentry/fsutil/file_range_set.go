@@ -257,3 +257,97 @@ func (s *FileRangeSet) Truncate(end uint64, mf *pgalloc.MemoryFile) uint64 {
 	}
 	return pagesFreed
 }
+
+// PunchHole updates s to reflect deallocation of the Mappable offsets in mr,
+// as for fallocate(FALLOC_FL_PUNCH_HOLE): pages that lie entirely within mr
+// are freed, and other bytes in mr are zeroed. It returns the number of pages
+// freed.
+func (s *FileRangeSet) PunchHole(mr memmap.MappableRange, mf *pgalloc.MemoryFile) (uint64, error) {
+	pgStart, ok := hostarch.Addr(mr.Start).RoundUp()
+	pgEnd := hostarch.PageRoundDown(mr.End)
+	if !ok || uint64(pgStart) >= pgEnd {
+		// mr doesn't contain any whole pages.
+		return 0, s.zeroRange(mr, mf)
+	}
+	if err := s.zeroRange(memmap.MappableRange{mr.Start, uint64(pgStart)}, mf); err != nil {
+		return 0, err
+	}
+	if err := s.zeroRange(memmap.MappableRange{pgEnd, mr.End}, mf); err != nil {
+		return 0, err
+	}
+	var pagesFreed uint64
+	s.RemoveRangeWith(memmap.MappableRange{uint64(pgStart), pgEnd}, func(seg FileRangeIterator) {
+		mf.DecRef(seg.FileRange())
+		pagesFreed += seg.Range().Length() / hostarch.PageSize
+	})
+	return pagesFreed, nil
+}
+
+// zeroRange zeroes the bytes stored for Mappable offsets in mr.
+func (s *FileRangeSet) zeroRange(mr memmap.MappableRange, mf *pgalloc.MemoryFile) error {
+	if mr.Length() == 0 {
+		return nil
+	}
+	for seg := s.LowerBoundSegment(mr.Start); seg.Ok() && seg.Start() < mr.End; seg = seg.NextSegment() {
+		ims, err := mf.MapInternal(seg.FileRangeOf(seg.Range().Intersect(mr)), hostarch.Write)
+		if err != nil {
+			return err
+		}
+		if _, err := safemem.ZeroSeq(ims); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Collapse updates s to reflect removal of the Mappable offsets in mr, as for
+// fallocate(FALLOC_FL_COLLAPSE_RANGE): pages in mr are freed, and offsets
+// after mr are shifted down by mr.Length(). It returns the number of pages
+// freed.
+//
+// Preconditions: mr must be page-aligned.
+func (s *FileRangeSet) Collapse(mr memmap.MappableRange, mf *pgalloc.MemoryFile) uint64 {
+	var pagesFreed uint64
+	gap := s.RemoveRangeWith(mr, func(seg FileRangeIterator) {
+		mf.DecRef(seg.FileRange())
+		pagesFreed += seg.Range().Length() / hostarch.PageSize
+	})
+	// Shifting every following segment by the same amount preserves their
+	// order, and the removed range guarantees that they can't overlap
+	// preceding segments.
+	for seg := gap.NextSegment(); seg.Ok(); seg = seg.NextSegment() {
+		r := seg.Range()
+		seg.SetRangeUnchecked(memmap.MappableRange{r.Start - mr.Length(), r.End - mr.Length()})
+	}
+	// The segments on either side of the removed range may now be
+	// mergeable.
+	if gap.Ok() {
+		if prev := gap.PrevSegment(); prev.Ok() {
+			s.MergeNext(prev)
+		}
+	}
+	return pagesFreed
+}
+
+// Expand updates s to reflect insertion of length bytes of unallocated space
+// at Mappable offset at, as for fallocate(FALLOC_FL_INSERT_RANGE): offsets at
+// or after at are shifted up by length.
+//
+// Preconditions:
+//   - at and length must be page-aligned.
+//   - No segment may end after math.MaxUint64 - length.
+func (s *FileRangeSet) Expand(at, length uint64) {
+	first := s.LowerBoundSegmentSplitBefore(at)
+	if !first.Ok() {
+		return
+	}
+	// Shift segments in descending order so that each shifted segment only
+	// moves into space that has already been vacated.
+	for seg := s.LastSegment(); seg.Ok(); seg = seg.PrevSegment() {
+		r := seg.Range()
+		seg.SetRangeUnchecked(memmap.MappableRange{r.Start + length, r.End + length})
+		if seg == first {
+			break
+		}
+	}
+}
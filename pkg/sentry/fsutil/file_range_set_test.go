@@ -0,0 +1,55 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsutil
+
+import (
+	"slices"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+)
+
+const ps = hostarch.PageSize
+
+func TestFileRangeSetCollapse(t *testing.T) {
+	var set FileRangeSet
+	set.InsertWithoutMerging(set.FindGap(0), memmap.MappableRange{0, ps}, 10*ps)
+	set.InsertWithoutMerging(set.FindGap(3*ps), memmap.MappableRange{3 * ps, 5 * ps}, 11*ps)
+	// Collapsing the unallocated range between the two segments makes them
+	// adjacent in both the Mappable and the file, so they are merged.
+	if freed := set.Collapse(memmap.MappableRange{ps, 3 * ps}, nil); freed != 0 {
+		t.Errorf("Collapse: got %d pages freed, want 0", freed)
+	}
+	want := []FileRangeFlatSegment{
+		{0, 3 * ps, 10 * ps},
+	}
+	if got := set.ExportSlice(); !slices.Equal(got, want) {
+		t.Errorf("set:\n\tgot %v,\n\twant %v", got, want)
+	}
+}
+
+func TestFileRangeSetExpand(t *testing.T) {
+	var set FileRangeSet
+	set.InsertWithoutMerging(set.FindGap(0), memmap.MappableRange{0, 4 * ps}, 10*ps)
+	set.Expand(ps, 2*ps)
+	want := []FileRangeFlatSegment{
+		{0, ps, 10 * ps},
+		{3 * ps, 6 * ps, 11 * ps},
+	}
+	if got := set.ExportSlice(); !slices.Equal(got, want) {
+		t.Errorf("set:\n\tgot %v,\n\twant %v", got, want)
+	}
+}
@@ -96,6 +96,11 @@ func (ns *UserNamespace) Root() *UserNamespace {
 	return ns
 }
 
+// Parent returns the parent of ns, or nil if ns is a root user namespace.
+func (ns *UserNamespace) Parent() *UserNamespace {
+	return ns.parent
+}
+
 // "The kernel imposes (since version 3.11) a limit of 32 nested levels of user
 // namespaces." - user_namespaces(7)
 const maxUserNamespaceDepth = 32
@@ -39,6 +39,7 @@ type CgroupControllerType string
 
 // Available cgroup controllers.
 const (
+	CgroupControllerIO      = CgroupControllerType("blkio")
 	CgroupControllerCPU     = CgroupControllerType("cpu")
 	CgroupControllerCPUAcct = CgroupControllerType("cpuacct")
 	CgroupControllerCPUSet  = CgroupControllerType("cpuset")
@@ -49,11 +50,13 @@ const (
 )
 
 // CgroupCtrls is the list of cgroup controllers.
-var CgroupCtrls = []CgroupControllerType{"cpu", "cpuacct", "cpuset", "devices", "job", "memory", "pids"}
+var CgroupCtrls = []CgroupControllerType{"cpu", "cpuacct", "cpuset", "devices", "job", "memory", "pids", "blkio"}
 
 // ParseCgroupController parses a string as a CgroupControllerType.
 func ParseCgroupController(val string) (CgroupControllerType, error) {
 	switch val {
+	case "blkio":
+		return CgroupControllerIO, nil
 	case "cpu":
 		return CgroupControllerCPU, nil
 	case "cpuacct":
@@ -73,6 +76,28 @@ func ParseCgroupController(val string) (CgroupControllerType, error) {
 	}
 }
 
+// UnifiedName returns the name of the controller in the cgroup v2 unified
+// hierarchy, which differs from its cgroup v1 name for the io controller.
+func (ty CgroupControllerType) UnifiedName() string {
+	if ty == CgroupControllerIO {
+		return "io"
+	}
+	return string(ty)
+}
+
+// ParseUnifiedCgroupController parses a controller name used in the cgroup v2
+// unified hierarchy as a CgroupControllerType.
+func ParseUnifiedCgroupController(val string) (CgroupControllerType, error) {
+	switch val {
+	case "io":
+		return CgroupControllerIO, nil
+	case string(CgroupControllerIO):
+		return "", fmt.Errorf("no such cgroup controller")
+	default:
+		return ParseCgroupController(val)
+	}
+}
+
 // CgroupResourceType represents a resource type tracked by a particular
 // controller.
 type CgroupResourceType int
@@ -130,6 +155,16 @@ func (c *Cgroup) decRef() {
 	c.Dentry.DecRef(context.Background())
 }
 
+// hasController returns whether ctl is attached to c's hierarchy.
+func (c *Cgroup) hasController(ctl CgroupControllerType) bool {
+	for _, cc := range c.Controllers() {
+		if cc.Type() == ctl {
+			return true
+		}
+	}
+	return false
+}
+
 // Path returns the absolute path of c, relative to its hierarchy root.
 func (c *Cgroup) Path() string {
 	return c.FSLocalPath()
@@ -224,6 +259,10 @@ type CgroupImpl interface {
 
 	// ID returns the id of this cgroup.
 	ID() uint32
+
+	// Unified returns whether this cgroup belongs to the cgroup v2 unified
+	// hierarchy.
+	Unified() bool
 }
 
 // hierarchy represents a cgroupfs filesystem instance, with a unique set of
@@ -269,6 +308,15 @@ type cgroupFS interface {
 	// RootCgroup returns the root cgroup of this instance. This returns the
 	// actual root, and ignores any overrides setting an effective root.
 	RootCgroup() Cgroup
+
+	// EffectiveRootCgroup returns the cgroup new tasks are placed in by
+	// default. See CgroupController.EffectiveRootCgroup.
+	EffectiveRootCgroup() Cgroup
+
+	// EnforceMemoryLimits checks the memory usage of the cgroups in this
+	// instance against their memory limits, and invokes the OOM killer for
+	// cgroups over their limit.
+	EnforceMemoryLimits(ctx context.Context)
 }
 
 // CgroupRegistry tracks the active set of cgroup controllers on the system.
@@ -305,6 +353,13 @@ type CgroupRegistry struct {
 	// +checklocks:mu
 	hierarchiesByName map[string]hierarchy
 
+	// unifiedHierarchyID is the id of the cgroup v2 unified hierarchy, or
+	// InvalidCgroupHierarchyID if cgroup2 hasn't been mounted. There is at
+	// most one unified hierarchy on the system.
+	//
+	// +checklocks:mu
+	unifiedHierarchyID uint32
+
 	// cgroups is the active set of cgroups. This contains all the cgroups
 	// on the system.
 	//
@@ -361,6 +416,11 @@ func (r *CgroupRegistry) FindHierarchy(name string, ctypes []CgroupControllerTyp
 	}
 
 	for _, h := range r.hierarchies {
+		if h.id == r.unifiedHierarchyID {
+			// The unified hierarchy can only be mounted as cgroup2, see
+			// FindUnifiedHierarchy.
+			continue
+		}
 		if h.match(ctypes) {
 			if !h.fs.TryIncRef() {
 				// Racing with filesystem destruction, namely h.fs.Release.
@@ -387,6 +447,54 @@ func (r *CgroupRegistry) FindHierarchy(name string, ctypes []CgroupControllerTyp
 	return nil, nil
 }
 
+// FindUnifiedHierarchy returns the cgroup v2 unified hierarchy filesystem, or
+// nil if the unified hierarchy doesn't exist. FindUnifiedHierarchy takes a
+// reference on the returned FS, which is transferred to the caller.
+func (r *CgroupRegistry) FindUnifiedHierarchy() *vfs.Filesystem {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.hierarchies[r.unifiedHierarchyID]
+	if !ok {
+		return nil
+	}
+	if !h.fs.TryIncRef() {
+		// Racing with filesystem destruction, see FindHierarchy.
+		r.unregisterLocked(h.id)
+		return nil
+	}
+	return h.fs
+}
+
+// UnboundControllers returns the subset of ctypes that isn't attached to any
+// hierarchy yet. The result is a snapshot in time, the caller must still
+// handle failures from Register and RegisterUnified.
+func (r *CgroupRegistry) UnboundControllers(ctypes []CgroupControllerType) []CgroupControllerType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unbound []CgroupControllerType
+	for _, ty := range ctypes {
+		if _, ok := r.controllers[ty]; !ok {
+			unbound = append(unbound, ty)
+		}
+	}
+	return unbound
+}
+
+// onUnifiedHierarchy returns whether the controller ctype is attached to the
+// unified hierarchy.
+func (r *CgroupRegistry) onUnifiedHierarchy(ctype CgroupControllerType) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.hierarchies[r.unifiedHierarchyID]
+	if !ok {
+		return false
+	}
+	_, ok = h.controllers[ctype]
+	return ok
+}
+
 // FindCgroup locates a cgroup with the given parameters.
 //
 // A cgroup is considered a match even if it contains other controllers on the
@@ -401,6 +509,9 @@ func (r *CgroupRegistry) FindCgroup(ctx context.Context, ctype CgroupControllerT
 	if err != nil {
 		return Cgroup{}, err
 	}
+	if vfsfs == nil && r.onUnifiedHierarchy(ctype) {
+		vfsfs = r.FindUnifiedHierarchy()
+	}
 	if vfsfs == nil {
 		return Cgroup{}, fmt.Errorf("controller not active")
 	}
@@ -427,20 +538,46 @@ func (r *CgroupRegistry) Register(name string, cs []CgroupController, fs cgroupF
 	if name == "" && len(cs) == 0 {
 		return fmt.Errorf("can't register hierarchy with both no controllers and no name")
 	}
+	if _, ok := r.hierarchiesByName[name]; name != "" && ok {
+		return fmt.Errorf("hierarchy named %q already exists", name)
+	}
+	_, err := r.registerLocked(name, cs, fs)
+	return err
+}
+
+// RegisterUnified registers the cgroup v2 unified hierarchy with the provided
+// set of controllers. Unlike cgroup v1 hierarchies, the unified hierarchy is
+// unnamed and may have no controllers at all. RegisterUnified fails if a
+// unified hierarchy already exists, or if any controller is already attached
+// to another hierarchy.
+func (r *CgroupRegistry) RegisterUnified(cs []CgroupController, fs cgroupFS) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.hierarchies[r.unifiedHierarchyID]; ok {
+		return fmt.Errorf("unified hierarchy already exists")
+	}
+	hid, err := r.registerLocked("", cs, fs)
+	if err != nil {
+		return err
+	}
+	r.unifiedHierarchyID = hid
+	return nil
+}
 
+// registerLocked registers a new hierarchy and returns its ID.
+//
+// +checklocks:r.mu
+func (r *CgroupRegistry) registerLocked(name string, cs []CgroupController, fs cgroupFS) (uint32, error) {
 	for _, c := range cs {
 		if _, ok := r.controllers[c.Type()]; ok {
-			return fmt.Errorf("controllers may only be mounted on a single hierarchy")
+			return InvalidCgroupHierarchyID, fmt.Errorf("controllers may only be mounted on a single hierarchy")
 		}
 	}
 
-	if _, ok := r.hierarchiesByName[name]; name != "" && ok {
-		return fmt.Errorf("hierarchy named %q already exists", name)
-	}
-
 	hid, err := r.nextHierarchyID()
 	if err != nil {
-		return err
+		return InvalidCgroupHierarchyID, err
 	}
 
 	// Must not fail below here, once we publish the hierarchy ID.
@@ -462,7 +599,7 @@ func (r *CgroupRegistry) Register(name string, cs []CgroupController, fs cgroupF
 	if name != "" {
 		r.hierarchiesByName[name] = h
 	}
-	return nil
+	return hid, nil
 }
 
 // Unregister removes a previously registered hierarchy from the registry. If no
@@ -482,6 +619,9 @@ func (r *CgroupRegistry) unregisterLocked(hid uint32) {
 		}
 		delete(r.hierarchies, hid)
 	}
+	if hid == r.unifiedHierarchyID {
+		r.unifiedHierarchyID = InvalidCgroupHierarchyID
+	}
 }
 
 // computeInitialGroups takes a reference on each of the returned cgroups. The
@@ -492,6 +632,7 @@ func (r *CgroupRegistry) computeInitialGroups(inherit map[Cgroup]struct{}) map[C
 
 	ctlSet := make(map[CgroupControllerType]CgroupController)
 	cgset := make(map[Cgroup]struct{})
+	inheritUnified := false
 
 	// Remember controllers from the inherited cgroups set...
 	for cg := range inherit {
@@ -500,6 +641,12 @@ func (r *CgroupRegistry) computeInitialGroups(inherit map[Cgroup]struct{}) map[C
 			ctlSet[ctl.Type()] = ctl
 			cgset[cg] = struct{}{}
 		}
+		if cg.Unified() {
+			// The unified hierarchy may have no controllers, but every task
+			// is still a member of exactly one cgroup in it.
+			inheritUnified = true
+			cgset[cg] = struct{}{}
+		}
 	}
 
 	// ... and add the root cgroups of all the missing controllers.
@@ -515,6 +662,16 @@ func (r *CgroupRegistry) computeInitialGroups(inherit map[Cgroup]struct{}) map[C
 			cgset[cg] = struct{}{}
 		}
 	}
+
+	// ... and the unified hierarchy, which is tracked independently of its
+	// controllers.
+	if h, ok := r.hierarchies[r.unifiedHierarchyID]; ok && !inheritUnified {
+		cg := h.fs.Impl().(cgroupFS).EffectiveRootCgroup()
+		if _, ok := cgset[cg]; !ok {
+			cg.IncRef() // Ref transferred to caller.
+			cgset[cg] = struct{}{}
+		}
+	}
 	return cgset
 }
 
@@ -527,7 +684,13 @@ func (r *CgroupRegistry) GenerateProcCgroups(buf *bytes.Buffer) {
 		if c.Enabled() {
 			en = 1
 		}
-		entries = append(entries, fmt.Sprintf("%s\t%d\t%d\t%d\n", c.Type(), c.HierarchyID(), c.NumCgroups(), en))
+		// Controllers on the unified hierarchy are reported with hierarchy
+		// ID 0, as in Linux.
+		hid := c.HierarchyID()
+		if hid == r.unifiedHierarchyID {
+			hid = 0
+		}
+		entries = append(entries, fmt.Sprintf("%s\t%d\t%d\t%d\n", c.Type(), hid, c.NumCgroups(), en))
 	}
 	r.mu.Unlock()
 
@@ -25,6 +25,7 @@ import (
 	"gvisor.dev/gvisor/pkg/bitmap"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/lock"
 	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
@@ -79,6 +80,11 @@ type FDTable struct {
 
 	k *Kernel
 
+	// id uniquely identifies this table. It is used to identify the owner of
+	// POSIX locks held through this table to remote filesystems. id is
+	// immutable.
+	id uint64
+
 	// mu protects below.
 	mu fdTableMutex `state:"nosave"`
 
@@ -130,18 +136,26 @@ func (f *FDTable) fileUnlock(ctx context.Context, file *vfs.FileDescription) {
 	if file.SupportsLocks() {
 		err := file.UnlockPOSIX(ctx, f, lock.LockRange{0, lock.LockEOF})
 		if err != nil && !linuxerr.Equals(linuxerr.ENOLCK, err) {
-			panic(fmt.Sprintf("UnlockPOSIX failed: %v", err))
+			// Filesystems that forward locks to a remote server, such as FUSE,
+			// may fail to release them. There is nothing more we can do.
+			log.Warningf("UnlockPOSIX failed: %v", err)
 		}
 	}
 }
 
 // NewFDTable allocates a new FDTable that may be used by tasks in k.
 func (k *Kernel) NewFDTable() *FDTable {
-	f := &FDTable{k: k}
+	f := &FDTable{k: k, id: k.UniqueID()}
 	f.init()
 	return f
 }
 
+// ID returns an identifier that is unique to f among all FDTables in the
+// kernel.
+func (f *FDTable) ID() uint64 {
+	return f.id
+}
+
 // DecRef implements RefCounter.DecRef.
 //
 // If f reaches zero references, all of its file descriptors are removed.
@@ -0,0 +1,387 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"math"
+	"math/bits"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// ioThrottleSlice is the amount of I/O, in time at the limited rate, that may
+// be issued in a burst before it's throttled. This is analogous to Linux's
+// blk-throttle time slice.
+const ioThrottleSlice = 100 * time.Millisecond
+
+// IOStats are the I/O statistics of a device.
+//
+// +stateify savable
+type IOStats struct {
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadIOs    uint64
+	WriteIOs   uint64
+}
+
+// IOLimits are the I/O limits of a device, in bytes or operations per second.
+// A limit of 0 is unlimited.
+//
+// +stateify savable
+type IOLimits struct {
+	ReadBPS   uint64
+	WriteBPS  uint64
+	ReadIOPS  uint64
+	WriteIOPS uint64
+}
+
+// ioThrottle limits a rate of bytes or operations per second, using the
+// generic cell rate algorithm (equivalent to a token bucket holding up to
+// ioThrottleSlice worth of tokens).
+//
+// +stateify savable
+type ioThrottle struct {
+	// rate is the limit in units per second, or 0 if unlimited.
+	rate uint64
+
+	// next is the time, in nanoseconds on the application monotonic clock, at
+	// which all units charged so far would have been transferred at rate.
+	next int64
+}
+
+// setRate changes the rate of l, forgetting past charges.
+func (l *ioThrottle) setRate(rate uint64) {
+	if l.rate != rate {
+		l.rate = rate
+		l.next = 0
+	}
+}
+
+// cost returns the time it takes to transfer n units at l.rate.
+func (l *ioThrottle) cost(n uint64) int64 {
+	hi, lo := bits.Mul64(n, uint64(time.Second))
+	if hi >= l.rate {
+		return math.MaxInt64
+	}
+	q, _ := bits.Div64(hi, lo, l.rate)
+	return int64(min(q, math.MaxInt64))
+}
+
+// delay returns how long a transfer must wait until l permits it, as of now.
+func (l *ioThrottle) delay(now int64) int64 {
+	if l.rate == 0 {
+		return 0
+	}
+	return max(l.next-now-ioThrottleSlice.Nanoseconds(), 0)
+}
+
+// charge accounts a transfer of n units at now.
+func (l *ioThrottle) charge(now int64, n uint64) {
+	if l.rate == 0 {
+		return
+	}
+	next := max(l.next, now)
+	if c := l.cost(n); c < math.MaxInt64-next {
+		l.next = next + c
+	} else {
+		l.next = math.MaxInt64
+	}
+}
+
+// refund returns n units charged by a transfer that was shorter than charged.
+func (l *ioThrottle) refund(n uint64) {
+	if l.rate == 0 {
+		return
+	}
+	l.next -= min(l.cost(n), l.next)
+}
+
+// ioDevice is the state of an IOCgroup for a device.
+//
+// +stateify savable
+type ioDevice struct {
+	// stats are the I/O statistics of the cgroup's own tasks.
+	stats IOStats
+
+	// limits are the limits configured on the cgroup.
+	limits IOLimits
+
+	// The throttles enforcing limits.
+	readBytes  ioThrottle
+	writeBytes ioThrottle
+	readIOs    ioThrottle
+	writeIOs   ioThrottle
+}
+
+// throttles returns the byte and operation throttles for the given direction.
+func (d *ioDevice) throttles(write bool) (*ioThrottle, *ioThrottle) {
+	if write {
+		return &d.writeBytes, &d.writeIOs
+	}
+	return &d.readBytes, &d.readIOs
+}
+
+// IOCgroup accounts and throttles the file I/O of the tasks in an io cgroup,
+// per device. Limits apply hierarchically: I/O must be permitted by the limits
+// of the cgroup and all of its ancestors.
+//
+// IOCgroup implements vfs.IOAccounter.
+//
+// +stateify savable
+type IOCgroup struct {
+	// parent is the IOCgroup of the parent cgroup, or nil for the root
+	// cgroup. Immutable.
+	parent *IOCgroup
+
+	// limited is true if any device has limits. limited is only set with mu
+	// locked, but is read without it so that I/O doesn't lock cgroups without
+	// limits.
+	limited atomicbitops.Bool
+
+	mu sync.Mutex `state:"nosave"`
+
+	// devices is the state of each device with I/O statistics or limits.
+	//
+	// +checklocks:mu
+	devices map[vfs.IODevice]*ioDevice
+
+	// children are the IOCgroups of the child cgroups.
+	//
+	// +checklocks:mu
+	children map[*IOCgroup]struct{}
+
+	// releasedStats are the I/O statistics of released descendant cgroups.
+	//
+	// +checklocks:mu
+	releasedStats map[vfs.IODevice]IOStats
+}
+
+var _ vfs.IOAccounter = (*IOCgroup)(nil)
+
+// NewIOCgroup returns a new IOCgroup for a cgroup whose parent cgroup has the
+// given IOCgroup.
+func NewIOCgroup(parent *IOCgroup) *IOCgroup {
+	g := &IOCgroup{
+		parent:  parent,
+		devices: make(map[vfs.IODevice]*ioDevice),
+	}
+	if parent != nil {
+		parent.mu.Lock()
+		if parent.children == nil {
+			parent.children = make(map[*IOCgroup]struct{})
+		}
+		parent.children[g] = struct{}{}
+		parent.mu.Unlock()
+	}
+	return g
+}
+
+// Release removes g from its parent, which keeps g's statistics. It's called
+// when g's cgroup is removed.
+func (g *IOCgroup) Release() {
+	p := g.parent
+	if p == nil {
+		return
+	}
+	stats := g.Stats(true /* recursive */)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.children, g)
+	if len(stats) == 0 {
+		return
+	}
+	if p.releasedStats == nil {
+		p.releasedStats = make(map[vfs.IODevice]IOStats)
+	}
+	addIOStats(p.releasedStats, stats)
+}
+
+// +checklocks:g.mu
+func (g *IOCgroup) deviceLocked(dev vfs.IODevice) *ioDevice {
+	d, ok := g.devices[dev]
+	if !ok {
+		d = &ioDevice{}
+		g.devices[dev] = d
+	}
+	return d
+}
+
+// delay returns how long an I/O to dev must wait until g's limits permit it.
+func (g *IOCgroup) delay(now int64, dev vfs.IODevice, write bool) int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	d, ok := g.devices[dev]
+	if !ok {
+		return 0
+	}
+	b, ops := d.throttles(write)
+	return max(b.delay(now), ops.delay(now))
+}
+
+// StartIO implements vfs.IOAccounter.StartIO.
+func (g *IOCgroup) StartIO(ctx context.Context, dev vfs.IODevice, write bool, size int64) error {
+	clock := KernelFromContext(ctx).MonotonicClock()
+	t := TaskFromContext(ctx)
+	for {
+		now := clock.Now().Nanoseconds()
+		var delay int64
+		for cg := g; cg != nil; cg = cg.parent {
+			if cg.limited.Load() {
+				delay = max(delay, cg.delay(now, dev, write))
+			}
+		}
+		if delay == 0 || t == nil {
+			break
+		}
+		if _, err := t.BlockWithTimeout(nil, true, time.Duration(delay)); err != nil && !linuxerr.Equals(linuxerr.ETIMEDOUT, err) {
+			return err
+		}
+	}
+
+	now := clock.Now().Nanoseconds()
+	for cg := g; cg != nil; cg = cg.parent {
+		if !cg.limited.Load() {
+			continue
+		}
+		cg.mu.Lock()
+		if d, ok := cg.devices[dev]; ok {
+			b, ops := d.throttles(write)
+			b.charge(now, uint64(size))
+			ops.charge(now, 1)
+		}
+		cg.mu.Unlock()
+	}
+	return nil
+}
+
+// FinishIO implements vfs.IOAccounter.FinishIO.
+func (g *IOCgroup) FinishIO(dev vfs.IODevice, write bool, size, n int64) {
+	if n <= 0 {
+		n = 0
+	}
+	if n < size {
+		for cg := g; cg != nil; cg = cg.parent {
+			if !cg.limited.Load() {
+				continue
+			}
+			cg.mu.Lock()
+			if d, ok := cg.devices[dev]; ok {
+				b, _ := d.throttles(write)
+				b.refund(uint64(size - n))
+			}
+			cg.mu.Unlock()
+		}
+	}
+	// Only g's own statistics are updated; statistics of ancestors are summed
+	// when read.
+	if n > 0 {
+		g.mu.Lock()
+		g.deviceLocked(dev).stats.account(write, uint64(n))
+		g.mu.Unlock()
+	}
+}
+
+// account adds a completed I/O of n bytes to s.
+func (s *IOStats) account(write bool, n uint64) {
+	if write {
+		s.WriteBytes += n
+		s.WriteIOs++
+	} else {
+		s.ReadBytes += n
+		s.ReadIOs++
+	}
+}
+
+// addIOStats adds the statistics in src to dst.
+func addIOStats(dst, src map[vfs.IODevice]IOStats) {
+	for dev, s := range src {
+		d := dst[dev]
+		d.ReadBytes += s.ReadBytes
+		d.WriteBytes += s.WriteBytes
+		d.ReadIOs += s.ReadIOs
+		d.WriteIOs += s.WriteIOs
+		dst[dev] = d
+	}
+}
+
+// Stats returns the I/O statistics of each device. If recursive is true, the
+// statistics include I/O by tasks in descendant cgroups.
+func (g *IOCgroup) Stats(recursive bool) map[vfs.IODevice]IOStats {
+	g.mu.Lock()
+	stats := make(map[vfs.IODevice]IOStats, len(g.devices))
+	for dev, d := range g.devices {
+		if d.stats != (IOStats{}) {
+			stats[dev] = d.stats
+		}
+	}
+	var children []*IOCgroup
+	if recursive {
+		addIOStats(stats, g.releasedStats)
+		children = make([]*IOCgroup, 0, len(g.children))
+		for c := range g.children {
+			children = append(children, c)
+		}
+	}
+	g.mu.Unlock()
+	for _, c := range children {
+		addIOStats(stats, c.Stats(true /* recursive */))
+	}
+	return stats
+}
+
+// Limits returns the I/O limits of each device with limits.
+func (g *IOCgroup) Limits() map[vfs.IODevice]IOLimits {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	limits := make(map[vfs.IODevice]IOLimits)
+	for dev, d := range g.devices {
+		if d.limits != (IOLimits{}) {
+			limits[dev] = d.limits
+		}
+	}
+	return limits
+}
+
+// UpdateLimits calls update with the I/O limits of dev, and sets them to the
+// updated value.
+func (g *IOCgroup) UpdateLimits(dev vfs.IODevice, update func(*IOLimits)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	d := g.deviceLocked(dev)
+	update(&d.limits)
+	d.readBytes.setRate(d.limits.ReadBPS)
+	d.writeBytes.setRate(d.limits.WriteBPS)
+	d.readIOs.setRate(d.limits.ReadIOPS)
+	d.writeIOs.setRate(d.limits.WriteIOPS)
+	limited := false
+	for _, d := range g.devices {
+		if d.limits != (IOLimits{}) {
+			limited = true
+			break
+		}
+	}
+	g.limited.Store(limited)
+}
+
+// SetIOCgroup sets the IOCgroup that accounts and throttles t's file I/O. If
+// g is nil, t's file I/O isn't accounted.
+func (t *Task) SetIOCgroup(g *IOCgroup) {
+	t.ioCgroup.Store(g)
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+func TestIOThrottle(t *testing.T) {
+	var l ioThrottle
+	l.setRate(1000)
+
+	// A burst of up to ioThrottleSlice is permitted without delay.
+	now := int64(time.Second)
+	l.charge(now, 100)
+	if got := l.delay(now); got != 0 {
+		t.Errorf("delay after charging one slice: got %v, want 0", time.Duration(got))
+	}
+
+	// Exceeding the burst is delayed until the excess has drained.
+	l.charge(now, 500)
+	if got, want := l.delay(now), (500 * time.Millisecond).Nanoseconds(); got != want {
+		t.Errorf("delay after charging 600 units: got %v, want %v", time.Duration(got), time.Duration(want))
+	}
+	if got := l.delay(now + (500 * time.Millisecond).Nanoseconds()); got != 0 {
+		t.Errorf("delay after draining: got %v, want 0", time.Duration(got))
+	}
+
+	// Refunds return unused units.
+	l.refund(500)
+	if got := l.delay(now); got != 0 {
+		t.Errorf("delay after refund: got %v, want 0", time.Duration(got))
+	}
+
+	// Charges don't accumulate while idle.
+	later := now + int64(time.Hour)
+	l.charge(later, 200)
+	if got, want := l.delay(later), (100 * time.Millisecond).Nanoseconds(); got != want {
+		t.Errorf("delay after idle: got %v, want %v", time.Duration(got), time.Duration(want))
+	}
+
+	// Huge charges saturate rather than overflow.
+	l.charge(later, math.MaxUint64)
+	if got := l.delay(later); got <= 0 {
+		t.Errorf("delay after huge charge: got %v, want > 0", time.Duration(got))
+	}
+
+	// Unlimited throttles never delay.
+	l.setRate(0)
+	if got := l.delay(later); got != 0 {
+		t.Errorf("delay when unlimited: got %v, want 0", time.Duration(got))
+	}
+}
+
+func TestIOCgroupStats(t *testing.T) {
+	dev := vfs.IODevice{Major: 0, Minor: 42}
+	parent := NewIOCgroup(nil)
+	child := NewIOCgroup(parent)
+
+	child.FinishIO(dev, false /* write */, 4096, 100)
+	child.FinishIO(dev, true /* write */, 10, 10)
+	parent.FinishIO(dev, true /* write */, 20, 20)
+	child.FinishIO(dev, false /* write */, 4096, 0)
+	child.FinishIO(dev, false /* write */, 4096, -1)
+
+	for _, test := range []struct {
+		name      string
+		g         *IOCgroup
+		recursive bool
+		want      IOStats
+	}{
+		{
+			name: "child",
+			g:    child,
+			want: IOStats{ReadBytes: 100, WriteBytes: 10, ReadIOs: 1, WriteIOs: 1},
+		},
+		{
+			name:      "child recursive",
+			g:         child,
+			recursive: true,
+			want:      IOStats{ReadBytes: 100, WriteBytes: 10, ReadIOs: 1, WriteIOs: 1},
+		},
+		{
+			name: "parent",
+			g:    parent,
+			want: IOStats{WriteBytes: 20, WriteIOs: 1},
+		},
+		{
+			name:      "parent recursive",
+			g:         parent,
+			recursive: true,
+			want:      IOStats{ReadBytes: 100, WriteBytes: 30, ReadIOs: 1, WriteIOs: 2},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			stats := test.g.Stats(test.recursive)
+			if got := stats[dev]; got != test.want {
+				t.Errorf("Stats(%t)[%v]: got %+v, want %+v", test.recursive, dev, got, test.want)
+			}
+		})
+	}
+	// Releasing the child keeps its statistics in the parent.
+	child.Release()
+	want := IOStats{ReadBytes: 100, WriteBytes: 30, ReadIOs: 1, WriteIOs: 2}
+	if got := parent.Stats(true /* recursive */)[dev]; got != want {
+		t.Errorf("Stats(true)[%v] after Release: got %+v, want %+v", dev, got, want)
+	}
+}
@@ -211,6 +211,17 @@ type Kernel struct {
 	// further protected by runningTasksMu (see incRunningTasks).
 	runningTasks atomicbitops.Int64
 
+	// uninterruptibleTasks is the total count of tasks currently in
+	// TaskGoroutineBlockedUninterruptible. Along with runningTasks, it
+	// determines the load average.
+	uninterruptibleTasks atomicbitops.Int64
+
+	// loadAvg is the system load average.
+	loadAvg loadAverage
+
+	// psi is the system-wide pressure stall information.
+	psi PSIGroup
+
 	// runningTasksCond is signaled when runningTasks is incremented from 0 to 1.
 	//
 	// Invariant: runningTasksCond.L == &runningTasksMu.
@@ -230,6 +241,10 @@ type Kernel struct {
 	// cpuClock if it's sleeping between ticks.
 	cpuClockTickerWakeCh chan struct{} `state:"nosave"`
 
+	// memoryLimitCheckCh is sent to by the goroutine that increments cpuClock
+	// to wake the goroutine that enforces memory cgroup limits.
+	memoryLimitCheckCh chan struct{} `state:"nosave"`
+
 	// cpuClockTickerStopCond is broadcast when cpuClockTickerRunning transitions
 	// from true to false.
 	//
@@ -336,6 +351,28 @@ type Kernel struct {
 	// YAMAPtraceScope is the current level of YAMA ptrace restrictions.
 	YAMAPtraceScope atomicbitops.Int32
 
+	// coreDumpMu protects corePattern.
+	coreDumpMu sync.Mutex `state:"nosave"`
+
+	// corePattern is the template used to name core dump files, as
+	// configured by /proc/sys/kernel/core_pattern.
+	corePattern string
+
+	// binfmtMisc holds the handlers registered through binfmt_misc instances
+	// mounted in the root user namespace.
+	binfmtMisc loader.BinfmtMisc
+
+	// binfmtMiscMu protects binfmtMiscNS.
+	binfmtMiscMu sync.Mutex `state:"nosave"`
+
+	// binfmtMiscNS maps non-root user namespaces to the handlers registered
+	// through binfmt_misc instances mounted in them. As in Linux, each user
+	// namespace gets its own handlers the first time it mounts binfmt_misc,
+	// and loses them when its last binfmt_misc filesystem is released.
+	//
+	// +checklocks:binfmtMiscMu
+	binfmtMiscNS map[*auth.UserNamespace]*binfmtMiscInstance
+
 	// cgroupRegistry contains the set of active cgroup controllers on the
 	// system. It is controller by cgroupfs. Nil if cgroupfs is unavailable on
 	// the system.
@@ -483,6 +520,7 @@ func (k *Kernel) Init(args InitKernelArgs) error {
 	}
 	k.runningTasksCond.L = &k.runningTasksMu
 	k.cpuClockTickerWakeCh = make(chan struct{}, 1)
+	k.memoryLimitCheckCh = make(chan struct{}, 1)
 	k.cpuClockTickerStopCond.L = &k.runningTasksMu
 	k.applicationCores = args.ApplicationCores
 	if args.UseHostCores {
@@ -504,6 +542,7 @@ func (k *Kernel) Init(args InitKernelArgs) error {
 	k.netlinkPorts = port.New()
 	k.ptraceExceptions = make(map[*Task]*Task)
 	k.YAMAPtraceScope = atomicbitops.FromInt32(linux.YAMA_SCOPE_RELATIONAL)
+	k.corePattern = defaultCorePattern
 	k.userCountersMap = make(map[auth.KUID]*UserCounters)
 	if args.MaxFDLimit == 0 {
 		args.MaxFDLimit = MaxFdLimit
@@ -763,6 +802,7 @@ func (k *Kernel) LoadFrom(ctx context.Context, r io.Reader, asyncMFLoader *Async
 
 	k.runningTasksCond.L = &k.runningTasksMu
 	k.cpuClockTickerWakeCh = make(chan struct{}, 1)
+	k.memoryLimitCheckCh = make(chan struct{}, 1)
 	k.cpuClockTickerStopCond.L = &k.runningTasksMu
 
 	initAppCores := k.applicationCores
@@ -1195,6 +1235,7 @@ func (k *Kernel) Start() error {
 	k.cpuClockTickerRunning = true
 	k.runningTasksMu.Unlock()
 	go k.runCPUClockTicker()
+	go k.runMemoryLimitEnforcer()
 	// If k was created by LoadKernelFrom, timers were stopped during
 	// Kernel.SaveTo and need to be resumed. If k was created by NewKernel,
 	// this is a no-op.
@@ -1575,6 +1616,79 @@ func (k *Kernel) Syslog() *syslog {
 	return &k.syslog
 }
 
+// binfmtMiscInstance holds the binfmt_misc handlers of a non-root user
+// namespace.
+//
+// +stateify savable
+type binfmtMiscInstance struct {
+	loader.BinfmtMisc
+
+	// filesystems is the number of binfmt_misc filesystems using the
+	// handlers.
+	filesystems int
+}
+
+// GetBinfmtMisc returns the binfmt_misc handlers owned by the user namespace
+// ns, creating them if necessary. Each call must be balanced by a call to
+// PutBinfmtMisc once the handlers are no longer in use.
+func (k *Kernel) GetBinfmtMisc(ns *auth.UserNamespace) *loader.BinfmtMisc {
+	if ns == k.rootUserNamespace {
+		return &k.binfmtMisc
+	}
+	k.binfmtMiscMu.Lock()
+	defer k.binfmtMiscMu.Unlock()
+	b, ok := k.binfmtMiscNS[ns]
+	if !ok {
+		if k.binfmtMiscNS == nil {
+			k.binfmtMiscNS = make(map[*auth.UserNamespace]*binfmtMiscInstance)
+		}
+		b = &binfmtMiscInstance{}
+		k.binfmtMiscNS[ns] = b
+	}
+	b.filesystems++
+	return &b.BinfmtMisc
+}
+
+// PutBinfmtMisc releases handlers returned by GetBinfmtMisc(ns). When the
+// handlers of a non-root user namespace are no longer used by any
+// binfmt_misc filesystem, they are unregistered, which releases the
+// interpreters opened by entries with the F flag, and execve in ns falls
+// back to the handlers of its ancestors.
+func (k *Kernel) PutBinfmtMisc(ctx context.Context, ns *auth.UserNamespace) {
+	if ns == k.rootUserNamespace {
+		return
+	}
+	k.binfmtMiscMu.Lock()
+	b, ok := k.binfmtMiscNS[ns]
+	if !ok {
+		k.binfmtMiscMu.Unlock()
+		panic("PutBinfmtMisc called without a matching GetBinfmtMisc")
+	}
+	b.filesystems--
+	if b.filesystems > 0 {
+		k.binfmtMiscMu.Unlock()
+		return
+	}
+	delete(k.binfmtMiscNS, ns)
+	k.binfmtMiscMu.Unlock()
+	b.Clear(ctx)
+}
+
+// binfmtMiscForExec returns the binfmt_misc handlers consulted by execve in
+// the user namespace ns: those of the nearest ancestor of ns, including ns
+// itself, that has mounted binfmt_misc, or the root user namespace's. See
+// fs/binfmt_misc.c:load_binfmt_misc().
+func (k *Kernel) binfmtMiscForExec(ns *auth.UserNamespace) *loader.BinfmtMisc {
+	k.binfmtMiscMu.Lock()
+	defer k.binfmtMiscMu.Unlock()
+	for ; ns != nil && ns != k.rootUserNamespace; ns = ns.Parent() {
+		if b, ok := k.binfmtMiscNS[ns]; ok {
+			return &b.BinfmtMisc
+		}
+	}
+	return &k.binfmtMisc
+}
+
 // GenerateInotifyCookie generates a unique inotify event cookie.
 //
 // Returned values may overlap with previously returned values if the value
@@ -0,0 +1,156 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"time"
+
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// Load averages are maintained as fixed-point numbers, as in Linux's
+// include/linux/sched/loadavg.h.
+const (
+	// LoadAvgShift is the number of fractional bits in values returned by
+	// Kernel.LoadAverage.
+	LoadAvgShift = 11
+
+	// loadAvgFixed1 is 1.0 in fixed-point.
+	loadAvgFixed1 = 1 << LoadAvgShift
+
+	// loadAvgFreq is the interval between load average samples. Linux uses
+	// 5*HZ+1 jiffies.
+	loadAvgFreq = 5 * time.Second
+
+	// loadAvgExp* are the decay factors applied at each sample for the 1, 5
+	// and 15 minute averages respectively, i.e. 1/exp(5s/1min) etc. in
+	// fixed-point.
+	loadAvgExp1  = 1884
+	loadAvgExp5  = 2014
+	loadAvgExp15 = 2037
+)
+
+var loadAvgExp = [3]uint64{loadAvgExp1, loadAvgExp5, loadAvgExp15}
+
+// loadAverage tracks exponentially-decayed averages of the number of tasks
+// that are runnable or in uninterruptible sleep, which is what Linux reports
+// as the system load average.
+//
+// +stateify savable
+type loadAverage struct {
+	// mu protects the below.
+	mu sync.Mutex `state:"nosave"`
+
+	// avenrun holds the 1, 5 and 15 minute load averages in fixed-point with
+	// LoadAvgShift fractional bits.
+	avenrun [3]uint64
+
+	// next is the time, in nanoseconds on the application monotonic clock, at
+	// which avenrun should next be sampled. next is 0 if no sample has been
+	// taken yet.
+	next int64
+}
+
+// calcLoad returns load decayed by exp over one sampling period during which
+// there were active tasks (in fixed-point). This is Linux's
+// kernel/sched/loadavg.c:calc_load().
+func calcLoad(load, exp, active uint64) uint64 {
+	newload := load*exp + active*(loadAvgFixed1-exp)
+	if active >= load {
+		newload += loadAvgFixed1 - 1
+	}
+	return newload / loadAvgFixed1
+}
+
+// fixedPowerInt returns x**n in fixed-point. This is Linux's
+// kernel/sched/loadavg.c:fixed_power_int().
+func fixedPowerInt(x, n uint64) uint64 {
+	result := uint64(loadAvgFixed1)
+	for n != 0 {
+		if n&1 != 0 {
+			result = (result*x + loadAvgFixed1/2) >> LoadAvgShift
+		}
+		n >>= 1
+		if n == 0 {
+			break
+		}
+		x = (x*x + loadAvgFixed1/2) >> LoadAvgShift
+	}
+	return result
+}
+
+// update brings the load averages up to date as of now, assuming that active
+// tasks were runnable or in uninterruptible sleep throughout any sampling
+// periods that have elapsed since the last update.
+func (l *loadAverage) update(now int64, active uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.updateLocked(now, active)
+}
+
+// Preconditions: l.mu must be locked.
+func (l *loadAverage) updateLocked(now int64, active uint64) {
+	if l.next == 0 {
+		l.next = now + loadAvgFreq.Nanoseconds()
+		return
+	}
+	if now < l.next {
+		return
+	}
+	// Like Linux's calc_global_nohz(), fold any periods that were missed
+	// (e.g. because the CPU clock ticker was idle) into a single update.
+	n := uint64((now-l.next)/loadAvgFreq.Nanoseconds()) + 1
+	active *= loadAvgFixed1
+	for i, exp := range loadAvgExp {
+		if n > 1 {
+			exp = fixedPowerInt(exp, n)
+		}
+		l.avenrun[i] = calcLoad(l.avenrun[i], exp, active)
+	}
+	l.next += int64(n) * loadAvgFreq.Nanoseconds()
+}
+
+// activeTasks returns the number of tasks that contribute to the load
+// average.
+func (k *Kernel) activeTasks() uint64 {
+	return uint64(k.runningTasks.Load() + k.uninterruptibleTasks.Load())
+}
+
+// updateLoadAverage samples the number of active tasks into the load average
+// if a sampling period has elapsed.
+func (k *Kernel) updateLoadAverage() {
+	k.loadAvg.update(k.MonotonicClock().Now().Nanoseconds(), k.activeTasks())
+}
+
+// LoadAverage returns the 1, 5 and 15 minute system load averages as
+// fixed-point numbers with LoadAvgShift fractional bits.
+func (k *Kernel) LoadAverage() [3]uint64 {
+	k.loadAvg.mu.Lock()
+	defer k.loadAvg.mu.Unlock()
+	k.loadAvg.updateLocked(k.MonotonicClock().Now().Nanoseconds(), k.activeTasks())
+	return k.loadAvg.avenrun
+}
+
+// NumRunningTasks returns the number of tasks that are currently running,
+// i.e. not blocked or stopped.
+func (k *Kernel) NumRunningTasks() int {
+	return int(k.runningTasks.Load())
+}
+
+// NumUninterruptibleTasks returns the number of tasks that are currently in
+// uninterruptible sleep.
+func (k *Kernel) NumUninterruptibleTasks() int {
+	return int(k.uninterruptibleTasks.Load())
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"testing"
+)
+
+func TestLoadAverageConverges(t *testing.T) {
+	var l loadAverage
+	now := int64(0)
+	l.update(now, 2)
+	// Keep two tasks active for two hours, sampling once per period.
+	for i := 0; i < 1440; i++ {
+		now += loadAvgFreq.Nanoseconds()
+		l.update(now, 2)
+	}
+	for i, avg := range l.avenrun {
+		if want := uint64(2 * loadAvgFixed1); avg != want {
+			t.Errorf("avenrun[%d] = %d, want %d", i, avg, want)
+		}
+	}
+}
+
+func TestLoadAverageDecay(t *testing.T) {
+	var l loadAverage
+	now := int64(0)
+	l.update(now, 0)
+	l.avenrun = [3]uint64{loadAvgFixed1, loadAvgFixed1, loadAvgFixed1}
+	now += loadAvgFreq.Nanoseconds()
+	l.update(now, 0)
+	for i, avg := range l.avenrun {
+		if want := loadAvgExp[i]; avg != want {
+			t.Errorf("avenrun[%d] = %d, want %d", i, avg, want)
+		}
+	}
+}
+
+func TestLoadAverageMissedPeriods(t *testing.T) {
+	// Folding missed periods into a single update should approximate
+	// sampling every period.
+	var stepped, folded loadAverage
+	now := int64(0)
+	stepped.update(now, 0)
+	folded.update(now, 0)
+	stepped.avenrun = [3]uint64{4 * loadAvgFixed1, 4 * loadAvgFixed1, 4 * loadAvgFixed1}
+	folded.avenrun = stepped.avenrun
+	const periods = 12
+	for i := 0; i < periods; i++ {
+		now += loadAvgFreq.Nanoseconds()
+		stepped.update(now, 0)
+	}
+	folded.update(now, 0)
+	for i := range stepped.avenrun {
+		diff := int64(stepped.avenrun[i]) - int64(folded.avenrun[i])
+		if diff < -periods || diff > periods {
+			t.Errorf("avenrun[%d]: stepped %d, folded %d", i, stepped.avenrun[i], folded.avenrun[i])
+		}
+	}
+	if stepped.next != folded.next {
+		t.Errorf("next: stepped %d, folded %d", stepped.next, folded.next)
+	}
+}
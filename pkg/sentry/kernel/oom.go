@@ -0,0 +1,214 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"math"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// oomScoreAdjMin is the oom_score_adj value that exempts a thread group from
+// the OOM killer. See Linux, include/uapi/linux/oom.h.
+const oomScoreAdjMin = -1000
+
+// memoryLimitCheckTicks is the number of CPU clock ticks between checks of
+// memory cgroup usage against their limits. Application memory is committed
+// lazily, so usage is only known after the MemoryFile has been scanned, which
+// is too expensive to do on every tick.
+const memoryLimitCheckTicks = 10
+
+// OOMDomain describes the memory cgroup that ran out of memory.
+type OOMDomain struct {
+	// Cgroup is the path of the memory cgroup, relative to its hierarchy
+	// root.
+	Cgroup string
+
+	// Limit is the memory limit of the cgroup in bytes.
+	Limit uint64
+
+	// Usage is the memory usage of the cgroup in bytes.
+	Usage uint64
+}
+
+// oomCandidate is a thread group considered by the OOM killer.
+type oomCandidate struct {
+	tg     *ThreadGroup
+	points int64
+	rss    uint64
+	vm     uint64
+}
+
+// oomBadness returns the OOM killer score for the thread group of t. Thread
+// groups with a higher score are killed first. ok is false if the thread group
+// can't be killed. See Linux, mm/oom_kill.c:oom_badness().
+func (k *Kernel) oomBadness(t *Task, totalPages uint64) (c oomCandidate, ok bool) {
+	tg := t.tg
+	if tg == k.globalInit {
+		return oomCandidate{}, false
+	}
+	adj := int64(tg.oomScoreAdj.Load())
+	if adj == oomScoreAdjMin {
+		return oomCandidate{}, false
+	}
+	var tmm *mm.MemoryManager
+	t.WithMuLocked(func(t *Task) {
+		tmm = t.MemoryManager()
+	})
+	if tmm == nil {
+		// t has exited.
+		return oomCandidate{}, false
+	}
+	rss := tmm.ResidentSetSize()
+	points := int64(rss / hostarch.PageSize)
+	points += adj * int64(totalPages/1000)
+	return oomCandidate{
+		tg:     tg,
+		points: points,
+		rss:    rss,
+		vm:     tmm.VirtualMemorySize(),
+	}, true
+}
+
+// OOMKill kills the thread group with the highest OOM badness among the
+// thread groups of ts, after domain ran out of memory. See Linux,
+// mm/oom_kill.c:out_of_memory().
+//
+// If a thread group in ts was already killed by the OOM killer and hasn't
+// exited yet, OOMKill doesn't kill anything and returns waiting == true, as
+// memory is about to be released. Otherwise, OOMKill returns the killed thread
+// group, or nil if no thread group in ts can be killed.
+func (k *Kernel) OOMKill(ts []*Task, domain OOMDomain) (victim *ThreadGroup, waiting bool) {
+	totalPages := domain.Limit / hostarch.PageSize
+	chosen := oomCandidate{points: math.MinInt64}
+	seen := make(map[*ThreadGroup]struct{})
+	for _, t := range ts {
+		if _, ok := seen[t.tg]; ok {
+			continue
+		}
+		seen[t.tg] = struct{}{}
+		if t.tg.oomVictim.Load() {
+			return nil, true
+		}
+		c, ok := k.oomBadness(t, totalPages)
+		if !ok || c.points < chosen.points {
+			continue
+		}
+		chosen = c
+	}
+	if chosen.tg == nil {
+		return nil, false
+	}
+	k.killOOMVictim(chosen, domain)
+	return chosen.tg, false
+}
+
+// OOMKillGroup kills every thread group of ts that can be killed and wasn't
+// already killed by the OOM killer, after domain ran out of memory and the
+// victim chosen by OOMKill belonged to a cgroup with memory.oom.group set. It
+// returns the number of thread groups killed. See Linux,
+// mm/oom_kill.c:oom_kill_memcg_member().
+func (k *Kernel) OOMKillGroup(ts []*Task, domain OOMDomain) int {
+	totalPages := domain.Limit / hostarch.PageSize
+	killed := 0
+	seen := make(map[*ThreadGroup]struct{})
+	for _, t := range ts {
+		if _, ok := seen[t.tg]; ok {
+			continue
+		}
+		seen[t.tg] = struct{}{}
+		if t.tg.oomVictim.Load() {
+			continue
+		}
+		c, ok := k.oomBadness(t, totalPages)
+		if !ok {
+			continue
+		}
+		k.killOOMVictim(c, domain)
+		killed++
+	}
+	return killed
+}
+
+// killOOMVictim kills the thread group of chosen and reports it.
+func (k *Kernel) killOOMVictim(chosen oomCandidate, domain OOMDomain) {
+	victim := chosen.tg
+	victim.oomVictim.Store(true)
+	if err := victim.SendSignal(&linux.SignalInfo{Signo: int32(linux.SIGKILL), Code: linux.SI_KERNEL}); err != nil {
+		log.Warningf("Failed to send SIGKILL to OOM victim: %v", err)
+	}
+
+	leader := victim.Leader()
+	if leader == nil {
+		return
+	}
+	log.Warningf("Memory cgroup out of memory: Killed process %d (%s) total-vm:%dkB, rss:%dkB, oom_score_adj:%d, memcg:%s limit:%dkB usage:%dkB",
+		k.tasks.Root.IDOfThreadGroup(victim), leader.Name(), chosen.vm/1024, chosen.rss/1024, victim.oomScoreAdj.Load(), domain.Cgroup, domain.Limit/1024, domain.Usage/1024)
+
+	if seccheck.Global.Enabled(seccheck.PointOOMKill) {
+		info := &pb.OOMKillInfo{
+			Cgroup:      domain.Cgroup,
+			Limit:       domain.Limit,
+			Usage:       domain.Usage,
+			Rss:         chosen.rss,
+			OomScoreAdj: victim.oomScoreAdj.Load(),
+		}
+		fields := seccheck.Global.GetFieldSet(seccheck.PointOOMKill)
+		if !fields.Context.Empty() {
+			info.ContextData = &pb.ContextData{}
+			LoadSeccheckData(leader, fields.Context, info.ContextData)
+		}
+		seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
+			return c.OOMKill(leader, fields, info)
+		})
+	}
+}
+
+// runMemoryLimitEnforcer enforces memory cgroup limits whenever it's woken by
+// the CPU clock ticker. Memory usage can only grow while tasks are running,
+// which is when the CPU clock ticker runs. Checking usage requires scanning
+// the MemoryFile, so it's done on a separate goroutine to avoid delaying CPU
+// clock ticks.
+func (k *Kernel) runMemoryLimitEnforcer() {
+	for range k.memoryLimitCheckCh {
+		k.cgroupRegistry.enforceMemoryLimits(k.SupervisorContext())
+	}
+}
+
+// enforceMemoryLimits checks the memory usage of all memory cgroups against
+// their limits, invoking the OOM killer as needed.
+func (r *CgroupRegistry) enforceMemoryLimits(ctx context.Context) {
+	r.mu.Lock()
+	ctl, ok := r.controllers[CgroupControllerMemory]
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+	h, ok := r.hierarchies[ctl.HierarchyID()]
+	if !ok || !h.fs.TryIncRef() {
+		r.mu.Unlock()
+		return
+	}
+	r.mu.Unlock()
+
+	h.fs.Impl().(cgroupFS).EnforceMemoryLimits(ctx)
+	h.fs.DecRef(ctx)
+}
@@ -147,3 +147,23 @@ func (p *pendingSignals) discardSpecific(sig linux.Signal) {
 	q.length = 0
 	p.pendingSet.Store(p.pendingSet.RacyLoad() &^ uint64(linux.SignalSetOf(sig)))
 }
+
+// peek returns copies of at most nr pending signals, skipping the first off.
+// Signals are ordered by signal number, then by the order in which they were
+// enqueued.
+func (p *pendingSignals) peek(off uint64, nr int) []linux.SignalInfo {
+	var infos []linux.SignalInfo
+	for i := range p.signals {
+		for ps := p.signals[i].pendingSignalList.Front(); ps != nil; ps = ps.Next() {
+			if len(infos) == nr {
+				return infos
+			}
+			if off > 0 {
+				off--
+				continue
+			}
+			infos = append(infos, *ps.SignalInfo)
+		}
+	}
+	return infos
+}
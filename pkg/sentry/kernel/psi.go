@@ -0,0 +1,651 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// Pressure stall information (PSI) reports the share of time in which tasks
+// were delayed waiting for a resource. See Linux's
+// Documentation/accounting/psi.rst.
+//
+// gVisor has no insight into when tasks are actually scheduled on a CPU, so
+// like CPU clocks, PSI is approximated by sampling the state of every task on
+// each CPU clock tick:
+//
+//   - A running task that wasn't chosen to be accounted CPU time for the tick
+//     is stalled on CPU, i.e. it's waiting on the Go scheduler or the
+//     platform.
+//
+//   - A task handling a page fault is stalled on memory, since that's where
+//     application memory is allocated (and possibly reclaimed).
+//
+//   - Any other task in uninterruptible sleep is stalled on I/O, since that's
+//     how blocking filesystem operations wait.
+
+// PSIResource is a resource for which pressure stall information is tracked.
+type PSIResource int
+
+// Resources for which pressure stall information is tracked, in the same
+// order as Linux's enum psi_res.
+const (
+	PSIIO PSIResource = iota
+	PSIMemory
+	PSICPU
+	numPSIResources
+)
+
+// String implements fmt.Stringer.String.
+func (r PSIResource) String() string {
+	switch r {
+	case PSIIO:
+		return "io"
+	case PSIMemory:
+		return "memory"
+	case PSICPU:
+		return "cpu"
+	default:
+		return fmt.Sprintf("PSIResource(%d)", int(r))
+	}
+}
+
+// Each resource has two stall states: "some" when at least one task is
+// stalled on the resource, and "full" when all non-idle tasks are stalled on
+// it simultaneously.
+const (
+	psiSome = iota
+	psiFull
+	numPSIStates
+)
+
+var psiStateNames = [numPSIStates]string{"some", "full"}
+
+const (
+	// psiAvgFreq is the interval between updates of the running averages.
+	// Linux uses 2*HZ+1 jiffies.
+	psiAvgFreq = 2 * time.Second
+
+	// psiExp* are the decay factors applied at each update of the 10s, 60s
+	// and 300s averages respectively, i.e. 1/exp(2s/10s) etc. in fixed-point
+	// with LoadAvgShift fractional bits.
+	psiExp10s  = 1677
+	psiExp60s  = 1981
+	psiExp300s = 2034
+
+	// psiTriggerWindowMin and psiTriggerWindowMax bound the time window of a
+	// trigger.
+	psiTriggerWindowMin = 500 * time.Millisecond
+	psiTriggerWindowMax = 10 * time.Second
+
+	// psiTriggerMaxLen is the maximum length of a trigger specification.
+	psiTriggerMaxLen = 32
+)
+
+var (
+	psiExp      = [3]uint64{psiExp10s, psiExp60s, psiExp300s}
+	psiAvgNames = [3]string{"10", "60", "300"}
+)
+
+// psiTaskCounts counts the tasks of a PSIGroup in each state during a sample.
+type psiTaskCounts struct {
+	running         int
+	onCPU           int
+	ioWait          int
+	memStall        int
+	memStallRunning int
+}
+
+// PSIGroup tracks pressure stall information for a set of tasks: either all
+// tasks, for the system-wide group, or the tasks of a cgroup and its
+// descendants.
+//
+// +stateify savable
+type PSIGroup struct {
+	// parent is the group of the parent cgroup. The system-wide group is the
+	// only group with a nil parent. Immutable.
+	parent *PSIGroup
+
+	// nr counts the tasks of the group in each state during the current
+	// sample. It's only accessed by the CPU clock ticker goroutine, and is
+	// reset after each sample.
+	nr psiTaskCounts `state:"nosave"`
+
+	// mu protects the below.
+	mu sync.Mutex `state:"nosave"`
+
+	// total is the cumulative stall time in nanoseconds of each state.
+	total [numPSIResources][numPSIStates]uint64
+
+	// avg holds the 10s, 60s and 300s running averages of the percentage of
+	// time spent in each state, in fixed-point with LoadAvgShift fractional
+	// bits.
+	avg [numPSIResources][numPSIStates][3]uint64
+
+	// avgTotal is the value of total as of the last update of avg.
+	avgTotal [numPSIResources][numPSIStates]uint64
+
+	// avgLast and avgNext are the times of the last and next updates of avg,
+	// in nanoseconds on the application monotonic clock. avgNext is 0 if avg
+	// has never been updated.
+	avgLast int64
+	avgNext int64
+
+	// triggers are the triggers registered on this group.
+	triggers map[*PSITrigger]struct{}
+}
+
+// NewPSIGroup returns a new PSIGroup for a cgroup whose parent cgroup's tasks
+// are accounted to parent.
+func NewPSIGroup(parent *PSIGroup) *PSIGroup {
+	return &PSIGroup{parent: parent}
+}
+
+// PSI returns the system-wide pressure stall information.
+func (k *Kernel) PSI() *PSIGroup {
+	return &k.psi
+}
+
+// isSystem returns whether g is the system-wide group.
+func (g *PSIGroup) isSystem() bool {
+	return g.parent == nil
+}
+
+// accountLocked adds delta nanoseconds to the stall time of the states that
+// g was in during the current sample.
+//
+// Preconditions: g.mu must be locked.
+func (g *PSIGroup) accountLocked(delta uint64) {
+	nr := &g.nr
+	if nr.ioWait > 0 {
+		g.total[PSIIO][psiSome] += delta
+		if nr.running == 0 {
+			g.total[PSIIO][psiFull] += delta
+		}
+	}
+	if nr.memStall > 0 {
+		g.total[PSIMemory][psiSome] += delta
+		if nr.running == nr.memStallRunning {
+			g.total[PSIMemory][psiFull] += delta
+		}
+	}
+	if nr.running > nr.onCPU {
+		g.total[PSICPU][psiSome] += delta
+	}
+	if nr.running > 0 && nr.onCPU == 0 && !g.isSystem() {
+		// CPU full is undefined at the system level, since some task is
+		// always running while there are runnable tasks.
+		g.total[PSICPU][psiFull] += delta
+	}
+}
+
+// updateAveragesLocked brings the running averages up to date as of now. It
+// is Linux's kernel/sched/psi.c:update_averages().
+//
+// Preconditions: g.mu must be locked.
+func (g *PSIGroup) updateAveragesLocked(now int64) {
+	freq := psiAvgFreq.Nanoseconds()
+	if g.avgNext == 0 {
+		g.avgLast = now
+		g.avgNext = now + freq
+		return
+	}
+	if now < g.avgNext {
+		return
+	}
+	// Fold any periods that were missed (e.g. because the CPU clock ticker
+	// was idle) into a single update, like the load average.
+	missed := uint64((now - g.avgNext) / freq)
+	period := uint64(now - (g.avgLast + int64(missed)*freq))
+	g.avgNext += int64(missed+1) * freq
+	g.avgLast = now
+	for r := range g.total {
+		for s := range g.total[r] {
+			sample := min(g.total[r][s]-g.avgTotal[r][s], period)
+			g.avgTotal[r][s] += sample
+			pct := sample * 100 / period * loadAvgFixed1
+			for i, exp := range psiExp {
+				if missed > 0 {
+					g.avg[r][s][i] = calcLoad(g.avg[r][s][i], fixedPowerInt(exp, missed), 0)
+				}
+				g.avg[r][s][i] = calcLoad(g.avg[r][s][i], exp, pct)
+			}
+		}
+	}
+}
+
+// updateTriggersLocked checks the triggers of g for stall time growth beyond
+// their threshold, and appends those that fire to fired.
+//
+// Preconditions: g.mu must be locked.
+func (g *PSIGroup) updateTriggersLocked(now int64, fired []*PSITrigger) []*PSITrigger {
+	for tr := range g.triggers {
+		value := g.total[tr.res][tr.state]
+		if value == tr.lastValue {
+			// No new stall time since the last update.
+			continue
+		}
+		tr.lastValue = value
+		if tr.windowUpdate(now, value) < tr.threshold {
+			continue
+		}
+		// Limit events to one per window.
+		if tr.fired && now < tr.lastEvent+int64(tr.window) {
+			continue
+		}
+		tr.fired = true
+		tr.lastEvent = now
+		tr.pending.Store(true)
+		fired = append(fired, tr)
+	}
+	return fired
+}
+
+// Generate writes the pressure stall information of g for res to buf, in the
+// format of /proc/pressure/<res>.
+func (g *PSIGroup) Generate(ctx context.Context, res PSIResource, buf *bytes.Buffer) {
+	now := KernelFromContext(ctx).MonotonicClock().Now().Nanoseconds()
+	g.mu.Lock()
+	g.updateAveragesLocked(now)
+	avg := g.avg[res]
+	total := g.total[res]
+	g.mu.Unlock()
+
+	for s := range total {
+		buf.WriteString(psiStateNames[s])
+		for i, a := range avg[s] {
+			// Like Linux's LOAD_INT() and LOAD_FRAC(), this truncates rather
+			// than rounds.
+			frac := (a & (loadAvgFixed1 - 1)) * 100 >> LoadAvgShift
+			fmt.Fprintf(buf, " avg%s=%d.%02d", psiAvgNames[i], a>>LoadAvgShift, frac)
+		}
+		fmt.Fprintf(buf, " total=%d\n", total[s]/uint64(time.Microsecond))
+	}
+}
+
+// PSITrigger is notified when the stall time of a resource grows by more than
+// a threshold within a time window. See Linux's
+// Documentation/accounting/psi.rst, "Monitoring for pressure thresholds".
+//
+// +stateify savable
+type PSITrigger struct {
+	// group is the group whose stall time is monitored. Immutable.
+	group *PSIGroup
+
+	// res and state select the monitored stall time. Immutable.
+	res   PSIResource
+	state int
+
+	// threshold and window are the threshold and time window, in
+	// nanoseconds. Immutable.
+	threshold uint64
+	window    uint64
+
+	// queue is notified with waiter.EventPri when the trigger fires.
+	// Immutable.
+	queue *waiter.Queue
+
+	// pending is set when the trigger fires, and cleared when the event is
+	// consumed by Readiness.
+	pending atomicbitops.Bool
+
+	// The fields below are protected by group.mu, and implement a moving
+	// window as in Linux's kernel/sched/psi.c:window_update().
+
+	// winStart is the start time of the current window, and winStartValue the
+	// stall time at winStart.
+	winStart      int64
+	winStartValue uint64
+
+	// prevGrowth is the stall time growth during the previous window.
+	prevGrowth uint64
+
+	// lastValue is the stall time as of the last update.
+	lastValue uint64
+
+	// fired is true if the trigger has fired before, and lastEvent is the
+	// time it last fired.
+	fired     bool
+	lastEvent int64
+}
+
+// windowUpdate returns the stall time growth within the window ending at now,
+// given that the stall time is value. Growth during the part of the window
+// that overlaps the previous window is interpolated from prevGrowth.
+//
+// Preconditions: tr.group.mu must be locked.
+func (tr *PSITrigger) windowUpdate(now int64, value uint64) uint64 {
+	elapsed := uint64(now - tr.winStart)
+	growth := value - tr.winStartValue
+	if elapsed > tr.window {
+		tr.winStart = now
+		tr.winStartValue = value
+		tr.prevGrowth = growth
+		return growth
+	}
+	return growth + tr.prevGrowth*(tr.window-elapsed)/tr.window
+}
+
+// Readiness returns the events that are ready for an FD with trigger tr. An
+// event is only reported once.
+func (tr *PSITrigger) Readiness(mask waiter.EventMask) waiter.EventMask {
+	ready := waiter.ReadableEvents | waiter.WritableEvents
+	if mask&waiter.EventPri != 0 && tr.pending.CompareAndSwap(true, false) {
+		ready |= waiter.EventPri
+	}
+	return ready & mask
+}
+
+// newTrigger registers a trigger for spec on g, which will notify queue. spec
+// has the format "<some|full> <threshold us> <window us>".
+func (g *PSIGroup) newTrigger(ctx context.Context, res PSIResource, spec string, privileged bool, queue *waiter.Queue) (*PSITrigger, error) {
+	var (
+		state               int
+		name                string
+		thresholdUS, window uint64
+	)
+	if n, _ := fmt.Sscanf(spec, "%s %d %d", &name, &thresholdUS, &window); n != 3 {
+		return nil, linuxerr.EINVAL
+	}
+	switch name {
+	case psiStateNames[psiSome]:
+		state = psiSome
+	case psiStateNames[psiFull]:
+		state = psiFull
+	default:
+		return nil, linuxerr.EINVAL
+	}
+	threshold := thresholdUS * uint64(time.Microsecond)
+	window *= uint64(time.Microsecond)
+	if window == 0 || window > uint64(psiTriggerWindowMax) {
+		return nil, linuxerr.EINVAL
+	}
+	if privileged {
+		if window < uint64(psiTriggerWindowMin) {
+			return nil, linuxerr.EINVAL
+		}
+	} else if window%uint64(psiAvgFreq) != 0 {
+		// Unprivileged triggers are limited to whole averaging periods, as
+		// in Linux.
+		return nil, linuxerr.EINVAL
+	}
+	if threshold == 0 || threshold > window {
+		return nil, linuxerr.EINVAL
+	}
+
+	now := KernelFromContext(ctx).MonotonicClock().Now().Nanoseconds()
+	tr := &PSITrigger{
+		group:     g,
+		res:       res,
+		state:     state,
+		threshold: threshold,
+		window:    window,
+		queue:     queue,
+		winStart:  now,
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	tr.winStartValue = g.total[res][state]
+	tr.lastValue = tr.winStartValue
+	if g.triggers == nil {
+		g.triggers = make(map[*PSITrigger]struct{})
+	}
+	g.triggers[tr] = struct{}{}
+	return tr, nil
+}
+
+// release unregisters tr from its group.
+func (tr *PSITrigger) release() {
+	tr.group.mu.Lock()
+	defer tr.group.mu.Unlock()
+	delete(tr.group.triggers, tr)
+}
+
+// psiFD is the state of an FD on a PSIFile.
+//
+// +stateify savable
+type psiFD struct {
+	// queue is notified when trigger fires.
+	queue waiter.Queue
+
+	// trigger is the trigger written to the FD, or nil if none has been
+	// written.
+	trigger *PSITrigger
+}
+
+// PSIFile implements vfs.WritableDynamicBytesSource for a pressure file, i.e.
+// /proc/pressure/<res> or <res>.pressure in a cgroup. Reading the file returns
+// the pressure stall information of the group. Writing a trigger
+// specification to the file registers a trigger, after which poll() on the FD
+// reports POLLPRI when the trigger fires.
+//
+// +stateify savable
+type PSIFile struct {
+	// group and res are the pressure stall information reported by the file.
+	// Immutable.
+	group *PSIGroup
+	res   PSIResource
+
+	mu sync.Mutex `state:"nosave"`
+
+	// fds holds the state of each FD on which triggers have been written or
+	// waited on.
+	//
+	// +checklocks:mu
+	fds map[*vfs.FileDescription]*psiFD
+}
+
+// NewPSIFile returns a PSIFile reporting g's pressure stall information for
+// res.
+func NewPSIFile(g *PSIGroup, res PSIResource) *PSIFile {
+	return &PSIFile{
+		group: g,
+		res:   res,
+		fds:   make(map[*vfs.FileDescription]*psiFD),
+	}
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (f *PSIFile) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	f.group.Generate(ctx, f.res, buf)
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (f *PSIFile) Write(ctx context.Context, fd *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	if src.NumBytes() == 0 || fd == nil {
+		return 0, linuxerr.EINVAL
+	}
+	buf := make([]byte, min(src.NumBytes(), psiTriggerMaxLen))
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+	// Like Linux, the last byte (usually a newline or NUL terminator) is
+	// dropped.
+	spec := string(buf[:n-1])
+	privileged := auth.CredentialsFromContext(ctx).HasCapability(linux.CAP_SYS_RESOURCE)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	state := f.fdLocked(fd)
+	if state.trigger != nil {
+		return 0, linuxerr.EBUSY
+	}
+	tr, err := f.group.newTrigger(ctx, f.res, spec, privileged, &state.queue)
+	if err != nil {
+		return 0, err
+	}
+	state.trigger = tr
+	return src.NumBytes(), nil
+}
+
+// +checklocks:f.mu
+func (f *PSIFile) fdLocked(fd *vfs.FileDescription) *psiFD {
+	state, ok := f.fds[fd]
+	if !ok {
+		state = &psiFD{}
+		f.fds[fd] = state
+	}
+	return state
+}
+
+// Readiness implements kernfs.WaitableDynamicBytesSource.Readiness.
+func (f *PSIFile) Readiness(fd *vfs.FileDescription, mask waiter.EventMask) waiter.EventMask {
+	var tr *PSITrigger
+	f.mu.Lock()
+	if state, ok := f.fds[fd]; ok {
+		tr = state.trigger
+	}
+	f.mu.Unlock()
+	if tr == nil {
+		// Like Linux, report an error for FDs without a trigger.
+		return (waiter.ReadableEvents | waiter.WritableEvents | waiter.EventErr | waiter.EventPri) & mask
+	}
+	return tr.Readiness(mask)
+}
+
+// EventRegister implements kernfs.WaitableDynamicBytesSource.EventRegister.
+func (f *PSIFile) EventRegister(fd *vfs.FileDescription, e *waiter.Entry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fdLocked(fd).queue.EventRegister(e)
+	return nil
+}
+
+// EventUnregister implements kernfs.WaitableDynamicBytesSource.EventUnregister.
+func (f *PSIFile) EventUnregister(fd *vfs.FileDescription, e *waiter.Entry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if state, ok := f.fds[fd]; ok {
+		state.queue.EventUnregister(e)
+	}
+}
+
+// ReleaseFD implements kernfs.WaitableDynamicBytesSource.ReleaseFD.
+func (f *PSIFile) ReleaseFD(ctx context.Context, fd *vfs.FileDescription) {
+	f.mu.Lock()
+	state, ok := f.fds[fd]
+	delete(f.fds, fd)
+	f.mu.Unlock()
+	if ok && state.trigger != nil {
+		state.trigger.release()
+	}
+}
+
+// psiSampler accumulates pressure stall information from samples of task
+// states. It's only used by the CPU clock ticker goroutine.
+type psiSampler struct {
+	// groups are the groups with tasks in the current sample.
+	groups []*PSIGroup
+
+	// fired is scratch storage for triggers that fire.
+	fired []*PSITrigger
+}
+
+// psiGroupOf returns the innermost group that t is accounted to.
+func (k *Kernel) psiGroupOf(t *Task) *PSIGroup {
+	if g := t.psiGroup.Load(); g != nil {
+		return g
+	}
+	return &k.psi
+}
+
+// sample records the states of the tasks in all, of which oncpu are the tasks
+// that were accounted CPU time during the current tick.
+func (s *psiSampler) sample(k *Kernel, all, oncpu []*Task) {
+	for _, t := range all {
+		memStall := t.memStall.Load()
+		var running, ioWait bool
+		switch t.TaskGoroutineState() {
+		case TaskGoroutineRunningApp, TaskGoroutineRunningSys:
+			running = true
+		case TaskGoroutineBlockedUninterruptible:
+			// Only blocking on file I/O marked by Task.IOWaitStart is an I/O
+			// stall; other uninterruptible sleeps aren't stalls.
+			ioWait = t.ioWait.Load() > 0
+			if !ioWait && !memStall {
+				continue
+			}
+		default:
+			continue
+		}
+		for g := k.psiGroupOf(t); g != nil; g = g.parent {
+			nr := &g.nr
+			if *nr == (psiTaskCounts{}) {
+				s.groups = append(s.groups, g)
+			}
+			switch {
+			case running:
+				nr.running++
+				if memStall {
+					nr.memStall++
+					nr.memStallRunning++
+				}
+			case memStall:
+				nr.memStall++
+			case ioWait:
+				nr.ioWait++
+			}
+		}
+	}
+	for _, t := range oncpu {
+		for g := k.psiGroupOf(t); g != nil; g = g.parent {
+			g.nr.onCPU++
+		}
+	}
+}
+
+// account adds delta nanoseconds to the stall time of the states of each
+// group in the current sample as of now, notifies triggers that fire, and
+// resets the sample.
+func (s *psiSampler) account(now int64, delta uint64) {
+	for _, g := range s.groups {
+		g.mu.Lock()
+		g.accountLocked(delta)
+		g.updateAveragesLocked(now)
+		if len(g.triggers) != 0 {
+			s.fired = g.updateTriggersLocked(now, s.fired)
+		}
+		g.mu.Unlock()
+		g.nr = psiTaskCounts{}
+	}
+	// Notify waiters outside of group locks.
+	for _, tr := range s.fired {
+		tr.queue.Notify(waiter.EventPri)
+	}
+	clear(s.groups)
+	s.groups = s.groups[:0]
+	clear(s.fired)
+	s.fired = s.fired[:0]
+}
+
+// SetPSIGroup sets the innermost group that t's pressure stall information is
+// accounted to. If g is nil, t is only accounted to the system-wide group.
+func (t *Task) SetPSIGroup(g *PSIGroup) {
+	t.psiGroup.Store(g)
+}
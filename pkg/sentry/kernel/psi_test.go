@@ -0,0 +1,148 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+)
+
+func TestPSIStates(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		nr   psiTaskCounts
+		want [numPSIResources][numPSIStates]uint64
+	}{
+		{
+			name: "idle",
+		},
+		{
+			name: "running on CPU",
+			nr:   psiTaskCounts{running: 2, onCPU: 2},
+		},
+		{
+			name: "waiting for CPU",
+			nr:   psiTaskCounts{running: 3, onCPU: 2},
+			want: [numPSIResources][numPSIStates]uint64{PSICPU: {1, 0}},
+		},
+		{
+			name: "no CPU",
+			nr:   psiTaskCounts{running: 1},
+			want: [numPSIResources][numPSIStates]uint64{PSICPU: {1, 1}},
+		},
+		{
+			name: "I/O with running task",
+			nr:   psiTaskCounts{running: 1, onCPU: 1, ioWait: 1},
+			want: [numPSIResources][numPSIStates]uint64{PSIIO: {1, 0}},
+		},
+		{
+			name: "I/O only",
+			nr:   psiTaskCounts{ioWait: 2},
+			want: [numPSIResources][numPSIStates]uint64{PSIIO: {1, 1}},
+		},
+		{
+			name: "memory with running task",
+			nr:   psiTaskCounts{running: 2, onCPU: 2, memStall: 1, memStallRunning: 1},
+			want: [numPSIResources][numPSIStates]uint64{PSIMemory: {1, 0}},
+		},
+		{
+			name: "memory only",
+			nr:   psiTaskCounts{running: 1, onCPU: 1, memStall: 2, memStallRunning: 1},
+			want: [numPSIResources][numPSIStates]uint64{PSIMemory: {1, 1}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			g := NewPSIGroup(&PSIGroup{})
+			g.nr = test.nr
+			g.accountLocked(1)
+			if g.total != test.want {
+				t.Errorf("total = %v, want %v", g.total, test.want)
+			}
+		})
+	}
+}
+
+func TestPSISystemCPUFull(t *testing.T) {
+	var g PSIGroup
+	g.nr = psiTaskCounts{running: 1}
+	g.accountLocked(1)
+	if got := g.total[PSICPU][psiFull]; got != 0 {
+		t.Errorf("system CPU full total = %d, want 0", got)
+	}
+}
+
+func TestPSIAverages(t *testing.T) {
+	var g PSIGroup
+	freq := psiAvgFreq.Nanoseconds()
+	now := int64(0)
+	g.updateAveragesLocked(now)
+	// Stall half of the time for an hour.
+	for i := 0; i < 1800; i++ {
+		g.total[PSIIO][psiSome] += uint64(freq / 2)
+		now += freq
+		g.updateAveragesLocked(now)
+	}
+	want := uint64(50 * loadAvgFixed1)
+	for i, avg := range g.avg[PSIIO][psiSome] {
+		if diff := int64(avg) - int64(want); diff < -loadAvgFixed1 || diff > loadAvgFixed1 {
+			t.Errorf("avg[%d] = %d, want %d", i, avg, want)
+		}
+	}
+	for i, avg := range g.avg[PSIIO][psiFull] {
+		if avg != 0 {
+			t.Errorf("full avg[%d] = %d, want 0", i, avg)
+		}
+	}
+}
+
+func TestPSITrigger(t *testing.T) {
+	g := NewPSIGroup(&PSIGroup{})
+	tr := &PSITrigger{
+		group:     g,
+		res:       PSIMemory,
+		state:     psiSome,
+		threshold: uint64(100 * time.Millisecond),
+		window:    uint64(time.Second),
+	}
+	g.triggers = map[*PSITrigger]struct{}{tr: {}}
+
+	tick := uint64(linux.ClockTick)
+	now := int64(0)
+	stall := func(ticks int) []*PSITrigger {
+		var fired []*PSITrigger
+		for i := 0; i < ticks; i++ {
+			now += int64(tick)
+			g.total[PSIMemory][psiSome] += tick
+			fired = g.updateTriggersLocked(now, fired)
+		}
+		return fired
+	}
+
+	if fired := stall(9); len(fired) != 0 {
+		t.Fatalf("trigger fired after 90ms of stall")
+	}
+	if fired := stall(1); len(fired) != 1 {
+		t.Fatalf("trigger didn't fire after 100ms of stall")
+	}
+	if !tr.pending.Load() {
+		t.Fatalf("trigger isn't pending after firing")
+	}
+	// Events are limited to one per window.
+	if fired := stall(10); len(fired) != 0 {
+		t.Errorf("trigger fired twice in one window")
+	}
+}
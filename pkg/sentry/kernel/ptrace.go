@@ -705,11 +705,11 @@ func (t *Task) ptraceSyscallEnter() (taskRunState, bool) {
 		return nil, false
 	case ptraceSyscallIntercept:
 		t.Debugf("Entering syscall-enter-stop from PTRACE_SYSCALL")
-		t.ptraceSyscallStopLocked()
+		t.ptraceSyscallStopLocked(linux.PTRACE_EVENTMSG_SYSCALL_ENTRY)
 		return (*runSyscallAfterSyscallEnterStop)(nil), true
 	case ptraceSyscallEmu:
 		t.Debugf("Entering syscall-enter-stop from PTRACE_SYSEMU")
-		t.ptraceSyscallStopLocked()
+		t.ptraceSyscallStopLocked(linux.PTRACE_EVENTMSG_SYSCALL_ENTRY)
 		return (*runSyscallAfterSysemuStop)(nil), true
 	}
 	panic(fmt.Sprintf("Unknown ptraceSyscallMode: %v", t.ptraceSyscallMode))
@@ -727,18 +727,135 @@ func (t *Task) ptraceSyscallExit() {
 		return
 	}
 	t.Debugf("Entering syscall-exit-stop")
-	t.ptraceSyscallStopLocked()
+	t.ptraceSyscallStopLocked(linux.PTRACE_EVENTMSG_SYSCALL_EXIT)
 }
 
+// ptraceSyscallStopLocked enters a syscall-enter-stop or syscall-exit-stop.
+// msg is the PTRACE_EVENTMSG_SYSCALL_* value identifying the stop, which is
+// returned by PTRACE_GETEVENTMSG and used by PTRACE_GET_SYSCALL_INFO.
+//
 // Preconditions: The TaskSet mutex must be locked.
-func (t *Task) ptraceSyscallStopLocked() {
+func (t *Task) ptraceSyscallStopLocked(msg uint64) {
 	code := int32(linux.SIGTRAP)
 	if t.ptraceOpts.SysGood {
 		code |= 0x80
 	}
+	t.ptraceEventMsg = msg
 	t.ptraceTrapLocked(code)
 }
 
+// maxSyscallErrno is the largest errno that may be returned by a syscall.
+// Compare Linux's include/linux/err.h:MAX_ERRNO.
+const maxSyscallErrno = 4095
+
+// ptraceGetSyscallInfo implements PTRACE_GET_SYSCALL_INFO for the given
+// ptrace-stopped tracee. It copies at most size bytes of information to addr
+// and returns the size of the available information.
+func (t *Task) ptraceGetSyscallInfo(target *Task, size uint64, addr hostarch.Addr) (uintptr, error) {
+	ac := target.Arch()
+	info := linux.PtraceSyscallInfo{
+		Op:                 linux.PTRACE_SYSCALL_INFO_NONE,
+		Arch:               target.SyscallTable().AuditNumber,
+		InstructionPointer: uint64(ac.IP()),
+		StackPointer:       uint64(ac.Stack()),
+	}
+	infoSize := uint64(linux.PtraceSyscallInfoNoneSize)
+
+	var args [6]uint64
+	for i, arg := range ac.SyscallArgs() {
+		args[i] = arg.Uint64()
+	}
+
+	t.tg.pidns.owner.mu.RLock()
+	var code int32
+	if target.ptraceSiginfo != nil {
+		code = target.ptraceSiginfo.Code
+	}
+	msg := target.ptraceEventMsg
+	t.tg.pidns.owner.mu.RUnlock()
+
+	// Syscall stops are only distinguishable from other SIGTRAP stops if
+	// PTRACE_O_TRACESYSGOOD is set; compare Linux's
+	// kernel/ptrace.c:ptrace_get_syscall_info().
+	switch code {
+	case int32(linux.SIGTRAP) | 0x80:
+		switch msg {
+		case linux.PTRACE_EVENTMSG_SYSCALL_ENTRY:
+			info.Op = linux.PTRACE_SYSCALL_INFO_ENTRY
+			info.SetEntry(uint64(ac.SyscallNo()), args)
+			infoSize = linux.PtraceSyscallInfoEntrySize
+		case linux.PTRACE_EVENTMSG_SYSCALL_EXIT:
+			rval := int64(ac.Return())
+			info.Op = linux.PTRACE_SYSCALL_INFO_EXIT
+			info.SetExit(rval, rval < 0 && rval >= -maxSyscallErrno)
+			infoSize = linux.PtraceSyscallInfoExitSize
+		}
+	case int32(linux.SIGTRAP) | linux.PTRACE_EVENT_SECCOMP<<8:
+		info.Op = linux.PTRACE_SYSCALL_INFO_SECCOMP
+		info.SetSeccomp(uint64(ac.SyscallNo()), args, uint32(msg))
+		infoSize = linux.PtraceSyscallInfoSeccompSize
+	}
+
+	buf := t.CopyScratchBuffer(info.SizeBytes())
+	info.MarshalUnsafe(buf)
+	if _, err := t.CopyOutBytes(addr, buf[:min(size, infoSize)]); err != nil {
+		return 0, err
+	}
+	return uintptr(infoSize), nil
+}
+
+// ptracePeekSiginfo implements PTRACE_PEEKSIGINFO for the given
+// ptrace-stopped tracee. It returns the number of signals copied to data.
+func (t *Task) ptracePeekSiginfo(target *Task, addr, data hostarch.Addr) (uintptr, error) {
+	var args linux.PtracePeekSiginfoArgs
+	if _, err := args.CopyIn(t, addr); err != nil {
+		return 0, err
+	}
+	if args.Flags&^linux.PTRACE_PEEKSIGINFO_SHARED != 0 || args.Nr < 0 {
+		return 0, linuxerr.EINVAL
+	}
+
+	target.tg.signalHandlers.mu.Lock()
+	var infos []linux.SignalInfo
+	if args.Flags&linux.PTRACE_PEEKSIGINFO_SHARED != 0 {
+		infos = target.tg.pendingSignals.peek(args.Off, int(args.Nr))
+	} else {
+		infos = target.pendingSignals.peek(args.Off, int(args.Nr))
+	}
+	target.tg.signalHandlers.mu.Unlock()
+
+	for i := range infos {
+		if _, err := infos[i].CopyOut(t, data); err != nil {
+			if i > 0 {
+				return uintptr(i), nil
+			}
+			return 0, err
+		}
+		data += hostarch.Addr(infos[i].SizeBytes())
+	}
+	return uintptr(len(infos)), nil
+}
+
+// ptraceSeccompGetFilter implements PTRACE_SECCOMP_GET_FILTER for the given
+// ptrace-stopped tracee. It returns the number of instructions in the filter.
+func (t *Task) ptraceSeccompGetFilter(target *Task, index uint64, data hostarch.Addr) (uintptr, error) {
+	// Filters may contain secrets, so only unconfined administrators may read
+	// them; compare Linux's kernel/seccomp.c:get_seccomp_filter().
+	if !t.HasCapabilityIn(linux.CAP_SYS_ADMIN, t.k.RootUserNamespace()) || t.SeccompMode() != linux.SECCOMP_MODE_NONE {
+		return 0, linuxerr.EACCES
+	}
+	filter, err := target.SyscallFilter(index)
+	if err != nil {
+		return 0, err
+	}
+	if data != 0 {
+		if _, err := linux.CopyBPFInstructionSliceOut(t, data, filter); err != nil {
+			return 0, err
+		}
+	}
+	return uintptr(len(filter)), nil
+}
+
 type ptraceCloneKind int32
 
 const (
@@ -1003,16 +1120,16 @@ func (t *Task) ptraceSetOptionsLocked(opts uintptr) error {
 }
 
 // Ptrace implements the ptrace system call.
-func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
+func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) (uintptr, error) {
 	// PTRACE_TRACEME ignores all other arguments.
 	if req == linux.PTRACE_TRACEME {
-		return t.ptraceTraceme()
+		return 0, t.ptraceTraceme()
 	}
 	// All other ptrace requests operate on a current or future tracee
 	// specified by pid.
 	target := t.tg.pidns.TaskWithID(pid)
 	if target == nil {
-		return linuxerr.ESRCH
+		return 0, linuxerr.ESRCH
 	}
 
 	// PTRACE_ATTACH and PTRACE_SEIZE do not require that target is not already
@@ -1020,24 +1137,24 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 	if req == linux.PTRACE_ATTACH || req == linux.PTRACE_SEIZE {
 		seize := req == linux.PTRACE_SEIZE
 		if seize && addr != 0 {
-			return linuxerr.EIO
+			return 0, linuxerr.EIO
 		}
-		return t.ptraceAttach(target, seize, uintptr(data))
+		return 0, t.ptraceAttach(target, seize, uintptr(data))
 	}
 	// PTRACE_KILL and PTRACE_INTERRUPT require that the target is a tracee,
 	// but does not require that it is ptrace-stopped.
 	if req == linux.PTRACE_KILL {
-		return t.ptraceKill(target)
+		return 0, t.ptraceKill(target)
 	}
 	if req == linux.PTRACE_INTERRUPT {
-		return t.ptraceInterrupt(target)
+		return 0, t.ptraceInterrupt(target)
 	}
 	// All other ptrace requests require that the target is a ptrace-stopped
 	// tracee, and freeze the ptrace-stop so the tracee can be operated on.
 	t.tg.pidns.owner.mu.RLock()
 	if target.Tracer() != t {
 		t.tg.pidns.owner.mu.RUnlock()
-		return linuxerr.ESRCH
+		return 0, linuxerr.ESRCH
 	}
 	if !target.ptraceFreeze() {
 		t.tg.pidns.owner.mu.RUnlock()
@@ -1045,7 +1162,7 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 		// PTRACE_TRACEME, PTRACE_INTERRUPT, and PTRACE_KILL) require the
 		// tracee to be in a ptrace-stop, otherwise they fail with ESRCH." -
 		// ptrace(2)
-		return linuxerr.ESRCH
+		return 0, linuxerr.ESRCH
 	}
 	t.tg.pidns.owner.mu.RUnlock()
 	// Even if the target has a ptrace-stop active, the tracee's task goroutine
@@ -1067,56 +1184,56 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 	case linux.PTRACE_DETACH:
 		if err := t.ptraceDetach(target, linux.Signal(data)); err != nil {
 			target.ptraceUnfreeze()
-			return err
+			return 0, err
 		}
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_CONT:
 		if err := target.ptraceUnstop(ptraceSyscallNone, false, linux.Signal(data)); err != nil {
 			target.ptraceUnfreeze()
-			return err
+			return 0, err
 		}
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_SYSCALL:
 		if err := target.ptraceUnstop(ptraceSyscallIntercept, false, linux.Signal(data)); err != nil {
 			target.ptraceUnfreeze()
-			return err
+			return 0, err
 		}
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_SINGLESTEP:
 		if err := target.ptraceUnstop(ptraceSyscallNone, true, linux.Signal(data)); err != nil {
 			target.ptraceUnfreeze()
-			return err
+			return 0, err
 		}
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_SYSEMU:
 		if err := target.ptraceUnstop(ptraceSyscallEmu, false, linux.Signal(data)); err != nil {
 			target.ptraceUnfreeze()
-			return err
+			return 0, err
 		}
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_SYSEMU_SINGLESTEP:
 		if err := target.ptraceUnstop(ptraceSyscallEmu, true, linux.Signal(data)); err != nil {
 			target.ptraceUnfreeze()
-			return err
+			return 0, err
 		}
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_LISTEN:
 		t.tg.pidns.owner.mu.RLock()
 		defer t.tg.pidns.owner.mu.RUnlock()
 		if !target.ptraceSeized {
-			return linuxerr.EIO
+			return 0, linuxerr.EIO
 		}
 		if target.ptraceSiginfo == nil {
-			return linuxerr.EIO
+			return 0, linuxerr.EIO
 		}
 		if target.ptraceSiginfo.Code>>8 != linux.PTRACE_EVENT_STOP {
-			return linuxerr.EIO
+			return 0, linuxerr.EIO
 		}
 		target.tg.signalHandlers.mu.Lock()
 		defer target.tg.signalHandlers.mu.Unlock()
@@ -1126,7 +1243,7 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 			target.stop.(*ptraceStop).listen = true
 			target.ptraceUnfreezeLocked()
 		}
-		return nil
+		return 0, nil
 	}
 
 	// All other ptrace requests expect us to unfreeze the stop.
@@ -1140,15 +1257,15 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 		// is the error flag." - ptrace(2)
 		word := t.Arch().Native(0)
 		if _, err := word.CopyIn(target.CopyContext(t, usermem.IOOpts{IgnorePermissions: true}), addr); err != nil {
-			return err
+			return 0, err
 		}
 		_, err := word.CopyOut(t, data)
-		return err
+		return 0, err
 
 	case linux.PTRACE_POKETEXT, linux.PTRACE_POKEDATA:
 		word := t.Arch().Native(uintptr(data))
 		_, err := word.CopyOut(target.CopyContext(t, usermem.IOOpts{IgnorePermissions: true}), addr)
-		return err
+		return 0, err
 
 	case linux.PTRACE_GETREGSET:
 		// "Read the tracee's registers. addr specifies, in an
@@ -1158,7 +1275,7 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 		// to indicate the actual number of bytes returned." - ptrace(2)
 		ars, err := t.CopyInIovecs(data, 1)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		ar := ars.Head()
@@ -1171,7 +1288,7 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 			},
 		}, int(ar.Length()), target.Kernel().FeatureSet())
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		// Update iovecs to represent the range of the written register set.
@@ -1180,12 +1297,12 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 			panic(fmt.Sprintf("%#x + %#x overflows. Invalid reg size > %#x", ar.Start, n, ar.Length()))
 		}
 		ar.End = end
-		return t.CopyOutIovecs(data, hostarch.AddrRangeSeqOf(ar))
+		return 0, t.CopyOutIovecs(data, hostarch.AddrRangeSeqOf(ar))
 
 	case linux.PTRACE_SETREGSET:
 		ars, err := t.CopyInIovecs(data, 1)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		ar := ars.Head()
@@ -1198,68 +1315,75 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 			},
 		}, int(ar.Length()), target.Kernel().FeatureSet())
 		if err != nil {
-			return err
+			return 0, err
 		}
 		target.p.FullStateChanged()
 		ar.End -= hostarch.Addr(n)
-		return t.CopyOutIovecs(data, hostarch.AddrRangeSeqOf(ar))
+		return 0, t.CopyOutIovecs(data, hostarch.AddrRangeSeqOf(ar))
 
 	case linux.PTRACE_GETSIGINFO:
 		t.tg.pidns.owner.mu.RLock()
 		defer t.tg.pidns.owner.mu.RUnlock()
 		if target.ptraceSiginfo == nil {
-			return linuxerr.EINVAL
+			return 0, linuxerr.EINVAL
 		}
 		_, err := target.ptraceSiginfo.CopyOut(t, data)
-		return err
+		return 0, err
 
 	case linux.PTRACE_SETSIGINFO:
 		var info linux.SignalInfo
 		if _, err := info.CopyIn(t, data); err != nil {
-			return err
+			return 0, err
 		}
 		t.tg.pidns.owner.mu.RLock()
 		defer t.tg.pidns.owner.mu.RUnlock()
 		if target.ptraceSiginfo == nil {
-			return linuxerr.EINVAL
+			return 0, linuxerr.EINVAL
 		}
 		target.ptraceSiginfo = &info
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_GETSIGMASK:
 		if addr != linux.SignalSetSize {
-			return linuxerr.EINVAL
+			return 0, linuxerr.EINVAL
 		}
 		mask := target.SignalMask()
 		_, err := mask.CopyOut(t, data)
-		return err
+		return 0, err
 
 	case linux.PTRACE_SETSIGMASK:
 		if addr != linux.SignalSetSize {
-			return linuxerr.EINVAL
+			return 0, linuxerr.EINVAL
 		}
 		var mask linux.SignalSet
 		if _, err := mask.CopyIn(t, data); err != nil {
-			return err
+			return 0, err
 		}
 		// The target's task goroutine is stopped, so this is safe:
 		target.SetSignalMask(mask &^ UnblockableSignals)
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_SETOPTIONS:
 		t.tg.pidns.owner.mu.Lock()
 		defer t.tg.pidns.owner.mu.Unlock()
-		return target.ptraceSetOptionsLocked(uintptr(data))
+		return 0, target.ptraceSetOptionsLocked(uintptr(data))
 
 	case linux.PTRACE_GETEVENTMSG:
 		t.tg.pidns.owner.mu.RLock()
 		defer t.tg.pidns.owner.mu.RUnlock()
 		_, err := primitive.CopyUint64Out(t, hostarch.Addr(data), target.ptraceEventMsg)
-		return err
+		return 0, err
+
+	case linux.PTRACE_GET_SYSCALL_INFO:
+		return t.ptraceGetSyscallInfo(target, uint64(addr), data)
+
+	case linux.PTRACE_PEEKSIGINFO:
+		return t.ptracePeekSiginfo(target, addr, data)
 
-	// PEEKSIGINFO is unimplemented but seems to have no users anywhere.
+	case linux.PTRACE_SECCOMP_GET_FILTER:
+		return t.ptraceSeccompGetFilter(target, uint64(addr), data)
 
 	default:
-		return t.ptraceArch(target, req, addr, data)
+		return 0, t.ptraceArch(target, req, addr, data)
 	}
 }
@@ -44,6 +44,11 @@ type taskSeccomp struct {
 	// in the order in which they were installed.
 	filters []bpf.Program
 
+	// sources are the programs installed by the application, before
+	// compilation; sources[i] is the program from which filters[i] was
+	// compiled. sources is used to implement PTRACE_SECCOMP_GET_FILTER.
+	sources [][]linux.BPFInstruction
+
 	// cache maps syscall numbers to the action to take for that syscall number.
 	// It is only populated for syscalls where determining this action does not
 	// involve any input data other than the architecture and the syscall
@@ -61,6 +66,7 @@ type taskSeccomp struct {
 func (ts *taskSeccomp) copy() *taskSeccomp {
 	return &taskSeccomp{
 		filters:          append(([]bpf.Program)(nil), ts.filters...),
+		sources:          append(([][]linux.BPFInstruction)(nil), ts.sources...),
 		cacheAuditNumber: ts.cacheAuditNumber,
 		cache:            ts.cache,
 	}
@@ -264,10 +270,11 @@ func (ts *taskSeccomp) populateCache(t *Task) {
 	}
 }
 
-// AppendSyscallFilter adds BPF program p as a system call filter.
+// AppendSyscallFilter adds BPF program p, compiled from source, as a system
+// call filter.
 //
 // Preconditions: The caller must be running on the task goroutine.
-func (t *Task) AppendSyscallFilter(p bpf.Program, syncAll bool) error {
+func (t *Task) AppendSyscallFilter(p bpf.Program, source []linux.BPFInstruction, syncAll bool) error {
 	// While syscallFilters are an atomic.Value we must take the mutex to prevent
 	// our read-copy-update from happening while another task is syncing syscall
 	// filters to us, this keeps the filters in a consistent state.
@@ -285,6 +292,7 @@ func (t *Task) AppendSyscallFilter(p bpf.Program, syncAll bool) error {
 			totalLength += f.Length() + 4
 		}
 		newSeccomp.filters = append(newSeccomp.filters, ts.filters...)
+		newSeccomp.sources = append(newSeccomp.sources, ts.sources...)
 	}
 
 	if totalLength > maxSyscallFilterInstructions {
@@ -292,6 +300,7 @@ func (t *Task) AppendSyscallFilter(p bpf.Program, syncAll bool) error {
 	}
 
 	newSeccomp.filters = append(newSeccomp.filters, p)
+	newSeccomp.sources = append(newSeccomp.sources, source)
 	newSeccomp.populateCache(t)
 	t.seccomp.Store(newSeccomp)
 
@@ -318,3 +327,17 @@ func (t *Task) SeccompMode() int {
 	}
 	return linux.SECCOMP_MODE_NONE
 }
+
+// SyscallFilter returns the source of the seccomp filter at the given index,
+// where the most recently installed filter has index 0, as for
+// PTRACE_SECCOMP_GET_FILTER.
+func (t *Task) SyscallFilter(index uint64) ([]linux.BPFInstruction, error) {
+	ts := t.seccomp.Load()
+	if ts == nil || len(ts.filters) == 0 {
+		return nil, linuxerr.EINVAL
+	}
+	if index >= uint64(len(ts.sources)) {
+		return nil, linuxerr.ENOENT
+	}
+	return ts.sources[uint64(len(ts.sources))-1-index], nil
+}
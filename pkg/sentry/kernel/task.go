@@ -24,6 +24,7 @@ import (
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/metric"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/futex"
@@ -98,6 +99,16 @@ type Task struct {
 	// haveSyscallReturn is exclusive to the task goroutine.
 	haveSyscallReturn bool
 
+	// inSyscall is true if the task goroutine is executing a syscall
+	// implementation, in which case syscallNo and syscallArgs are the number
+	// and arguments of that syscall.
+	//
+	// inSyscall, syscallNo and syscallArgs are exclusive to the task
+	// goroutine.
+	inSyscall   bool                  `state:"nosave"`
+	syscallNo   uintptr               `state:"nosave"`
+	syscallArgs arch.SyscallArguments `state:"nosave"`
+
 	// interruptChan is notified whenever the task goroutine is interrupted
 	// (usually by a pending signal). interruptChan is effectively a condition
 	// variable that can be used in select statements.
@@ -123,6 +134,17 @@ type Task struct {
 	// gostateTime is owned by the task goroutine.
 	gostateTime atomicbitops.Int64
 
+	// blockSite records where the task goroutine last entered a blocked or
+	// stopped gostate. blockSite is owned by the task goroutine, and is
+	// protected by gostateSeq.
+	blockSite blockSite `state:"nosave"`
+
+	// waitChannel is recorded in blockSite when the task goroutine blocks.
+	// waitChannel is set by Task.SetWaitChannel around blocking operations.
+	//
+	// waitChannel is exclusive to the task goroutine.
+	waitChannel WaitChannel `state:"nosave"`
+
 	// appCPUClock approximates the amount of time the task goroutine has spent
 	// in TaskGoroutineRunningApp.
 	appCPUClock ktime.SyntheticClock
@@ -139,6 +161,15 @@ type Task struct {
 	// owned by the task goroutine.
 	yieldCount atomicbitops.Uint64
 
+	// minorFaults and majorFaults are the number of application page faults
+	// handled by the task goroutine that did not and did require I/O
+	// respectively.
+	//
+	// minorFaults and majorFaults are accessed using atomic memory
+	// operations, and are owned by the task goroutine.
+	minorFaults atomicbitops.Uint64
+	majorFaults atomicbitops.Uint64
+
 	// pendingSignals is the set of pending signals that may be handled only by
 	// this task.
 	//
@@ -228,6 +259,13 @@ type Task struct {
 	// stop is protected by the signal mutex.
 	stop TaskStop
 
+	// If cgroupFrozen is true, the task has been frozen by the cgroup v2
+	// freezer, and enters a cgroupFreezeStop instead of returning to user
+	// space.
+	//
+	// cgroupFrozen is protected by the signal mutex.
+	cgroupFrozen bool
+
 	// stopCount is the number of active external stops (calls to
 	// Task.BeginExternalStop that have not been paired with a call to
 	// Task.EndExternalStop), plus 1 if stop is not nil. Hence stopCount is
@@ -618,6 +656,24 @@ type Task struct {
 	// memCgID is the memory cgroup id.
 	memCgID atomicbitops.Uint32
 
+	// psiGroup is the innermost group that the task's pressure stall
+	// information is accounted to, or nil if the task is only accounted to
+	// the system-wide group.
+	psiGroup psiGroupAtomicPtr
+
+	// ioCgroup accounts and throttles the task's file I/O, or is nil if the
+	// task isn't in an io cgroup.
+	ioCgroup ioCgroupAtomicPtr
+
+	// memStall is set while the task goroutine is stalled on memory, for
+	// pressure stall information. memStall is owned by the task goroutine.
+	memStall atomicbitops.Bool
+
+	// ioWait is the number of reads and writes of files outside the sentry
+	// that the task goroutine is performing, for pressure stall information.
+	// ioWait is owned by the task goroutine.
+	ioWait atomicbitops.Int32
+
 	// userCounters is a pointer to a set of user counters.
 	//
 	// The userCounters pointer is exclusive to the task goroutine, but the
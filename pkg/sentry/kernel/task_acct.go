@@ -161,6 +161,20 @@ func (l *rlimitCPUHardListener) NotifyTimer(exp uint64) {
 	l.tg.appSysCPUClockLast.Load().SendGroupSignal(SignalInfoPriv(linux.SIGKILL))
 }
 
+// accountFault records that the task goroutine handled an application page
+// fault. major indicates whether handling the fault required I/O.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) accountFault(major bool) {
+	if major {
+		t.majorFaults.Add(1)
+		t.tg.majorFaults.Add(1)
+	} else {
+		t.minorFaults.Add(1)
+		t.tg.minorFaults.Add(1)
+	}
+}
+
 // IOUsage returns the io usage of the thread.
 func (t *Task) IOUsage() *usage.IO {
 	return t.ioUsage
@@ -180,6 +194,17 @@ func (tg *ThreadGroup) IOUsage() *usage.IO {
 	return &io
 }
 
+// JoinedChildIOUsage returns the total io usage of all joined descendants of
+// tg, as for RUSAGE_CHILDREN.
+func (tg *ThreadGroup) JoinedChildIOUsage() *usage.IO {
+	tg.pidns.owner.mu.RLock()
+	defer tg.pidns.owner.mu.RUnlock()
+
+	var io usage.IO
+	tg.childIOUsage.Clone(&io)
+	return &io
+}
+
 // Name returns t's name.
 func (t *Task) Name() string {
 	t.mu.Lock()
@@ -255,6 +255,17 @@ func (t *Task) UninterruptibleSleepFinish(activate bool) {
 	}
 }
 
+// IOWaitStart implements vfs.IOWaiter.IOWaitStart.
+func (t *Task) IOWaitStart() {
+	t.assertTaskGoroutine()
+	t.ioWait.Add(1)
+}
+
+// IOWaitFinish implements vfs.IOWaiter.IOWaitFinish.
+func (t *Task) IOWaitFinish() {
+	t.ioWait.Add(-1)
+}
+
 // interrupted returns true if interrupt or interruptSelf has been called at
 // least once since the last call to unsetInterrupted.
 func (t *Task) interrupted() bool {
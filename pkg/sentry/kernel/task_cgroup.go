@@ -237,6 +237,15 @@ func (t *Task) GetCgroupEntries() []TaskCgroupEntry {
 	// kernfs.ancestryRWMutex when calculating cgroup paths.
 	cgEntries := make([]TaskCgroupEntry, 0, len(cgroups))
 	for _, c := range cgroups {
+		if c.Unified() {
+			// The unified hierarchy is always reported as "0::<path>".
+			cgEntries = append(cgEntries, TaskCgroupEntry{
+				HierarchyID: 0,
+				Path:        c.Path(),
+			})
+			continue
+		}
+
 		ctls := c.Controllers()
 		ctlNames := make([]string, 0, len(ctls))
 
@@ -277,6 +286,12 @@ func (t *Task) chargeLocked(target *Task, ctl CgroupControllerType, res CgroupRe
 	// Due to the uniqueness of controllers on hierarchies, at most one cgroup
 	// in t.cgroups will match.
 	for c := range t.cgroups {
+		if !c.hasController(ctl) {
+			// With several hierarchies, e.g. cgroup v1 and the unified
+			// hierarchy mounted side by side, t is a member of cgroups that
+			// don't have ctl.
+			continue
+		}
 		err := c.Charge(target, c.Dentry, ctl, res, value)
 		if err == nil {
 			c.IncRef()
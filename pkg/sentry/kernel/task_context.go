@@ -31,6 +31,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/platform"
 	"gvisor.dev/gvisor/pkg/sentry/unimpl"
 	"gvisor.dev/gvisor/pkg/sentry/uniqueid"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sync"
 )
@@ -97,6 +98,13 @@ func (t *Task) contextValue(key any, isTaskGoroutine bool) any {
 			defer t.mu.Unlock()
 		}
 		return t.fsContext.RootDirectory()
+	case vfs.CtxIOAccounter:
+		if g := t.ioCgroup.Load(); g != nil {
+			return g
+		}
+		return nil
+	case vfs.CtxIOWaiter:
+		return t
 	case vfs.CtxMountNamespace:
 		if !isTaskGoroutine {
 			t.mu.Lock()
@@ -134,6 +142,8 @@ func (t *Task) contextValue(key any, isTaskGoroutine bool) any {
 		return t.k.GenerateInotifyCookie()
 	case unimpl.CtxEvents:
 		return t.k
+	case usage.CtxIO:
+		return t.ioUsage
 	case cpuid.CtxFeatureSet:
 		return t.k.featureSet
 	default:
@@ -0,0 +1,852 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+// This file implements core dumps for tasks killed by signals whose default
+// action is SignalActionCore. In brief, the task receiving such a signal
+// (the "dumper") initiates a group exit and waits for all other tasks in its
+// thread group to stop executing application code, analogous to Linux's
+// fs/coredump.c:coredump_wait(). It then writes an ELF core file describing
+// the registers of every thread and the contents of its address space to the
+// destination described by /proc/sys/kernel/core_pattern, and finally
+// completes its own exit.
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/pipefs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/limits"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	// defaultCorePattern is the default value of
+	// /proc/sys/kernel/core_pattern.
+	defaultCorePattern = "core"
+
+	// maxCorePatternLen is the maximum length of a core pattern, including
+	// the NUL terminator. Linux: fs/coredump.c:CORENAME_MAX_SIZE.
+	maxCorePatternLen = 128
+
+	// coreDumpChunkSize is the maximum number of bytes of application memory
+	// that are read at once while writing a core dump.
+	coreDumpChunkSize = 64 * hostarch.PageSize
+
+	// maxCoreDumpRegSetLen is the maximum size of a register set written to
+	// a core dump.
+	maxCoreDumpRegSetLen = hostarch.PageSize
+)
+
+// elfMagicCore is the magic number at the start of an ELF core file.
+var elfMagicCore = []byte{0x7f, 'E', 'L', 'F'}
+
+// CorePattern returns the template used to name core dump files.
+func (k *Kernel) CorePattern() string {
+	k.coreDumpMu.Lock()
+	defer k.coreDumpMu.Unlock()
+	return k.corePattern
+}
+
+// SetCorePattern sets the template used to name core dump files, as for
+// writes to /proc/sys/kernel/core_pattern.
+func (k *Kernel) SetCorePattern(pattern string) error {
+	if len(pattern) >= maxCorePatternLen {
+		return linuxerr.EINVAL
+	}
+	k.coreDumpMu.Lock()
+	defer k.coreDumpMu.Unlock()
+	k.corePattern = pattern
+	return nil
+}
+
+// coreDumpStop is a TaskStop placed on a task that is about to write a core
+// dump while it waits for the other tasks in its thread group to exit.
+//
+// +stateify savable
+type coreDumpStop struct{}
+
+// Killable implements TaskStop.Killable.
+func (*coreDumpStop) Killable() bool { return true }
+
+// beginCoreDump initiates a group exit for a signal whose default action is
+// SignalActionCore, and returns the run state that will write the core dump
+// once all other tasks in the thread group have stopped.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) beginCoreDump(info *linux.SignalInfo) taskRunState {
+	ws := linux.WaitStatusTerminationSignal(linux.Signal(info.Signo))
+
+	t.tg.pidns.owner.mu.Lock()
+	defer t.tg.pidns.owner.mu.Unlock()
+	t.tg.signalHandlers.mu.Lock()
+	defer t.tg.signalHandlers.mu.Unlock()
+
+	if t.tg.exiting || t.tg.execing != nil {
+		// We lost to a racing group exit or exec, which takes precedence;
+		// compare Linux's fs/coredump.c:zap_threads().
+		t.prepareGroupExitLocked(ws)
+		return (*runExit)(nil)
+	}
+
+	// Record the tasks whose state will appear in the core dump before
+	// killing them, since non-leader tasks may be reaped as soon as they
+	// exit. The dumper always comes first, as in Linux.
+	threads := []*Task{t}
+	for sibling := t.tg.tasks.Front(); sibling != nil; sibling = sibling.Next() {
+		if sibling != t && sibling.exitStateLocked() == TaskExitNone {
+			threads = append(threads, sibling)
+		}
+	}
+
+	t.prepareGroupExitLocked(ws)
+	t.tg.coreDumper = t
+	if t.tg.activeTasks > 1 {
+		// The last sibling to call exitThreadGroup will wake t.
+		t.beginInternalStopLocked((*coreDumpStop)(nil))
+	}
+	return &runCoreDump{info: *info, threads: threads}
+}
+
+// runCoreDump writes a core dump after all other tasks in the thread group
+// have stopped executing application code, then continues exiting.
+//
+// +stateify savable
+type runCoreDump struct {
+	// info is the signal that caused the core dump.
+	info linux.SignalInfo
+
+	// threads are the tasks whose state is described by the core dump, with
+	// the dumping task first.
+	threads []*Task
+}
+
+func (r *runCoreDump) execute(t *Task) taskRunState {
+	t.tg.pidns.owner.mu.Lock()
+	t.tg.signalHandlers.mu.Lock()
+	t.tg.coreDumper = nil
+	killed := t.killedLocked()
+	t.tg.signalHandlers.mu.Unlock()
+	t.tg.pidns.owner.mu.Unlock()
+
+	if killed {
+		// A fatal signal aborts the core dump; compare Linux's
+		// fs/coredump.c:dump_interrupted().
+		t.Debugf("Core dump for signal %d aborted: task killed", r.info.Signo)
+		return (*runExit)(nil)
+	}
+
+	if err := t.p.PullFullState(t.MemoryManager().AddressSpace(), t.Arch()); err != nil {
+		t.Warningf("Unable to pull a full state for core dump: %v", err)
+	}
+	if err := t.writeCoreDump(&r.info, r.threads); err != nil {
+		t.Debugf("Core dump for signal %d not written: %v", r.info.Signo, err)
+		return (*runExit)(nil)
+	}
+
+	t.tg.signalHandlers.mu.Lock()
+	t.tg.exitStatus = t.tg.exitStatus.WithCoreDump()
+	t.exitStatus = t.tg.exitStatus
+	t.tg.signalHandlers.mu.Unlock()
+	return (*runExit)(nil)
+}
+
+// prepareCoreDumpExit is called by tasks that are exiting while another task
+// in their thread group is waiting to write a core dump, to ensure that their
+// complete register state is available to the dumper.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) prepareCoreDumpExit() {
+	t.tg.signalHandlers.mu.Lock()
+	dumper := t.tg.coreDumper
+	t.tg.signalHandlers.mu.Unlock()
+	if dumper == nil || dumper == t {
+		return
+	}
+	if err := t.p.PullFullState(t.MemoryManager().AddressSpace(), t.Arch()); err != nil {
+		t.Warningf("Unable to pull a full state for core dump: %v", err)
+	}
+}
+
+// writeCoreDump writes a core dump describing t's thread group to the
+// destination configured by t's kernel. The returned error describes why no
+// core dump was written; it is not returned to the application.
+func (t *Task) writeCoreDump(info *linux.SignalInfo, threads []*Task) error {
+	m := t.MemoryManager()
+	dumpability := m.Dumpability()
+	if dumpability == mm.NotDumpable {
+		return fmt.Errorf("address space is not dumpable")
+	}
+
+	pattern := t.k.CorePattern()
+	if pattern == "" {
+		return fmt.Errorf("core_pattern is empty")
+	}
+	limit := t.tg.Limits().Get(limits.Core).Cur
+
+	var (
+		fd       *vfs.FileDescription
+		maxSize  uint64
+		seekable bool
+	)
+	if helper, ok := strings.CutPrefix(pattern, "|"); ok {
+		// "Since kernel 2.6.19, Linux supports an alternate syntax for the
+		// /proc/sys/kernel/core_pattern file. If the first character of this
+		// file is a pipe symbol (|), then the remainder of the line is
+		// treated as the command-line for a user-space program (or script)
+		// that is to be executed." - core(5)
+		//
+		// RLIMIT_CORE doesn't limit the size of piped core dumps, but a limit
+		// of 1 disables them to protect against recursive crashes of the
+		// helper; compare Linux's fs/coredump.c:do_coredump().
+		if limit == 1 {
+			return fmt.Errorf("RLIMIT_CORE is 1, refusing to pipe core dump")
+		}
+		var argv []string
+		for _, field := range strings.Fields(helper) {
+			argv = append(argv, t.expandCorePattern(field, info, limit))
+		}
+		var err error
+		fd, err = t.startCoreDumpHelper(argv)
+		if err != nil {
+			return fmt.Errorf("failed to start core dump helper %v: %w", argv, err)
+		}
+		maxSize = limits.Infinity
+	} else {
+		if limit < hostarch.PageSize {
+			return fmt.Errorf("RLIMIT_CORE %d is too small", limit)
+		}
+		name := t.expandCorePattern(pattern, info, limit)
+		if dumpability == mm.RootDumpable && !strings.HasPrefix(name, "/") {
+			return fmt.Errorf("core dumps of privileged processes require an absolute core_pattern")
+		}
+		var err error
+		fd, err = t.openCoreDumpFile(name, dumpability == mm.RootDumpable)
+		if err != nil {
+			return fmt.Errorf("failed to open core dump file %q: %w", name, err)
+		}
+		maxSize = limit
+		// openCoreDumpFile only returns regular files.
+		seekable = true
+	}
+	defer fd.DecRef(t)
+
+	w := &coreDumpWriter{t: t, fd: fd, limit: maxSize, seekable: seekable}
+	return t.writeCoreDumpELF(w, info, threads)
+}
+
+// expandCorePattern expands the % specifiers in the given core_pattern
+// template, as described by core(5).
+func (t *Task) expandCorePattern(pattern string, info *linux.SignalInfo, limit uint64) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(pattern) {
+			// A trailing % is dropped.
+			break
+		}
+		switch pattern[i] {
+		case '%':
+			b.WriteByte('%')
+		case 'c':
+			if limit == limits.Infinity {
+				b.WriteString(strconv.FormatInt(-1, 10))
+			} else {
+				b.WriteString(strconv.FormatUint(limit, 10))
+			}
+		case 'd':
+			b.WriteString(strconv.Itoa(int(t.MemoryManager().Dumpability())))
+		case 'e':
+			b.WriteString(t.Name())
+		case 'E':
+			if exe := t.MemoryManager().Executable(); exe != nil {
+				b.WriteString(strings.ReplaceAll(exe.MappedName(t), "/", "!"))
+				exe.DecRef(t)
+			}
+		case 'g':
+			creds := t.Credentials()
+			b.WriteString(strconv.FormatUint(uint64(creds.RealKGID.In(creds.UserNamespace).OrOverflow()), 10))
+		case 'h':
+			b.WriteString(t.UTSNamespace().HostName())
+		case 'i':
+			b.WriteString(strconv.Itoa(int(t.tg.pidns.IDOfTask(t))))
+		case 'I':
+			b.WriteString(strconv.Itoa(int(t.k.tasks.Root.IDOfTask(t))))
+		case 'p':
+			b.WriteString(strconv.Itoa(int(t.tg.pidns.IDOfThreadGroup(t.tg))))
+		case 'P':
+			b.WriteString(strconv.Itoa(int(t.k.tasks.Root.IDOfThreadGroup(t.tg))))
+		case 's':
+			b.WriteString(strconv.Itoa(int(info.Signo)))
+		case 't':
+			b.WriteString(strconv.FormatInt(t.k.RealtimeClock().Now().Seconds(), 10))
+		case 'u':
+			creds := t.Credentials()
+			b.WriteString(strconv.FormatUint(uint64(creds.RealKUID.In(creds.UserNamespace).OrOverflow()), 10))
+		default:
+			// Unknown specifiers are dropped.
+		}
+	}
+	return b.String()
+}
+
+// openCoreDumpFile opens the regular file at the given path, relative to t's
+// working directory, for writing a core dump. If asRoot is true, the file is
+// created and opened with root's filesystem credentials.
+func (t *Task) openCoreDumpFile(name string, asRoot bool) (*vfs.FileDescription, error) {
+	creds := t.Credentials()
+	if asRoot {
+		creds = creds.Fork()
+		creds.EffectiveKUID = auth.RootKUID
+		creds.EffectiveKGID = auth.RootKGID
+	}
+
+	root := t.FSContext().RootDirectory()
+	defer root.DecRef(t)
+	wd := t.FSContext().WorkingDirectory()
+	defer wd.DecRef(t)
+	pop := vfs.PathOperation{
+		Root:  root,
+		Start: wd,
+		Path:  fspath.Parse(name),
+	}
+	flags := uint32(linux.O_CREAT | linux.O_WRONLY | linux.O_NOFOLLOW | linux.O_LARGEFILE)
+	if asRoot {
+		flags |= linux.O_EXCL
+	}
+	fd, err := t.k.VFS().OpenAt(t, creds, &pop, &vfs.OpenOptions{
+		Flags: flags,
+		Mode:  0600,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Refuse to write to files that could be used to trick a privileged
+	// process into corrupting data it doesn't own. Compare Linux's
+	// fs/coredump.c:do_coredump().
+	stat, err := fd.Stat(t, vfs.StatOptions{Mask: linux.STATX_TYPE | linux.STATX_NLINK | linux.STATX_UID})
+	if err != nil {
+		fd.DecRef(t)
+		return nil, err
+	}
+	if stat.Mode&linux.S_IFMT != linux.S_IFREG {
+		fd.DecRef(t)
+		return nil, linuxerr.EINVAL
+	}
+	if stat.Nlink > 1 {
+		fd.DecRef(t)
+		return nil, linuxerr.EMLINK
+	}
+	if auth.KUID(stat.UID) != creds.EffectiveKUID {
+		// Core dumps are only written to files owned by the dumping user.
+		fd.DecRef(t)
+		return nil, linuxerr.EPERM
+	}
+	if err := fd.SetStat(t, vfs.SetStatOptions{
+		Stat: linux.Statx{
+			Mask: linux.STATX_SIZE,
+			Size: 0,
+		},
+	}); err != nil {
+		fd.DecRef(t)
+		return nil, err
+	}
+	return fd, nil
+}
+
+// startCoreDumpHelper starts the core dump helper program described by argv
+// as a new process in the root namespaces, with root credentials and a pipe
+// as its standard input. It returns the write end of the pipe.
+func (t *Task) startCoreDumpHelper(argv []string) (*vfs.FileDescription, error) {
+	if len(argv) == 0 || !strings.HasPrefix(argv[0], "/") {
+		return nil, linuxerr.ENOENT
+	}
+
+	r, w, err := pipefs.NewConnectedPipeFDs(t, t.k.PipeMount(), 0 /* flags */)
+	if err != nil {
+		return nil, err
+	}
+	defer r.DecRef(t)
+
+	fdTable := t.k.NewFDTable()
+	defer fdTable.DecRef(t)
+	if _, err := fdTable.NewFDAt(t, 0, r, FDFlags{}); err != nil {
+		w.DecRef(t)
+		return nil, err
+	}
+
+	// RLIMIT_CORE of 1 prevents the helper from recursively piping its own
+	// core dump to itself if it crashes; compare Linux's
+	// fs/coredump.c:umh_pipe_setup().
+	ls := limits.NewLimitSet()
+	ls.SetUnchecked(limits.Core, limits.Limit{Cur: 1, Max: 1})
+
+	args := CreateProcessArgs{
+		Filename: argv[0],
+		Argv:     argv,
+		// Compare Linux's fs/coredump.c:do_coredump() =>
+		// kernel/umh.c:call_usermodehelper_setup().
+		Envv: []string{
+			"HOME=/",
+			"PATH=/sbin:/bin:/usr/sbin:/usr/bin",
+		},
+		Credentials:          auth.NewRootCredentials(t.k.RootUserNamespace()),
+		FDTable:              fdTable,
+		Umask:                0022,
+		Limits:               ls,
+		MaxSymlinkTraversals: linux.MaxSymlinkTraversals,
+		UTSNamespace:         t.k.RootUTSNamespace(),
+		IPCNamespace:         t.k.RootIPCNamespace(),
+		PIDNamespace:         t.k.RootPIDNamespace(),
+	}
+	tg, _, err := t.k.CreateProcess(args)
+	if err != nil {
+		w.DecRef(t)
+		return nil, err
+	}
+	t.k.StartProcess(tg)
+	return w, nil
+}
+
+// coreDumpWriter writes a core dump to a file, enforcing a size limit.
+type coreDumpWriter struct {
+	t     *Task
+	fd    *vfs.FileDescription
+	limit uint64
+
+	// seekable is true if fd supports seeking, allowing skipped bytes to be
+	// left as a hole in the core dump file instead of being written.
+	seekable bool
+
+	// written is the number of bytes written or skipped so far.
+	written uint64
+}
+
+// Write implements io.Writer.Write. If writing all of src would cause the
+// core dump to exceed its size limit, Write writes as much as the limit allows
+// and returns EFBIG.
+func (w *coreDumpWriter) Write(src []byte) (int, error) {
+	var err error
+	if remaining := w.limit - w.written; uint64(len(src)) > remaining {
+		src = src[:remaining]
+		err = linuxerr.EFBIG
+	}
+	n, werr := w.write(src)
+	w.written += uint64(n)
+	if werr != nil {
+		return n, werr
+	}
+	return n, err
+}
+
+// write writes src to w.fd, blocking if necessary.
+func (w *coreDumpWriter) write(src []byte) (int, error) {
+	t := w.t
+	ioseq := usermem.BytesIOSequence(src)
+	n, err := w.fd.Write(t, ioseq, vfs.WriteOptions{})
+	if !linuxerr.Equals(linuxerr.ErrWouldBlock, err) {
+		return int(n), err
+	}
+
+	// Writes to pipes may block until the helper reads. Only fatal signals
+	// may interrupt such writes; since t is exiting, its signal mask is no
+	// longer observable.
+	t.SetSignalMask(^linux.SignalSet(0))
+	e, ch := waiter.NewChannelEntry(waiter.WritableEvents | waiter.EventHUp | waiter.EventErr)
+	if err := w.fd.EventRegister(&e); err != nil {
+		return int(n), err
+	}
+	defer w.fd.EventUnregister(&e)
+	total := n
+	for {
+		ioseq = ioseq.DropFirst64(n)
+		n, err = w.fd.Write(t, ioseq, vfs.WriteOptions{})
+		total += n
+		if !linuxerr.Equals(linuxerr.ErrWouldBlock, err) {
+			return int(total), err
+		}
+		if err := t.Block(ch); err != nil {
+			return int(total), err
+		}
+	}
+}
+
+// skip advances the core dump by n zero bytes, which are used to represent
+// memory that could not be read or was never populated. If w.fd is seekable,
+// the zero bytes are left as a hole, except for the last one, which is written
+// so that the core dump file extends past the hole; compare Linux's
+// fs/coredump.c:dump_skip_to() and do_coredump().
+func (w *coreDumpWriter) skip(n uint64) error {
+	if n == 0 {
+		return nil
+	}
+	var zeroes [hostarch.PageSize]byte
+	if w.seekable {
+		var err error
+		seek := n - 1
+		if remaining := w.limit - w.written; seek >= remaining {
+			seek = remaining
+			err = linuxerr.EFBIG
+		}
+		if _, serr := w.fd.Seek(w.t, int64(seek), linux.SEEK_CUR); serr != nil {
+			return serr
+		}
+		w.written += seek
+		if err != nil {
+			return err
+		}
+		n -= seek
+	}
+	for n > 0 {
+		chunk := min(n, uint64(len(zeroes)))
+		if _, err := w.Write(zeroes[:chunk]); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// coreDumpNote is an ELF note in a core dump.
+type coreDumpNote struct {
+	name string
+	typ  uint32
+	desc []byte
+}
+
+// alignNote returns n rounded up to the 4-byte alignment of ELF note fields.
+func alignNote(n int) uint64 {
+	return uint64((n + 3) &^ 3)
+}
+
+// appendTo appends the encoded note to buf.
+func (n *coreDumpNote) appendTo(buf []byte) []byte {
+	hdr := linux.ElfNote64{
+		Namesz: uint32(len(n.name) + 1),
+		Descsz: uint32(len(n.desc)),
+		Type:   n.typ,
+	}
+	buf = append(buf, marshal.Marshal(&hdr)...)
+	buf = append(buf, n.name...)
+	buf = append(buf, make([]byte, alignNote(len(n.name)+1)-uint64(len(n.name)))...)
+	buf = append(buf, n.desc...)
+	buf = append(buf, make([]byte, alignNote(len(n.desc))-uint64(len(n.desc)))...)
+	return buf
+}
+
+// coreDumpIDs are the process identifiers recorded in a core dump, as seen in
+// the dumping task's PID namespace.
+type coreDumpIDs struct {
+	pid  int32
+	ppid int32
+	pgrp int32
+	sid  int32
+}
+
+// coreDumpIDs returns the identifiers of t's thread group for inclusion in a
+// core dump.
+func (t *Task) coreDumpIDs() coreDumpIDs {
+	pidns := t.tg.pidns
+	pidns.owner.mu.RLock()
+	defer pidns.owner.mu.RUnlock()
+	ids := coreDumpIDs{pid: int32(pidns.tgids[t.tg])}
+	if parent := t.tg.leader.parent; parent != nil {
+		ids.ppid = int32(pidns.tgids[parent.tg])
+	}
+	if pg := t.tg.processGroup; pg != nil {
+		ids.pgrp = int32(pidns.pgids[pg])
+		ids.sid = int32(pidns.sids[pg.session])
+	}
+	return ids
+}
+
+// coreDumpThreadNotes returns the notes describing the registers of thread
+// other, which is either t or a task in t's thread group that has stopped
+// executing application code.
+func (t *Task) coreDumpThreadNotes(other *Task, info *linux.SignalInfo, ids *coreDumpIDs) []coreDumpNote {
+	var regs bytes.Buffer
+	if _, err := other.Arch().PtraceGetRegs(&regs); err != nil {
+		t.Warningf("Failed to get registers of task %d for core dump: %v", t.tg.pidns.IDOfTask(other), err)
+	}
+	status := linux.ElfPrStatus{
+		Info: linux.ElfSiginfo{
+			Signo: info.Signo,
+			Code:  info.Code,
+		},
+		Cursig:  int16(info.Signo),
+		Sigpend: uint64(other.PendingSignals()),
+		Sighold: uint64(other.SignalMask()),
+		Pid:     int32(t.tg.pidns.IDOfTask(other)),
+		Ppid:    ids.ppid,
+		Pgrp:    ids.pgrp,
+		Sid:     ids.sid,
+	}
+	if other == t.tg.Leader() {
+		// The leader's times cover the whole process. Compare Linux's
+		// fs/binfmt_elf.c:fill_prstatus().
+		cpu := t.tg.CPUStats()
+		status.Utime = linux.DurationToTimeval(cpu.UserTime)
+		status.Stime = linux.DurationToTimeval(cpu.SysTime)
+	} else {
+		cpu := other.CPUStats()
+		status.Utime = linux.DurationToTimeval(cpu.UserTime)
+		status.Stime = linux.DurationToTimeval(cpu.SysTime)
+	}
+	child := t.tg.JoinedChildCPUStats()
+	status.Cutime = linux.DurationToTimeval(child.UserTime)
+	status.Cstime = linux.DurationToTimeval(child.SysTime)
+	if regs.Len() >= status.Reg.SizeBytes() {
+		status.Reg.UnmarshalBytes(regs.Bytes())
+	}
+
+	var fpregs bytes.Buffer
+	_, fperr := other.Arch().PtraceGetRegSet(linux.NT_PRFPREG, &fpregs, maxCoreDumpRegSetLen, t.k.FeatureSet())
+	if fperr == nil && fpregs.Len() > 0 {
+		status.Fpvalid = 1
+	}
+
+	notes := []coreDumpNote{{name: "CORE", typ: linux.NT_PRSTATUS, desc: marshal.Marshal(&status)}}
+	if status.Fpvalid != 0 {
+		notes = append(notes, coreDumpNote{name: "CORE", typ: linux.NT_PRFPREG, desc: fpregs.Bytes()})
+	}
+	return notes
+}
+
+// coreDumpProcessNotes returns the notes describing t's thread group as a
+// whole.
+func (t *Task) coreDumpProcessNotes(info *linux.SignalInfo, ids *coreDumpIDs, vmas []mm.CoreDumpVMA) []coreDumpNote {
+	m := t.MemoryManager()
+
+	// NT_PRPSINFO.
+	creds := t.Credentials()
+	psinfo := linux.ElfPrPsinfo{
+		State: 0,
+		Sname: 'R',
+		UID:   uint32(creds.RealKUID.In(creds.UserNamespace).OrOverflow()),
+		GID:   uint32(creds.RealKGID.In(creds.UserNamespace).OrOverflow()),
+		Pid:   ids.pid,
+		Ppid:  ids.ppid,
+		Pgrp:  ids.pgrp,
+		Sid:   ids.sid,
+	}
+	copy(psinfo.Fname[:len(psinfo.Fname)-1], t.Name())
+	if argvStart, argvEnd := m.ArgvStart(), m.ArgvEnd(); argvEnd > argvStart {
+		n := min(int(argvEnd-argvStart), len(psinfo.Psargs)-1)
+		if _, err := m.CopyIn(t, argvStart, psinfo.Psargs[:n], usermem.IOOpts{IgnorePermissions: true}); err == nil {
+			// Arguments are separated by NUL bytes in memory but by spaces
+			// in psargs.
+			for i := range psinfo.Psargs[:n] {
+				if psinfo.Psargs[i] == 0 {
+					psinfo.Psargs[i] = ' '
+				}
+			}
+		}
+	}
+
+	// NT_AUXV.
+	var auxv []byte
+	for _, e := range append(m.Auxv(), arch.AuxEntry{Key: linux.AT_NULL}) {
+		auxv = hostarch.ByteOrder.AppendUint64(auxv, e.Key)
+		auxv = hostarch.ByteOrder.AppendUint64(auxv, uint64(e.Value))
+	}
+
+	// NT_FILE: "long count -- how many files are mapped; long page_size --
+	// units for file_ofs; array of [COUNT] elements of { long start; long
+	// end; long file_ofs; }; followed by COUNT filenames in ASCII" -
+	// fs/binfmt_elf.c:fill_files_note().
+	var (
+		files     []byte
+		filenames []byte
+		count     uint64
+	)
+	for _, vma := range vmas {
+		if !vma.FileBacked {
+			continue
+		}
+		files = hostarch.ByteOrder.AppendUint64(files, uint64(vma.Range.Start))
+		files = hostarch.ByteOrder.AppendUint64(files, uint64(vma.Range.End))
+		files = hostarch.ByteOrder.AppendUint64(files, vma.Offset/hostarch.PageSize)
+		filenames = append(filenames, vma.Name...)
+		filenames = append(filenames, 0)
+		count++
+	}
+	var fileNote []byte
+	fileNote = hostarch.ByteOrder.AppendUint64(fileNote, count)
+	fileNote = hostarch.ByteOrder.AppendUint64(fileNote, hostarch.PageSize)
+	fileNote = append(fileNote, files...)
+	fileNote = append(fileNote, filenames...)
+
+	return []coreDumpNote{
+		{name: "CORE", typ: linux.NT_PRPSINFO, desc: marshal.Marshal(&psinfo)},
+		{name: "CORE", typ: linux.NT_SIGINFO, desc: marshal.Marshal(info)},
+		{name: "CORE", typ: linux.NT_AUXV, desc: auxv},
+		{name: "CORE", typ: linux.NT_FILE, desc: fileNote},
+	}
+}
+
+// writeCoreDumpELF writes an ELF core file describing t's thread group to w.
+// Compare Linux's fs/binfmt_elf.c:elf_core_dump().
+func (t *Task) writeCoreDumpELF(w *coreDumpWriter, info *linux.SignalInfo, threads []*Task) error {
+	m := t.MemoryManager()
+	vmas := m.CoreDumpVMAs(t)
+
+	var machine elf.Machine
+	switch t.Arch().Arch() {
+	case arch.AMD64:
+		machine = elf.EM_X86_64
+	case arch.ARM64:
+		machine = elf.EM_AARCH64
+	default:
+		return fmt.Errorf("unsupported architecture %v", t.Arch().Arch())
+	}
+
+	// Build the notes. The first thread's notes are followed by the
+	// process-wide notes, which are followed by the remaining threads' notes.
+	ids := t.coreDumpIDs()
+	var notes []byte
+	for i, thread := range threads {
+		threadNotes := t.coreDumpThreadNotes(thread, info, &ids)
+		notes = threadNotes[0].appendTo(notes)
+		if i == 0 {
+			for _, n := range t.coreDumpProcessNotes(info, &ids, vmas) {
+				notes = n.appendTo(notes)
+			}
+		}
+		for _, n := range threadNotes[1:] {
+			notes = n.appendTo(notes)
+		}
+	}
+
+	var (
+		ehdr linux.ElfHeader64
+		phdr linux.ElfProg64
+	)
+	phnum := 1 + len(vmas)
+	if phnum >= int(elf.PN_XNUM) {
+		return fmt.Errorf("too many mappings (%d) to dump", len(vmas))
+	}
+	notesOff := uint64(ehdr.SizeBytes() + phnum*phdr.SizeBytes())
+	dataOff, ok := hostarch.Addr(notesOff + uint64(len(notes))).RoundUp()
+	if !ok {
+		return linuxerr.EFBIG
+	}
+
+	copy(ehdr.Ident[:], elfMagicCore)
+	ehdr.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	ehdr.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	ehdr.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	ehdr.Ident[elf.EI_OSABI] = byte(elf.ELFOSABI_NONE)
+	ehdr.Type = uint16(elf.ET_CORE)
+	ehdr.Machine = uint16(machine)
+	ehdr.Version = uint32(elf.EV_CURRENT)
+	ehdr.Phoff = uint64(ehdr.SizeBytes())
+	ehdr.Ehsize = uint16(ehdr.SizeBytes())
+	ehdr.Phentsize = uint16(phdr.SizeBytes())
+	ehdr.Phnum = uint16(phnum)
+
+	hdrs := marshal.Marshal(&ehdr)
+	phdr = linux.ElfProg64{
+		Type:   uint32(elf.PT_NOTE),
+		Off:    notesOff,
+		Filesz: uint64(len(notes)),
+	}
+	hdrs = append(hdrs, marshal.Marshal(&phdr)...)
+	off := uint64(dataOff)
+	for _, vma := range vmas {
+		var flags elf.ProgFlag
+		if vma.Perms.Read {
+			flags |= elf.PF_R
+		}
+		if vma.Perms.Write {
+			flags |= elf.PF_W
+		}
+		if vma.Perms.Execute {
+			flags |= elf.PF_X
+		}
+		phdr = linux.ElfProg64{
+			Type:   uint32(elf.PT_LOAD),
+			Flags:  uint32(flags),
+			Off:    off,
+			Vaddr:  uint64(vma.Range.Start),
+			Filesz: vma.DumpSize,
+			Memsz:  uint64(vma.Range.Length()),
+			Align:  hostarch.PageSize,
+		}
+		hdrs = append(hdrs, marshal.Marshal(&phdr)...)
+		off += vma.DumpSize
+	}
+
+	if _, err := w.Write(hdrs); err != nil {
+		return err
+	}
+	if _, err := w.Write(notes); err != nil {
+		return err
+	}
+	if err := w.skip(uint64(dataOff) - notesOff - uint64(len(notes))); err != nil {
+		return err
+	}
+
+	buf := make([]byte, coreDumpChunkSize)
+	for _, vma := range vmas {
+		for addr, end := vma.Range.Start, vma.Range.Start+hostarch.Addr(vma.DumpSize); addr < end; {
+			// Skip over pages that have never been populated instead of
+			// faulting them in.
+			ar := m.NextCoreDumpRange(hostarch.AddrRange{addr, end})
+			if err := w.skip(uint64(ar.Start - addr)); err != nil {
+				return err
+			}
+			for addr = ar.Start; addr < ar.End; {
+				chunk := buf[:min(uint64(ar.End-addr), uint64(len(buf)))]
+				n, err := m.CopyIn(t, addr, chunk, usermem.IOOpts{IgnorePermissions: true})
+				if n > 0 {
+					if _, err := w.Write(chunk[:n]); err != nil {
+						return err
+					}
+					addr += hostarch.Addr(n)
+				}
+				if err != nil {
+					// Memory that can't be read, e.g. a mapped file that has
+					// been truncated, is represented by a page of zeroes.
+					// Compare Linux's fs/coredump.c:dump_user_range().
+					skip := min(uint64(ar.End-addr), hostarch.PageSize-uint64(addr.PageOffset()))
+					if err := w.skip(skip); err != nil {
+						return err
+					}
+					addr += hostarch.Addr(skip)
+				}
+			}
+		}
+	}
+	return nil
+}
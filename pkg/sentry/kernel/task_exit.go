@@ -255,6 +255,7 @@ func (*runExitMain) execute(t *Task) taskRunState {
 		})
 	}
 
+	t.prepareCoreDumpExit()
 	lastExiter := t.exitThreadGroup()
 
 	t.ResetKcov()
@@ -356,6 +357,14 @@ func (t *Task) exitThreadGroup() bool {
 	t.tg.activeTasks--
 	last := t.tg.activeTasks == 0
 
+	// If another task is waiting to write a core dump, and it is the only
+	// remaining active task, wake it.
+	if dumper := t.tg.coreDumper; dumper != nil && dumper != t && t.tg.activeTasks == 1 {
+		if _, ok := dumper.stop.(*coreDumpStop); ok {
+			dumper.endInternalStopLocked()
+		}
+	}
+
 	// Ensure that someone will handle the signals we can't.
 	t.setSignalMaskLocked(^linux.SignalSet(0))
 
@@ -1022,6 +1031,8 @@ func (t *Task) Wait(opts *WaitOptions) (*WaitResult, error) {
 	w, ch := waiter.NewChannelEntry(opts.Events)
 	t.tg.eventQueue.EventRegister(&w)
 	defer t.tg.eventQueue.EventUnregister(&w)
+	prev := t.SetWaitChannel(WaitChannelWait)
+	defer t.SetWaitChannel(prev)
 	for {
 		wr, err := t.waitOnce(opts)
 		if err != ErrNoWaitableEvent {
@@ -1192,6 +1203,10 @@ func (t *Task) waitCollectZombieLocked(target *Task, opts *WaitOptions, asPtrace
 		if target == target.tg.leader {
 			t.tg.childCPUStats.Accumulate(target.tg.CPUStats())
 			t.tg.childCPUStats.Accumulate(target.tg.childCPUStats)
+			// All tasks in target.tg have exited, so their I/O usage has been
+			// accumulated into target.tg.ioUsage.
+			t.tg.childIOUsage.Accumulate(target.tg.ioUsage)
+			t.tg.childIOUsage.Accumulate(&target.tg.childIOUsage)
 			// Update t's child max resident set size. The size will be the maximum
 			// of this thread's size and all its childrens' sizes.
 			if t.tg.childMaxRSS < target.tg.maxRSS {
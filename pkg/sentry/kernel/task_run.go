@@ -287,9 +287,12 @@ func (app *runApp) execute(t *Task) taskRunState {
 
 			region := trace.StartRegion(t.traceContext, faultRegion)
 			addr := hostarch.Addr(info.Addr())
-			err := t.MemoryManager().HandleUserFault(t, addr, at, hostarch.Addr(t.Arch().Stack()))
+			t.memStall.Store(true)
+			major, err := t.MemoryManager().HandleUserFault(t, addr, at, hostarch.Addr(t.Arch().Stack()))
+			t.memStall.Store(false)
 			region.End()
 			if err == nil {
+				t.accountFault(major)
 				// The fault was handled appropriately.
 				// We can resume running the application.
 				return (*runApp)(nil)
@@ -91,11 +91,18 @@ func (t *Task) accountTaskGoroutineEnter(state TaskGoroutineState) {
 	t.gostateSeq.BeginWrite()
 	t.gostate.Store(uint32(state))
 	t.touchGostateTime()
+	switch state {
+	case TaskGoroutineBlockedInterruptible, TaskGoroutineBlockedUninterruptible, TaskGoroutineStopped:
+		t.recordBlockSiteLocked()
+	}
 	t.gostateSeq.EndWrite()
 	if state != TaskGoroutineRunningApp {
 		// Task is blocking/stopping.
 		t.k.decRunningTasks()
 	}
+	if state == TaskGoroutineBlockedUninterruptible {
+		t.k.uninterruptibleTasks.Add(1)
+	}
 }
 
 // Preconditions:
@@ -103,6 +110,9 @@ func (t *Task) accountTaskGoroutineEnter(state TaskGoroutineState) {
 //   - The caller must be leaving a state indicated by a previous call to
 //     t.accountTaskGoroutineEnter(state).
 func (t *Task) accountTaskGoroutineLeave(state TaskGoroutineState) {
+	if state == TaskGoroutineBlockedUninterruptible {
+		t.k.uninterruptibleTasks.Add(-1)
+	}
 	if state != TaskGoroutineRunningApp {
 		// Task is unblocking/continuing.
 		t.k.incRunningTasks()
@@ -162,6 +172,8 @@ func (t *Task) CPUStats() usage.CPUStats {
 		UserTime:          time.Duration(appNS),
 		SysTime:           time.Duration(sysNS),
 		VoluntarySwitches: t.yieldCount.Load(),
+		MinorFaults:       t.minorFaults.Load(),
+		MajorFaults:       t.majorFaults.Load(),
 	}
 }
 
@@ -177,6 +189,8 @@ func (tg *ThreadGroup) CPUStats() usage.CPUStats {
 		UserTime:          time.Duration(appNS),
 		SysTime:           time.Duration(sysNS),
 		VoluntarySwitches: tg.yieldCount.Load(),
+		MinorFaults:       tg.minorFaults.Load(),
+		MajorFaults:       tg.majorFaults.Load(),
 	}
 }
 
@@ -196,11 +210,27 @@ func (k *Kernel) runCPUClockTicker() {
 	var (
 		allTasks []*Task
 		incTasks = make([]*Task, k.applicationCores)
+		ticks    uint64
+		psi      psiSampler
 	)
 
 	for {
 		// Stop CPU clocks while nothing is running.
 		if k.runningTasks.Load() == 0 {
+			// Tasks in uninterruptible sleep remain stalled while the ticker
+			// is idle. Since only running tasks can enter uninterruptible
+			// sleep, and tasks leaving it wake the ticker, their states can't
+			// change until then. So sample them once now, and account the
+			// idle time to pressure stall information when the ticker
+			// resumes.
+			var idleStart int64
+			if k.uninterruptibleTasks.Load() != 0 {
+				allTasks = k.tasks.Root.TasksAppend(allTasks)
+				psi.sample(k, allTasks, nil)
+				clear(allTasks)
+				allTasks = allTasks[:0]
+				idleStart = k.MonotonicClock().Now().Nanoseconds()
+			}
 			k.runningTasksMu.Lock()
 			if k.runningTasks.Load() == 0 {
 				k.cpuClockTickerRunning = false
@@ -212,6 +242,10 @@ func (k *Kernel) runCPUClockTicker() {
 				// k.runningTasksCond.Wait().
 			}
 			k.runningTasksMu.Unlock()
+			if idleStart != 0 {
+				now := k.MonotonicClock().Now().Nanoseconds()
+				psi.account(now, uint64(now-idleStart))
+			}
 		}
 
 		// Wait for the next CPU clock tick.
@@ -225,6 +259,21 @@ func (k *Kernel) runCPUClockTicker() {
 		// Advance the "kernel CPU clock".
 		k.cpuClock.Add(linux.ClockTick.Nanoseconds())
 
+		// Sample the load average. While the ticker is idle, missed samples
+		// are folded in by the next update (here or in k.LoadAverage()).
+		k.updateLoadAverage()
+
+		// Enforce memory cgroup limits. This is done less frequently than
+		// other per-tick work, see memoryLimitCheckTicks, and on another
+		// goroutine since it may be slow. If the previous check is still in
+		// progress, skip this one.
+		if ticks++; ticks%memoryLimitCheckTicks == 0 {
+			select {
+			case k.memoryLimitCheckCh <- struct{}{}:
+			default:
+			}
+		}
+
 		// Advance CPU clocks. gVisor generally has no knowledge of when sentry
 		// or application code is actually running on a CPU (due to Go and/or
 		// host kernel scheduling, with significant variation between
@@ -280,6 +329,11 @@ func (k *Kernel) runCPUClockTicker() {
 			}
 		}
 
+		// Sample pressure stall information, treating the tasks that were
+		// accounted CPU time as the ones that were running on a CPU.
+		psi.sample(k, allTasks, incTasks[:numIncTasks])
+		psi.account(k.MonotonicClock().Now().Nanoseconds(), uint64(linux.ClockTick))
+
 		// Reset storage for the next iteration.
 		clear(allTasks)
 		allTasks = allTasks[:0]
@@ -200,6 +200,11 @@ func (t *Task) deliverSignal(info *linux.SignalInfo, act linux.SigAction) taskRu
 		t.Debugf("Signal %d, PID: %d, TID: %d, fault addr: %#x: terminating thread group", info.Signo, ucs.Pid, ucs.Tid, ucs.FaultAddr)
 		eventchannel.Emit(ucs)
 
+		if sigact == SignalActionCore {
+			// "Default action is to terminate the process and dump core" -
+			// signal(7)
+			return t.beginCoreDump(info)
+		}
 		t.PrepareGroupExit(linux.WaitStatusTerminationSignal(sig))
 		return (*runExit)(nil)
 
@@ -349,7 +354,9 @@ func (t *Task) Sigtimedwait(set linux.SignalSet, timeout time.Duration) (*linux.
 
 	// Wait for a timeout or new signal.
 	t.tg.signalHandlers.mu.Unlock()
+	prev := t.SetWaitChannel(WaitChannelSigtimedwait)
 	_, err := t.BlockWithTimeout(nil, true, timeout)
+	t.SetWaitChannel(prev)
 	t.tg.signalHandlers.mu.Lock()
 
 	// Restore the original signal mask.
@@ -1041,6 +1048,15 @@ func (*runInterrupt) execute(t *Task) taskRunState {
 		return (*runInterrupt)(nil)
 	}
 
+	// Are we frozen by the cgroup v2 freezer? This path is analogous to
+	// Linux's kernel/signal.c:get_signal() => do_freezer_trap(). SIGKILL
+	// ends the stop, and is then dequeued below.
+	if t.cgroupFrozen && !t.killedLocked() {
+		t.beginInternalStopLocked((*cgroupFreezeStop)(nil))
+		t.tg.signalHandlers.mu.Unlock()
+		return (*runInterrupt)(nil)
+	}
+
 	// Are there signals pending?
 	if info := t.dequeueSignalLocked(linux.SignalSet(t.signalMask.RacyLoad())); info != nil {
 		if err := t.p.PullFullState(t.MemoryManager().AddressSpace(), t.Arch()); err != nil {
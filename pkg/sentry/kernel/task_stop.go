@@ -138,6 +138,34 @@ func (t *Task) endInternalStopLocked() {
 	t.endStopLocked()
 }
 
+// cgroupFreezeStop is a TaskStop placed on tasks frozen by the cgroup v2
+// freezer. As in Linux, frozen tasks can still be killed.
+//
+// +stateify savable
+type cgroupFreezeStop struct{}
+
+// Killable implements TaskStop.Killable.
+func (*cgroupFreezeStop) Killable() bool { return true }
+
+// SetCgroupFrozen freezes or thaws t on behalf of the cgroup v2 freezer. A
+// frozen task enters a cgroupFreezeStop before returning to user space, and
+// remains stopped until it is thawed or killed. SetCgroupFrozen does not wait
+// for t's task goroutine to stop. This is analogous to Linux's
+// kernel/cgroup/freezer.c:cgroup_freeze_task().
+func (t *Task) SetCgroupFrozen(frozen bool) {
+	sh := t.tg.signalLock()
+	defer sh.mu.Unlock()
+	if t.cgroupFrozen == frozen {
+		return
+	}
+	t.cgroupFrozen = frozen
+	if frozen {
+		t.interrupt()
+	} else if _, ok := t.stop.(*cgroupFreezeStop); ok {
+		t.endInternalStopLocked()
+	}
+}
+
 // BeginExternalStop indicates the start of an external stop that applies to t.
 // BeginExternalStop does not wait for t's task goroutine to stop.
 func (t *Task) BeginExternalStop() {
@@ -138,6 +138,9 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 		if trace.IsEnabled() {
 			region = trace.StartRegion(t.traceContext, s.LookupName(sysno))
 		}
+		t.inSyscall = true
+		t.syscallNo = sysno
+		t.syscallArgs = args
 		if fn != nil {
 			// Call our syscall implementation.
 			rval, ctrl, err = fn(t, sysno, args)
@@ -145,6 +148,7 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 			// Use the missing function if not found.
 			rval, err = t.SyscallTable().Missing(t, sysno, args)
 		}
+		t.inSyscall = false
 		if region != nil {
 			region.End()
 		}
@@ -0,0 +1,248 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"reflect"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+)
+
+// WaitChannel identifies what a task goroutine is waiting for while it is
+// blocked, for /proc/[pid]/wchan and /proc/[pid]/stack.
+type WaitChannel uint32
+
+// Wait channels, named for the closest equivalent Linux function.
+const (
+	// WaitChannelNone indicates that the task goroutine's wait channel is
+	// unknown.
+	WaitChannelNone WaitChannel = iota
+	WaitChannelSelect
+	WaitChannelPoll
+	WaitChannelEpoll
+	WaitChannelFutexWait
+	WaitChannelFutexLockPI
+	WaitChannelWait
+	WaitChannelNanosleep
+	WaitChannelPause
+	WaitChannelSigsuspend
+	WaitChannelSigtimedwait
+
+	// WaitChannelRead and WaitChannelWrite are qualified by the package
+	// implementing the file being read or written, e.g. "pipe_read".
+	WaitChannelRead
+	WaitChannelWrite
+)
+
+var waitChannelNames = [...]string{
+	WaitChannelNone:         "",
+	WaitChannelSelect:       "do_select",
+	WaitChannelPoll:         "do_sys_poll",
+	WaitChannelEpoll:        "ep_poll",
+	WaitChannelFutexWait:    "futex_wait_queue",
+	WaitChannelFutexLockPI:  "futex_lock_pi",
+	WaitChannelWait:         "do_wait",
+	WaitChannelNanosleep:    "hrtimer_nanosleep",
+	WaitChannelPause:        "sys_pause",
+	WaitChannelSigsuspend:   "sigsuspend",
+	WaitChannelSigtimedwait: "do_sigtimedwait",
+	WaitChannelRead:         "read",
+	WaitChannelWrite:        "write",
+}
+
+// SetWaitChannel sets the wait channel reported while t's task goroutine is
+// blocked, and returns the previous wait channel, which the caller should
+// restore once it is done blocking.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) SetWaitChannel(wc WaitChannel) WaitChannel {
+	prev := t.waitChannel
+	t.waitChannel = wc
+	return prev
+}
+
+// blockSite records where the task goroutine last blocked or stopped, for
+// /proc/[pid]/stack, /proc/[pid]/syscall and /proc/[pid]/wchan.
+//
+// All fields in blockSite are owned by the task goroutine, and are written
+// within Task.gostateSeq write critical sections; they are accessed using
+// atomic memory operations so that other goroutines may read them while
+// holding a gostateSeq read epoch.
+type blockSite struct {
+	// wchan is the task goroutine's WaitChannel when it blocked.
+	wchan atomicbitops.Uint32
+
+	// sysno is the number of the syscall that blocked, or -1 if the task
+	// goroutine blocked outside of a syscall.
+	sysno atomicbitops.Int64
+
+	// args are the arguments of the syscall that blocked. args is only
+	// meaningful if sysno != -1.
+	args [6]atomicbitops.Uint64
+
+	// sp and pc are the application's stack pointer and instruction pointer
+	// when the task goroutine blocked.
+	sp atomicbitops.Uint64
+	pc atomicbitops.Uint64
+}
+
+// recordBlockSiteLocked records the current state of the task goroutine in
+// t.blockSite.
+//
+// Preconditions:
+//   - The caller must be running on the task goroutine.
+//   - The caller must be in a t.gostateSeq write critical section.
+func (t *Task) recordBlockSiteLocked() {
+	b := &t.blockSite
+	b.wchan.Store(uint32(t.waitChannel))
+	if t.inSyscall {
+		b.sysno.Store(int64(t.syscallNo))
+		for i, arg := range t.syscallArgs {
+			b.args[i].Store(arg.Uint64())
+		}
+	} else {
+		b.sysno.Store(-1)
+	}
+	b.sp.Store(uint64(t.Arch().Stack()))
+	b.pc.Store(uint64(t.Arch().IP()))
+}
+
+// TaskBlockInfo describes where a task goroutine is blocked or stopped.
+type TaskBlockInfo struct {
+	// State is the state of the task goroutine. If State is not
+	// TaskGoroutineBlockedInterruptible, TaskGoroutineBlockedUninterruptible,
+	// or TaskGoroutineStopped, all other fields are unset.
+	State TaskGoroutineState
+
+	// WaitChannel is what the task goroutine is waiting for.
+	WaitChannel WaitChannel
+
+	// Sysno is the number of the blocked syscall, or -1 if the task goroutine
+	// is not blocked in a syscall.
+	Sysno int64
+
+	// Args are the arguments of the blocked syscall.
+	Args [6]uint64
+
+	// SP and PC are the application's stack pointer and instruction pointer.
+	SP uint64
+	PC uint64
+}
+
+// Blocked returns true if info describes a blocked or stopped task
+// goroutine.
+func (info *TaskBlockInfo) Blocked() bool {
+	switch info.State {
+	case TaskGoroutineBlockedInterruptible, TaskGoroutineBlockedUninterruptible, TaskGoroutineStopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// BlockInfo returns a description of where t's task goroutine is blocked or
+// stopped.
+func (t *Task) BlockInfo() TaskBlockInfo {
+	for {
+		epoch := t.gostateSeq.BeginRead()
+		info := TaskBlockInfo{State: t.TaskGoroutineState()}
+		if info.Blocked() {
+			b := &t.blockSite
+			info.WaitChannel = WaitChannel(b.wchan.Load())
+			info.Sysno = b.sysno.Load()
+			for i := range info.Args {
+				info.Args[i] = b.args[i].Load()
+			}
+			info.SP = b.sp.Load()
+			info.PC = b.pc.Load()
+		}
+		if t.gostateSeq.ReadOk(epoch) {
+			return info
+		}
+	}
+}
+
+// WaitChannel returns the name of the Linux function that is the closest
+// equivalent to what t's task goroutine is blocked on, analogous to Linux's
+// /proc/[pid]/wchan, or "0" if the task goroutine is not blocked or the
+// equivalent Linux function is unknown.
+func (t *Task) WaitChannel() string {
+	info := t.BlockInfo()
+	if wchan := t.linuxWaitChannel(&info); wchan != "" {
+		return wchan
+	}
+	return "0"
+}
+
+// KernelStack returns the names of the Linux functions that approximate the
+// kernel stack on which t's task goroutine is blocked, innermost first, or nil
+// if the task goroutine is not blocked or the equivalent Linux functions are
+// unknown.
+func (t *Task) KernelStack() []string {
+	info := t.BlockInfo()
+	wchan := t.linuxWaitChannel(&info)
+	if wchan == "" {
+		return nil
+	}
+	return []string{wchan}
+}
+
+// linuxWaitChannel returns the Linux wait channel equivalent to where the task
+// goroutine described by info is blocked, or an empty string if the task
+// goroutine is not blocked or there is no known equivalent.
+func (t *Task) linuxWaitChannel(info *TaskBlockInfo) string {
+	if !info.Blocked() {
+		return ""
+	}
+	if info.State == TaskGoroutineStopped {
+		return "do_signal_stop"
+	}
+	wc := info.WaitChannel
+	if int(wc) >= len(waitChannelNames) {
+		return ""
+	}
+	name := waitChannelNames[wc]
+	if (wc == WaitChannelRead || wc == WaitChannelWrite) && info.Sysno != -1 {
+		// The first argument to all read and write syscalls is the file
+		// descriptor.
+		if pkg := t.filePackage(int32(info.Args[0])); pkg != "" {
+			return pkg + "_" + name
+		}
+	}
+	return name
+}
+
+// filePackage returns the last element of the package path of the
+// implementation of the file represented by fd in t's file descriptor table,
+// or an empty string if no such file exists.
+func (t *Task) filePackage(fd int32) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fdTable == nil {
+		return ""
+	}
+	// We don't need a reference on file since we only inspect its type.
+	file, _, _ := t.fdTable.get(fd)
+	if file == nil {
+		return ""
+	}
+	typ := reflect.TypeOf(file.Impl())
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	pkg := typ.PkgPath()
+	return pkg[strings.LastIndexByte(pkg, '/')+1:]
+}
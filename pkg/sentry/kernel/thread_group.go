@@ -210,6 +210,12 @@ type ThreadGroup struct {
 	// in the thread group.
 	yieldCount atomicbitops.Uint64
 
+	// minorFaults and majorFaults are the sums of Task.minorFaults and
+	// Task.majorFaults respectively for all past and present tasks in the
+	// thread group.
+	minorFaults atomicbitops.Uint64
+	majorFaults atomicbitops.Uint64
+
 	// childCPUStats is the CPU usage of all joined descendants of this thread
 	// group. childCPUStats is protected by the TaskSet mutex.
 	childCPUStats usage.CPUStats
@@ -218,6 +224,10 @@ type ThreadGroup struct {
 	// The ioUsage pointer is immutable.
 	ioUsage *usage.IO
 
+	// childIOUsage is the I/O usage of all joined descendants of this thread
+	// group. childIOUsage is protected by the TaskSet mutex.
+	childIOUsage usage.IO
+
 	// maxRSS is the historical maximum resident set size of the thread group, updated when:
 	//
 	//	- A task in the thread group exits, since after all tasks have
@@ -259,11 +269,14 @@ type ThreadGroup struct {
 	// tty is protected by the signal mutex.
 	tty *TTY
 
-	// oomScoreAdj is the thread group's OOM score adjustment. This is
-	// currently not used but is maintained for consistency.
-	// TODO(gvisor.dev/issue/1967)
+	// oomScoreAdj is the thread group's OOM score adjustment, which biases
+	// the choice of the memory cgroup OOM killer. See Kernel.OOMKill.
 	oomScoreAdj atomicbitops.Int32
 
+	// oomVictim is set once the thread group has been killed by the OOM
+	// killer.
+	oomVictim atomicbitops.Bool
+
 	// isChildSubreaper and hasChildSubreaper correspond to Linux's
 	// signal_struct::is_child_subreaper and has_child_subreaper.
 	//
@@ -353,6 +353,13 @@ func (ns *PIDNamespace) NumTasks() int {
 	return len(ns.tids)
 }
 
+// LastThreadID returns the last ThreadID allocated in ns.
+func (ns *PIDNamespace) LastThreadID() ThreadID {
+	ns.owner.mu.RLock()
+	defer ns.owner.mu.RUnlock()
+	return ns.last
+}
+
 // NumTasksPerContainer returns the number of tasks in ns that belongs to given container.
 func (ns *PIDNamespace) NumTasksPerContainer(cid string) int {
 	ns.owner.mu.RLock()
@@ -440,6 +447,14 @@ type threadGroupNode struct {
 	// execing is protected by the TaskSet mutex.
 	execing *Task
 
+	// If coreDumper is not nil, it is a task in the thread group that has
+	// killed all other tasks so that it can write a core dump once they have
+	// stopped. coreDumper is analogous to Linux's
+	// signal_struct::core_state.
+	//
+	// coreDumper is protected by both the TaskSet mutex and the signal mutex.
+	coreDumper *Task
+
 	// tasks is all tasks in the thread group that have not yet been reaped.
 	//
 	// tasks is protected by both the TaskSet mutex and the signal mutex:
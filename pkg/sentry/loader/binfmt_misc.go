@@ -0,0 +1,251 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// BinprmBufSize is the number of bytes at the beginning of an executable that
+// are available for matching against binfmt_misc magic numbers.
+//
+// Defined in include/uapi/linux/binfmts.h.
+const BinprmBufSize = 256
+
+// BinfmtMiscFlags are the flags of a binfmt_misc entry.
+type BinfmtMiscFlags uint8
+
+// Flags for BinfmtMiscEntry.Flags. See
+// Documentation/admin-guide/binfmt-misc.rst.
+const (
+	// BinfmtMiscPreserveArgv0 ('P') passes the original argv[0] to the
+	// interpreter rather than replacing it with the executable's path.
+	BinfmtMiscPreserveArgv0 BinfmtMiscFlags = 1 << iota
+
+	// BinfmtMiscOpenBinary ('O') passes an open file descriptor for the
+	// executable to the interpreter via AT_EXECFD.
+	BinfmtMiscOpenBinary
+
+	// BinfmtMiscCredentials ('C') computes credentials from the executable
+	// rather than the interpreter. It implies BinfmtMiscOpenBinary.
+	BinfmtMiscCredentials
+
+	// BinfmtMiscFixBinary ('F') opens the interpreter when the entry is
+	// registered rather than when it is used.
+	BinfmtMiscFixBinary
+)
+
+// BinfmtMiscEntry is a binfmt_misc handler for a class of executables.
+//
+// All fields other than enabled are immutable.
+//
+// +stateify savable
+type BinfmtMiscEntry struct {
+	// Name is the name of the entry's file in binfmt_misc.
+	Name string
+
+	// Extension is the file name extension, without the leading '.', matched
+	// by the entry. If Extension is empty, the entry matches Magic instead.
+	Extension string
+
+	// Offset is the offset of Magic from the beginning of the executable.
+	Offset int
+
+	// Magic is the byte sequence matched by the entry.
+	Magic []byte
+
+	// Mask, if not nil, is ANDed with the executable's bytes before they are
+	// compared with Magic. len(Mask) == len(Magic).
+	Mask []byte
+
+	// Interpreter is the path of the interpreter that runs matching
+	// executables.
+	Interpreter string
+
+	// Flags are the entry's flags.
+	Flags BinfmtMiscFlags
+
+	// InterpreterFile is the interpreter, opened when the entry was
+	// registered, if Flags contains BinfmtMiscFixBinary.
+	InterpreterFile *vfs.FileDescription
+
+	// enabled is true if the entry can match executables.
+	enabled atomicbitops.Bool
+}
+
+// Enabled returns true if e can match executables.
+func (e *BinfmtMiscEntry) Enabled() bool {
+	return e.enabled.Load()
+}
+
+// SetEnabled enables or disables e.
+func (e *BinfmtMiscEntry) SetEnabled(enabled bool) {
+	e.enabled.Store(enabled)
+}
+
+// matches returns true if e matches an executable with the given name whose
+// first bytes are hdr.
+//
+// Preconditions: len(hdr) == BinprmBufSize.
+func (e *BinfmtMiscEntry) matches(name string, hdr []byte) bool {
+	if e.Extension != "" {
+		// Like Linux, this considers the whole path rather than only the
+		// last component.
+		i := strings.LastIndexByte(name, '.')
+		return i >= 0 && name[i+1:] == e.Extension
+	}
+	s := hdr[e.Offset : e.Offset+len(e.Magic)]
+	for i, b := range e.Magic {
+		if e.Mask != nil {
+			if (s[i]^b)&e.Mask[i] != 0 {
+				return false
+			}
+		} else if s[i] != b {
+			return false
+		}
+	}
+	return true
+}
+
+// BinfmtMisc is the set of binfmt_misc handlers that execve consults for
+// executables that can't be run natively.
+//
+// +stateify savable
+type BinfmtMisc struct {
+	// mu protects the below.
+	mu sync.Mutex `state:"nosave"`
+
+	// disabled is true if no entries may match.
+	disabled bool
+
+	// entries are the registered entries, from least to most recently
+	// registered.
+	entries []*BinfmtMiscEntry
+}
+
+// Enabled returns true if b's entries can match executables.
+func (b *BinfmtMisc) Enabled() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.disabled
+}
+
+// SetEnabled enables or disables all of b's entries.
+func (b *BinfmtMisc) SetEnabled(enabled bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.disabled = !enabled
+}
+
+// Register adds e to b. e is enabled. b takes ownership of the reference on
+// e.InterpreterFile, if any.
+func (b *BinfmtMisc) Register(e *BinfmtMiscEntry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, other := range b.entries {
+		if other.Name == e.Name {
+			return linuxerr.EEXIST
+		}
+	}
+	e.enabled.Store(true)
+	b.entries = append(b.entries, e)
+	return nil
+}
+
+// Lookup returns the entry with the given name, or nil if no such entry
+// exists.
+func (b *BinfmtMisc) Lookup(name string) *BinfmtMiscEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, e := range b.entries {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Entries returns a snapshot of the entries in b, from least to most recently
+// registered.
+func (b *BinfmtMisc) Entries() []*BinfmtMiscEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*BinfmtMiscEntry(nil), b.entries...)
+}
+
+// Remove removes e from b. It is a no-op if e has already been removed.
+func (b *BinfmtMisc) Remove(ctx context.Context, e *BinfmtMiscEntry) {
+	b.mu.Lock()
+	for i, other := range b.entries {
+		if other == e {
+			b.entries = append(b.entries[:i], b.entries[i+1:]...)
+			b.mu.Unlock()
+			e.release(ctx)
+			return
+		}
+	}
+	b.mu.Unlock()
+}
+
+// Clear removes all entries from b.
+func (b *BinfmtMisc) Clear(ctx context.Context) {
+	b.mu.Lock()
+	entries := b.entries
+	b.entries = nil
+	b.mu.Unlock()
+	for _, e := range entries {
+		e.release(ctx)
+	}
+}
+
+func (e *BinfmtMiscEntry) release(ctx context.Context) {
+	if e.InterpreterFile != nil {
+		e.InterpreterFile.DecRef(ctx)
+	}
+}
+
+// match returns the most recently registered enabled entry that matches an
+// executable with the given name whose first bytes are hdr, or nil if no
+// entry matches. If the returned entry has an InterpreterFile, match returns
+// a reference on it that the caller must release.
+//
+// Preconditions: len(hdr) == BinprmBufSize.
+func (b *BinfmtMisc) match(name string, hdr []byte) (*BinfmtMiscEntry, *vfs.FileDescription) {
+	if b == nil {
+		return nil, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.disabled {
+		return nil, nil
+	}
+	for i := len(b.entries) - 1; i >= 0; i-- {
+		e := b.entries[i]
+		if !e.Enabled() || !e.matches(name, hdr) {
+			continue
+		}
+		if e.InterpreterFile != nil {
+			e.InterpreterFile.IncRef()
+		}
+		return e, e.InterpreterFile
+	}
+	return nil, nil
+}
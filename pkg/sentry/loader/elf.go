@@ -608,6 +608,13 @@ func loadInitialELF(ctx context.Context, m *mm.MemoryManager, fs cpuid.FeatureSe
 	return le, ac, err
 }
 
+// isHostELF returns true if f is an ELF that can be run on the host
+// architecture.
+func isHostELF(ctx context.Context, f fullReader) bool {
+	info, err := parseHeader(ctx, f)
+	return err == nil && info.arch == arch.Host
+}
+
 // loadInterpreterELF loads f into mm.
 //
 // The interpreter must be for the same OS/Arch as the initial ELF.
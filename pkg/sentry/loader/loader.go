@@ -86,6 +86,16 @@ type LoadArgs struct {
 
 	// Features specifies the CPU feature set for the executable.
 	Features cpuid.FeatureSet
+
+	// BinfmtMisc, if not nil, holds the binfmt_misc handlers that are
+	// consulted for executables that can't be loaded natively.
+	BinfmtMisc *BinfmtMisc
+
+	// InstallExecFD, if not nil, is called to install a file descriptor for
+	// the executable when it is run by a binfmt_misc handler with the
+	// BinfmtMiscOpenBinary flag. It returns the new file descriptor, which
+	// is passed to the interpreter in AT_EXECFD.
+	InstallExecFD func(f *vfs.FileDescription) (int32, error)
 }
 
 // openPath opens args.Filename and checks that it is valid for loading.
@@ -144,6 +154,17 @@ func checkIsRegularFile(ctx context.Context, fd *vfs.FileDescription, filename s
 	return nil
 }
 
+// binfmtMiscArgv returns the arguments passed to entry's interpreter when it
+// runs the executable at filename with the given argv. This is
+// fs/binfmt_misc.c:load_misc_binary().
+func binfmtMiscArgv(entry *BinfmtMiscEntry, filename string, argv []string) []string {
+	newArgv := []string{entry.Interpreter, filename}
+	if entry.Flags&BinfmtMiscPreserveArgv0 == 0 && len(argv) > 0 {
+		argv = argv[1:]
+	}
+	return append(newArgv, argv...)
+}
+
 // allocStack allocates and maps a stack in to any available part of the address space.
 func allocStack(ctx context.Context, m *mm.MemoryManager, a *arch.Context64) (*arch.Stack, error) {
 	ar, err := m.MapStack(ctx)
@@ -164,76 +185,127 @@ const (
 // caller is responsible for checking that the user can execute this file.
 // If nil, the path args.Filename is resolved and loaded (check that the user
 // can execute this file is done here in this case). If the executable is an
-// interpreter script rather than an ELF, the binary of the corresponding
-// interpreter will be loaded.
+// interpreter script rather than an ELF, or is handled by binfmt_misc, the
+// binary of the corresponding interpreter will be loaded.
 //
 // It returns:
 //   - loadedELF, description of the loaded binary
 //   - arch.Context64 matching the binary arch
 //   - fs.Dirent of the binary file
+//   - fs.Dirent of the file from which credentials are computed
 //   - Possibly updated args.Argv
-func loadExecutable(ctx context.Context, args LoadArgs) (loadedELF, *arch.Context64, *vfs.FileDescription, []string, error) {
+func loadExecutable(ctx context.Context, args LoadArgs) (loadedELF, *arch.Context64, *vfs.FileDescription, *vfs.FileDescription, []string, error) {
+	var (
+		credFile *vfs.FileDescription
+		execFD   = int32(-1)
+	)
 	for i := 0; i < maxLoaderAttempts; i++ {
 		if args.File == nil {
 			var err error
 			args.File, err = openPath(ctx, args)
 			if err != nil {
 				ctx.Infof("Error opening %s: %v", args.Filename, err)
-				return loadedELF{}, nil, nil, nil, err
+				return loadedELF{}, nil, nil, nil, nil, err
 			}
-			// Ensure file is release in case the code loops or errors out.
-			defer args.File.DecRef(ctx)
 		} else {
 			if err := checkIsRegularFile(ctx, args.File, args.Filename); err != nil {
-				return loadedELF{}, nil, nil, nil, err
+				return loadedELF{}, nil, nil, nil, nil, err
 			}
+			args.File.IncRef()
 		}
+		// Ensure file is release in case the code loops or errors out.
+		defer args.File.DecRef(ctx)
 
 		// Check the header. Is this an ELF or interpreter script?
-		var hdr [4]uint8
+		var hdr [BinprmBufSize]uint8
 		// N.B. We assume that reading from a regular file cannot block.
-		_, err := args.File.ReadFull(ctx, usermem.BytesIOSequence(hdr[:]), 0)
+		n, err := args.File.ReadFull(ctx, usermem.BytesIOSequence(hdr[:]), 0)
 		// Allow unexpected EOF, as a valid executable could be only three bytes
 		// (e.g., #!a).
 		if err != nil && err != io.ErrUnexpectedEOF {
 			if err == io.EOF {
 				err = linuxerr.ENOEXEC
 			}
-			return loadedELF{}, nil, nil, nil, err
+			return loadedELF{}, nil, nil, nil, nil, err
+		}
+
+		isELF := bytes.Equal(hdr[:len(elfMagic)], []byte(elfMagic))
+		isScript := bytes.Equal(hdr[:2], []byte(interpreterScriptMagic))
+		// As in Linux, binfmt_misc is consulted only for files that can't be
+		// loaded as native ELFs or interpreter scripts.
+		if isELF && !isHostELF(ctx, args.File) || !isELF && !isScript {
+			if entry, interpFile := args.BinfmtMisc.match(args.Filename, hdr[:]); entry != nil {
+				if credFile == nil && entry.Flags&BinfmtMiscCredentials != 0 {
+					// args.File remains referenced until we return.
+					credFile = args.File
+				}
+				var err error
+				switch {
+				case entry.Flags&BinfmtMiscOpenBinary != 0 && args.InstallExecFD != nil:
+					if execFD < 0 {
+						execFD, err = args.InstallExecFD(args.File)
+					}
+				case args.CloseOnExec:
+					// The interpreter can only access the executable through
+					// AT_EXECFD.
+					err = linuxerr.ENOENT
+				}
+				if interpFile != nil {
+					// Drop the reference returned by match when we return.
+					defer interpFile.DecRef(ctx)
+				}
+				if err != nil {
+					return loadedELF{}, nil, nil, nil, nil, err
+				}
+				args.Argv = binfmtMiscArgv(entry, args.Filename, args.Argv)
+				args.Filename = entry.Interpreter
+				args.ResolveFinal = true
+				// Refresh the traversal limit for the interpreter.
+				*args.RemainingTraversals = linux.MaxSymlinkTraversals
+				args.File = interpFile
+				continue
+			}
 		}
 
 		switch {
-		case bytes.Equal(hdr[:], []byte(elfMagic)):
+		case isELF:
 			loaded, ac, err := loadELF(ctx, args)
 			if err != nil {
 				ctx.Infof("Error loading ELF: %v", err)
-				return loadedELF{}, nil, nil, nil, err
+				return loadedELF{}, nil, nil, nil, nil, err
+			}
+			if execFD >= 0 {
+				loaded.auxv = append(loaded.auxv, arch.AuxEntry{linux.AT_EXECFD, hostarch.Addr(execFD)})
 			}
 			// An ELF is always terminal. Hold on to file.
 			args.File.IncRef()
-			return loaded, ac, args.File, args.Argv, err
+			if credFile == nil {
+				credFile = args.File
+			}
+			credFile.IncRef()
+			return loaded, ac, args.File, credFile, args.Argv, err
 
-		case bytes.Equal(hdr[:2], []byte(interpreterScriptMagic)):
+		case isScript:
 			if args.CloseOnExec {
-				return loadedELF{}, nil, nil, nil, linuxerr.ENOENT
+				return loadedELF{}, nil, nil, nil, nil, linuxerr.ENOENT
 			}
 			args.Filename, args.Argv, err = parseInterpreterScript(ctx, args.Filename, args.File, args.Argv)
 			if err != nil {
 				ctx.Infof("Error loading interpreter script: %v", err)
-				return loadedELF{}, nil, nil, nil, err
+				return loadedELF{}, nil, nil, nil, nil, err
 			}
 			// Refresh the traversal limit for the interpreter.
 			*args.RemainingTraversals = linux.MaxSymlinkTraversals
 
 		default:
-			ctx.Infof("Unknown magic: %v", hdr)
-			return loadedELF{}, nil, nil, nil, linuxerr.ENOEXEC
+			ctx.Infof("Unknown magic: %v", hdr[:min(n, len(elfMagic))])
+			return loadedELF{}, nil, nil, nil, nil, linuxerr.ENOEXEC
 		}
 		// Set to nil in case we loop on a Interpreter Script.
 		args.File = nil
 	}
 
-	return loadedELF{}, nil, nil, nil, linuxerr.ELOOP
+	return loadedELF{}, nil, nil, nil, nil, linuxerr.ELOOP
 }
 
 // ImageInfo represents the information for the loaded image.
@@ -259,12 +331,13 @@ type ImageInfo struct {
 //   - Load is called on the Task goroutine.
 func Load(ctx context.Context, args LoadArgs, extraAuxv []arch.AuxEntry, vdso *VDSO) (ImageInfo, *syserr.Error) {
 	// Load the executable itself.
-	loaded, ac, file, newArgv, err := loadExecutable(ctx, args)
+	loaded, ac, file, credFile, newArgv, err := loadExecutable(ctx, args)
 	if err != nil {
 		return ImageInfo{}, syserr.NewDynamic(fmt.Sprintf("failed to load %s: %v", args.Filename, err), syserr.FromError(err).ToLinux())
 	}
 	defer file.DecRef(ctx)
-	fileCaps, err := file.GetXattr(ctx, &vfs.GetXattrOptions{Name: linux.XATTR_SECURITY_CAPABILITY, Size: linux.XATTR_CAPS_SZ_3})
+	defer credFile.DecRef(ctx)
+	fileCaps, err := credFile.GetXattr(ctx, &vfs.GetXattrOptions{Name: linux.XATTR_SECURITY_CAPABILITY, Size: linux.XATTR_CAPS_SZ_3})
 	switch {
 	case linuxerr.Equals(linuxerr.ENODATA, err), linuxerr.Equals(linuxerr.EOPNOTSUPP, err):
 		// Linux converts EOPNOTSUPP to ENODATA in
@@ -102,6 +102,51 @@ type Mappable interface {
 	InvalidateUnsavable(ctx context.Context) error
 }
 
+// HugepageMappable may be implemented by a Mappable whose Translations may be
+// hugepage-backed.
+type HugepageMappable interface {
+	// MayHugepage returns true if hugepage-aligned ranges of the Mappable may
+	// be hugepage-backed, such that mappings of the Mappable should be
+	// hugepage-aligned.
+	MayHugepage() bool
+}
+
+// HugepageAdvice is the madvise(MADV_HUGEPAGE) or madvise(MADV_NOHUGEPAGE)
+// setting of a mapping.
+type HugepageAdvice uint8
+
+const (
+	// HugepageAdviceNone indicates that neither MADV_HUGEPAGE nor
+	// MADV_NOHUGEPAGE has been applied to the mapping.
+	HugepageAdviceNone HugepageAdvice = iota
+
+	// HugepageAdviceHuge indicates that MADV_HUGEPAGE has been applied to the
+	// mapping more recently than MADV_NOHUGEPAGE.
+	HugepageAdviceHuge
+
+	// HugepageAdviceNoHuge indicates that MADV_NOHUGEPAGE has been applied to
+	// the mapping more recently than MADV_HUGEPAGE.
+	HugepageAdviceNoHuge
+)
+
+// contextID is this package's type for context.Context.Value keys.
+type contextID int
+
+const (
+	// CtxHugepageAdvice is a Context.Value key for the HugepageAdvice of the
+	// mapping for which Mappable.Translate is called.
+	CtxHugepageAdvice contextID = iota
+)
+
+// HugepageAdviceFromContext returns the HugepageAdvice of the mapping being
+// translated by ctx, or HugepageAdviceNone if there is none.
+func HugepageAdviceFromContext(ctx context.Context) HugepageAdvice {
+	if v := ctx.Value(CtxHugepageAdvice); v != nil {
+		return v.(HugepageAdvice)
+	}
+	return HugepageAdviceNone
+}
+
 // Translations are returned by Mappable.Translate.
 type Translation struct {
 	// Source is the translated range in the Mappable.
@@ -214,7 +214,7 @@ func (mm *MemoryManager) mapASLocked(ctx context.Context, pseg pmaIterator, ar h
 		pmaAR := pseg.Range()
 		pmaMapAR := pmaAR.Intersect(mapAR)
 		perms := pma.effectivePerms
-		if pma.needCOW {
+		if pma.needCOW || pma.softDirtyCleared {
 			perms.Write = false
 		}
 		if perms.Any() { // MapFile precondition
@@ -0,0 +1,215 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mm
+
+import (
+	"bytes"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// elfMagic is the magic number at the start of every ELF file.
+var elfMagic = []byte{0x7f, 'E', 'L', 'F'}
+
+// CoreDumpFilter returns the bitmask of linux.MMF_DUMP_* flags that selects
+// which mappings are written to core dumps.
+func (mm *MemoryManager) CoreDumpFilter() uint32 {
+	return mm.coreDumpFilter.Load()
+}
+
+// SetCoreDumpFilter sets the bitmask returned by CoreDumpFilter. Bits outside
+// of linux.MMF_DUMP_FILTER_MASK are ignored.
+func (mm *MemoryManager) SetCoreDumpFilter(filter uint32) {
+	mm.coreDumpFilter.Store(filter & linux.MMF_DUMP_FILTER_MASK)
+}
+
+// CoreDumpVMA describes a virtual memory area as it appears in a core dump.
+type CoreDumpVMA struct {
+	// Range is the range of addresses spanned by the vma.
+	Range hostarch.AddrRange
+
+	// Perms are the application-defined permissions of the vma.
+	Perms hostarch.AccessType
+
+	// DumpSize is the number of bytes at the start of Range whose contents
+	// are written to the core dump. DumpSize is always page-aligned and no
+	// greater than Range.Length().
+	DumpSize uint64
+
+	// Name is the name of the vma, as shown in /proc/[pid]/maps.
+	Name string
+
+	// FileBacked is true if the vma maps a file named by Name.
+	FileBacked bool
+
+	// Offset is the offset into the mapped file at which the vma begins. If
+	// FileBacked is false, Offset is meaningless.
+	Offset uint64
+}
+
+// CoreDumpVMAs returns a description of each vma in mm for inclusion in a
+// core dump, in address order. The amount of each vma whose contents should be
+// dumped is chosen based on mm's core dump filter, consistent with Linux's
+// fs/coredump.c:vma_dump_size().
+func (mm *MemoryManager) CoreDumpVMAs(ctx context.Context) []CoreDumpVMA {
+	filter := mm.CoreDumpFilter()
+
+	var (
+		vmas []CoreDumpVMA
+		// elfHeaderCandidates are indices into vmas that should be dumped
+		// only if they begin with an ELF header.
+		elfHeaderCandidates []int
+	)
+	mm.mappingMu.RLock()
+	mm.activeMu.RLock()
+	for vseg := mm.vmas.FirstSegment(); vseg.Ok(); vseg = vseg.NextSegment() {
+		vma := vseg.ValuePtr()
+		ar := vseg.Range()
+		cv := CoreDumpVMA{
+			Range:  ar,
+			Perms:  vma.realPerms,
+			Offset: vma.off,
+		}
+		if vma.name != "" {
+			cv.Name = vma.name
+		} else if vma.id != nil {
+			cv.Name = vma.id.MappedName(ctx)
+		}
+		// Named vmas are either anonymous (named by prctl(PR_SET_VMA)) or
+		// special mappings like the vDSO, neither of which are file-backed.
+		cv.FileBacked = vma.id != nil && vma.name == "" && !strings.HasPrefix(cv.Name, "[")
+
+		size, checkELF := mm.vmaDumpSizeLocked(vma, ar, cv.Name, filter)
+		if checkELF {
+			elfHeaderCandidates = append(elfHeaderCandidates, len(vmas))
+		}
+		cv.DumpSize = size
+		vmas = append(vmas, cv)
+	}
+	mm.activeMu.RUnlock()
+	mm.mappingMu.RUnlock()
+
+	// Checking for an ELF header requires reading application memory, which
+	// can't be done while holding mm.mappingMu.
+	for _, i := range elfHeaderCandidates {
+		var magic [4]byte
+		if _, err := mm.CopyIn(ctx, vmas[i].Range.Start, magic[:], usermem.IOOpts{IgnorePermissions: true}); err != nil {
+			continue
+		}
+		if bytes.Equal(magic[:], elfMagic) {
+			vmas[i].DumpSize = hostarch.PageSize
+		}
+	}
+	return vmas
+}
+
+// vmaDumpSizeLocked returns the number of bytes of the given vma that should
+// be written to a core dump. If checkELF is true, the first page of the vma
+// should additionally be dumped if it contains an ELF header.
+//
+// Preconditions: mm.mappingMu and mm.activeMu must be locked.
+func (mm *MemoryManager) vmaDumpSizeLocked(vma *vma, ar hostarch.AddrRange, name string, filter uint32) (size uint64, checkELF bool) {
+	whole := uint64(ar.Length())
+
+	// "Exclude from a core dump those pages in the range specified by addr
+	// and length." - madvise(2)
+	if vma.dontdump {
+		return 0, false
+	}
+
+	// Always dump the vDSO so that debuggers can unwind through signal
+	// frames. Compare Linux's always_dump_vma().
+	if name == "[vdso]" || name == "[vvar]" {
+		return whole, false
+	}
+
+	if !vma.private {
+		// Linux checks whether the mapped inode has been unlinked; the
+		// closest analogue available to us is the " (deleted)" suffix applied
+		// by MappedName, which is also used for anonymous shared memory.
+		// Shared vmas can only be named by prctl(PR_SET_VMA_ANON_NAME) if
+		// they are anonymous.
+		if vma.mappable == nil || vma.name != "" || strings.HasSuffix(name, " (deleted)") {
+			if filter&linux.MMF_DUMP_ANON_SHARED != 0 {
+				return whole, false
+			}
+		} else if filter&linux.MMF_DUMP_MAPPED_SHARED != 0 {
+			return whole, false
+		}
+		return 0, false
+	}
+
+	// Private file mappings that have been written to are treated as
+	// anonymous, equivalent to Linux's vma->anon_vma != NULL.
+	if (vma.mappable == nil || mm.hasPrivatePMAsLocked(ar)) && filter&linux.MMF_DUMP_ANON_PRIVATE != 0 {
+		return whole, false
+	}
+	if vma.mappable == nil {
+		return 0, false
+	}
+	if filter&linux.MMF_DUMP_MAPPED_PRIVATE != 0 {
+		return whole, false
+	}
+	return 0, filter&linux.MMF_DUMP_ELF_HEADERS != 0 && vma.off == 0 && vma.realPerms.Read
+}
+
+// hasPrivatePMAsLocked returns true if any pma in ar holds a private copy of
+// its data, i.e. if the corresponding memory has been copied-on-write.
+//
+// Preconditions: mm.activeMu must be locked.
+func (mm *MemoryManager) hasPrivatePMAsLocked(ar hostarch.AddrRange) bool {
+	for pseg := mm.pmas.LowerBoundSegment(ar.Start); pseg.Ok() && pseg.Start() < ar.End; pseg = pseg.NextSegment() {
+		if pseg.ValuePtr().private {
+			return true
+		}
+	}
+	return false
+}
+
+// NextCoreDumpRange returns the first subrange of ar whose contents should be
+// read into a core dump. Addresses in ar before the returned range are holes,
+// which read as zeroes. If the remainder of ar is a hole, NextCoreDumpRange
+// returns an empty range at ar.End.
+//
+// Pages of anonymous private vmas that have no pma have never been touched,
+// and are treated as holes rather than being faulted in, so that dumping a
+// large untouched reservation doesn't commit memory for it. Compare Linux's
+// mm/gup.c:no_page_table() for FOLL_DUMP.
+func (mm *MemoryManager) NextCoreDumpRange(ar hostarch.AddrRange) hostarch.AddrRange {
+	mm.mappingMu.RLock()
+	defer mm.mappingMu.RUnlock()
+	mm.activeMu.RLock()
+	defer mm.activeMu.RUnlock()
+	for vseg := mm.vmas.LowerBoundSegment(ar.Start); vseg.Ok() && vseg.Start() < ar.End; vseg = vseg.NextSegment() {
+		vr := vseg.Range().Intersect(ar)
+		if vseg.ValuePtr().mappable != nil {
+			return vr
+		}
+		pseg := mm.pmas.LowerBoundSegment(vr.Start)
+		if !pseg.Ok() || pseg.Start() >= vr.End {
+			continue
+		}
+		pr := hostarch.AddrRange{max(pseg.Start(), vr.Start), pseg.End()}
+		for pseg = pseg.NextSegment(); pseg.Ok() && pseg.Start() == pr.End && pr.End < vr.End; pseg = pseg.NextSegment() {
+			pr.End = pseg.End()
+		}
+		return pr.Intersect(vr)
+	}
+	return hostarch.AddrRange{ar.End, ar.End}
+}
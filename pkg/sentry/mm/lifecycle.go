@@ -17,6 +17,7 @@ package mm
 import (
 	"fmt"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/hostarch"
@@ -36,6 +37,7 @@ func NewMemoryManager(p platform.Platform, mf *pgalloc.MemoryFile, sleepForActiv
 		users:              atomicbitops.FromInt32(1),
 		auxv:               arch.Auxv{},
 		dumpability:        atomicbitops.FromInt32(int32(UserDumpable)),
+		coreDumpFilter:     atomicbitops.FromUint32(linux.MMF_DUMP_FILTER_DEFAULT),
 		aioManager:         aioManager{contexts: make(map[uint64]*AIOContext)},
 		sleepForActivation: sleepForActivation,
 	}
@@ -91,6 +93,7 @@ func (mm *MemoryManager) Fork(ctx context.Context) (*MemoryManager, error) {
 		// IncRef'd below, once we know that there isn't an error.
 		executable:         mm.executable,
 		dumpability:        atomicbitops.FromInt32(mm.dumpability.Load()),
+		coreDumpFilter:     atomicbitops.FromUint32(mm.coreDumpFilter.Load()),
 		aioManager:         aioManager{contexts: make(map[uint64]*AIOContext)},
 		sleepForActivation: mm.sleepForActivation,
 		vdsoSigReturnAddr:  mm.vdsoSigReturnAddr,
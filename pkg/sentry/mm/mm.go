@@ -197,6 +197,11 @@ type MemoryManager struct {
 	// by metadataMu.
 	dumpability atomicbitops.Int32
 
+	// coreDumpFilter is the bitmask of linux.MMF_DUMP_* flags controlling
+	// which mappings are included in core dumps, as configured by
+	// /proc/[pid]/coredump_filter.
+	coreDumpFilter atomicbitops.Uint32
+
 	metadataMu metadataMutex `state:"nosave"`
 
 	// argv is the application argv. This is set up by the loader and may be
@@ -300,6 +305,14 @@ type vma struct {
 	// dontfork is the MADV_DONTFORK setting for this vma configured by madvise().
 	dontfork bool
 
+	// dontdump is the MADV_DONTDUMP setting for this vma configured by
+	// madvise().
+	dontdump bool
+
+	// hugepageAdvice is the MADV_HUGEPAGE or MADV_NOHUGEPAGE setting for this
+	// vma configured by madvise().
+	hugepageAdvice memmap.HugepageAdvice
+
 	mlockMode memmap.MLockMode
 
 	// numaPolicy is the NUMA policy for this vma set by mbind().
@@ -337,6 +350,8 @@ func (v *vma) copy() vma {
 		growsDown:      v.growsDown,
 		isStack:        v.isStack,
 		dontfork:       v.dontfork,
+		dontdump:       v.dontdump,
+		hugepageAdvice: v.hugepageAdvice,
 		mlockMode:      v.mlockMode,
 		numaPolicy:     v.numaPolicy,
 		numaNodemask:   v.numaNodemask,
@@ -394,6 +409,13 @@ type pma struct {
 	// Invariant: If huge == true, then private == true.
 	huge bool
 
+	// If softDirtyCleared is true, the pma has not been written to since its
+	// soft-dirty bit was last cleared by a write of CLEAR_REFS_SOFT_DIRTY to
+	// /proc/[pid]/clear_refs, and Write is withheld from AddressSpace
+	// mappings of the pma so that the next write by the application faults
+	// (and sets the soft-dirty bit again). All new pmas are soft-dirty.
+	softDirtyCleared bool
+
 	// If internalMappings is not empty, it is the cached return value of
 	// file.MapInternal for the memmap.FileRange mapped by this pma.
 	internalMappings safemem.BlockSeq `state:"nosave"`
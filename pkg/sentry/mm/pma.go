@@ -56,6 +56,10 @@ func (mm *MemoryManager) existingPMAsLocked(ar hostarch.AddrRange, at hostarch.A
 		if !perms.SupersetOf(at) {
 			return pmaIterator{}
 		}
+		if at.Write && pma.softDirtyCleared {
+			// getPMAsLocked must set the pma's soft-dirty bit.
+			return pmaIterator{}
+		}
 		if needInternalMappings && pma.internalMappings.IsEmpty() {
 			return pmaIterator{}
 		}
@@ -339,7 +343,7 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 						perms.Read = true
 						perms.Write = false
 					}
-					ts, err := vma.mappable.Translate(ctx, reqMR, optMR, perms)
+					ts, err := vma.mappable.Translate(vma.translateContext(ctx), reqMR, optMR, perms)
 					if checkInvariants {
 						if err := memmap.CheckTranslateResult(reqMR, optMR, perms, ts, err); err != nil {
 							panic(fmt.Sprintf("Mappable(%T).Translate(%v, %v, %v): %v", vma.mappable, reqMR, optMR, perms, err))
@@ -471,6 +475,7 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 					oldpma.needCOW = false
 					oldpma.private = true
 					oldpma.huge = huge
+					oldpma.softDirtyCleared = false
 					oldpma.internalMappings = safemem.BlockSeq{}
 					// Try to merge the pma with its neighbors.
 					if prev := pseg.PrevSegment(); prev.Ok() {
@@ -507,7 +512,7 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 					reqAR := optAR.Intersect(ar)
 					reqMR := vseg.mappableRangeOf(reqAR)
 					perms := oldpma.translatePerms.Union(at)
-					ts, err := vma.mappable.Translate(ctx, reqMR, optMR, perms)
+					ts, err := vma.mappable.Translate(vma.translateContext(ctx), reqMR, optMR, perms)
 					if checkInvariants {
 						if err := memmap.CheckTranslateResult(reqMR, optMR, perms, ts, err); err != nil {
 							panic(fmt.Sprintf("Mappable(%T).Translate(%v, %v, %v): %v", vma.mappable, reqMR, optMR, perms, err))
@@ -556,6 +561,18 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 					} else {
 						pseg = pmaIterator{}
 					}
+				} else if at.Write && oldpma.softDirtyCleared {
+					// The pma is being written to for the first time since
+					// its soft-dirty bit was cleared. Set it again for the
+					// written pages only, so that writes to other pages
+					// continue to be tracked.
+					dirtyAR := pseg.Range().Intersect(ar)
+					if dirtyAR != pseg.Range() {
+						pseg = mm.pmas.Isolate(pseg, dirtyAR)
+						pstart = pmaIterator{} // iterators invalidated
+					}
+					pseg.ValuePtr().softDirtyCleared = false
+					pseg, pgap = pseg.NextNonEmpty()
 				} else {
 					// We have a usable pma; continue.
 					pseg, pgap = pseg.NextNonEmpty()
@@ -947,7 +964,8 @@ func (pmaSetFunctions) Merge(ar1 hostarch.AddrRange, pma1 pma, ar2 hostarch.Addr
 		pma1.maxPerms != pma2.maxPerms ||
 		pma1.needCOW != pma2.needCOW ||
 		pma1.private != pma2.private ||
-		pma1.huge != pma2.huge {
+		pma1.huge != pma2.huge ||
+		pma1.softDirtyCleared != pma2.softDirtyCleared {
 		return pma{}, false
 	}
 
@@ -18,10 +18,12 @@ import (
 	"bytes"
 	"fmt"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 )
 
 const (
@@ -42,6 +44,7 @@ const (
 		"Referenced:            0 kB\n" +
 		"Anonymous:             0 kB\n" +
 		"AnonHugePages:         0 kB\n" +
+		"ShmemPmdMapped:        0 kB\n" +
 		"Shared_Hugetlb:        0 kB\n" +
 		"Private_Hugetlb:       0 kB\n" +
 		"Swap:                  0 kB\n" +
@@ -167,6 +170,7 @@ func (mm *MemoryManager) vmaSmapsEntryIntoLocked(ctx context.Context, vseg vmaIt
 	mm.activeMu.RLock()
 	var rss uint64
 	var anon uint64
+	var shmemPmd uint64
 	vsegAR := vseg.Range()
 	for pseg := mm.pmas.LowerBoundSegment(vsegAR.Start); pseg.Ok() && pseg.Start() < vsegAR.End; pseg = pseg.NextSegment() {
 		psegAR := pseg.Range().Intersect(vsegAR)
@@ -174,6 +178,8 @@ func (mm *MemoryManager) vmaSmapsEntryIntoLocked(ctx context.Context, vseg vmaIt
 		rss += size
 		if pseg.ValuePtr().private {
 			anon += size
+		} else {
+			shmemPmd += pseg.hugeMappableBytes(psegAR)
 		}
 	}
 	mm.activeMu.RUnlock()
@@ -198,8 +204,9 @@ func (mm *MemoryManager) vmaSmapsEntryIntoLocked(ctx context.Context, vseg vmaIt
 	// Pretend that all pages are "referenced" (recently touched).
 	fmt.Fprintf(b, "Referenced:     %8d kB\n", rss/1024)
 	fmt.Fprintf(b, "Anonymous:      %8d kB\n", anon/1024)
-	// Hugepages (hugetlb and THP) are not implemented.
+	// Anonymous THP and hugetlb are not reported.
 	fmt.Fprintf(b, "AnonHugePages:  %8d kB\n", 0)
+	fmt.Fprintf(b, "ShmemPmdMapped: %8d kB\n", shmemPmd/1024)
 	fmt.Fprintf(b, "Shared_Hugetlb: %8d kB\n", 0)
 	fmt.Fprintf(b, "Private_Hugetlb: %7d kB\n", 0)
 	// Swap is not implemented.
@@ -252,3 +259,106 @@ func (mm *MemoryManager) vmaSmapsEntryIntoLocked(ctx context.Context, vseg vmaIt
 	}
 	b.WriteString("\n")
 }
+
+// hugeMappableBytes returns the number of bytes in ar, a subset of pseg's
+// range, that are mapped by pseg to hugepage-backed memory with compatible
+// alignment, such that they may be mapped by huge page table entries.
+//
+// Preconditions: mm.activeMu must be locked.
+func (pseg pmaIterator) hugeMappableBytes(ar hostarch.AddrRange) uint64 {
+	pma := pseg.ValuePtr()
+	mf, ok := pma.file.(*pgalloc.MemoryFile)
+	if !ok {
+		return 0
+	}
+	start, ok := ar.Start.HugeRoundUp()
+	if !ok {
+		return 0
+	}
+	end := ar.End.HugeRoundDown()
+	if start >= end {
+		return 0
+	}
+	off := pseg.fileRangeOf(hostarch.AddrRange{start, end})
+	if !hostarch.IsHugePageAligned(off.Start) || !mf.IsHugepageBacked(off) {
+		return 0
+	}
+	return uint64(end - start)
+}
+
+// PagemapEntries returns the number of entries in /proc/[pid]/pagemap, one
+// for each page in the address space (Linux: TASK_SIZE >> PAGE_SHIFT).
+func (mm *MemoryManager) PagemapEntries() uint64 {
+	return uint64(mm.layout.MaxAddr) / hostarch.PageSize
+}
+
+// ReadPagemap is called by fsimpl/proc.pagemapSource.ReadEntries to implement
+// /proc/[pid]/pagemap. It sets entries[i] to the pagemap entry for the page at
+// addr+i*hostarch.PageSize, and returns the number of entries set, which is
+// less than len(entries) if the end of the address space is reached. If
+// showPFN is false, page frame numbers are omitted from entries.
+//
+// Page frame numbers are offsets, in pages, into the pgalloc.MemoryFile that
+// backs the page; pages backed by other files have no page frame number.
+//
+// Preconditions: addr is page-aligned.
+func (mm *MemoryManager) ReadPagemap(addr hostarch.Addr, entries []uint64, showPFN bool) int {
+	mm.activeMu.RLock()
+	defer mm.activeMu.RUnlock()
+
+	n := 0
+	pseg := mm.pmas.LowerBoundSegment(addr)
+	for ; n < len(entries) && addr < mm.layout.MaxAddr; n, addr = n+1, addr+hostarch.PageSize {
+		for pseg.Ok() && pseg.End() <= addr {
+			pseg = pseg.NextSegment()
+		}
+		if !pseg.Ok() || addr < pseg.Start() {
+			// Pages without pmas are neither present nor swapped.
+			entries[n] = 0
+			continue
+		}
+		pma := pseg.ValuePtr()
+		pme := uint64(linux.PM_PRESENT)
+		if showPFN {
+			if _, ok := pma.file.(*pgalloc.MemoryFile); ok {
+				pme |= ((pma.off + uint64(addr-pseg.Start())) / hostarch.PageSize) & linux.PM_PFRAME_MASK
+			}
+		}
+		if !pma.softDirtyCleared {
+			pme |= linux.PM_SOFT_DIRTY
+		}
+		if pma.private {
+			// Private pages that don't need copy-on-write aren't shared with
+			// any other MemoryManager.
+			if !pma.needCOW {
+				pme |= linux.PM_MMAP_EXCLUSIVE
+			}
+		} else {
+			pme |= linux.PM_FILE
+		}
+		entries[n] = pme
+	}
+	return n
+}
+
+// ClearSoftDirty clears the soft-dirty bits of all pages in mm, as for a write
+// of CLEAR_REFS_SOFT_DIRTY to /proc/[pid]/clear_refs.
+func (mm *MemoryManager) ClearSoftDirty() {
+	mm.activeMu.Lock()
+	defer mm.activeMu.Unlock()
+
+	for pseg := mm.pmas.FirstSegment(); pseg.Ok(); pseg = pseg.NextSegment() {
+		pma := pseg.ValuePtr()
+		if pma.softDirtyCleared {
+			continue
+		}
+		pma.softDirtyCleared = true
+		if pma.effectivePerms.Write && !pma.needCOW {
+			// Write-protect the pma in the AddressSpace, so that the next
+			// write to it faults and sets its soft-dirty bit again.
+			mm.unmapASLocked(pseg.Range())
+		}
+	}
+	// pmas that were split to track soft-dirty bits can now be merged.
+	mm.pmas.MergeAll()
+}
@@ -26,17 +26,29 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/kernel/futex"
 	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
 )
 
 // HandleUserFault handles an application page fault. sp is the faulting
-// application thread's stack pointer.
+// application thread's stack pointer. If the fault is handled successfully,
+// major is true if handling it required reading data from a filesystem, as
+// opposed to being satisfied by memory that was already resident.
 //
 // Preconditions: mm.as != nil.
-func (mm *MemoryManager) HandleUserFault(ctx context.Context, addr hostarch.Addr, at hostarch.AccessType, sp hostarch.Addr) error {
+func (mm *MemoryManager) HandleUserFault(ctx context.Context, addr hostarch.Addr, at hostarch.AccessType, sp hostarch.Addr) (major bool, err error) {
 	addr = hostarch.UntaggedUserAddr(addr)
 	ar, ok := addr.RoundDown().ToRange(hostarch.PageSize)
 	if !ok {
-		return linuxerr.EFAULT
+		return false, linuxerr.EFAULT
+	}
+
+	// Filesystems charge reads from backing storage to the faulting context,
+	// so any such reads performed while handling the fault indicate that it
+	// was a major fault.
+	io := usage.IOFromContext(ctx)
+	var bytesRead uint64
+	if io != nil {
+		bytesRead = io.BytesRead.Load()
 	}
 
 	// Don't bother trying existingPMAsLocked; in most cases, if we did have
@@ -49,7 +61,7 @@ func (mm *MemoryManager) HandleUserFault(ctx context.Context, addr hostarch.Addr
 	vseg, _, err := mm.getVMAsLocked(ctx, ar, at, false)
 	if err != nil {
 		mm.mappingMu.RUnlock()
-		return err
+		return false, err
 	}
 
 	// Ensure that we have a usable pma.
@@ -58,8 +70,9 @@ func (mm *MemoryManager) HandleUserFault(ctx context.Context, addr hostarch.Addr
 	mm.mappingMu.RUnlock()
 	if err != nil {
 		mm.activeMu.Unlock()
-		return err
+		return false, err
 	}
+	major = io != nil && io.BytesRead.Load() != bytesRead
 
 	// Downgrade to a read-lock on activeMu since we don't need to mutate pmas
 	// anymore.
@@ -68,7 +81,7 @@ func (mm *MemoryManager) HandleUserFault(ctx context.Context, addr hostarch.Addr
 	// Map the faulted page into the active AddressSpace.
 	err = mm.mapASLocked(ctx, pseg, ar, memmap.PlatformEffectDefault)
 	mm.activeMu.RUnlock()
-	return err
+	return major, err
 }
 
 // MMap establishes a memory mapping.
@@ -1307,6 +1320,31 @@ func (mm *MemoryManager) SetDontFork(addr hostarch.Addr, length uint64, dontfork
 	})
 }
 
+// SetDontDump implements the semantics of madvise MADV_DONTDUMP and
+// MADV_DODUMP.
+//
+// Preconditions: addr and length are page-aligned.
+func (mm *MemoryManager) SetDontDump(addr hostarch.Addr, length uint64, dontdump bool) error {
+	addr = hostarch.UntaggedUserAddr(addr)
+	return mm.madviseMutateVMAs(addr, length, func(vseg vmaIterator) error {
+		vseg.ValuePtr().dontdump = dontdump
+		return nil
+	})
+}
+
+// SetHugepageAdvice implements the semantics of madvise MADV_HUGEPAGE and
+// MADV_NOHUGEPAGE. The advice is passed to Translate for mappings of
+// memmap.HugepageMappables, and is otherwise ignored.
+//
+// Preconditions: addr and length are page-aligned.
+func (mm *MemoryManager) SetHugepageAdvice(addr hostarch.Addr, length uint64, advice memmap.HugepageAdvice) error {
+	addr = hostarch.UntaggedUserAddr(addr)
+	return mm.madviseMutateVMAs(addr, length, func(vseg vmaIterator) error {
+		vseg.ValuePtr().hugepageAdvice = advice
+		return nil
+	})
+}
+
 // SetVMAAnonName implements the semantics of Linux's
 // prctl(PR_SET_VMA, PR_SET_VMA_ANON_NAME).
 func (mm *MemoryManager) SetVMAAnonName(addr hostarch.Addr, length uint64, name string, nameIsNil bool) error {
@@ -1490,6 +1528,14 @@ func (mm *MemoryManager) MaxResidentSetSize() uint64 {
 	return mm.maxRSS
 }
 
+// ResetMaxResidentSetSize resets mm's max RSS to its current RSS, as for a
+// write of CLEAR_REFS_MM_HIWATER_RSS to /proc/[pid]/clear_refs.
+func (mm *MemoryManager) ResetMaxResidentSetSize() {
+	mm.activeMu.Lock()
+	defer mm.activeMu.Unlock()
+	mm.maxRSS = mm.curRSS
+}
+
 // VirtualDataSize returns the size of private data segments in mm.
 func (mm *MemoryManager) VirtualDataSize() uint64 {
 	mm.mappingMu.RLock()
@@ -51,6 +51,7 @@ func (mm *MemoryManager) createVMALocked(ctx context.Context, opts memmap.MMapOp
 		Private:   opts.Private,
 		Unmap:     opts.Unmap,
 		Map32Bit:  opts.Map32Bit,
+		Huge:      mayHugepage(opts.Mappable, opts.Offset),
 	})
 	if err != nil {
 		// Can't force without opts.Unmap and opts.Fixed.
@@ -156,6 +157,10 @@ type findAvailableOpts struct {
 	Private   bool
 	Unmap     bool
 	Map32Bit  bool
+
+	// Huge is true if the mapped memmap.Mappable may be hugepage-backed at
+	// hugepage-aligned offsets.
+	Huge bool
 }
 
 // map32Start/End are the bounds to which MAP_32BIT mappings are constrained,
@@ -198,7 +203,7 @@ func (mm *MemoryManager) findAvailableLocked(length uint64, opts findAvailableOp
 	// Prefer hugepage alignment if a hugepage or more is requested and the vma
 	// will actually be eligible for hugepages.
 	alignment := uint64(hostarch.PageSize)
-	if length >= hostarch.HugePageSize && opts.Private && !opts.GrowsDown && !opts.Stack {
+	if length >= hostarch.HugePageSize && (opts.Private || opts.Huge) && !opts.GrowsDown && !opts.Stack {
 		alignment = hostarch.HugePageSize
 	}
 
@@ -211,6 +216,22 @@ func (mm *MemoryManager) findAvailableLocked(length uint64, opts findAvailableOp
 	return mm.findHighestAvailableLocked(length, alignment, hostarch.AddrRange{mm.layout.MinAddr, mm.layout.TopDownBase})
 }
 
+// translateContext returns the context in which vma.mappable.Translate is
+// called for vma.
+func (vma *vma) translateContext(ctx context.Context) context.Context {
+	if vma.hugepageAdvice == memmap.HugepageAdviceNone {
+		return ctx
+	}
+	return context.WithValue(ctx, memmap.CtxHugepageAdvice, vma.hugepageAdvice)
+}
+
+// mayHugepage returns true if a mapping of mappable at offset may be
+// hugepage-backed. Compare Linux's mm/shmem.c:shmem_get_unmapped_area().
+func mayHugepage(mappable memmap.Mappable, offset uint64) bool {
+	hm, ok := mappable.(memmap.HugepageMappable)
+	return ok && hostarch.IsHugePageAligned(offset) && hm.MayHugepage()
+}
+
 func (mm *MemoryManager) applicationAddrRange() hostarch.AddrRange {
 	return hostarch.AddrRange{mm.layout.MinAddr, mm.layout.MaxAddr}
 }
@@ -472,6 +493,8 @@ func (vmaSetFunctions) Merge(ar1 hostarch.AddrRange, vma1 vma, ar2 hostarch.Addr
 		vma1.numaPolicy != vma2.numaPolicy ||
 		vma1.numaNodemask != vma2.numaNodemask ||
 		vma1.dontfork != vma2.dontfork ||
+		vma1.dontdump != vma2.dontdump ||
+		vma1.hugepageAdvice != vma2.hugepageAdvice ||
 		vma1.id != vma2.id ||
 		vma1.name != vma2.name ||
 		vma1.nameMut != vma2.nameMut {
@@ -1812,6 +1812,119 @@ func (f *MemoryFile) FD() int {
 	return int(f.file.Fd())
 }
 
+// IsHugepageBacked returns true if all pages in fr are expected to be
+// hugepage-backed.
+//
+// Preconditions: At least one reference must be held on all pages in fr.
+func (f *MemoryFile) IsHugepageBacked(fr memmap.FileRange) bool {
+	huge := true
+	f.forEachChunk(fr, func(chunk *chunkInfo, chunkFR memmap.FileRange) bool {
+		huge = chunk.huge
+		return huge
+	})
+	return huge
+}
+
+// ReadPageFlags sets flags[i] to the Linux /proc/kpageflags entry (a bitmask
+// of linux.KPF_* bits) for the page at file offset start+i*hostarch.PageSize.
+// It returns the number of entries set, which is less than len(flags) if the
+// end of the file is reached.
+//
+// Preconditions: start is page-aligned.
+func (f *MemoryFile) ReadPageFlags(start uint64, flags []uint64) int {
+	size := f.TotalSize()
+	if start >= size {
+		return 0
+	}
+	n := len(flags)
+	if maxN := (size - start) / hostarch.PageSize; uint64(n) > maxN {
+		n = int(maxN)
+	}
+	fr := memmap.FileRange{start, start + uint64(n)*hostarch.PageSize}
+	index := func(off uint64) int {
+		return int((off - start) / hostarch.PageSize)
+	}
+
+	// Only snapshot the state of pages in fr while holding f.mu, and compute
+	// flags after releasing it, to avoid delaying allocations.
+	type acctRange struct {
+		fr             memmap.FileRange
+		kind           usage.MemoryKind
+		knownCommitted bool
+	}
+	var (
+		used []memmap.FileRange
+		acct []acctRange
+	)
+	f.mu.Lock()
+	f.forEachChunk(fr, func(chunk *chunkInfo, chunkFR memmap.FileRange) bool {
+		unfree := &f.unfreeSmall
+		if chunk.huge {
+			unfree = &f.unfreeHuge
+		}
+		unfree.VisitRange(chunkFR, func(ufseg unfreeIterator) bool {
+			if ufseg.ValuePtr().refs != 0 {
+				used = append(used, ufseg.Range().Intersect(chunkFR))
+			}
+			return true
+		})
+		return true
+	})
+	f.memAcct.VisitRange(fr, func(maseg memAcctIterator) bool {
+		ma := maseg.ValuePtr()
+		if !ma.wasteOrReleasing {
+			acct = append(acct, acctRange{
+				fr:             maseg.Range().Intersect(fr),
+				kind:           ma.kind,
+				knownCommitted: ma.knownCommitted,
+			})
+		}
+		return true
+	})
+	f.mu.Unlock()
+
+	// Pages that aren't in use are reported as free, like pages in Linux's
+	// buddy allocator.
+	for i := range flags[:n] {
+		flags[i] = 1 << linux.KPF_BUDDY
+	}
+	for _, usedFR := range used {
+		f.forEachChunk(usedFR, func(chunk *chunkInfo, chunkFR memmap.FileRange) bool {
+			for off := chunkFR.Start; off < chunkFR.End; off += hostarch.PageSize {
+				pf := uint64(1 << linux.KPF_UPTODATE)
+				if chunk.huge {
+					pf |= 1 << linux.KPF_THP
+					if hostarch.IsHugePageAligned(off) {
+						pf |= 1 << linux.KPF_COMPOUND_HEAD
+					} else {
+						pf |= 1 << linux.KPF_COMPOUND_TAIL
+					}
+				}
+				flags[index(off)] = pf
+			}
+			return true
+		})
+	}
+	for _, ar := range acct {
+		for off := ar.fr.Start; off < ar.fr.End; off += hostarch.PageSize {
+			pf := &flags[index(off)]
+			if *pf&(1<<linux.KPF_BUDDY) != 0 {
+				continue
+			}
+			switch ar.kind {
+			case usage.Anonymous:
+				*pf |= 1<<linux.KPF_ANON | 1<<linux.KPF_SWAPBACKED
+			case usage.PageCache, usage.Tmpfs:
+				*pf |= 1 << linux.KPF_LRU
+			}
+			if ar.knownCommitted {
+				*pf |= 1 << linux.KPF_DIRTY
+			}
+		}
+	}
+	return n
+}
+
 // IsDiskBacked returns true if f is backed by a file on disk.
 func (f *MemoryFile) IsDiskBacked() bool {
 	return f.opts.DiskBackedFile
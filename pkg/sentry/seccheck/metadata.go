@@ -30,6 +30,7 @@ const (
 	PointExecve
 	PointExitNotifyParent
 	PointTaskExit
+	PointOOMKill
 
 	// Add new Points above this line.
 	pointLengthBeforeSyscalls
@@ -294,6 +295,11 @@ func genericInit() {
 		Name:          "sentry/task_exit",
 		ContextFields: defaultContextFields,
 	})
+	registerPoint(PointDesc{
+		ID:            PointOOMKill,
+		Name:          "sentry/oom_kill",
+		ContextFields: defaultContextFields,
+	})
 }
 
 var initOnce sync.Once
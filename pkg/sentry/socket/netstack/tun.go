@@ -20,6 +20,10 @@ import (
 	"gvisor.dev/gvisor/pkg/tcpip/link/tun"
 )
 
+// TUNFeatures are the Linux TUN flags supported by tun devices, as reported by
+// TUNGETFEATURES.
+const TUNFeatures = linux.IFF_TUN | linux.IFF_TAP | linux.IFF_NO_PI | linux.IFF_ONE_QUEUE | linux.IFF_VNET_HDR | linux.IFF_MULTI_QUEUE | linux.IFF_TUN_EXCL
+
 // TUNFlagsToLinux converts a tun.Flags to Linux TUN flags.
 func TUNFlagsToLinux(flags tun.Flags) uint16 {
 	ret := uint16(linux.IFF_NOFILTER)
@@ -35,6 +39,12 @@ func TUNFlagsToLinux(flags tun.Flags) uint16 {
 	if flags.Exclusive {
 		ret |= linux.IFF_TUN_EXCL
 	}
+	if flags.MultiQueue {
+		ret |= linux.IFF_MULTI_QUEUE
+	}
+	if flags.VnetHdr {
+		ret |= linux.IFF_VNET_HDR
+	}
 	return ret
 }
 
@@ -43,7 +53,7 @@ func LinuxToTUNFlags(flags uint16) (tun.Flags, error) {
 	// Linux adds IFF_NOFILTER (the same value as IFF_NO_PI unfortunately)
 	// when there is no sk_filter. See __tun_chr_ioctl() in
 	// net/drivers/tun.c.
-	if flags&^uint16(linux.IFF_TUN|linux.IFF_TAP|linux.IFF_NO_PI|linux.IFF_ONE_QUEUE|linux.IFF_TUN_EXCL) != 0 {
+	if flags&^uint16(TUNFeatures) != 0 {
 		return tun.Flags{}, linuxerr.EINVAL
 	}
 	return tun.Flags{
@@ -51,5 +61,7 @@ func LinuxToTUNFlags(flags uint16) (tun.Flags, error) {
 		TAP:          flags&linux.IFF_TAP != 0,
 		NoPacketInfo: flags&linux.IFF_NO_PI != 0,
 		Exclusive:    flags&linux.IFF_TUN_EXCL != 0,
+		MultiQueue:   flags&linux.IFF_MULTI_QUEUE != 0,
+		VnetHdr:      flags&linux.IFF_VNET_HDR != 0,
 	}, nil
 }
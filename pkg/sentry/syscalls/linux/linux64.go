@@ -150,10 +150,10 @@ var AMD64 = &kernel.SyscallTable{
 		95:  syscalls.Supported("umask", Umask),
 		96:  syscalls.Supported("gettimeofday", Gettimeofday),
 		97:  syscalls.Supported("getrlimit", Getrlimit),
-		98:  syscalls.PartiallySupported("getrusage", Getrusage, "Fields ru_maxrss, ru_minflt, ru_majflt, ru_inblock, ru_oublock are not supported. Fields ru_utime and ru_stime have low precision.", nil),
-		99:  syscalls.PartiallySupported("sysinfo", Sysinfo, "Fields loads, sharedram, bufferram, totalswap, freeswap, totalhigh, freehigh not supported.", nil),
+		98:  syscalls.PartiallySupported("getrusage", Getrusage, "Fields ru_utime and ru_stime have low precision.", nil),
+		99:  syscalls.Supported("sysinfo", Sysinfo),
 		100: syscalls.Supported("times", Times),
-		101: syscalls.PartiallySupported("ptrace", Ptrace, "Options PTRACE_PEEKSIGINFO, PTRACE_SECCOMP_GET_FILTER not supported.", nil),
+		101: syscalls.PartiallySupported("ptrace", Ptrace, "Option PTRACE_SECCOMP_GET_METADATA not supported.", nil),
 		102: syscalls.Supported("getuid", Getuid),
 		103: syscalls.PartiallySupported("syslog", Syslog, "Outputs a dummy message for security reasons.", nil),
 		104: syscalls.Supported("getgid", Getgid),
@@ -231,7 +231,7 @@ var AMD64 = &kernel.SyscallTable{
 		176: syscalls.CapError("delete_module", linux.CAP_SYS_MODULE, "", nil),
 		177: syscalls.Error("get_kernel_syms", linuxerr.ENOSYS, "Not supported in Linux > 2.6.", nil),
 		178: syscalls.Error("query_module", linuxerr.ENOSYS, "Not supported in Linux > 2.6.", nil),
-		179: syscalls.CapError("quotactl", linux.CAP_SYS_ADMIN, "", nil), // requires cap_sys_admin for most operations
+		179: syscalls.PartiallySupported("quotactl", Quotactl, "Only supported on tmpfs mounted with quota options. Q_QUOTAON and Q_QUOTAOFF are not supported.", nil),
 		180: syscalls.Error("nfsservctl", linuxerr.ENOSYS, "Removed after Linux 3.1.", nil),
 		181: syscalls.Error("getpmsg", linuxerr.ENOSYS, "Not implemented in Linux.", nil),
 		182: syscalls.Error("putpmsg", linuxerr.ENOSYS, "Not implemented in Linux.", nil),
@@ -381,7 +381,7 @@ var AMD64 = &kernel.SyscallTable{
 
 		// Syscalls implemented after 325 are "backports" from versions
 		// of Linux after 4.4.
-		326: syscalls.ErrorWithEvent("copy_file_range", linuxerr.ENOSYS, "", nil),
+		326: syscalls.Supported("copy_file_range", CopyFileRange),
 		327: syscalls.SupportedPoint("preadv2", Preadv2, PointPreadv2),
 		328: syscalls.SupportedPoint("pwritev2", Pwritev2, PointPwritev2),
 		329: syscalls.ErrorWithEvent("pkey_mprotect", linuxerr.ENOSYS, "", nil),
@@ -407,6 +407,7 @@ var AMD64 = &kernel.SyscallTable{
 		436: syscalls.Supported("close_range", CloseRange),
 		439: syscalls.Supported("faccessat2", Faccessat2),
 		441: syscalls.Supported("epoll_pwait2", EpollPwait2),
+		443: syscalls.PartiallySupported("quotactl_fd", QuotactlFD, "Only supported on tmpfs mounted with quota options. Q_QUOTAON and Q_QUOTAOFF are not supported.", nil),
 	},
 	Emulate: map[hostarch.Addr]uintptr{
 		0xffffffffff600000: 96,  // vsyscall gettimeofday(2)
@@ -491,7 +492,7 @@ var ARM64 = &kernel.SyscallTable{
 		57:  syscalls.SupportedPoint("close", Close, PointClose),
 		58:  syscalls.CapError("vhangup", linux.CAP_SYS_TTY_CONFIG, "", nil),
 		59:  syscalls.SupportedPoint("pipe2", Pipe2, PointPipe2),
-		60:  syscalls.CapError("quotactl", linux.CAP_SYS_ADMIN, "", nil), // requires cap_sys_admin for most operations
+		60:  syscalls.PartiallySupported("quotactl", Quotactl, "Only supported on tmpfs mounted with quota options. Q_QUOTAON and Q_QUOTAOFF are not supported.", nil),
 		61:  syscalls.Supported("getdents64", Getdents64),
 		62:  syscalls.Supported("lseek", Lseek),
 		63:  syscalls.SupportedPoint("read", Read, PointRead),
@@ -548,7 +549,7 @@ var ARM64 = &kernel.SyscallTable{
 		114: syscalls.Supported("clock_getres", ClockGetres),
 		115: syscalls.Supported("clock_nanosleep", ClockNanosleep),
 		116: syscalls.PartiallySupported("syslog", Syslog, "Outputs a dummy message for security reasons.", nil),
-		117: syscalls.PartiallySupported("ptrace", Ptrace, "Options PTRACE_PEEKSIGINFO, PTRACE_SECCOMP_GET_FILTER not supported.", nil),
+		117: syscalls.PartiallySupported("ptrace", Ptrace, "Option PTRACE_SECCOMP_GET_METADATA not supported.", nil),
 		118: syscalls.CapError("sched_setparam", linux.CAP_SYS_NICE, "", nil),
 		119: syscalls.PartiallySupported("sched_setscheduler", SchedSetscheduler, "Stub implementation.", nil),
 		120: syscalls.PartiallySupported("sched_getscheduler", SchedGetscheduler, "Stub implementation.", nil),
@@ -596,7 +597,7 @@ var ARM64 = &kernel.SyscallTable{
 		162: syscalls.Supported("setdomainname", Setdomainname),
 		163: syscalls.Supported("getrlimit", Getrlimit),
 		164: syscalls.PartiallySupported("setrlimit", Setrlimit, "Not all rlimits are enforced.", nil),
-		165: syscalls.PartiallySupported("getrusage", Getrusage, "Fields ru_maxrss, ru_minflt, ru_majflt, ru_inblock, ru_oublock are not supported. Fields ru_utime and ru_stime have low precision.", nil),
+		165: syscalls.PartiallySupported("getrusage", Getrusage, "Fields ru_utime and ru_stime have low precision.", nil),
 		166: syscalls.Supported("umask", Umask),
 		167: syscalls.PartiallySupported("prctl", Prctl, "Not all options are supported.", nil),
 		168: syscalls.Supported("getcpu", Getcpu),
@@ -610,7 +611,7 @@ var ARM64 = &kernel.SyscallTable{
 		176: syscalls.Supported("getgid", Getgid),
 		177: syscalls.Supported("getegid", Getegid),
 		178: syscalls.Supported("gettid", Gettid),
-		179: syscalls.PartiallySupported("sysinfo", Sysinfo, "Fields loads, sharedram, bufferram, totalswap, freeswap, totalhigh, freehigh not supported.", nil),
+		179: syscalls.Supported("sysinfo", Sysinfo),
 		180: syscalls.Supported("mq_open", MqOpen),
 		181: syscalls.Supported("mq_unlink", MqUnlink),
 		182: syscalls.ErrorWithEvent("mq_timedsend", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),    // TODO(b/29354921)
@@ -702,7 +703,7 @@ var ARM64 = &kernel.SyscallTable{
 		284: syscalls.PartiallySupported("mlock2", Mlock2, "Stub implementation. The sandbox lacks appropriate permissions.", nil),
 
 		// Syscalls after 284 are "backports" from versions of Linux after 4.4.
-		285: syscalls.ErrorWithEvent("copy_file_range", linuxerr.ENOSYS, "", nil),
+		285: syscalls.Supported("copy_file_range", CopyFileRange),
 		286: syscalls.SupportedPoint("preadv2", Preadv2, PointPreadv2),
 		287: syscalls.SupportedPoint("pwritev2", Pwritev2, PointPwritev2),
 		288: syscalls.ErrorWithEvent("pkey_mprotect", linuxerr.ENOSYS, "", nil),
@@ -728,6 +729,7 @@ var ARM64 = &kernel.SyscallTable{
 		436: syscalls.Supported("close_range", CloseRange),
 		439: syscalls.Supported("faccessat2", Faccessat2),
 		441: syscalls.Supported("epoll_pwait2", EpollPwait2),
+		443: syscalls.PartiallySupported("quotactl_fd", QuotactlFD, "Only supported on tmpfs mounted with quota options. Q_QUOTAON and Q_QUOTAOFF are not supported.", nil),
 	},
 	Emulate: map[hostarch.Addr]uintptr{},
 	Missing: func(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
@@ -175,7 +175,10 @@ func waitEpoll(t *kernel.Task, epfd int32, eventsAddr hostarch.Addr, maxEvents i
 				deadline = t.Kernel().MonotonicClock().Now().Add(timeoutDur)
 				haveDeadline = true
 			}
-			if err := t.BlockWithDeadline(ch, haveDeadline, deadline); err != nil {
+			prev := t.SetWaitChannel(kernel.WaitChannelEpoll)
+			err := t.BlockWithDeadline(ch, haveDeadline, deadline)
+			t.SetWaitChannel(prev)
+			if err != nil {
 				if linuxerr.Equals(linuxerr.ETIMEDOUT, err) {
 					err = nil
 				}
@@ -1609,29 +1609,63 @@ func Fallocate(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintp
 	if !file.IsWritable() {
 		return 0, nil, linuxerr.EBADF
 	}
-	if mode != 0 {
-		return 0, nil, linuxerr.ENOTSUP
-	}
 	if offset < 0 || length <= 0 {
 		return 0, nil, linuxerr.EINVAL
 	}
+	if err := checkFallocateMode(mode); err != nil {
+		return 0, nil, err
+	}
 
 	size := offset + length
 	if size < 0 {
 		return 0, nil, linuxerr.EFBIG
 	}
-	limit := limits.FromContext(t).Get(limits.FileSize).Cur
-	if uint64(size) >= limit {
-		t.SendSignal(&linux.SignalInfo{
-			Signo: int32(linux.SIGXFSZ),
-			Code:  linux.SI_USER,
-		})
-		return 0, nil, linuxerr.EFBIG
+	// Only modes that may extend the file to size are subject to
+	// RLIMIT_FSIZE. Compare Linux's mm/shmem.c:shmem_fallocate() =>
+	// fs/attr.c:inode_newsize_ok().
+	if mode&(linux.FALLOC_FL_KEEP_SIZE|linux.FALLOC_FL_PUNCH_HOLE|linux.FALLOC_FL_COLLAPSE_RANGE|linux.FALLOC_FL_INSERT_RANGE) == 0 {
+		limit := limits.FromContext(t).Get(limits.FileSize).Cur
+		if uint64(size) >= limit {
+			t.SendSignal(&linux.SignalInfo{
+				Signo: int32(linux.SIGXFSZ),
+				Code:  linux.SI_USER,
+			})
+			return 0, nil, linuxerr.EFBIG
+		}
 	}
 
 	return 0, nil, file.Allocate(t, mode, uint64(offset), uint64(length))
 }
 
+// checkFallocateMode checks that mode is a valid combination of fallocate(2)
+// flags. Whether a valid mode is supported depends on the file. This is the
+// filesystem-independent part of Linux's fs/open.c:vfs_fallocate().
+func checkFallocateMode(mode uint64) error {
+	const supportedMask = linux.FALLOC_FL_KEEP_SIZE | linux.FALLOC_FL_PUNCH_HOLE |
+		linux.FALLOC_FL_COLLAPSE_RANGE | linux.FALLOC_FL_ZERO_RANGE |
+		linux.FALLOC_FL_INSERT_RANGE | linux.FALLOC_FL_UNSHARE_RANGE
+	switch {
+	case mode&^supportedMask != 0:
+		return linuxerr.EOPNOTSUPP
+	case mode&(linux.FALLOC_FL_PUNCH_HOLE|linux.FALLOC_FL_ZERO_RANGE) == linux.FALLOC_FL_PUNCH_HOLE|linux.FALLOC_FL_ZERO_RANGE:
+		// Punch hole and zero range are mutually exclusive.
+		return linuxerr.EOPNOTSUPP
+	case mode&linux.FALLOC_FL_PUNCH_HOLE != 0 && mode&linux.FALLOC_FL_KEEP_SIZE == 0:
+		// Punch hole must have keep size set.
+		return linuxerr.EOPNOTSUPP
+	case mode&linux.FALLOC_FL_COLLAPSE_RANGE != 0 && mode != linux.FALLOC_FL_COLLAPSE_RANGE:
+		// Collapse range should only be used exclusively.
+		return linuxerr.EINVAL
+	case mode&linux.FALLOC_FL_INSERT_RANGE != 0 && mode != linux.FALLOC_FL_INSERT_RANGE:
+		// Insert range should only be used exclusively.
+		return linuxerr.EINVAL
+	case mode&linux.FALLOC_FL_UNSHARE_RANGE != 0 && mode&^(linux.FALLOC_FL_UNSHARE_RANGE|linux.FALLOC_FL_KEEP_SIZE) != 0:
+		// Unshare range should only be used with allocate mode.
+		return linuxerr.EINVAL
+	}
+	return nil
+}
+
 // Flock implements linux syscall flock(2).
 func Flock(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	fd := args[0].Int()
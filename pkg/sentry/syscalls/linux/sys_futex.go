@@ -58,6 +58,7 @@ func futexWaitAbsolute(t *kernel.Task, clockRealtime bool, ts linux.Timespec, fo
 		return 0, err
 	}
 
+	prev := t.SetWaitChannel(kernel.WaitChannelFutexWait)
 	if forever {
 		err = t.Block(w.C)
 	} else if clockRealtime {
@@ -65,6 +66,7 @@ func futexWaitAbsolute(t *kernel.Task, clockRealtime bool, ts linux.Timespec, fo
 	} else {
 		err = t.BlockWithDeadline(w.C, true, ktime.FromTimespec(ts))
 	}
+	t.SetWaitChannel(prev)
 
 	t.Futex().WaitComplete(w, t)
 	return 0, linuxerr.ConvertIntr(err, linuxerr.ERESTARTSYS)
@@ -87,7 +89,9 @@ func futexWaitDuration(t *kernel.Task, duration time.Duration, forever bool, add
 		return 0, err
 	}
 
+	prev := t.SetWaitChannel(kernel.WaitChannelFutexWait)
 	remaining, err := t.BlockWithTimeout(w.C, !forever, duration)
+	t.SetWaitChannel(prev)
 	t.Futex().WaitComplete(w, t)
 	if err == nil {
 		return 0, nil
@@ -128,11 +132,13 @@ func futexLockPI(t *kernel.Task, ts linux.Timespec, forever bool, addr hostarch.
 		return nil
 	}
 
+	prev := t.SetWaitChannel(kernel.WaitChannelFutexLockPI)
 	if forever {
 		err = t.Block(w.C)
 	} else {
 		err = t.BlockWithDeadlineFrom(w.C, t.Kernel().RealtimeClock(), true, ktime.FromTimespec(ts))
 	}
+	t.SetWaitChannel(prev)
 
 	t.Futex().WaitComplete(w, t)
 	return linuxerr.ConvertIntr(err, linuxerr.ERESTARTSYS)
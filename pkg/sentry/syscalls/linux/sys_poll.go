@@ -191,7 +191,9 @@ func doPoll(t *kernel.Task, addr hostarch.Addr, nfds uint, timeout time.Duration
 	for i := range pfd {
 		pfd[i].Events |= linux.POLLHUP | linux.POLLERR
 	}
+	prev := t.SetWaitChannel(kernel.WaitChannelPoll)
 	remainingTimeout, n, err := pollBlock(t, pfd, timeout)
+	t.SetWaitChannel(prev)
 	err = linuxerr.ConvertIntr(err, linuxerr.EINTR)
 
 	// The poll entries are copied out regardless of whether
@@ -301,7 +303,10 @@ func doSelect(t *kernel.Task, nfds int, readFDs, writeFDs, exceptFDs hostarch.Ad
 	}
 
 	// Do the syscall, then count the number of bits set.
-	if _, _, err = pollBlock(t, pfd, timeout); err != nil {
+	prev := t.SetWaitChannel(kernel.WaitChannelSelect)
+	_, _, err = pollBlock(t, pfd, timeout)
+	t.SetWaitChannel(prev)
+	if err != nil {
 		return 0, linuxerr.ConvertIntr(err, linuxerr.EINTR)
 	}
 
@@ -0,0 +1,216 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// Quotactl implements Linux syscall quotactl(2).
+//
+// Filesystems in the sandbox aren't backed by block devices, so special may be
+// the path of any file in the filesystem, rather than only the filesystem's
+// block device.
+func Quotactl(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	cmd := args[0].Uint()
+	specialAddr := args[1].Pointer()
+	id := args[2].Uint()
+	addr := args[3].Pointer()
+
+	if specialAddr == 0 {
+		// Q_SYNC with a NULL special syncs all filesystems, which is a no-op
+		// since quota usage is never written back.
+		if cmd>>linux.SUBCMDSHIFT == linux.Q_SYNC {
+			return 0, nil, nil
+		}
+		return 0, nil, linuxerr.EFAULT
+	}
+	path, err := copyInPath(t, specialAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	tpop, err := getTaskPathOperation(t, linux.AT_FDCWD, path, disallowEmptyPath, followFinalSymlink)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tpop.Release(t)
+	vd, err := t.Kernel().VFS().GetDentryAt(t, t.Credentials(), &tpop.pop, &vfs.GetDentryOptions{})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer vd.DecRef(t)
+	return 0, nil, quotactl(t, vd.Mount(), cmd, id, addr)
+}
+
+// QuotactlFD implements Linux syscall quotactl_fd(2).
+func QuotactlFD(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	fd := args[0].Int()
+	cmd := args[1].Uint()
+	id := args[2].Uint()
+	addr := args[3].Pointer()
+
+	file := t.GetFile(fd)
+	if file == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer file.DecRef(t)
+	return 0, nil, quotactl(t, file.Mount(), cmd, id, addr)
+}
+
+// quotactl performs the quotactl(2) command cmd on the filesystem mounted at
+// mnt.
+func quotactl(t *kernel.Task, mnt *vfs.Mount, cmd, id uint32, addr hostarch.Addr) error {
+	subcmd := cmd >> linux.SUBCMDSHIFT
+	qtype := cmd & linux.SUBCMDMASK
+	if qtype > linux.PRJQUOTA {
+		return linuxerr.EINVAL
+	}
+
+	// Check permissions as in Linux's fs/quota/quota.c:check_quotactl_permission().
+	creds := t.Credentials()
+	switch subcmd {
+	case linux.Q_GETFMT, linux.Q_SYNC, linux.Q_GETINFO:
+	case linux.Q_GETQUOTA:
+		if (qtype == linux.USRQUOTA && creds.EffectiveKUID == creds.UserNamespace.MapToKUID(auth.UID(id))) ||
+			(qtype == linux.GRPQUOTA && creds.InGroup(creds.UserNamespace.MapToKGID(auth.GID(id)))) {
+			break
+		}
+		fallthrough
+	default:
+		// Filesystems with quotas can only be mounted by the root user
+		// namespace, which owns them.
+		if !creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, creds.UserNamespace.Root()) {
+			return linuxerr.EPERM
+		}
+	}
+
+	qfs, ok := mnt.Filesystem().Impl().(vfs.QuotaFilesystemImpl)
+	if !ok {
+		return linuxerr.ENOSYS
+	}
+
+	// Quotas are indexed by kernel ids.
+	var kid uint32
+	switch subcmd {
+	case linux.Q_GETQUOTA, linux.Q_SETQUOTA, linux.Q_GETNEXTQUOTA:
+		switch qtype {
+		case linux.USRQUOTA:
+			kuid := creds.UserNamespace.MapToKUID(auth.UID(id))
+			if !kuid.Ok() {
+				return linuxerr.EINVAL
+			}
+			kid = uint32(kuid)
+		case linux.GRPQUOTA:
+			kgid := creds.UserNamespace.MapToKGID(auth.GID(id))
+			if !kgid.Ok() {
+				return linuxerr.EINVAL
+			}
+			kid = uint32(kgid)
+		default:
+			kid = id
+		}
+	}
+
+	switch subcmd {
+	case linux.Q_SYNC:
+		// Quota usage is never written back, so there is nothing to sync.
+		if _, err := qfs.GetQuotaFormat(qtype); err != nil {
+			return err
+		}
+		return nil
+
+	case linux.Q_QUOTAON, linux.Q_QUOTAOFF:
+		// Quotas can only be enabled or disabled by mount options.
+		return linuxerr.EINVAL
+
+	case linux.Q_GETFMT:
+		format, err := qfs.GetQuotaFormat(qtype)
+		if err != nil {
+			return err
+		}
+		_, err = primitive.CopyUint32Out(t, addr, format)
+		return err
+
+	case linux.Q_GETINFO:
+		info, err := qfs.GetQuotaInfo(qtype)
+		if err != nil {
+			return err
+		}
+		_, err = info.CopyOut(t, addr)
+		return err
+
+	case linux.Q_SETINFO:
+		var info linux.IfDqinfo
+		if _, err := info.CopyIn(t, addr); err != nil {
+			return err
+		}
+		return qfs.SetQuotaInfo(qtype, &info)
+
+	case linux.Q_GETQUOTA:
+		dq, err := qfs.GetQuota(qtype, kid)
+		if err != nil {
+			return err
+		}
+		_, err = dq.CopyOut(t, addr)
+		return err
+
+	case linux.Q_GETNEXTQUOTA:
+		// Skip quotas of ids that aren't mapped in the caller's user namespace,
+		// as in Linux's fs/quota/quota.c:quota_getnextquota().
+		for {
+			dq, err := qfs.GetNextQuota(qtype, kid)
+			if err != nil {
+				return err
+			}
+			next := dq.ID
+			var mapped bool
+			switch qtype {
+			case linux.USRQUOTA:
+				uid := creds.UserNamespace.MapFromKUID(auth.KUID(dq.ID))
+				dq.ID, mapped = uint32(uid), uid.Ok()
+			case linux.GRPQUOTA:
+				gid := creds.UserNamespace.MapFromKGID(auth.KGID(dq.ID))
+				dq.ID, mapped = uint32(gid), gid.Ok()
+			default:
+				mapped = true
+			}
+			if mapped {
+				_, err = dq.CopyOut(t, addr)
+				return err
+			}
+			if next == ^uint32(0) {
+				return linuxerr.ENOENT
+			}
+			kid = next + 1
+		}
+
+	case linux.Q_SETQUOTA:
+		var dq linux.IfDqblk
+		if _, err := dq.CopyIn(t, addr); err != nil {
+			return err
+		}
+		return qfs.SetQuota(qtype, kid, &dq)
+
+	default:
+		return linuxerr.EINVAL
+	}
+}
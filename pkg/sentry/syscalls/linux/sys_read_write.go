@@ -105,6 +105,7 @@ func read(t *kernel.Task, file *vfs.FileDescription, dst usermem.IOSequence, opt
 	if err := file.EventRegister(&w); err != nil {
 		return n, err
 	}
+	prev := t.SetWaitChannel(kernel.WaitChannelRead)
 
 	total := n
 	for {
@@ -127,6 +128,7 @@ func read(t *kernel.Task, file *vfs.FileDescription, dst usermem.IOSequence, opt
 			break
 		}
 	}
+	t.SetWaitChannel(prev)
 	file.EventUnregister(&w)
 
 	return total, err
@@ -262,6 +264,7 @@ func pread(t *kernel.Task, file *vfs.FileDescription, dst usermem.IOSequence, of
 	if err := file.EventRegister(&w); err != nil {
 		return n, err
 	}
+	prev := t.SetWaitChannel(kernel.WaitChannelRead)
 	total := n
 	for {
 		// Shorten dst to reflect bytes previously read.
@@ -283,6 +286,7 @@ func pread(t *kernel.Task, file *vfs.FileDescription, dst usermem.IOSequence, of
 			break
 		}
 	}
+	t.SetWaitChannel(prev)
 	file.EventUnregister(&w)
 	return total, err
 }
@@ -359,6 +363,7 @@ func write(t *kernel.Task, file *vfs.FileDescription, src usermem.IOSequence, op
 	if err := file.EventRegister(&w); err != nil {
 		return n, err
 	}
+	prev := t.SetWaitChannel(kernel.WaitChannelWrite)
 
 	total := n
 	for {
@@ -381,6 +386,7 @@ func write(t *kernel.Task, file *vfs.FileDescription, src usermem.IOSequence, op
 			break
 		}
 	}
+	t.SetWaitChannel(prev)
 	file.EventUnregister(&w)
 	return total, err
 }
@@ -515,6 +521,7 @@ func pwrite(t *kernel.Task, file *vfs.FileDescription, src usermem.IOSequence, o
 	if err := file.EventRegister(&w); err != nil {
 		return n, err
 	}
+	prev := t.SetWaitChannel(kernel.WaitChannelWrite)
 
 	total := n
 	for {
@@ -537,6 +544,7 @@ func pwrite(t *kernel.Task, file *vfs.FileDescription, src usermem.IOSequence, o
 			break
 		}
 	}
+	t.SetWaitChannel(prev)
 	file.EventUnregister(&w)
 	return total, err
 }
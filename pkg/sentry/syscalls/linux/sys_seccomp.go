@@ -79,7 +79,7 @@ func seccomp(t *kernel.Task, mode, flags uint64, addr hostarch.Addr) error {
 		return linuxerr.EINVAL
 	}
 
-	return t.AppendSyscallFilter(compiledFilter, tsync)
+	return t.AppendSyscallFilter(compiledFilter, filter, tsync)
 }
 
 // Seccomp implements linux syscall seccomp(2).
@@ -330,7 +330,10 @@ func Sigaltstack(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uin
 
 // Pause implements linux syscall pause(2).
 func Pause(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
-	return 0, nil, linuxerr.ConvertIntr(t.Block(nil), linuxerr.ERESTARTNOHAND)
+	prev := t.SetWaitChannel(kernel.WaitChannelPause)
+	err := t.Block(nil)
+	t.SetWaitChannel(prev)
+	return 0, nil, linuxerr.ConvertIntr(err, linuxerr.ERESTARTNOHAND)
 }
 
 // RtSigpending implements linux syscall rt_sigpending(2).
@@ -478,7 +481,10 @@ func RtSigsuspend(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (ui
 	t.SetSavedSignalMask(oldmask)
 
 	// Perform the wait.
-	return 0, nil, linuxerr.ConvertIntr(t.Block(nil), linuxerr.ERESTARTNOHAND)
+	prev := t.SetWaitChannel(kernel.WaitChannelSigsuspend)
+	err := t.Block(nil)
+	t.SetWaitChannel(prev)
+	return 0, nil, linuxerr.ConvertIntr(err, linuxerr.ERESTARTNOHAND)
 }
 
 // RestartSyscall implements the linux syscall restart_syscall(2).
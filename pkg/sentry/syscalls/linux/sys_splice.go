@@ -19,6 +19,7 @@ import (
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
@@ -471,6 +472,202 @@ func Sendfile(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintpt
 	return uintptr(total), nil, HandleIOError(t, total != 0, err, linuxerr.ERESTARTSYS, "sendfile", inFile)
 }
 
+// CopyFileRange implements Linux syscall copy_file_range(2).
+func CopyFileRange(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	inFD := args[0].Int()
+	inOffsetAddr := args[1].Pointer()
+	outFD := args[2].Int()
+	outOffsetAddr := args[3].Pointer()
+	count := int64(args[4].SizeT())
+	flags := args[5].Uint()
+
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	inFile := t.GetFile(inFD)
+	if inFile == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer inFile.DecRef(t)
+	if !inFile.IsReadable() {
+		return 0, nil, linuxerr.EBADF
+	}
+
+	outFile := t.GetFile(outFD)
+	if outFile == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer outFile.DecRef(t)
+	if !outFile.IsWritable() || outFile.StatusFlags()&linux.O_APPEND != 0 {
+		return 0, nil, linuxerr.EBADF
+	}
+
+	// Both files must be regular files, as in Linux
+	// (fs/read_write.c:generic_file_rw_checks).
+	inStat, err := copyFileRangeStat(t, inFile)
+	if err != nil {
+		return 0, nil, err
+	}
+	outStat, err := copyFileRangeStat(t, outFile)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	inOffset, err := copyFileRangeOffset(t, inFile, inOffsetAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	outOffset, err := copyFileRangeOffset(t, outFile, outOffsetAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	if count < 0 || inOffset+count < 0 || outOffset+count < 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if count == 0 {
+		return 0, nil, nil
+	}
+	if count > int64(kernel.MAX_RW_COUNT) {
+		count = int64(kernel.MAX_RW_COUNT)
+	}
+
+	// Copying a file onto an overlapping range of itself is not allowed.
+	if inStat.DevMajor == outStat.DevMajor && inStat.DevMinor == outStat.DevMinor && inStat.Ino == outStat.Ino &&
+		inOffset+count > outOffset && outOffset+count > inOffset {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	// copy_file_range is only supported for filesystems that can offload
+	// copies, currently FUSE. Other files fail with EXDEV, for which callers
+	// fall back to copying the data themselves.
+	impl, ok := inFile.Impl().(vfs.FileDescriptionCopyRangeImpl)
+	if !ok {
+		return 0, nil, linuxerr.EXDEV
+	}
+
+	// Let the filesystem copy the data if it can, e.g. on the server side.
+	// Otherwise, if both files are on the same filesystem, read inFile to a
+	// buffer, then write the contents to outFile. As in Linux since 5.19
+	// (fs/read_write.c:vfs_copy_file_range), copies between filesystems that
+	// can't be offloaded fail with EXDEV.
+	total, err := impl.CopyRange(t, inOffset, outFile, outOffset, count)
+	if linuxerr.Equals(linuxerr.EXDEV, err) || linuxerr.Equals(linuxerr.EOPNOTSUPP, err) {
+		if inFile.Mount().Filesystem() != outFile.Mount().Filesystem() {
+			return 0, nil, linuxerr.EXDEV
+		}
+		total, err = copyFileRangeFallback(t, inFile, inOffset, outFile, outOffset, count)
+	}
+
+	if total != 0 {
+		if err := copyFileRangeUpdateOffset(t, inFile, inOffsetAddr, inOffset+total); err != nil {
+			return 0, nil, err
+		}
+		if err := copyFileRangeUpdateOffset(t, outFile, outOffsetAddr, outOffset+total); err != nil {
+			return 0, nil, err
+		}
+		if err != nil && err != io.EOF {
+			// If a partial copy is completed, the error is dropped. Log it here.
+			log.Debugf("copy_file_range completed a partial copy with error: %v", err)
+			err = nil
+		}
+	}
+
+	// We can only pass a single file to handleIOError, so pick inFile arbitrarily.
+	// This is used only for debugging purposes.
+	return uintptr(total), nil, HandleIOError(t, total != 0, err, linuxerr.ERESTARTSYS, "copy_file_range", inFile)
+}
+
+// copyFileRangeStat returns the type, device and inode number of fd, which
+// must be a regular file.
+func copyFileRangeStat(t *kernel.Task, fd *vfs.FileDescription) (linux.Statx, error) {
+	stat, err := fd.Stat(t, vfs.StatOptions{Mask: linux.STATX_TYPE | linux.STATX_INO})
+	if err != nil {
+		return linux.Statx{}, err
+	}
+	if stat.Mask&linux.STATX_TYPE == 0 {
+		return linux.Statx{}, linuxerr.EINVAL
+	}
+	switch stat.Mode & linux.S_IFMT {
+	case linux.S_IFREG:
+		return stat, nil
+	case linux.S_IFDIR:
+		return linux.Statx{}, linuxerr.EISDIR
+	default:
+		return linux.Statx{}, linuxerr.EINVAL
+	}
+}
+
+// copyFileRangeOffset returns the offset in fd to copy from or to: the one at
+// offsetAddr if it isn't 0, and the file offset otherwise.
+func copyFileRangeOffset(t *kernel.Task, fd *vfs.FileDescription, offsetAddr hostarch.Addr) (int64, error) {
+	if offsetAddr == 0 {
+		return fd.Seek(t, 0, linux.SEEK_CUR)
+	}
+	if fd.Options().DenyPRead {
+		return 0, linuxerr.ESPIPE
+	}
+	var offsetP primitive.Int64
+	if _, err := offsetP.CopyIn(t, offsetAddr); err != nil {
+		return 0, err
+	}
+	if offsetP < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	return int64(offsetP), nil
+}
+
+// copyFileRangeUpdateOffset stores offset at offsetAddr if it isn't 0, and in
+// the file offset of fd otherwise.
+func copyFileRangeUpdateOffset(t *kernel.Task, fd *vfs.FileDescription, offsetAddr hostarch.Addr, offset int64) error {
+	if offsetAddr == 0 {
+		_, err := fd.Seek(t, offset, linux.SEEK_SET)
+		return err
+	}
+	offsetP := primitive.Int64(offset)
+	_, err := offsetP.CopyOut(t, offsetAddr)
+	return err
+}
+
+// copyFileRangeFallback copies up to count bytes from inFile at inOffset to
+// outFile at outOffset through a buffer, and returns the number of bytes
+// copied.
+func copyFileRangeFallback(t *kernel.Task, inFile *vfs.FileDescription, inOffset int64, outFile *vfs.FileDescription, outOffset int64, count int64) (int64, error) {
+	// As for sendfile, the buffer size is limited to the size of a pipe to
+	// avoid large memory allocations and long delays.
+	bufSize := count
+	if bufSize > pipe.MaximumPipeSize {
+		bufSize = pipe.MaximumPipeSize
+	}
+	buf := make([]byte, bufSize)
+	var total int64
+	for total < count {
+		if int64(len(buf)) > count-total {
+			buf = buf[:count-total]
+		}
+		readN, err := inFile.PRead(t, usermem.BytesIOSequence(buf), inOffset+total, vfs.ReadOptions{})
+		if readN == 0 {
+			return total, err
+		}
+		// Only report the bytes that were actually written.
+		writeN, werr := outFile.PWrite(t, usermem.BytesIOSequence(buf[:readN]), outOffset+total, vfs.WriteOptions{})
+		total += writeN
+		if werr != nil {
+			return total, werr
+		}
+		if writeN < readN {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+		if t.Interrupted() {
+			return total, linuxerr.ErrInterrupted
+		}
+	}
+	return total, nil
+}
+
 // dualWaiter is used to wait on one or both vfs.FileDescriptions. It is not
 // thread-safe, and does not take a reference on the vfs.FileDescriptions.
 //
@@ -39,13 +39,18 @@ func Sysinfo(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr
 		memFree = 0
 	}
 
-	// Only a subset of the fields in sysinfo_t make sense to return.
+	// There is no swap, high memory or block device buffer cache, so the
+	// corresponding fields are always zero.
 	si := linux.Sysinfo{
-		Procs:    uint16(t.Kernel().TaskSet().Root.NumTasks()),
-		Uptime:   t.Kernel().MonotonicClock().Now().Seconds(),
-		TotalRAM: totalSize,
-		FreeRAM:  memFree,
-		Unit:     1,
+		Procs:     uint16(t.Kernel().TaskSet().Root.NumTasks()),
+		Uptime:    t.Kernel().MonotonicClock().Now().Seconds(),
+		TotalRAM:  totalSize,
+		FreeRAM:   memFree,
+		SharedRAM: memStats.Tmpfs,
+		Unit:      1,
+	}
+	for i, avg := range t.Kernel().LoadAverage() {
+		si.Loads[i] = avg << (linux.SI_LOAD_SHIFT - kernel.LoadAvgShift)
 	}
 	_, err = si.CopyOut(t, addr)
 	return 0, nil, err
@@ -179,8 +179,25 @@ func execveat(t *kernel.Task, dirfd int32, pathnameAddr, argvAddr, envvAddr host
 		}
 	}
 
+	// A binfmt_misc handler may pass the executable to its interpreter as an
+	// open file descriptor.
+	execFD := int32(-1)
+	loadArgs.InstallExecFD = func(f *vfs.FileDescription) (int32, error) {
+		fd, err := t.NewFDFrom(0, f, kernel.FDFlags{})
+		if err != nil {
+			return -1, err
+		}
+		execFD = fd
+		return fd, nil
+	}
+
 	image, se := t.Kernel().LoadTaskImage(t, loadArgs)
 	if se != nil {
+		if execFD >= 0 {
+			if file := t.FDTable().Remove(t, execFD); file != nil {
+				file.DecRef(t)
+			}
+		}
 		return 0, nil, se.ToError()
 	}
 
@@ -786,5 +803,6 @@ func Ptrace(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 	addr := args[2].Pointer()
 	data := args[3].Pointer()
 
-	return 0, nil, t.Ptrace(req, pid, addr, data)
+	n, err := t.Ptrace(req, pid, addr, data)
+	return n, nil, err
 }
@@ -241,7 +241,9 @@ func ClockNanosleep(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (
 // If blocking is interrupted, the syscall is restarted with the original
 // arguments.
 func clockNanosleepUntil(t *kernel.Task, c ktime.Clock, end ktime.Time, rem hostarch.Addr, needRestartBlock bool) error {
+	prev := t.SetWaitChannel(kernel.WaitChannelNanosleep)
 	err := t.BlockWithDeadlineFrom(nil, c, true, end)
+	t.SetWaitChannel(prev)
 
 	switch {
 	case linuxerr.Equals(linuxerr.ETIMEDOUT, err):
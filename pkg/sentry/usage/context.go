@@ -0,0 +1,37 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usage
+
+import (
+	"context"
+)
+
+// contextID is this package's type for context.Context.Value keys.
+type contextID int
+
+const (
+	// CtxIO is a Context.Value key for the *IO to which I/O performed on
+	// behalf of the context is charged.
+	CtxIO contextID = iota
+)
+
+// IOFromContext returns the *IO to which I/O performed on behalf of ctx is
+// charged, or nil if no such *IO exists.
+func IOFromContext(ctx context.Context) *IO {
+	if v := ctx.Value(CtxIO); v != nil {
+		return v.(*IO)
+	}
+	return nil
+}
@@ -87,6 +87,21 @@ func (i *IO) AccountWriteIO(bytes int64) {
 	}
 }
 
+// blockSize is the unit in which struct rusage reports block I/O.
+const blockSize = 512
+
+// BlocksRead returns the number of 512-byte blocks read into pagecache, as
+// reported by struct rusage::ru_inblock.
+func (i *IO) BlocksRead() uint64 {
+	return i.BytesRead.Load() / blockSize
+}
+
+// BlocksWritten returns the number of 512-byte blocks written from pagecache,
+// as reported by struct rusage::ru_oublock.
+func (i *IO) BlocksWritten() uint64 {
+	return i.BytesWritten.Load() / blockSize
+}
+
 // Accumulate adds up io usages.
 func (i *IO) Accumulate(io *IO) {
 	i.CharsRead.Add(io.CharsRead.Load())
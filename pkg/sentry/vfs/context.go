@@ -34,6 +34,14 @@ const (
 	// mapping filesystem unique IDs (cf. gofer.InternalFilesystemOptions.UniqueID)
 	// to host FDs.
 	CtxRestoreFilesystemFDMap
+
+	// CtxIOAccounter is a Context.Value key for the IOAccounter that accounts
+	// and throttles file I/O performed by the context.
+	CtxIOAccounter
+
+	// CtxIOWaiter is a Context.Value key for the IOWaiter that records when
+	// the context is waiting for file I/O.
+	CtxIOWaiter
 )
 
 // MountNamespaceFromContext returns the MountNamespace used by ctx. If ctx is
@@ -348,8 +348,12 @@ type FileDescriptionImpl interface {
 	// represented by the FileDescription.
 	StatFS(ctx context.Context) (linux.Statfs, error)
 
-	// Allocate grows the file to offset + length bytes.
-	// Only mode == 0 is supported currently.
+	// Allocate allocates or deallocates space for the byte range [offset,
+	// offset + length), as specified by mode, which is a combination of
+	// linux.FALLOC_FL_* flags. If mode is 0, Allocate grows the file to
+	// offset + length bytes if it is smaller. mode has already been checked
+	// for invalid combinations of flags; implementations should return
+	// EOPNOTSUPP for modes they don't support.
 	//
 	// Allocate should return EISDIR on directories, ESPIPE on pipes, and ENODEV on
 	// other files where it is not supported.
@@ -487,6 +491,17 @@ type FileDescriptionImpl interface {
 	UnregisterFileAsyncHandler(fd *FileDescription)
 }
 
+// FileDescriptionCopyRangeImpl is an optional extension to
+// FileDescriptionImpl for files that can copy data to other files without the
+// data going through the sentry, as for copy_file_range(2).
+type FileDescriptionCopyRangeImpl interface {
+	// CopyRange copies up to length bytes from offset srcOff in the file to
+	// offset dstOff in dst, and returns the number of bytes copied. If the
+	// copy can't be offloaded, CopyRange returns EXDEV or EOPNOTSUPP without
+	// copying anything, and the caller should copy the data itself.
+	CopyRange(ctx context.Context, srcOff int64, dst *FileDescription, dstOff, length int64) (int64, error)
+}
+
 // Dirent holds the information contained in struct linux_dirent64.
 //
 // +stateify savable
@@ -584,7 +599,8 @@ func (fd *FileDescription) StatFS(ctx context.Context) (linux.Statfs, error) {
 	return fd.impl.StatFS(ctx)
 }
 
-// Allocate grows file represented by FileDescription to offset + length bytes.
+// Allocate allocates or deallocates space for the file represented by
+// FileDescription, as specified by mode.
 func (fd *FileDescription) Allocate(ctx context.Context, mode, offset, length uint64) error {
 	if !fd.IsWritable() {
 		return linuxerr.EBADF
@@ -632,8 +648,13 @@ func (fd *FileDescription) PRead(ctx context.Context, dst usermem.IOSequence, of
 	if !fd.readable {
 		return 0, linuxerr.EBADF
 	}
+	acct, err := fd.startIO(ctx, false /* write */, dst.NumBytes())
+	if err != nil {
+		return 0, err
+	}
 	start := fsmetric.StartReadWait()
 	n, err := fd.impl.PRead(ctx, dst, offset, opts)
+	acct.finish(n)
 	if n > 0 {
 		fd.Dentry().InotifyWithParent(ctx, linux.IN_ACCESS, 0, PathEvent)
 	}
@@ -647,8 +668,13 @@ func (fd *FileDescription) Read(ctx context.Context, dst usermem.IOSequence, opt
 	if !fd.readable {
 		return 0, linuxerr.EBADF
 	}
+	acct, err := fd.startIO(ctx, false /* write */, dst.NumBytes())
+	if err != nil {
+		return 0, err
+	}
 	start := fsmetric.StartReadWait()
 	n, err := fd.impl.Read(ctx, dst, opts)
+	acct.finish(n)
 	if n > 0 {
 		fd.Dentry().InotifyWithParent(ctx, linux.IN_ACCESS, 0, PathEvent)
 	}
@@ -667,7 +693,12 @@ func (fd *FileDescription) PWrite(ctx context.Context, src usermem.IOSequence, o
 	if !fd.writable {
 		return 0, linuxerr.EBADF
 	}
+	acct, err := fd.startIO(ctx, true /* write */, src.NumBytes())
+	if err != nil {
+		return 0, err
+	}
 	n, err := fd.impl.PWrite(ctx, src, offset, opts)
+	acct.finish(n)
 	if n > 0 {
 		fd.Dentry().InotifyWithParent(ctx, linux.IN_MODIFY, 0, PathEvent)
 	}
@@ -679,7 +710,12 @@ func (fd *FileDescription) Write(ctx context.Context, src usermem.IOSequence, op
 	if !fd.writable {
 		return 0, linuxerr.EBADF
 	}
+	acct, err := fd.startIO(ctx, true /* write */, src.NumBytes())
+	if err != nil {
+		return 0, err
+	}
 	n, err := fd.impl.Write(ctx, src, opts)
+	acct.finish(n)
 	if n > 0 {
 		fd.Dentry().InotifyWithParent(ctx, linux.IN_MODIFY, 0, PathEvent)
 	}
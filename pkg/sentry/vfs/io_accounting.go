@@ -0,0 +1,131 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"gvisor.dev/gvisor/pkg/context"
+)
+
+// IODevice identifies the device that file I/O is accounted to.
+//
+// +stateify savable
+type IODevice struct {
+	Major uint32
+	Minor uint32
+}
+
+// IOAccounter accounts and throttles file I/O, e.g. for an io cgroup.
+type IOAccounter interface {
+	// StartIO is called before a read or write of up to size bytes to dev. It
+	// may block to enforce I/O limits, in which case it returns a non-nil
+	// error if interrupted.
+	StartIO(ctx context.Context, dev IODevice, write bool, size int64) error
+
+	// FinishIO is called after a read or write started by StartIO completes,
+	// having transferred n bytes (or n < 0 if it failed without transferring
+	// any bytes).
+	FinishIO(dev IODevice, write bool, size, n int64)
+}
+
+// IOWaiter records when a context is waiting for file I/O performed outside
+// the sentry, e.g. for pressure stall information.
+type IOWaiter interface {
+	// IOWaitStart is called before a read or write on an accounted file.
+	// Calls to IOWaitStart may nest.
+	IOWaitStart()
+
+	// IOWaitFinish is called after a read or write started by IOWaitStart
+	// completes.
+	IOWaitFinish()
+}
+
+// IOAccountedFileDescriptionImpl may be implemented by a FileDescriptionImpl
+// whose reads and writes perform I/O outside the sentry, and should be
+// accounted and throttled per device.
+type IOAccountedFileDescriptionImpl interface {
+	// IODevice returns the device that I/O on the file is accounted to. If
+	// ok is false, I/O on the file isn't accounted.
+	IODevice() (dev IODevice, ok bool)
+}
+
+// ioAccounterFromContext returns the IOAccounter used by ctx, or nil if ctx
+// doesn't account file I/O.
+func ioAccounterFromContext(ctx context.Context) IOAccounter {
+	if v := ctx.Value(CtxIOAccounter); v != nil {
+		return v.(IOAccounter)
+	}
+	return nil
+}
+
+// ioWaiterFromContext returns the IOWaiter used by ctx, or nil if ctx doesn't
+// record waiting for file I/O.
+func ioWaiterFromContext(ctx context.Context) IOWaiter {
+	if v := ctx.Value(CtxIOWaiter); v != nil {
+		return v.(IOWaiter)
+	}
+	return nil
+}
+
+// ioAccount is a read or write in progress on an accounted file.
+type ioAccount struct {
+	acc    IOAccounter
+	waiter IOWaiter
+	dev    IODevice
+	write  bool
+	size   int64
+}
+
+// startIO starts accounting a read or write of up to size bytes on fd. If fd
+// isn't accounted by ctx, startIO returns a zero ioAccount.
+func (fd *FileDescription) startIO(ctx context.Context, write bool, size int64) (ioAccount, error) {
+	impl, ok := fd.impl.(IOAccountedFileDescriptionImpl)
+	if !ok || size == 0 {
+		return ioAccount{}, nil
+	}
+	acc := ioAccounterFromContext(ctx)
+	w := ioWaiterFromContext(ctx)
+	if acc == nil && w == nil {
+		return ioAccount{}, nil
+	}
+	dev, ok := impl.IODevice()
+	if !ok {
+		return ioAccount{}, nil
+	}
+	if acc != nil {
+		if err := acc.StartIO(ctx, dev, write, size); err != nil {
+			return ioAccount{}, err
+		}
+	}
+	if w != nil {
+		w.IOWaitStart()
+	}
+	return ioAccount{
+		acc:    acc,
+		waiter: w,
+		dev:    dev,
+		write:  write,
+		size:   size,
+	}, nil
+}
+
+// finish completes accounting an I/O that transferred n bytes.
+func (a *ioAccount) finish(n int64) {
+	if a.waiter != nil {
+		a.waiter.IOWaitFinish()
+	}
+	if a.acc != nil {
+		a.acc.FinishIO(a.dev, a.write, a.size, n)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+)
+
+// QuotaFilesystemImpl may be implemented by a FilesystemImpl that supports
+// disk quotas, as managed by quotactl(2). qtype is linux.USRQUOTA,
+// linux.GRPQUOTA or linux.PRJQUOTA; methods return ESRCH if quotas of qtype
+// aren't enabled on the filesystem. ids are kernel ids (auth.KUID, auth.KGID or
+// project ids); callers are responsible for mapping them from user namespaces
+// and for permission checks.
+type QuotaFilesystemImpl interface {
+	// GetQuotaFormat returns the quota format (linux.QFMT_*) of quotas of
+	// type qtype.
+	GetQuotaFormat(qtype uint32) (uint32, error)
+
+	// GetQuotaInfo returns the grace periods and flags of quotas of type
+	// qtype.
+	GetQuotaInfo(qtype uint32) (linux.IfDqinfo, error)
+
+	// SetQuotaInfo sets the grace periods and flags of quotas of type qtype
+	// that are indicated by info.Valid.
+	SetQuotaInfo(qtype uint32, info *linux.IfDqinfo) error
+
+	// GetQuota returns the limits and usage of the quota of type qtype for
+	// id.
+	GetQuota(qtype, id uint32) (linux.IfDqblk, error)
+
+	// GetNextQuota returns the limits and usage of the quota of type qtype
+	// for the lowest id >= the given id that has a quota. If there is no
+	// such id, it returns ENOENT.
+	GetNextQuota(qtype, id uint32) (linux.IfNextDqblk, error)
+
+	// SetQuota sets the limits, usage and grace times of the quota of type
+	// qtype for id that are indicated by dq.Valid.
+	SetQuota(qtype, id uint32, dq *linux.IfDqblk) error
+}
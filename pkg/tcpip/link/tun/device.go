@@ -16,11 +16,14 @@ package tun
 
 import (
 	"fmt"
+	"math"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/buffer"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/rand"
 	"gvisor.dev/gvisor/pkg/tcpip"
 	"gvisor.dev/gvisor/pkg/tcpip/header"
 	"gvisor.dev/gvisor/pkg/tcpip/link/channel"
@@ -46,8 +49,18 @@ var zeroMAC [6]byte
 type Device struct {
 	waiter.Queue
 
-	mu           deviceRWMutex `state:"nosave"`
-	endpoint     *tunEndpoint
+	mu       deviceRWMutex `state:"nosave"`
+	endpoint *tunEndpoint
+
+	// queue holds the outbound packets read from d. For a multi-queue NIC,
+	// queue is owned by d; otherwise it is the only queue of the NIC, shared
+	// by all devices attached to it.
+	queue *channel.Endpoint
+
+	// detached is true if queue has been detached from its multi-queue NIC
+	// by TUNSETQUEUE.
+	detached bool
+
 	notifyHandle *channel.NotificationHandle
 	flags        Flags
 }
@@ -60,6 +73,8 @@ type Flags struct {
 	TAP          bool
 	NoPacketInfo bool
 	Exclusive    bool
+	MultiQueue   bool
+	VnetHdr      bool
 }
 
 // beforeSave is invoked by stateify.
@@ -93,10 +108,21 @@ func (d *Device) Release(ctx context.Context) {
 
 	// Decrease refcount if there is an endpoint associated with this file.
 	if d.endpoint != nil {
-		d.endpoint.Drain()
-		d.endpoint.RemoveNotify(d.notifyHandle)
+		if d.endpoint.multiQueue {
+			if !d.detached {
+				d.endpoint.detachQueue(d.queue)
+			}
+			// Close the queue, which discards pending packets, rather
+			// than draining it, since a concurrent WritePackets may
+			// still write to it.
+			d.queue.Close()
+		} else {
+			d.queue.Drain()
+		}
+		d.queue.RemoveNotify(d.notifyHandle)
 		d.endpoint.DecRef(ctx)
 		d.endpoint = nil
+		d.queue = nil
 	}
 }
 
@@ -129,12 +155,105 @@ func (d *Device) SetIff(ctx context.Context, s *stack.Stack, name string, flags
 		return err
 	}
 
+	queue := endpoint.Endpoint
+	if flags.MultiQueue {
+		queue = channel.New(defaultDevOutQueueLen, 0, "")
+		if err := endpoint.attachQueue(queue); err != nil {
+			endpoint.DecRef(ctx)
+			return err
+		}
+	}
+
 	d.endpoint = endpoint
-	d.notifyHandle = d.endpoint.AddNotify(d)
+	d.queue = queue
+	d.notifyHandle = queue.AddNotify(d)
 	d.flags = flags
 	return nil
 }
 
+// SetQueue services TUNSETQUEUE ioctl(2) request. It attaches the queue of d
+// to its multi-queue NIC if attach is true, and detaches it otherwise. A
+// detached queue doesn't receive packets, and d can't be read or written until
+// it is attached again.
+func (d *Device) SetQueue(attach bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// See drivers/net/tun.c:tun_set_queue().
+	if d.endpoint == nil || !d.endpoint.multiQueue {
+		return linuxerr.EINVAL
+	}
+	if attach {
+		if !d.detached {
+			return linuxerr.EINVAL
+		}
+		if err := d.endpoint.attachQueue(d.queue); err != nil {
+			return err
+		}
+		d.detached = false
+		return nil
+	}
+	if d.detached {
+		return linuxerr.EINVAL
+	}
+	d.endpoint.detachQueue(d.queue)
+	d.queue.Drain()
+	d.detached = true
+	return nil
+}
+
+// VnetHdrSize services TUNGETVNETHDRSZ ioctl(2) request.
+func (d *Device) VnetHdrSize() (int32, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.endpoint == nil || d.detached {
+		return 0, linuxerr.EBADFD
+	}
+	return d.endpoint.vnetHdrSize.Load(), nil
+}
+
+// SetVnetHdrSize services TUNSETVNETHDRSZ ioctl(2) request. The size applies
+// to all devices attached to the NIC.
+func (d *Device) SetVnetHdrSize(size int32) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.endpoint == nil || d.detached {
+		return linuxerr.EBADFD
+	}
+	if size < int32(linux.SizeOfVirtioNetHdr) {
+		return linuxerr.EINVAL
+	}
+	d.endpoint.vnetHdrSize.Store(size)
+	return nil
+}
+
+// SetOffload services TUNSETOFFLOAD ioctl(2) request. offloads is a mask of
+// linux.TUN_F_* flags for the offloads that the reader of packets with a
+// virtio-net header can handle. The offloads apply to all devices attached to
+// the NIC.
+func (d *Device) SetOffload(offloads uint32) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.endpoint == nil || d.detached {
+		return linuxerr.EBADFD
+	}
+	if offloads&^supportedOffloads != 0 {
+		return linuxerr.EINVAL
+	}
+	// Segmentation offloads are only enabled together with the checksum
+	// offload. See drivers/net/tun.c:set_offload().
+	if offloads&linux.TUN_F_CSUM == 0 {
+		offloads = 0
+	}
+	// Without a virtio-net header, offloads can't be described to the
+	// reader.
+	if !d.flags.VnetHdr {
+		return nil
+	}
+	d.endpoint.offloads.Store(offloads)
+	return nil
+}
+
 func attachOrCreateNIC(ctx context.Context, s *stack.Stack, name, prefix string, linkCaps stack.LinkEndpointCapabilities, flags Flags) (*tunEndpoint, error) {
 	for {
 		// 1. Try to attach to an existing NIC.
@@ -150,6 +269,10 @@ func attachOrCreateNIC(ctx context.Context, s *stack.Stack, name, prefix string,
 					// Not a NIC created by tun device.
 					return nil, linuxerr.EOPNOTSUPP
 				}
+				if endpoint.multiQueue != flags.MultiQueue {
+					// See drivers/net/tun.c:tun_set_iff().
+					return nil, linuxerr.EINVAL
+				}
 				if !endpoint.TryIncRef() {
 					// Race detected: NIC got deleted in between.
 					continue
@@ -161,13 +284,16 @@ func attachOrCreateNIC(ctx context.Context, s *stack.Stack, name, prefix string,
 		// 2. Creating a new NIC.
 		id := s.NextNICID()
 		endpoint := &tunEndpoint{
-			Endpoint: channel.New(defaultDevOutQueueLen, defaultDevMtu, ""),
-			stack:    s,
-			nicID:    id,
-			name:     name,
-			isTap:    prefix == "tap",
+			Endpoint:   channel.New(defaultDevOutQueueLen, defaultDevMtu, ""),
+			stack:      s,
+			nicID:      id,
+			name:       name,
+			isTap:      prefix == "tap",
+			multiQueue: flags.MultiQueue,
+			seed:       rand.Uint32(),
 		}
 		endpoint.InitRefs()
+		endpoint.vnetHdrSize.Store(int32(linux.SizeOfVirtioNetHdr))
 		endpoint.Endpoint.LinkEPCapabilities = linkCaps
 		if endpoint.name == "" {
 			endpoint.name = fmt.Sprintf("%s%d", prefix, id)
@@ -206,12 +332,31 @@ func (d *Device) MTU() (uint32, error) {
 	return endpoint.MTU(), nil
 }
 
+// MaxWriteSize returns the size of the largest write to d. Packets written
+// with a virtio-net header may be GSO packets larger than the MTU.
+func (d *Device) MaxWriteSize() (int64, error) {
+	d.mu.RLock()
+	endpoint := d.endpoint
+	d.mu.RUnlock()
+	if endpoint == nil {
+		return 0, linuxerr.EBADFD
+	}
+	if !endpoint.IsAttached() {
+		return 0, linuxerr.EIO
+	}
+	if !d.flags.VnetHdr {
+		return int64(endpoint.MTU()), nil
+	}
+	return PacketInfoHeaderSize + int64(endpoint.vnetHdrSize.Load()) + header.EthernetMinimumSize + math.MaxUint16, nil
+}
+
 // Write inject one inbound packet to the network interface.
 func (d *Device) Write(data *buffer.View) (int64, error) {
 	d.mu.RLock()
 	endpoint := d.endpoint
+	detached := d.detached
 	d.mu.RUnlock()
-	if endpoint == nil {
+	if endpoint == nil || detached {
 		return 0, linuxerr.EBADFD
 	}
 	if !endpoint.IsAttached() {
@@ -234,6 +379,27 @@ func (d *Device) Write(data *buffer.View) (int64, error) {
 		data.TrimFront(PacketInfoHeaderSize)
 	}
 
+	// Virtio-net header.
+	var vnetHdr linux.VirtioNetHdr
+	var gso stack.GSO
+	if d.flags.VnetHdr {
+		vnetHdrSize := int(endpoint.vnetHdrSize.Load())
+		if data.Size() < vnetHdrSize {
+			return 0, linuxerr.EINVAL
+		}
+		vnetHdr.UnmarshalUnsafe(data.AsSlice())
+		data.TrimFront(vnetHdrSize)
+
+		linkHdrLen := 0
+		if d.flags.TAP {
+			linkHdrLen = header.EthernetMinimumSize
+		}
+		var err error
+		if gso, err = packetGSOFromVnetHdr(&vnetHdr, data.AsSlice(), linkHdrLen); err != nil {
+			return 0, err
+		}
+	}
+
 	// Ethernet header (TAP only).
 	var ethHdr header.Ethernet
 	if d.flags.TAP {
@@ -255,7 +421,7 @@ func (d *Device) Write(data *buffer.View) (int64, error) {
 		protocol = pktInfoHdr.Protocol()
 	case ethHdr != nil:
 		protocol = ethHdr.Type()
-	case d.flags.TUN:
+	case d.flags.TUN && data.Size() > 0:
 		// TUN interface with IFF_NO_PI enabled, thus
 		// we need to determine protocol from version field
 		version := data.AsSlice()[0] >> 4
@@ -272,6 +438,8 @@ func (d *Device) Write(data *buffer.View) (int64, error) {
 	})
 	defer pkt.DecRef()
 	copy(pkt.LinkHeader().Push(len(ethHdr)), ethHdr)
+	pkt.GSOOptions = gso
+	pkt.RXChecksumValidated = vnetHdr.Flags&linux.VIRTIO_NET_HDR_F_DATA_VALID != 0
 	endpoint.InjectInbound(protocol, pkt)
 	return dataLen, nil
 }
@@ -280,39 +448,78 @@ func (d *Device) Write(data *buffer.View) (int64, error) {
 func (d *Device) Read() (*buffer.View, error) {
 	d.mu.RLock()
 	endpoint := d.endpoint
+	queue := d.queue
+	detached := d.detached
 	d.mu.RUnlock()
-	if endpoint == nil {
+	if endpoint == nil || detached {
 		return nil, linuxerr.EBADFD
 	}
 
-	pkt := endpoint.Read()
-	if pkt == nil {
-		return nil, linuxerr.ErrWouldBlock
+	for {
+		pkt := queue.Read()
+		if pkt == nil {
+			return nil, linuxerr.ErrWouldBlock
+		}
+		v := d.encodePkt(endpoint, pkt)
+		pkt.DecRef()
+		if v != nil {
+			return v, nil
+		}
 	}
-	v := d.encodePkt(pkt)
-	pkt.DecRef()
-	return v, nil
 }
 
-// encodePkt encodes packet for fd side.
-func (d *Device) encodePkt(pkt *stack.PacketBuffer) *buffer.View {
-	var view *buffer.View
+// encodePkt encodes packet for fd side. It returns nil if the packet is
+// dropped because it requires an offload that the fd side can't handle.
+func (d *Device) encodePkt(endpoint *tunEndpoint, pkt *stack.PacketBuffer) *buffer.View {
+	pktView := pkt.ToView()
+
+	// Offloads.
+	var vnetHdr linux.VirtioNetHdr
+	if pkt.GSOOptions.Type != stack.GSONone {
+		var offloads uint32
+		if d.flags.VnetHdr {
+			offloads = endpoint.offloads.Load()
+		}
+		vnetHdr = vnetHdrFromPacket(pkt)
+		if !applyOffloads(&vnetHdr, pktView.AsSlice(), offloads) {
+			pktView.Release()
+			return nil
+		}
+	}
+
+	hdrLen := 0
+	if !d.flags.NoPacketInfo {
+		hdrLen += PacketInfoHeaderSize
+	}
+	vnetHdrSize := 0
+	if d.flags.VnetHdr {
+		vnetHdrSize = int(endpoint.vnetHdrSize.Load())
+		hdrLen += vnetHdrSize
+	}
+	if hdrLen == 0 {
+		return pktView
+	}
+	defer pktView.Release()
+
+	view := buffer.NewView(hdrLen + pktView.Size())
+	view.Grow(hdrLen)
+	hdr := view.AsSlice()
+	clear(hdr)
 
 	// Packet information.
 	if !d.flags.NoPacketInfo {
-		view = buffer.NewView(PacketInfoHeaderSize + pkt.Size())
-		view.Grow(PacketInfoHeaderSize)
-		hdr := PacketInfoHeader(view.AsSlice())
-		hdr.Encode(&PacketInfoFields{
+		PacketInfoHeader(hdr).Encode(&PacketInfoFields{
 			Protocol: pkt.NetworkProtocolNumber,
 		})
-		pktView := pkt.ToView()
-		view.Write(pktView.AsSlice())
-		pktView.Release()
-	} else {
-		view = pkt.ToView()
+		hdr = hdr[PacketInfoHeaderSize:]
 	}
 
+	// Virtio-net header. Bytes beyond struct virtio_net_hdr are zero.
+	if vnetHdrSize != 0 {
+		vnetHdr.MarshalUnsafe(hdr)
+	}
+
+	view.Write(pktView.AsSlice())
 	return view
 }
 
@@ -338,9 +545,9 @@ func (d *Device) Flags() Flags {
 func (d *Device) Readiness(mask waiter.EventMask) waiter.EventMask {
 	if mask&waiter.ReadableEvents != 0 {
 		d.mu.RLock()
-		endpoint := d.endpoint
+		queue := d.queue
 		d.mu.RUnlock()
-		if endpoint != nil && endpoint.NumQueued() == 0 {
+		if queue != nil && queue.NumQueued() == 0 {
 			mask &= ^waiter.ReadableEvents
 		}
 	}
@@ -369,6 +576,31 @@ type tunEndpoint struct {
 	persistent atomicbitops.Bool
 	closed     atomicbitops.Bool
 
+	// multiQueue is true if the NIC has a queue for each attached device,
+	// rather than a single queue shared by all of them. multiQueue is
+	// immutable.
+	multiQueue bool
+
+	// seed is the seed of the flow hash used to select the queue of an
+	// outbound packet. seed is immutable.
+	seed uint32
+
+	// queuesMu protects queues.
+	queuesMu queuesRWMutex `state:"nosave"`
+
+	// queues are the attached queues of a multi-queue NIC. queues is
+	// replaced, rather than modified, when a queue is attached or detached.
+	//
+	// +checklocks:queuesMu
+	queues []*channel.Endpoint
+
+	// vnetHdrSize is the size of the virtio-net header preceding packets
+	// read from and written to devices with Flags.VnetHdr.
+	vnetHdrSize atomicbitops.Int32
+
+	// offloads is the mask of linux.TUN_F_* offloads set by TUNSETOFFLOAD.
+	offloads atomicbitops.Uint32
+
 	mu            endpointMutex `state:"nosave"`
 	onCloseAction func()        `state:"nosave"`
 }
@@ -0,0 +1,133 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tun
+
+import (
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/hash/jenkins"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/channel"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// maxQueues is the maximum number of queues attached to a multi-queue NIC.
+// drivers/net/tun.c:MAX_TAP_QUEUES.
+const maxQueues = 256
+
+// attachQueue attaches q to the multi-queue NIC.
+func (e *tunEndpoint) attachQueue(q *channel.Endpoint) error {
+	e.queuesMu.Lock()
+	defer e.queuesMu.Unlock()
+	if len(e.queues) >= maxQueues {
+		return linuxerr.E2BIG
+	}
+	queues := make([]*channel.Endpoint, len(e.queues), len(e.queues)+1)
+	copy(queues, e.queues)
+	e.queues = append(queues, q)
+	return nil
+}
+
+// detachQueue detaches q from the multi-queue NIC.
+func (e *tunEndpoint) detachQueue(q *channel.Endpoint) {
+	e.queuesMu.Lock()
+	defer e.queuesMu.Unlock()
+	queues := make([]*channel.Endpoint, 0, len(e.queues))
+	for _, other := range e.queues {
+		if other != q {
+			queues = append(queues, other)
+		}
+	}
+	e.queues = queues
+}
+
+// WritePackets implements stack.LinkEndpoint.WritePackets. The packets of a
+// multi-queue NIC are distributed among its queues by flow hash, so that the
+// packets of a connection are read from the same queue.
+func (e *tunEndpoint) WritePackets(pkts stack.PacketBufferList) (int, tcpip.Error) {
+	if !e.multiQueue {
+		return e.Endpoint.WritePackets(pkts)
+	}
+
+	e.queuesMu.RLock()
+	queues := e.queues
+	e.queuesMu.RUnlock()
+	if len(queues) == 0 {
+		// Linux drops packets while all queues are detached.
+		return pkts.Len(), nil
+	}
+
+	n := 0
+	for _, pkt := range pkts.AsSlice() {
+		q := queues[e.flowHash(pkt)%uint32(len(queues))]
+		var pl stack.PacketBufferList
+		pl.PushBack(pkt)
+		written, err := q.WritePackets(pl)
+		if err != nil {
+			if n == 0 {
+				return 0, err
+			}
+			break
+		}
+		if written == 0 {
+			// The queue is full.
+			break
+		}
+		n++
+	}
+	return n, nil
+}
+
+// flowHash returns the hash of the flow of the outbound packet pkt, computed
+// from its network addresses and, for TCP and UDP, its ports.
+func (e *tunEndpoint) flowHash(pkt *stack.PacketBuffer) uint32 {
+	h := jenkins.Sum32(e.seed)
+	switch pkt.NetworkProtocolNumber {
+	case header.IPv4ProtocolNumber:
+		ipv4 := header.IPv4(pkt.NetworkHeader().Slice())
+		if len(ipv4) < header.IPv4MinimumSize {
+			return 0
+		}
+		h.Write(ipv4.SourceAddressSlice())
+		h.Write(ipv4.DestinationAddressSlice())
+	case header.IPv6ProtocolNumber:
+		ipv6 := header.IPv6(pkt.NetworkHeader().Slice())
+		if len(ipv6) < header.IPv6MinimumSize {
+			return 0
+		}
+		h.Write(ipv6.SourceAddressSlice())
+		h.Write(ipv6.DestinationAddressSlice())
+	default:
+		// Packets such as ARP don't belong to a flow.
+		return 0
+	}
+	switch pkt.TransportProtocolNumber {
+	case header.TCPProtocolNumber, header.UDPProtocolNumber:
+		// Both headers start with the source and destination ports.
+		if ports := pkt.TransportHeader().Slice(); len(ports) >= 4 {
+			h.Write(ports[:4])
+		}
+	}
+	return h.Sum32()
+}
+
+// SupportedGSO implements stack.GSOEndpoint.SupportedGSO. The stack sends TCP
+// GSO packets once the segmentation offloads are enabled by TUNSETOFFLOAD.
+func (e *tunEndpoint) SupportedGSO() stack.SupportedGSO {
+	if e.offloads.Load()&gsoOffloads == gsoOffloads {
+		return stack.HostGSOSupported
+	}
+	return stack.GSONotSupported
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tun
+
+import (
+	"encoding/binary"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/tcpip/checksum"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// supportedOffloads are the offloads accepted by TUNSETOFFLOAD. See
+// drivers/net/tun.c:set_offload().
+const supportedOffloads = linux.TUN_F_CSUM | linux.TUN_F_TSO4 | linux.TUN_F_TSO6 | linux.TUN_F_TSO_ECN | linux.TUN_F_UFO | linux.TUN_F_USO4 | linux.TUN_F_USO6
+
+// gsoOffloads are the offloads that must all be enabled for the stack to send
+// TCP GSO packets through the NIC. The stack chooses the GSO type of a
+// connection based on its network protocol only, so both TSO4 and TSO6 are
+// required.
+const gsoOffloads = linux.TUN_F_CSUM | linux.TUN_F_TSO4 | linux.TUN_F_TSO6
+
+// vnetHdrFromPacket returns the virtio-net header describing the checksum and
+// segmentation offloads requested for the outbound packet pkt.
+//
+// Preconditions: pkt.GSOOptions.Type is not stack.GSOGvisor.
+func vnetHdrFromPacket(pkt *stack.PacketBuffer) linux.VirtioNetHdr {
+	var hdr linux.VirtioNetHdr
+	if pkt.GSOOptions.Type == stack.GSONone {
+		return hdr
+	}
+	if pkt.GSOOptions.NeedsCsum {
+		hdr.Flags = linux.VIRTIO_NET_HDR_F_NEEDS_CSUM
+		hdr.CsumStart = uint16(len(pkt.LinkHeader().Slice()) + len(pkt.NetworkHeader().Slice()))
+		hdr.CsumOffset = pkt.GSOOptions.CsumOffset
+	}
+	if pkt.Data().Size() > int(pkt.GSOOptions.MSS) {
+		switch pkt.GSOOptions.Type {
+		case stack.GSOTCPv4:
+			hdr.GSOType = linux.VIRTIO_NET_HDR_GSO_TCPV4
+		case stack.GSOTCPv6:
+			hdr.GSOType = linux.VIRTIO_NET_HDR_GSO_TCPV6
+		}
+		hdr.HdrLen = uint16(pkt.HeaderSize())
+		hdr.GSOSize = pkt.GSOOptions.MSS
+	}
+	return hdr
+}
+
+// packetGSOFromVnetHdr returns the GSO options of an inbound packet with the
+// virtio-net header hdr. frame is the packet, starting with the link header,
+// if any, of length linkHdrLen.
+//
+// If hdr requests a checksum, packetGSOFromVnetHdr completes it in frame, so
+// the returned options never require one.
+func packetGSOFromVnetHdr(hdr *linux.VirtioNetHdr, frame []byte, linkHdrLen int) (stack.GSO, error) {
+	var gso stack.GSO
+	if hdr.Flags&linux.VIRTIO_NET_HDR_F_NEEDS_CSUM != 0 {
+		start := int(hdr.CsumStart)
+		off := start + int(hdr.CsumOffset)
+		if start < linkHdrLen || off+2 > len(frame) {
+			return gso, linuxerr.EINVAL
+		}
+		finishChecksum(frame, start, off)
+		gso.CsumOffset = hdr.CsumOffset
+		gso.L3HdrLen = uint16(start - linkHdrLen)
+	}
+
+	switch hdr.GSOType &^ linux.VIRTIO_NET_HDR_GSO_ECN {
+	case linux.VIRTIO_NET_HDR_GSO_NONE:
+		return gso, nil
+	case linux.VIRTIO_NET_HDR_GSO_TCPV4:
+		gso.Type = stack.GSOTCPv4
+	case linux.VIRTIO_NET_HDR_GSO_TCPV6:
+		gso.Type = stack.GSOTCPv6
+	default:
+		return gso, linuxerr.EINVAL
+	}
+	// The transport header is located by the checksum offload, so
+	// segmentation offload requires it.
+	if hdr.Flags&linux.VIRTIO_NET_HDR_F_NEEDS_CSUM == 0 || hdr.GSOSize == 0 {
+		return stack.GSO{}, linuxerr.EINVAL
+	}
+	gso.MSS = hdr.GSOSize
+	return gso, nil
+}
+
+// applyOffloads adjusts hdr, describing the outbound packet frame, to the
+// offloads enabled by TUNSETOFFLOAD. Checksums that can't be offloaded are
+// completed in frame. applyOffloads returns false if the packet requires a
+// segmentation offload that isn't enabled.
+func applyOffloads(hdr *linux.VirtioNetHdr, frame []byte, offloads uint32) bool {
+	switch hdr.GSOType {
+	case linux.VIRTIO_NET_HDR_GSO_TCPV4:
+		if offloads&linux.TUN_F_TSO4 == 0 {
+			return false
+		}
+	case linux.VIRTIO_NET_HDR_GSO_TCPV6:
+		if offloads&linux.TUN_F_TSO6 == 0 {
+			return false
+		}
+	}
+	if hdr.Flags&linux.VIRTIO_NET_HDR_F_NEEDS_CSUM != 0 && offloads&linux.TUN_F_CSUM == 0 {
+		start := int(hdr.CsumStart)
+		off := start + int(hdr.CsumOffset)
+		if off+2 <= len(frame) {
+			finishChecksum(frame, start, off)
+		}
+		hdr.Flags &^= linux.VIRTIO_NET_HDR_F_NEEDS_CSUM
+		hdr.CsumStart = 0
+		hdr.CsumOffset = 0
+	}
+	return true
+}
+
+// finishChecksum completes the partial checksum of frame. The checksum covers
+// frame[start:] and is stored at frame[off:]. As for CHECKSUM_PARTIAL in
+// Linux, the checksum field holds the checksum of the pseudo-header.
+//
+// Preconditions: start <= off && off+2 <= len(frame).
+func finishChecksum(frame []byte, start, off int) {
+	xsum := checksum.Checksum(frame[start:], 0)
+	binary.BigEndian.PutUint16(frame[off:], ^xsum)
+}
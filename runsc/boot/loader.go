@@ -1214,7 +1214,7 @@ func (l *Loader) createContainerProcess(info *containerInfo) (*kernel.ThreadGrou
 	}
 	l.startGoferMonitor(info)
 
-	if l.root.cid == l.sandboxID {
+	if l.root.cid == l.sandboxID && !usesCgroupV2(info.spec) {
 		// Mounts cgroups for all the controllers.
 		if err := l.mountCgroupMounts(info.conf, info.procArgs.Credentials); err != nil {
 			return nil, nil, err
@@ -1250,7 +1250,7 @@ func (l *Loader) createContainerProcess(info *containerInfo) (*kernel.ThreadGrou
 	// Install seccomp filters with the new task if there are any.
 	if info.conf.OCISeccomp {
 		if info.spec.Linux != nil && info.spec.Linux.Seccomp != nil {
-			program, err := seccomp.BuildProgram(info.spec.Linux.Seccomp)
+			program, source, err := seccomp.BuildProgram(info.spec.Linux.Seccomp)
 			if err != nil {
 				return nil, nil, fmt.Errorf("building seccomp program: %w", err)
 			}
@@ -1262,7 +1262,7 @@ func (l *Loader) createContainerProcess(info *containerInfo) (*kernel.ThreadGrou
 
 			task := tg.Leader()
 			// NOTE: It seems Flags are ignored by runc so we ignore them too.
-			if err := task.AppendSyscallFilter(program, true); err != nil {
+			if err := task.AppendSyscallFilter(program, source, true); err != nil {
 				return nil, nil, fmt.Errorf("appending seccomp filters: %w", err)
 			}
 		}
@@ -35,6 +35,7 @@ import (
 	"gvisor.dev/gvisor/pkg/fd"
 	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/devices/loopdev"
 	"gvisor.dev/gvisor/pkg/sentry/devices/memdev"
 	"gvisor.dev/gvisor/pkg/sentry/devices/nvproxy"
 	"gvisor.dev/gvisor/pkg/sentry/devices/nvproxy/nvconf"
@@ -42,6 +43,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/devices/tpuproxy/vfio"
 	"gvisor.dev/gvisor/pkg/sentry/devices/ttydev"
 	"gvisor.dev/gvisor/pkg/sentry/devices/tundev"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/binfmtmisc"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/cgroupfs"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/dev"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/devpts"
@@ -94,10 +96,18 @@ func registerFilesystems(k *kernel.Kernel, info *containerInfo) error {
 	ctx := k.SupervisorContext()
 	vfsObj := k.VFS()
 
+	vfsObj.MustRegisterFilesystemType(binfmtmisc.Name, &binfmtmisc.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
+		AllowUserMount: true,
+		AllowUserList:  true,
+	})
 	vfsObj.MustRegisterFilesystemType(cgroupfs.Name, &cgroupfs.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
 		AllowUserMount: true,
 		AllowUserList:  true,
 	})
+	vfsObj.MustRegisterFilesystemType(cgroupfs.V2Name, &cgroupfs.V2FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
+		AllowUserMount: true,
+		AllowUserList:  true,
+	})
 	vfsObj.MustRegisterFilesystemType(devpts.Name, &devpts.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
 		AllowUserList:  true,
 		AllowUserMount: true,
@@ -108,7 +118,10 @@ func registerFilesystems(k *kernel.Kernel, info *containerInfo) error {
 		AllowUserList:  true,
 	})
 	vfsObj.MustRegisterFilesystemType(erofs.Name, &erofs.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
-		AllowUserList: true,
+		// Applications can only mount EROFS images stored on loop devices.
+		AllowUserMount: info.conf.LoopDevices,
+		AllowUserList:  true,
+		RequiresDevice: true,
 	})
 	vfsObj.MustRegisterFilesystemType(fuse.Name, &fuse.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
 		AllowUserMount: true,
@@ -154,6 +167,11 @@ func registerFilesystems(k *kernel.Kernel, info *containerInfo) error {
 	if err := fuse.Register(vfsObj); err != nil {
 		return fmt.Errorf("registering fusedev: %w", err)
 	}
+	if info.conf.LoopDevices {
+		if err := loopdev.Register(vfsObj); err != nil {
+			return fmt.Errorf("registering loopdev: %w", err)
+		}
+	}
 
 	if err := nvproxyRegisterDevices(info, vfsObj, k.NvidiaDriverVersion); err != nil {
 		return err
@@ -977,6 +995,13 @@ func getMountNameAndOptions(spec *specs.Spec, conf *config.Config, m *mountInfo,
 			return "", nil, err
 		}
 
+	case cgroupfs.V2Name:
+		var err error
+		mopts, data, err = consumeMountOptions(mopts, cgroupfs.SupportedV2MountOptions...)
+		if err != nil {
+			return "", nil, err
+		}
+
 	default:
 		log.Warningf("ignoring unknown filesystem type %q", m.mount.Type)
 		return "", nil, nil
@@ -1138,6 +1163,18 @@ func (c *containerMounter) getSharedMount(ctx context.Context, spec *specs.Spec,
 	return sharedMount, nil
 }
 
+// usesCgroupV2 returns true if spec mounts the cgroup v2 unified hierarchy at
+// /sys/fs/cgroup. In that case, the shared v1 cgroup mounts aren't created, so
+// that all v2-capable controllers are bound to the unified hierarchy.
+func usesCgroupV2(spec *specs.Spec) bool {
+	for _, m := range spec.Mounts {
+		if m.Type == cgroupfs.V2Name && filepath.Clean(m.Destination) == "/sys/fs/cgroup" {
+			return true
+		}
+	}
+	return false
+}
+
 // mountCgroupMounts mounts the cgroups which are shared across all containers.
 // Postcondition: Initialized k.cgroupMounts on success.
 func (l *Loader) mountCgroupMounts(conf *config.Config, creds *auth.Credentials) error {
@@ -0,0 +1,118 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/pkg/erofs"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// Mkerofs implements subcommands.Command for the "mkerofs" command.
+type Mkerofs struct{}
+
+// Name implements subcommands.Command.
+func (*Mkerofs) Name() string {
+	return "mkerofs"
+}
+
+// Synopsis implements subcommands.Command.
+func (*Mkerofs) Synopsis() string {
+	return "builds an EROFS image from directories and OCI image layers"
+}
+
+// Usage implements subcommands.Command.
+func (*Mkerofs) Usage() string {
+	return `mkerofs <image> <source>...
+
+Builds an EROFS image which can be used as the root filesystem with the
+"dev.gvisor.spec.rootfs.type=erofs" annotation. Each source is either a host
+directory or an OCI image layer (an optionally gzip-compressed tar file), and
+sources are merged in the given order. Whiteouts in the layers remove the files
+added by the previous sources.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (*Mkerofs) SetFlags(*flag.FlagSet) {}
+
+// Execute implements subcommands.Command.Execute.
+func (*Mkerofs) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() < 2 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+
+	b := erofs.NewBuilder()
+	defer b.Close()
+	for _, src := range f.Args()[1:] {
+		if err := addErofsSource(b, src); err != nil {
+			util.Fatalf("adding %q: %v", src, err)
+		}
+	}
+
+	output := f.Arg(0)
+	out, err := os.OpenFile(output, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+	if err != nil {
+		util.Fatalf("error opening output: %v", err)
+	}
+	if err := b.Build(out); err != nil {
+		out.Close()
+		os.Remove(output)
+		util.Fatalf("error building image: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		util.Fatalf("error closing output: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
+
+// addErofsSource merges the directory or image layer at path src into the
+// image being built by b.
+func addErofsSource(b *erofs.Builder, src string) error {
+	st, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if st.IsDir() {
+		return b.AddDir(src)
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var layer io.Reader = r
+	if magic, err := r.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("invalid gzip stream: %w", err)
+		}
+		defer zr.Close()
+		layer = zr
+	}
+	return b.AddLayer(layer)
+}
@@ -316,6 +316,10 @@ type Config struct {
 	// exists, but is mostly idle. Not supported in rootless mode.
 	DirectFS bool `flag:"directfs"`
 
+	// LoopDevices exposes loop devices to the sandbox, and allows applications
+	// to mount EROFS images stored on them.
+	LoopDevices bool `flag:"loop-devices"`
+
 	// AppHugePages enables support for application huge pages.
 	AppHugePages bool `flag:"app-huge-pages"`
 
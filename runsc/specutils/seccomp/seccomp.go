@@ -40,15 +40,16 @@ var (
 )
 
 // BuildProgram generates a bpf program based on the given OCI seccomp
-// config.
-func BuildProgram(s *specs.LinuxSeccomp) (bpf.Program, error) {
+// config. It also returns the instructions that the program was compiled from,
+// which are reported by PTRACE_SECCOMP_GET_FILTER.
+func BuildProgram(s *specs.LinuxSeccomp) (bpf.Program, []linux.BPFInstruction, error) {
 	defaultAction, err := convertAction(s.DefaultAction)
 	if err != nil {
-		return bpf.Program{}, fmt.Errorf("secomp default action: %w", err)
+		return bpf.Program{}, nil, fmt.Errorf("secomp default action: %w", err)
 	}
 	ruleset, err := convertRules(s)
 	if err != nil {
-		return bpf.Program{}, fmt.Errorf("invalid seccomp rules: %w", err)
+		return bpf.Program{}, nil, fmt.Errorf("invalid seccomp rules: %w", err)
 	}
 
 	instrs, _, err := seccomp.BuildProgram(ruleset, seccomp.ProgramOptions{
@@ -56,15 +57,19 @@ func BuildProgram(s *specs.LinuxSeccomp) (bpf.Program, error) {
 		BadArchAction: killThreadAction,
 	})
 	if err != nil {
-		return bpf.Program{}, fmt.Errorf("building seccomp program: %w", err)
+		return bpf.Program{}, nil, fmt.Errorf("building seccomp program: %w", err)
 	}
 
 	program, err := bpf.Compile(instrs, true /* optimize */)
 	if err != nil {
-		return bpf.Program{}, fmt.Errorf("compiling seccomp program: %w", err)
+		return bpf.Program{}, nil, fmt.Errorf("compiling seccomp program: %w", err)
 	}
 
-	return program, nil
+	source := make([]linux.BPFInstruction, len(instrs))
+	for i, ins := range instrs {
+		source[i] = linux.BPFInstruction(ins)
+	}
+	return program, source, nil
 }
 
 // lookupSyscallNo gets the syscall number for the syscall with the given name
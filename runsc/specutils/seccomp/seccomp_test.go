@@ -375,7 +375,7 @@ var (
 func TestRunscSeccomp(t *testing.T) {
 	for _, tc := range seccompTests {
 		t.Run(tc.name, func(t *testing.T) {
-			runscProgram, err := BuildProgram(&tc.config)
+			runscProgram, _, err := BuildProgram(&tc.config)
 			if err != nil {
 				t.Fatalf("generating runsc BPF: %v", err)
 			}
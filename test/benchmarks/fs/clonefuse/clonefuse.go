@@ -0,0 +1,234 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Binary clonefuse starts a FUSE server that serves a single file of zeros,
+// named "file", through several /dev/fuse file descriptors cloned with
+// FUSE_DEV_IOC_CLONE. Reads are answered after a fixed latency, emulating a
+// server backed by slow storage, so that the throughput of the server is
+// bound by the number of requests it processes in parallel.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/marshal"
+)
+
+var (
+	dir           = flag.String("dir", "/tmp", "The directory to mount the fuse filesystem on.")
+	readers       = flag.Int("readers", 1, "The number of threads reading requests, each from its own /dev/fuse file descriptor.")
+	sizeMB        = flag.Int("size", 1024, "The size of the served file in megabytes.")
+	latency       = flag.Duration("latency", time.Millisecond, "The time taken to answer a read request.")
+	maxBackground = flag.Int("max_background", 0, "The max_background mount option, unset if zero.")
+)
+
+const (
+	// fileName is the name of the served file.
+	fileName = "file"
+
+	// rootID and fileID are the node IDs of the root directory and the served
+	// file.
+	rootID = 1
+	fileID = 2
+
+	// maxWrite is the maximum size of a write request.
+	maxWrite = 1 << 20
+
+	// bufSize is the size of the buffers requests are read into. It must fit
+	// the largest write request.
+	bufSize = maxWrite + 4096
+)
+
+// attr returns the attributes of the node with the given ID.
+func attr(nodeID uint64) linux.FUSEAttr {
+	if nodeID == fileID {
+		return linux.FUSEAttr{
+			Ino:     fileID,
+			Size:    uint64(*sizeMB) << 20,
+			Mode:    uint32(linux.ModeRegular | 0444),
+			Nlink:   1,
+			BlkSize: 4096,
+		}
+	}
+	return linux.FUSEAttr{
+		Ino:     rootID,
+		Mode:    uint32(linux.ModeDirectory | 0755),
+		Nlink:   2,
+		BlkSize: 4096,
+	}
+}
+
+// handle processes the request with the given header and payload, and returns
+// the payload of the reply and its error.
+func handle(hdr *linux.FUSEHeaderIn, payload []byte) ([]byte, unix.Errno) {
+	switch hdr.Opcode {
+	case linux.FUSE_INIT:
+		var in linux.FUSEInitIn
+		in.UnmarshalBytes(payload)
+		out := linux.FUSEInitOut{
+			Major:        linux.FUSE_KERNEL_VERSION,
+			Minor:        in.Minor,
+			MaxReadahead: in.MaxReadahead,
+			MaxWrite:     maxWrite,
+			MaxPages:     maxWrite / 4096,
+			Flags:        linux.FUSE_MAX_PAGES,
+		}
+		if *maxBackground != 0 {
+			out.MaxBackground = uint16(*maxBackground)
+			out.CongestionThreshold = uint16(*maxBackground * 3 / 4)
+		}
+		return marshalOut(&out), 0
+	case linux.FUSE_LOOKUP:
+		if hdr.NodeID != rootID || string(payload) != fileName+"\x00" {
+			return nil, unix.ENOENT
+		}
+		out := linux.FUSEEntryOut{
+			NodeID:     fileID,
+			EntryValid: 3600,
+			AttrValid:  3600,
+			Attr:       attr(fileID),
+		}
+		return marshalOut(&out), 0
+	case linux.FUSE_GETATTR:
+		out := linux.FUSEAttrOut{
+			AttrValid: 3600,
+			Attr:      attr(hdr.NodeID),
+		}
+		return marshalOut(&out), 0
+	case linux.FUSE_OPEN:
+		// Bypass the page cache, so that every read reaches the server.
+		out := linux.FUSEOpenOut{OpenFlag: linux.FOPEN_DIRECT_IO}
+		return marshalOut(&out), 0
+	case linux.FUSE_OPENDIR:
+		var out linux.FUSEOpenOut
+		return marshalOut(&out), 0
+	case linux.FUSE_READ:
+		var in linux.FUSEReadIn
+		in.UnmarshalBytes(payload)
+		time.Sleep(*latency)
+		size := uint64(*sizeMB) << 20
+		if in.Offset >= size {
+			return nil, 0
+		}
+		return make([]byte, min(uint64(in.Size), size-in.Offset)), 0
+	case linux.FUSE_STATFS:
+		out := linux.FUSEStatfsOut{
+			BlockSize:    4096,
+			NameLength:   255,
+			FragmentSize: 4096,
+		}
+		return marshalOut(&out), 0
+	case linux.FUSE_READDIR, linux.FUSE_RELEASE, linux.FUSE_RELEASEDIR, linux.FUSE_FLUSH, linux.FUSE_ACCESS:
+		return nil, 0
+	default:
+		return nil, unix.ENOSYS
+	}
+}
+
+// marshalOut returns the bytes of the reply payload m.
+func marshalOut(m marshal.Marshallable) []byte {
+	buf := make([]byte, m.SizeBytes())
+	m.MarshalBytes(buf)
+	return buf
+}
+
+// serve reads requests from the /dev/fuse file descriptor fd, and replies to
+// them, until the filesystem is unmounted.
+func serve(fd int) {
+	buf := make([]byte, bufSize)
+	for {
+		n, err := unix.Read(fd, buf)
+		switch err {
+		case nil:
+		case unix.EINTR, unix.EAGAIN:
+			continue
+		case unix.ENODEV, unix.EPERM:
+			// The filesystem was unmounted.
+			os.Exit(0)
+		default:
+			log.Warningf("reading request failed: %v", err)
+			os.Exit(1)
+		}
+
+		var hdr linux.FUSEHeaderIn
+		hdr.UnmarshalBytes(buf[:n])
+		out, errno := handle(&hdr, buf[hdr.SizeBytes():n])
+		switch hdr.Opcode {
+		case linux.FUSE_FORGET, linux.FUSE_BATCH_FORGET, linux.FUSE_INTERRUPT:
+			// These requests don't have replies.
+			continue
+		}
+
+		outHdr := linux.FUSEHeaderOut{
+			Len:    linux.SizeOfFUSEHeaderOut + uint32(len(out)),
+			Error:  -int32(errno),
+			Unique: hdr.Unique,
+		}
+		reply := make([]byte, outHdr.Len)
+		copy(outHdr.MarshalBytes(reply), out)
+		// Replies to interrupted requests may be rejected.
+		if _, err := unix.Write(fd, reply); err != nil && err != unix.ENOENT && err != unix.EINVAL {
+			log.Warningf("writing reply failed: %v", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *readers < 1 {
+		log.Warningf("--readers must be at least 1")
+		os.Exit(1)
+	}
+
+	fd, err := unix.Open("/dev/fuse", unix.O_RDWR, 0)
+	if err != nil {
+		log.Warningf("could not open /dev/fuse: %v", err)
+		os.Exit(1)
+	}
+	opts := fmt.Sprintf("fd=%d,rootmode=40000,user_id=0,group_id=0,allow_other", fd)
+	if *maxBackground != 0 {
+		opts += fmt.Sprintf(",max_background=%d", *maxBackground)
+	}
+	if err := unix.Mount("clonefuse", *dir, "fuse", 0, opts); err != nil {
+		log.Warningf("could not mount fuse filesystem on %q: %v", *dir, err)
+		os.Exit(1)
+	}
+
+	fds := []int{fd}
+	for i := 1; i < *readers; i++ {
+		clone, err := unix.Open("/dev/fuse", unix.O_RDWR, 0)
+		if err != nil {
+			log.Warningf("could not open /dev/fuse: %v", err)
+			os.Exit(1)
+		}
+		oldFD := uint32(fd)
+		if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(clone), linux.FUSE_DEV_IOC_CLONE, uintptr(unsafe.Pointer(&oldFD))); errno != 0 {
+			log.Warningf("FUSE_DEV_IOC_CLONE failed: %v", errno)
+			os.Exit(1)
+		}
+		fds = append(fds, clone)
+	}
+	for _, fd := range fds[1:] {
+		go serve(fd)
+	}
+	serve(fds[0])
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package fuseclone_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/test/dockerutil"
+	"gvisor.dev/gvisor/test/benchmarks/harness"
+	"gvisor.dev/gvisor/test/benchmarks/tools"
+)
+
+const (
+	// mountDir is where the FUSE filesystem is mounted in the container.
+	mountDir = "/fuse"
+
+	// mountTimeout is how long to wait for the FUSE filesystem to be
+	// mounted.
+	mountTimeout = 10 * time.Second
+)
+
+// BenchmarkFuseClone runs parallel fio reads of a file served by a FUSE
+// server whose reads take a fixed time. The server processes requests with an
+// increasing number of threads, each reading requests from its own /dev/fuse
+// file descriptor cloned with FUSE_DEV_IOC_CLONE, so that the throughput
+// scales with the number of threads.
+func BenchmarkFuseClone(b *testing.B) {
+	machine, err := harness.GetMachine()
+	if err != nil {
+		b.Fatalf("failed to get machine with: %v", err)
+	}
+	defer machine.CleanUp()
+
+	for _, readers := range []int{1, 2, 4, 8} {
+		tc := tools.Fio{
+			Test:        "read",
+			IOEngine:    tools.EngineSync,
+			Jobs:        8,
+			BlockSizeKB: 64,
+			IODepth:     1,
+		}
+		readersParam := tools.Parameter{
+			Name:  "readers",
+			Value: strconv.Itoa(readers),
+		}
+		_, name := tc.Parameters(b, readersParam)
+		b.Run(name, func(b *testing.B) {
+			b.StopTimer()
+			tc.SizeMB = b.N
+
+			ctx := context.Background()
+			container := machine.GetContainer(ctx, b)
+			defer container.CleanUp(ctx)
+
+			runOpts := dockerutil.RunOpts{
+				Image: "benchmarks/fio",
+			}
+			container.CopyFiles(&runOpts, "/fusebin", "test/benchmarks/fs/clonefuse/clonefuse")
+			if err := container.Spawn(
+				ctx, runOpts,
+				// Sleep on the order of b.N.
+				"sleep", fmt.Sprintf("%d", 1000*b.N),
+			); err != nil {
+				b.Fatalf("failed to start fio container with: %v", err)
+			}
+
+			if out, err := container.Exec(ctx, dockerutil.ExecOpts{}, "mkdir", "-p", mountDir); err != nil {
+				b.Fatalf("failed to make directory: %v (%s)", err, out)
+			}
+			if _, err := container.ExecProcess(ctx, dockerutil.ExecOpts{
+				Privileged: true,
+			}, "/fusebin/clonefuse", "--dir="+mountDir, fmt.Sprintf("--readers=%d", readers), fmt.Sprintf("--size=%d", tc.SizeMB)); err != nil {
+				b.Fatalf("starting fuse server failed with: %v", err)
+			}
+
+			// Wait for the FUSE filesystem to be mounted.
+			file := mountDir + "/file"
+			deadline := time.Now().Add(mountTimeout)
+			for {
+				out, err := container.Exec(ctx, dockerutil.ExecOpts{}, "stat", file)
+				if err == nil {
+					break
+				}
+				if time.Now().After(deadline) {
+					b.Fatalf("fuse filesystem not mounted: %v (%s)", err, out)
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			// Run fio.
+			cmd := tc.MakeCmd(file)
+			b.StartTimer()
+			data, err := container.Exec(ctx, dockerutil.ExecOpts{}, cmd...)
+			if err != nil {
+				b.Fatalf("failed to run cmd %v: %v", cmd, err)
+			}
+			b.StopTimer()
+			tc.Report(b, data)
+		})
+	}
+}
+
+// TestMain is the main method for package fuseclone.
+func TestMain(m *testing.M) {
+	harness.Init()
+	os.Exit(m.Run())
+}
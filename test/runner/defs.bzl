@@ -81,6 +81,8 @@ def _syscall_test(
         save_resume = False,
         netstack_sr = False,
         nftables = False,
+        loop_devices = False,
+        cgroup_v2 = False,
         **kwargs):
     # Prepend "runsc" to non-native platform names.
     full_platform = platform if platform == "native" else "runsc_" + platform
@@ -177,6 +179,8 @@ def _syscall_test(
         "--save-resume=" + str(save_resume),
         "--netstack-sr=" + str(netstack_sr),
         "--nftables=" + str(nftables),
+        "--loop-devices=" + str(loop_devices),
+        "--cgroup-v2=" + str(cgroup_v2),
     ]
 
     # Trace points are platform agnostic, so enable them for ptrace only.
@@ -222,6 +226,8 @@ def syscall_test_variants(
         overlay = False,
         netstack_sr = False,
         nftables = False,
+        loop_devices = False,
+        cgroup_v2 = False,
         **kwargs):
     """Generates syscall tests for all variants.
 
@@ -246,6 +252,8 @@ def syscall_test_variants(
       size: test size.
       timeout: timeout for the test.
       save_resume: save resume test.
+      loop_devices: expose loop devices to the sandbox.
+      cgroup_v2: mount the cgroup v2 unified hierarchy at /sys/fs/cgroup.
       **kwargs: additional test arguments.
     """
     for platform, platform_tags in all_platforms():
@@ -272,6 +280,8 @@ def syscall_test_variants(
             overlay = overlay,
             netstack_sr = netstack_sr,
             nftables = nftables,
+            loop_devices = loop_devices,
+            cgroup_v2 = cgroup_v2,
             **kwargs
         )
 
@@ -296,6 +306,8 @@ def syscall_test_variants(
             timeout = timeout,
             netstack_sr = netstack_sr,
             nftables = nftables,
+            loop_devices = loop_devices,
+            cgroup_v2 = cgroup_v2,
             **kwargs
         )
 
@@ -321,6 +333,8 @@ def syscall_test_variants(
             timeout = timeout,
             netstack_sr = netstack_sr,
             nftables = nftables,
+            loop_devices = loop_devices,
+            cgroup_v2 = cgroup_v2,
             **kwargs
         )
     if not use_tmpfs:
@@ -345,6 +359,8 @@ def syscall_test_variants(
             timeout = timeout,
             netstack_sr = netstack_sr,
             nftables = nftables,
+            loop_devices = loop_devices,
+            cgroup_v2 = cgroup_v2,
             **kwargs
         )
     if add_fusefs:
@@ -367,6 +383,8 @@ def syscall_test_variants(
             timeout = timeout,
             netstack_sr = netstack_sr,
             nftables = nftables,
+            loop_devices = loop_devices,
+            cgroup_v2 = cgroup_v2,
             **kwargs
         )
 
@@ -392,6 +410,8 @@ def syscall_test(
         overlay = False,
         netstack_sr = False,
         nftables = False,
+        loop_devices = False,
+        cgroup_v2 = False,
         perf = False,
         **kwargs):
     """syscall_test is a macro that will create targets for all platforms.
@@ -417,6 +437,8 @@ def syscall_test(
       size: test size.
       overlay: add overlayfs test variants.
       netstack_sr: if save is true, add netstack save/restore test variants.
+      loop_devices: expose loop devices to the sandbox.
+      cgroup_v2: mount the cgroup v2 unified hierarchy at /sys/fs/cgroup.
       perf: test is a benchmark.
       **kwargs: additional test arguments.
     """
@@ -471,6 +493,8 @@ def syscall_test(
         overlay = overlay,
         netstack_sr = False,
         nftables = nftables,
+        loop_devices = loop_devices,
+        cgroup_v2 = cgroup_v2,
         **kwargs
     )
 
@@ -501,6 +525,8 @@ def syscall_test(
             "long",  # timeout, use long timeout for S/R tests.
             netstack_sr = False,
             nftables = nftables,
+            loop_devices = loop_devices,
+            cgroup_v2 = cgroup_v2,
             **kwargs
         )
 
@@ -528,6 +554,8 @@ def syscall_test(
                 "long",  # timeout, use long timeout for S/R tests.
                 netstack_sr = True,  # netstack_sr, generate all tests with netstack s/r.
                 nftables = nftables,
+                loop_devices = loop_devices,
+                cgroup_v2 = cgroup_v2,
                 **kwargs
             )
 
@@ -555,5 +583,7 @@ def syscall_test(
             "long",  # timeout, use long timeout for S/R tests.
             netstack_sr = False,
             nftables = nftables,
+            loop_devices = loop_devices,
+            cgroup_v2 = cgroup_v2,
             **kwargs
         )
@@ -73,6 +73,8 @@ var (
 	saveResume       = flag.Bool("save-resume", false, "enables save resume")
 	netstackSR       = flag.Bool("netstack-sr", false, "enables netstack s/r")
 	nftables         = flag.Bool("nftables", false, "enables nftables")
+	loopDevices      = flag.Bool("loop-devices", false, "exposes loop devices to the sandbox")
+	cgroupV2         = flag.Bool("cgroup-v2", false, "mounts the cgroup v2 unified hierarchy instead of cgroup v1 hierarchies")
 )
 
 const (
@@ -323,6 +325,7 @@ func runRunsc(tc *gtest.TestCase, spec *specs.Spec) error {
 		"-TESTONLY-allow-packet-endpoint-write=true",
 		fmt.Sprintf("-panic-signal=%d", unix.SIGTERM),
 		fmt.Sprintf("-iouring=%t", *ioUring),
+		fmt.Sprintf("-loop-devices=%t", *loopDevices),
 		"-watchdog-action=panic",
 		"-platform", *platform,
 		"-file-access", *fileAccess,
@@ -961,9 +964,13 @@ func runTestCaseRunsc(testBin string, tc *gtest.TestCase, args []string, t *test
 	}
 
 	// Add cgroup mount to enable cgroups for all tests.
+	cgroupType := "cgroup"
+	if *cgroupV2 {
+		cgroupType = "cgroup2"
+	}
 	spec.Mounts = append(spec.Mounts, specs.Mount{
 		Destination: "/sys/fs/cgroup",
-		Type:        "cgroup",
+		Type:        cgroupType,
 	})
 	if err := runRunsc(tc, spec); err != nil {
 		t.Errorf("test %q failed with error %v, want nil", tc.FullName(), err)
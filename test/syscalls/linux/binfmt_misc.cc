@@ -0,0 +1,205 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <sched.h>
+#include <sys/mount.h>
+#include <sys/statfs.h>
+#include <unistd.h>
+
+#include <string>
+#include <vector>
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "absl/strings/str_cat.h"
+#include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/mount_util.h"
+#include "test/util/multiprocess_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+namespace {
+
+constexpr char kEntryName[] = "gvisor_binfmt_test";
+constexpr int kBinfmtMagic = 0x42494e4d;
+
+class BinfmtMiscTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+    dir_ = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+    mount_ = ASSERT_NO_ERRNO_AND_VALUE(
+        Mount("none", dir_.path(), "binfmt_misc", 0, "", 0));
+  }
+
+  void TearDown() override {
+    // Remove the test entry if a test failed to do so, since binfmt_misc
+    // entries are global.
+    if (!dir_.path().empty()) {
+      const std::string entry = JoinPath(dir_.path(), kEntryName);
+      if (Exists(entry).ValueOr(false)) {
+        EXPECT_NO_ERRNO(SetContents(entry, "-1"));
+      }
+    }
+  }
+
+  // Register writes reg to the register file.
+  PosixError Register(const std::string& reg) {
+    ASSIGN_OR_RETURN_ERRNO(
+        FileDescriptor fd,
+        Open(JoinPath(dir_.path(), "register"), O_WRONLY));
+    RETURN_ERROR_IF_SYSCALL_FAIL(WriteFd(fd.get(), reg.data(), reg.size()));
+    return NoError();
+  }
+
+  TempPath dir_;
+  Cleanup mount_;
+};
+
+TEST_F(BinfmtMiscTest, Statfs) {
+  struct statfs st;
+  ASSERT_THAT(statfs(dir_.path().c_str(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.f_type, kBinfmtMagic);
+}
+
+TEST_F(BinfmtMiscTest, StaticFiles) {
+  EXPECT_NO_ERRNO(Exists(JoinPath(dir_.path(), "register")));
+  EXPECT_THAT(GetContents(JoinPath(dir_.path(), "status")),
+              IsPosixErrorOkAndHolds(::testing::AnyOf("enabled\n",
+                                                      "disabled\n")));
+}
+
+TEST_F(BinfmtMiscTest, RegisterExtension) {
+  ASSERT_NO_ERRNO(
+      Register(absl::StrCat(":", kEntryName, ":E::gvbfm::/bin/interp:P\n")));
+  const std::string entry = JoinPath(dir_.path(), kEntryName);
+  EXPECT_THAT(GetContents(entry),
+              IsPosixErrorOkAndHolds("enabled\ninterpreter /bin/interp\n"
+                                     "flags: P\nextension .gvbfm\n"));
+
+  std::vector<std::string> children =
+      ASSERT_NO_ERRNO_AND_VALUE(ListDir(dir_.path(), false));
+  EXPECT_THAT(children, ::testing::Contains(kEntryName));
+
+  // Registering the same name again fails.
+  EXPECT_THAT(
+      Register(absl::StrCat(":", kEntryName, ":E::gvbfm::/bin/interp:")),
+      PosixErrorIs(EEXIST));
+
+  ASSERT_NO_ERRNO(SetContents(entry, "-1"));
+  EXPECT_THAT(Exists(entry), IsPosixErrorOkAndHolds(false));
+}
+
+TEST_F(BinfmtMiscTest, RegisterMagic) {
+  ASSERT_NO_ERRNO(Register(absl::StrCat(
+      ":", kEntryName, ":M:2:\\x7fGV:\\xff\\xff\\x0f:/bin/interp:OC")));
+  const std::string entry = JoinPath(dir_.path(), kEntryName);
+  EXPECT_THAT(GetContents(entry),
+              IsPosixErrorOkAndHolds("enabled\ninterpreter /bin/interp\n"
+                                     "flags: OC\noffset 2\nmagic 7f4756\n"
+                                     "mask ffff0f\n"));
+  ASSERT_NO_ERRNO(SetContents(entry, "-1"));
+}
+
+TEST_F(BinfmtMiscTest, DisableEntry) {
+  ASSERT_NO_ERRNO(
+      Register(absl::StrCat(":", kEntryName, ":E::gvbfm::/bin/interp:")));
+  const std::string entry = JoinPath(dir_.path(), kEntryName);
+  ASSERT_NO_ERRNO(SetContents(entry, "0"));
+  EXPECT_THAT(GetContents(entry),
+              IsPosixErrorOkAndHolds(::testing::StartsWith("disabled\n")));
+  ASSERT_NO_ERRNO(SetContents(entry, "1"));
+  EXPECT_THAT(GetContents(entry),
+              IsPosixErrorOkAndHolds(::testing::StartsWith("enabled\n")));
+  EXPECT_THAT(SetContents(entry, "2"), PosixErrorIs(EINVAL));
+  ASSERT_NO_ERRNO(SetContents(entry, "-1"));
+}
+
+TEST_F(BinfmtMiscTest, RegisterInvalid) {
+  EXPECT_THAT(Register(":x:E::"), PosixErrorIs(EINVAL));
+  EXPECT_THAT(Register(absl::StrCat(":", kEntryName, ":Q::gvbfm::/bin/i:")),
+              PosixErrorIs(EINVAL));
+  EXPECT_THAT(Register(absl::StrCat(":", kEntryName, ":E::gvbfm::/bin/i:Z")),
+              PosixErrorIs(EINVAL));
+  EXPECT_THAT(Register(absl::StrCat(":", kEntryName, ":M:300:ab::/bin/i:")),
+              PosixErrorIs(EINVAL));
+}
+
+// Entries registered in a child user namespace are not visible to, and don't
+// affect, its parent.
+TEST_F(BinfmtMiscTest, UserNamespaceEntriesAreIsolated) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(CanCreateUserNamespace()));
+  const uid_t uid = geteuid();
+  const gid_t gid = getegid();
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const std::string path = dir.path();
+
+  const auto rest = [&] {
+    TEST_PCHECK(unshare(CLONE_NEWUSER | CLONE_NEWNS) == 0);
+    TEST_CHECK_NO_ERRNO(SetContents("/proc/self/setgroups", "deny"));
+    TEST_CHECK_NO_ERRNO(
+        SetContents("/proc/self/uid_map", absl::StrCat("0 ", uid, " 1")));
+    TEST_CHECK_NO_ERRNO(
+        SetContents("/proc/self/gid_map", absl::StrCat("0 ", gid, " 1")));
+    TEST_PCHECK(mount("none", path.c_str(), "binfmt_misc", 0, nullptr) == 0);
+    TEST_CHECK_NO_ERRNO(
+        SetContents(JoinPath(path, "register"),
+                    absl::StrCat(":", kEntryName, ":E::gvbfm::/bin/interp:")));
+    TEST_CHECK(Exists(JoinPath(path, kEntryName)).ValueOr(false));
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+
+  EXPECT_THAT(Exists(JoinPath(dir_.path(), kEntryName)),
+              IsPosixErrorOkAndHolds(false));
+}
+
+TEST_F(BinfmtMiscTest, UserNamespaceEntriesReleasedOnUnmount) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(CanCreateUserNamespace()));
+  const uid_t uid = geteuid();
+  const gid_t gid = getegid();
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const std::string path = dir.path();
+
+  const auto rest = [&] {
+    TEST_PCHECK(unshare(CLONE_NEWUSER | CLONE_NEWNS) == 0);
+    TEST_CHECK_NO_ERRNO(SetContents("/proc/self/setgroups", "deny"));
+    TEST_CHECK_NO_ERRNO(
+        SetContents("/proc/self/uid_map", absl::StrCat("0 ", uid, " 1")));
+    TEST_CHECK_NO_ERRNO(
+        SetContents("/proc/self/gid_map", absl::StrCat("0 ", gid, " 1")));
+    TEST_PCHECK(mount("none", path.c_str(), "binfmt_misc", 0, nullptr) == 0);
+    TEST_CHECK_NO_ERRNO(SetContents(
+        JoinPath(path, "register"),
+        absl::StrCat(":", kEntryName, ":E::gvbfm::/bin/true:F")));
+    TEST_CHECK(Exists(JoinPath(path, kEntryName)).ValueOr(false));
+    TEST_PCHECK(umount(path.c_str()) == 0);
+
+    // The entries of the user namespace are gone with its last binfmt_misc
+    // instance.
+    TEST_PCHECK(mount("none", path.c_str(), "binfmt_misc", 0, nullptr) == 0);
+    TEST_CHECK(!Exists(JoinPath(path, kEntryName)).ValueOr(true));
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+}  // namespace
+}  // namespace testing
+}  // namespace gvisor
@@ -17,8 +17,10 @@
 
 #include <limits.h>
 #include <linux/magic.h>
+#include <signal.h>
 #include <sys/mount.h>
 #include <sys/statfs.h>
+#include <sys/wait.h>
 #include <unistd.h>
 
 #include <cerrno>
@@ -29,8 +31,10 @@
 #include "absl/container/flat_hash_map.h"
 #include "absl/container/flat_hash_set.h"
 #include "absl/strings/ascii.h"
+#include "absl/strings/match.h"
 #include "absl/strings/str_split.h"
 #include "absl/synchronization/notification.h"
+#include "absl/time/clock.h"
 #include "absl/time/time.h"
 #include "test/util/cgroup_util.h"
 #include "test/util/cleanup.h"
@@ -51,11 +55,12 @@ using ::testing::Each;
 using ::testing::Eq;
 using ::testing::Ge;
 using ::testing::Gt;
+using ::testing::HasSubstr;
 using ::testing::Key;
 using ::testing::Not;
 
 std::vector<std::string> known_controllers = {
-    "cpu", "cpuset", "cpuacct", "devices", "job", "memory", "pids",
+    "blkio", "cpu", "cpuset", "cpuacct", "devices", "job", "memory", "pids",
 };
 
 bool CgroupsAvailable() {
@@ -496,6 +501,71 @@ TEST(MemoryCgroup, MemoryUsageInBytes) {
   EXPECT_GE(usage, 0);
 }
 
+// kMemoryLimit is the memory limit used by OOM tests. Memory hogs allocate
+// twice as much.
+constexpr int64_t kMemoryLimit = 16 << 20;
+
+TEST(MemoryCgroup, OOMKillOverLimit) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Cgroup c = Cgroup::RootCgroup("/sys/fs/cgroup/memory");
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("child"));
+  ASSERT_NO_ERRNO(
+      child.WriteIntegerControlFile("memory.limit_in_bytes", kMemoryLimit));
+
+  const pid_t idle = ASSERT_NO_ERRNO_AND_VALUE(ForkMemoryHog(child, 0));
+  auto cleanup = Cleanup([idle] {
+    kill(idle, SIGKILL);
+    waitpid(idle, nullptr, 0);
+  });
+  const pid_t hog =
+      ASSERT_NO_ERRNO_AND_VALUE(ForkMemoryHog(child, 2 * kMemoryLimit));
+  EXPECT_NO_ERRNO(WaitForKill(hog, absl::Seconds(30)));
+
+  // Only the process using the most memory is killed.
+  EXPECT_THAT(waitpid(idle, nullptr, WNOHANG), SyscallSucceedsWithValue(0));
+  EXPECT_THAT(child.ReadControlFile("memory.oom_control"),
+              IsPosixErrorOkAndHolds(HasSubstr("oom_kill 1\n")));
+}
+
+TEST(MemoryCgroup, OOMKillDisable) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Cgroup c = Cgroup::RootCgroup("/sys/fs/cgroup/memory");
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("child"));
+  ASSERT_NO_ERRNO(child.WriteIntegerControlFile("memory.oom_control", 1));
+  ASSERT_NO_ERRNO(
+      child.WriteIntegerControlFile("memory.limit_in_bytes", kMemoryLimit));
+
+  const pid_t hog =
+      ASSERT_NO_ERRNO_AND_VALUE(ForkMemoryHog(child, 2 * kMemoryLimit));
+  auto cleanup = Cleanup([hog] {
+    kill(hog, SIGKILL);
+    waitpid(hog, nullptr, 0);
+  });
+
+  // The cgroup goes under OOM, but nothing is killed.
+  const absl::Time deadline = absl::Now() + absl::Seconds(30);
+  std::string oom_control;
+  do {
+    oom_control =
+        ASSERT_NO_ERRNO_AND_VALUE(child.ReadControlFile("memory.oom_control"));
+    if (absl::StrContains(oom_control, "under_oom 1\n")) {
+      break;
+    }
+    absl::SleepFor(absl::Milliseconds(10));
+  } while (absl::Now() < deadline);
+  EXPECT_THAT(oom_control, HasSubstr("oom_kill_disable 1\n"));
+  ASSERT_THAT(oom_control, HasSubstr("under_oom 1\n"));
+  EXPECT_THAT(oom_control, HasSubstr("oom_kill 0\n"));
+  EXPECT_THAT(waitpid(hog, nullptr, WNOHANG), SyscallSucceedsWithValue(0));
+
+  // Re-enabling the OOM killer kills the hog.
+  ASSERT_NO_ERRNO(child.WriteIntegerControlFile("memory.oom_control", 0));
+  EXPECT_NO_ERRNO(WaitForKill(hog, absl::Seconds(30)));
+  cleanup.Release();
+}
+
 TEST(CPUCgroup, ControlFilesHaveDefaultValues) {
   SKIP_IF(!CgroupsAvailable());
 
@@ -1086,6 +1156,42 @@ TEST(DevicesCgroup, IgnorePartialMatchRule) {
               IsPosixErrorOkAndHolds("c 7:* rw\n"));
 }
 
+TEST(BlkioCgroup, ControlFilesExist) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Cgroup c = Cgroup::RootCgroup("/sys/fs/cgroup/blkio");
+
+  EXPECT_THAT(c.ReadControlFile("blkio.throttle.read_bps_device"),
+              IsPosixErrorOkAndHolds(""));
+  EXPECT_THAT(c.ReadControlFile("blkio.throttle.write_iops_device"),
+              IsPosixErrorOkAndHolds(""));
+  EXPECT_NO_ERRNO(c.ReadControlFile("blkio.throttle.io_service_bytes"));
+  EXPECT_NO_ERRNO(c.ReadControlFile("blkio.throttle.io_serviced_recursive"));
+}
+
+TEST(BlkioCgroup, SetAndClearThrottle) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Cgroup c = Cgroup::RootCgroup("/sys/fs/cgroup/blkio");
+
+  ASSERT_NO_ERRNO(
+      c.WriteControlFile("blkio.throttle.write_bps_device", "0:1234 1048576"));
+  EXPECT_THAT(c.ReadControlFile("blkio.throttle.write_bps_device"),
+              IsPosixErrorOkAndHolds("0:1234 1048576\n"));
+  // Other limits of the device are unaffected.
+  EXPECT_THAT(c.ReadControlFile("blkio.throttle.read_bps_device"),
+              IsPosixErrorOkAndHolds(""));
+
+  // A limit of 0 removes the limit.
+  ASSERT_NO_ERRNO(
+      c.WriteControlFile("blkio.throttle.write_bps_device", "0:1234 0"));
+  EXPECT_THAT(c.ReadControlFile("blkio.throttle.write_bps_device"),
+              IsPosixErrorOkAndHolds(""));
+
+  EXPECT_THAT(c.WriteControlFile("blkio.throttle.write_bps_device", "1234 1"),
+              PosixErrorIs(EINVAL));
+}
+
 }  // namespace
 }  // namespace testing
 }  // namespace gvisor
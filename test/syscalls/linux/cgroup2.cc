@@ -0,0 +1,352 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Tests for the cgroup v2 unified hierarchy. These tests run in sandboxes that
+// mount the unified hierarchy at /sys/fs/cgroup instead of cgroup v1
+// hierarchies.
+
+#include <linux/magic.h>
+#include <signal.h>
+#include <sys/statfs.h>
+#include <sys/wait.h>
+#include <unistd.h>
+
+#include <cerrno>
+#include <cstdint>
+#include <string>
+#include <vector>
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "absl/strings/numbers.h"
+#include "absl/strings/str_cat.h"
+#include "absl/strings/str_split.h"
+#include "absl/strings/string_view.h"
+#include "absl/time/clock.h"
+#include "absl/time/time.h"
+#include "test/util/capability_util.h"
+#include "test/util/cgroup_util.h"
+#include "test/util/cleanup.h"
+#include "test/util/fs_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+namespace {
+
+using ::testing::_;
+using ::testing::Contains;
+using ::testing::Ge;
+using ::testing::IsEmpty;
+using ::testing::Not;
+using ::testing::UnorderedElementsAre;
+
+constexpr char kRoot[] = "/sys/fs/cgroup";
+
+// kMemoryLimit is the memory limit used by OOM tests. Memory hogs allocate
+// twice as much.
+constexpr int64_t kMemoryLimit = 16 << 20;
+
+bool Cgroup2Available() {
+  if (!IsRunningOnGvisor() ||
+      !TEST_CHECK_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN))) {
+    return false;
+  }
+  struct statfs st;
+  return statfs(kRoot, &st) == 0 && st.f_type == CGROUP2_SUPER_MAGIC;
+}
+
+// Returns the value of key in the flat keyed control file name of cg.
+PosixErrorOr<int64_t> ReadKeyedControlFile(const Cgroup& cg,
+                                           absl::string_view name,
+                                           absl::string_view key) {
+  ASSIGN_OR_RETURN_ERRNO(std::string contents, cg.ReadControlFile(name));
+  for (absl::string_view line :
+       absl::StrSplit(contents, '\n', absl::SkipEmpty())) {
+    std::vector<absl::string_view> fields = absl::StrSplit(line, ' ');
+    int64_t val;
+    if (fields.size() == 2 && fields[0] == key &&
+        absl::SimpleAtoi(fields[1], &val)) {
+      return val;
+    }
+  }
+  return PosixError(ENOENT, absl::StrCat("no key ", key, " in ", name));
+}
+
+// Waits up to timeout for key in the flat keyed control file name of cg to
+// have a value of at least want.
+PosixError WaitForKeyedControlFile(const Cgroup& cg, absl::string_view name,
+                                   absl::string_view key, int64_t want,
+                                   absl::Duration timeout) {
+  const absl::Time deadline = absl::Now() + timeout;
+  while (true) {
+    ASSIGN_OR_RETURN_ERRNO(int64_t val, ReadKeyedControlFile(cg, name, key));
+    if (val >= want) {
+      return NoError();
+    }
+    if (absl::Now() > deadline) {
+      return PosixError(ETIMEDOUT, absl::StrCat(name, " ", key, " is ", val,
+                                                ", want at least ", want));
+    }
+    absl::SleepFor(absl::Milliseconds(10));
+  }
+}
+
+// Creates a child of parent named name, with the memory controller enabled.
+PosixErrorOr<Cgroup> CreateMemoryCgroup(const Cgroup& parent,
+                                        absl::string_view name) {
+  RETURN_IF_ERRNO(parent.WriteControlFile("cgroup.subtree_control", "+memory"));
+  return parent.CreateChild(name);
+}
+
+// KillOnExit returns a Cleanup that kills and reaps pid.
+Cleanup KillOnExit(pid_t pid) {
+  return Cleanup([pid] {
+    kill(pid, SIGKILL);
+    waitpid(pid, nullptr, 0);
+  });
+}
+
+TEST(Cgroup2, RootControllers) {
+  SKIP_IF(!Cgroup2Available());
+
+  Cgroup root = Cgroup::RootCgroup(kRoot);
+  EXPECT_THAT(root.ReadControlFile("cgroup.controllers"),
+              IsPosixErrorOkAndHolds("cpu cpuset io memory pids\n"));
+}
+
+TEST(Cgroup2, SubtreeControlEnablesChildControllers) {
+  SKIP_IF(!Cgroup2Available());
+
+  Cgroup root = Cgroup::RootCgroup(kRoot);
+  Cgroup cg = ASSERT_NO_ERRNO_AND_VALUE(root.CreateChild("cg"));
+  EXPECT_THAT(cg.ReadControlFile("cgroup.controllers"),
+              IsPosixErrorOkAndHolds("\n"));
+  EXPECT_THAT(Exists(cg.Relpath("memory.max")), IsPosixErrorOkAndHolds(false));
+  EXPECT_THAT(Exists(cg.Relpath("pids.max")), IsPosixErrorOkAndHolds(false));
+
+  ASSERT_NO_ERRNO(
+      root.WriteControlFile("cgroup.subtree_control", "+memory +pids"));
+  EXPECT_THAT(root.ReadControlFile("cgroup.subtree_control"),
+              IsPosixErrorOkAndHolds("memory pids\n"));
+  EXPECT_THAT(cg.ReadControlFile("cgroup.controllers"),
+              IsPosixErrorOkAndHolds("memory pids\n"));
+  EXPECT_THAT(Exists(cg.Relpath("memory.max")), IsPosixErrorOkAndHolds(true));
+  EXPECT_THAT(Exists(cg.Relpath("pids.max")), IsPosixErrorOkAndHolds(true));
+
+  // Controller files disappear when the controller is disabled.
+  ASSERT_NO_ERRNO(root.WriteControlFile("cgroup.subtree_control", "-pids"));
+  EXPECT_THAT(cg.ReadControlFile("cgroup.controllers"),
+              IsPosixErrorOkAndHolds("memory\n"));
+  EXPECT_THAT(Exists(cg.Relpath("pids.max")), IsPosixErrorOkAndHolds(false));
+}
+
+TEST(Cgroup2, SubtreeControlErrors) {
+  SKIP_IF(!Cgroup2Available());
+
+  Cgroup root = Cgroup::RootCgroup(kRoot);
+  Cgroup cg = ASSERT_NO_ERRNO_AND_VALUE(CreateMemoryCgroup(root, "cg"));
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(cg.CreateChild("child"));
+
+  // Malformed and unknown controllers.
+  EXPECT_THAT(cg.WriteControlFile("cgroup.subtree_control", "memory"),
+              PosixErrorIs(EINVAL, _));
+  EXPECT_THAT(cg.WriteControlFile("cgroup.subtree_control", "+bogus"),
+              PosixErrorIs(EINVAL, _));
+
+  // Only controllers listed in cgroup.controllers can be enabled.
+  EXPECT_THAT(cg.WriteControlFile("cgroup.subtree_control", "+pids"),
+              PosixErrorIs(ENOENT, _));
+
+  // A controller can't be disabled while a descendant has it enabled.
+  ASSERT_NO_ERRNO(cg.WriteControlFile("cgroup.subtree_control", "+memory"));
+  EXPECT_THAT(child.ReadControlFile("cgroup.controllers"),
+              IsPosixErrorOkAndHolds("memory\n"));
+  EXPECT_THAT(root.WriteControlFile("cgroup.subtree_control", "-memory"),
+              PosixErrorIs(EBUSY, _));
+  ASSERT_NO_ERRNO(cg.WriteControlFile("cgroup.subtree_control", "-memory"));
+  EXPECT_NO_ERRNO(root.WriteControlFile("cgroup.subtree_control", "-memory"));
+}
+
+TEST(Cgroup2, NoInternalProcesses) {
+  SKIP_IF(!Cgroup2Available());
+
+  Cgroup root = Cgroup::RootCgroup(kRoot);
+  Cgroup parent = ASSERT_NO_ERRNO_AND_VALUE(CreateMemoryCgroup(root, "parent"));
+  Cgroup leaf = ASSERT_NO_ERRNO_AND_VALUE(CreateMemoryCgroup(parent, "leaf"));
+
+  // parent has controllers enabled for its children, so it can't contain
+  // processes.
+  const pid_t pid = ASSERT_NO_ERRNO_AND_VALUE(ForkMemoryHog(leaf, 0));
+  auto cleanup = KillOnExit(pid);
+  EXPECT_THAT(parent.Enter(pid), PosixErrorIs(EBUSY, _));
+  EXPECT_THAT(leaf.Procs(), IsPosixErrorOkAndHolds(UnorderedElementsAre(pid)));
+
+  // Once it has no controllers enabled, it can.
+  ASSERT_NO_ERRNO(parent.WriteControlFile("cgroup.subtree_control", "-memory"));
+  ASSERT_NO_ERRNO(parent.Enter(pid));
+
+  // And then controllers can't be enabled for its children.
+  EXPECT_THAT(parent.WriteControlFile("cgroup.subtree_control", "+memory"),
+              PosixErrorIs(EBUSY, _));
+
+  // The root cgroup is exempt, and contains this process.
+  EXPECT_NO_ERRNO(root.ContainsCallingProcess());
+  EXPECT_NO_ERRNO(root.WriteControlFile("cgroup.subtree_control", "+pids"));
+}
+
+TEST(Cgroup2, ProcsMigration) {
+  SKIP_IF(!Cgroup2Available());
+
+  Cgroup root = Cgroup::RootCgroup(kRoot);
+  Cgroup a = ASSERT_NO_ERRNO_AND_VALUE(root.CreateChild("a"));
+  Cgroup b = ASSERT_NO_ERRNO_AND_VALUE(root.CreateChild("b"));
+
+  const pid_t pid = ASSERT_NO_ERRNO_AND_VALUE(ForkMemoryHog(a, 0));
+  auto cleanup = KillOnExit(pid);
+  const std::string proc_cgroup = absl::StrCat("/proc/", pid, "/cgroup");
+  EXPECT_THAT(a.Procs(), IsPosixErrorOkAndHolds(UnorderedElementsAre(pid)));
+  EXPECT_THAT(root.Procs(), IsPosixErrorOkAndHolds(Not(Contains(pid))));
+  EXPECT_THAT(GetContents(proc_cgroup), IsPosixErrorOkAndHolds("0::/a\n"));
+
+  ASSERT_NO_ERRNO(b.Enter(pid));
+  EXPECT_THAT(a.Procs(), IsPosixErrorOkAndHolds(IsEmpty()));
+  EXPECT_THAT(b.Procs(), IsPosixErrorOkAndHolds(UnorderedElementsAre(pid)));
+  EXPECT_THAT(GetContents(proc_cgroup), IsPosixErrorOkAndHolds("0::/b\n"));
+  EXPECT_THAT(ReadKeyedControlFile(a, "cgroup.events", "populated"),
+              IsPosixErrorOkAndHolds(0));
+  EXPECT_THAT(ReadKeyedControlFile(b, "cgroup.events", "populated"),
+              IsPosixErrorOkAndHolds(1));
+
+  // a can be removed now that it's empty.
+  EXPECT_NO_ERRNO(a.Delete());
+}
+
+TEST(Cgroup2, KillFrozenProcess) {
+  SKIP_IF(!Cgroup2Available());
+
+  Cgroup root = Cgroup::RootCgroup(kRoot);
+  Cgroup cg = ASSERT_NO_ERRNO_AND_VALUE(root.CreateChild("frozen"));
+  const pid_t pid = ASSERT_NO_ERRNO_AND_VALUE(ForkMemoryHog(cg, 0));
+  ASSERT_NO_ERRNO(cg.WriteControlFile("cgroup.freeze", "1"));
+  EXPECT_THAT(ReadKeyedControlFile(cg, "cgroup.events", "frozen"),
+              IsPosixErrorOkAndHolds(1));
+
+  // Frozen processes can still be killed.
+  ASSERT_THAT(kill(pid, SIGKILL), SyscallSucceeds());
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(pid, &status, 0),
+              SyscallSucceedsWithValue(pid));
+  EXPECT_TRUE(WIFSIGNALED(status) && WTERMSIG(status) == SIGKILL)
+      << "status = " << status;
+}
+
+TEST(Cgroup2Memory, OOMGroupDefaults) {
+  SKIP_IF(!Cgroup2Available());
+
+  Cgroup root = Cgroup::RootCgroup(kRoot);
+  Cgroup cg = ASSERT_NO_ERRNO_AND_VALUE(CreateMemoryCgroup(root, "cg"));
+  EXPECT_THAT(cg.ReadIntegerControlFile("memory.oom.group"),
+              IsPosixErrorOkAndHolds(0));
+  ASSERT_NO_ERRNO(cg.WriteIntegerControlFile("memory.oom.group", 1));
+  EXPECT_THAT(cg.ReadIntegerControlFile("memory.oom.group"),
+              IsPosixErrorOkAndHolds(1));
+  EXPECT_THAT(cg.WriteIntegerControlFile("memory.oom.group", 2),
+              PosixErrorIs(EINVAL, _));
+  EXPECT_THAT(cg.ReadIntegerControlFile("memory.oom.group"),
+              IsPosixErrorOkAndHolds(1));
+}
+
+TEST(Cgroup2Memory, MemoryMaxOOMKill) {
+  SKIP_IF(!Cgroup2Available());
+
+  Cgroup root = Cgroup::RootCgroup(kRoot);
+  Cgroup cg = ASSERT_NO_ERRNO_AND_VALUE(CreateMemoryCgroup(root, "cg"));
+  ASSERT_NO_ERRNO(cg.WriteIntegerControlFile("memory.max", kMemoryLimit));
+
+  const pid_t idle = ASSERT_NO_ERRNO_AND_VALUE(ForkMemoryHog(cg, 0));
+  auto cleanup = KillOnExit(idle);
+  const pid_t hog =
+      ASSERT_NO_ERRNO_AND_VALUE(ForkMemoryHog(cg, 2 * kMemoryLimit));
+  EXPECT_NO_ERRNO(WaitForKill(hog, absl::Seconds(30)));
+
+  // Only the process using the most memory is killed.
+  EXPECT_THAT(waitpid(idle, nullptr, WNOHANG), SyscallSucceedsWithValue(0));
+  EXPECT_THAT(ReadKeyedControlFile(cg, "memory.events", "max"),
+              IsPosixErrorOkAndHolds(Ge(1)));
+  EXPECT_THAT(ReadKeyedControlFile(cg, "memory.events", "oom"),
+              IsPosixErrorOkAndHolds(Ge(1)));
+  EXPECT_THAT(ReadKeyedControlFile(cg, "memory.events", "oom_kill"),
+              IsPosixErrorOkAndHolds(1));
+}
+
+TEST(Cgroup2Memory, OOMGroupKillsWholeCgroup) {
+  SKIP_IF(!Cgroup2Available());
+
+  // The limit is set on parent, and the victim is in group, which has
+  // memory.oom.group set. All processes in group are killed, but not those in
+  // its sibling other.
+  Cgroup root = Cgroup::RootCgroup(kRoot);
+  Cgroup parent = ASSERT_NO_ERRNO_AND_VALUE(CreateMemoryCgroup(root, "parent"));
+  ASSERT_NO_ERRNO(parent.WriteIntegerControlFile("memory.max", kMemoryLimit));
+  Cgroup group = ASSERT_NO_ERRNO_AND_VALUE(CreateMemoryCgroup(parent, "group"));
+  ASSERT_NO_ERRNO(group.WriteIntegerControlFile("memory.oom.group", 1));
+  Cgroup other = ASSERT_NO_ERRNO_AND_VALUE(parent.CreateChild("other"));
+
+  const pid_t bystander = ASSERT_NO_ERRNO_AND_VALUE(ForkMemoryHog(other, 0));
+  auto bystander_cleanup = KillOnExit(bystander);
+  const pid_t idle = ASSERT_NO_ERRNO_AND_VALUE(ForkMemoryHog(group, 0));
+  auto idle_cleanup = KillOnExit(idle);
+  const pid_t hog =
+      ASSERT_NO_ERRNO_AND_VALUE(ForkMemoryHog(group, 2 * kMemoryLimit));
+  EXPECT_NO_ERRNO(WaitForKill(hog, absl::Seconds(30)));
+  EXPECT_NO_ERRNO(WaitForKill(idle, absl::Seconds(30)));
+  idle_cleanup.Release();
+
+  EXPECT_THAT(waitpid(bystander, nullptr, WNOHANG),
+              SyscallSucceedsWithValue(0));
+  EXPECT_THAT(ReadKeyedControlFile(parent, "memory.events", "oom_kill"),
+              IsPosixErrorOkAndHolds(2));
+}
+
+TEST(Cgroup2Memory, EventsHighCountsCrossings) {
+  SKIP_IF(!Cgroup2Available());
+
+  Cgroup root = Cgroup::RootCgroup(kRoot);
+  Cgroup cg = ASSERT_NO_ERRNO_AND_VALUE(CreateMemoryCgroup(root, "cg"));
+  ASSERT_NO_ERRNO(cg.WriteIntegerControlFile("memory.high", kMemoryLimit));
+  EXPECT_THAT(ReadKeyedControlFile(cg, "memory.events", "high"),
+              IsPosixErrorOkAndHolds(0));
+
+  const pid_t hog =
+      ASSERT_NO_ERRNO_AND_VALUE(ForkMemoryHog(cg, 2 * kMemoryLimit));
+  auto cleanup = KillOnExit(hog);
+  ASSERT_NO_ERRNO(WaitForKeyedControlFile(cg, "memory.events", "high", 1,
+                                          absl::Seconds(30)));
+
+  // Staying over memory.high isn't a new event.
+  absl::SleepFor(absl::Seconds(1));
+  EXPECT_THAT(ReadKeyedControlFile(cg, "memory.events", "high"),
+              IsPosixErrorOkAndHolds(1));
+
+  // memory.high only throttles, so nothing is killed.
+  EXPECT_THAT(waitpid(hog, nullptr, WNOHANG), SyscallSucceedsWithValue(0));
+  EXPECT_THAT(ReadKeyedControlFile(cg, "memory.events", "oom_kill"),
+              IsPosixErrorOkAndHolds(0));
+}
+
+}  // namespace
+}  // namespace testing
+}  // namespace gvisor
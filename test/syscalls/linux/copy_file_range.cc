@@ -0,0 +1,208 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <stdlib.h>
+#include <sys/syscall.h>
+#include <unistd.h>
+
+#include <string>
+#include <vector>
+
+#include "gtest/gtest.h"
+#include "absl/strings/string_view.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+constexpr char kData[] = "The quick brown fox jumps over the lazy dog.";
+constexpr size_t kDataSize = sizeof(kData) - 1;
+
+ssize_t CopyFileRange(int fd_in, off_t* off_in, int fd_out, off_t* off_out,
+                      size_t len, unsigned int flags) {
+  return syscall(SYS_copy_file_range, fd_in, off_in, fd_out, off_out, len,
+                 flags);
+}
+
+// In gVisor, copy_file_range only copies files on FUSE filesystems, and fails
+// with EXDEV otherwise.
+bool CopySupported() {
+  return !IsRunningOnGvisor() ||
+         absl::NullSafeStringView(getenv("GVISOR_FUSE_TEST")) == "TRUE";
+}
+
+std::string ReadContents(int fd) {
+  std::vector<char> buf(kDataSize * 2);
+  ssize_t n = pread(fd, buf.data(), buf.size(), 0);
+  if (n < 0) {
+    return "";
+  }
+  return std::string(buf.data(), n);
+}
+
+TEST(CopyFileRangeTest, CopyWithOffsets) {
+  SKIP_IF(!CopySupported());
+
+  const TempPath in_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), kData, TempPath::kDefaultFileMode));
+  const TempPath out_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const FileDescriptor inf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file.path(), O_RDONLY));
+  const FileDescriptor outf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(out_file.path(), O_RDWR));
+
+  off_t off_in = 4;
+  off_t off_out = 2;
+  EXPECT_THAT(CopyFileRange(inf.get(), &off_in, outf.get(), &off_out, 5, 0),
+              SyscallSucceedsWithValue(5));
+  EXPECT_EQ(off_in, 9);
+  EXPECT_EQ(off_out, 7);
+
+  // The file offsets are left untouched.
+  EXPECT_THAT(lseek(inf.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(0));
+  EXPECT_THAT(lseek(outf.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(0));
+  EXPECT_EQ(ReadContents(outf.get()), std::string("\0\0quick", 7));
+}
+
+TEST(CopyFileRangeTest, CopyWithFileOffsets) {
+  SKIP_IF(!CopySupported());
+
+  const TempPath in_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), kData, TempPath::kDefaultFileMode));
+  const TempPath out_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const FileDescriptor inf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file.path(), O_RDONLY));
+  const FileDescriptor outf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(out_file.path(), O_RDWR));
+
+  ASSERT_THAT(lseek(inf.get(), 4, SEEK_SET), SyscallSucceeds());
+  EXPECT_THAT(CopyFileRange(inf.get(), nullptr, outf.get(), nullptr,
+                            kDataSize, 0),
+              SyscallSucceedsWithValue(kDataSize - 4));
+  EXPECT_THAT(lseek(inf.get(), 0, SEEK_CUR),
+              SyscallSucceedsWithValue(kDataSize));
+  EXPECT_THAT(lseek(outf.get(), 0, SEEK_CUR),
+              SyscallSucceedsWithValue(kDataSize - 4));
+  EXPECT_EQ(ReadContents(outf.get()), std::string(kData + 4));
+
+  // At the end of the input file, nothing is copied.
+  EXPECT_THAT(CopyFileRange(inf.get(), nullptr, outf.get(), nullptr,
+                            kDataSize, 0),
+              SyscallSucceedsWithValue(0));
+}
+
+TEST(CopyFileRangeTest, InvalidFlags) {
+  const TempPath in_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), kData, TempPath::kDefaultFileMode));
+  const TempPath out_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const FileDescriptor inf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file.path(), O_RDONLY));
+  const FileDescriptor outf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(out_file.path(), O_WRONLY));
+
+  EXPECT_THAT(CopyFileRange(inf.get(), nullptr, outf.get(), nullptr, 1, 1),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(CopyFileRangeTest, BadFileModes) {
+  const TempPath in_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), kData, TempPath::kDefaultFileMode));
+  const TempPath out_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const FileDescriptor inf_wronly =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file.path(), O_WRONLY));
+  const FileDescriptor inf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file.path(), O_RDONLY));
+  const FileDescriptor outf_rdonly =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(out_file.path(), O_RDONLY));
+  const FileDescriptor outf_append =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(out_file.path(), O_WRONLY | O_APPEND));
+
+  EXPECT_THAT(CopyFileRange(inf_wronly.get(), nullptr, outf_append.get(),
+                            nullptr, 1, 0),
+              SyscallFailsWithErrno(EBADF));
+  EXPECT_THAT(
+      CopyFileRange(inf.get(), nullptr, outf_rdonly.get(), nullptr, 1, 0),
+      SyscallFailsWithErrno(EBADF));
+  EXPECT_THAT(
+      CopyFileRange(inf.get(), nullptr, outf_append.get(), nullptr, 1, 0),
+      SyscallFailsWithErrno(EBADF));
+}
+
+TEST(CopyFileRangeTest, NonRegularFiles) {
+  const TempPath out_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const FileDescriptor outf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(out_file.path(), O_WRONLY));
+
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const FileDescriptor dirfd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_RDONLY | O_DIRECTORY));
+  EXPECT_THAT(CopyFileRange(dirfd.get(), nullptr, outf.get(), nullptr, 1, 0),
+              SyscallFailsWithErrno(EISDIR));
+
+  int fds[2];
+  ASSERT_THAT(pipe(fds), SyscallSucceeds());
+  const FileDescriptor rfd(fds[0]);
+  const FileDescriptor wfd(fds[1]);
+  ASSERT_THAT(WriteFd(wfd.get(), kData, kDataSize),
+              SyscallSucceedsWithValue(kDataSize));
+  EXPECT_THAT(CopyFileRange(rfd.get(), nullptr, outf.get(), nullptr, 1, 0),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(CopyFileRangeTest, SameFile) {
+  SKIP_IF(!CopySupported());
+
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), kData, TempPath::kDefaultFileMode));
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDWR));
+
+  // Overlapping ranges are rejected.
+  off_t off_in = 0;
+  off_t off_out = 4;
+  EXPECT_THAT(CopyFileRange(fd.get(), &off_in, fd.get(), &off_out, 8, 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Disjoint ranges are fine.
+  off_out = kDataSize;
+  EXPECT_THAT(CopyFileRange(fd.get(), &off_in, fd.get(), &off_out, 4, 0),
+              SyscallSucceedsWithValue(4));
+  EXPECT_EQ(ReadContents(fd.get()), std::string(kData) + "The ");
+}
+
+TEST(CopyFileRangeTest, CrossFilesystem) {
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), kData, TempPath::kDefaultFileMode));
+  const FileDescriptor inf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+  int memfd;
+  ASSERT_THAT(memfd = syscall(__NR_memfd_create, "copy_file_range", 0),
+              SyscallSucceeds());
+  const FileDescriptor outf(memfd);
+
+  // memfds are on their own filesystem, and copies between filesystems that
+  // don't implement copy_file_range themselves aren't allowed.
+  EXPECT_THAT(
+      CopyFileRange(inf.get(), nullptr, outf.get(), nullptr, kDataSize, 0),
+      SyscallFailsWithErrno(EXDEV));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
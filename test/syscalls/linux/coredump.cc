@@ -0,0 +1,347 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <elf.h>
+#include <errno.h>
+#include <fcntl.h>
+#include <signal.h>
+#include <string.h>
+#include <sys/mman.h>
+#include <sys/resource.h>
+#include <sys/stat.h>
+#include <sys/types.h>
+#include <sys/wait.h>
+#include <unistd.h>
+
+#include <cstdint>
+#include <functional>
+#include <string>
+#include <utility>
+#include <vector>
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "absl/strings/match.h"
+#include "absl/strings/str_cat.h"
+#include "absl/strings/str_split.h"
+#include "absl/strings/string_view.h"
+#include "absl/time/clock.h"
+#include "absl/time/time.h"
+#include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/memory_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+constexpr char kCorePattern[] = "/proc/sys/kernel/core_pattern";
+
+// SetCorePattern sets core_pattern to pattern, and returns a Cleanup that
+// restores the original core_pattern.
+PosixErrorOr<Cleanup> SetCorePattern(absl::string_view pattern) {
+  ASSIGN_OR_RETURN_ERRNO(std::string old, GetContents(kCorePattern));
+  if (!old.empty() && old.back() == '\n') {
+    old.pop_back();
+  }
+  RETURN_IF_ERRNO(SetContents(kCorePattern, pattern));
+  return Cleanup(
+      [old] { TEST_CHECK_NO_ERRNO(SetContents(kCorePattern, old)); });
+}
+
+// Crash forks a child that sets RLIMIT_CORE to limit, runs fn, and is then
+// killed by SIGABRT. It returns the child's pid and wait status.
+PosixErrorOr<std::pair<pid_t, int>> Crash(rlim_t limit,
+                                          const std::function<void()>& fn) {
+  const pid_t child = fork();
+  if (child == 0) {
+    struct rlimit rl = {limit, limit};
+    TEST_PCHECK(setrlimit(RLIMIT_CORE, &rl) == 0);
+    TEST_PCHECK(signal(SIGABRT, SIG_DFL) != SIG_ERR);
+    fn();
+    raise(SIGABRT);
+    _exit(1);
+  }
+  if (child < 0) {
+    return PosixError(errno, "fork");
+  }
+  int status;
+  if (RetryEINTR(waitpid)(child, &status, 0) < 0) {
+    return PosixError(errno, "waitpid");
+  }
+  if (!WIFSIGNALED(status) || WTERMSIG(status) != SIGABRT) {
+    return PosixError(EINVAL, absl::StrCat("unexpected status ", status));
+  }
+  return std::make_pair(child, status);
+}
+
+PosixErrorOr<std::pair<pid_t, int>> Crash(rlim_t limit) {
+  return Crash(limit, [] {});
+}
+
+// CoreFile is the ELF header and program headers of a core dump file.
+struct CoreFile {
+  FileDescriptor fd;
+  Elf64_Ehdr ehdr;
+  std::vector<Elf64_Phdr> phdrs;
+};
+
+PosixErrorOr<CoreFile> ReadCoreFile(const std::string& path) {
+  CoreFile core;
+  ASSIGN_OR_RETURN_ERRNO(core.fd, Open(path, O_RDONLY));
+  if (pread(core.fd.get(), &core.ehdr, sizeof(core.ehdr), 0) !=
+      sizeof(core.ehdr)) {
+    return PosixError(EIO, "short read of ELF header");
+  }
+  if (memcmp(core.ehdr.e_ident, ELFMAG, SELFMAG) != 0 ||
+      core.ehdr.e_type != ET_CORE ||
+      core.ehdr.e_phentsize != sizeof(Elf64_Phdr)) {
+    return PosixError(EINVAL, "bad ELF header");
+  }
+  core.phdrs.resize(core.ehdr.e_phnum);
+  const size_t size = core.phdrs.size() * sizeof(Elf64_Phdr);
+  if (pread(core.fd.get(), core.phdrs.data(), size, core.ehdr.e_phoff) !=
+      static_cast<ssize_t>(size)) {
+    return PosixError(EIO, "short read of program headers");
+  }
+  return core;
+}
+
+// Returns the PT_LOAD program header of core that contains addr.
+PosixErrorOr<Elf64_Phdr> FindLoad(const CoreFile& core, uintptr_t addr) {
+  for (const Elf64_Phdr& phdr : core.phdrs) {
+    if (phdr.p_type == PT_LOAD && phdr.p_vaddr <= addr &&
+        addr < phdr.p_vaddr + phdr.p_memsz) {
+      return phdr;
+    }
+  }
+  return PosixError(ENOENT, absl::StrCat("no PT_LOAD for ", addr));
+}
+
+// WaitForFile waits up to timeout for a file to exist at path.
+PosixError WaitForFile(const std::string& path, absl::Duration timeout) {
+  const absl::Time deadline = absl::Now() + timeout;
+  while (true) {
+    ASSIGN_OR_RETURN_ERRNO(bool exists, Exists(path));
+    if (exists) {
+      return NoError();
+    }
+    if (absl::Now() > deadline) {
+      return PosixError(ETIMEDOUT, absl::StrCat(path, " doesn't exist"));
+    }
+    absl::SleepFor(absl::Milliseconds(10));
+  }
+}
+
+TEST(CoredumpFilterTest, ReadWrite) {
+  // Linux's default, MMF_DUMP_FILTER_DEFAULT.
+  EXPECT_THAT(GetContents("/proc/self/coredump_filter"),
+              IsPosixErrorOkAndHolds("00000033\n"));
+
+  auto restore = Cleanup([] {
+    TEST_CHECK_NO_ERRNO(SetContents("/proc/self/coredump_filter", "0x33"));
+  });
+  ASSERT_NO_ERRNO(SetContents("/proc/self/coredump_filter", "0x7"));
+  EXPECT_THAT(GetContents("/proc/self/coredump_filter"),
+              IsPosixErrorOkAndHolds("00000007\n"));
+  EXPECT_THAT(SetContents("/proc/self/coredump_filter", "bogus"),
+              PosixErrorIs(EINVAL, ::testing::_));
+
+  // gVisor doesn't support partial writes; Linux ignores the offset.
+  if (IsRunningOnGvisor()) {
+    const FileDescriptor fd =
+        ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/self/coredump_filter", O_WRONLY));
+    EXPECT_THAT(pwrite(fd.get(), "0x3", 3, 1), SyscallFailsWithErrno(EINVAL));
+    EXPECT_THAT(GetContents("/proc/self/coredump_filter"),
+                IsPosixErrorOkAndHolds("00000007\n"));
+  }
+
+  // The filter is inherited across fork.
+  const pid_t child = fork();
+  if (child == 0) {
+    TEST_CHECK(GetContents("/proc/self/coredump_filter").ValueOrDie() ==
+               "00000007\n");
+    _exit(0);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0) << status;
+}
+
+// The remaining tests change the sandbox-wide core_pattern, which would affect
+// the host if run natively.
+
+TEST(CoreDumpTest, CorePatternFile) {
+  SKIP_IF(!IsRunningOnGvisor());
+
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto restore = ASSERT_NO_ERRNO_AND_VALUE(
+      SetCorePattern(JoinPath(dir.path(), "core.%p.%s")));
+
+  const auto [child, status] =
+      ASSERT_NO_ERRNO_AND_VALUE(Crash(RLIM_INFINITY));
+  EXPECT_TRUE(WCOREDUMP(status));
+
+  const CoreFile core = ASSERT_NO_ERRNO_AND_VALUE(ReadCoreFile(
+      JoinPath(dir.path(), absl::StrCat("core.", child, ".", SIGABRT))));
+  ASSERT_FALSE(core.phdrs.empty());
+  EXPECT_EQ(core.phdrs[0].p_type, PT_NOTE);
+}
+
+TEST(CoreDumpTest, RlimitCoreZero) {
+  SKIP_IF(!IsRunningOnGvisor());
+
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto restore = ASSERT_NO_ERRNO_AND_VALUE(
+      SetCorePattern(JoinPath(dir.path(), "core")));
+
+  const auto [child, status] = ASSERT_NO_ERRNO_AND_VALUE(Crash(0));
+  EXPECT_FALSE(WCOREDUMP(status));
+  EXPECT_THAT(Exists(JoinPath(dir.path(), "core")),
+              IsPosixErrorOkAndHolds(false));
+}
+
+TEST(CoreDumpTest, RlimitCoreTruncates) {
+  SKIP_IF(!IsRunningOnGvisor());
+
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const std::string path = JoinPath(dir.path(), "core");
+  auto restore = ASSERT_NO_ERRNO_AND_VALUE(SetCorePattern(path));
+
+  constexpr rlim_t kLimit = 4 * kPageSize;
+  const auto [child, status] = ASSERT_NO_ERRNO_AND_VALUE(Crash(kLimit));
+  // A truncated core dump isn't reported as a core dump.
+  EXPECT_FALSE(WCOREDUMP(status));
+
+  struct stat st;
+  ASSERT_THAT(stat(path.c_str(), &st), SyscallSucceeds());
+  EXPECT_GT(st.st_size, 0);
+  EXPECT_LE(st.st_size, static_cast<off_t>(kLimit));
+}
+
+TEST(CoreDumpTest, SkipsUnpopulatedAnonymousMemory) {
+  SKIP_IF(!IsRunningOnGvisor());
+
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const std::string path = JoinPath(dir.path(), "core");
+  auto restore = ASSERT_NO_ERRNO_AND_VALUE(SetCorePattern(path));
+
+  // Only one page of a large anonymous mapping is touched, by the child.
+  constexpr size_t kSize = 256 << 20;
+  constexpr char kContents[] = "core dump contents";
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kSize, PROT_READ | PROT_WRITE, MAP_PRIVATE | MAP_NORESERVE));
+  const uintptr_t touched = m.addr() + kSize / 2;
+  ASSERT_NO_ERRNO(Crash(RLIM_INFINITY, [&] {
+    memcpy(reinterpret_cast<void*>(touched), kContents, sizeof(kContents));
+  }));
+
+  const CoreFile core = ASSERT_NO_ERRNO_AND_VALUE(ReadCoreFile(path));
+  const Elf64_Phdr load = ASSERT_NO_ERRNO_AND_VALUE(FindLoad(core, touched));
+  ASSERT_EQ(load.p_filesz, load.p_memsz);
+  char buf[sizeof(kContents)] = {};
+  ASSERT_THAT(pread(core.fd.get(), buf, sizeof(buf),
+                    load.p_offset + (touched - load.p_vaddr)),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_STREQ(buf, kContents);
+
+  // Untouched pages are holes in the core dump file, rather than zeroes.
+  struct stat st;
+  ASSERT_THAT(fstat(core.fd.get(), &st), SyscallSucceeds());
+  EXPECT_GE(st.st_size, static_cast<off_t>(kSize));
+  EXPECT_LT(st.st_blocks * 512, static_cast<blkcnt_t>(kSize / 4));
+}
+
+TEST(CoreDumpTest, CoredumpFilterExcludesAnonymousMemory) {
+  SKIP_IF(!IsRunningOnGvisor());
+
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const std::string path = JoinPath(dir.path(), "core");
+  auto restore = ASSERT_NO_ERRNO_AND_VALUE(SetCorePattern(path));
+
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  ASSERT_NO_ERRNO(Crash(RLIM_INFINITY, [&] {
+    memset(m.ptr(), 1, kPageSize);
+    TEST_CHECK_NO_ERRNO(SetContents("/proc/self/coredump_filter", "0"));
+  }));
+
+  const CoreFile core = ASSERT_NO_ERRNO_AND_VALUE(ReadCoreFile(path));
+  const Elf64_Phdr load = ASSERT_NO_ERRNO_AND_VALUE(FindLoad(core, m.addr()));
+  EXPECT_EQ(load.p_filesz, 0u);
+}
+
+TEST(CoreDumpTest, CorePatternPipe) {
+  SKIP_IF(!IsRunningOnGvisor());
+
+  // The helper saves the core dump and its own resource limits. Fields of a
+  // piped core_pattern are split on whitespace, so ${IFS} is used to separate
+  // shell words.
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto restore = ASSERT_NO_ERRNO_AND_VALUE(SetCorePattern(
+      absl::StrCat("|/bin/sh -c cd${IFS}", dir.path(),
+                   ";cat>core;cat</proc/self/limits>limits;>done")));
+
+  // RLIMIT_CORE doesn't limit piped core dumps.
+  const auto [child, status] = ASSERT_NO_ERRNO_AND_VALUE(Crash(kPageSize));
+  EXPECT_TRUE(WCOREDUMP(status));
+
+  ASSERT_NO_ERRNO(
+      WaitForFile(JoinPath(dir.path(), "done"), absl::Seconds(30)));
+  EXPECT_NO_ERRNO(ReadCoreFile(JoinPath(dir.path(), "core")));
+
+  // The helper runs with an RLIMIT_CORE of 1, so that it can't recursively
+  // pipe its own core dump.
+  const std::string limits = ASSERT_NO_ERRNO_AND_VALUE(
+      GetContents(JoinPath(dir.path(), "limits")));
+  bool found = false;
+  for (absl::string_view line : absl::StrSplit(limits, '\n')) {
+    if (absl::StartsWith(line, "Max core file size")) {
+      std::vector<absl::string_view> fields =
+          absl::StrSplit(line, ' ', absl::SkipEmpty());
+      ASSERT_EQ(fields.size(), 7) << line;
+      EXPECT_EQ(fields[4], "1") << line;
+      EXPECT_EQ(fields[5], "1") << line;
+      found = true;
+    }
+  }
+  EXPECT_TRUE(found) << limits;
+}
+
+TEST(CoreDumpTest, PipeRefusedWithRlimitCoreOne) {
+  SKIP_IF(!IsRunningOnGvisor());
+
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const std::string core_path = JoinPath(dir.path(), "core");
+  auto restore = ASSERT_NO_ERRNO_AND_VALUE(
+      SetCorePattern(absl::StrCat("|/bin/sh -c cat>", core_path)));
+
+  const auto [child, status] = ASSERT_NO_ERRNO_AND_VALUE(Crash(1));
+  EXPECT_FALSE(WCOREDUMP(status));
+  // Give a helper that was incorrectly started time to run.
+  absl::SleepFor(absl::Seconds(1));
+  EXPECT_THAT(Exists(core_path), IsPosixErrorOkAndHolds(false));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
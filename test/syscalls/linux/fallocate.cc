@@ -14,8 +14,10 @@
 
 #include <errno.h>
 #include <fcntl.h>
+#include <linux/falloc.h>
 #include <signal.h>
 #include <sys/eventfd.h>
+#include <sys/mman.h>
 #include <sys/resource.h>
 #include <sys/signalfd.h>
 #include <sys/socket.h>
@@ -26,6 +28,7 @@
 #include <unistd.h>
 
 #include <ctime>
+#include <string>
 
 #include "gtest/gtest.h"
 #include "absl/strings/str_cat.h"
@@ -34,6 +37,7 @@
 #include "test/util/cleanup.h"
 #include "test/util/eventfd_util.h"
 #include "test/util/file_descriptor.h"
+#include "test/util/memory_util.h"
 #include "test/util/posix_error.h"
 #include "test/util/socket_util.h"
 #include "test/util/temp_path.h"
@@ -193,6 +197,162 @@ TEST_F(AllocateTest, FallocateOtherFDs) {
   close(pipefds[1]);
 }
 
+
+TEST_F(AllocateTest, FallocateInvalidModes) {
+  // Unknown flags.
+  EXPECT_THAT(fallocate(test_file_fd_.get(), 0x80, 0, 10),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+  // Punch hole requires keep size.
+  EXPECT_THAT(fallocate(test_file_fd_.get(), FALLOC_FL_PUNCH_HOLE, 0, 10),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+  // Punch hole and zero range are mutually exclusive.
+  EXPECT_THAT(fallocate(test_file_fd_.get(),
+                        FALLOC_FL_PUNCH_HOLE | FALLOC_FL_ZERO_RANGE |
+                            FALLOC_FL_KEEP_SIZE,
+                        0, 10),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+  // Collapse and insert range must be used alone.
+  EXPECT_THAT(
+      fallocate(test_file_fd_.get(),
+                FALLOC_FL_COLLAPSE_RANGE | FALLOC_FL_KEEP_SIZE, 0, 10),
+      SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(fallocate(test_file_fd_.get(),
+                        FALLOC_FL_INSERT_RANGE | FALLOC_FL_KEEP_SIZE, 0, 10),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(AllocateTest, FallocateKeepSize) {
+  ASSERT_THAT(
+      fallocate(test_file_fd_.get(), FALLOC_FL_KEEP_SIZE, 0, kPageSize),
+      SyscallSucceeds());
+  struct stat buf;
+  ASSERT_THAT(fstat(test_file_fd_.get(), &buf), SyscallSucceeds());
+  EXPECT_EQ(buf.st_size, 0);
+
+  // RLIMIT_FSIZE doesn't apply since the file doesn't grow.
+  struct rlimit initial_lim;
+  ASSERT_THAT(getrlimit(RLIMIT_FSIZE, &initial_lim), SyscallSucceeds());
+  auto cleanup = Cleanup([&initial_lim] {
+    EXPECT_THAT(setrlimit(RLIMIT_FSIZE, &initial_lim), SyscallSucceeds());
+  });
+  struct rlimit setlim = initial_lim;
+  setlim.rlim_cur = 1024;
+  ASSERT_THAT(setrlimit(RLIMIT_FSIZE, &setlim), SyscallSucceeds());
+  EXPECT_THAT(
+      fallocate(test_file_fd_.get(), FALLOC_FL_KEEP_SIZE, 0, 2 * kPageSize),
+      SyscallSucceeds());
+}
+
+// FillPages writes n pages to fd, where page i is filled with 'a' + i.
+void FillPages(int fd, int n) {
+  for (int i = 0; i < n; i++) {
+    std::string page(kPageSize, 'a' + i);
+    ASSERT_THAT(PwriteFd(fd, page.data(), page.size(), i * kPageSize),
+                SyscallSucceedsWithValue(kPageSize));
+  }
+}
+
+// ReadAll returns the contents of the file at fd.
+PosixErrorOr<std::string> ReadAll(int fd) {
+  struct stat buf;
+  RETURN_ERROR_IF_SYSCALL_FAIL(fstat(fd, &buf));
+  std::string contents(buf.st_size, '\0');
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      PreadFd(fd, contents.data(), contents.size(), 0));
+  return contents;
+}
+
+TEST_F(AllocateTest, FallocatePunchHole) {
+  ASSERT_NO_FATAL_FAILURE(FillPages(test_file_fd_.get(), 3));
+  // Punch a hole that covers page 1 and parts of pages 0 and 2.
+  const off_t offset = kPageSize - 10;
+  const off_t len = kPageSize + 20;
+  int ret = fallocate(test_file_fd_.get(),
+                      FALLOC_FL_PUNCH_HOLE | FALLOC_FL_KEEP_SIZE, offset, len);
+  if (ret < 0 && errno == EOPNOTSUPP) {
+    GTEST_SKIP() << "Filesystem does not support FALLOC_FL_PUNCH_HOLE";
+  }
+  ASSERT_THAT(ret, SyscallSucceeds());
+
+  std::string want = std::string(offset, 'a') + std::string(len, '\0') +
+                     std::string(3 * kPageSize - offset - len, 'c');
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(ReadAll(test_file_fd_.get())), want);
+}
+
+TEST_F(AllocateTest, FallocatePunchHoleMapped) {
+  ASSERT_NO_FATAL_FAILURE(FillPages(test_file_fd_.get(), 2));
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      Mmap(nullptr, 2 * kPageSize, PROT_READ, MAP_SHARED,
+           test_file_fd_.get(), 0));
+  const char* p = reinterpret_cast<const char*>(m.ptr());
+  ASSERT_EQ(p[0], 'a');
+  int ret = fallocate(test_file_fd_.get(),
+                      FALLOC_FL_PUNCH_HOLE | FALLOC_FL_KEEP_SIZE, 0, kPageSize);
+  if (ret < 0 && errno == EOPNOTSUPP) {
+    GTEST_SKIP() << "Filesystem does not support FALLOC_FL_PUNCH_HOLE";
+  }
+  ASSERT_THAT(ret, SyscallSucceeds());
+  EXPECT_EQ(p[0], '\0');
+  EXPECT_EQ(p[kPageSize], 'b');
+}
+
+TEST_F(AllocateTest, FallocateZeroRange) {
+  ASSERT_NO_FATAL_FAILURE(FillPages(test_file_fd_.get(), 1));
+  int ret = fallocate(test_file_fd_.get(), FALLOC_FL_ZERO_RANGE, 10,
+                      2 * kPageSize);
+  if (ret < 0 && errno == EOPNOTSUPP) {
+    GTEST_SKIP() << "Filesystem does not support FALLOC_FL_ZERO_RANGE";
+  }
+  ASSERT_THAT(ret, SyscallSucceeds());
+
+  // Without FALLOC_FL_KEEP_SIZE, the file grows to cover the range.
+  std::string want =
+      std::string(10, 'a') + std::string(2 * kPageSize, '\0');
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(ReadAll(test_file_fd_.get())), want);
+}
+
+TEST_F(AllocateTest, FallocateCollapseRange) {
+  ASSERT_NO_FATAL_FAILURE(FillPages(test_file_fd_.get(), 3));
+  int ret = fallocate(test_file_fd_.get(), FALLOC_FL_COLLAPSE_RANGE,
+                      kPageSize, kPageSize);
+  if (ret < 0 && errno == EOPNOTSUPP) {
+    GTEST_SKIP() << "Filesystem does not support FALLOC_FL_COLLAPSE_RANGE";
+  }
+  ASSERT_THAT(ret, SyscallSucceeds());
+
+  std::string want =
+      std::string(kPageSize, 'a') + std::string(kPageSize, 'c');
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(ReadAll(test_file_fd_.get())), want);
+
+  // The range must be block-aligned and may not reach the end of the file.
+  EXPECT_THAT(fallocate(test_file_fd_.get(), FALLOC_FL_COLLAPSE_RANGE, 1,
+                        kPageSize),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(fallocate(test_file_fd_.get(), FALLOC_FL_COLLAPSE_RANGE,
+                        kPageSize, kPageSize),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(AllocateTest, FallocateInsertRange) {
+  ASSERT_NO_FATAL_FAILURE(FillPages(test_file_fd_.get(), 2));
+  int ret = fallocate(test_file_fd_.get(), FALLOC_FL_INSERT_RANGE, kPageSize,
+                      kPageSize);
+  if (ret < 0 && errno == EOPNOTSUPP) {
+    GTEST_SKIP() << "Filesystem does not support FALLOC_FL_INSERT_RANGE";
+  }
+  ASSERT_THAT(ret, SyscallSucceeds());
+
+  std::string want = std::string(kPageSize, 'a') +
+                     std::string(kPageSize, '\0') +
+                     std::string(kPageSize, 'b');
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(ReadAll(test_file_fd_.get())), want);
+
+  // The offset must be within the file.
+  EXPECT_THAT(fallocate(test_file_fd_.get(), FALLOC_FL_INSERT_RANGE,
+                        3 * kPageSize, kPageSize),
+              SyscallFailsWithErrno(EINVAL));
+}
+
 }  // namespace
 }  // namespace testing
 }  // namespace gvisor
@@ -16,8 +16,11 @@
 #include <linux/capability.h>
 #include <linux/fuse.h>
 #include <stdio.h>
+#include <sys/file.h>
 #include <sys/mount.h>
 #include <sys/stat.h>
+#include <sys/syscall.h>
+#include <sys/xattr.h>
 #include <unistd.h>
 
 #include <cerrno>
@@ -34,6 +37,7 @@
 #include "test/util/fs_util.h"
 #include "test/util/linux_capability_util.h"
 #include "test/util/mount_util.h"
+#include "test/util/multiprocess_util.h"
 #include "test/util/posix_error.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
@@ -47,8 +51,7 @@ namespace {
 
 TEST(FuseTest, RejectBadInit) {
   SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
-  const FileDescriptor fd =
-      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/fuse", O_RDWR, 0));
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/fuse", O_RDWR, 0));
 
   auto mount_point = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
   auto mount_opts =
@@ -89,6 +92,149 @@ TEST(FuseTest, LookupUpdatesInode) {
               SyscallFailsWithErrno(ENOENT));
 }
 
+TEST(FuseTest, Xattrs) {
+  SKIP_IF(absl::NullSafeStringView(getenv("GVISOR_FUSE_TEST")) != "TRUE");
+  TempPath path = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileIn(GetAbsoluteTestTmpdir()));
+  const char* file = path.path().c_str();
+  const char kName[] = "user.gvisor.test";
+  const std::string kValue = "value";
+
+  int ret = setxattr(file, kName, kValue.data(), kValue.size(), 0);
+  if (ret < 0 && errno == EOPNOTSUPP) {
+    GTEST_SKIP() << "FUSE server does not support extended attributes";
+  }
+  ASSERT_THAT(ret, SyscallSucceeds());
+
+  // A zero size returns the size of the value.
+  EXPECT_THAT(getxattr(file, kName, nullptr, 0),
+              SyscallSucceedsWithValue(kValue.size()));
+  std::vector<char> buf(kValue.size());
+  EXPECT_THAT(getxattr(file, kName, buf.data(), buf.size()),
+              SyscallSucceedsWithValue(kValue.size()));
+  EXPECT_EQ(std::string(buf.data(), buf.size()), kValue);
+  EXPECT_THAT(getxattr(file, kName, buf.data(), buf.size() - 1),
+              SyscallFailsWithErrno(ERANGE));
+
+  // The list contains the attribute.
+  ssize_t list_size;
+  ASSERT_THAT(list_size = listxattr(file, nullptr, 0),
+              SyscallSucceedsWithValue(Ge(sizeof(kName))));
+  std::vector<char> list(list_size);
+  ASSERT_THAT(listxattr(file, list.data(), list.size()),
+              SyscallSucceedsWithValue(list_size));
+  std::vector<std::string> names;
+  for (size_t i = 0; i < list.size(); i += names.back().size() + 1) {
+    names.push_back(std::string(&list[i]));
+  }
+  EXPECT_THAT(names, ::testing::Contains(kName));
+
+  ASSERT_THAT(removexattr(file, kName), SyscallSucceeds());
+  EXPECT_THAT(getxattr(file, kName, nullptr, 0),
+              SyscallFailsWithErrno(ENODATA));
+}
+
+TEST(FuseTest, PosixLocks) {
+  SKIP_IF(absl::NullSafeStringView(getenv("GVISOR_FUSE_TEST")) != "TRUE");
+  TempPath path = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileIn(GetAbsoluteTestTmpdir()));
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path.path(), O_RDWR));
+
+  struct flock fl = {};
+  fl.l_type = F_WRLCK;
+  fl.l_whence = SEEK_SET;
+  fl.l_start = 10;
+  fl.l_len = 10;
+  ASSERT_THAT(fcntl(fd.get(), F_SETLK, &fl), SyscallSucceeds());
+
+  // Another process can't take a conflicting lock, and sees ours.
+  const pid_t parent = getpid();
+  const auto rest = [&] {
+    struct flock child_fl = {};
+    child_fl.l_type = F_RDLCK;
+    child_fl.l_whence = SEEK_SET;
+    child_fl.l_start = 15;
+    child_fl.l_len = 0;
+    TEST_PCHECK(fcntl(fd.get(), F_SETLK, &child_fl) < 0 &&
+                (errno == EAGAIN || errno == EACCES));
+    TEST_PCHECK(fcntl(fd.get(), F_GETLK, &child_fl) == 0);
+    TEST_CHECK(child_fl.l_type == F_WRLCK);
+    TEST_CHECK(child_fl.l_start == 10);
+    TEST_CHECK(child_fl.l_len == 10);
+    TEST_CHECK(child_fl.l_pid == parent);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+
+  fl.l_type = F_UNLCK;
+  ASSERT_THAT(fcntl(fd.get(), F_SETLK, &fl), SyscallSucceeds());
+  const auto unlocked = [&] {
+    struct flock child_fl = {};
+    child_fl.l_type = F_WRLCK;
+    child_fl.l_whence = SEEK_SET;
+    TEST_PCHECK(fcntl(fd.get(), F_SETLK, &child_fl) == 0);
+  };
+  EXPECT_THAT(InForkedProcess(unlocked), IsPosixErrorOkAndHolds(0));
+}
+
+TEST(FuseTest, FlockLocks) {
+  SKIP_IF(absl::NullSafeStringView(getenv("GVISOR_FUSE_TEST")) != "TRUE");
+  TempPath path = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileIn(GetAbsoluteTestTmpdir()));
+  FileDescriptor fd1 = ASSERT_NO_ERRNO_AND_VALUE(Open(path.path(), O_RDWR));
+  FileDescriptor fd2 = ASSERT_NO_ERRNO_AND_VALUE(Open(path.path(), O_RDWR));
+
+  ASSERT_THAT(flock(fd1.get(), LOCK_EX | LOCK_NB), SyscallSucceeds());
+  EXPECT_THAT(flock(fd2.get(), LOCK_SH | LOCK_NB),
+              SyscallFailsWithErrno(EWOULDBLOCK));
+
+  // Closing the file releases the lock.
+  fd1.reset();
+  EXPECT_THAT(flock(fd2.get(), LOCK_EX | LOCK_NB), SyscallSucceeds());
+  EXPECT_THAT(flock(fd2.get(), LOCK_UN), SyscallSucceeds());
+}
+
+TEST(FuseTest, SeekDataHole) {
+  SKIP_IF(absl::NullSafeStringView(getenv("GVISOR_FUSE_TEST")) != "TRUE");
+  const std::string kFileData = "data";
+  TempPath path = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), kFileData, TempPath::kDefaultFileMode));
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path.path(), O_RDONLY));
+
+  // Whether or not the server implements FUSE_LSEEK, a file without holes is
+  // all data followed by the implicit hole at its end.
+  EXPECT_THAT(lseek(fd.get(), 1, SEEK_DATA), SyscallSucceedsWithValue(1));
+  EXPECT_THAT(lseek(fd.get(), 1, SEEK_HOLE),
+              SyscallSucceedsWithValue(kFileData.size()));
+  EXPECT_THAT(lseek(fd.get(), kFileData.size(), SEEK_DATA),
+              SyscallFailsWithErrno(ENXIO));
+}
+
+TEST(FuseTest, CopyFileRange) {
+  SKIP_IF(absl::NullSafeStringView(getenv("GVISOR_FUSE_TEST")) != "TRUE");
+  const std::string kFileData = "copied data";
+  TempPath src = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), kFileData, TempPath::kDefaultFileMode));
+  TempPath dst = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileIn(GetAbsoluteTestTmpdir()));
+  FileDescriptor src_fd = ASSERT_NO_ERRNO_AND_VALUE(Open(src.path(), O_RDONLY));
+  FileDescriptor dst_fd = ASSERT_NO_ERRNO_AND_VALUE(Open(dst.path(), O_RDWR));
+
+  // The copy is offloaded to the server if it implements
+  // FUSE_COPY_FILE_RANGE, and done by the sentry otherwise.
+  off_t off_in = 0;
+  off_t off_out = 0;
+  ASSERT_THAT(syscall(SYS_copy_file_range, src_fd.get(), &off_in, dst_fd.get(),
+                      &off_out, kFileData.size(), 0),
+              SyscallSucceedsWithValue(kFileData.size()));
+  struct stat st;
+  ASSERT_THAT(fstat(dst_fd.get(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_size, kFileData.size());
+  std::vector<char> buf(kFileData.size());
+  ASSERT_THAT(pread(dst_fd.get(), buf.data(), buf.size(), 0),
+              SyscallSucceedsWithValue(kFileData.size()));
+  EXPECT_EQ(std::string(buf.data(), buf.size()), kFileData);
+}
+
 }  // namespace
 }  // namespace testing
 }  // namespace gvisor
@@ -179,6 +179,42 @@ TEST(GetrusageTest, Wait4) {
   EXPECT_GT(rusage_children.ru_maxrss, 0);
 }
 
+TEST(GetrusageTest, MinorFaults) {
+  constexpr int kPages = 16;
+  struct rusage before;
+  ASSERT_THAT(getrusage(RUSAGE_SELF, &before), SyscallSucceeds());
+
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(MmapAnon(
+      kPages * kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  char* addr = reinterpret_cast<char*>(m.ptr());
+  for (int i = 0; i < kPages; i++) {
+    addr[i * kPageSize] = 1;
+  }
+
+  struct rusage after;
+  ASSERT_THAT(getrusage(RUSAGE_SELF, &after), SyscallSucceeds());
+  // Touching fresh anonymous memory never requires I/O, so these faults are
+  // minor. More than one page may be faulted in at a time, so only require
+  // progress.
+  EXPECT_GT(after.ru_minflt, before.ru_minflt);
+}
+
+TEST(GetrusageTest, ChildFaults) {
+  pid_t pid = fork();
+  if (pid == 0) {
+    Mapping m = TEST_CHECK_NO_ERRNO_AND_VALUE(
+        MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+    *reinterpret_cast<volatile char*>(m.ptr()) = 1;
+    _exit(0);
+  }
+  ASSERT_THAT(pid, SyscallSucceeds());
+  struct rusage rusage_children;
+  int status;
+  ASSERT_THAT(RetryEINTR(wait4)(pid, &status, 0, &rusage_children),
+              SyscallSucceeds());
+  EXPECT_GT(rusage_children.ru_minflt, 0);
+}
+
 }  // namespace
 
 }  // namespace testing
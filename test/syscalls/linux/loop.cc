@@ -0,0 +1,186 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <linux/fs.h>
+#include <linux/loop.h>
+#include <stdint.h>
+#include <sys/ioctl.h>
+#include <sys/mount.h>
+#include <sys/stat.h>
+#include <unistd.h>
+
+#include <string>
+
+#include "gtest/gtest.h"
+#include "absl/strings/str_cat.h"
+#include "test/util/capability_util.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/posix_error.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+constexpr int kImageSize = 8192;
+
+// LoopTest binds a free loop device. Loop devices are only available in gVisor
+// if runsc is started with --loop-devices.
+class LoopTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+    SKIP_IF(access("/dev/loop-control", F_OK) != 0);
+
+    const FileDescriptor control =
+        ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/loop-control", O_RDWR));
+    int idx;
+    ASSERT_THAT(idx = ioctl(control.get(), LOOP_CTL_GET_FREE),
+                SyscallSucceeds());
+    index_ = idx;
+    path_ = absl::StrCat("/dev/loop", idx);
+    SKIP_IF(access(path_.c_str(), F_OK) != 0);
+    loop_ = ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_RDWR));
+
+    contents_.resize(kImageSize);
+    for (int i = 0; i < kImageSize; i++) {
+      contents_[i] = 'a' + i % 26;
+    }
+    backing_ = ASSERT_NO_ERRNO_AND_VALUE(
+        TempPath::CreateFileWith(GetAbsoluteTestTmpdir(), contents_, 0644));
+  }
+
+  void TearDown() override {
+    if (loop_.get() >= 0) {
+      // The device may already be unbound.
+      ioctl(loop_.get(), LOOP_CLR_FD);
+    }
+  }
+
+  int index_ = -1;
+  std::string path_;
+  FileDescriptor loop_;
+  std::string contents_;
+  TempPath backing_;
+};
+
+TEST_F(LoopTest, SetFdExposesBackingFile) {
+  const FileDescriptor backing =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(backing_.path(), O_RDWR));
+  ASSERT_THAT(ioctl(loop_.get(), LOOP_SET_FD, backing.get()),
+              SyscallSucceeds());
+
+  uint64_t size;
+  ASSERT_THAT(ioctl(loop_.get(), BLKGETSIZE64, &size), SyscallSucceeds());
+  EXPECT_EQ(size, kImageSize);
+
+  std::string buf(kImageSize, '\0');
+  ASSERT_THAT(pread(loop_.get(), buf.data(), buf.size(), 0),
+              SyscallSucceedsWithValue(kImageSize));
+  EXPECT_EQ(buf, contents_);
+
+  struct stat st;
+  ASSERT_THAT(fstat(backing.get(), &st), SyscallSucceeds());
+  struct loop_info64 info = {};
+  ASSERT_THAT(ioctl(loop_.get(), LOOP_GET_STATUS64, &info), SyscallSucceeds());
+  EXPECT_EQ(info.lo_number, index_);
+  EXPECT_EQ(info.lo_inode, st.st_ino);
+  EXPECT_EQ(info.lo_offset, 0);
+  EXPECT_EQ(info.lo_flags & LO_FLAGS_READ_ONLY, 0);
+
+  ASSERT_THAT(ioctl(loop_.get(), LOOP_CLR_FD), SyscallSucceeds());
+  EXPECT_THAT(ioctl(loop_.get(), LOOP_GET_STATUS64, &info),
+              SyscallFailsWithErrno(ENXIO));
+}
+
+TEST_F(LoopTest, SetFdBusy) {
+  const FileDescriptor backing =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(backing_.path(), O_RDONLY));
+  ASSERT_THAT(ioctl(loop_.get(), LOOP_SET_FD, backing.get()),
+              SyscallSucceeds());
+  EXPECT_THAT(ioctl(loop_.get(), LOOP_SET_FD, backing.get()),
+              SyscallFailsWithErrno(EBUSY));
+}
+
+TEST_F(LoopTest, ClrFdUnbound) {
+  EXPECT_THAT(ioctl(loop_.get(), LOOP_CLR_FD), SyscallFailsWithErrno(ENXIO));
+}
+
+TEST_F(LoopTest, WriteThrough) {
+  const FileDescriptor backing =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(backing_.path(), O_RDWR));
+  ASSERT_THAT(ioctl(loop_.get(), LOOP_SET_FD, backing.get()),
+              SyscallSucceeds());
+
+  std::string data(512, 'z');
+  ASSERT_THAT(pwrite(loop_.get(), data.data(), data.size(), 512),
+              SyscallSucceedsWithValue(data.size()));
+  ASSERT_THAT(fsync(loop_.get()), SyscallSucceeds());
+
+  std::string buf(data.size(), '\0');
+  ASSERT_THAT(pread(backing.get(), buf.data(), buf.size(), 512),
+              SyscallSucceedsWithValue(buf.size()));
+  EXPECT_EQ(buf, data);
+}
+
+TEST_F(LoopTest, ConfigureOffsetReadOnly) {
+  const FileDescriptor backing =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(backing_.path(), O_RDWR));
+  struct loop_config config = {};
+  config.fd = backing.get();
+  config.info.lo_offset = kImageSize / 2;
+  config.info.lo_flags = LO_FLAGS_READ_ONLY;
+  ASSERT_THAT(ioctl(loop_.get(), LOOP_CONFIGURE, &config), SyscallSucceeds());
+
+  uint64_t size;
+  ASSERT_THAT(ioctl(loop_.get(), BLKGETSIZE64, &size), SyscallSucceeds());
+  EXPECT_EQ(size, kImageSize / 2);
+
+  std::string buf(kImageSize / 2, '\0');
+  ASSERT_THAT(pread(loop_.get(), buf.data(), buf.size(), 0),
+              SyscallSucceedsWithValue(buf.size()));
+  EXPECT_EQ(buf, contents_.substr(kImageSize / 2));
+
+  EXPECT_THAT(pwrite(loop_.get(), buf.data(), buf.size(), 0),
+              SyscallFailsWithErrno(EPERM));
+
+  struct loop_info64 info = {};
+  ASSERT_THAT(ioctl(loop_.get(), LOOP_GET_STATUS64, &info), SyscallSucceeds());
+  EXPECT_EQ(info.lo_offset, kImageSize / 2);
+  EXPECT_NE(info.lo_flags & LO_FLAGS_READ_ONLY, 0);
+}
+
+TEST_F(LoopTest, MountInvalidErofsImage) {
+  // The backing file doesn't contain an EROFS superblock.
+  const FileDescriptor backing =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(backing_.path(), O_RDONLY));
+  ASSERT_THAT(ioctl(loop_.get(), LOOP_SET_FD, backing.get()),
+              SyscallSucceeds());
+
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const int ret =
+      mount(path_.c_str(), dir.path().c_str(), "erofs", MS_RDONLY, nullptr);
+  EXPECT_EQ(ret, -1);
+  if (IsRunningOnGvisor()) {
+    EXPECT_EQ(errno, EINVAL);
+  }
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -67,6 +67,7 @@
 #include "test/util/mount_util.h"
 #include "test/util/multiprocess_util.h"
 #include "test/util/posix_error.h"
+#include "test/util/proc_util.h"
 #include "test/util/save_util.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
@@ -921,6 +922,108 @@ TEST(MountTest, TmpfsSizeMmap) {
   EXPECT_THAT(munmap(addr, 2 * kPageSize), SyscallSucceeds());
 }
 
+// Returns the ShmemPmdMapped field of the /proc/self/smaps entry containing
+// addr, in kB.
+PosixErrorOr<size_t> ShmemPmdMappedKB(void* addr) {
+  ASSIGN_OR_RETURN_ERRNO(auto entries, ReadProcSelfSmaps());
+  ASSIGN_OR_RETURN_ERRNO(
+      auto entry,
+      FindUniqueSmapsEntry(entries, reinterpret_cast<uintptr_t>(addr)));
+  if (!entry.shmem_pmd_mapped_kb.has_value()) {
+    return PosixError(ENOENT, "no ShmemPmdMapped in smaps");
+  }
+  return entry.shmem_pmd_mapped_kb.value();
+}
+
+constexpr size_t kHugePageSize = 2 << 20;
+
+TEST(MountTest, TmpfsHugeOption) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  // huge=always is tested first to find out whether huge pages are available
+  // at all; the other policies are expected to match it.
+  size_t always_kb = 0;
+  for (const char* policy : {"always", "never", "within_size", "advise"}) {
+    SCOPED_TRACE(policy);
+    auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+    auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+        Mount("", dir.path(), kTmpfs, 0, absl::StrCat("huge=", policy), 0));
+    auto fd = ASSERT_NO_ERRNO_AND_VALUE(
+        Open(JoinPath(dir.path(), "foo"), O_CREAT | O_RDWR, 0777));
+    ASSERT_THAT(ftruncate(fd.get(), 2 * kHugePageSize), SyscallSucceeds());
+    void* addr = mmap(nullptr, 2 * kHugePageSize, PROT_READ | PROT_WRITE,
+                      MAP_SHARED, fd.get(), 0);
+    ASSERT_NE(addr, MAP_FAILED);
+    EXPECT_THAT(madvise(addr, 2 * kHugePageSize, MADV_HUGEPAGE),
+                SyscallSucceeds());
+    memset(addr, 1, 2 * kHugePageSize);
+
+    const size_t kb = ASSERT_NO_ERRNO_AND_VALUE(ShmemPmdMappedKB(addr));
+    if (strcmp(policy, "always") == 0) {
+      EXPECT_THAT(kb, ::testing::AnyOf(size_t{0}, 2 * kHugePageSize / 1024));
+      always_kb = kb;
+    } else if (strcmp(policy, "never") == 0) {
+      EXPECT_EQ(kb, 0);
+    } else {
+      EXPECT_EQ(kb, always_kb);
+    }
+    EXPECT_THAT(munmap(addr, 2 * kHugePageSize), SyscallSucceeds());
+  }
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  EXPECT_THAT(mount("", dir.path().c_str(), kTmpfs, 0, "huge=sometimes"),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(MountTest, TmpfsHugeWithinSize) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), kTmpfs, 0, "huge=within_size", 0));
+  auto fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Open(JoinPath(dir.path(), "foo"), O_CREAT | O_RDWR, 0777));
+  // The file ends within its first huge page, so no huge page lies entirely
+  // within its size.
+  ASSERT_THAT(ftruncate(fd.get(), kHugePageSize / 2), SyscallSucceeds());
+  void* addr = mmap(nullptr, kHugePageSize, PROT_READ | PROT_WRITE, MAP_SHARED,
+                    fd.get(), 0);
+  ASSERT_NE(addr, MAP_FAILED);
+  auto cleanup = Cleanup([addr] { munmap(addr, kHugePageSize); });
+  memset(addr, 1, kHugePageSize / 2);
+  EXPECT_THAT(ShmemPmdMappedKB(addr), IsPosixErrorOkAndHolds(0));
+}
+
+// With huge=advise, only mappings with MADV_HUGEPAGE are hugepage-backed,
+// even if other mappings of the same file aren't.
+TEST(MountTest, TmpfsHugeAdvisePerMapping) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), kTmpfs, 0, "huge=advise", 0));
+  auto fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Open(JoinPath(dir.path(), "foo"), O_CREAT | O_RDWR, 0777));
+  ASSERT_THAT(ftruncate(fd.get(), 2 * kHugePageSize), SyscallSucceeds());
+
+  void* advised = mmap(nullptr, kHugePageSize, PROT_READ | PROT_WRITE,
+                       MAP_SHARED, fd.get(), 0);
+  ASSERT_NE(advised, MAP_FAILED);
+  auto cleanup_advised =
+      Cleanup([advised] { munmap(advised, kHugePageSize); });
+  void* unadvised = mmap(nullptr, kHugePageSize, PROT_READ | PROT_WRITE,
+                         MAP_SHARED, fd.get(), kHugePageSize);
+  ASSERT_NE(unadvised, MAP_FAILED);
+  auto cleanup_unadvised =
+      Cleanup([unadvised] { munmap(unadvised, kHugePageSize); });
+  ASSERT_THAT(madvise(advised, kHugePageSize, MADV_HUGEPAGE),
+              SyscallSucceeds());
+
+  memset(unadvised, 1, kHugePageSize);
+  memset(advised, 1, kHugePageSize);
+  EXPECT_THAT(ShmemPmdMappedKB(unadvised), IsPosixErrorOkAndHolds(0));
+  const size_t kb = ASSERT_NO_ERRNO_AND_VALUE(ShmemPmdMappedKB(advised));
+  // Huge pages may not be available at all.
+  EXPECT_THAT(kb, ::testing::AnyOf(size_t{0}, kHugePageSize / 1024));
+}
+
 TEST(MountTest, SimpleBind) {
   SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
 
@@ -0,0 +1,266 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <errno.h>
+#include <fcntl.h>
+#include <string.h>
+#include <sys/mount.h>
+#include <sys/stat.h>
+#include <sys/xattr.h>
+#include <unistd.h>
+
+#include <string>
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "absl/strings/str_cat.h"
+#include "absl/strings/string_view.h"
+#include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/mount_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+namespace {
+
+constexpr char kData[] = "hello";
+constexpr char kMetacopyXattr[] = "trusted.overlay.metacopy";
+constexpr char kRedirectXattr[] = "trusted.overlay.redirect";
+
+// OverlayTest sets up the layers of an overlay on a tmpfs mount. Tests
+// populate the layers, then mount the overlay with MountOverlay.
+class OverlayTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+    dir_ = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+    tmpfs_ = ASSERT_NO_ERRNO_AND_VALUE(
+        Mount("none", dir_.path(), "tmpfs", 0, "mode=0755", 0));
+    for (const char* name : {"lower", "upper", "work", "merged"}) {
+      ASSERT_NO_ERRNO(Mkdir(JoinPath(dir_.path(), name)));
+    }
+  }
+
+  // MountOverlay mounts the overlay with the extra mount options opts. It
+  // skips the test if the host kernel doesn't support opts.
+  void MountOverlay(const std::string& opts) {
+    std::string data = absl::StrCat("lowerdir=", Lower(""),
+                                    ",upperdir=", Upper(""),
+                                    ",workdir=", JoinPath(dir_.path(), "work"));
+    if (!opts.empty()) {
+      absl::StrAppend(&data, ",", opts);
+    }
+    auto mount = Mount("overlay", Merged(""), "overlay", 0, data, 0);
+    if (!IsRunningOnGvisor() && !mount.ok() &&
+        mount.error().errno_value() == EINVAL) {
+      GTEST_SKIP() << "overlay options not supported: " << opts;
+    }
+    overlay_ = ASSERT_NO_ERRNO_AND_VALUE(std::move(mount));
+  }
+
+  std::string Lower(absl::string_view path) const {
+    return JoinPath(dir_.path(), "lower", path);
+  }
+  std::string Upper(absl::string_view path) const {
+    return JoinPath(dir_.path(), "upper", path);
+  }
+  std::string Merged(absl::string_view path) const {
+    return JoinPath(dir_.path(), "merged", path);
+  }
+
+  TempPath dir_;
+  Cleanup tmpfs_;
+  Cleanup overlay_;
+};
+
+// HasXattr returns true if the file at path has the extended attribute name.
+bool HasXattr(const std::string& path, const char* name) {
+  return lgetxattr(path.c_str(), name, nullptr, 0) >= 0;
+}
+
+TEST_F(OverlayTest, MetacopyChmod) {
+  ASSERT_NO_ERRNO(SetContents(Lower("file"), kData));
+  ASSERT_NO_FATAL_FAILURE(MountOverlay("metacopy=on"));
+
+  ASSERT_THAT(chmod(Merged("file").c_str(), 0600), SyscallSucceeds());
+
+  // Only the file's metadata was copied up.
+  struct stat st;
+  ASSERT_THAT(stat(Upper("file").c_str(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_mode & 0777, 0600);
+  EXPECT_EQ(st.st_size, static_cast<off_t>(strlen(kData)));
+  EXPECT_EQ(st.st_blocks, 0);
+  EXPECT_TRUE(HasXattr(Upper("file"), kMetacopyXattr));
+
+  // The data is still read from the lower layer.
+  ASSERT_THAT(stat(Merged("file").c_str(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_mode & 0777, 0600);
+  EXPECT_THAT(GetContents(Merged("file")), IsPosixErrorOkAndHolds(kData));
+}
+
+TEST_F(OverlayTest, MetacopyChown) {
+  ASSERT_NO_ERRNO(SetContents(Lower("file"), kData));
+  ASSERT_NO_FATAL_FAILURE(MountOverlay("metacopy=on"));
+
+  ASSERT_THAT(chown(Merged("file").c_str(), getuid(), getgid()),
+              SyscallSucceeds());
+
+  struct stat st;
+  ASSERT_THAT(stat(Upper("file").c_str(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_blocks, 0);
+  EXPECT_TRUE(HasXattr(Upper("file"), kMetacopyXattr));
+  EXPECT_THAT(GetContents(Merged("file")), IsPosixErrorOkAndHolds(kData));
+}
+
+TEST_F(OverlayTest, MetacopyDisabled) {
+  ASSERT_NO_ERRNO(SetContents(Lower("file"), kData));
+  ASSERT_NO_FATAL_FAILURE(MountOverlay(""));
+
+  ASSERT_THAT(chmod(Merged("file").c_str(), 0600), SyscallSucceeds());
+
+  // Without metacopy=on, the file's data is copied up along with its
+  // metadata.
+  EXPECT_FALSE(HasXattr(Upper("file"), kMetacopyXattr));
+  EXPECT_THAT(GetContents(Upper("file")), IsPosixErrorOkAndHolds(kData));
+}
+
+// Metacopy files are marked with an extended attribute on the upper layer. If
+// the upper layer doesn't support it, as with gofer mounts in gVisor, the
+// overlay copies up data instead.
+TEST(OverlayUpperLayerTest, MetacopyWithoutUpperXattrs) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  // The upper layer is on the test's filesystem rather than on tmpfs.
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const std::string upper = JoinPath(dir.path(), "upper");
+  const std::string work = JoinPath(dir.path(), "work");
+  const std::string merged = JoinPath(dir.path(), "merged");
+  for (const std::string& path : {upper, work, merged}) {
+    ASSERT_NO_ERRNO(Mkdir(path));
+  }
+  const TempPath lower = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const tmpfs = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("none", lower.path(), "tmpfs", 0, "mode=0755", 0));
+  ASSERT_NO_ERRNO(SetContents(JoinPath(lower.path(), "file"), kData));
+
+  auto mount = Mount("overlay", merged, "overlay", 0,
+                     absl::StrCat("lowerdir=", lower.path(), ",upperdir=",
+                                  upper, ",workdir=", work, ",metacopy=on"),
+                     0);
+  if (!IsRunningOnGvisor() && !mount.ok() &&
+      mount.error().errno_value() == EINVAL) {
+    GTEST_SKIP() << "overlay not supported on the test's filesystem";
+  }
+  auto const overlay = ASSERT_NO_ERRNO_AND_VALUE(std::move(mount));
+
+  ASSERT_THAT(chmod(JoinPath(merged, "file").c_str(), 0600),
+              SyscallSucceeds());
+  struct stat st;
+  ASSERT_THAT(stat(JoinPath(merged, "file").c_str(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_mode & 0777, 0600);
+  EXPECT_THAT(GetContents(JoinPath(merged, "file")),
+              IsPosixErrorOkAndHolds(kData));
+
+  const bool upper_xattrs =
+      lgetxattr(upper.c_str(), kMetacopyXattr, nullptr, 0) >= 0 ||
+      errno != EOPNOTSUPP;
+  if (upper_xattrs) {
+    EXPECT_TRUE(HasXattr(JoinPath(upper, "file"), kMetacopyXattr));
+  } else {
+    EXPECT_THAT(GetContents(JoinPath(upper, "file")),
+                IsPosixErrorOkAndHolds(kData));
+  }
+}
+
+TEST_F(OverlayTest, DataCopyUpOnOpenForWrite) {
+  ASSERT_NO_ERRNO(SetContents(Lower("file"), kData));
+  ASSERT_NO_FATAL_FAILURE(MountOverlay("metacopy=on"));
+
+  ASSERT_THAT(chmod(Merged("file").c_str(), 0600), SyscallSucceeds());
+  ASSERT_TRUE(HasXattr(Upper("file"), kMetacopyXattr));
+
+  // Opening the file for writing copies up its data, even if nothing is
+  // written.
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(Merged("file"), O_WRONLY));
+  EXPECT_FALSE(HasXattr(Upper("file"), kMetacopyXattr));
+  EXPECT_THAT(GetContents(Upper("file")), IsPosixErrorOkAndHolds(kData));
+
+  // The lower layer is unaffected by writes.
+  ASSERT_THAT(WriteFd(fd.get(), "J", 1), SyscallSucceedsWithValue(1));
+  EXPECT_THAT(GetContents(Merged("file")), IsPosixErrorOkAndHolds("Jello"));
+  EXPECT_THAT(GetContents(Lower("file")), IsPosixErrorOkAndHolds(kData));
+}
+
+TEST_F(OverlayTest, RenameRedirectedDirectory) {
+  ASSERT_NO_ERRNO(Mkdir(Lower("dir")));
+  ASSERT_NO_ERRNO(SetContents(Lower("dir/file"), kData));
+  ASSERT_NO_FATAL_FAILURE(MountOverlay("redirect_dir=on"));
+
+  ASSERT_THAT(rename(Merged("dir").c_str(), Merged("renamed").c_str()),
+              SyscallSucceeds());
+
+  // The directory's contents weren't copied up; instead, the directory on the
+  // upper layer redirects lookups to the lower one.
+  EXPECT_TRUE(HasXattr(Upper("renamed"), kRedirectXattr));
+  EXPECT_THAT(Exists(Upper("renamed/file")), IsPosixErrorOkAndHolds(false));
+  EXPECT_THAT(GetContents(Merged("renamed/file")),
+              IsPosixErrorOkAndHolds(kData));
+  EXPECT_THAT(Exists(Merged("dir")), IsPosixErrorOkAndHolds(false));
+
+  // Redirected directories can be renamed again.
+  ASSERT_NO_ERRNO(Mkdir(Merged("parent")));
+  ASSERT_THAT(
+      rename(Merged("renamed").c_str(), Merged("parent/again").c_str()),
+      SyscallSucceeds());
+  EXPECT_THAT(GetContents(Merged("parent/again/file")),
+              IsPosixErrorOkAndHolds(kData));
+  EXPECT_THAT(Exists(Merged("renamed")), IsPosixErrorOkAndHolds(false));
+}
+
+TEST_F(OverlayTest, RedirectDirNoFollow) {
+  ASSERT_NO_ERRNO(Mkdir(Lower("dir")));
+  ASSERT_NO_ERRNO(SetContents(Lower("dir/file"), kData));
+  // Create a redirect on the upper layer, as if dir had been renamed to
+  // renamed with redirect_dir=on.
+  ASSERT_NO_ERRNO(Mkdir(Upper("renamed")));
+  ASSERT_THAT(lsetxattr(Upper("renamed").c_str(), kRedirectXattr, "dir", 3, 0),
+              SyscallSucceeds());
+  ASSERT_NO_FATAL_FAILURE(MountOverlay("redirect_dir=nofollow"));
+
+  // The redirect isn't followed.
+  EXPECT_THAT(Exists(Merged("renamed")), IsPosixErrorOkAndHolds(true));
+  EXPECT_THAT(Exists(Merged("renamed/file")), IsPosixErrorOkAndHolds(false));
+  EXPECT_THAT(GetContents(Merged("dir/file")), IsPosixErrorOkAndHolds(kData));
+}
+
+TEST_F(OverlayTest, UserxattrConflicts) {
+  const std::string base =
+      absl::StrCat("lowerdir=", Lower(""), ",upperdir=", Upper(""),
+                   ",workdir=", JoinPath(dir_.path(), "work"), ",userxattr");
+  EXPECT_THAT(mount("overlay", Merged("").c_str(), "overlay", 0,
+                    absl::StrCat(base, ",metacopy=on").c_str()),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(mount("overlay", Merged("").c_str(), "overlay", 0,
+                    absl::StrCat(base, ",redirect_dir=on").c_str()),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+}  // namespace
+}  // namespace testing
+}  // namespace gvisor
@@ -30,6 +30,7 @@
 #include <sys/stat.h>
 #include <sys/statfs.h>
 #include <sys/utsname.h>
+#include <sys/wait.h>
 #include <syscall.h>
 #include <unistd.h>
 
@@ -1614,6 +1615,34 @@ TEST(ProcLoadavg, Fields) {
   EXPECT_TRUE(absl::SimpleAtoi(fields[5], &val2)) << proc_loadvg;
 }
 
+TEST(ProcLoadavg, TaskCounts) {
+  pid_t child = fork();
+  if (child == 0) {
+    _exit(0);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+
+  std::string proc_loadvg =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/loadavg"));
+  std::vector<std::string> fields =
+      absl::StrSplit(proc_loadvg, absl::ByAnyChar(" /\n"),
+                     absl::SkipWhitespace());
+  ASSERT_EQ(fields.size(), 6) << proc_loadvg;
+
+  uint64_t running, total, last_pid;
+  ASSERT_TRUE(absl::SimpleAtoi(fields[3], &running)) << proc_loadvg;
+  ASSERT_TRUE(absl::SimpleAtoi(fields[4], &total)) << proc_loadvg;
+  ASSERT_TRUE(absl::SimpleAtoi(fields[5], &last_pid)) << proc_loadvg;
+  // At least this task is running.
+  EXPECT_GE(running, 1) << proc_loadvg;
+  EXPECT_GE(total, running) << proc_loadvg;
+  // PIDs may wrap, but not in the course of this test.
+  EXPECT_GE(last_pid, child) << proc_loadvg;
+}
+
 // NOTE: Tests in priority.cc also check certain priority related fields in
 // /proc/self/stat.
 
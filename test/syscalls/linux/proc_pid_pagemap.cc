@@ -0,0 +1,165 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <stdint.h>
+#include <sys/mman.h>
+#include <unistd.h>
+
+#include <limits>
+#include <utility>
+
+#include "gtest/gtest.h"
+#include "absl/strings/str_cat.h"
+#include "test/util/capability_util.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/memory_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+// Bits in /proc/[pid]/pagemap entries.
+constexpr uint64_t kPagemapPFNMask = (uint64_t{1} << 55) - 1;
+constexpr uint64_t kPagemapSoftDirty = uint64_t{1} << 55;
+constexpr uint64_t kPagemapExclusive = uint64_t{1} << 56;
+constexpr uint64_t kPagemapPresent = uint64_t{1} << 63;
+
+PosixErrorOr<uint64_t> PagemapEntry(int fd, uintptr_t addr) {
+  uint64_t entry;
+  off_t const off = (addr / kPageSize) * sizeof(entry);
+  int const n = pread(fd, &entry, sizeof(entry), off);
+  if (n < 0) {
+    return PosixError(errno, "pread");
+  }
+  if (n != sizeof(entry)) {
+    return PosixError(EIO, absl::StrCat("short read: ", n));
+  }
+  return entry;
+}
+
+PosixError ClearSoftDirty() {
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor fd,
+                         Open("/proc/self/clear_refs", O_WRONLY));
+  if (WriteFd(fd.get(), "4", 1) != 1) {
+    return PosixError(errno, "write clear_refs");
+  }
+  return NoError();
+}
+
+TEST(ProcPidPagemapTest, PresentPages) {
+  Mapping const m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(2 * kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  FileDescriptor const fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/self/pagemap", O_RDONLY));
+
+  *reinterpret_cast<volatile char*>(m.addr()) = 1;
+
+  uint64_t const touched =
+      ASSERT_NO_ERRNO_AND_VALUE(PagemapEntry(fd.get(), m.addr()));
+  EXPECT_TRUE(touched & kPagemapPresent);
+  EXPECT_TRUE(touched & kPagemapExclusive);
+  if (!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN))) {
+    EXPECT_EQ(touched & kPagemapPFNMask, 0);
+  }
+
+  uint64_t const untouched =
+      ASSERT_NO_ERRNO_AND_VALUE(PagemapEntry(fd.get(), m.addr() + kPageSize));
+  EXPECT_FALSE(untouched & kPagemapPresent);
+}
+
+TEST(ProcPidPagemapTest, UnalignedRead) {
+  FileDescriptor const fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/self/pagemap", O_RDONLY));
+  char buf[16];
+  EXPECT_THAT(pread(fd.get(), buf, 8, 1), SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(pread(fd.get(), buf, 7, 0), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(ProcPidPagemapTest, ReadPastAddressSpace) {
+  FileDescriptor const fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/self/pagemap", O_RDONLY));
+  uint64_t entry;
+  // The entry for an address past the end of any address space.
+  off_t const off = ((uint64_t{1} << 63) / kPageSize) * sizeof(entry);
+  EXPECT_THAT(pread(fd.get(), &entry, sizeof(entry), off),
+              SyscallSucceedsWithValue(0));
+  // The largest aligned offset, which doesn't correspond to any address.
+  off_t const max_off = std::numeric_limits<off_t>::max() & ~off_t{7};
+  EXPECT_THAT(pread(fd.get(), &entry, sizeof(entry), max_off),
+              SyscallSucceedsWithValue(0));
+}
+
+TEST(ProcPidPagemapTest, SoftDirty) {
+  Mapping const m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  FileDescriptor const fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/self/pagemap", O_RDONLY));
+  volatile char* const p = reinterpret_cast<volatile char*>(m.addr());
+
+  *p = 1;
+  uint64_t entry = ASSERT_NO_ERRNO_AND_VALUE(PagemapEntry(fd.get(), m.addr()));
+  EXPECT_TRUE(entry & kPagemapSoftDirty);
+
+  ASSERT_NO_ERRNO(ClearSoftDirty());
+  entry = ASSERT_NO_ERRNO_AND_VALUE(PagemapEntry(fd.get(), m.addr()));
+  EXPECT_TRUE(entry & kPagemapPresent);
+  EXPECT_FALSE(entry & kPagemapSoftDirty);
+
+  // Reads don't make the page soft-dirty.
+  EXPECT_EQ(*p, 1);
+  entry = ASSERT_NO_ERRNO_AND_VALUE(PagemapEntry(fd.get(), m.addr()));
+  EXPECT_FALSE(entry & kPagemapSoftDirty);
+
+  *p = 2;
+  entry = ASSERT_NO_ERRNO_AND_VALUE(PagemapEntry(fd.get(), m.addr()));
+  EXPECT_TRUE(entry & kPagemapSoftDirty);
+}
+
+TEST(ProcPidPagemapTest, ClearRefsInvalid) {
+  FileDescriptor const fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/self/clear_refs", O_WRONLY));
+  EXPECT_THAT(WriteFd(fd.get(), "9", 1), SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(WriteFd(fd.get(), "x", 1), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(ProcKpageflagsTest, Read) {
+  auto fd_or = Open("/proc/kpageflags", O_RDONLY);
+  SKIP_IF(!fd_or.ok() && (fd_or.error().errno_value() == EACCES ||
+                           fd_or.error().errno_value() == EPERM));
+  FileDescriptor const fd = ASSERT_NO_ERRNO_AND_VALUE(std::move(fd_or));
+  uint64_t flags[8];
+  EXPECT_THAT(pread(fd.get(), flags, sizeof(flags), 0),
+              SyscallSucceedsWithValue(sizeof(flags)));
+  EXPECT_THAT(pread(fd.get(), flags, sizeof(flags), 4),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(ProcKpageflagsTest, RequiresCapSysAdmin) {
+  // Linux only checks file permissions.
+  SKIP_IF(!IsRunningOnGvisor());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  AutoCapability cap(CAP_SYS_ADMIN, false);
+  EXPECT_THAT(Open("/proc/kpageflags", O_RDONLY),
+              PosixErrorIs(EPERM, ::testing::_));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
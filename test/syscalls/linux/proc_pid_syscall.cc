@@ -0,0 +1,148 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <signal.h>
+#include <stdint.h>
+#include <sys/syscall.h>
+#include <sys/wait.h>
+#include <unistd.h>
+
+#include <string>
+#include <vector>
+
+#include "gtest/gtest.h"
+#include "absl/strings/match.h"
+#include "absl/strings/numbers.h"
+#include "absl/strings/str_cat.h"
+#include "absl/strings/str_split.h"
+#include "absl/time/clock.h"
+#include "absl/time/time.h"
+#include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+// ForkBlockedReader forks a child that blocks reading from a new pipe, and
+// waits until /proc/[pid]/syscall shows it blocked in read(2). It returns the
+// child's PID and the read end of the pipe, and sets fields to the fields of
+// the child's /proc/[pid]/syscall.
+void ForkBlockedReader(pid_t* child, int pipe_fds[2],
+                       std::vector<std::string>* fields) {
+  ASSERT_THAT(pipe(pipe_fds), SyscallSucceeds());
+  *child = fork();
+  if (*child == 0) {
+    char c;
+    read(pipe_fds[0], &c, 1);
+    _exit(0);
+  }
+  ASSERT_THAT(*child, SyscallSucceeds());
+
+  const std::string path = absl::StrCat("/proc/", *child, "/syscall");
+  const absl::Time deadline = absl::Now() + absl::Seconds(30);
+  while (true) {
+    const std::string contents = ASSERT_NO_ERRNO_AND_VALUE(GetContents(path));
+    *fields = absl::StrSplit(contents, absl::ByAnyChar(" \n"),
+                             absl::SkipEmpty());
+    if (!fields->empty() && (*fields)[0] == absl::StrCat(SYS_read)) {
+      return;
+    }
+    ASSERT_LT(absl::Now(), deadline) << "child never blocked: " << contents;
+    absl::SleepFor(absl::Milliseconds(10));
+  }
+}
+
+void KillChild(pid_t child) {
+  EXPECT_THAT(kill(child, SIGKILL), SyscallSucceeds());
+  int status;
+  EXPECT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+}
+
+TEST(ProcPidSyscallTest, BlockedInRead) {
+  pid_t child;
+  int pipe_fds[2];
+  std::vector<std::string> fields;
+  ASSERT_NO_FATAL_FAILURE(ForkBlockedReader(&child, pipe_fds, &fields));
+  auto cleanup = Cleanup([&] {
+    KillChild(child);
+    close(pipe_fds[0]);
+    close(pipe_fds[1]);
+  });
+
+  // Syscall number, 6 arguments, stack pointer and instruction pointer.
+  ASSERT_EQ(fields.size(), 9);
+  uint64_t fd;
+  ASSERT_TRUE(absl::SimpleHexAtoi(fields[1], &fd)) << fields[1];
+  EXPECT_EQ(fd, pipe_fds[0]);
+  uint64_t count;
+  ASSERT_TRUE(absl::SimpleHexAtoi(fields[3], &count)) << fields[3];
+  EXPECT_EQ(count, 1);
+}
+
+TEST(ProcPidSyscallTest, Wchan) {
+  pid_t child;
+  int pipe_fds[2];
+  std::vector<std::string> fields;
+  ASSERT_NO_FATAL_FAILURE(ForkBlockedReader(&child, pipe_fds, &fields));
+  auto cleanup = Cleanup([&] {
+    KillChild(child);
+    close(pipe_fds[0]);
+    close(pipe_fds[1]);
+  });
+
+  const std::string wchan = ASSERT_NO_ERRNO_AND_VALUE(
+      GetContents(absl::StrCat("/proc/", child, "/wchan")));
+  EXPECT_NE(wchan, "0");
+  if (IsRunningOnGvisor()) {
+    EXPECT_EQ(wchan, "pipe_read");
+  }
+}
+
+TEST(ProcPidSyscallTest, Stack) {
+  pid_t child;
+  int pipe_fds[2];
+  std::vector<std::string> fields;
+  ASSERT_NO_FATAL_FAILURE(ForkBlockedReader(&child, pipe_fds, &fields));
+  auto cleanup = Cleanup([&] {
+    KillChild(child);
+    close(pipe_fds[0]);
+    close(pipe_fds[1]);
+  });
+
+  const std::string path = absl::StrCat("/proc/", child, "/stack");
+  if (!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN))) {
+    std::string contents;
+    EXPECT_THAT(GetContents(path, &contents), PosixErrorIs(EACCES));
+    return;
+  }
+  const std::string stack = ASSERT_NO_ERRNO_AND_VALUE(GetContents(path));
+  EXPECT_NE(stack, "");
+  for (absl::string_view line :
+       absl::StrSplit(stack, '\n', absl::SkipEmpty())) {
+    EXPECT_TRUE(absl::StartsWith(line, "[<")) << line;
+  }
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -13,11 +13,14 @@
 // limitations under the License.
 
 #include <elf.h>
+#include <linux/filter.h>
+#include <linux/seccomp.h>
 #include <signal.h>
 #include <stddef.h>
 #include <sys/prctl.h>
 #include <sys/ptrace.h>
 #include <sys/socket.h>
+#include <sys/syscall.h>
 #include <sys/time.h>
 #include <sys/types.h>
 #include <sys/user.h>
@@ -29,6 +32,7 @@
 
 #include "gmock/gmock.h"
 #include "gtest/gtest.h"
+#include "absl/base/macros.h"
 #include "absl/flags/flag.h"
 #include "absl/strings/string_view.h"
 #include "absl/time/clock.h"
@@ -105,6 +109,49 @@ constexpr auto kPtraceSysemu = static_cast<__ptrace_request>(31);
 // PTRACE_EVENT_STOP").
 constexpr int kPtraceEventStop = 128;
 
+// PTRACE_PEEKSIGINFO is not defined until glibc 2.18,
+// PTRACE_SECCOMP_GET_FILTER is not defined until glibc 2.26, and
+// PTRACE_GET_SYSCALL_INFO is not defined until glibc 2.31.
+constexpr auto kPtracePeekSiginfo = static_cast<__ptrace_request>(0x4209);
+constexpr auto kPtraceSeccompGetFilter = static_cast<__ptrace_request>(0x420c);
+constexpr auto kPtraceGetSyscallInfo = static_cast<__ptrace_request>(0x420e);
+
+// Equivalent to struct ptrace_peeksiginfo_args.
+struct PtracePeekSiginfoArgs {
+  uint64_t off;
+  uint32_t flags;
+  int32_t nr;
+};
+
+// Values of PtraceSyscallInfo::op.
+constexpr uint8_t kPtraceSyscallInfoNone = 0;
+constexpr uint8_t kPtraceSyscallInfoEntry = 1;
+constexpr uint8_t kPtraceSyscallInfoExit = 2;
+
+// Equivalent to struct ptrace_syscall_info.
+struct PtraceSyscallInfo {
+  uint8_t op;
+  uint8_t pad[3];
+  uint32_t arch;
+  uint64_t instruction_pointer;
+  uint64_t stack_pointer;
+  union {
+    struct {
+      uint64_t nr;
+      uint64_t args[6];
+    } entry;
+    struct {
+      int64_t rval;
+      uint8_t is_error;
+    } exit;
+    struct {
+      uint64_t nr;
+      uint64_t args[6];
+      uint32_t ret_data;
+    } seccomp;
+  };
+};
+
 // Sends sig to the current process with tgkill(2).
 //
 // glibc's raise(2) may change the signal mask before sending the signal. These
@@ -1295,6 +1342,214 @@ TEST(PtraceTest, GetSiginfo_SetSiginfo_SignalInjection) {
       << " status " << status;
 }
 
+TEST(PtraceTest, PeekSiginfo) {
+  constexpr int kBlockSignal = SIGUSR1;
+
+  pid_t const child_pid = fork();
+  if (child_pid == 0) {
+    // In child process.
+    sigset_t blocked;
+    TEST_PCHECK(sigemptyset(&blocked) == 0);
+    TEST_PCHECK(sigaddset(&blocked, kBlockSignal) == 0);
+    TEST_PCHECK(sigprocmask(SIG_BLOCK, &blocked, nullptr) == 0);
+    MaybeSave();
+
+    TEST_PCHECK(ptrace(PTRACE_TRACEME, 0, 0, 0) == 0);
+    MaybeSave();
+
+    // Leave kBlockSignal pending on the thread, then stop.
+    RaiseSignal(kBlockSignal);
+    RaiseSignal(SIGSTOP);
+    _exit(0);
+  }
+  // In parent process.
+  ASSERT_THAT(child_pid, SyscallSucceeds());
+
+  int status;
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSTOPPED(status) && WSTOPSIG(status) == SIGSTOP)
+      << " status " << status;
+
+  // kBlockSignal is pending on the thread.
+  siginfo_t infos[2] = {};
+  PtracePeekSiginfoArgs args = {.off = 0, .flags = 0, .nr = 2};
+  ASSERT_THAT(ptrace(kPtracePeekSiginfo, child_pid, &args, infos),
+              SyscallSucceedsWithValue(1));
+  EXPECT_EQ(infos[0].si_signo, kBlockSignal);
+  EXPECT_EQ(infos[0].si_code, SI_TKILL);
+
+  // Skipping it leaves nothing to copy.
+  args.off = 1;
+  EXPECT_THAT(ptrace(kPtracePeekSiginfo, child_pid, &args, infos),
+              SyscallSucceedsWithValue(0));
+
+  // Nothing is pending on the thread group.
+  args.off = 0;
+  args.flags = 1;  // PTRACE_PEEKSIGINFO_SHARED
+  EXPECT_THAT(ptrace(kPtracePeekSiginfo, child_pid, &args, infos),
+              SyscallSucceedsWithValue(0));
+
+  // Invalid flags and counts are rejected.
+  args.flags = 2;
+  EXPECT_THAT(ptrace(kPtracePeekSiginfo, child_pid, &args, infos),
+              SyscallFailsWithErrno(EINVAL));
+  args.flags = 0;
+  args.nr = -1;
+  EXPECT_THAT(ptrace(kPtracePeekSiginfo, child_pid, &args, infos),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Clean up the child.
+  ASSERT_THAT(kill(child_pid, SIGKILL), SyscallSucceeds());
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSIGNALED(status) && WTERMSIG(status) == SIGKILL)
+      << " status " << status;
+}
+
+TEST(PtraceTest, GetSyscallInfo) {
+  pid_t const child_pid = fork();
+  if (child_pid == 0) {
+    // In child process.
+    TEST_PCHECK(ptrace(PTRACE_TRACEME, 0, 0, 0) == 0);
+    MaybeSave();
+    RaiseSignal(SIGSTOP);
+
+    // getcwd(NULL, 0) fails with ERANGE.
+    syscall(SYS_getcwd, nullptr, 0);
+    _exit(0);
+  }
+  // In parent process.
+  ASSERT_THAT(child_pid, SyscallSucceeds());
+
+  int status;
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSTOPPED(status) && WSTOPSIG(status) == SIGSTOP)
+      << " status " << status;
+  ASSERT_THAT(ptrace(PTRACE_SETOPTIONS, child_pid, 0, PTRACE_O_TRACESYSGOOD),
+              SyscallSucceeds());
+
+  // A signal-delivery-stop is not a syscall stop.
+  PtraceSyscallInfo info = {};
+  ASSERT_THAT(ptrace(kPtraceGetSyscallInfo, child_pid, sizeof(info), &info),
+              SyscallSucceedsWithValue(offsetof(PtraceSyscallInfo, entry)));
+  EXPECT_EQ(info.op, kPtraceSyscallInfoNone);
+  EXPECT_NE(info.instruction_pointer, 0);
+  EXPECT_NE(info.stack_pointer, 0);
+
+  // Suppress SIGSTOP and wait for syscall-enter-stop.
+  ASSERT_THAT(ptrace(PTRACE_SYSCALL, child_pid, 0, 0), SyscallSucceeds());
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSTOPPED(status) && WSTOPSIG(status) == (SIGTRAP | 0x80))
+      << " status " << status;
+  ASSERT_THAT(ptrace(kPtraceGetSyscallInfo, child_pid, sizeof(info), &info),
+              SyscallSucceedsWithValue(offsetof(PtraceSyscallInfo, entry) +
+                                       sizeof(info.entry)));
+  EXPECT_EQ(info.op, kPtraceSyscallInfoEntry);
+  EXPECT_EQ(info.entry.nr, SYS_getcwd);
+  EXPECT_EQ(info.entry.args[0], 0);
+  EXPECT_EQ(info.entry.args[1], 0);
+
+  // Only the requested number of bytes is copied.
+  PtraceSyscallInfo partial = {};
+  ASSERT_THAT(ptrace(kPtraceGetSyscallInfo, child_pid, 1, &partial),
+              SyscallSucceedsWithValue(offsetof(PtraceSyscallInfo, entry) +
+                                       sizeof(info.entry)));
+  EXPECT_EQ(partial.op, kPtraceSyscallInfoEntry);
+  EXPECT_EQ(partial.arch, 0);
+
+  // Wait for syscall-exit-stop.
+  ASSERT_THAT(ptrace(PTRACE_SYSCALL, child_pid, 0, 0), SyscallSucceeds());
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSTOPPED(status) && WSTOPSIG(status) == (SIGTRAP | 0x80))
+      << " status " << status;
+  ASSERT_THAT(ptrace(kPtraceGetSyscallInfo, child_pid, sizeof(info), &info),
+              SyscallSucceedsWithValue(offsetof(PtraceSyscallInfo, exit) +
+                                       offsetof(decltype(info.exit), is_error) +
+                                       1));
+  EXPECT_EQ(info.op, kPtraceSyscallInfoExit);
+  EXPECT_EQ(info.exit.rval, -ERANGE);
+  EXPECT_EQ(info.exit.is_error, 1);
+
+  // Clean up the child.
+  ASSERT_THAT(kill(child_pid, SIGKILL), SyscallSucceeds());
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSIGNALED(status) && WTERMSIG(status) == SIGKILL)
+      << " status " << status;
+}
+
+TEST(PtraceTest, SeccompGetFilter) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  // Two filters that allow everything, of different lengths.
+  struct sock_filter short_filter[] = {
+      BPF_STMT(BPF_RET | BPF_K, SECCOMP_RET_ALLOW),
+  };
+  struct sock_filter long_filter[] = {
+      BPF_STMT(BPF_LD | BPF_W | BPF_ABS, offsetof(struct seccomp_data, nr)),
+      BPF_STMT(BPF_RET | BPF_K, SECCOMP_RET_ALLOW),
+  };
+
+  pid_t const child_pid = fork();
+  if (child_pid == 0) {
+    // In child process.
+    TEST_PCHECK(ptrace(PTRACE_TRACEME, 0, 0, 0) == 0);
+    TEST_PCHECK(prctl(PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0) == 0);
+    struct sock_fprog prog = {
+        .len = ABSL_ARRAYSIZE(short_filter),
+        .filter = short_filter,
+    };
+    TEST_PCHECK(syscall(SYS_seccomp, SECCOMP_SET_MODE_FILTER, 0, &prog) == 0);
+    prog = {
+        .len = ABSL_ARRAYSIZE(long_filter),
+        .filter = long_filter,
+    };
+    TEST_PCHECK(syscall(SYS_seccomp, SECCOMP_SET_MODE_FILTER, 0, &prog) == 0);
+    MaybeSave();
+    RaiseSignal(SIGSTOP);
+    _exit(0);
+  }
+  // In parent process.
+  ASSERT_THAT(child_pid, SyscallSucceeds());
+
+  int status;
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSTOPPED(status) && WSTOPSIG(status) == SIGSTOP)
+      << " status " << status;
+
+  // The most recently installed filter has index 0. Without a buffer, only
+  // the number of instructions is returned.
+  EXPECT_THAT(ptrace(kPtraceSeccompGetFilter, child_pid, 0, nullptr),
+              SyscallSucceedsWithValue(ABSL_ARRAYSIZE(long_filter)));
+  struct sock_filter got[ABSL_ARRAYSIZE(long_filter)] = {};
+  ASSERT_THAT(ptrace(kPtraceSeccompGetFilter, child_pid, 0, got),
+              SyscallSucceedsWithValue(ABSL_ARRAYSIZE(long_filter)));
+  for (size_t i = 0; i < ABSL_ARRAYSIZE(long_filter); i++) {
+    EXPECT_EQ(got[i].code, long_filter[i].code) << i;
+    EXPECT_EQ(got[i].k, long_filter[i].k) << i;
+  }
+  ASSERT_THAT(ptrace(kPtraceSeccompGetFilter, child_pid, 1, got),
+              SyscallSucceedsWithValue(ABSL_ARRAYSIZE(short_filter)));
+  EXPECT_EQ(got[0].code, short_filter[0].code);
+  EXPECT_EQ(got[0].k, short_filter[0].k);
+
+  // There is no third filter.
+  EXPECT_THAT(ptrace(kPtraceSeccompGetFilter, child_pid, 2, got),
+              SyscallFailsWithErrno(ENOENT));
+
+  // Clean up the child.
+  ASSERT_THAT(kill(child_pid, SIGKILL), SyscallSucceeds());
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSIGNALED(status) && WTERMSIG(status) == SIGKILL)
+      << " status " << status;
+}
+
 TEST(PtraceTest, SIGKILLDoesNotCauseSignalDeliveryStop) {
   pid_t const child_pid = fork();
   if (child_pid == 0) {
@@ -0,0 +1,98 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <sched.h>
+#include <sys/mount.h>
+#include <sys/quota.h>
+#include <sys/syscall.h>
+#include <unistd.h>
+
+#include <utility>
+
+#include "gtest/gtest.h"
+#include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/mount_util.h"
+#include "test/util/multiprocess_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+#ifndef SYS_quotactl_fd
+#define SYS_quotactl_fd 443
+#endif
+
+namespace gvisor {
+namespace testing {
+namespace {
+
+// An id that isn't the test's uid.
+constexpr uid_t kOtherID = 12345;
+
+int QuotactlFD(int fd, int cmd, int id, struct dqblk* dq) {
+  return syscall(SYS_quotactl_fd, fd, cmd, id, dq);
+}
+
+class QuotactlTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+    SKIP_IF(getuid() == kOtherID);
+    dir_ = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+    auto mount = Mount("", dir_.path(), "tmpfs", 0, "usrquota", 0);
+    // Quotas are only supported on tmpfs since Linux 6.6.
+    SKIP_IF(!IsRunningOnGvisor() && !mount.ok() &&
+            mount.error().errno_value() == EINVAL);
+    mount_ = ASSERT_NO_ERRNO_AND_VALUE(std::move(mount));
+    fd_ = ASSERT_NO_ERRNO_AND_VALUE(Open(dir_.path(), O_RDONLY | O_DIRECTORY));
+  }
+
+  TempPath dir_;
+  Cleanup mount_;
+  FileDescriptor fd_;
+};
+
+TEST_F(QuotactlTest, GetQuota) {
+  struct dqblk dq = {};
+  EXPECT_THAT(QuotactlFD(fd_.get(), QCMD(Q_GETQUOTA, USRQUOTA), kOtherID, &dq),
+              SyscallSucceeds());
+}
+
+TEST_F(QuotactlTest, UnprivilegedGetQuotaOfOtherUser) {
+  AutoCapability cap(CAP_SYS_ADMIN, false);
+  struct dqblk dq = {};
+  EXPECT_THAT(QuotactlFD(fd_.get(), QCMD(Q_GETQUOTA, USRQUOTA), kOtherID, &dq),
+              SyscallFailsWithErrno(EPERM));
+}
+
+// The root user of a child user namespace doesn't have CAP_SYS_ADMIN in the
+// user namespace that owns the filesystem.
+TEST_F(QuotactlTest, UserNamespaceRootGetQuotaOfOtherUser) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(CanCreateUserNamespace()));
+  const int fd = fd_.get();
+  const auto rest = [fd] {
+    TEST_PCHECK(unshare(CLONE_NEWUSER) == 0);
+    struct dqblk dq = {};
+    TEST_CHECK(QuotactlFD(fd, QCMD(Q_GETQUOTA, USRQUOTA), kOtherID, &dq) ==
+               -1);
+    TEST_PCHECK(errno == EPERM);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+}  // namespace
+}  // namespace testing
+}  // namespace gvisor
@@ -0,0 +1,191 @@
+// Copyright 2026 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <arpa/inet.h>
+#include <net/if.h>
+#include <netinet/in.h>
+#include <sched.h>
+#include <stdint.h>
+#include <sys/ioctl.h>
+#include <sys/mount.h>
+#include <sys/socket.h>
+
+#include <algorithm>
+#include <string>
+#include <vector>
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "absl/strings/numbers.h"
+#include "absl/strings/str_cat.h"
+#include "absl/strings/strip.h"
+#include "test/util/capability_util.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/mount_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/socket_util.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+#include "test/util/thread_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+using ::testing::Contains;
+
+std::vector<std::string> InterfaceNames() {
+  std::vector<std::string> names;
+  struct if_nameindex* ifs = if_nameindex();
+  if (ifs == nullptr) {
+    return names;
+  }
+  for (struct if_nameindex* i = ifs; i->if_index != 0; i++) {
+    names.push_back(i->if_name);
+  }
+  if_freenameindex(ifs);
+  std::sort(names.begin(), names.end());
+  return names;
+}
+
+PosixErrorOr<std::string> NetAttr(absl::string_view iface,
+                                  absl::string_view attr) {
+  ASSIGN_OR_RETURN_ERRNO(
+      std::string contents,
+      GetContents(absl::StrCat("/sys/class/net/", iface, "/", attr)));
+  return std::string(absl::StripSuffix(contents, "\n"));
+}
+
+PosixErrorOr<uint64_t> NetStat(absl::string_view iface,
+                               absl::string_view stat) {
+  ASSIGN_OR_RETURN_ERRNO(std::string contents,
+                         NetAttr(iface, absl::StrCat("statistics/", stat)));
+  uint64_t val;
+  if (!absl::SimpleAtoi(contents, &val)) {
+    return PosixError(EINVAL, absl::StrCat("invalid statistic: ", contents));
+  }
+  return val;
+}
+
+TEST(SysfsNetTest, ListsAllInterfaces) {
+  std::vector<std::string> want = InterfaceNames();
+  ASSERT_FALSE(want.empty());
+
+  std::vector<std::string> got =
+      ASSERT_NO_ERRNO_AND_VALUE(ListDir("/sys/class/net", true));
+  std::sort(got.begin(), got.end());
+  EXPECT_EQ(got, want);
+}
+
+// Interfaces are listed from the network namespace that sysfs was mounted in,
+// not the namespace of the reader.
+TEST(SysfsNetTest, UsesMountNetworkNamespace) {
+  SKIP_IF(IsRunningWithHostinet());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const std::vector<std::string> want = InterfaceNames();
+  ASSERT_FALSE(want.empty());
+  auto outer = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto inner = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const auto outer_mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("sysfs", outer.path(), "sysfs", 0, "", 0));
+
+  ScopedThread t([&] {
+    ASSERT_THAT(unshare(CLONE_NEWNET), SyscallSucceeds());
+
+    // The sysfs mounted in the original namespace is unaffected.
+    std::vector<std::string> got = ASSERT_NO_ERRNO_AND_VALUE(
+        ListDir(JoinPath(outer.path(), "class/net"), true));
+    std::sort(got.begin(), got.end());
+    EXPECT_EQ(got, want);
+
+    // A sysfs mounted in the new namespace lists its loopback interface.
+    const auto inner_mount = ASSERT_NO_ERRNO_AND_VALUE(
+        Mount("sysfs", inner.path(), "sysfs", 0, "", 0));
+    got = ASSERT_NO_ERRNO_AND_VALUE(
+        ListDir(JoinPath(inner.path(), "class/net"), true));
+    EXPECT_THAT(got, Contains("lo"));
+  });
+}
+
+TEST(SysfsNetTest, LoopbackAttributes) {
+  const unsigned int ifindex = if_nametoindex("lo");
+  SKIP_IF(ifindex == 0);
+
+  EXPECT_THAT(NetAttr("lo", "ifindex"),
+              IsPosixErrorOkAndHolds(absl::StrCat(ifindex)));
+  EXPECT_THAT(NetAttr("lo", "address"),
+              IsPosixErrorOkAndHolds("00:00:00:00:00:00"));
+  EXPECT_THAT(NetAttr("lo", "operstate"), IsPosixErrorOkAndHolds("unknown"));
+
+  FileDescriptor sock =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  struct ifreq ifr = {};
+  snprintf(ifr.ifr_name, IFNAMSIZ, "lo");
+  ASSERT_THAT(ioctl(sock.get(), SIOCGIFMTU, &ifr), SyscallSucceeds());
+  EXPECT_THAT(NetAttr("lo", "mtu"),
+              IsPosixErrorOkAndHolds(absl::StrCat(ifr.ifr_mtu)));
+
+  ASSERT_THAT(ioctl(sock.get(), SIOCGIFFLAGS, &ifr), SyscallSucceeds());
+  const std::string flags_str =
+      ASSERT_NO_ERRNO_AND_VALUE(NetAttr("lo", "flags"));
+  uint32_t flags;
+  ASSERT_TRUE(absl::SimpleHexAtoi(flags_str, &flags)) << flags_str;
+  EXPECT_EQ(flags & IFF_LOOPBACK, IFF_LOOPBACK);
+  EXPECT_EQ(flags & 0xffff, ifr.ifr_flags & 0xffff);
+}
+
+TEST(SysfsNetTest, LoopbackStatistics) {
+  SKIP_IF(if_nametoindex("lo") == 0);
+
+  FileDescriptor sock =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  struct sockaddr_in addr = {};
+  addr.sin_family = AF_INET;
+  addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  ASSERT_THAT(bind(sock.get(), reinterpret_cast<struct sockaddr*>(&addr),
+                   sizeof(addr)),
+              SyscallSucceeds());
+  socklen_t addrlen = sizeof(addr);
+  ASSERT_THAT(getsockname(sock.get(), reinterpret_cast<struct sockaddr*>(&addr),
+                          &addrlen),
+              SyscallSucceeds());
+
+  const uint64_t before =
+      ASSERT_NO_ERRNO_AND_VALUE(NetStat("lo", "tx_packets"));
+  char buf[16] = {};
+  ASSERT_THAT(sendto(sock.get(), buf, sizeof(buf), 0,
+                     reinterpret_cast<struct sockaddr*>(&addr), addrlen),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_THAT(RecvTimeout(sock.get(), buf, sizeof(buf), 5 /* seconds */),
+              IsPosixErrorOkAndHolds(sizeof(buf)));
+  const uint64_t after =
+      ASSERT_NO_ERRNO_AND_VALUE(NetStat("lo", "tx_packets"));
+  EXPECT_GT(after, before);
+
+  // Statistics that aren't tracked still exist.
+  EXPECT_NO_ERRNO(NetStat("lo", "rx_crc_errors"));
+}
+
+TEST(SysfsNetTest, NonexistentInterface) {
+  EXPECT_THAT(NetAttr("doesnotexist0", "mtu"), PosixErrorIs(ENOENT, _));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
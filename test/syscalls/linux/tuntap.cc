@@ -18,6 +18,7 @@
 #include <linux/if_arp.h>
 #include <linux/if_ether.h>
 #include <linux/if_tun.h>
+#include <linux/virtio_net.h>
 #include <netinet/ip.h>
 #include <netinet/ip_icmp.h>
 #include <poll.h>
@@ -771,5 +772,178 @@ TEST_F(TuntapTest, RawPacketSocket) {
   }
 }
 
+TEST_F(TuntapTest, MultiQueueAttachDetach) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+
+  FileDescriptor fd1 = ASSERT_NO_ERRNO_AND_VALUE(Open(kDevNetTun, O_RDWR));
+  FileDescriptor fd2 = ASSERT_NO_ERRNO_AND_VALUE(Open(kDevNetTun, O_RDWR));
+  FileDescriptor fd3 = ASSERT_NO_ERRNO_AND_VALUE(Open(kDevNetTun, O_RDWR));
+
+  struct ifreq ifr = {};
+  ifr.ifr_flags = IFF_TUN | IFF_NO_PI | IFF_MULTI_QUEUE;
+  strncpy(ifr.ifr_name, kTunName, IFNAMSIZ);
+  ASSERT_THAT(ioctl(fd1.get(), TUNSETIFF, &ifr), SyscallSucceeds());
+  ASSERT_THAT(ioctl(fd2.get(), TUNSETIFF, &ifr), SyscallSucceeds());
+
+  // A single queue can't be attached to a multi-queue interface.
+  struct ifreq ifr_single = {};
+  ifr_single.ifr_flags = IFF_TUN | IFF_NO_PI;
+  strncpy(ifr_single.ifr_name, kTunName, IFNAMSIZ);
+  EXPECT_THAT(ioctl(fd3.get(), TUNSETIFF, &ifr_single),
+              SyscallFailsWithErrno(EINVAL));
+
+  struct ifreq ifr_get = {};
+  ASSERT_THAT(ioctl(fd2.get(), TUNGETIFF, &ifr_get), SyscallSucceeds());
+  EXPECT_NE(ifr_get.ifr_flags & IFF_MULTI_QUEUE, 0);
+
+  struct ifreq ifr_queue = {};
+  ifr_queue.ifr_flags = IFF_ATTACH_QUEUE;
+  // The queue is already attached.
+  EXPECT_THAT(ioctl(fd2.get(), TUNSETQUEUE, &ifr_queue),
+              SyscallFailsWithErrno(EINVAL));
+
+  ifr_queue.ifr_flags = IFF_DETACH_QUEUE;
+  ASSERT_THAT(ioctl(fd2.get(), TUNSETQUEUE, &ifr_queue), SyscallSucceeds());
+  EXPECT_THAT(ioctl(fd2.get(), TUNSETQUEUE, &ifr_queue),
+              SyscallFailsWithErrno(EINVAL));
+
+  // A detached queue can't be used.
+  char buf[128];
+  EXPECT_THAT(read(fd2.get(), buf, sizeof(buf)), SyscallFailsWithErrno(EBADFD));
+
+  ifr_queue.ifr_flags = IFF_ATTACH_QUEUE;
+  EXPECT_THAT(ioctl(fd2.get(), TUNSETQUEUE, &ifr_queue), SyscallSucceeds());
+
+  // The interface is removed once all queues are closed.
+  fd1.reset();
+  fd2.reset();
+  EXPECT_THAT(GetLinkByName(kTunName), PosixErrorIs(ENOENT));
+}
+
+TEST_F(TuntapTest, SetQueueSingleQueue) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(kDevNetTun, O_RDWR));
+
+  struct ifreq ifr = {};
+  ifr.ifr_flags = IFF_TUN | IFF_NO_PI;
+  strncpy(ifr.ifr_name, kTunName, IFNAMSIZ);
+  ASSERT_THAT(ioctl(fd.get(), TUNSETIFF, &ifr), SyscallSucceeds());
+
+  struct ifreq ifr_queue = {};
+  ifr_queue.ifr_flags = IFF_DETACH_QUEUE;
+  EXPECT_THAT(ioctl(fd.get(), TUNSETQUEUE, &ifr_queue),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(TuntapTest, GetFeatures) {
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(kDevNetTun, O_RDWR));
+
+  unsigned int features = 0;
+  ASSERT_THAT(ioctl(fd.get(), TUNGETFEATURES, &features), SyscallSucceeds());
+  EXPECT_NE(features & IFF_TUN, 0);
+  EXPECT_NE(features & IFF_TAP, 0);
+  EXPECT_NE(features & IFF_NO_PI, 0);
+  EXPECT_NE(features & IFF_VNET_HDR, 0);
+  EXPECT_NE(features & IFF_MULTI_QUEUE, 0);
+}
+
+TEST_F(TuntapTest, VnetHdrSize) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(kDevNetTun, O_RDWR));
+
+  int size;
+  // No interface is attached yet.
+  EXPECT_THAT(ioctl(fd.get(), TUNGETVNETHDRSZ, &size),
+              SyscallFailsWithErrno(EBADFD));
+
+  struct ifreq ifr = {};
+  ifr.ifr_flags = IFF_TUN | IFF_NO_PI | IFF_VNET_HDR;
+  strncpy(ifr.ifr_name, kTunName, IFNAMSIZ);
+  ASSERT_THAT(ioctl(fd.get(), TUNSETIFF, &ifr), SyscallSucceeds());
+
+  ASSERT_THAT(ioctl(fd.get(), TUNGETVNETHDRSZ, &size), SyscallSucceeds());
+  EXPECT_EQ(size, sizeof(struct virtio_net_hdr));
+
+  size = sizeof(struct virtio_net_hdr) - 1;
+  EXPECT_THAT(ioctl(fd.get(), TUNSETVNETHDRSZ, &size),
+              SyscallFailsWithErrno(EINVAL));
+
+  size = sizeof(struct virtio_net_hdr_mrg_rxbuf);
+  ASSERT_THAT(ioctl(fd.get(), TUNSETVNETHDRSZ, &size), SyscallSucceeds());
+  size = 0;
+  ASSERT_THAT(ioctl(fd.get(), TUNGETVNETHDRSZ, &size), SyscallSucceeds());
+  EXPECT_EQ(size, sizeof(struct virtio_net_hdr_mrg_rxbuf));
+
+  EXPECT_THAT(ioctl(fd.get(), TUNSETOFFLOAD, 0x80000000),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(ioctl(fd.get(), TUNSETOFFLOAD, TUN_F_CSUM | TUN_F_TSO4),
+              SyscallSucceeds());
+}
+
+// This test pings the kernel through a TUN device whose packets are preceded by
+// a virtio-net header.
+TEST_F(TuntapTest, PingKernelVnetHdr) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+
+  // Interface creation.
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(kDevNetTun, O_RDWR));
+
+  struct ifreq ifr_set = {};
+  ifr_set.ifr_flags = IFF_TUN | IFF_NO_PI | IFF_VNET_HDR;
+  strncpy(ifr_set.ifr_name, kTunName, IFNAMSIZ);
+  ASSERT_THAT(ioctl(fd.get(), TUNSETIFF, &ifr_set), SyscallSucceeds());
+
+  // Interface setup.
+  auto link = ASSERT_NO_ERRNO_AND_VALUE(GetLinkByName(kTunName));
+  const struct in_addr dev_ipv4_addr = {.s_addr = kTapIPAddr};
+  FileDescriptor nlsk =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_ROUTE));
+  EXPECT_NO_ERRNO(LinkAddLocalAddr(nlsk, link.index, AF_INET, 24,
+                                   &dev_ipv4_addr, sizeof(dev_ipv4_addr)));
+  if (!IsRunningOnGvisor()) {
+    ASSERT_NO_ERRNO(LinkChangeFlags(link.index, IFF_UP, IFF_UP));
+  }
+
+  struct vnet_ping_pkt {
+    virtio_net_hdr vnet;
+    ping_ip_pkt ip_pkt;
+  } __attribute__((packed));
+
+  vnet_ping_pkt ping_req = {};
+  ping_req.ip_pkt = CreatePingIPPacket(kTapPeerIPAddr, kTapIPAddr);
+
+  // A write must contain a complete virtio-net header.
+  EXPECT_THAT(write(fd.get(), &ping_req, sizeof(ping_req.vnet) - 1),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Send ICMP query
+  EXPECT_THAT(write(fd.get(), &ping_req, sizeof(ping_req)),
+              SyscallSucceedsWithValue(sizeof(ping_req)));
+
+  // Receive loop to process inbound packets.
+  while (1) {
+    vnet_ping_pkt ping_resp = {};
+    size_t n;
+    ASSERT_THAT(n = read(fd.get(), &ping_resp, sizeof(ping_resp)),
+                SyscallSucceeds());
+    if (n != sizeof(ping_resp)) {
+      continue;
+    }
+
+    // Process ping response packet.
+    if (!memcmp(&ping_resp.ip_pkt.ip.saddr, &ping_req.ip_pkt.ip.daddr,
+                kIPLen) &&
+        !memcmp(&ping_resp.ip_pkt.ip.daddr, &ping_req.ip_pkt.ip.saddr,
+                kIPLen) &&
+        ping_resp.ip_pkt.icmp.type == 0 && ping_resp.ip_pkt.icmp.code == 0) {
+      EXPECT_EQ(ping_resp.vnet.gso_type, VIRTIO_NET_HDR_GSO_NONE);
+      // Ends and passes the test.
+      break;
+    }
+  }
+}
+
 }  // namespace testing
 }  // namespace gvisor
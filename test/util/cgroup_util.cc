@@ -14,11 +14,16 @@
 
 #include "test/util/cgroup_util.h"
 
+#include <signal.h>
+#include <sys/mman.h>
 #include <sys/syscall.h>
+#include <sys/wait.h>
 #include <unistd.h>
 
 #include "absl/strings/str_cat.h"
 #include "absl/strings/str_split.h"
+#include "absl/time/clock.h"
+#include "absl/time/time.h"
 #include "test/util/fs_util.h"
 #include "test/util/mount_util.h"
 
@@ -318,5 +323,81 @@ ProcPIDCgroupEntries(pid_t pid) {
   return entries;
 }
 
+PosixErrorOr<pid_t> ForkMemoryHog(const Cgroup& cg, size_t size) {
+  int fds[2];
+  if (pipe(fds) < 0) {
+    return PosixError(errno, "pipe");
+  }
+  const pid_t pid = fork();
+  if (pid < 0) {
+    const int err = errno;
+    close(fds[0]);
+    close(fds[1]);
+    return PosixError(err, "fork");
+  }
+  if (pid == 0) {
+    close(fds[1]);
+    // Wait to be moved to cg, so that our memory is charged to it.
+    char c;
+    if (read(fds[0], &c, 1) != 1) {
+      _exit(1);
+    }
+    if (size == 0) {
+      while (true) {
+        pause();
+      }
+    }
+    void* addr = mmap(nullptr, size, PROT_READ | PROT_WRITE,
+                      MAP_PRIVATE | MAP_ANONYMOUS, -1, 0);
+    if (addr == MAP_FAILED) {
+      _exit(1);
+    }
+    volatile char* mem = static_cast<volatile char*>(addr);
+    const size_t page_size = getpagesize();
+    for (char val = 1;; val++) {
+      for (size_t off = 0; off < size; off += page_size) {
+        mem[off] = val;
+      }
+      usleep(10000);
+    }
+  }
+
+  close(fds[0]);
+  PosixError err = cg.Enter(pid);
+  if (err.ok() && write(fds[1], "x", 1) != 1) {
+    err = PosixError(errno, "write");
+  }
+  close(fds[1]);
+  if (!err.ok()) {
+    kill(pid, SIGKILL);
+    waitpid(pid, nullptr, 0);
+    return err;
+  }
+  return pid;
+}
+
+PosixError WaitForKill(pid_t pid, absl::Duration timeout) {
+  const absl::Time deadline = absl::Now() + timeout;
+  while (true) {
+    int status;
+    const pid_t ret = waitpid(pid, &status, WNOHANG);
+    if (ret < 0) {
+      return PosixError(errno, "waitpid");
+    }
+    if (ret == pid) {
+      if (WIFSIGNALED(status) && WTERMSIG(status) == SIGKILL) {
+        return NoError();
+      }
+      return PosixError(EINVAL, absl::StrCat("child ", pid,
+                                             " exited with status ", status));
+    }
+    if (absl::Now() > deadline) {
+      return PosixError(ETIMEDOUT, absl::StrCat("child ", pid,
+                                                " wasn't killed"));
+    }
+    absl::SleepFor(absl::Milliseconds(10));
+  }
+}
+
 }  // namespace testing
 }  // namespace gvisor
@@ -20,6 +20,7 @@
 #include "absl/container/flat_hash_map.h"
 #include "absl/container/flat_hash_set.h"
 #include "absl/strings/string_view.h"
+#include "absl/time/time.h"
 #include "test/util/cleanup.h"
 #include "test/util/fs_util.h"
 #include "test/util/temp_path.h"
@@ -159,6 +160,15 @@ struct PIDCgroupEntry {
 PosixErrorOr<absl::flat_hash_map<std::string, PIDCgroupEntry>>
 ProcPIDCgroupEntries(pid_t pid);
 
+// Forks a child process in cg that allocates size bytes of anonymous memory,
+// and keeps writing to it until it's killed. If size is 0, the child only
+// waits to be killed. Returns the child's PID.
+PosixErrorOr<pid_t> ForkMemoryHog(const Cgroup& cg, size_t size);
+
+// Waits up to timeout for the child process pid to be killed by SIGKILL, and
+// reaps it.
+PosixError WaitForKill(pid_t pid, absl::Duration timeout);
+
 }  // namespace testing
 }  // namespace gvisor
 
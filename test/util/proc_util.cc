@@ -276,6 +276,8 @@ PosixErrorOr<std::vector<ProcSmapsEntry>> ParseProcSmaps(
       RETURN_IF_ERRNO(on_optional_field_kb(&entry->anonymous_kb));
     } else if (key == "AnonHugePages") {
       RETURN_IF_ERRNO(on_optional_field_kb(&entry->anon_huge_pages_kb));
+    } else if (key == "ShmemPmdMapped") {
+      RETURN_IF_ERRNO(on_optional_field_kb(&entry->shmem_pmd_mapped_kb));
     } else if (key == "Shared_Hugetlb") {
       RETURN_IF_ERRNO(on_optional_field_kb(&entry->shared_hugetlb_kb));
     } else if (key == "Private_Hugetlb") {
@@ -63,6 +63,7 @@ struct ProcSmapsEntry {
   absl::optional<size_t> referenced_kb;
   absl::optional<size_t> anonymous_kb;
   absl::optional<size_t> anon_huge_pages_kb;
+  absl::optional<size_t> shmem_pmd_mapped_kb;
   absl::optional<size_t> shared_hugetlb_kb;
   absl::optional<size_t> private_hugetlb_kb;
   absl::optional<size_t> swap_kb;